@@ -17,6 +17,9 @@ limitations under the License.
 package main
 
 import (
+	"github.com/google/knative-gcp/pkg/broker/config/stream"
+	"github.com/google/knative-gcp/pkg/broker/config/volume"
+	"github.com/google/knative-gcp/pkg/broker/ingress"
 	"github.com/google/knative-gcp/pkg/metrics"
 	"github.com/google/knative-gcp/pkg/utils"
 	"github.com/google/knative-gcp/pkg/utils/appcredentials"
@@ -37,6 +40,12 @@ type envConfig struct {
 
 	// Default 300Mi.
 	PublishBufferedByteLimit int `envconfig:"PUBLISH_BUFFERED_BYTES_LIMIT" default:"314572800"`
+
+	// TargetsStreamAddress is the address of the controller streaming the targets config of the
+	// BrokerCell. When empty, the targets config is only read from the ConfigMap volume.
+	TargetsStreamAddress string `envconfig:"TARGETS_STREAM_ADDRESS"`
+	BrokerCellNamespace  string `envconfig:"BROKER_CELL_NAMESPACE"`
+	BrokerCellName       string `envconfig:"BROKER_CELL_NAME"`
 }
 
 const (
@@ -63,7 +72,10 @@ func main() {
 	}
 	logger.Desugar().Info("Starting ingress handler", zap.Any("envConfig", env), zap.Any("Project ID", projectID))
 
-	ingress, err := InitializeHandler(
+	// targetsUpdateCh is signaled each time the targets config is updated, so that the trigger
+	// filter index is rebuilt.
+	targetsUpdateCh := make(chan struct{})
+	ingressHandler, err := InitializeHandler(
 		ctx,
 		clients.Port(env.Port),
 		clients.ProjectID(projectID),
@@ -71,13 +83,20 @@ func main() {
 		metrics.ContainerName(component),
 		publishSetting(logger.Desugar(), env),
 		env.AuthType,
+		[]volume.Option{volume.WithNotifyChan(targetsUpdateCh)},
+		[]stream.Option{
+			stream.WithAddress(env.TargetsStreamAddress),
+			stream.WithBrokerCell(env.BrokerCellNamespace, env.BrokerCellName),
+			stream.WithNotifyChan(targetsUpdateCh),
+		},
+		ingress.TargetsUpdates(targetsUpdateCh),
 	)
 	if err != nil {
 		logger.Desugar().Fatal("Unable to create ingress handler: ", zap.Error(err))
 	}
 
-	logger.Desugar().Info("Starting ingress.", zap.Any("ingress", ingress))
-	if err := ingress.Start(ctx); err != nil {
+	logger.Desugar().Info("Starting ingress.", zap.Any("ingress", ingressHandler))
+	if err := ingressHandler.Start(ctx); err != nil {
 		logger.Desugar().Fatal("failed to start ingress: ", zap.Error(err))
 	}
 }
@@ -8,6 +8,8 @@ package main
 import (
 	"cloud.google.com/go/pubsub"
 	"context"
+	"github.com/google/knative-gcp/pkg/broker/claimcheck"
+	"github.com/google/knative-gcp/pkg/broker/config/stream"
 	"github.com/google/knative-gcp/pkg/broker/config/volume"
 	"github.com/google/knative-gcp/pkg/broker/ingress"
 	"github.com/google/knative-gcp/pkg/metrics"
@@ -17,10 +19,9 @@ import (
 
 // Injectors from wire.go:
 
-func InitializeHandler(ctx context.Context, port clients.Port, projectID clients.ProjectID, podName metrics.PodName, containerName metrics.ContainerName, publishSettings pubsub.PublishSettings, authType authcheck.AuthType) (*ingress.Handler, error) {
+func InitializeHandler(ctx context.Context, port clients.Port, projectID clients.ProjectID, podName metrics.PodName, containerName metrics.ContainerName, publishSettings pubsub.PublishSettings, authType authcheck.AuthType, targetsVolumeOpts []volume.Option, targetsStreamOpts []stream.Option, targetsUpdates ingress.TargetsUpdates) (*ingress.Handler, error) {
 	httpMessageReceiver := clients.NewHTTPMessageReceiverWithChecker(port, authType)
-	v := _wireValue
-	readonlyTargets, err := volume.NewTargetsFromFile(v...)
+	readonlyTargets, err := stream.NewTargets(ctx, targetsVolumeOpts, targetsStreamOpts)
 	if err != nil {
 		return nil, err
 	}
@@ -28,15 +29,17 @@ func InitializeHandler(ctx context.Context, port clients.Port, projectID clients
 	if err != nil {
 		return nil, err
 	}
-	multiTopicDecoupleSink := ingress.NewMultiTopicDecoupleSink(ctx, readonlyTargets, client, publishSettings)
 	ingressReporter, err := metrics.NewIngressReporter(podName, containerName)
 	if err != nil {
 		return nil, err
 	}
-	handler := ingress.NewHandler(ctx, httpMessageReceiver, multiTopicDecoupleSink, ingressReporter, authType)
+	storageClient, err := clients.NewStorageClient(ctx)
+	if err != nil {
+		return nil, err
+	}
+	store := claimcheck.NewStore(storageClient)
+	multiTopicDecoupleSink := ingress.NewMultiTopicDecoupleSink(ctx, readonlyTargets, client, publishSettings, ingressReporter, store, targetsUpdates)
+	authenticator := ingress.NewAuthenticator(ctx, readonlyTargets)
+	handler := ingress.NewHandler(ctx, httpMessageReceiver, multiTopicDecoupleSink, authenticator, readonlyTargets, ingressReporter, authType)
 	return handler, nil
 }
-
-var (
-	_wireValue = []volume.Option(nil)
-)
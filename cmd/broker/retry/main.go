@@ -23,9 +23,13 @@ import (
 
 	"cloud.google.com/go/pubsub"
 	"go.uber.org/zap"
+	"knative.dev/pkg/system"
 
+	"github.com/google/knative-gcp/pkg/broker/claimcheck"
+	"github.com/google/knative-gcp/pkg/broker/config/stream"
 	"github.com/google/knative-gcp/pkg/broker/config/volume"
 	"github.com/google/knative-gcp/pkg/broker/handler"
+	"github.com/google/knative-gcp/pkg/broker/shard"
 	"github.com/google/knative-gcp/pkg/metrics"
 	"github.com/google/knative-gcp/pkg/utils"
 	"github.com/google/knative-gcp/pkg/utils/appcredentials"
@@ -45,6 +49,16 @@ type envConfig struct {
 	TargetsConfigPath  string `envconfig:"TARGETS_CONFIG_PATH" default:"/var/run/cloud-run-events/broker/targets"`
 	HandlerConcurrency int    `envconfig:"HANDLER_CONCURRENCY"`
 
+	// TargetsStreamAddress is the address of the controller streaming the targets config of the
+	// BrokerCell. When empty, the targets config is only read from the ConfigMap volume.
+	TargetsStreamAddress string `envconfig:"TARGETS_STREAM_ADDRESS"`
+	BrokerCellNamespace  string `envconfig:"BROKER_CELL_NAMESPACE"`
+	BrokerCellName       string `envconfig:"BROKER_CELL_NAME"`
+
+	// ShardGroup is the group of the replicas sharing the triggers, each replica handling a shard of
+	// them. When empty, each replica handles all the triggers.
+	ShardGroup string `envconfig:"SHARD_GROUP"`
+
 	// Environment variable containing the authType, which represents the authentication configuration mode the Pod is using.
 	AuthType authcheck.AuthType `envconfig:"K_GCP_AUTH_TYPE" default:""`
 
@@ -61,6 +75,13 @@ type envConfig struct {
 
 	// Max to 10m.
 	TimeoutPerEvent time.Duration `envconfig:"TIMEOUT_PER_EVENT"`
+
+	// CircuitBreakerThreshold is the number of consecutive delivery failures that opens the
+	// circuit breaker of a trigger. Zero disables the circuit breakers.
+	CircuitBreakerThreshold int `envconfig:"CIRCUIT_BREAKER_THRESHOLD" default:"5"`
+	// CircuitBreakerOpenDuration is how long the circuit breaker of a trigger stays open before
+	// a delivery is attempted again.
+	CircuitBreakerOpenDuration time.Duration `envconfig:"CIRCUIT_BREAKER_OPEN_DURATION" default:"30s"`
 }
 
 func main() {
@@ -86,7 +107,22 @@ func main() {
 		logger.Fatalf("failed to get default ProjectID: %v", err)
 	}
 
+	storageClient, err := clients.NewStorageClient(ctx)
+	if err != nil {
+		logger.Fatal("Failed to create storage client", zap.Error(err))
+	}
+	opts := append(buildHandlerOptions(env), handler.WithClaimCheckStore(claimcheck.NewStore(storageClient)))
+
 	syncSignal := poolSyncSignal(ctx, targetsUpdateCh)
+	if env.ShardGroup != "" {
+		// Membership changes resync the pool so that the triggers are rebalanced.
+		shards := shard.NewAssigner(env.PodName)
+		leases := res.KubeClient.CoordinationV1().Leases(system.Namespace())
+		if err := shard.NewLeaseMembership(leases, env.ShardGroup, shards).Start(ctx, targetsUpdateCh); err != nil {
+			logger.Fatal("Failed to join the shard group", zap.Error(err))
+		}
+		opts = append(opts, handler.WithShards(shards))
+	}
 	syncPool, err := InitializeSyncPool(
 		ctx,
 		clients.ProjectID(projectID),
@@ -96,7 +132,12 @@ func main() {
 			volume.WithPath(env.TargetsConfigPath),
 			volume.WithNotifyChan(targetsUpdateCh),
 		},
-		buildHandlerOptions(env)...,
+		[]stream.Option{
+			stream.WithAddress(env.TargetsStreamAddress),
+			stream.WithBrokerCell(env.BrokerCellNamespace, env.BrokerCellName),
+			stream.WithNotifyChan(targetsUpdateCh),
+		},
+		opts...,
 	)
 	if err != nil {
 		logger.Fatal("Failed to get retry sync pool", zap.Error(err))
@@ -148,6 +189,7 @@ func buildHandlerOptions(env envConfig) []handler.Option {
 		opts = append(opts, handler.WithTimeoutPerEvent(env.TimeoutPerEvent))
 	}
 	opts = append(opts, handler.WithPubsubReceiveSettings(rs))
+	opts = append(opts, handler.WithCircuitBreaker(env.CircuitBreakerThreshold, env.CircuitBreakerOpenDuration))
 	// The default CeClient is good?
 	return opts
 }
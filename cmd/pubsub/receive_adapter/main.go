@@ -17,8 +17,11 @@ limitations under the License.
 package main
 
 import (
+	"context"
+	"encoding/json"
 	"flag"
 	"fmt"
+	"os"
 	"time"
 
 	"github.com/google/knative-gcp/pkg/testing/testloggingutil"
@@ -30,6 +33,7 @@ import (
 	"knative.dev/pkg/signals"
 	"knative.dev/pkg/tracing"
 
+	gcpduckv1 "github.com/google/knative-gcp/pkg/apis/duck/v1"
 	. "github.com/google/knative-gcp/pkg/pubsub/adapter"
 	"github.com/google/knative-gcp/pkg/pubsub/adapter/converters"
 	tracingconfig "github.com/google/knative-gcp/pkg/tracing"
@@ -64,6 +68,15 @@ type envConfig struct {
 	// Otherwise, only Sink is used (for either the sub.reply or sub.reply)
 	Transformer string `envconfig:"TRANSFORMER_URI"`
 
+	// Environment variable containing the URI where the messages that can't be converted
+	// into events, and the events the sink failed to accept DeadLetterRetry times, are sent.
+	// Only set if the dead letter sink is not a Pub/Sub topic.
+	DeadLetterSink string `envconfig:"DEAD_LETTER_SINK_URI"`
+
+	// Environment variable containing the number of failed delivery attempts after which an
+	// event is sent to the dead letter sink. Only set along with DeadLetterSink.
+	DeadLetterRetry int32 `envconfig:"DEAD_LETTER_RETRY"`
+
 	// Environment variable specifying the type of adapter to use.
 	// Used for CE conversion.
 	AdapterType string `envconfig:"ADAPTER_TYPE"`
@@ -82,6 +95,14 @@ type envConfig struct {
 	// event.
 	ExtensionsBase64 string `envconfig:"K_CE_EXTENSIONS" required:"true"`
 
+	// EventMappingJSON is a JSON string of the EventMapping converting the messages into events.
+	// Only set if the PullSubscription has a mapping.
+	EventMappingJSON string `envconfig:"K_CE_MAPPING"`
+
+	// RawPayloadJSON is a JSON string of the RawPayload options of the events. Only set if the
+	// PullSubscription sends the raw payload of the messages.
+	RawPayloadJSON string `envconfig:"K_CE_RAW_PAYLOAD"`
+
 	// MetricsConfigJson is a json string of metrics.ExporterOptions.
 	// This is used to configure the metrics exporter options, the config is
 	// stored in a config map inside the controllers namespace and copied here.
@@ -113,50 +134,18 @@ func main() {
 
 	flag.Parse()
 
+	if path := os.Getenv(adapterPoolConfigEnvKey); path != "" {
+		runPool(path)
+		return
+	}
+
 	var env envConfig
 	if err := envconfig.Process("", &env); err != nil {
 		panic(fmt.Sprintf("Failed to process env var: %s", err))
 	}
 
-	// Convert json logging.Config to logging.Config.
-	loggingConfig, err := logging.JSONToConfig(env.LoggingConfigJson)
-	if err != nil {
-		fmt.Printf("Failed to process logging config: %s", err.Error())
-		// Use default logging config.
-		if loggingConfig, err = logging.NewConfigFromMap(map[string]string{}); err != nil {
-			// If this fails, there is no recovering.
-			panic(err)
-		}
-	}
-
-	sl, _ := logging.NewLoggerFromConfig(loggingConfig, component)
-	logger := sl.Desugar()
+	ctx, logger := setupObservability(env.LoggingConfigJson, env.MetricsConfigJson, env.TracingConfigJson)
 	defer flush(logger)
-	ctx := logging.WithLogger(signals.NewContext(), logger.Sugar())
-
-	// This is added purely for the TestCloudLogging E2E tests, which verify that the log line is
-	// written based on environment variables.
-	testloggingutil.LogBasedOnEnv(logger)
-
-	// Convert json metrics.ExporterOptions to metrics.ExporterOptions.
-	metricsConfig, err := metrics.JSONToOptions(env.MetricsConfigJson)
-	if err != nil {
-		logger.Error("Failed to process metrics options", zap.Error(err))
-	}
-
-	if metricsConfig != nil {
-		if err := metrics.UpdateExporter(ctx, *metricsConfig, logger.Sugar()); err != nil {
-			logger.Fatal("Failed to create the metrics exporter", zap.Error(err))
-		}
-	}
-
-	tracingConfig, err := tracingconfig.JSONToConfig(env.TracingConfigJson)
-	if err != nil {
-		logger.Error("Failed to process tracing options", zap.Error(err))
-	}
-	if err := tracing.SetupStaticPublishing(logger.Sugar(), "", tracingConfig); err != nil {
-		logger.Error("Failed to setup tracing", zap.Error(err), zap.Any("tracingConfig", tracingConfig))
-	}
 
 	projectID, err := utils.ProjectIDOrDefault("")
 	if err != nil {
@@ -169,15 +158,32 @@ func main() {
 		logger.Error("Failed to convert base64 extensions to map: %v", zap.Error(err))
 	}
 
+	var converter converters.Converter
+	if env.EventMappingJSON != "" {
+		if converter, err = parseEventMapping(env.EventMappingJSON); err != nil {
+			logger.Fatal("Failed to compile the event mapping", zap.Error(err))
+		}
+	}
+	if env.RawPayloadJSON != "" {
+		var rawPayload gcpduckv1.RawPayload
+		if err := json.Unmarshal([]byte(env.RawPayloadJSON), &rawPayload); err != nil {
+			logger.Fatal("Failed to parse the raw payload options", zap.Error(err))
+		}
+		converter = converters.NewRawPayloadConverter(&rawPayload)
+	}
+
 	logger.Info("Initializing adapter", zap.String("projectID", projectID), zap.String("topicID", env.Topic), zap.String("subscriptionID", env.Subscription))
 
 	args := &AdapterArgs{
-		TopicID:        env.Topic,
-		ConverterType:  converters.ConverterType(env.AdapterType),
-		SinkURI:        env.Sink,
-		TransformerURI: env.Transformer,
-		Extensions:     extensions,
-		AuthType:       env.AuthType,
+		TopicID:           env.Topic,
+		ConverterType:     converters.ConverterType(env.AdapterType),
+		Converter:         converter,
+		SinkURI:           env.Sink,
+		TransformerURI:    env.Transformer,
+		DeadLetterSinkURI: env.DeadLetterSink,
+		DeadLetterRetry:   env.DeadLetterRetry,
+		Extensions:        extensions,
+		AuthType:          env.AuthType,
 	}
 
 	adapter, err := InitializeAdapter(ctx,
@@ -205,6 +211,63 @@ func main() {
 	logger.Info("Exiting...")
 }
 
+// parseEventMapping compiles the JSON EventMapping into a converter.
+func parseEventMapping(mappingJSON string) (converters.Converter, error) {
+	var mapping gcpduckv1.EventMapping
+	if err := json.Unmarshal([]byte(mappingJSON), &mapping); err != nil {
+		return nil, err
+	}
+	c, err := converters.NewMappingConverter(&mapping)
+	if err != nil {
+		return nil, err
+	}
+	return c, nil
+}
+
+// setupObservability sets up the logging, metrics and tracing from their JSON configs, and returns
+// the context and logger of the receive adapter.
+func setupObservability(loggingConfigJSON, metricsConfigJSON, tracingConfigJSON string) (context.Context, *zap.Logger) {
+	// Convert json logging.Config to logging.Config.
+	loggingConfig, err := logging.JSONToConfig(loggingConfigJSON)
+	if err != nil {
+		fmt.Printf("Failed to process logging config: %s", err.Error())
+		// Use default logging config.
+		if loggingConfig, err = logging.NewConfigFromMap(map[string]string{}); err != nil {
+			// If this fails, there is no recovering.
+			panic(err)
+		}
+	}
+
+	sl, _ := logging.NewLoggerFromConfig(loggingConfig, component)
+	logger := sl.Desugar()
+	ctx := logging.WithLogger(signals.NewContext(), logger.Sugar())
+
+	// This is added purely for the TestCloudLogging E2E tests, which verify that the log line is
+	// written based on environment variables.
+	testloggingutil.LogBasedOnEnv(logger)
+
+	// Convert json metrics.ExporterOptions to metrics.ExporterOptions.
+	metricsConfig, err := metrics.JSONToOptions(metricsConfigJSON)
+	if err != nil {
+		logger.Error("Failed to process metrics options", zap.Error(err))
+	}
+
+	if metricsConfig != nil {
+		if err := metrics.UpdateExporter(ctx, *metricsConfig, logger.Sugar()); err != nil {
+			logger.Fatal("Failed to create the metrics exporter", zap.Error(err))
+		}
+	}
+
+	tracingConfig, err := tracingconfig.JSONToConfig(tracingConfigJSON)
+	if err != nil {
+		logger.Error("Failed to process tracing options", zap.Error(err))
+	}
+	if err := tracing.SetupStaticPublishing(logger.Sugar(), "", tracingConfig); err != nil {
+		logger.Error("Failed to setup tracing", zap.Error(err), zap.Any("tracingConfig", tracingConfig))
+	}
+	return ctx, logger
+}
+
 func flush(logger *zap.Logger) {
 	_ = logger.Sync()
 	metrics.FlushExporter()
@@ -0,0 +1,71 @@
+/*
+Copyright 2021 Google LLC
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package main
+
+import (
+	"fmt"
+
+	"github.com/kelseyhightower/envconfig"
+	"go.uber.org/zap"
+
+	"github.com/google/knative-gcp/pkg/pubsub/adapter/pool"
+	"github.com/google/knative-gcp/pkg/utils/authcheck"
+	"github.com/google/knative-gcp/pkg/utils/clients"
+)
+
+// adapterPoolConfigEnvKey is the environment variable holding the path of the adapter pool
+// config. When it is set, the receive adapter runs as the adapter pool, delivering the events of
+// all the entries of the config.
+const adapterPoolConfigEnvKey = "ADAPTER_POOL_CONFIG"
+
+// poolEnvConfig is the environment of the receive adapter running as the adapter pool. The
+// arguments of each subscription come from the pool config instead.
+type poolEnvConfig struct {
+	// Environment variable containing the authType, which represents the authentication configuration mode the Pod is using.
+	AuthType authcheck.AuthType `envconfig:"K_GCP_AUTH_TYPE" default:""`
+
+	// MetricsConfigJson is a json string of metrics.ExporterOptions.
+	MetricsConfigJson string `envconfig:"K_METRICS_CONFIG" required:"true"`
+
+	// LoggingConfigJson is a json string of logging.Config.
+	LoggingConfigJson string `envconfig:"K_LOGGING_CONFIG" required:"true"`
+
+	// TracingConfigJson is a JSON string of tracing.Config.
+	TracingConfigJson string `envconfig:"K_TRACING_CONFIG" required:"true"`
+}
+
+// runPool runs the adapter pool with the config in the given file.
+func runPool(path string) {
+	var env poolEnvConfig
+	if err := envconfig.Process("", &env); err != nil {
+		panic(fmt.Sprintf("Failed to process env var: %s", err))
+	}
+
+	ctx, logger := setupObservability(env.LoggingConfigJson, env.MetricsConfigJson, env.TracingConfigJson)
+	defer flush(logger)
+
+	// The pooled adapters share the authentication probe of the pod.
+	pc := authcheck.NewProbeChecker(logger, env.AuthType)
+	go pc.Start(ctx)
+
+	logger.Info("Starting the receive adapter pool", zap.String("config", path))
+	p := pool.New(clients.NewHTTPClient(ctx, maxConnectionsPerHost))
+	if err := p.Watch(ctx, path); err != nil {
+		logger.Error("Receive adapter pool has stopped with error", zap.Error(err))
+	}
+	logger.Info("Exiting...")
+}
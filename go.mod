@@ -1,21 +1,31 @@
 module github.com/google/knative-gcp
 
-go 1.15
+go 1.16
 
 require (
-	cloud.google.com/go v0.72.0
+	cloud.google.com/go v0.104.0
+	cloud.google.com/go/cloudbuild v1.2.0
+	cloud.google.com/go/compute v1.7.0
+	cloud.google.com/go/container v1.4.0 // indirect
+	cloud.google.com/go/iam v0.3.0
 	cloud.google.com/go/logging v1.0.1-0.20200331222814-69e77e66e597
-	cloud.google.com/go/pubsub v1.8.0
-	cloud.google.com/go/storage v1.10.0
+	cloud.google.com/go/monitoring v1.6.0
+	cloud.google.com/go/profiler v0.3.0
+	cloud.google.com/go/pubsub v1.25.1
+	cloud.google.com/go/scheduler v1.4.0
+	cloud.google.com/go/storage v1.23.0
+	cloud.google.com/go/trace v1.2.0
 	github.com/cloudevents/sdk-go/observability/opencensus/v2 v2.4.1
 	github.com/cloudevents/sdk-go/protocol/pubsub/v2 v2.2.1-0.20200806165906-9ae0708e27fa
 	github.com/cloudevents/sdk-go/v2 v2.4.1
 	github.com/fsnotify/fsnotify v1.4.9
+	github.com/ghodss/yaml v1.0.0
 	github.com/golang/protobuf v1.5.2
-	github.com/google/go-cmp v0.5.5
-	github.com/google/uuid v1.2.0
+	github.com/google/cel-go v0.7.3
+	github.com/google/go-cmp v0.5.8
+	github.com/google/uuid v1.3.0
 	github.com/google/wire v0.4.0
-	github.com/googleapis/gax-go/v2 v2.0.5
+	github.com/googleapis/gax-go/v2 v2.4.0
 	github.com/kelseyhightower/envconfig v1.4.0
 	github.com/lightstep/tracecontext.go v0.0.0-20181129014701-1757c391b1ac
 	github.com/pkg/errors v0.9.1
@@ -24,13 +34,13 @@ require (
 	go.opencensus.io v0.23.0
 	go.uber.org/multierr v1.6.0
 	go.uber.org/zap v1.16.0
-	golang.org/x/net v0.0.0-20210415231046-e915ea6b2b7d
-	golang.org/x/oauth2 v0.0.0-20210413134643-5e61552d6c78
-	golang.org/x/sync v0.0.0-20210220032951-036812b2e83c
-	google.golang.org/api v0.36.0
-	google.golang.org/genproto v0.0.0-20210416161957-9910b6c460de
-	google.golang.org/grpc v1.37.0
-	google.golang.org/protobuf v1.26.0
+	golang.org/x/net v0.0.0-20220909164309-bea034e7d591
+	golang.org/x/oauth2 v0.0.0-20220909003341-f21342109be1
+	golang.org/x/sync v0.0.0-20220601150217-0de741cfad7f
+	google.golang.org/api v0.96.0
+	google.golang.org/genproto v0.0.0-20220916172020-2692e8806bfa
+	google.golang.org/grpc v1.48.0
+	google.golang.org/protobuf v1.28.1
 	k8s.io/api v0.19.7
 	k8s.io/apimachinery v0.19.7
 	k8s.io/client-go v0.19.7
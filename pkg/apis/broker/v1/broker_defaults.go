@@ -20,14 +20,21 @@ import (
 	"context"
 
 	eventingduckv1 "knative.dev/eventing/pkg/apis/duck/v1"
+	"knative.dev/eventing/pkg/apis/eventing"
 	"knative.dev/pkg/apis"
 	"knative.dev/pkg/logging"
 
 	"github.com/google/knative-gcp/pkg/apis/configs/brokerdelivery"
+	inteventsv1alpha1 "github.com/google/knative-gcp/pkg/apis/intevents/v1alpha1"
 )
 
 // SetDefaults sets the default field values for a Broker.
 func (b *Broker) SetDefaults(ctx context.Context) {
+	// Assign the default BrokerCell to the GCP Brokers that don't select one.
+	if b.GetAnnotations()[eventing.BrokerClassKey] == BrokerClass {
+		inteventsv1alpha1.SetDefaultBrokerCell(b)
+	}
+
 	// Apply the default Broker delivery settings from the context.
 	withNS := apis.WithinParent(ctx, b.ObjectMeta)
 	deliverySpecDefaults := brokerdelivery.FromContextOrDefaults(withNS).BrokerDeliverySpecDefaults
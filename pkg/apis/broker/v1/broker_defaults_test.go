@@ -253,6 +253,43 @@ func TestBroker_SetDefaults(t *testing.T) {
 				},
 			},
 		},
+		"default BrokerCell of a GCP Broker": {
+			initial: Broker{
+				ObjectMeta: metav1.ObjectMeta{
+					Namespace:   "mynamespace4",
+					Annotations: map[string]string{"eventing.knative.dev/broker.class": BrokerClass},
+				},
+			},
+			expected: Broker{
+				ObjectMeta: metav1.ObjectMeta{
+					Namespace:   "mynamespace4",
+					Labels:      map[string]string{"events.cloud.google.com/broker-cell": "default"},
+					Annotations: map[string]string{"eventing.knative.dev/broker.class": BrokerClass},
+				},
+				Spec: eventingv1.BrokerSpec{
+					Delivery: &eventingduckv1.DeliverySpec{},
+				},
+			},
+		},
+		"selected BrokerCell of a GCP Broker": {
+			initial: Broker{
+				ObjectMeta: metav1.ObjectMeta{
+					Namespace:   "mynamespace4",
+					Labels:      map[string]string{"events.cloud.google.com/broker-cell": "team"},
+					Annotations: map[string]string{"eventing.knative.dev/broker.class": BrokerClass},
+				},
+			},
+			expected: Broker{
+				ObjectMeta: metav1.ObjectMeta{
+					Namespace:   "mynamespace4",
+					Labels:      map[string]string{"events.cloud.google.com/broker-cell": "team"},
+					Annotations: map[string]string{"eventing.knative.dev/broker.class": BrokerClass},
+				},
+				Spec: eventingv1.BrokerSpec{
+					Delivery: &eventingduckv1.DeliverySpec{},
+				},
+			},
+		},
 	}
 	for n, tc := range testCases {
 		t.Run(n, func(t *testing.T) {
@@ -37,6 +37,10 @@ const (
 	// BrokerConditionSubscription reports the status of the Broker's PubSub
 	// subscription. This condition is specific to the Google Cloud Broker.
 	BrokerConditionSubscription apis.ConditionType = "SubscriptionReady"
+	// BrokerConditionDataPlane reports whether the data plane has loaded the current generation of
+	// the Broker. It doesn't affect the readiness of the Broker, since the data plane only loads
+	// the Broker as ready once it is ready.
+	BrokerConditionDataPlane apis.ConditionType = "DataPlaneReady"
 )
 
 // GetCondition returns the condition currently associated with the given type, or nil.
@@ -105,3 +109,14 @@ func (bs *BrokerStatus) MarkSubscriptionUnknown(reason, format string, args ...i
 func (bs *BrokerStatus) MarkSubscriptionReady(_ string) {
 	brokerCondSet.Manage(bs).MarkTrue(BrokerConditionSubscription)
 }
+
+// PropagateDataPlaneObservedGeneration marks the data plane ready once it has loaded the given
+// generation of the Broker.
+func (bs *BrokerStatus) PropagateDataPlaneObservedGeneration(observed, generation int64) {
+	if observed >= generation {
+		brokerCondSet.Manage(bs).MarkTrue(BrokerConditionDataPlane)
+		return
+	}
+	brokerCondSet.Manage(bs).MarkUnknown(BrokerConditionDataPlane, "DataPlaneNotObserved",
+		"The data plane has not loaded generation %d of the Broker yet", generation)
+}
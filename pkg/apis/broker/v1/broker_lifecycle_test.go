@@ -385,3 +385,40 @@ func TestBrokerConditionStatus(t *testing.T) {
 		})
 	}
 }
+
+func TestBrokerPropagateDataPlaneObservedGeneration(t *testing.T) {
+	for _, tc := range []struct {
+		name       string
+		observed   int64
+		wantStatus corev1.ConditionStatus
+	}{{
+		name:       "not observed",
+		observed:   0,
+		wantStatus: corev1.ConditionUnknown,
+	}, {
+		name:       "older generation observed",
+		observed:   1,
+		wantStatus: corev1.ConditionUnknown,
+	}, {
+		name:       "current generation observed",
+		observed:   2,
+		wantStatus: corev1.ConditionTrue,
+	}} {
+		t.Run(tc.name, func(t *testing.T) {
+			bs := &BrokerStatus{}
+			bs.InitializeConditions()
+			bs.SetAddress(apis.HTTP("example.com"))
+			bs.MarkBrokerCellReady()
+			bs.MarkTopicReady()
+			bs.MarkSubscriptionReady("")
+			bs.PropagateDataPlaneObservedGeneration(tc.observed, 2)
+			if got := bs.GetCondition(BrokerConditionDataPlane).Status; got != tc.wantStatus {
+				t.Errorf("unexpected data plane condition status: want %v, got %v", tc.wantStatus, got)
+			}
+			// The data plane condition doesn't affect the readiness of the Broker.
+			if !bs.IsReady() {
+				t.Error("expected the Broker to be ready")
+			}
+		})
+	}
+}
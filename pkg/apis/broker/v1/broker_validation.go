@@ -21,7 +21,9 @@ import (
 
 	eventingduckv1 "knative.dev/eventing/pkg/apis/duck/v1"
 	"knative.dev/pkg/apis"
-	duckv1 "knative.dev/pkg/apis/duck/v1"
+
+	"github.com/google/knative-gcp/pkg/apis/duck"
+	inteventsv1alpha1 "github.com/google/knative-gcp/pkg/apis/intevents/v1alpha1"
 )
 
 // Validate verifies that the Broker is valid.
@@ -29,7 +31,14 @@ func (b *Broker) Validate(ctx context.Context) *apis.FieldError {
 	// We validate the GCP Broker's delivery spec. The eventing webhook will run
 	// the other usual validations.
 	withNS := apis.AllowDifferentNamespace(apis.WithinParent(ctx, b.ObjectMeta))
-	return ValidateDeliverySpec(withNS, b.Spec.Delivery).ViaField("spec", "delivery")
+	return ValidateDeliverySpec(withNS, b.Spec.Delivery).ViaField("spec", "delivery").
+		Also(validateResponseClassification(b.GetAnnotations()).ViaFieldKey("annotations", ResponseClassificationAnnotation).ViaField("metadata")).
+		Also(validateDeliveryAuth(b.GetAnnotations()).ViaFieldKey("annotations", DeliveryAuthAnnotation).ViaField("metadata")).
+		Also(validateIngressAuth(b).ViaFieldKey("annotations", IngressAuthAnnotation).ViaField("metadata")).
+		Also(validateClaimCheck(b).ViaFieldKey("annotations", ClaimCheckAnnotation).ViaField("metadata")).
+		Also(validateOrdering(b).ViaFieldKey("annotations", OrderingAnnotation).ViaField("metadata")).
+		Also(validateReplay(b.GetAnnotations()).ViaFieldKey("annotations", ReplayAnnotation).ViaField("metadata")).
+		Also(inteventsv1alpha1.ValidateBrokerCellLabel(b).ViaFieldKey("labels", inteventsv1alpha1.BrokerCellLabelKey).ViaField("metadata"))
 }
 
 func ValidateDeliverySpec(ctx context.Context, spec *eventingduckv1.DeliverySpec) *apis.FieldError {
@@ -46,25 +55,5 @@ func ValidateDeliverySpec(ctx context.Context, spec *eventingduckv1.DeliverySpec
 	if spec.Retry != nil && spec.DeadLetterSink == nil {
 		errs = errs.Also(apis.ErrGeneric("need DeadLetterSink when retry is defined", "deadLetterSink"))
 	}
-	return errs.Also(ValidateDeadLetterSink(ctx, spec.DeadLetterSink).ViaField("deadLetterSink"))
-}
-
-func ValidateDeadLetterSink(ctx context.Context, sink *duckv1.Destination) *apis.FieldError {
-	if sink == nil {
-		return nil
-	}
-	if sink.URI == nil {
-		return apis.ErrMissingField("uri")
-	}
-	if scheme := sink.URI.Scheme; scheme != "pubsub" {
-		return apis.ErrInvalidValue("Dead letter sink URI scheme should be pubsub", "uri")
-	}
-	topicID := sink.URI.Host
-	if topicID == "" {
-		return apis.ErrInvalidValue("Dead letter topic must not be empty", "uri")
-	}
-	if len(topicID) > 255 {
-		return apis.ErrInvalidValue("Dead letter topic maximum length is 255 characters", "uri")
-	}
-	return nil
+	return errs.Also(duck.ValidateDeadLetterSink(ctx, spec.DeadLetterSink).ViaField("deadLetterSink"))
 }
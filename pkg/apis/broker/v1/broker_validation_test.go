@@ -22,6 +22,8 @@ import (
 	"testing"
 
 	"github.com/google/go-cmp/cmp"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/util/validation"
 	eventingduckv1 "knative.dev/eventing/pkg/apis/duck/v1"
 	eventingv1 "knative.dev/eventing/pkg/apis/eventing/v1"
 	"knative.dev/pkg/apis"
@@ -84,9 +86,9 @@ func TestBroker_Validate(t *testing.T) {
 				},
 			},
 		},
-		want: apis.ErrMissingField("spec.delivery.deadLetterSink.uri"),
+		want: apis.ErrGeneric("expected at least one, got none", "spec.delivery.deadLetterSink.ref", "spec.delivery.deadLetterSink.uri"),
 	}, {
-		name: "invalid dead letter sink uri scheme",
+		name: "invalid relative dead letter sink uri",
 		broker: Broker{
 			Spec: eventingv1.BrokerSpec{
 				Delivery: &eventingduckv1.DeliverySpec{
@@ -94,14 +96,35 @@ func TestBroker_Validate(t *testing.T) {
 					BackoffPolicy: &bop,
 					DeadLetterSink: &duckv1.Destination{
 						URI: &apis.URL{
-							Scheme: "http",
+							Path: "/dead-letter",
+						},
+					},
+				},
+			},
+		},
+		want: apis.ErrInvalidValue("Relative URI is not allowed when Ref and [apiVersion, kind, name] is absent", "spec.delivery.deadLetterSink.uri"),
+	}, {
+		name: "invalid dead letter topic with ref",
+		broker: Broker{
+			Spec: eventingv1.BrokerSpec{
+				Delivery: &eventingduckv1.DeliverySpec{
+					BackoffDelay:  &bod,
+					BackoffPolicy: &bop,
+					DeadLetterSink: &duckv1.Destination{
+						Ref: &duckv1.KReference{
+							APIVersion: "serving.knative.dev/v1",
+							Kind:       "Service",
+							Name:       "dead-letter",
+						},
+						URI: &apis.URL{
+							Scheme: "pubsub",
 							Host:   "test-topic-id",
 						},
 					},
 				},
 			},
 		},
-		want: apis.ErrInvalidValue("Dead letter sink URI scheme should be pubsub", "spec.delivery.deadLetterSink.uri"),
+		want: apis.ErrGeneric("Ref is not allowed with a Pub/Sub dead letter topic", "spec.delivery.deadLetterSink.ref", "spec.delivery.deadLetterSink.uri"),
 	}, {
 		name: "invalid empty dead letter topic id",
 		broker: Broker{
@@ -152,6 +175,52 @@ func TestBroker_Validate(t *testing.T) {
 				},
 			},
 		},
+	}, {
+		name: "valid dead letter uri",
+		broker: Broker{
+			Spec: eventingv1.BrokerSpec{
+				Delivery: &eventingduckv1.DeliverySpec{
+					BackoffDelay:  &bod,
+					BackoffPolicy: &bop,
+					DeadLetterSink: &duckv1.Destination{
+						URI: &apis.URL{
+							Scheme: "http",
+							Host:   "dead-letter.ns.svc.cluster.local",
+						},
+					},
+				},
+			},
+		},
+	}, {
+		name: "valid dead letter ref",
+		broker: Broker{
+			Spec: eventingv1.BrokerSpec{
+				Delivery: &eventingduckv1.DeliverySpec{
+					BackoffDelay:  &bod,
+					BackoffPolicy: &bop,
+					DeadLetterSink: &duckv1.Destination{
+						Ref: &duckv1.KReference{
+							APIVersion: "serving.knative.dev/v1",
+							Kind:       "Service",
+							Namespace:  "ns",
+							Name:       "dead-letter",
+						},
+					},
+				},
+			},
+		},
+	}, {
+		name: "invalid BrokerCell label",
+		broker: Broker{
+			ObjectMeta: metav1.ObjectMeta{
+				Labels: map[string]string{"events.cloud.google.com/broker-cell": "Team_A"},
+			},
+		},
+		want: &apis.FieldError{
+			Message: "invalid value: Team_A",
+			Paths:   []string{"metadata.labels.[events.cloud.google.com/broker-cell]"},
+			Details: strings.Join(validation.IsDNS1123Label("Team_A"), "; "),
+		},
 	}}
 
 	for _, test := range tests {
@@ -0,0 +1,127 @@
+/*
+Copyright 2021 Google LLC
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package v1
+
+import (
+	"encoding/json"
+	"fmt"
+	"time"
+
+	"knative.dev/pkg/apis"
+)
+
+const (
+	// ClaimCheckAnnotation is the annotation key used to store the large payloads of the events
+	// sent to a Broker in Cloud Storage, rather than in the Pub/Sub messages. Its value is a JSON
+	// ClaimCheck.
+	ClaimCheckAnnotation = "events.cloud.google.com/claimCheck"
+
+	// DefaultClaimCheckThresholdBytes is the default size above which payloads are stored in
+	// Cloud Storage.
+	DefaultClaimCheckThresholdBytes = 256 * 1024
+	// DefaultClaimCheckRetentionDays is the default number of days payloads are kept in Cloud
+	// Storage. It's the longest retention of the Pub/Sub messages plus the margin, so that the
+	// events replayed or retried near the end of their retention can still be rehydrated.
+	DefaultClaimCheckRetentionDays = 8
+	// ClaimCheckRetentionMargin is how much longer than the acknowledged events of a replay the
+	// payloads must be kept, for the events replayed near the end of their retention to be
+	// delivered.
+	ClaimCheckRetentionMargin = 24 * time.Hour
+)
+
+// ClaimCheck stores the payloads of the events above a threshold in a Cloud Storage bucket. The
+// events only carry a reference to their payload until they are delivered to subscribers.
+type ClaimCheck struct {
+	// Bucket is the name of the Cloud Storage bucket the payloads are stored in. It must be one
+	// of the ClaimCheckBuckets of the BrokerCell of the Broker, or the claim check is ignored.
+	// The payloads of the Broker are stored under a prefix of its own, which the Broker
+	// controller deletes once the payloads are older than the retention, and when the Broker is
+	// deleted.
+	Bucket string `json:"bucket"`
+
+	// ThresholdBytes is the size above which payloads are stored in the bucket. Defaults to
+	// 256KiB.
+	// +optional
+	ThresholdBytes int64 `json:"thresholdBytes,omitempty"`
+
+	// RetentionDays is the number of days the payloads are kept in the bucket. It must cover the
+	// retention of the replay of the Broker, if any, plus a day. Defaults to 8.
+	// +optional
+	RetentionDays int64 `json:"retentionDays,omitempty"`
+}
+
+// GetClaimCheck returns the claim check set in the ClaimCheckAnnotation of the Broker, if any,
+// with its defaults.
+func (b *Broker) GetClaimCheck() (*ClaimCheck, error) {
+	v, ok := b.GetAnnotations()[ClaimCheckAnnotation]
+	if !ok {
+		return nil, nil
+	}
+	var c ClaimCheck
+	if err := json.Unmarshal([]byte(v), &c); err != nil {
+		return nil, err
+	}
+	if c.ThresholdBytes == 0 {
+		c.ThresholdBytes = DefaultClaimCheckThresholdBytes
+	}
+	if c.RetentionDays == 0 {
+		c.RetentionDays = DefaultClaimCheckRetentionDays
+	}
+	return &c, nil
+}
+
+// Retention returns how long the payloads are kept in the bucket.
+func (c *ClaimCheck) Retention() time.Duration {
+	return time.Duration(c.RetentionDays) * 24 * time.Hour
+}
+
+// Validate checks that the bucket is set and that the threshold and retention are positive.
+func (c *ClaimCheck) Validate() *apis.FieldError {
+	var errs *apis.FieldError
+	if c.Bucket == "" {
+		errs = errs.Also(apis.ErrMissingField("bucket"))
+	}
+	if c.ThresholdBytes <= 0 {
+		errs = errs.Also(apis.ErrInvalidValue(c.ThresholdBytes, "thresholdBytes"))
+	}
+	if c.RetentionDays <= 0 {
+		errs = errs.Also(apis.ErrInvalidValue(c.RetentionDays, "retentionDays"))
+	}
+	return errs
+}
+
+func validateClaimCheck(b *Broker) *apis.FieldError {
+	c, err := b.GetClaimCheck()
+	if err != nil {
+		return apis.ErrInvalidValue(err.Error(), apis.CurrentField)
+	}
+	if c == nil {
+		return nil
+	}
+	errs := c.Validate()
+	// The payloads of the events of a replay must be kept as long as the events. The replay is
+	// validated on its own.
+	if r, err := b.GetReplay(); err == nil && r != nil && c.RetentionDays > 0 {
+		if retention, err := r.RetentionDuration(); err == nil && c.Retention() < retention+ClaimCheckRetentionMargin {
+			errs = errs.Also(&apis.FieldError{
+				Message: fmt.Sprintf("retentionDays must cover the replay retention %v plus %v", retention, ClaimCheckRetentionMargin),
+				Paths:   []string{"retentionDays"},
+			})
+		}
+	}
+	return errs
+}
@@ -0,0 +1,119 @@
+/*
+Copyright 2021 Google LLC
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package v1
+
+import (
+	"context"
+	"testing"
+
+	"github.com/google/go-cmp/cmp"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+)
+
+func TestGetClaimCheck(t *testing.T) {
+	cases := []struct {
+		name       string
+		annotation string
+		want       *ClaimCheck
+	}{{
+		name: "no claim check",
+	}, {
+		name:       "defaults",
+		annotation: `{"bucket":"payloads"}`,
+		want:       &ClaimCheck{Bucket: "payloads", ThresholdBytes: DefaultClaimCheckThresholdBytes, RetentionDays: DefaultClaimCheckRetentionDays},
+	}, {
+		name:       "custom",
+		annotation: `{"bucket":"payloads","thresholdBytes":1024,"retentionDays":3}`,
+		want:       &ClaimCheck{Bucket: "payloads", ThresholdBytes: 1024, RetentionDays: 3},
+	}}
+	for _, tc := range cases {
+		t.Run(tc.name, func(t *testing.T) {
+			b := &Broker{}
+			if tc.annotation != "" {
+				b.SetAnnotations(map[string]string{ClaimCheckAnnotation: tc.annotation})
+			}
+			got, err := b.GetClaimCheck()
+			if err != nil {
+				t.Fatalf("GetClaimCheck() error = %v", err)
+			}
+			if diff := cmp.Diff(tc.want, got); diff != "" {
+				t.Errorf("GetClaimCheck() (-want,+got): %v", diff)
+			}
+		})
+	}
+}
+
+func TestValidateClaimCheck(t *testing.T) {
+	cases := []struct {
+		name       string
+		annotation string
+		replay     string
+		wantErr    string
+	}{{
+		name:       "valid",
+		annotation: `{"bucket":"payloads","thresholdBytes":1024}`,
+	}, {
+		name:       "not json",
+		annotation: `{`,
+		wantErr:    `invalid value: unexpected end of JSON input: metadata.annotations.[events.cloud.google.com/claimCheck]`,
+	}, {
+		name:       "missing bucket",
+		annotation: `{}`,
+		wantErr:    `missing field(s): metadata.annotations.[events.cloud.google.com/claimCheck].bucket`,
+	}, {
+		name:       "negative threshold",
+		annotation: `{"bucket":"payloads","thresholdBytes":-1}`,
+		wantErr:    `invalid value: -1: metadata.annotations.[events.cloud.google.com/claimCheck].thresholdBytes`,
+	}, {
+		name:       "negative retention",
+		annotation: `{"bucket":"payloads","retentionDays":-1}`,
+		wantErr:    `invalid value: -1: metadata.annotations.[events.cloud.google.com/claimCheck].retentionDays`,
+	}, {
+		name:       "default retention covers the replay",
+		annotation: `{"bucket":"payloads"}`,
+		replay:     `{}`,
+	}, {
+		name:       "retention covers the replay",
+		annotation: `{"bucket":"payloads","retentionDays":2}`,
+		replay:     `{"retention":"24h"}`,
+	}, {
+		name:       "retention shorter than the replay",
+		annotation: `{"bucket":"payloads","retentionDays":7}`,
+		replay:     `{}`,
+		wantErr:    `retentionDays must cover the replay retention 168h0m0s plus 24h0m0s: metadata.annotations.[events.cloud.google.com/claimCheck].retentionDays`,
+	}}
+	for _, tc := range cases {
+		t.Run(tc.name, func(t *testing.T) {
+			b := &Broker{ObjectMeta: metav1.ObjectMeta{
+				Annotations: map[string]string{ClaimCheckAnnotation: tc.annotation},
+			}}
+			if tc.replay != "" {
+				b.Annotations[ReplayAnnotation] = tc.replay
+			}
+			err := b.Validate(context.Background())
+			if tc.wantErr == "" {
+				if err != nil {
+					t.Errorf("Validate() = %v, want nil", err)
+				}
+				return
+			}
+			if err == nil || err.Error() != tc.wantErr {
+				t.Errorf("Validate() = %v, want %v", err, tc.wantErr)
+			}
+		})
+	}
+}
@@ -0,0 +1,38 @@
+/*
+Copyright 2021 Google LLC
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package v1
+
+import (
+	"strconv"
+
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+)
+
+// DataPlaneObservedGenerationAnnotation is the annotation key set by the BrokerCell controller on
+// the Brokers and Triggers once all the data plane pods of their BrokerCell have loaded them. Its
+// value is the metadata.generation of the Broker or Trigger they loaded.
+const DataPlaneObservedGenerationAnnotation = "events.cloud.google.com/dataPlaneObservedGeneration"
+
+// DataPlaneObservedGeneration returns the generation of the Broker or Trigger loaded by the data
+// plane, or zero if the data plane hasn't loaded it yet.
+func DataPlaneObservedGeneration(obj metav1.Object) int64 {
+	generation, err := strconv.ParseInt(obj.GetAnnotations()[DataPlaneObservedGenerationAnnotation], 10, 64)
+	if err != nil {
+		return 0
+	}
+	return generation
+}
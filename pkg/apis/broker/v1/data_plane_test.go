@@ -0,0 +1,49 @@
+/*
+Copyright 2021 Google LLC
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package v1
+
+import (
+	"testing"
+
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+)
+
+func TestDataPlaneObservedGeneration(t *testing.T) {
+	for _, tc := range []struct {
+		name        string
+		annotations map[string]string
+		want        int64
+	}{{
+		name: "no annotation",
+		want: 0,
+	}, {
+		name:        "observed generation",
+		annotations: map[string]string{DataPlaneObservedGenerationAnnotation: "3"},
+		want:        3,
+	}, {
+		name:        "malformed annotation",
+		annotations: map[string]string{DataPlaneObservedGenerationAnnotation: "three"},
+		want:        0,
+	}} {
+		t.Run(tc.name, func(t *testing.T) {
+			trigger := &Trigger{ObjectMeta: metav1.ObjectMeta{Annotations: tc.annotations}}
+			if got := DataPlaneObservedGeneration(trigger); got != tc.want {
+				t.Errorf("DataPlaneObservedGeneration() = %d, want %d", got, tc.want)
+			}
+		})
+	}
+}
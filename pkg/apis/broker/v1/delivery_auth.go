@@ -0,0 +1,124 @@
+/*
+Copyright 2021 Google LLC
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package v1
+
+import (
+	"encoding/json"
+	"net/url"
+	"regexp"
+
+	"knative.dev/pkg/apis"
+)
+
+// DeliveryAuthAnnotation is the annotation key used to authenticate the deliveries to subscribers.
+// It can be set on a Broker, for all its Triggers, or on a Trigger, in which case it replaces the
+// one of the Broker. Its value is a JSON DeliveryAuth.
+const DeliveryAuthAnnotation = "events.cloud.google.com/deliveryAuth"
+
+// secretKeyPattern matches valid keys of Secrets.
+var secretKeyPattern = regexp.MustCompile(`^[-._a-zA-Z0-9]+$`)
+
+// DeliveryAuth sets how the deliveries to subscribers are authenticated. Exactly one of its
+// fields must be set.
+type DeliveryAuth struct {
+	// OIDC attaches a Google-signed ID token of the Google service account of the data plane.
+	// The token is also attached to the replies of the subscriber.
+	// +optional
+	OIDC *OIDCAuth `json:"oidc,omitempty"`
+
+	// BearerToken attaches a static bearer token read from a Secret.
+	// +optional
+	BearerToken *BearerTokenAuth `json:"bearerToken,omitempty"`
+}
+
+// OIDCAuth configures the ID tokens attached to deliveries.
+type OIDCAuth struct {
+	// Audience is the audience of the ID tokens. It must be a URL with the same scheme and host
+	// as the URI the event is delivered to, e.g. the root URL of a Cloud Run service. Defaults to
+	// the URI the event is delivered to.
+	// +optional
+	Audience string `json:"audience,omitempty"`
+}
+
+// BearerTokenAuth configures the static bearer token attached to deliveries.
+type BearerTokenAuth struct {
+	// Key is the key of the token in the broker-delivery-tokens Secret of the BrokerCell
+	// namespace. The key is prefixed by the namespace of the Trigger followed by a dot, so that
+	// a Trigger can only use the tokens of its own namespace. For example, the token of the key
+	// "subscriber" of a Trigger in the namespace "default" is stored in the key
+	// "default.subscriber".
+	Key string `json:"key"`
+}
+
+// GetDeliveryAuth returns the delivery authentication set in the DeliveryAuthAnnotation of the
+// Trigger, if any.
+func (t *Trigger) GetDeliveryAuth() (*DeliveryAuth, error) {
+	return getDeliveryAuth(t.GetAnnotations())
+}
+
+// GetDeliveryAuth returns the delivery authentication set in the DeliveryAuthAnnotation of the
+// Broker, if any.
+func (b *Broker) GetDeliveryAuth() (*DeliveryAuth, error) {
+	return getDeliveryAuth(b.GetAnnotations())
+}
+
+func getDeliveryAuth(annotations map[string]string) (*DeliveryAuth, error) {
+	v, ok := annotations[DeliveryAuthAnnotation]
+	if !ok {
+		return nil, nil
+	}
+	var a DeliveryAuth
+	if err := json.Unmarshal([]byte(v), &a); err != nil {
+		return nil, err
+	}
+	return &a, nil
+}
+
+// Validate checks that exactly one authentication mode is set.
+func (a *DeliveryAuth) Validate() *apis.FieldError {
+	switch {
+	case a.OIDC != nil && a.BearerToken != nil:
+		return apis.ErrMultipleOneOf("oidc", "bearerToken")
+	case a.OIDC != nil:
+		if a.OIDC.Audience != "" {
+			// The origin of the audience is checked by the data plane against the subscriber
+			// URI, which is only known once the Trigger is reconciled.
+			if u, err := url.Parse(a.OIDC.Audience); err != nil || !u.IsAbs() || u.Host == "" {
+				return apis.ErrInvalidValue(a.OIDC.Audience, "oidc.audience")
+			}
+		}
+		return nil
+	case a.BearerToken != nil:
+		if !secretKeyPattern.MatchString(a.BearerToken.Key) {
+			return apis.ErrInvalidValue(a.BearerToken.Key, "bearerToken.key")
+		}
+		return nil
+	default:
+		return apis.ErrMissingOneOf("oidc", "bearerToken")
+	}
+}
+
+func validateDeliveryAuth(annotations map[string]string) *apis.FieldError {
+	a, err := getDeliveryAuth(annotations)
+	if err != nil {
+		return apis.ErrInvalidValue(err.Error(), apis.CurrentField)
+	}
+	if a == nil {
+		return nil
+	}
+	return a.Validate()
+}
@@ -0,0 +1,83 @@
+/*
+Copyright 2021 Google LLC
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package v1
+
+import (
+	"context"
+	"testing"
+
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"knative.dev/pkg/apis"
+)
+
+func TestValidateDeliveryAuth(t *testing.T) {
+	cases := []struct {
+		name    string
+		auth    string
+		wantErr string
+	}{{
+		name: "oidc",
+		auth: `{"oidc":{}}`,
+	}, {
+		name: "oidc with audience",
+		auth: `{"oidc":{"audience":"https://subscriber.run.app"}}`,
+	}, {
+		name:    "oidc with relative audience",
+		auth:    `{"oidc":{"audience":"123.apps.googleusercontent.com"}}`,
+		wantErr: `invalid value: 123.apps.googleusercontent.com: metadata.annotations.[events.cloud.google.com/deliveryAuth].oidc.audience`,
+	}, {
+		name: "bearer token",
+		auth: `{"bearerToken":{"key":"subscriber-token"}}`,
+	}, {
+		name:    "not json",
+		auth:    `{`,
+		wantErr: `invalid value: unexpected end of JSON input: metadata.annotations.[events.cloud.google.com/deliveryAuth]`,
+	}, {
+		name:    "no mode",
+		auth:    `{}`,
+		wantErr: `expected exactly one, got neither: metadata.annotations.[events.cloud.google.com/deliveryAuth].bearerToken, metadata.annotations.[events.cloud.google.com/deliveryAuth].oidc`,
+	}, {
+		name:    "both modes",
+		auth:    `{"oidc":{},"bearerToken":{"key":"token"}}`,
+		wantErr: `expected exactly one, got both: metadata.annotations.[events.cloud.google.com/deliveryAuth].bearerToken, metadata.annotations.[events.cloud.google.com/deliveryAuth].oidc`,
+	}, {
+		name:    "invalid key",
+		auth:    `{"bearerToken":{"key":"a/b"}}`,
+		wantErr: `invalid value: a/b: metadata.annotations.[events.cloud.google.com/deliveryAuth].bearerToken.key`,
+	}}
+	for _, tc := range cases {
+		t.Run(tc.name, func(t *testing.T) {
+			meta := metav1.ObjectMeta{
+				Annotations: map[string]string{DeliveryAuthAnnotation: tc.auth},
+			}
+			for name, err := range map[string]*apis.FieldError{
+				"Trigger": (&Trigger{ObjectMeta: meta}).Validate(context.Background()),
+				"Broker":  (&Broker{ObjectMeta: meta}).Validate(context.Background()),
+			} {
+				if tc.wantErr == "" {
+					if err != nil {
+						t.Errorf("%s Validate() = %v, want nil", name, err)
+					}
+					continue
+				}
+				if err == nil || err.Error() != tc.wantErr {
+					t.Errorf("%s Validate() = %v, want %v", name, err, tc.wantErr)
+				}
+			}
+		})
+	}
+}
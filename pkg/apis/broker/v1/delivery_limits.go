@@ -0,0 +1,76 @@
+/*
+Copyright 2021 Google LLC
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package v1
+
+import (
+	"strconv"
+
+	"knative.dev/pkg/apis"
+)
+
+const (
+	// MaxConcurrentDeliveriesAnnotation is the annotation key used to limit the number of events
+	// delivered concurrently to the subscriber of a Trigger, by each data plane pod.
+	MaxConcurrentDeliveriesAnnotation = "events.cloud.google.com/maxConcurrentDeliveries"
+	// MaxEventsPerSecondAnnotation is the annotation key used to limit the number of events
+	// delivered each second to the subscriber of a Trigger, by each data plane pod.
+	MaxEventsPerSecondAnnotation = "events.cloud.google.com/maxEventsPerSecond"
+)
+
+// DeliveryLimits limits the deliveries to the subscriber of a Trigger. Zero means no limit.
+type DeliveryLimits struct {
+	MaxConcurrentDeliveries int32
+	MaxEventsPerSecond      int32
+}
+
+// GetDeliveryLimits returns the delivery limits set in the MaxConcurrentDeliveriesAnnotation and
+// the MaxEventsPerSecondAnnotation of the Trigger.
+func (t *Trigger) GetDeliveryLimits() (DeliveryLimits, error) {
+	var limits DeliveryLimits
+	var err error
+	if limits.MaxConcurrentDeliveries, err = getLimit(t.GetAnnotations(), MaxConcurrentDeliveriesAnnotation); err != nil {
+		return DeliveryLimits{}, err
+	}
+	if limits.MaxEventsPerSecond, err = getLimit(t.GetAnnotations(), MaxEventsPerSecondAnnotation); err != nil {
+		return DeliveryLimits{}, err
+	}
+	return limits, nil
+}
+
+func getLimit(annotations map[string]string, key string) (int32, error) {
+	v, ok := annotations[key]
+	if !ok {
+		return 0, nil
+	}
+	limit, err := strconv.ParseInt(v, 10, 32)
+	if err != nil {
+		return 0, err
+	}
+	return int32(limit), nil
+}
+
+func (t *Trigger) validateDeliveryLimits() *apis.FieldError {
+	var errs *apis.FieldError
+	for _, key := range []string{MaxConcurrentDeliveriesAnnotation, MaxEventsPerSecondAnnotation} {
+		if v, ok := t.GetAnnotations()[key]; ok {
+			if limit, err := strconv.ParseInt(v, 10, 32); err != nil || limit <= 0 {
+				errs = errs.Also(apis.ErrInvalidValue(v+" is not a positive integer", apis.CurrentField).ViaFieldKey("annotations", key))
+			}
+		}
+	}
+	return errs
+}
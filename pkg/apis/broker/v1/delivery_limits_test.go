@@ -0,0 +1,76 @@
+/*
+Copyright 2021 Google LLC
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package v1
+
+import (
+	"context"
+	"testing"
+
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+)
+
+func TestDeliveryLimits(t *testing.T) {
+	cases := []struct {
+		name        string
+		annotations map[string]string
+		want        DeliveryLimits
+		wantErr     string
+	}{{
+		name: "no limits",
+	}, {
+		name: "both limits",
+		annotations: map[string]string{
+			MaxConcurrentDeliveriesAnnotation: "10",
+			MaxEventsPerSecondAnnotation:      "100",
+		},
+		want: DeliveryLimits{MaxConcurrentDeliveries: 10, MaxEventsPerSecond: 100},
+	}, {
+		name:        "concurrency only",
+		annotations: map[string]string{MaxConcurrentDeliveriesAnnotation: "1"},
+		want:        DeliveryLimits{MaxConcurrentDeliveries: 1},
+	}, {
+		name:        "not a number",
+		annotations: map[string]string{MaxEventsPerSecondAnnotation: "fast"},
+		wantErr:     "invalid value: fast is not a positive integer: metadata.annotations.[events.cloud.google.com/maxEventsPerSecond]",
+	}, {
+		name:        "zero",
+		annotations: map[string]string{MaxConcurrentDeliveriesAnnotation: "0"},
+		wantErr:     "invalid value: 0 is not a positive integer: metadata.annotations.[events.cloud.google.com/maxConcurrentDeliveries]",
+	}}
+	for _, tc := range cases {
+		t.Run(tc.name, func(t *testing.T) {
+			trigger := &Trigger{ObjectMeta: metav1.ObjectMeta{Annotations: tc.annotations}}
+			err := trigger.Validate(context.Background())
+			if tc.wantErr != "" {
+				if err == nil || err.Error() != tc.wantErr {
+					t.Errorf("Validate() = %v, want %v", err, tc.wantErr)
+				}
+				return
+			}
+			if err != nil {
+				t.Errorf("Validate() = %v, want nil", err)
+			}
+			got, gErr := trigger.GetDeliveryLimits()
+			if gErr != nil {
+				t.Fatalf("GetDeliveryLimits() error = %v", gErr)
+			}
+			if got != tc.want {
+				t.Errorf("GetDeliveryLimits() = %+v, want %+v", got, tc.want)
+			}
+		})
+	}
+}
@@ -0,0 +1,82 @@
+/*
+Copyright 2021 Google LLC
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package v1
+
+import (
+	"encoding/json"
+	"strings"
+
+	"knative.dev/pkg/apis"
+)
+
+// IngressAuthAnnotation is the annotation key used to authenticate the events sent to a Broker.
+// Its value is a JSON IngressAuth. Without it, the Broker accepts events from any caller.
+const IngressAuthAnnotation = "events.cloud.google.com/ingressAuth"
+
+// IngressAuth restricts the callers allowed to send events to a Broker. The callers authenticate
+// with a bearer token in the Authorization header: either a Kubernetes projected service account
+// token, or a Google-signed ID token.
+type IngressAuth struct {
+	// AllowedIdentities are the identities allowed to send events to the Broker. A Kubernetes
+	// service account is identified as "system:serviceaccount:<namespace>:<name>", a Google
+	// service account by its email.
+	AllowedIdentities []string `json:"allowedIdentities"`
+
+	// Audience is the audience the tokens must be issued for. Defaults to the address of the
+	// Broker.
+	// +optional
+	Audience string `json:"audience,omitempty"`
+}
+
+// GetIngressAuth returns the ingress authentication set in the IngressAuthAnnotation of the
+// Broker, if any.
+func (b *Broker) GetIngressAuth() (*IngressAuth, error) {
+	v, ok := b.GetAnnotations()[IngressAuthAnnotation]
+	if !ok {
+		return nil, nil
+	}
+	var a IngressAuth
+	if err := json.Unmarshal([]byte(v), &a); err != nil {
+		return nil, err
+	}
+	return &a, nil
+}
+
+// Validate checks that at least one identity is allowed and that none is empty.
+func (a *IngressAuth) Validate() *apis.FieldError {
+	if len(a.AllowedIdentities) == 0 {
+		return apis.ErrMissingField("allowedIdentities")
+	}
+	var errs *apis.FieldError
+	for i, id := range a.AllowedIdentities {
+		if strings.TrimSpace(id) == "" {
+			errs = errs.Also(apis.ErrInvalidArrayValue(id, "allowedIdentities", i))
+		}
+	}
+	return errs
+}
+
+func validateIngressAuth(b *Broker) *apis.FieldError {
+	a, err := b.GetIngressAuth()
+	if err != nil {
+		return apis.ErrInvalidValue(err.Error(), apis.CurrentField)
+	}
+	if a == nil {
+		return nil
+	}
+	return a.Validate()
+}
@@ -0,0 +1,86 @@
+/*
+Copyright 2021 Google LLC
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package v1
+
+import (
+	"context"
+	"testing"
+
+	"github.com/google/go-cmp/cmp"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+)
+
+func TestGetIngressAuth(t *testing.T) {
+	b := &Broker{ObjectMeta: metav1.ObjectMeta{Annotations: map[string]string{
+		IngressAuthAnnotation: `{"allowedIdentities":["system:serviceaccount:ns:sender"],"audience":"broker"}`,
+	}}}
+	got, err := b.GetIngressAuth()
+	if err != nil {
+		t.Fatalf("GetIngressAuth() error = %v", err)
+	}
+	want := &IngressAuth{AllowedIdentities: []string{"system:serviceaccount:ns:sender"}, Audience: "broker"}
+	if diff := cmp.Diff(want, got); diff != "" {
+		t.Errorf("GetIngressAuth() (-want,+got): %v", diff)
+	}
+
+	if got, err := (&Broker{}).GetIngressAuth(); got != nil || err != nil {
+		t.Errorf("GetIngressAuth() without annotation = %v, %v, want nil, nil", got, err)
+	}
+}
+
+func TestValidateIngressAuth(t *testing.T) {
+	cases := []struct {
+		name    string
+		auth    string
+		wantErr string
+	}{{
+		name: "kubernetes and google service accounts",
+		auth: `{"allowedIdentities":["system:serviceaccount:ns:sender","sender@project.iam.gserviceaccount.com"]}`,
+	}, {
+		name: "custom audience",
+		auth: `{"allowedIdentities":["system:serviceaccount:ns:sender"],"audience":"broker"}`,
+	}, {
+		name:    "not json",
+		auth:    `{`,
+		wantErr: `invalid value: unexpected end of JSON input: metadata.annotations.[events.cloud.google.com/ingressAuth]`,
+	}, {
+		name:    "no identity",
+		auth:    `{"allowedIdentities":[]}`,
+		wantErr: `missing field(s): metadata.annotations.[events.cloud.google.com/ingressAuth].allowedIdentities`,
+	}, {
+		name:    "empty identity",
+		auth:    `{"allowedIdentities":["system:serviceaccount:ns:sender"," "]}`,
+		wantErr: `invalid value:  : metadata.annotations.[events.cloud.google.com/ingressAuth].allowedIdentities[1]`,
+	}}
+	for _, tc := range cases {
+		t.Run(tc.name, func(t *testing.T) {
+			b := &Broker{ObjectMeta: metav1.ObjectMeta{
+				Annotations: map[string]string{IngressAuthAnnotation: tc.auth},
+			}}
+			err := b.Validate(context.Background())
+			if tc.wantErr == "" {
+				if err != nil {
+					t.Errorf("Validate() = %v, want nil", err)
+				}
+				return
+			}
+			if err == nil || err.Error() != tc.wantErr {
+				t.Errorf("Validate() = %v, want %v", err, tc.wantErr)
+			}
+		})
+	}
+}
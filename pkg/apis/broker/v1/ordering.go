@@ -0,0 +1,83 @@
+/*
+Copyright 2021 Google LLC
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package v1
+
+import (
+	"encoding/json"
+	"regexp"
+
+	"knative.dev/pkg/apis"
+)
+
+const (
+	// OrderingAnnotation is the annotation key used to deliver the events sent to a Broker in
+	// order for each value of a CloudEvent extension. Its value is a JSON Ordering. Message
+	// ordering can only be enabled on the Pub/Sub subscriptions when they are created, so the
+	// annotation should be set when the Broker is created.
+	OrderingAnnotation = "events.cloud.google.com/ordering"
+
+	// DefaultOrderingKeyExtension is the default extension holding the ordering key of the events.
+	DefaultOrderingKeyExtension = "partitionkey"
+)
+
+// extensionName matches the valid names of CloudEvent attributes.
+var extensionName = regexp.MustCompile(`^[a-z0-9]+$`)
+
+// Ordering delivers the events with the same ordering key to each Trigger in the order they were
+// accepted by the Broker. The events without an ordering key are not ordered.
+type Ordering struct {
+	// KeyExtension is the CloudEvent extension holding the ordering key of the events. Defaults
+	// to partitionkey.
+	// +optional
+	KeyExtension string `json:"keyExtension,omitempty"`
+}
+
+// GetOrdering returns the ordering set in the OrderingAnnotation of the Broker, if any, with its
+// defaults.
+func (b *Broker) GetOrdering() (*Ordering, error) {
+	v, ok := b.GetAnnotations()[OrderingAnnotation]
+	if !ok {
+		return nil, nil
+	}
+	var o Ordering
+	if err := json.Unmarshal([]byte(v), &o); err != nil {
+		return nil, err
+	}
+	if o.KeyExtension == "" {
+		o.KeyExtension = DefaultOrderingKeyExtension
+	}
+	return &o, nil
+}
+
+// Validate checks that the key extension is a valid CloudEvent attribute name.
+func (o *Ordering) Validate() *apis.FieldError {
+	if !extensionName.MatchString(o.KeyExtension) {
+		return apis.ErrInvalidValue(o.KeyExtension, "keyExtension")
+	}
+	return nil
+}
+
+func validateOrdering(b *Broker) *apis.FieldError {
+	o, err := b.GetOrdering()
+	if err != nil {
+		return apis.ErrInvalidValue(err.Error(), apis.CurrentField)
+	}
+	if o == nil {
+		return nil
+	}
+	return o.Validate()
+}
@@ -0,0 +1,94 @@
+/*
+Copyright 2021 Google LLC
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package v1
+
+import (
+	"context"
+	"testing"
+
+	"github.com/google/go-cmp/cmp"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+)
+
+func TestGetOrdering(t *testing.T) {
+	cases := []struct {
+		name       string
+		annotation string
+		want       *Ordering
+	}{{
+		name: "no ordering",
+	}, {
+		name:       "default key extension",
+		annotation: `{}`,
+		want:       &Ordering{KeyExtension: DefaultOrderingKeyExtension},
+	}, {
+		name:       "custom key extension",
+		annotation: `{"keyExtension":"subject"}`,
+		want:       &Ordering{KeyExtension: "subject"},
+	}}
+	for _, tc := range cases {
+		t.Run(tc.name, func(t *testing.T) {
+			b := &Broker{}
+			if tc.annotation != "" {
+				b.SetAnnotations(map[string]string{OrderingAnnotation: tc.annotation})
+			}
+			got, err := b.GetOrdering()
+			if err != nil {
+				t.Fatalf("GetOrdering() error = %v", err)
+			}
+			if diff := cmp.Diff(tc.want, got); diff != "" {
+				t.Errorf("GetOrdering() (-want,+got): %v", diff)
+			}
+		})
+	}
+}
+
+func TestValidateOrdering(t *testing.T) {
+	cases := []struct {
+		name       string
+		annotation string
+		wantErr    string
+	}{{
+		name:       "valid",
+		annotation: `{"keyExtension":"orderkey"}`,
+	}, {
+		name:       "not json",
+		annotation: `{`,
+		wantErr:    `invalid value: unexpected end of JSON input: metadata.annotations.[events.cloud.google.com/ordering]`,
+	}, {
+		name:       "invalid key extension",
+		annotation: `{"keyExtension":"Order-Key"}`,
+		wantErr:    `invalid value: Order-Key: metadata.annotations.[events.cloud.google.com/ordering].keyExtension`,
+	}}
+	for _, tc := range cases {
+		t.Run(tc.name, func(t *testing.T) {
+			b := &Broker{ObjectMeta: metav1.ObjectMeta{
+				Annotations: map[string]string{OrderingAnnotation: tc.annotation},
+			}}
+			err := b.Validate(context.Background())
+			if tc.wantErr == "" {
+				if err != nil {
+					t.Errorf("Validate() = %v, want nil", err)
+				}
+				return
+			}
+			if err == nil || err.Error() != tc.wantErr {
+				t.Errorf("Validate() = %v, want %v", err, tc.wantErr)
+			}
+		})
+	}
+}
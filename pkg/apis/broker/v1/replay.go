@@ -0,0 +1,229 @@
+/*
+Copyright 2021 Google LLC
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package v1
+
+import (
+	"encoding/json"
+	"time"
+
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"knative.dev/pkg/apis"
+	duckv1 "knative.dev/pkg/apis/duck/v1"
+)
+
+const (
+	// ReplayAnnotation is the annotation key used to keep the acknowledged events of a Broker or a
+	// Trigger, and to replay them from a point in time or from a Pub/Sub snapshot. Its value is a
+	// JSON Replay. A Broker replays the events to all its Triggers through its decoupling
+	// subscription. A Trigger replays the events of its Broker to its subscriber only, through a
+	// replay subscription of the decoupling topic of the Broker that exists while the annotation
+	// is set. The replay subscription only holds the events published after it was created, so it
+	// can't be seeked to an earlier time, only to a snapshot of the decoupling subscription.
+	ReplayAnnotation = "events.cloud.google.com/replay"
+	// LastReplayStatusAnnotation is the status annotation key holding the last replay applied to
+	// the subscription of a Broker or a Trigger, as a JSON Replay without its retention.
+	LastReplayStatusAnnotation = "events.cloud.google.com/lastReplay"
+	// LastReplayTimeStatusAnnotation is the status annotation key holding the time the last
+	// replay was applied to the replay subscription of a Trigger, in RFC 3339 format. Only the
+	// events published before this time are replayed to the Trigger, since the later events are
+	// delivered by the decoupling subscription of the Broker.
+	LastReplayTimeStatusAnnotation = "events.cloud.google.com/lastReplayTime"
+	// ReplaySubscriptionStatusAnnotation is the status annotation key holding the ID of the
+	// replay subscription of a Trigger while it may exist, so that it is only deleted if it was
+	// created.
+	ReplaySubscriptionStatusAnnotation = "events.cloud.google.com/replaySubscription"
+
+	// DefaultReplayRetention is the default retention of the acknowledged events, the longest
+	// supported by Pub/Sub.
+	DefaultReplayRetention = 7 * 24 * time.Hour
+	// MinReplayRetention is the shortest retention of the acknowledged events supported by
+	// Pub/Sub.
+	MinReplayRetention = 10 * time.Minute
+)
+
+// Replay keeps the acknowledged events in the Pub/Sub subscriptions, so that they can be replayed
+// from a point in time or from a snapshot. A replay is applied once, when the Time, Snapshot or ID
+// changes.
+type Replay struct {
+	// Retention is how long the acknowledged events are kept, between 10m and 168h. Defaults to
+	// 168h.
+	// +optional
+	Retention string `json:"retention,omitempty"`
+
+	// ID identifies the replay, so that the same point can be replayed again by changing it.
+	// +optional
+	ID string `json:"id,omitempty"`
+
+	// Time replays the events accepted since this time.
+	// +optional
+	Time *metav1.Time `json:"time,omitempty"`
+
+	// Snapshot replays the events from the Pub/Sub snapshot with this ID, in the project of the
+	// subscription.
+	// +optional
+	Snapshot string `json:"snapshot,omitempty"`
+}
+
+// GetReplay returns the replay set in the ReplayAnnotation of the Broker, if any, with its
+// defaults.
+func (b *Broker) GetReplay() (*Replay, error) {
+	return getReplay(b.GetAnnotations())
+}
+
+// GetReplay returns the replay set in the ReplayAnnotation of the Trigger, if any, with its
+// defaults.
+func (t *Trigger) GetReplay() (*Replay, error) {
+	return getReplay(t.GetAnnotations())
+}
+
+func getReplay(annotations map[string]string) (*Replay, error) {
+	v, ok := annotations[ReplayAnnotation]
+	if !ok {
+		return nil, nil
+	}
+	var r Replay
+	if err := json.Unmarshal([]byte(v), &r); err != nil {
+		return nil, err
+	}
+	if r.Retention == "" {
+		r.Retention = DefaultReplayRetention.String()
+	}
+	return &r, nil
+}
+
+// RetentionDuration returns the parsed retention of the acknowledged events.
+func (r *Replay) RetentionDuration() (time.Duration, error) {
+	return time.ParseDuration(r.Retention)
+}
+
+// Seeks returns true if the replay seeks the subscription to a time or a snapshot.
+func (r *Replay) Seeks() bool {
+	return r.Time != nil || r.Snapshot != ""
+}
+
+// Applied returns the value of the LastReplayStatusAnnotation once the replay is applied.
+func (r *Replay) Applied() string {
+	b, _ := json.Marshal(Replay{ID: r.ID, Time: r.Time, Snapshot: r.Snapshot})
+	return string(b)
+}
+
+// Validate checks that the retention is supported by Pub/Sub and that at most one of the time and
+// the snapshot is set.
+func (r *Replay) Validate() *apis.FieldError {
+	var errs *apis.FieldError
+	retention, err := r.RetentionDuration()
+	if err != nil {
+		errs = errs.Also(apis.ErrInvalidValue(r.Retention, "retention"))
+	} else if retention < MinReplayRetention || retention > DefaultReplayRetention {
+		errs = errs.Also(apis.ErrOutOfBoundsValue(r.Retention, MinReplayRetention, DefaultReplayRetention, "retention"))
+	}
+	if r.Time != nil && r.Snapshot != "" {
+		errs = errs.Also(apis.ErrMultipleOneOf("time", "snapshot"))
+	}
+	return errs
+}
+
+func validateReplay(annotations map[string]string) *apis.FieldError {
+	r, err := getReplay(annotations)
+	if err != nil {
+		return apis.ErrInvalidValue(err.Error(), apis.CurrentField)
+	}
+	if r == nil {
+		return nil
+	}
+	return r.Validate()
+}
+
+// GetLastReplay returns the last replay applied to the decoupling subscription of the Broker.
+func (bs *BrokerStatus) GetLastReplay() string {
+	return getLastReplay(&bs.Status)
+}
+
+// SetLastReplay records the last replay applied to the decoupling subscription of the Broker.
+func (bs *BrokerStatus) SetLastReplay(applied string) {
+	setLastReplay(&bs.Status, applied)
+}
+
+// GetLastReplay returns the last replay applied to the replay subscription of the Trigger.
+func (ts *TriggerStatus) GetLastReplay() string {
+	return getLastReplay(&ts.Status)
+}
+
+// SetLastReplay records the last replay applied to the replay subscription of the Trigger. An
+// empty replay removes the last replay, along with the time it was applied.
+func (ts *TriggerStatus) SetLastReplay(applied string) {
+	setLastReplay(&ts.Status, applied)
+	if applied == "" {
+		delete(ts.Annotations, LastReplayTimeStatusAnnotation)
+	}
+}
+
+// GetLastReplayTime returns the time the last replay was applied to the replay subscription of
+// the Trigger, if any.
+func (ts *TriggerStatus) GetLastReplayTime() (time.Time, bool) {
+	v, ok := ts.Annotations[LastReplayTimeStatusAnnotation]
+	if !ok {
+		return time.Time{}, false
+	}
+	t, err := time.Parse(time.RFC3339Nano, v)
+	if err != nil {
+		return time.Time{}, false
+	}
+	return t, true
+}
+
+// SetLastReplayTime records the time the last replay was applied to the replay subscription of
+// the Trigger.
+func (ts *TriggerStatus) SetLastReplayTime(t time.Time) {
+	if ts.Annotations == nil {
+		ts.Annotations = make(map[string]string, 1)
+	}
+	ts.Annotations[LastReplayTimeStatusAnnotation] = t.UTC().Format(time.RFC3339Nano)
+}
+
+// GetReplaySubscription returns the ID of the replay subscription of the Trigger, if it may exist.
+func (ts *TriggerStatus) GetReplaySubscription() string {
+	return ts.Annotations[ReplaySubscriptionStatusAnnotation]
+}
+
+// SetReplaySubscription records the ID of the replay subscription of the Trigger before it is
+// created. An empty ID records that it was deleted.
+func (ts *TriggerStatus) SetReplaySubscription(id string) {
+	if id == "" {
+		delete(ts.Annotations, ReplaySubscriptionStatusAnnotation)
+		return
+	}
+	if ts.Annotations == nil {
+		ts.Annotations = make(map[string]string, 1)
+	}
+	ts.Annotations[ReplaySubscriptionStatusAnnotation] = id
+}
+
+func getLastReplay(s *duckv1.Status) string {
+	return s.Annotations[LastReplayStatusAnnotation]
+}
+
+func setLastReplay(s *duckv1.Status, applied string) {
+	if applied == "" {
+		delete(s.Annotations, LastReplayStatusAnnotation)
+		return
+	}
+	if s.Annotations == nil {
+		s.Annotations = make(map[string]string, 1)
+	}
+	s.Annotations[LastReplayStatusAnnotation] = applied
+}
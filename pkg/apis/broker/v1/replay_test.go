@@ -0,0 +1,163 @@
+/*
+Copyright 2021 Google LLC
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package v1
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/google/go-cmp/cmp"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"knative.dev/pkg/apis"
+)
+
+func TestGetReplay(t *testing.T) {
+	replayTime := metav1.NewTime(time.Date(2021, 5, 1, 0, 0, 0, 0, time.UTC))
+	cases := []struct {
+		name       string
+		annotation string
+		want       *Replay
+	}{{
+		name: "no replay",
+	}, {
+		name:       "default retention",
+		annotation: `{}`,
+		want:       &Replay{Retention: "168h0m0s"},
+	}, {
+		name:       "replay from time",
+		annotation: `{"retention":"24h","id":"fix-1","time":"2021-05-01T00:00:00Z"}`,
+		want:       &Replay{Retention: "24h", ID: "fix-1", Time: &replayTime},
+	}, {
+		name:       "replay from snapshot",
+		annotation: `{"snapshot":"before-deploy"}`,
+		want:       &Replay{Retention: "168h0m0s", Snapshot: "before-deploy"},
+	}}
+	for _, tc := range cases {
+		t.Run(tc.name, func(t *testing.T) {
+			annotations := map[string]string{}
+			if tc.annotation != "" {
+				annotations[ReplayAnnotation] = tc.annotation
+			}
+			b := &Broker{ObjectMeta: metav1.ObjectMeta{Annotations: annotations}}
+			got, err := b.GetReplay()
+			if err != nil {
+				t.Fatalf("Broker.GetReplay() error = %v", err)
+			}
+			if diff := cmp.Diff(tc.want, got); diff != "" {
+				t.Errorf("Broker.GetReplay() (-want,+got): %v", diff)
+			}
+			tr := &Trigger{ObjectMeta: metav1.ObjectMeta{Annotations: annotations}}
+			got, err = tr.GetReplay()
+			if err != nil {
+				t.Fatalf("Trigger.GetReplay() error = %v", err)
+			}
+			if diff := cmp.Diff(tc.want, got); diff != "" {
+				t.Errorf("Trigger.GetReplay() (-want,+got): %v", diff)
+			}
+		})
+	}
+}
+
+func TestReplayApplied(t *testing.T) {
+	replayTime := metav1.NewTime(time.Date(2021, 5, 1, 0, 0, 0, 0, time.UTC))
+	r := &Replay{Retention: "24h", ID: "fix-1", Time: &replayTime}
+	if got, want := r.Applied(), `{"id":"fix-1","time":"2021-05-01T00:00:00Z"}`; got != want {
+		t.Errorf("Applied() got=%s, want=%s", got, want)
+	}
+
+	bs := &BrokerStatus{}
+	if got := bs.GetLastReplay(); got != "" {
+		t.Errorf("GetLastReplay() of a new status got=%q, want none", got)
+	}
+	bs.SetLastReplay(r.Applied())
+	if got := bs.Annotations[LastReplayStatusAnnotation]; got != r.Applied() {
+		t.Errorf("status annotation got=%q, want=%q", got, r.Applied())
+	}
+}
+
+func TestTriggerLastReplayTime(t *testing.T) {
+	ts := &TriggerStatus{}
+	if _, ok := ts.GetLastReplayTime(); ok {
+		t.Error("GetLastReplayTime() of a new status got a time, want none")
+	}
+	applied := time.Date(2021, 5, 2, 10, 30, 0, 500, time.UTC)
+	ts.SetLastReplay(`{"time":"2021-05-01T00:00:00Z"}`)
+	ts.SetLastReplayTime(applied)
+	if got, ok := ts.GetLastReplayTime(); !ok || !got.Equal(applied) {
+		t.Errorf("GetLastReplayTime() got=%v, %t, want=%v, true", got, ok, applied)
+	}
+
+	ts.SetLastReplay("")
+	if got := ts.GetLastReplay(); got != "" {
+		t.Errorf("GetLastReplay() of a removed replay got=%q, want none", got)
+	}
+	if _, ok := ts.GetLastReplayTime(); ok {
+		t.Error("GetLastReplayTime() of a removed replay got a time, want none")
+	}
+}
+
+func TestValidateReplay(t *testing.T) {
+	cases := []struct {
+		name       string
+		annotation string
+		wantErr    string
+	}{{
+		name:       "valid time",
+		annotation: `{"retention":"72h","time":"2021-05-01T00:00:00Z"}`,
+	}, {
+		name:       "valid snapshot",
+		annotation: `{"snapshot":"before-deploy"}`,
+	}, {
+		name:       "not json",
+		annotation: `{`,
+		wantErr:    `invalid value: unexpected end of JSON input: metadata.annotations.[events.cloud.google.com/replay]`,
+	}, {
+		name:       "invalid retention",
+		annotation: `{"retention":"a week"}`,
+		wantErr:    `invalid value: a week: metadata.annotations.[events.cloud.google.com/replay].retention`,
+	}, {
+		name:       "retention too long",
+		annotation: `{"retention":"240h"}`,
+		wantErr:    `expected 10m0s <= 240h <= 168h0m0s: metadata.annotations.[events.cloud.google.com/replay].retention`,
+	}, {
+		name:       "time and snapshot",
+		annotation: `{"time":"2021-05-01T00:00:00Z","snapshot":"before-deploy"}`,
+		wantErr:    `expected exactly one, got both: metadata.annotations.[events.cloud.google.com/replay].snapshot, metadata.annotations.[events.cloud.google.com/replay].time`,
+	}}
+	for _, tc := range cases {
+		t.Run(tc.name, func(t *testing.T) {
+			annotations := map[string]string{ReplayAnnotation: tc.annotation}
+			b := &Broker{ObjectMeta: metav1.ObjectMeta{Annotations: annotations}}
+			tr := &Trigger{ObjectMeta: metav1.ObjectMeta{Annotations: annotations}}
+			for kind, err := range map[string]*apis.FieldError{
+				"Broker":  b.Validate(context.Background()),
+				"Trigger": tr.Validate(context.Background()),
+			} {
+				if tc.wantErr == "" {
+					if err != nil {
+						t.Errorf("%s.Validate() = %v, want nil", kind, err)
+					}
+					continue
+				}
+				if err == nil || err.Error() != tc.wantErr {
+					t.Errorf("%s.Validate() = %v, want %v", kind, err, tc.wantErr)
+				}
+			}
+		})
+	}
+}
@@ -0,0 +1,134 @@
+/*
+Copyright 2021 Google LLC
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package v1
+
+import (
+	"encoding/json"
+	"fmt"
+	"regexp"
+	"strconv"
+
+	"knative.dev/pkg/apis"
+)
+
+// ResponseClassificationAnnotation is the annotation key used to set how the responses of
+// subscribers are handled. It can be set on a Broker, for all its Triggers, or on a Trigger, in
+// which case it replaces the one of the Broker. Its value is a JSON ResponseClassification.
+const ResponseClassificationAnnotation = "events.cloud.google.com/responseClassification"
+
+// statusCodePattern matches a status code, e.g. "429", or a class of status codes, e.g. "4xx".
+var statusCodePattern = regexp.MustCompile(`^[1-5]([0-9]{2}|xx)$`)
+
+// ResponseClassification classifies the non 2xx responses of subscribers. Each list holds either
+// status codes, e.g. "429", or classes of status codes, e.g. "4xx". A status code that is both
+// listed and part of a listed class is classified by the status code. The responses that are
+// not classified are retried.
+type ResponseClassification struct {
+	// Success lists the responses that are treated as successful deliveries. Their body is never
+	// treated as a reply.
+	// +optional
+	Success []string `json:"success,omitempty"`
+
+	// DeadLetter lists the responses that are not retried. The event is sent to the dead letter
+	// sink right away. Responses are still retried when the dead letter sink is not handled by
+	// the data plane, so that the Pub/Sub dead letter policy moves the event to a topic sink.
+	// +optional
+	DeadLetter []string `json:"deadLetter,omitempty"`
+
+	// Retry lists the responses that are retried, e.g. to retry some of the status codes of a
+	// class listed in DeadLetter.
+	// +optional
+	Retry []string `json:"retry,omitempty"`
+}
+
+// GetResponseClassification returns the response classification set in the
+// ResponseClassificationAnnotation of the Trigger, if any.
+func (t *Trigger) GetResponseClassification() (*ResponseClassification, error) {
+	return getResponseClassification(t.GetAnnotations())
+}
+
+// GetResponseClassification returns the response classification set in the
+// ResponseClassificationAnnotation of the Broker, if any.
+func (b *Broker) GetResponseClassification() (*ResponseClassification, error) {
+	return getResponseClassification(b.GetAnnotations())
+}
+
+func getResponseClassification(annotations map[string]string) (*ResponseClassification, error) {
+	v, ok := annotations[ResponseClassificationAnnotation]
+	if !ok {
+		return nil, nil
+	}
+	var c ResponseClassification
+	if err := json.Unmarshal([]byte(v), &c); err != nil {
+		return nil, err
+	}
+	return &c, nil
+}
+
+// ParseStatusCodes returns the range of status codes matching a status code or a class of status
+// codes of a ResponseClassification.
+func ParseStatusCodes(s string) (min, max int, err error) {
+	if !statusCodePattern.MatchString(s) {
+		return 0, 0, fmt.Errorf("%q is neither a status code nor a class of status codes", s)
+	}
+	if s[1:] == "xx" {
+		min = int(s[0]-'0') * 100
+		return min, min + 99, nil
+	}
+	code, _ := strconv.Atoi(s)
+	return code, code, nil
+}
+
+// Validate checks that the status codes are well formed, are not successful ones, and are not
+// listed twice.
+func (c *ResponseClassification) Validate() *apis.FieldError {
+	var errs *apis.FieldError
+	seen := make(map[string]bool)
+	for _, l := range []struct {
+		field string
+		codes []string
+	}{
+		{"success", c.Success},
+		{"deadLetter", c.DeadLetter},
+		{"retry", c.Retry},
+	} {
+		for i, s := range l.codes {
+			min, _, err := ParseStatusCodes(s)
+			switch {
+			case err != nil:
+				errs = errs.Also(apis.ErrInvalidArrayValue(s, l.field, i))
+			case min/100 == 2:
+				errs = errs.Also(apis.ErrGeneric("2xx responses are always successful", apis.CurrentField).ViaFieldIndex(l.field, i))
+			case seen[s]:
+				errs = errs.Also(apis.ErrGeneric(fmt.Sprintf("%q is classified more than once", s), apis.CurrentField).ViaFieldIndex(l.field, i))
+			}
+			seen[s] = true
+		}
+	}
+	return errs
+}
+
+func validateResponseClassification(annotations map[string]string) *apis.FieldError {
+	c, err := getResponseClassification(annotations)
+	if err != nil {
+		return apis.ErrInvalidValue(err.Error(), apis.CurrentField)
+	}
+	if c == nil {
+		return nil
+	}
+	return c.Validate()
+}
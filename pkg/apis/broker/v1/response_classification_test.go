@@ -0,0 +1,101 @@
+/*
+Copyright 2021 Google LLC
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package v1
+
+import (
+	"context"
+	"testing"
+
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"knative.dev/pkg/apis"
+)
+
+func TestParseStatusCodes(t *testing.T) {
+	cases := []struct {
+		codes   string
+		wantMin int
+		wantMax int
+		wantErr bool
+	}{
+		{codes: "429", wantMin: 429, wantMax: 429},
+		{codes: "4xx", wantMin: 400, wantMax: 499},
+		{codes: "5xx", wantMin: 500, wantMax: 599},
+		{codes: "600", wantErr: true},
+		{codes: "4x9", wantErr: true},
+		{codes: "40", wantErr: true},
+		{codes: "", wantErr: true},
+	}
+	for _, tc := range cases {
+		t.Run(tc.codes, func(t *testing.T) {
+			min, max, err := ParseStatusCodes(tc.codes)
+			if (err != nil) != tc.wantErr {
+				t.Fatalf("ParseStatusCodes() error = %v, wantErr %v", err, tc.wantErr)
+			}
+			if min != tc.wantMin || max != tc.wantMax {
+				t.Errorf("ParseStatusCodes() = (%d, %d), want (%d, %d)", min, max, tc.wantMin, tc.wantMax)
+			}
+		})
+	}
+}
+
+func TestValidateResponseClassification(t *testing.T) {
+	cases := []struct {
+		name           string
+		classification string
+		wantErr        string
+	}{{
+		name:           "valid classification",
+		classification: `{"success":["409"],"deadLetter":["4xx"],"retry":["408","429"]}`,
+	}, {
+		name:           "not json",
+		classification: `[`,
+		wantErr:        `invalid value: unexpected end of JSON input: metadata.annotations.[events.cloud.google.com/responseClassification]`,
+	}, {
+		name:           "invalid status code",
+		classification: `{"deadLetter":["4xx","abc"]}`,
+		wantErr:        `invalid value: abc: metadata.annotations.[events.cloud.google.com/responseClassification].deadLetter[1]`,
+	}, {
+		name:           "successful status code",
+		classification: `{"retry":["2xx"]}`,
+		wantErr:        `2xx responses are always successful: metadata.annotations.[events.cloud.google.com/responseClassification].retry[0]`,
+	}, {
+		name:           "status code classified twice",
+		classification: `{"success":["404"],"deadLetter":["404"]}`,
+		wantErr:        `"404" is classified more than once: metadata.annotations.[events.cloud.google.com/responseClassification].deadLetter[0]`,
+	}}
+	for _, tc := range cases {
+		t.Run(tc.name, func(t *testing.T) {
+			meta := metav1.ObjectMeta{
+				Annotations: map[string]string{ResponseClassificationAnnotation: tc.classification},
+			}
+			for name, err := range map[string]*apis.FieldError{
+				"Trigger": (&Trigger{ObjectMeta: meta}).Validate(context.Background()),
+				"Broker":  (&Broker{ObjectMeta: meta}).Validate(context.Background()),
+			} {
+				if tc.wantErr == "" {
+					if err != nil {
+						t.Errorf("%s Validate() = %v, want nil", name, err)
+					}
+					continue
+				}
+				if err == nil || err.Error() != tc.wantErr {
+					t.Errorf("%s Validate() = %v, want %v", name, err, tc.wantErr)
+				}
+			}
+		})
+	}
+}
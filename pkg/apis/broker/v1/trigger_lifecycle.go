@@ -34,6 +34,10 @@ var triggerCondSet = apis.NewLivingConditionSet(
 const (
 	TriggerConditionTopic        apis.ConditionType = "TopicReady"
 	TriggerConditionSubscription apis.ConditionType = "SubscriptionReady"
+	// TriggerConditionDataPlane reports whether the data plane has loaded the current generation
+	// of the Trigger. It doesn't affect the readiness of the Trigger, since the data plane only
+	// loads the Trigger as ready once it is ready.
+	TriggerConditionDataPlane apis.ConditionType = "DataPlaneReady"
 )
 
 // GetCondition returns the condition currently associated with the given type, or nil.
@@ -159,3 +163,14 @@ func (ts *TriggerStatus) PropagateDependencyStatus(src *duckv1.Source) {
 		ts.MarkDependencyUnknown("DependencyUnknown", "The status of Dependency is invalid: %v", sc.Status)
 	}
 }
+
+// PropagateDataPlaneObservedGeneration marks the data plane ready once it has loaded the given
+// generation of the Trigger.
+func (ts *TriggerStatus) PropagateDataPlaneObservedGeneration(observed, generation int64) {
+	if observed >= generation {
+		triggerCondSet.Manage(ts).MarkTrue(TriggerConditionDataPlane)
+		return
+	}
+	triggerCondSet.Manage(ts).MarkUnknown(TriggerConditionDataPlane, "DataPlaneNotObserved",
+		"The data plane has not loaded generation %d of the Trigger yet", generation)
+}
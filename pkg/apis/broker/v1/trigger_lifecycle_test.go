@@ -428,3 +428,20 @@ func TestTriggerConditionStatus(t *testing.T) {
 		})
 	}
 }
+
+func TestTriggerPropagateDataPlaneObservedGeneration(t *testing.T) {
+	ts := &TriggerStatus{}
+	ts.InitializeConditions()
+	ts.PropagateDataPlaneObservedGeneration(1, 2)
+	if got := ts.GetCondition(TriggerConditionDataPlane).Status; got != corev1.ConditionUnknown {
+		t.Errorf("unexpected data plane condition status: want %v, got %v", corev1.ConditionUnknown, got)
+	}
+	ts.PropagateDataPlaneObservedGeneration(2, 2)
+	if got := ts.GetCondition(TriggerConditionDataPlane).Status; got != corev1.ConditionTrue {
+		t.Errorf("unexpected data plane condition status: want %v, got %v", corev1.ConditionTrue, got)
+	}
+	// The data plane condition doesn't affect the readiness of the Trigger.
+	if got := ts.GetTopLevelCondition().Status; got != corev1.ConditionUnknown {
+		t.Errorf("unexpected readiness: want %v, got %v", corev1.ConditionUnknown, got)
+	}
+}
@@ -17,9 +17,12 @@ limitations under the License.
 package v1
 
 import (
+	"encoding/json"
+
 	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
 	"k8s.io/apimachinery/pkg/runtime"
 	"k8s.io/apimachinery/pkg/runtime/schema"
+	eventingduckv1 "knative.dev/eventing/pkg/apis/duck/v1"
 	eventingv1 "knative.dev/eventing/pkg/apis/eventing/v1"
 	"knative.dev/pkg/apis"
 	duckv1 "knative.dev/pkg/apis/duck/v1"
@@ -33,6 +36,10 @@ const (
 	// InjectionAnnotation is the annotation key used to enable knative eventing injection for a namespace and automatically create a default broker.
 	// This will be used when the client creates a trigger paired with default broker and the default broker doesn't exist in the namespace
 	InjectionAnnotation = "knative-eventing-injection"
+	// FiltersAnnotation is the annotation key used to set the filters of a Trigger in the dialects of
+	// the CloudEvents Subscriptions API. Its value is a JSON list of SubscriptionsAPIFilter. An event
+	// must pass all of them, as well as spec.filter, to be delivered to the subscriber.
+	FiltersAnnotation = "events.cloud.google.com/filters"
 )
 
 // +genclient
@@ -94,6 +101,42 @@ type TriggerStatus struct {
 	//SubscriptionID string `json:"subscriptionId,omitempty"`
 }
 
+// SubscriptionsAPIFilter is a filter expression in one of the dialects of the CloudEvents
+// Subscriptions API. Exactly one of the fields must be set.
+type SubscriptionsAPIFilter struct {
+	// All evaluates to true if all the nested expressions evaluate to true.
+	// +optional
+	All []SubscriptionsAPIFilter `json:"all,omitempty"`
+
+	// Any evaluates to true if at least one of the nested expressions evaluates to true.
+	// +optional
+	Any []SubscriptionsAPIFilter `json:"any,omitempty"`
+
+	// Not evaluates to true if the nested expression evaluates to false.
+	// +optional
+	Not *SubscriptionsAPIFilter `json:"not,omitempty"`
+
+	// Exact evaluates to true if the value of the matching CloudEvents attribute matches exactly
+	// the String value specified (case sensitive). Exact must contain exactly one property.
+	// +optional
+	Exact map[string]string `json:"exact,omitempty"`
+
+	// Prefix evaluates to true if the value of the matching CloudEvents attribute starts with the
+	// String value specified (case sensitive). Prefix must contain exactly one property.
+	// +optional
+	Prefix map[string]string `json:"prefix,omitempty"`
+
+	// Suffix evaluates to true if the value of the matching CloudEvents attribute ends with the
+	// String value specified (case sensitive). Suffix must contain exactly one property.
+	// +optional
+	Suffix map[string]string `json:"suffix,omitempty"`
+
+	// SQL is a CloudEvents SQL expression that will be evaluated to true or false against each
+	// CloudEvent.
+	// +optional
+	SQL string `json:"cesql,omitempty"`
+}
+
 // +k8s:deepcopy-gen:interfaces=k8s.io/apimachinery/pkg/runtime.Object
 
 // TriggerList is a collection of Triggers.
@@ -104,6 +147,44 @@ type TriggerList struct {
 	Items           []Trigger `json:"items"`
 }
 
+// GetFilters returns the Subscriptions API filters set in the FiltersAnnotation, if any.
+func (t *Trigger) GetFilters() ([]SubscriptionsAPIFilter, error) {
+	v, ok := t.GetAnnotations()[FiltersAnnotation]
+	if !ok {
+		return nil, nil
+	}
+	var filters []SubscriptionsAPIFilter
+	if err := json.Unmarshal([]byte(v), &filters); err != nil {
+		return nil, err
+	}
+	return filters, nil
+}
+
+// GetDeliverySpec returns the delivery spec of the events of the Trigger: the delivery spec of the
+// Trigger, with the fields it doesn't set taken from the delivery spec of its Broker.
+func (t *Trigger) GetDeliverySpec(b *Broker) *eventingduckv1.DeliverySpec {
+	if t.Spec.Delivery == nil {
+		return b.Spec.Delivery
+	}
+	delivery := t.Spec.Delivery.DeepCopy()
+	if b.Spec.Delivery == nil {
+		return delivery
+	}
+	if delivery.DeadLetterSink == nil {
+		delivery.DeadLetterSink = b.Spec.Delivery.DeadLetterSink
+	}
+	if delivery.Retry == nil {
+		delivery.Retry = b.Spec.Delivery.Retry
+	}
+	if delivery.BackoffPolicy == nil {
+		delivery.BackoffPolicy = b.Spec.Delivery.BackoffPolicy
+	}
+	if delivery.BackoffDelay == nil {
+		delivery.BackoffDelay = b.Spec.Delivery.BackoffDelay
+	}
+	return delivery
+}
+
 // GetGroupVersionKind returns GroupVersionKind for Triggers.
 func (t *Trigger) GetGroupVersionKind() schema.GroupVersionKind {
 	return SchemeGroupVersion.WithKind("Trigger")
@@ -21,8 +21,11 @@ import (
 
 	"github.com/google/go-cmp/cmp"
 	"k8s.io/apimachinery/pkg/runtime/schema"
+	eventingduckv1 "knative.dev/eventing/pkg/apis/duck/v1"
 	eventingv1 "knative.dev/eventing/pkg/apis/eventing/v1"
 	"knative.dev/pkg/apis"
+	duckv1 "knative.dev/pkg/apis/duck/v1"
+	"knative.dev/pkg/ptr"
 )
 
 func TestTrigger_GetGroupVersionKind(t *testing.T) {
@@ -64,3 +67,55 @@ func TestTrigger_GetStatus(t *testing.T) {
 		t.Errorf("GetStatus=%v, want=%v", got, want)
 	}
 }
+
+func TestTrigger_GetDeliverySpec(t *testing.T) {
+	linear := eventingduckv1.BackoffPolicyLinear
+	exponential := eventingduckv1.BackoffPolicyExponential
+	brokerDelivery := &eventingduckv1.DeliverySpec{
+		DeadLetterSink: &duckv1.Destination{URI: apis.HTTP("broker-dead-letter")},
+		Retry:          ptr.Int32(5),
+		BackoffPolicy:  &exponential,
+		BackoffDelay:   ptr.String("PT1S"),
+	}
+	cases := []struct {
+		name     string
+		trigger  *eventingduckv1.DeliverySpec
+		broker   *eventingduckv1.DeliverySpec
+		expected *eventingduckv1.DeliverySpec
+	}{{
+		name:     "broker delivery",
+		broker:   brokerDelivery,
+		expected: brokerDelivery,
+	}, {
+		name: "trigger delivery without broker delivery",
+		trigger: &eventingduckv1.DeliverySpec{
+			Retry: ptr.Int32(3),
+		},
+		expected: &eventingduckv1.DeliverySpec{
+			Retry: ptr.Int32(3),
+		},
+	}, {
+		name: "trigger dead letter sink and backoff policy",
+		trigger: &eventingduckv1.DeliverySpec{
+			DeadLetterSink: &duckv1.Destination{URI: apis.HTTP("trigger-dead-letter")},
+			BackoffPolicy:  &linear,
+		},
+		broker: brokerDelivery,
+		expected: &eventingduckv1.DeliverySpec{
+			DeadLetterSink: &duckv1.Destination{URI: apis.HTTP("trigger-dead-letter")},
+			Retry:          ptr.Int32(5),
+			BackoffPolicy:  &linear,
+			BackoffDelay:   ptr.String("PT1S"),
+		},
+	}}
+	for _, tc := range cases {
+		t.Run(tc.name, func(t *testing.T) {
+			trig := &Trigger{Spec: eventingv1.TriggerSpec{Delivery: tc.trigger}}
+			b := &Broker{}
+			b.Spec.Delivery = tc.broker
+			if diff := cmp.Diff(tc.expected, trig.GetDeliverySpec(b)); diff != "" {
+				t.Errorf("GetDeliverySpec (-want +got): %v", diff)
+			}
+		})
+	}
+}
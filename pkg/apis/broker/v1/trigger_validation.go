@@ -18,13 +18,118 @@ package v1
 
 import (
 	"context"
+	"regexp"
 
 	"knative.dev/pkg/apis"
+
+	"github.com/google/knative-gcp/pkg/apis/duck"
+	"github.com/google/knative-gcp/pkg/broker/eventfilter/cesql"
 )
 
+// validAttributeName matches valid CloudEvents attribute names.
+var validAttributeName = regexp.MustCompile(`^[a-z0-9]+$`)
+
 // Validate the Trigger.
 func (t *Trigger) Validate(ctx context.Context) *apis.FieldError {
-	// The Google Cloud Broker doesn't have any custom validations. The
-	// eventing webhook will run the usual validations.
+	// The eventing webhook will run the usual validations of the spec. The Google Cloud Broker
+	// only validates its own annotations.
+	return t.validateFilters().ViaFieldKey("annotations", FiltersAnnotation).
+		Also(validateResponseClassification(t.GetAnnotations()).ViaFieldKey("annotations", ResponseClassificationAnnotation)).
+		Also(t.validateDeliveryLimits()).
+		Also(validateDeliveryAuth(t.GetAnnotations()).ViaFieldKey("annotations", DeliveryAuthAnnotation)).
+		Also(validateReplay(t.GetAnnotations()).ViaFieldKey("annotations", ReplayAnnotation)).
+		ViaField("metadata").
+		Also(t.validateDeadLetterSink(ctx).ViaField("spec", "delivery", "deadLetterSink"))
+}
+
+// validateDeadLetterSink validates the dead letter sink of the Trigger, which may be a Pub/Sub
+// topic like the dead letter sink of a Broker.
+func (t *Trigger) validateDeadLetterSink(ctx context.Context) *apis.FieldError {
+	if t.Spec.Delivery == nil {
+		return nil
+	}
+	withNS := apis.AllowDifferentNamespace(apis.WithinParent(ctx, t.ObjectMeta))
+	return duck.ValidateDeadLetterSink(withNS, t.Spec.Delivery.DeadLetterSink)
+}
+
+func (t *Trigger) validateFilters() *apis.FieldError {
+	filters, err := t.GetFilters()
+	if err != nil {
+		return apis.ErrInvalidValue(err.Error(), apis.CurrentField)
+	}
+	var errs *apis.FieldError
+	for i, f := range filters {
+		errs = errs.Also(f.Validate().ViaIndex(i))
+	}
+	return errs
+}
+
+// Validate checks that exactly one dialect is set and that the expression of each dialect is
+// well formed.
+func (f *SubscriptionsAPIFilter) Validate() *apis.FieldError {
+	var errs *apis.FieldError
+	var set []string
+	if f.Exact != nil {
+		set = append(set, "exact")
+		errs = errs.Also(validateSingleAttributeMap(f.Exact).ViaField("exact"))
+	}
+	if f.Prefix != nil {
+		set = append(set, "prefix")
+		errs = errs.Also(validateSingleAttributeMap(f.Prefix).ViaField("prefix"))
+	}
+	if f.Suffix != nil {
+		set = append(set, "suffix")
+		errs = errs.Also(validateSingleAttributeMap(f.Suffix).ViaField("suffix"))
+	}
+	if f.All != nil {
+		set = append(set, "all")
+		if len(f.All) == 0 {
+			errs = errs.Also(apis.ErrInvalidValue("must contain at least one filter", "all"))
+		}
+		for i, nested := range f.All {
+			errs = errs.Also(nested.Validate().ViaFieldIndex("all", i))
+		}
+	}
+	if f.Any != nil {
+		set = append(set, "any")
+		if len(f.Any) == 0 {
+			errs = errs.Also(apis.ErrInvalidValue("must contain at least one filter", "any"))
+		}
+		for i, nested := range f.Any {
+			errs = errs.Also(nested.Validate().ViaFieldIndex("any", i))
+		}
+	}
+	if f.Not != nil {
+		set = append(set, "not")
+		errs = errs.Also(f.Not.Validate().ViaField("not"))
+	}
+	if f.SQL != "" {
+		set = append(set, "cesql")
+		if _, err := cesql.Parse(f.SQL); err != nil {
+			errs = errs.Also(apis.ErrInvalidValue(err.Error(), "cesql"))
+		}
+	}
+	switch len(set) {
+	case 0:
+		errs = errs.Also(apis.ErrMissingOneOf("exact", "prefix", "suffix", "all", "any", "not", "cesql"))
+	case 1:
+	default:
+		errs = errs.Also(apis.ErrMultipleOneOf(set...))
+	}
+	return errs
+}
+
+func validateSingleAttributeMap(m map[string]string) *apis.FieldError {
+	if len(m) != 1 {
+		return &apis.FieldError{
+			Message: "Multiple items found, can have only one key-value",
+			Paths:   []string{apis.CurrentField},
+		}
+	}
+	for k := range m {
+		if !validAttributeName.MatchString(k) {
+			return apis.ErrInvalidKeyName(k, apis.CurrentField, "Attribute name must contain only lowercase alphanumeric characters")
+		}
+	}
 	return nil
 }
@@ -19,6 +19,12 @@ package v1
 import (
 	"context"
 	"testing"
+
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	eventingduckv1 "knative.dev/eventing/pkg/apis/duck/v1"
+	eventingv1 "knative.dev/eventing/pkg/apis/eventing/v1"
+	"knative.dev/pkg/apis"
+	duckv1 "knative.dev/pkg/apis/duck/v1"
 )
 
 func TestTrigger_Validate(t *testing.T) {
@@ -27,3 +33,81 @@ func TestTrigger_Validate(t *testing.T) {
 		t.Errorf("expected nil, got %v", err)
 	}
 }
+
+func TestTrigger_ValidateFilters(t *testing.T) {
+	cases := []struct {
+		name    string
+		filters string
+		wantErr string
+	}{{
+		name:    "valid filters",
+		filters: `[{"prefix":{"type":"google.cloud.storage.object.v1."}},{"any":[{"exact":{"subject":"a"}},{"not":{"suffix":{"subject":".png"}}}]},{"cesql":"bucket LIKE 'prod-%'"}]`,
+	}, {
+		name:    "not json",
+		filters: `{`,
+		wantErr: `invalid value: unexpected end of JSON input: metadata.annotations.[events.cloud.google.com/filters]`,
+	}, {
+		name:    "no dialect",
+		filters: `[{}]`,
+		wantErr: `expected exactly one, got neither: metadata.annotations.[events.cloud.google.com/filters][0].all, metadata.annotations.[events.cloud.google.com/filters][0].any, metadata.annotations.[events.cloud.google.com/filters][0].cesql, metadata.annotations.[events.cloud.google.com/filters][0].exact, metadata.annotations.[events.cloud.google.com/filters][0].not, metadata.annotations.[events.cloud.google.com/filters][0].prefix, metadata.annotations.[events.cloud.google.com/filters][0].suffix`,
+	}, {
+		name:    "multiple dialects",
+		filters: `[{"exact":{"type":"a"},"cesql":"type = 'a'"}]`,
+		wantErr: `expected exactly one, got both: metadata.annotations.[events.cloud.google.com/filters][0].cesql, metadata.annotations.[events.cloud.google.com/filters][0].exact`,
+	}, {
+		name:    "multiple attributes",
+		filters: `[{"exact":{"type":"a","source":"b"}}]`,
+		wantErr: `Multiple items found, can have only one key-value: metadata.annotations.[events.cloud.google.com/filters][0].exact`,
+	}, {
+		name:    "invalid attribute name",
+		filters: `[{"prefix":{"Type":"a"}}]`,
+		wantErr: `invalid key name "Type": metadata.annotations.[events.cloud.google.com/filters][0].prefix` + "\n" + `Attribute name must contain only lowercase alphanumeric characters`,
+	}, {
+		name:    "empty all",
+		filters: `[{"all":[]}]`,
+		wantErr: `invalid value: must contain at least one filter: metadata.annotations.[events.cloud.google.com/filters][0].all`,
+	}, {
+		name:    "empty any",
+		filters: `[{"not":{"any":[]}}]`,
+		wantErr: `invalid value: must contain at least one filter: metadata.annotations.[events.cloud.google.com/filters][0].not.any`,
+	}, {
+		name:    "invalid nested filter",
+		filters: `[{"all":[{"exact":{"type":"a"}},{"not":{"cesql":"type ="}}]}]`,
+		wantErr: `invalid value: unexpected end of expression: metadata.annotations.[events.cloud.google.com/filters][0].all[1].not.cesql`,
+	}}
+	for _, tc := range cases {
+		t.Run(tc.name, func(t *testing.T) {
+			trig := Trigger{
+				ObjectMeta: metav1.ObjectMeta{
+					Annotations: map[string]string{FiltersAnnotation: tc.filters},
+				},
+			}
+			err := trig.Validate(context.TODO())
+			if tc.wantErr == "" {
+				if err != nil {
+					t.Errorf("expected nil, got %v", err)
+				}
+				return
+			}
+			if err == nil || err.Error() != tc.wantErr {
+				t.Errorf("Validate() = %v, want %v", err, tc.wantErr)
+			}
+		})
+	}
+}
+
+func TestTrigger_ValidateDeadLetterSink(t *testing.T) {
+	topic := apis.HTTP("dead-letter")
+	topic.Scheme = "pubsub"
+	trig := Trigger{Spec: eventingv1.TriggerSpec{Delivery: &eventingduckv1.DeliverySpec{
+		DeadLetterSink: &duckv1.Destination{URI: topic},
+	}}}
+	if err := trig.Validate(context.TODO()); err != nil {
+		t.Errorf("expected nil, got %v", err)
+	}
+	trig.Spec.Delivery.DeadLetterSink.URI.Host = ""
+	want := "invalid value: Dead letter topic must not be empty: spec.delivery.deadLetterSink.uri"
+	if err := trig.Validate(context.TODO()); err == nil || err.Error() != want {
+		t.Errorf("Validate() = %v, want %v", err, want)
+	}
+}
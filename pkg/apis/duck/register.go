@@ -34,6 +34,14 @@ const (
 	// AutoscalingMaxScaleAnnotation is the annotation to specify the maximum number of pods to scale to.
 	AutoscalingMaxScaleAnnotation = Autoscaling + "/maxScale"
 
+	// AdapterModeAnnotation is the annotation selecting how the events of a source or
+	// PullSubscription are received. Without it, they are received by a receive adapter of their
+	// own.
+	AdapterModeAnnotation = "events.cloud.google.com/adapterMode"
+	// AdapterModePool receives the events with the receive adapter pool shared by all the
+	// PullSubscriptions using it.
+	AdapterModePool = "pool"
+
 	// KEDA is Keda autoscaler.
 	KEDA = "keda.autoscaling.knative.dev"
 
@@ -0,0 +1,61 @@
+/*
+Copyright 2021 Google LLC
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package v1
+
+import (
+	"fmt"
+
+	"github.com/google/cel-go/cel"
+	"github.com/google/cel-go/checker/decls"
+	exprpb "google.golang.org/genproto/googleapis/api/expr/v1alpha1"
+)
+
+// NewEventMappingCELEnv returns the environment in which the CEL expressions of the mappings are
+// compiled. Its variables are the fields of the message.
+func NewEventMappingCELEnv() (*cel.Env, error) {
+	return cel.NewEnv(cel.Declarations(
+		decls.NewVar("id", decls.String),
+		decls.NewVar("publishTime", decls.Timestamp),
+		decls.NewVar("orderingKey", decls.String),
+		decls.NewVar("attributes", decls.NewMapType(decls.String, decls.String)),
+		decls.NewVar("data", decls.Dyn),
+	))
+}
+
+// CompileEventMappingExpression compiles a CEL expression of a mapping in env. The expressions of
+// the attributes of the events must evaluate to strings, while the expression of the data may
+// evaluate to any JSON value.
+func CompileEventMappingExpression(env *cel.Env, text string, data bool) (*cel.Ast, error) {
+	ast, issues := env.Compile(text)
+	if issues != nil && issues.Err() != nil {
+		return nil, issues.Err()
+	}
+	if data {
+		return ast, nil
+	}
+	switch ast.ResultType().GetTypeKind().(type) {
+	case *exprpb.Type_Dyn:
+		// The type of the value is only known once the expression is evaluated.
+	case *exprpb.Type_Primitive:
+		if ast.ResultType().GetPrimitive() != exprpb.Type_STRING {
+			return nil, fmt.Errorf("expression must evaluate to a string, not %s", cel.FormatType(ast.ResultType()))
+		}
+	default:
+		return nil, fmt.Errorf("expression must evaluate to a string, not %s", cel.FormatType(ast.ResultType()))
+	}
+	return ast, nil
+}
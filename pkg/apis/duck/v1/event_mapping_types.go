@@ -0,0 +1,81 @@
+/*
+Copyright 2021 Google LLC
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package v1
+
+// EventMapping defines how Pub/Sub messages are converted into CloudEvents, instead of wrapping
+// them in the Pub/Sub push message envelope.
+//
+// The values are JSONPath templates, as supported by kubectl, that are evaluated against the
+// message. The message has the fields:
+//
+//	id: the ID of the message.
+//	publishTime: the publish time of the message, in RFC 3339 format.
+//	orderingKey: the ordering key of the message.
+//	attributes: the attributes of the message.
+//	data: the payload of the message, parsed as JSON if it is JSON, or as a string otherwise.
+//
+// E.g., `com.example.order.{.attributes.action}` or `{.data.order.id}`. If Language is CEL, the
+// values are CEL expressions instead, whose variables are the fields of the message, e.g.
+// `"com.example.order." + attributes.action` or `data.order.id`.
+type EventMapping struct {
+	// Language is the language of the values of the mapping, either `JSONPath` or `CEL`. Defaults
+	// to `JSONPath`.
+	// +optional
+	Language MappingLanguage `json:"language,omitempty"`
+
+	// Type is the template of the type of the events.
+	Type string `json:"type"`
+
+	// Source is the template of the source of the events.
+	Source string `json:"source"`
+
+	// Subject is the template of the subject of the events. The events have no subject if the
+	// template refers to a missing field.
+	// +optional
+	Subject string `json:"subject,omitempty"`
+
+	// ID is the template of the ID of the events. Defaults to the ID of the message.
+	// +optional
+	ID string `json:"id,omitempty"`
+
+	// Extensions are the templates of CloudEvents extensions, keyed by the name of the extension.
+	// An extension is not set if its template refers to a missing field.
+	// +optional
+	Extensions map[string]string `json:"extensions,omitempty"`
+
+	// Data is the JSONPath of the part of the message payload that is the data of the events,
+	// e.g. `{.data.order}`, or the CEL expression of the data, e.g. `data.order`. Defaults to the
+	// whole payload of the message.
+	// +optional
+	Data string `json:"data,omitempty"`
+
+	// DataContentType is the content type of the data of the events. Defaults to
+	// `application/json` if Data is set, and to no content type otherwise.
+	// +optional
+	DataContentType string `json:"dataContentType,omitempty"`
+}
+
+// MappingLanguage is the language of the values of an EventMapping.
+type MappingLanguage string
+
+const (
+	// MappingLanguageJSONPath is the language of the JSONPath templates, as supported by kubectl.
+	MappingLanguageJSONPath MappingLanguage = "JSONPath"
+
+	// MappingLanguageCEL is the Common Expression Language.
+	MappingLanguageCEL MappingLanguage = "CEL"
+)
@@ -0,0 +1,101 @@
+/*
+Copyright 2021 Google LLC
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package v1
+
+import (
+	"context"
+	"regexp"
+
+	"github.com/google/cel-go/cel"
+	"k8s.io/client-go/util/jsonpath"
+	"knative.dev/pkg/apis"
+)
+
+// extensionNameRegex matches the valid names of CloudEvents extensions.
+var extensionNameRegex = regexp.MustCompile(`^[a-z0-9]+$`)
+
+// Validate checks that the values of the mapping are valid JSONPath templates or CEL expressions,
+// and that the required ones are set.
+func (m *EventMapping) Validate(ctx context.Context) *apis.FieldError {
+	var errs *apis.FieldError
+	validate := func(field, tmpl string, _ bool) *apis.FieldError {
+		return validateTemplate(field, tmpl)
+	}
+	switch m.Language {
+	case "", MappingLanguageJSONPath:
+	case MappingLanguageCEL:
+		env, err := NewEventMappingCELEnv()
+		if err != nil {
+			return apis.ErrGeneric(err.Error())
+		}
+		validate = func(field, expr string, data bool) *apis.FieldError {
+			return validateExpression(env, field, expr, data)
+		}
+	default:
+		return apis.ErrInvalidValue(m.Language, "language")
+	}
+	if m.Type == "" {
+		errs = errs.Also(apis.ErrMissingField("type"))
+	}
+	if m.Source == "" {
+		errs = errs.Also(apis.ErrMissingField("source"))
+	}
+	for field, tmpl := range map[string]string{
+		"type":    m.Type,
+		"source":  m.Source,
+		"subject": m.Subject,
+		"id":      m.ID,
+	} {
+		errs = errs.Also(validate(field, tmpl, false))
+	}
+	errs = errs.Also(validate("data", m.Data, true))
+	for name, tmpl := range m.Extensions {
+		if !extensionNameRegex.MatchString(name) {
+			errs = errs.Also(apis.ErrInvalidKeyName(name, "extensions"))
+		}
+		errs = errs.Also(validate(name, tmpl, false).ViaField("extensions"))
+	}
+	return errs
+}
+
+func validateTemplate(field, tmpl string) *apis.FieldError {
+	if tmpl == "" {
+		return nil
+	}
+	if err := jsonpath.New(field).Parse(tmpl); err != nil {
+		return &apis.FieldError{
+			Message: "invalid JSONPath template: " + tmpl,
+			Paths:   []string{field},
+			Details: err.Error(),
+		}
+	}
+	return nil
+}
+
+func validateExpression(env *cel.Env, field, expr string, data bool) *apis.FieldError {
+	if expr == "" {
+		return nil
+	}
+	if _, err := CompileEventMappingExpression(env, expr, data); err != nil {
+		return &apis.FieldError{
+			Message: "invalid CEL expression: " + expr,
+			Paths:   []string{field},
+			Details: err.Error(),
+		}
+	}
+	return nil
+}
@@ -0,0 +1,98 @@
+/*
+Copyright 2021 Google LLC
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package v1
+
+import (
+	"context"
+	"testing"
+)
+
+func TestEventMappingValidate(t *testing.T) {
+	tests := []struct {
+		name    string
+		mapping *EventMapping
+		wantErr bool
+	}{{
+		name: "valid",
+		mapping: &EventMapping{
+			Type:       "com.example.{.attributes.action}",
+			Source:     "//orders",
+			Subject:    "{.data.order.id}",
+			Extensions: map[string]string{"region": "{.attributes.region}"},
+			Data:       "{.data.order}",
+		},
+	}, {
+		name:    "missing type and source",
+		mapping: &EventMapping{Subject: "{.data.order.id}"},
+		wantErr: true,
+	}, {
+		name:    "invalid template",
+		mapping: &EventMapping{Type: "{.attributes", Source: "//orders"},
+		wantErr: true,
+	}, {
+		name: "invalid extension name",
+		mapping: &EventMapping{
+			Type:       "com.example",
+			Source:     "//orders",
+			Extensions: map[string]string{"Region-1": "{.attributes.region}"},
+		},
+		wantErr: true,
+	}, {
+		name: "valid CEL",
+		mapping: &EventMapping{
+			Language:   MappingLanguageCEL,
+			Type:       `"com.example." + attributes.action`,
+			Source:     `"//orders"`,
+			Subject:    "data.order.id",
+			Extensions: map[string]string{"region": "attributes.region"},
+			Data:       "data.order",
+		},
+	}, {
+		name: "invalid CEL expression",
+		mapping: &EventMapping{
+			Language: MappingLanguageCEL,
+			Type:     `"com.example." +`,
+			Source:   `"//orders"`,
+		},
+		wantErr: true,
+	}, {
+		name: "CEL expression not evaluating to a string",
+		mapping: &EventMapping{
+			Language: MappingLanguageCEL,
+			Type:     "size(attributes)",
+			Source:   `"//orders"`,
+		},
+		wantErr: true,
+	}, {
+		name: "unknown language",
+		mapping: &EventMapping{
+			Language: "XPath",
+			Type:     "com.example",
+			Source:   "//orders",
+		},
+		wantErr: true,
+	}}
+
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			err := test.mapping.Validate(context.Background())
+			if got := err != nil; got != test.wantErr {
+				t.Errorf("Validate() = %v, wanted error %t", err, test.wantErr)
+			}
+		})
+	}
+}
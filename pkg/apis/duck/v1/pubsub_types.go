@@ -23,9 +23,12 @@ import (
 	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
 	"k8s.io/apimachinery/pkg/runtime"
 
+	eventingduckv1 "knative.dev/eventing/pkg/apis/duck/v1"
 	"knative.dev/pkg/apis"
 	"knative.dev/pkg/apis/duck"
 	duckv1 "knative.dev/pkg/apis/duck/v1"
+
+	gcpduck "github.com/google/knative-gcp/pkg/apis/duck"
 )
 
 // PubSub is an Implementable "duck type".
@@ -63,6 +66,52 @@ type PubSubSpec struct {
 	// If omitted, defaults to same as the cluster.
 	// +optional
 	Project string `json:"project,omitempty"`
+
+	// Delivery is the retry and dead letter policy of the Cloud Pub/Sub subscription. A
+	// DeadLetterSink that is a Pub/Sub topic URI, e.g. pubsub://topic, receives the messages that
+	// were not delivered after Retry attempts. Any other DeadLetterSink receives the events that
+	// the receive adapter failed to deliver Retry times, and the messages that could not be
+	// converted to events.
+	// +optional
+	Delivery *eventingduckv1.DeliverySpec `json:"delivery,omitempty"`
+
+	// EnableMessageOrdering delivers the messages published with the same ordering key one at a
+	// time, in the order they were published. It can't be changed once the Cloud Pub/Sub
+	// subscription is created.
+	// +optional
+	EnableMessageOrdering bool `json:"enableMessageOrdering,omitempty"`
+
+	// EnableExactlyOnceDelivery makes Cloud Pub/Sub stop redelivering a message once its
+	// acknowledgement succeeded, and the receive adapter only considers a message acknowledged
+	// once Cloud Pub/Sub confirmed it. It can be changed on an existing subscription.
+	// +optional
+	EnableExactlyOnceDelivery bool `json:"enableExactlyOnceDelivery,omitempty"`
+
+	// Filter is an expression in the Cloud Pub/Sub filter language, e.g.
+	// 'attributes.region = "eu"'. Only the messages whose attributes match the filter are
+	// delivered. It can't be changed once the Cloud Pub/Sub subscription is created.
+	// +optional
+	Filter string `json:"filter,omitempty"`
+
+	// ExpirationTTL is how long the Cloud Pub/Sub subscription can be inactive before Cloud
+	// Pub/Sub deletes it, e.g. '720h', or 'never'. It must be at least 1 day and not shorter than
+	// the retention duration of the messages. Defaults to 31 days ('744h').
+	// +optional
+	ExpirationTTL *string `json:"expirationTTL,omitempty"`
+}
+
+// GetExpirationTTL parses ExpirationTTL and returns the default if it is not set or an error
+// occurs. It returns 0 if the subscription never expires.
+func (s PubSubSpec) GetExpirationTTL() time.Duration {
+	if s.ExpirationTTL != nil {
+		if *s.ExpirationTTL == gcpduck.ExpirationTTLNever {
+			return 0
+		}
+		if duration, err := time.ParseDuration(*s.ExpirationTTL); err == nil {
+			return duration
+		}
+	}
+	return gcpduck.DefaultExpirationTTL
 }
 
 // PubSubStatus shows how we expect folks to embed Addressable in
@@ -0,0 +1,33 @@
+/*
+Copyright 2021 Google LLC
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package v1
+
+// RawPayload sends the payload of Pub/Sub messages as is as the data of the events, instead of
+// wrapping it in the Pub/Sub push message envelope. The attributes of the messages are sent as
+// CloudEvents extensions, named after the attributes in lower case without the characters other
+// than letters and digits.
+type RawPayload struct {
+	// ContentTypeAttribute is the attribute of the messages holding the content type of their
+	// payload. Defaults to `content-type`.
+	// +optional
+	ContentTypeAttribute string `json:"contentTypeAttribute,omitempty"`
+
+	// DefaultContentType is the content type of the payload of the messages that don't have the
+	// content type attribute. Defaults to `application/octet-stream`.
+	// +optional
+	DefaultContentType string `json:"defaultContentType,omitempty"`
+}
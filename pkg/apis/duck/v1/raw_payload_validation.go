@@ -0,0 +1,35 @@
+/*
+Copyright 2021 Google LLC
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package v1
+
+import (
+	"context"
+	"mime"
+
+	"knative.dev/pkg/apis"
+)
+
+// Validate checks that the default content type is a valid media type.
+func (p *RawPayload) Validate(ctx context.Context) *apis.FieldError {
+	if p.DefaultContentType == "" {
+		return nil
+	}
+	if _, _, err := mime.ParseMediaType(p.DefaultContentType); err != nil {
+		return apis.ErrInvalidValue(p.DefaultContentType, "defaultContentType")
+	}
+	return nil
+}
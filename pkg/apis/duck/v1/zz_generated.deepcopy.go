@@ -23,10 +23,34 @@ package v1
 import (
 	corev1 "k8s.io/api/core/v1"
 	runtime "k8s.io/apimachinery/pkg/runtime"
+	eventingduckv1 "knative.dev/eventing/pkg/apis/duck/v1"
 	apis "knative.dev/pkg/apis"
 	duckv1 "knative.dev/pkg/apis/duck/v1"
 )
 
+// DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
+func (in *EventMapping) DeepCopyInto(out *EventMapping) {
+	*out = *in
+	if in.Extensions != nil {
+		in, out := &in.Extensions, &out.Extensions
+		*out = make(map[string]string, len(*in))
+		for key, val := range *in {
+			(*out)[key] = val
+		}
+	}
+	return
+}
+
+// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new EventMapping.
+func (in *EventMapping) DeepCopy() *EventMapping {
+	if in == nil {
+		return nil
+	}
+	out := new(EventMapping)
+	in.DeepCopyInto(out)
+	return out
+}
+
 // DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
 func (in *IdentitySpec) DeepCopyInto(out *IdentitySpec) {
 	*out = *in
@@ -131,6 +155,16 @@ func (in *PubSubSpec) DeepCopyInto(out *PubSubSpec) {
 		*out = new(corev1.SecretKeySelector)
 		(*in).DeepCopyInto(*out)
 	}
+	if in.Delivery != nil {
+		in, out := &in.Delivery, &out.Delivery
+		*out = new(eventingduckv1.DeliverySpec)
+		(*in).DeepCopyInto(*out)
+	}
+	if in.ExpirationTTL != nil {
+		in, out := &in.ExpirationTTL, &out.ExpirationTTL
+		*out = new(string)
+		**out = **in
+	}
 	return
 }
 
@@ -171,6 +205,22 @@ func (in *PubSubStatus) DeepCopy() *PubSubStatus {
 	return out
 }
 
+// DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
+func (in *RawPayload) DeepCopyInto(out *RawPayload) {
+	*out = *in
+	return
+}
+
+// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new RawPayload.
+func (in *RawPayload) DeepCopy() *RawPayload {
+	if in == nil {
+		return nil
+	}
+	out := new(RawPayload)
+	in.DeepCopyInto(out)
+	return out
+}
+
 // DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
 func (in *Resource) DeepCopyInto(out *Resource) {
 	*out = *in
@@ -23,12 +23,37 @@ import (
 	"math"
 	"regexp"
 	"strconv"
+	"time"
 
 	"github.com/google/go-cmp/cmp"
+	"github.com/rickb777/date/period"
 
 	corev1 "k8s.io/api/core/v1"
 	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	eventingduckv1 "knative.dev/eventing/pkg/apis/duck/v1"
 	"knative.dev/pkg/apis"
+	duckv1 "knative.dev/pkg/apis/duck/v1"
+)
+
+const (
+	// minDeliveryAttempts and maxDeliveryAttempts are the bounds of the delivery attempts of a
+	// Pub/Sub dead letter policy.
+	minDeliveryAttempts = 5
+	maxDeliveryAttempts = 100
+
+	// maxFilterLength is the maximum length of the filter of a Pub/Sub subscription.
+	maxFilterLength = 256
+
+	// minExpirationTTL and maxExpirationTTL are the bounds of the expiration TTL of a Pub/Sub
+	// subscription.
+	minExpirationTTL = 24 * time.Hour
+	maxExpirationTTL = 365 * 24 * time.Hour
+
+	// DefaultExpirationTTL is the expiration TTL Pub/Sub sets on subscriptions (31 days).
+	DefaultExpirationTTL = 31 * 24 * time.Hour
+
+	// ExpirationTTLNever is the expiration TTL of a Pub/Sub subscription that never expires.
+	ExpirationTTLNever = "never"
 )
 
 var (
@@ -39,7 +64,16 @@ var (
 
 // ValidateAutoscalingAnnotations validates the autoscaling annotations.
 // The class ensures that we reconcile using the corresponding controller.
+// The adapter mode annotation is validated as well, since the receive adapter pool is scaled on its
+// own and can't be combined with an autoscaling class.
 func ValidateAutoscalingAnnotations(ctx context.Context, annotations map[string]string, errs *apis.FieldError) *apis.FieldError {
+	if adapterMode, ok := annotations[AdapterModeAnnotation]; ok {
+		if adapterMode != AdapterModePool {
+			errs = errs.Also(apis.ErrInvalidValue(adapterMode, fmt.Sprintf("metadata.annotations[%s]", AdapterModeAnnotation)))
+		} else if _, ok := annotations[AutoscalingClassAnnotation]; ok {
+			errs = errs.Also(apis.ErrMultipleOneOf(fmt.Sprintf("metadata.annotations[%s]", AdapterModeAnnotation), fmt.Sprintf("metadata.annotations[%s]", AutoscalingClassAnnotation)))
+		}
+	}
 	if autoscalingClass, ok := annotations[AutoscalingClassAnnotation]; ok {
 		// Only supported autoscaling class is KEDA.
 		if autoscalingClass != KEDA {
@@ -153,3 +187,77 @@ func validateK8sServiceAccount(kServiceAccountName string) *apis.FieldError {
 	}
 	return nil
 }
+
+// ValidateDelivery validates the delivery spec of a Pub/Sub subscription. Retry bounds the delivery
+// attempts before an event is dead lettered, so it requires a dead letter sink.
+func ValidateDelivery(ctx context.Context, spec *eventingduckv1.DeliverySpec) *apis.FieldError {
+	if spec == nil {
+		return nil
+	}
+	var errs *apis.FieldError
+	if spec.BackoffDelay != nil {
+		if _, err := period.Parse(*spec.BackoffDelay); err != nil {
+			errs = errs.Also(apis.ErrInvalidValue(*spec.BackoffDelay, "backoffDelay"))
+		}
+	}
+	if spec.Retry != nil {
+		if spec.DeadLetterSink == nil {
+			errs = errs.Also(apis.ErrGeneric("retry requires a dead letter sink", "retry", "deadLetterSink"))
+		} else if *spec.Retry < minDeliveryAttempts || *spec.Retry > maxDeliveryAttempts {
+			errs = errs.Also(apis.ErrOutOfBoundsValue(*spec.Retry, minDeliveryAttempts, maxDeliveryAttempts, "retry"))
+		}
+	}
+	return errs.Also(ValidateDeadLetterSink(ctx, spec.DeadLetterSink).ViaField("deadLetterSink"))
+}
+
+// ValidateDeadLetterSink validates the dead letter sink of a delivery spec. A Pub/Sub topic URI
+// is dead lettered by Pub/Sub, any other addressable or absolute URI is dead lettered by the
+// data plane.
+func ValidateDeadLetterSink(ctx context.Context, sink *duckv1.Destination) *apis.FieldError {
+	if sink == nil {
+		return nil
+	}
+	if sink.URI == nil || sink.URI.Scheme != "pubsub" {
+		return duckv1.ValidateDestination(ctx, *sink)
+	}
+	if sink.Ref != nil {
+		return apis.ErrGeneric("Ref is not allowed with a Pub/Sub dead letter topic", "ref", "uri")
+	}
+	topicID := sink.URI.Host
+	if topicID == "" {
+		return apis.ErrInvalidValue("Dead letter topic must not be empty", "uri")
+	}
+	if len(topicID) > 255 {
+		return apis.ErrInvalidValue("Dead letter topic maximum length is 255 characters", "uri")
+	}
+	return nil
+}
+
+// ValidateFilter validates the filter of a Pub/Sub subscription. The syntax of the filter is
+// only checked by Pub/Sub when the subscription is created.
+func ValidateFilter(filter string) *apis.FieldError {
+	if len(filter) > maxFilterLength {
+		return apis.ErrInvalidValue(fmt.Sprintf("Filter maximum length is %d characters", maxFilterLength), "filter")
+	}
+	return nil
+}
+
+// ValidateExpirationTTL validates the expiration TTL of a Pub/Sub subscription, which Pub/Sub
+// requires to be at least the retention duration of its messages.
+func ValidateExpirationTTL(ttl *string, retentionDuration time.Duration) *apis.FieldError {
+	if ttl == nil || *ttl == ExpirationTTLNever {
+		return nil
+	}
+	d, err := time.ParseDuration(*ttl)
+	if err != nil {
+		return apis.ErrInvalidValue(*ttl, "expirationTTL")
+	}
+	min := minExpirationTTL
+	if retentionDuration > min {
+		min = retentionDuration
+	}
+	if d < min || d > maxExpirationTTL {
+		return apis.ErrOutOfBoundsValue(*ttl, min.String(), maxExpirationTTL.String(), "expirationTTL")
+	}
+	return nil
+}
@@ -19,7 +19,9 @@ package duck
 
 import (
 	"context"
+	"strings"
 	"testing"
+	"time"
 
 	"github.com/google/go-cmp/cmp"
 	gcpauthtesthelper "github.com/google/knative-gcp/pkg/apis/configs/gcpauth/testhelper"
@@ -27,7 +29,10 @@ import (
 
 	corev1 "k8s.io/api/core/v1"
 	v1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	eventingduckv1 "knative.dev/eventing/pkg/apis/duck/v1"
 	"knative.dev/pkg/apis"
+	duckv1 "knative.dev/pkg/apis/duck/v1"
+	"knative.dev/pkg/ptr"
 )
 
 func TestValidateAutoscalingAnnotations(t *testing.T) {
@@ -108,6 +113,26 @@ func TestValidateAutoscalingAnnotations(t *testing.T) {
 			}(),
 			error: true,
 		},
+		"ok adapter pool": {
+			objMeta: &v1.ObjectMeta{
+				Annotations: map[string]string{AdapterModeAnnotation: AdapterModePool},
+			},
+			error: false,
+		},
+		"invalid adapter mode": {
+			objMeta: &v1.ObjectMeta{
+				Annotations: map[string]string{AdapterModeAnnotation: "shared"},
+			},
+			error: true,
+		},
+		"adapter pool with keda scaling": {
+			objMeta: func() *v1.ObjectMeta {
+				obj := kedaScaling.DeepCopy()
+				obj.Annotations[AdapterModeAnnotation] = AdapterModePool
+				return obj
+			}(),
+			error: true,
+		},
 	}
 	for n, tc := range testCases {
 		t.Run(n, func(t *testing.T) {
@@ -345,3 +370,110 @@ func TestValidateCredential(t *testing.T) {
 		}
 	}
 }
+
+func TestValidateDelivery(t *testing.T) {
+	topic := apis.HTTP("dead-letter")
+	topic.Scheme = "pubsub"
+	testCases := []struct {
+		name    string
+		spec    *eventingduckv1.DeliverySpec
+		wantErr bool
+	}{{
+		name: "nil spec",
+	}, {
+		name: "dead letter topic with retry",
+		spec: &eventingduckv1.DeliverySpec{
+			DeadLetterSink: &duckv1.Destination{URI: topic},
+			Retry:          ptr.Int32(10),
+			BackoffDelay:   ptr.String("PT1S"),
+		},
+	}, {
+		name: "dead letter sink",
+		spec: &eventingduckv1.DeliverySpec{
+			DeadLetterSink: &duckv1.Destination{URI: apis.HTTP("dead-letter.example.com")},
+		},
+	}, {
+		name: "dead letter sink with retry",
+		spec: &eventingduckv1.DeliverySpec{
+			DeadLetterSink: &duckv1.Destination{URI: apis.HTTP("dead-letter.example.com")},
+			Retry:          ptr.Int32(10),
+		},
+	}, {
+		name: "retry without dead letter sink",
+		spec: &eventingduckv1.DeliverySpec{
+			Retry: ptr.Int32(10),
+		},
+		wantErr: true,
+	}, {
+		name: "retry out of bounds",
+		spec: &eventingduckv1.DeliverySpec{
+			DeadLetterSink: &duckv1.Destination{URI: topic},
+			Retry:          ptr.Int32(1),
+		},
+		wantErr: true,
+	}, {
+		name: "invalid backoff delay",
+		spec: &eventingduckv1.DeliverySpec{
+			BackoffDelay: ptr.String("1s"),
+		},
+		wantErr: true,
+	}}
+
+	for _, tc := range testCases {
+		errs := ValidateDelivery(context.Background(), tc.spec)
+		if got := errs != nil; got != tc.wantErr {
+			t.Errorf("%s: unexpected error %v, wanted error %t", tc.name, errs, tc.wantErr)
+		}
+	}
+}
+
+func TestValidateFilter(t *testing.T) {
+	if errs := ValidateFilter(`attributes.region = "eu"`); errs != nil {
+		t.Errorf("unexpected error %v", errs)
+	}
+	if errs := ValidateFilter(strings.Repeat("a", maxFilterLength+1)); errs == nil {
+		t.Error("expected an error for a filter too long")
+	}
+}
+
+func TestValidateExpirationTTL(t *testing.T) {
+	testCases := []struct {
+		name      string
+		ttl       *string
+		retention time.Duration
+		wantErr   bool
+	}{{
+		name: "nil ttl",
+	}, {
+		name: "never",
+		ttl:  ptr.String(ExpirationTTLNever),
+	}, {
+		name:      "valid ttl",
+		ttl:       ptr.String("720h"),
+		retention: 7 * 24 * time.Hour,
+	}, {
+		name:    "invalid ttl",
+		ttl:     ptr.String("30d"),
+		wantErr: true,
+	}, {
+		name:    "ttl shorter than a day",
+		ttl:     ptr.String("1h"),
+		wantErr: true,
+	}, {
+		name:      "ttl shorter than the retention duration",
+		ttl:       ptr.String("48h"),
+		retention: 7 * 24 * time.Hour,
+		wantErr:   true,
+	}, {
+		name:    "ttl longer than a year",
+		ttl:     ptr.String("9000h"),
+		wantErr: true,
+	}}
+
+	for _, tc := range testCases {
+		errs := ValidateExpirationTTL(tc.ttl, tc.retention)
+		if got := errs != nil; got != tc.wantErr {
+			t.Errorf("%s: unexpected error %v, wanted error %t", tc.name, errs, tc.wantErr)
+		}
+	}
+}
@@ -22,6 +22,7 @@ import (
 	"github.com/google/go-cmp/cmp"
 	"github.com/google/go-cmp/cmp/cmpopts"
 	"github.com/google/knative-gcp/pkg/apis/duck"
+	"github.com/google/knative-gcp/pkg/apis/intevents"
 
 	"k8s.io/apimachinery/pkg/api/equality"
 	"knative.dev/pkg/apis"
@@ -61,6 +62,10 @@ func (current *CloudAuditLogsSourceSpec) Validate(ctx context.Context) *apis.Fie
 		errs = errs.Also(err)
 	}
 
+	errs = errs.Also(duck.ValidateDelivery(ctx, current.Delivery).ViaField("delivery"))
+	errs = errs.Also(duck.ValidateFilter(current.Filter))
+	errs = errs.Also(duck.ValidateExpirationTTL(current.ExpirationTTL, intevents.DefaultRetentionDuration))
+
 	return errs
 }
 
@@ -74,7 +79,7 @@ func (current *CloudAuditLogsSource) CheckImmutableFields(ctx context.Context, o
 	// Everything else is mutable.
 	if diff := cmp.Diff(original.Spec, current.Spec,
 		cmpopts.IgnoreFields(CloudAuditLogsSourceSpec{},
-			"Sink", "CloudEventOverrides")); diff != "" {
+			"Sink", "CloudEventOverrides", "Delivery", "ExpirationTTL", "EnableExactlyOnceDelivery")); diff != "" {
 		errs = errs.Also(
 			&apis.FieldError{
 				Message: "Immutable fields changed (-old +new)",
@@ -23,6 +23,7 @@ import (
 	"github.com/google/go-cmp/cmp"
 	"github.com/google/go-cmp/cmp/cmpopts"
 	"github.com/google/knative-gcp/pkg/apis/duck"
+	"github.com/google/knative-gcp/pkg/apis/intevents"
 )
 
 func (current *CloudBuildSource) Validate(ctx context.Context) *apis.FieldError {
@@ -50,6 +51,10 @@ func (current *CloudBuildSourceSpec) Validate(ctx context.Context) *apis.FieldEr
 		errs = errs.Also(err)
 	}
 
+	errs = errs.Also(duck.ValidateDelivery(ctx, current.Delivery).ViaField("delivery"))
+	errs = errs.Also(duck.ValidateFilter(current.Filter))
+	errs = errs.Also(duck.ValidateExpirationTTL(current.ExpirationTTL, intevents.DefaultRetentionDuration))
+
 	return errs
 }
 
@@ -62,7 +67,7 @@ func (current *CloudBuildSource) CheckImmutableFields(ctx context.Context, origi
 	// Modification of Topic, Secret and Project are not allowed. Everything else is mutable.
 	if diff := cmp.Diff(original.Spec, current.Spec,
 		cmpopts.IgnoreFields(CloudBuildSourceSpec{},
-			"Sink", "CloudEventOverrides")); diff != "" {
+			"Sink", "CloudEventOverrides", "Delivery", "ExpirationTTL", "EnableExactlyOnceDelivery")); diff != "" {
 		errs = errs.Also(&apis.FieldError{
 			Message: "Immutable fields changed (-old +new)",
 			Paths:   []string{"spec"},
@@ -89,6 +89,16 @@ type CloudPubSubSourceSpec struct {
 	// shorter than 10 minutes. Defaults to 7 days ('7d').
 	// +optional
 	RetentionDuration *string `json:"retentionDuration,omitempty"`
+
+	// Mapping converts the Pub/Sub messages into events with the given attributes and data,
+	// instead of wrapping them in the Pub/Sub push message envelope.
+	// +optional
+	Mapping *gcpduckv1.EventMapping `json:"mapping,omitempty"`
+
+	// RawPayload sends the payload of the Pub/Sub messages as the data of the events, instead of
+	// wrapping it in the Pub/Sub push message envelope. It can't be set along with Mapping.
+	// +optional
+	RawPayload *gcpduckv1.RawPayload `json:"rawPayload,omitempty"`
 }
 
 // GetAckDeadline parses AckDeadline and returns the default if an error occurs.
@@ -169,6 +179,16 @@ func (s *CloudPubSubSource) PubSubStatus() *gcpduckv1.PubSubStatus {
 	return &s.Status.PubSubStatus
 }
 
+// EventMapping returns the mapping of the messages into events, if any.
+func (ps *CloudPubSubSource) EventMapping() *gcpduckv1.EventMapping {
+	return ps.Spec.Mapping
+}
+
+// RawPayload returns how the raw payload of the messages is sent, if it is not wrapped.
+func (ps *CloudPubSubSource) RawPayload() *gcpduckv1.RawPayload {
+	return ps.Spec.RawPayload
+}
+
 // GetConditionSet retrieves the condition set for this resource. Implements the KRShaped interface.
 func (*CloudPubSubSource) GetConditionSet() apis.ConditionSet {
 	return pubSubCondSet
@@ -77,6 +77,21 @@ func (current *CloudPubSubSourceSpec) Validate(ctx context.Context) *apis.FieldE
 		errs = errs.Also(err)
 	}
 
+	errs = errs.Also(duck.ValidateDelivery(ctx, current.Delivery).ViaField("delivery"))
+	errs = errs.Also(duck.ValidateFilter(current.Filter))
+	// The PullSubscription of the source retains the messages for the default retention duration.
+	errs = errs.Also(duck.ValidateExpirationTTL(current.ExpirationTTL, intevents.DefaultRetentionDuration))
+
+	if current.Mapping != nil {
+		errs = errs.Also(current.Mapping.Validate(ctx).ViaField("mapping"))
+	}
+	if current.RawPayload != nil {
+		errs = errs.Also(current.RawPayload.Validate(ctx).ViaField("rawPayload"))
+		if current.Mapping != nil {
+			errs = errs.Also(apis.ErrMultipleOneOf("mapping", "rawPayload"))
+		}
+	}
+
 	return errs
 }
 
@@ -89,7 +104,7 @@ func (current *CloudPubSubSource) CheckImmutableFields(ctx context.Context, orig
 	// Modification of Topic, Secret, AckDeadline, RetainAckedMessages, RetentionDuration, ServiceAccountName and Project are not allowed.
 	// Everything else is mutable.
 	if diff := cmp.Diff(original.Spec, current.Spec,
-		cmpopts.IgnoreFields(CloudPubSubSourceSpec{}, "Sink", "CloudEventOverrides")); diff != "" {
+		cmpopts.IgnoreFields(CloudPubSubSourceSpec{}, "Sink", "CloudEventOverrides", "Delivery", "ExpirationTTL", "EnableExactlyOnceDelivery", "Mapping", "RawPayload")); diff != "" {
 		errs = errs.Also(&apis.FieldError{
 			Message: "Immutable fields changed (-old +new)",
 			Paths:   []string{"spec"},
@@ -238,6 +238,47 @@ func TestCloudPubSubSourceCheckValidationFields(t *testing.T) {
 			}(),
 			error: true,
 		},
+		"ok mapping": {
+			spec: func() CloudPubSubSourceSpec {
+				obj := pubSubSourceSpec.DeepCopy()
+				obj.Mapping = &gcpduckv1.EventMapping{Type: "com.example.{.attributes.action}", Source: "//orders"}
+				return *obj
+			}(),
+			error: false,
+		},
+		"bad mapping": {
+			spec: func() CloudPubSubSourceSpec {
+				obj := pubSubSourceSpec.DeepCopy()
+				obj.Mapping = &gcpduckv1.EventMapping{Type: "{.attributes", Source: "//orders"}
+				return *obj
+			}(),
+			error: true,
+		},
+		"ok raw payload": {
+			spec: func() CloudPubSubSourceSpec {
+				obj := pubSubSourceSpec.DeepCopy()
+				obj.RawPayload = &gcpduckv1.RawPayload{DefaultContentType: "application/json"}
+				return *obj
+			}(),
+			error: false,
+		},
+		"bad raw payload content type": {
+			spec: func() CloudPubSubSourceSpec {
+				obj := pubSubSourceSpec.DeepCopy()
+				obj.RawPayload = &gcpduckv1.RawPayload{DefaultContentType: "not a media type"}
+				return *obj
+			}(),
+			error: true,
+		},
+		"mapping and raw payload at the same time": {
+			spec: func() CloudPubSubSourceSpec {
+				obj := pubSubSourceSpec.DeepCopy()
+				obj.Mapping = &gcpduckv1.EventMapping{Type: "com.example", Source: "//orders"}
+				obj.RawPayload = &gcpduckv1.RawPayload{}
+				return *obj
+			}(),
+			error: true,
+		},
 	}
 	for n, tc := range testCases {
 		t.Run(n, func(t *testing.T) {
@@ -22,6 +22,7 @@ import (
 	"github.com/google/go-cmp/cmp"
 	"github.com/google/go-cmp/cmp/cmpopts"
 	"github.com/google/knative-gcp/pkg/apis/duck"
+	"github.com/google/knative-gcp/pkg/apis/intevents"
 	"k8s.io/apimachinery/pkg/api/equality"
 	"knative.dev/pkg/apis"
 	duckv1 "knative.dev/pkg/apis/duck/v1"
@@ -66,6 +67,10 @@ func (current *CloudSchedulerSourceSpec) Validate(ctx context.Context) *apis.Fie
 		errs = errs.Also(err)
 	}
 
+	errs = errs.Also(duck.ValidateDelivery(ctx, current.Delivery).ViaField("delivery"))
+	errs = errs.Also(duck.ValidateFilter(current.Filter))
+	errs = errs.Also(duck.ValidateExpirationTTL(current.ExpirationTTL, intevents.DefaultRetentionDuration))
+
 	return errs
 }
 
@@ -78,7 +83,7 @@ func (current *CloudSchedulerSource) CheckImmutableFields(ctx context.Context, o
 	// Modification of Location, Schedule, Data, Secret, ServiceAccountName, Project are not allowed.
 	// Everything else is mutable.
 	if diff := cmp.Diff(original.Spec, current.Spec,
-		cmpopts.IgnoreFields(CloudSchedulerSourceSpec{}, "Sink", "CloudEventOverrides")); diff != "" {
+		cmpopts.IgnoreFields(CloudSchedulerSourceSpec{}, "Sink", "CloudEventOverrides", "Delivery", "ExpirationTTL", "EnableExactlyOnceDelivery")); diff != "" {
 		errs = errs.Also(&apis.FieldError{
 			Message: "Immutable fields changed (-old +new)",
 			Paths:   []string{"spec"},
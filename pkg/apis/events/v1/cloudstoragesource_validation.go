@@ -26,6 +26,7 @@ import (
 	"github.com/google/go-cmp/cmp"
 	"github.com/google/go-cmp/cmp/cmpopts"
 	"github.com/google/knative-gcp/pkg/apis/duck"
+	"github.com/google/knative-gcp/pkg/apis/intevents"
 )
 
 func (current *CloudStorageSource) Validate(ctx context.Context) *apis.FieldError {
@@ -57,6 +58,10 @@ func (current *CloudStorageSourceSpec) Validate(ctx context.Context) *apis.Field
 		errs = errs.Also(err)
 	}
 
+	errs = errs.Also(duck.ValidateDelivery(ctx, current.Delivery).ViaField("delivery"))
+	errs = errs.Also(duck.ValidateFilter(current.Filter))
+	errs = errs.Also(duck.ValidateExpirationTTL(current.ExpirationTTL, intevents.DefaultRetentionDuration))
+
 	return errs
 }
 
@@ -70,7 +75,7 @@ func (current *CloudStorageSource) CheckImmutableFields(ctx context.Context, ori
 	// Everything else is mutable.
 	if diff := cmp.Diff(original.Spec, current.Spec,
 		cmpopts.IgnoreFields(CloudStorageSourceSpec{},
-			"Sink", "CloudEventOverrides")); diff != "" {
+			"Sink", "CloudEventOverrides", "Delivery", "ExpirationTTL", "EnableExactlyOnceDelivery")); diff != "" {
 		errs = errs.Also(&apis.FieldError{
 			Message: "Immutable fields changed (-old +new)",
 			Paths:   []string{"spec"},
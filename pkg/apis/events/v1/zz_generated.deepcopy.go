@@ -21,6 +21,7 @@ limitations under the License.
 package v1
 
 import (
+	duckv1 "github.com/google/knative-gcp/pkg/apis/duck/v1"
 	runtime "k8s.io/apimachinery/pkg/runtime"
 )
 
@@ -289,6 +290,16 @@ func (in *CloudPubSubSourceSpec) DeepCopyInto(out *CloudPubSubSourceSpec) {
 		*out = new(string)
 		**out = **in
 	}
+	if in.Mapping != nil {
+		in, out := &in.Mapping, &out.Mapping
+		*out = new(duckv1.EventMapping)
+		(*in).DeepCopyInto(*out)
+	}
+	if in.RawPayload != nil {
+		in, out := &in.RawPayload, &out.RawPayload
+		*out = new(duckv1.RawPayload)
+		**out = **in
+	}
 	return
 }
 
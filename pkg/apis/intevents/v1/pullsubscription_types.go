@@ -94,6 +94,16 @@ type PullSubscriptionSpec struct {
 	// PullSubscription uses.
 	// +optional
 	AdapterType string `json:"adapterType,omitempty"`
+
+	// Mapping converts the Pub/Sub messages into events with the given attributes and data,
+	// instead of wrapping them in the Pub/Sub push message envelope.
+	// +optional
+	Mapping *v1.EventMapping `json:"mapping,omitempty"`
+
+	// RawPayload sends the payload of the Pub/Sub messages as the data of the events, instead of
+	// wrapping it in the Pub/Sub push message envelope. It can't be set along with Mapping.
+	// +optional
+	RawPayload *v1.RawPayload `json:"rawPayload,omitempty"`
 }
 
 // GetAckDeadline parses AckDeadline and returns the default if an error occurs.
@@ -154,6 +164,11 @@ type PullSubscriptionStatus struct {
 	// +optional
 	TransformerURI *apis.URL `json:"transformerUri,omitempty"`
 
+	// DeadLetterSinkURI is the current active dead letter sink URI of the messages that could not
+	// be converted to events. It is not set for a Pub/Sub dead letter topic.
+	// +optional
+	DeadLetterSinkURI *apis.URL `json:"deadLetterSinkUri,omitempty"`
+
 	// SubscriptionID is the created subscription ID used by the PullSubscription.
 	// +optional
 	SubscriptionID string `json:"subscriptionId,omitempty"`
@@ -89,6 +89,20 @@ func (current *PullSubscriptionSpec) Validate(ctx context.Context) *apis.FieldEr
 		}
 	}
 
+	errs = errs.Also(duck.ValidateDelivery(ctx, current.Delivery).ViaField("delivery"))
+	errs = errs.Also(duck.ValidateFilter(current.Filter))
+	errs = errs.Also(duck.ValidateExpirationTTL(current.ExpirationTTL, current.GetRetentionDuration()))
+
+	if current.Mapping != nil {
+		errs = errs.Also(current.Mapping.Validate(ctx).ViaField("mapping"))
+	}
+	if current.RawPayload != nil {
+		errs = errs.Also(current.RawPayload.Validate(ctx).ViaField("rawPayload"))
+		if current.Mapping != nil {
+			errs = errs.Also(apis.ErrMultipleOneOf("mapping", "rawPayload"))
+		}
+	}
+
 	return errs
 }
 
@@ -110,11 +124,13 @@ func (current *PullSubscription) CheckImmutableFields(ctx context.Context, origi
 	}
 
 	var errs *apis.FieldError
-	// Modification of Topic, Secret, AckDeadline, RetainAckedMessages, RetentionDuration and Project are not allowed.
+	// Modification of Topic, Secret, AckDeadline, RetainAckedMessages, RetentionDuration, Project,
+	// EnableMessageOrdering and Filter are not allowed.
 	// Everything else is mutable.
 	if diff := cmp.Diff(original.Spec, current.Spec,
 		cmpopts.IgnoreFields(PullSubscriptionSpec{},
-			"Sink", "Transformer", "CloudEventOverrides")); diff != "" {
+			"Sink", "Transformer", "CloudEventOverrides", "Delivery", "ExpirationTTL", "EnableExactlyOnceDelivery",
+			"Mapping", "RawPayload")); diff != "" {
 		errs = errs.Also(&apis.FieldError{
 			Message: "Immutable fields changed (-old +new)",
 			Paths:   []string{"spec"},
@@ -18,6 +18,7 @@ package v1
 
 import (
 	"context"
+	"strings"
 	"testing"
 
 	"github.com/google/knative-gcp/pkg/apis/duck"
@@ -120,6 +121,22 @@ func TestPullSubscriptionCheckValidationFields(t *testing.T) {
 			}(),
 			error: true,
 		},
+		"bad ExpirationTTL, range": {
+			spec: func() PullSubscriptionSpec {
+				obj := pullSubscriptionSpec.DeepCopy()
+				obj.ExpirationTTL = ptr.String("1h")
+				return *obj
+			}(),
+			error: true,
+		},
+		"bad Filter, length": {
+			spec: func() PullSubscriptionSpec {
+				obj := pullSubscriptionSpec.DeepCopy()
+				obj.Filter = strings.Repeat("a", 257)
+				return *obj
+			}(),
+			error: true,
+		},
 		"bad AckDeadline": {
 			spec: func() PullSubscriptionSpec {
 				obj := pullSubscriptionSpec.DeepCopy()
@@ -733,6 +750,42 @@ func TestPullSubscriptionCheckImmutableFields(t *testing.T) {
 			},
 			allowed: false,
 		},
+		"Filter changed": {
+			orig: &pullSubscriptionSpec,
+			updated: func() PullSubscriptionSpec {
+				obj := pullSubscriptionSpec.DeepCopy()
+				obj.Filter = `attributes.region = "eu"`
+				return *obj
+			}(),
+			allowed: false,
+		},
+		"EnableMessageOrdering changed": {
+			orig: &pullSubscriptionSpec,
+			updated: func() PullSubscriptionSpec {
+				obj := pullSubscriptionSpec.DeepCopy()
+				obj.EnableMessageOrdering = true
+				return *obj
+			}(),
+			allowed: false,
+		},
+		"ExpirationTTL changed": {
+			orig: &pullSubscriptionSpec,
+			updated: func() PullSubscriptionSpec {
+				obj := pullSubscriptionSpec.DeepCopy()
+				obj.ExpirationTTL = ptr.String("never")
+				return *obj
+			}(),
+			allowed: true,
+		},
+		"EnableExactlyOnceDelivery changed": {
+			orig: &pullSubscriptionSpec,
+			updated: func() PullSubscriptionSpec {
+				obj := pullSubscriptionSpec.DeepCopy()
+				obj.EnableExactlyOnceDelivery = true
+				return *obj
+			}(),
+			allowed: true,
+		},
 		"ClusterName annotation added": {
 			origAnnotation: nil,
 			updatedAnnotation: map[string]string{
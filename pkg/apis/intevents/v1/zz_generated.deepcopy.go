@@ -21,6 +21,7 @@ limitations under the License.
 package v1
 
 import (
+	apisduckv1 "github.com/google/knative-gcp/pkg/apis/duck/v1"
 	corev1 "k8s.io/api/core/v1"
 	runtime "k8s.io/apimachinery/pkg/runtime"
 	apis "knative.dev/pkg/apis"
@@ -107,6 +108,16 @@ func (in *PullSubscriptionSpec) DeepCopyInto(out *PullSubscriptionSpec) {
 		*out = new(duckv1.Destination)
 		(*in).DeepCopyInto(*out)
 	}
+	if in.Mapping != nil {
+		in, out := &in.Mapping, &out.Mapping
+		*out = new(apisduckv1.EventMapping)
+		(*in).DeepCopyInto(*out)
+	}
+	if in.RawPayload != nil {
+		in, out := &in.RawPayload, &out.RawPayload
+		*out = new(apisduckv1.RawPayload)
+		**out = **in
+	}
 	return
 }
 
@@ -129,6 +140,11 @@ func (in *PullSubscriptionStatus) DeepCopyInto(out *PullSubscriptionStatus) {
 		*out = new(apis.URL)
 		(*in).DeepCopyInto(*out)
 	}
+	if in.DeadLetterSinkURI != nil {
+		in, out := &in.DeadLetterSinkURI, &out.DeadLetterSinkURI
+		*out = new(apis.URL)
+		(*in).DeepCopyInto(*out)
+	}
 	return
 }
 
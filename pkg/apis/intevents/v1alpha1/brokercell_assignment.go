@@ -0,0 +1,72 @@
+/*
+Copyright 2021 Google LLC
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package v1alpha1
+
+import (
+	"strings"
+
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/util/validation"
+	"knative.dev/pkg/apis"
+)
+
+const (
+	// BrokerCellLabelKey is the label of the Brokers and Channels selecting the BrokerCell that
+	// serves them. The BrokerCells are in the system namespace.
+	BrokerCellLabelKey = "events.cloud.google.com/broker-cell"
+
+	// DefaultBrokerCellName is the name of the BrokerCell serving the Brokers and Channels
+	// without the BrokerCellLabelKey label.
+	DefaultBrokerCellName = "default"
+)
+
+// BrokerCellName returns the name of the BrokerCell serving the Broker or Channel.
+func BrokerCellName(obj metav1.Object) string {
+	if name := obj.GetLabels()[BrokerCellLabelKey]; name != "" {
+		return name
+	}
+	return DefaultBrokerCellName
+}
+
+// SetDefaultBrokerCell assigns the default BrokerCell to the Broker or Channel, unless it already
+// selects one.
+func SetDefaultBrokerCell(obj metav1.Object) {
+	labels := obj.GetLabels()
+	if labels[BrokerCellLabelKey] != "" {
+		return
+	}
+	if labels == nil {
+		labels = make(map[string]string, 1)
+	}
+	labels[BrokerCellLabelKey] = DefaultBrokerCellName
+	obj.SetLabels(labels)
+}
+
+// ValidateBrokerCellLabel validates the BrokerCell selected by the Broker or Channel, which must
+// be a valid BrokerCell name.
+func ValidateBrokerCellLabel(obj metav1.Object) *apis.FieldError {
+	name, ok := obj.GetLabels()[BrokerCellLabelKey]
+	if !ok {
+		return nil
+	}
+	if errs := validation.IsDNS1123Label(name); len(errs) > 0 {
+		fe := apis.ErrInvalidValue(name, apis.CurrentField)
+		fe.Details = strings.Join(errs, "; ")
+		return fe
+	}
+	return nil
+}
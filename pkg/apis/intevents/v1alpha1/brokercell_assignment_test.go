@@ -0,0 +1,65 @@
+/*
+Copyright 2021 Google LLC
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package v1alpha1
+
+import (
+	"testing"
+
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+)
+
+func TestBrokerCellAssignment(t *testing.T) {
+	for _, tc := range []struct {
+		name      string
+		labels    map[string]string
+		wantName  string
+		wantValid bool
+	}{{
+		name:      "no label",
+		wantName:  DefaultBrokerCellName,
+		wantValid: true,
+	}, {
+		name:      "empty label",
+		labels:    map[string]string{BrokerCellLabelKey: ""},
+		wantName:  DefaultBrokerCellName,
+		wantValid: false,
+	}, {
+		name:      "selected BrokerCell",
+		labels:    map[string]string{BrokerCellLabelKey: "team-a"},
+		wantName:  "team-a",
+		wantValid: true,
+	}, {
+		name:      "invalid BrokerCell name",
+		labels:    map[string]string{BrokerCellLabelKey: "Team_A"},
+		wantName:  "Team_A",
+		wantValid: false,
+	}} {
+		t.Run(tc.name, func(t *testing.T) {
+			obj := &metav1.ObjectMeta{Labels: tc.labels}
+			if got := BrokerCellName(obj); got != tc.wantName {
+				t.Errorf("BrokerCellName got=%q, want=%q", got, tc.wantName)
+			}
+			if err := ValidateBrokerCellLabel(obj); (err == nil) != tc.wantValid {
+				t.Errorf("ValidateBrokerCellLabel got=%v, want valid=%v", err, tc.wantValid)
+			}
+			SetDefaultBrokerCell(obj)
+			if got := obj.Labels[BrokerCellLabelKey]; got != tc.wantName {
+				t.Errorf("Defaulted BrokerCell label got=%q, want=%q", got, tc.wantName)
+			}
+		})
+	}
+}
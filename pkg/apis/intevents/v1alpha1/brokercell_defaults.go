@@ -19,6 +19,7 @@ package v1alpha1
 import (
 	"context"
 
+	"k8s.io/apimachinery/pkg/util/intstr"
 	"knative.dev/pkg/ptr"
 )
 
@@ -48,6 +49,7 @@ const (
 	memoryLimitRetry      string = "1500Mi"
 	minReplicas           int32  = 1
 	maxReplicas           int32  = 10
+	targetBacklog         int64  = 100
 )
 
 // SetDefaults sets the default field values for a BrokerCell.
@@ -63,16 +65,19 @@ func (bcs *BrokerCellSpec) SetDefaults(ctx context.Context) {
 		bcs.Components.Fanout = makeComponent(cpuRequestFanout, cpuLimitFanout, memoryRequestFanout, memoryLimitFanout, avgCPUUtilizationFanout, avgMemoryUsageFanout)
 	}
 	bcs.Components.Fanout.setAutoScalingDefaults()
+	bcs.Components.Fanout.setSchedulingDefaults()
 	// Ingress defaults
 	if bcs.Components.Ingress == nil {
 		bcs.Components.Ingress = makeComponent(cpuRequestIngress, cpuLimitIngress, memoryRequestIngress, memoryLimitIngress, avgCPUUtilizationIngress, avgMemoryUsageIngress)
 	}
 	bcs.Components.Ingress.setAutoScalingDefaults()
+	bcs.Components.Ingress.setSchedulingDefaults()
 	// Retry defaults
 	if bcs.Components.Retry == nil {
 		bcs.Components.Retry = makeComponent(cpuRequestRetry, cpuLimitRetry, memoryRequestRetry, memoryLimitRetry, avgCPUUtilizationRetry, avgMemoryUsageRetry)
 	}
 	bcs.Components.Retry.setAutoScalingDefaults()
+	bcs.Components.Retry.setSchedulingDefaults()
 }
 
 func makeComponent(cpuRequest, cpuLimit, memoryRequest, memoryLimit string, avgCPUUtilization int32, targetMemoryUsage string) *ComponentParameters {
@@ -93,4 +98,19 @@ func (componentParams *ComponentParameters) setAutoScalingDefaults() {
 	if componentParams.MaxReplicas == nil {
 		componentParams.MaxReplicas = ptr.Int32(maxReplicas)
 	}
+	if ba := componentParams.BacklogAutoscaling; ba != nil {
+		if ba.Class == "" {
+			ba.Class = BacklogAutoscalingExternalMetrics
+		}
+		if ba.TargetBacklog == nil {
+			ba.TargetBacklog = ptr.Int64(targetBacklog)
+		}
+	}
+}
+
+func (componentParams *ComponentParameters) setSchedulingDefaults() {
+	if pdb := componentParams.PodDisruptionBudget; pdb != nil && pdb.MinAvailable == nil && pdb.MaxUnavailable == nil {
+		maxUnavailable := intstr.FromInt(1)
+		pdb.MaxUnavailable = &maxUnavailable
+	}
 }
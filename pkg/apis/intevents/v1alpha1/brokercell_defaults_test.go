@@ -25,6 +25,7 @@ import (
 	"github.com/google/knative-gcp/pkg/apis/duck"
 	testingMetadataClient "github.com/google/knative-gcp/pkg/gclient/metadata/testing"
 	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/util/intstr"
 	"knative.dev/pkg/ptr"
 )
 
@@ -67,7 +68,7 @@ func TestBrokerCell_SetDefaults(t *testing.T) {
 				},
 			},
 			Spec: BrokerCellSpec{
-				ComponentsParametersSpec{
+				Components: ComponentsParametersSpec{
 					Fanout: &ComponentParameters{
 						CPURequest:        fanoutSpecPrefix + customCPURequest,
 						CPULimit:          fanoutSpecPrefix + customCPULimit,
@@ -108,7 +109,7 @@ func TestBrokerCell_SetDefaults(t *testing.T) {
 				},
 			},
 			Spec: BrokerCellSpec{
-				ComponentsParametersSpec{
+				Components: ComponentsParametersSpec{
 					Fanout: &ComponentParameters{
 						CPURequest:        fanoutSpecPrefix + customCPURequest,
 						CPULimit:          fanoutSpecPrefix + customCPULimit,
@@ -146,7 +147,7 @@ func TestBrokerCell_SetDefaults(t *testing.T) {
 		name: "Defaulting for resource specification is not applied when some of the parameters are specified",
 		start: &BrokerCell{
 			Spec: BrokerCellSpec{
-				ComponentsParametersSpec{
+				Components: ComponentsParametersSpec{
 					Fanout: &ComponentParameters{
 						CPURequest: "10000",
 					},
@@ -155,7 +156,7 @@ func TestBrokerCell_SetDefaults(t *testing.T) {
 		},
 		want: &BrokerCell{
 			Spec: BrokerCellSpec{
-				ComponentsParametersSpec{
+				Components: ComponentsParametersSpec{
 					Fanout: (&ComponentParameters{
 						CPURequest:        "10000",
 						CPULimit:          "",
@@ -173,7 +174,7 @@ func TestBrokerCell_SetDefaults(t *testing.T) {
 		name: "Defaulting for resource specification is not applied when a target CPU or memory parameter is specified",
 		start: &BrokerCell{
 			Spec: BrokerCellSpec{
-				ComponentsParametersSpec{
+				Components: ComponentsParametersSpec{
 					Fanout: &ComponentParameters{
 						AvgCPUUtilization: ptr.Int32(95),
 					},
@@ -182,7 +183,7 @@ func TestBrokerCell_SetDefaults(t *testing.T) {
 		},
 		want: &BrokerCell{
 			Spec: BrokerCellSpec{
-				ComponentsParametersSpec{
+				Components: ComponentsParametersSpec{
 					Fanout: (&ComponentParameters{
 						AvgCPUUtilization: ptr.Int32(95),
 						AvgMemoryUsage:    nil,
@@ -196,6 +197,69 @@ func TestBrokerCell_SetDefaults(t *testing.T) {
 				},
 			},
 		},
+	}, {
+		name: "Backlog autoscaling defaults",
+		start: &BrokerCell{
+			Spec: BrokerCellSpec{
+				Components: ComponentsParametersSpec{
+					Fanout: &ComponentParameters{
+						BacklogAutoscaling: &BacklogAutoscalingSpec{},
+					},
+					Retry: &ComponentParameters{
+						BacklogAutoscaling: &BacklogAutoscalingSpec{
+							Class:         BacklogAutoscalingExternalMetrics,
+							TargetBacklog: ptr.Int64(10),
+						},
+					},
+				},
+			},
+		},
+		want: &BrokerCell{
+			Spec: BrokerCellSpec{
+				Components: ComponentsParametersSpec{
+					Fanout: (&ComponentParameters{
+						BacklogAutoscaling: &BacklogAutoscalingSpec{
+							Class:         BacklogAutoscalingExternalMetrics,
+							TargetBacklog: ptr.Int64(targetBacklog),
+						},
+					}).WithDefaultReplicas(),
+					Ingress: makeComponent(cpuRequestIngress, cpuLimitIngress, memoryRequestIngress, memoryLimitIngress, avgCPUUtilizationIngress, avgMemoryUsageIngress).WithDefaultReplicas(),
+					Retry: (&ComponentParameters{
+						BacklogAutoscaling: &BacklogAutoscalingSpec{
+							Class:         BacklogAutoscalingExternalMetrics,
+							TargetBacklog: ptr.Int64(10),
+						},
+					}).WithDefaultReplicas(),
+				},
+			},
+		},
+	}, {
+		name: "PodDisruptionBudget defaults",
+		start: &BrokerCell{
+			Spec: BrokerCellSpec{
+				Components: ComponentsParametersSpec{
+					Fanout: &ComponentParameters{
+						PodDisruptionBudget: &PodDisruptionBudgetSpec{},
+					},
+					Retry: &ComponentParameters{
+						PodDisruptionBudget: &PodDisruptionBudgetSpec{MinAvailable: intOrStringPtr(intstr.FromString("50%"))},
+					},
+				},
+			},
+		},
+		want: &BrokerCell{
+			Spec: BrokerCellSpec{
+				Components: ComponentsParametersSpec{
+					Fanout: (&ComponentParameters{
+						PodDisruptionBudget: &PodDisruptionBudgetSpec{MaxUnavailable: intOrStringPtr(intstr.FromInt(1))},
+					}).WithDefaultReplicas(),
+					Ingress: makeComponent(cpuRequestIngress, cpuLimitIngress, memoryRequestIngress, memoryLimitIngress, avgCPUUtilizationIngress, avgMemoryUsageIngress).WithDefaultReplicas(),
+					Retry: (&ComponentParameters{
+						PodDisruptionBudget: &PodDisruptionBudgetSpec{MinAvailable: intOrStringPtr(intstr.FromString("50%"))},
+					}).WithDefaultReplicas(),
+				},
+			},
+		},
 	}}
 
 	for _, test := range tests {
@@ -221,3 +285,7 @@ func (componentParams *ComponentParameters) WithDefaultReplicas() *ComponentPara
 	componentParams.MaxReplicas = ptr.Int32(maxReplicas)
 	return componentParams
 }
+
+func intOrStringPtr(v intstr.IntOrString) *intstr.IntOrString {
+	return &v
+}
@@ -17,9 +17,11 @@ limitations under the License.
 package v1alpha1
 
 import (
+	corev1 "k8s.io/api/core/v1"
 	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
 	"k8s.io/apimachinery/pkg/runtime"
 	"k8s.io/apimachinery/pkg/runtime/schema"
+	"k8s.io/apimachinery/pkg/util/intstr"
 	"knative.dev/pkg/apis"
 	duckv1 "knative.dev/pkg/apis/duck/v1"
 	"knative.dev/pkg/kmeta"
@@ -109,6 +111,73 @@ type ComponentParameters struct {
 
 	// MaxReplicas specifies the maximum replica count for the component.
 	MaxReplicas *int32 `json:"maxReplicas,omitempty"`
+
+	// BacklogAutoscaling scales the component on the number of undelivered messages of its
+	// Pub/Sub subscriptions: the decouple subscriptions for fanout, and the retry
+	// subscriptions for retry. Not supported by ingress.
+	// +optional
+	BacklogAutoscaling *BacklogAutoscalingSpec `json:"backlogAutoscaling,omitempty"`
+
+	// PriorityClassName is the PriorityClass of the pods of the component.
+	// +optional
+	PriorityClassName string `json:"priorityClassName,omitempty"`
+
+	// NodeSelector restricts the pods of the component to the nodes with these labels.
+	// +optional
+	NodeSelector map[string]string `json:"nodeSelector,omitempty"`
+
+	// Tolerations allow the pods of the component to be scheduled on tainted nodes.
+	// +optional
+	Tolerations []corev1.Toleration `json:"tolerations,omitempty"`
+
+	// TopologySpreadConstraints spread the pods of the component across topology domains.
+	// Constraints without a label selector select the pods of the component.
+	// +optional
+	TopologySpreadConstraints []corev1.TopologySpreadConstraint `json:"topologySpreadConstraints,omitempty"`
+
+	// PodDisruptionBudget limits the number of pods of the component that are evicted at the
+	// same time. No PodDisruptionBudget is created if it is not specified.
+	// +optional
+	PodDisruptionBudget *PodDisruptionBudgetSpec `json:"podDisruptionBudget,omitempty"`
+}
+
+// PodDisruptionBudgetSpec specifies the PodDisruptionBudget of a component. At most one of
+// MinAvailable and MaxUnavailable can be specified.
+type PodDisruptionBudgetSpec struct {
+	// MinAvailable is the number or percentage of pods that must remain available.
+	// +optional
+	MinAvailable *intstr.IntOrString `json:"minAvailable,omitempty"`
+
+	// MaxUnavailable is the number or percentage of pods that can be unavailable. Defaults to 1
+	// if MinAvailable is not specified.
+	// +optional
+	MaxUnavailable *intstr.IntOrString `json:"maxUnavailable,omitempty"`
+}
+
+// BacklogAutoscalingClass is the autoscaler scaling a component on its backlog.
+type BacklogAutoscalingClass string
+
+const (
+	// BacklogAutoscalingKEDA scales the component with a KEDA ScaledObject, which replaces its
+	// HorizontalPodAutoscaler. KEDA reads one subscription per trigger, so the component is
+	// scaled on the largest backlog of its subscriptions rather than on their sum.
+	BacklogAutoscalingKEDA BacklogAutoscalingClass = "keda"
+	// BacklogAutoscalingExternalMetrics adds the backlog summed over the subscriptions of the
+	// component as an external metric of its HorizontalPodAutoscaler. The external metrics must
+	// be served by the Stackdriver custom metrics adapter.
+	BacklogAutoscalingExternalMetrics BacklogAutoscalingClass = "externalMetrics"
+)
+
+// BacklogAutoscalingSpec specifies how a component is scaled on its backlog.
+type BacklogAutoscalingSpec struct {
+	// Class is the autoscaler, either externalMetrics or keda. Defaults to externalMetrics.
+	// +optional
+	Class BacklogAutoscalingClass `json:"class,omitempty"`
+
+	// TargetBacklog is the number of undelivered messages per replica targeted by the
+	// autoscaler. Defaults to 100.
+	// +optional
+	TargetBacklog *int64 `json:"targetBacklog,omitempty"`
 }
 
 // ComponentsParametersSpec specifies separate parameters for each component
@@ -124,6 +193,68 @@ type BrokerCellSpec struct {
 	// Components specifies parameters of each component (fanout, ingress,
 	// retry) of a BrokerCell.
 	Components ComponentsParametersSpec `json:"components,omitempty"`
+
+	// IngressFiltering specifies whether the ingress drops the events that none of the
+	// Triggers of their Broker is interested in, instead of publishing them to the
+	// Broker's decouple topic. Defaults to false.
+	// +optional
+	IngressFiltering *bool `json:"ingressFiltering,omitempty"`
+
+	// Sharding specifies whether the Brokers and the Triggers are split between the replicas
+	// of the fanout and retry components, instead of being handled by each replica. The
+	// replicas keep track of each other with Leases. Defaults to false.
+	// +optional
+	Sharding *bool `json:"sharding,omitempty"`
+
+	// TargetsConfig specifies how the targets config of the BrokerCell is stored in ConfigMaps.
+	// By default, it is stored uncompressed in a single ConfigMap, which cannot hold more than
+	// a few thousand Triggers.
+	// +optional
+	TargetsConfig *TargetsConfigSpec `json:"targetsConfig,omitempty"`
+
+	// ClaimCheckBuckets are the Cloud Storage buckets the Brokers of the BrokerCell may store
+	// their payloads in with a claim check. The controller deletes the expired payloads of the
+	// Brokers with its own credentials, so only the buckets the cluster admins trust with them
+	// should be allowed. The claim checks of other buckets are ignored. No bucket is allowed by
+	// default.
+	// +optional
+	ClaimCheckBuckets []string `json:"claimCheckBuckets,omitempty"`
+}
+
+// ClaimCheckBucketAllowed returns whether the Brokers of the BrokerCell may store their payloads in
+// the bucket.
+func (bc *BrokerCell) ClaimCheckBucketAllowed(bucket string) bool {
+	for _, b := range bc.Spec.ClaimCheckBuckets {
+		if b == bucket {
+			return true
+		}
+	}
+	return false
+}
+
+// TargetsConfigCompression is the compression of the targets config ConfigMaps.
+type TargetsConfigCompression string
+
+const (
+	// TargetsConfigCompressionNone stores the targets config uncompressed.
+	TargetsConfigCompressionNone TargetsConfigCompression = "none"
+	// TargetsConfigCompressionGzip compresses the targets config with gzip.
+	TargetsConfigCompressionGzip TargetsConfigCompression = "gzip"
+
+	// MaxTargetsConfigShards is the maximum number of ConfigMaps holding the targets config.
+	MaxTargetsConfigShards = 64
+)
+
+// TargetsConfigSpec specifies how the targets config of a BrokerCell is split between ConfigMaps.
+type TargetsConfigSpec struct {
+	// Shards is the number of ConfigMaps the targets config is split into, each of them holding
+	// the Brokers and Channels whose key hashes to it. Defaults to 1.
+	// +optional
+	Shards *int32 `json:"shards,omitempty"`
+
+	// Compression is the compression of the ConfigMaps, either none or gzip. Defaults to none.
+	// +optional
+	Compression TargetsConfigCompression `json:"compression,omitempty"`
 }
 
 // BrokerCellStatus represents the current state of a BrokerCell.
@@ -64,3 +64,16 @@ func TestBrokerCell_GetStatus(t *testing.T) {
 		t.Errorf("GetStatus=%v, want=%v", got, want)
 	}
 }
+
+func TestBrokerCell_ClaimCheckBucketAllowed(t *testing.T) {
+	bc := &BrokerCell{Spec: BrokerCellSpec{ClaimCheckBuckets: []string{"payloads"}}}
+	if !bc.ClaimCheckBucketAllowed("payloads") {
+		t.Error("ClaimCheckBucketAllowed(payloads) = false, want true")
+	}
+	if bc.ClaimCheckBucketAllowed("other") {
+		t.Error("ClaimCheckBucketAllowed(other) = true, want false")
+	}
+	if (&BrokerCell{}).ClaimCheckBucketAllowed("payloads") {
+		t.Error("ClaimCheckBucketAllowed(payloads) = true without allowed buckets, want false")
+	}
+}
@@ -19,9 +19,14 @@ package v1alpha1
 import (
 	"context"
 	"fmt"
+	"strconv"
+	"strings"
 
 	resourceutil "github.com/google/knative-gcp/pkg/utils/resource"
+	corev1 "k8s.io/api/core/v1"
 	"k8s.io/apimachinery/pkg/api/resource"
+	"k8s.io/apimachinery/pkg/util/intstr"
+	"k8s.io/apimachinery/pkg/util/validation"
 	"knative.dev/pkg/apis"
 )
 
@@ -38,10 +43,34 @@ func (bcs *BrokerCellSpec) Validate(ctx context.Context) *apis.FieldError {
 	}
 	if bcs.Components.Ingress != nil {
 		fieldErrors = bcs.Components.Ingress.ValidateResourceRequirementSpecification(fieldErrors, "components.ingress")
+		if bcs.Components.Ingress.BacklogAutoscaling != nil {
+			fieldErrors = fieldErrors.Also(apis.ErrDisallowedFields("components.ingress.backlogAutoscaling"))
+		}
 	}
 	if bcs.Components.Retry != nil {
 		fieldErrors = bcs.Components.Retry.ValidateResourceRequirementSpecification(fieldErrors, "components.retry")
 	}
+	if bcs.TargetsConfig != nil {
+		fieldErrors = fieldErrors.Also(bcs.TargetsConfig.Validate(ctx).ViaField("targetsConfig"))
+	}
+	for i, bucket := range bcs.ClaimCheckBuckets {
+		if bucket == "" {
+			fieldErrors = fieldErrors.Also(apis.ErrInvalidArrayValue(bucket, "claimCheckBuckets", i))
+		}
+	}
+	return fieldErrors
+}
+
+func (tcs *TargetsConfigSpec) Validate(_ context.Context) *apis.FieldError {
+	var fieldErrors *apis.FieldError
+	if tcs.Shards != nil && (*tcs.Shards < 1 || *tcs.Shards > MaxTargetsConfigShards) {
+		fieldErrors = fieldErrors.Also(apis.ErrOutOfBoundsValue(*tcs.Shards, 1, MaxTargetsConfigShards, "shards"))
+	}
+	switch tcs.Compression {
+	case "", TargetsConfigCompressionNone, TargetsConfigCompressionGzip:
+	default:
+		fieldErrors = fieldErrors.Also(apis.ErrInvalidValue(tcs.Compression, "compression"))
+	}
 	return fieldErrors
 }
 
@@ -49,6 +78,7 @@ func (componentParams *ComponentParameters) ValidateResourceRequirementSpecifica
 	fieldErrors = componentParams.ValidateQuantityFormats(fieldErrors, componentPath)
 	fieldErrors = componentParams.ValidateResourceSpecification(fieldErrors, componentPath)
 	fieldErrors = componentParams.ValidateAutoscalingSpecification(fieldErrors, componentPath)
+	fieldErrors = componentParams.ValidateSchedulingSpecification(fieldErrors, componentPath)
 	return fieldErrors
 }
 
@@ -92,7 +122,7 @@ func (componentParams *ComponentParameters) ValidateAutoscalingSpecification(fie
 	// At least one of the autoscaling metrics should be specified
 	// TODO: consider adjusting this rule (https://github.com/google/knative-gcp/issues/1632)
 	isAvgMemoryUsageSpecified := componentParams.AvgMemoryUsage != nil && *componentParams.AvgMemoryUsage != ""
-	if componentParams.AvgCPUUtilization == nil && !isAvgMemoryUsageSpecified {
+	if componentParams.AvgCPUUtilization == nil && !isAvgMemoryUsageSpecified && componentParams.BacklogAutoscaling == nil {
 		invalidValueError := apis.ErrInvalidValue(nil, componentPath)
 		invalidValueError.Details = "At least one of the autoscaling metrics (avgCPUUtilization, avgMemoryUsage) should be specified"
 		fieldErrors = fieldErrors.Also(invalidValueError)
@@ -102,6 +132,22 @@ func (componentParams *ComponentParameters) ValidateAutoscalingSpecification(fie
 		invalidValueError.Details = "minReplicas value can not exceed the value of maxReplicas"
 		fieldErrors = fieldErrors.Also(invalidValueError)
 	}
+	if componentParams.BacklogAutoscaling != nil {
+		fieldErrors = fieldErrors.Also(componentParams.BacklogAutoscaling.Validate().ViaField(componentPath, "backlogAutoscaling"))
+	}
+	return fieldErrors
+}
+
+func (bas *BacklogAutoscalingSpec) Validate() *apis.FieldError {
+	var fieldErrors *apis.FieldError
+	switch bas.Class {
+	case "", BacklogAutoscalingKEDA, BacklogAutoscalingExternalMetrics:
+	default:
+		fieldErrors = fieldErrors.Also(apis.ErrInvalidValue(bas.Class, "class"))
+	}
+	if bas.TargetBacklog != nil && *bas.TargetBacklog < 1 {
+		fieldErrors = fieldErrors.Also(apis.ErrInvalidValue(*bas.TargetBacklog, "targetBacklog"))
+	}
 	return fieldErrors
 }
 
@@ -141,3 +187,93 @@ func (componentParams *ComponentParameters) ValidateQuantityFormats(fieldErrors
 	}
 	return fieldErrors
 }
+
+func (componentParams *ComponentParameters) ValidateSchedulingSpecification(fieldErrors *apis.FieldError, componentPath string) *apis.FieldError {
+	var errs *apis.FieldError
+	if name := componentParams.PriorityClassName; name != "" && len(validation.IsDNS1123Subdomain(name)) > 0 {
+		errs = errs.Also(apis.ErrInvalidValue(name, "priorityClassName"))
+	}
+	for k, v := range componentParams.NodeSelector {
+		if len(validation.IsQualifiedName(k)) > 0 || len(validation.IsValidLabelValue(v)) > 0 {
+			errs = errs.Also(apis.ErrInvalidValue(v, apis.CurrentField).ViaFieldKey("nodeSelector", k))
+		}
+	}
+	for i, toleration := range componentParams.Tolerations {
+		errs = errs.Also(validateToleration(toleration).ViaFieldIndex("tolerations", i))
+	}
+	for i, constraint := range componentParams.TopologySpreadConstraints {
+		errs = errs.Also(validateTopologySpreadConstraint(constraint).ViaFieldIndex("topologySpreadConstraints", i))
+	}
+	if componentParams.PodDisruptionBudget != nil {
+		errs = errs.Also(componentParams.PodDisruptionBudget.Validate().ViaField("podDisruptionBudget"))
+	}
+	return fieldErrors.Also(errs.ViaField(componentPath))
+}
+
+func validateToleration(toleration corev1.Toleration) *apis.FieldError {
+	var errs *apis.FieldError
+	if toleration.Key != "" && len(validation.IsQualifiedName(toleration.Key)) > 0 {
+		errs = errs.Also(apis.ErrInvalidValue(toleration.Key, "key"))
+	}
+	switch toleration.Operator {
+	case corev1.TolerationOpExists:
+		if toleration.Value != "" {
+			errs = errs.Also(apis.ErrDisallowedFields("value"))
+		}
+	case "", corev1.TolerationOpEqual:
+		if toleration.Key == "" {
+			errs = errs.Also(apis.ErrMissingField("key"))
+		}
+	default:
+		errs = errs.Also(apis.ErrInvalidValue(toleration.Operator, "operator"))
+	}
+	switch toleration.Effect {
+	case "", corev1.TaintEffectNoSchedule, corev1.TaintEffectPreferNoSchedule, corev1.TaintEffectNoExecute:
+	default:
+		errs = errs.Also(apis.ErrInvalidValue(toleration.Effect, "effect"))
+	}
+	return errs
+}
+
+func validateTopologySpreadConstraint(constraint corev1.TopologySpreadConstraint) *apis.FieldError {
+	var errs *apis.FieldError
+	if constraint.MaxSkew < 1 {
+		errs = errs.Also(apis.ErrInvalidValue(constraint.MaxSkew, "maxSkew"))
+	}
+	if constraint.TopologyKey == "" {
+		errs = errs.Also(apis.ErrMissingField("topologyKey"))
+	}
+	switch constraint.WhenUnsatisfiable {
+	case corev1.DoNotSchedule, corev1.ScheduleAnyway:
+	default:
+		errs = errs.Also(apis.ErrInvalidValue(constraint.WhenUnsatisfiable, "whenUnsatisfiable"))
+	}
+	return errs
+}
+
+func (pdbs *PodDisruptionBudgetSpec) Validate() *apis.FieldError {
+	if pdbs.MinAvailable != nil && pdbs.MaxUnavailable != nil {
+		return apis.ErrMultipleOneOf("minAvailable", "maxUnavailable")
+	}
+	var errs *apis.FieldError
+	if pdbs.MinAvailable != nil && !isValidIntOrPercent(*pdbs.MinAvailable) {
+		errs = errs.Also(apis.ErrInvalidValue(pdbs.MinAvailable.String(), "minAvailable"))
+	}
+	if pdbs.MaxUnavailable != nil && !isValidIntOrPercent(*pdbs.MaxUnavailable) {
+		errs = errs.Also(apis.ErrInvalidValue(pdbs.MaxUnavailable.String(), "maxUnavailable"))
+	}
+	return errs
+}
+
+// isValidIntOrPercent returns whether v is a non-negative integer or a percentage between 0%
+// and 100%.
+func isValidIntOrPercent(v intstr.IntOrString) bool {
+	if v.Type == intstr.Int {
+		return v.IntVal >= 0
+	}
+	if !strings.HasSuffix(v.StrVal, "%") {
+		return false
+	}
+	percent, err := strconv.Atoi(strings.TrimSuffix(v.StrVal, "%"))
+	return err == nil && percent >= 0 && percent <= 100
+}
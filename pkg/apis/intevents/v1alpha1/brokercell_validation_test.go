@@ -21,6 +21,8 @@ import (
 	"testing"
 
 	"github.com/google/go-cmp/cmp"
+	corev1 "k8s.io/api/core/v1"
+	"k8s.io/apimachinery/pkg/util/intstr"
 	"knative.dev/pkg/apis"
 	"knative.dev/pkg/ptr"
 )
@@ -180,6 +182,137 @@ func TestBrokerCell_Validate(t *testing.T) {
 			},
 			want: nil,
 		},
+		{
+			name: "Sharded and compressed targets config",
+			brokerCell: BrokerCell{
+				Spec: (func() BrokerCellSpec {
+					spec := MakeDefaultBrokerCellSpec()
+					spec.TargetsConfig = &TargetsConfigSpec{
+						Shards:      ptr.Int32(8),
+						Compression: TargetsConfigCompressionGzip,
+					}
+					return spec
+				}()),
+			},
+			want: nil,
+		},
+		{
+			name: "Invalid targets config",
+			brokerCell: BrokerCell{
+				Spec: (func() BrokerCellSpec {
+					spec := MakeDefaultBrokerCellSpec()
+					spec.TargetsConfig = &TargetsConfigSpec{
+						Shards:      ptr.Int32(0),
+						Compression: "zstd",
+					}
+					return spec
+				}()),
+			},
+			want: func() *apis.FieldError {
+				var fieldErrors *apis.FieldError
+				fieldErrors = fieldErrors.Also(apis.ErrOutOfBoundsValue(0, 1, MaxTargetsConfigShards, "spec.targetsConfig.shards"))
+				fieldErrors = fieldErrors.Also(apis.ErrInvalidValue("zstd", "spec.targetsConfig.compression"))
+				return fieldErrors
+			}(),
+		},
+		{
+			name: "Invalid claim check bucket",
+			brokerCell: BrokerCell{
+				Spec: (func() BrokerCellSpec {
+					spec := MakeDefaultBrokerCellSpec()
+					spec.ClaimCheckBuckets = []string{"payloads", ""}
+					return spec
+				}()),
+			},
+			want: apis.ErrInvalidArrayValue("", "spec.claimCheckBuckets", 1),
+		},
+		{
+			name: "Backlog autoscaling is an autoscaling metric",
+			brokerCell: BrokerCell{
+				Spec: (func() BrokerCellSpec {
+					spec := MakeDefaultBrokerCellSpec()
+					spec.Components.Fanout.AvgCPUUtilization = nil
+					spec.Components.Fanout.AvgMemoryUsage = nil
+					spec.Components.Fanout.BacklogAutoscaling = &BacklogAutoscalingSpec{Class: BacklogAutoscalingKEDA, TargetBacklog: ptr.Int64(10)}
+					spec.Components.Retry.BacklogAutoscaling = &BacklogAutoscalingSpec{Class: BacklogAutoscalingExternalMetrics}
+					return spec
+				}()),
+			},
+			want: nil,
+		},
+		{
+			name: "Invalid backlog autoscaling",
+			brokerCell: BrokerCell{
+				Spec: (func() BrokerCellSpec {
+					spec := MakeDefaultBrokerCellSpec()
+					spec.Components.Ingress.BacklogAutoscaling = &BacklogAutoscalingSpec{}
+					spec.Components.Retry.BacklogAutoscaling = &BacklogAutoscalingSpec{Class: "prometheus", TargetBacklog: ptr.Int64(0)}
+					return spec
+				}()),
+			},
+			want: func() *apis.FieldError {
+				var fieldErrors *apis.FieldError
+				fieldErrors = fieldErrors.Also(apis.ErrDisallowedFields("spec.components.ingress.backlogAutoscaling"))
+				fieldErrors = fieldErrors.Also(apis.ErrInvalidValue("prometheus", "spec.components.retry.backlogAutoscaling.class"))
+				fieldErrors = fieldErrors.Also(apis.ErrInvalidValue(0, "spec.components.retry.backlogAutoscaling.targetBacklog"))
+				return fieldErrors
+			}(),
+		},
+		{
+			name: "Scheduling and disruption controls",
+			brokerCell: BrokerCell{
+				Spec: (func() BrokerCellSpec {
+					spec := MakeDefaultBrokerCellSpec()
+					minAvailable := intstr.FromString("50%")
+					spec.Components.Fanout.PriorityClassName = "broker-priority"
+					spec.Components.Fanout.NodeSelector = map[string]string{"cloud.google.com/gke-nodepool": "broker"}
+					spec.Components.Fanout.Tolerations = []corev1.Toleration{{
+						Key:      "dedicated",
+						Operator: corev1.TolerationOpEqual,
+						Value:    "broker",
+						Effect:   corev1.TaintEffectNoSchedule,
+					}}
+					spec.Components.Fanout.TopologySpreadConstraints = []corev1.TopologySpreadConstraint{{
+						MaxSkew:           1,
+						TopologyKey:       "topology.kubernetes.io/zone",
+						WhenUnsatisfiable: corev1.ScheduleAnyway,
+					}}
+					spec.Components.Fanout.PodDisruptionBudget = &PodDisruptionBudgetSpec{MinAvailable: &minAvailable}
+					return spec
+				}()),
+			},
+			want: nil,
+		},
+		{
+			name: "Invalid scheduling and disruption controls",
+			brokerCell: BrokerCell{
+				Spec: (func() BrokerCellSpec {
+					spec := MakeDefaultBrokerCellSpec()
+					minAvailable := intstr.FromInt(1)
+					maxUnavailable := intstr.FromString("150%")
+					spec.Components.Fanout.PriorityClassName = "Broker_Priority"
+					spec.Components.Fanout.NodeSelector = map[string]string{"pool": "a b"}
+					spec.Components.Fanout.Tolerations = []corev1.Toleration{{Operator: "In", Effect: "NoWay"}}
+					spec.Components.Fanout.TopologySpreadConstraints = []corev1.TopologySpreadConstraint{{WhenUnsatisfiable: "Maybe"}}
+					spec.Components.Fanout.PodDisruptionBudget = &PodDisruptionBudgetSpec{MinAvailable: &minAvailable, MaxUnavailable: &maxUnavailable}
+					spec.Components.Retry.PodDisruptionBudget = &PodDisruptionBudgetSpec{MaxUnavailable: &maxUnavailable}
+					return spec
+				}()),
+			},
+			want: func() *apis.FieldError {
+				var fieldErrors *apis.FieldError
+				fieldErrors = fieldErrors.Also(apis.ErrInvalidValue("Broker_Priority", "spec.components.fanout.priorityClassName"))
+				fieldErrors = fieldErrors.Also(apis.ErrInvalidValue("a b", "spec.components.fanout.nodeSelector[pool]"))
+				fieldErrors = fieldErrors.Also(apis.ErrInvalidValue("In", "spec.components.fanout.tolerations[0].operator"))
+				fieldErrors = fieldErrors.Also(apis.ErrInvalidValue("NoWay", "spec.components.fanout.tolerations[0].effect"))
+				fieldErrors = fieldErrors.Also(apis.ErrInvalidValue(0, "spec.components.fanout.topologySpreadConstraints[0].maxSkew"))
+				fieldErrors = fieldErrors.Also(apis.ErrMissingField("spec.components.fanout.topologySpreadConstraints[0].topologyKey"))
+				fieldErrors = fieldErrors.Also(apis.ErrInvalidValue("Maybe", "spec.components.fanout.topologySpreadConstraints[0].whenUnsatisfiable"))
+				fieldErrors = fieldErrors.Also(apis.ErrMultipleOneOf("spec.components.fanout.podDisruptionBudget.minAvailable", "spec.components.fanout.podDisruptionBudget.maxUnavailable"))
+				fieldErrors = fieldErrors.Also(apis.ErrInvalidValue("150%", "spec.components.retry.podDisruptionBudget.maxUnavailable"))
+				return fieldErrors
+			}(),
+		},
 	}
 
 	for _, test := range tests {
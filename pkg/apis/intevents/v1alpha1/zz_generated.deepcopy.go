@@ -21,9 +21,32 @@ limitations under the License.
 package v1alpha1
 
 import (
+	v1 "k8s.io/api/core/v1"
 	runtime "k8s.io/apimachinery/pkg/runtime"
+	intstr "k8s.io/apimachinery/pkg/util/intstr"
 )
 
+// DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
+func (in *BacklogAutoscalingSpec) DeepCopyInto(out *BacklogAutoscalingSpec) {
+	*out = *in
+	if in.TargetBacklog != nil {
+		in, out := &in.TargetBacklog, &out.TargetBacklog
+		*out = new(int64)
+		**out = **in
+	}
+	return
+}
+
+// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new BacklogAutoscalingSpec.
+func (in *BacklogAutoscalingSpec) DeepCopy() *BacklogAutoscalingSpec {
+	if in == nil {
+		return nil
+	}
+	out := new(BacklogAutoscalingSpec)
+	in.DeepCopyInto(out)
+	return out
+}
+
 // DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
 func (in *BrokerCell) DeepCopyInto(out *BrokerCell) {
 	*out = *in
@@ -89,6 +112,26 @@ func (in *BrokerCellList) DeepCopyObject() runtime.Object {
 func (in *BrokerCellSpec) DeepCopyInto(out *BrokerCellSpec) {
 	*out = *in
 	in.Components.DeepCopyInto(&out.Components)
+	if in.IngressFiltering != nil {
+		in, out := &in.IngressFiltering, &out.IngressFiltering
+		*out = new(bool)
+		**out = **in
+	}
+	if in.Sharding != nil {
+		in, out := &in.Sharding, &out.Sharding
+		*out = new(bool)
+		**out = **in
+	}
+	if in.TargetsConfig != nil {
+		in, out := &in.TargetsConfig, &out.TargetsConfig
+		*out = new(TargetsConfigSpec)
+		(*in).DeepCopyInto(*out)
+	}
+	if in.ClaimCheckBuckets != nil {
+		in, out := &in.ClaimCheckBuckets, &out.ClaimCheckBuckets
+		*out = make([]string, len(*in))
+		copy(*out, *in)
+	}
 	return
 }
 
@@ -142,6 +185,37 @@ func (in *ComponentParameters) DeepCopyInto(out *ComponentParameters) {
 		*out = new(int32)
 		**out = **in
 	}
+	if in.BacklogAutoscaling != nil {
+		in, out := &in.BacklogAutoscaling, &out.BacklogAutoscaling
+		*out = new(BacklogAutoscalingSpec)
+		(*in).DeepCopyInto(*out)
+	}
+	if in.NodeSelector != nil {
+		in, out := &in.NodeSelector, &out.NodeSelector
+		*out = make(map[string]string, len(*in))
+		for key, val := range *in {
+			(*out)[key] = val
+		}
+	}
+	if in.Tolerations != nil {
+		in, out := &in.Tolerations, &out.Tolerations
+		*out = make([]v1.Toleration, len(*in))
+		for i := range *in {
+			(*in)[i].DeepCopyInto(&(*out)[i])
+		}
+	}
+	if in.TopologySpreadConstraints != nil {
+		in, out := &in.TopologySpreadConstraints, &out.TopologySpreadConstraints
+		*out = make([]v1.TopologySpreadConstraint, len(*in))
+		for i := range *in {
+			(*in)[i].DeepCopyInto(&(*out)[i])
+		}
+	}
+	if in.PodDisruptionBudget != nil {
+		in, out := &in.PodDisruptionBudget, &out.PodDisruptionBudget
+		*out = new(PodDisruptionBudgetSpec)
+		(*in).DeepCopyInto(*out)
+	}
 	return
 }
 
@@ -186,6 +260,32 @@ func (in *ComponentsParametersSpec) DeepCopy() *ComponentsParametersSpec {
 	return out
 }
 
+// DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
+func (in *PodDisruptionBudgetSpec) DeepCopyInto(out *PodDisruptionBudgetSpec) {
+	*out = *in
+	if in.MinAvailable != nil {
+		in, out := &in.MinAvailable, &out.MinAvailable
+		*out = new(intstr.IntOrString)
+		**out = **in
+	}
+	if in.MaxUnavailable != nil {
+		in, out := &in.MaxUnavailable, &out.MaxUnavailable
+		*out = new(intstr.IntOrString)
+		**out = **in
+	}
+	return
+}
+
+// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new PodDisruptionBudgetSpec.
+func (in *PodDisruptionBudgetSpec) DeepCopy() *PodDisruptionBudgetSpec {
+	if in == nil {
+		return nil
+	}
+	out := new(PodDisruptionBudgetSpec)
+	in.DeepCopyInto(out)
+	return out
+}
+
 // DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
 func (in *ResourceSpecification) DeepCopyInto(out *ResourceSpecification) {
 	*out = *in
@@ -219,3 +319,24 @@ func (in *SystemResource) DeepCopy() *SystemResource {
 	in.DeepCopyInto(out)
 	return out
 }
+
+// DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
+func (in *TargetsConfigSpec) DeepCopyInto(out *TargetsConfigSpec) {
+	*out = *in
+	if in.Shards != nil {
+		in, out := &in.Shards, &out.Shards
+		*out = new(int32)
+		**out = **in
+	}
+	return
+}
+
+// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new TargetsConfigSpec.
+func (in *TargetsConfigSpec) DeepCopy() *TargetsConfigSpec {
+	if in == nil {
+		return nil
+	}
+	out := new(TargetsConfigSpec)
+	in.DeepCopyInto(out)
+	return out
+}
@@ -41,6 +41,9 @@ func TestChannelDefaults(t *testing.T) {
 			in: Channel{},
 			want: Channel{
 				ObjectMeta: v1.ObjectMeta{
+					Labels: map[string]string{
+						"events.cloud.google.com/broker-cell": "default",
+					},
 					Annotations: map[string]string{
 						"messaging.knative.dev/subscribable": "v1beta1",
 					},
@@ -58,6 +61,29 @@ func TestChannelDefaults(t *testing.T) {
 			},
 			want: Channel{
 				ObjectMeta: v1.ObjectMeta{
+					Labels: map[string]string{
+						"events.cloud.google.com/broker-cell": "default",
+					},
+					Annotations: map[string]string{
+						"messaging.knative.dev/subscribable": "v1beta1",
+					},
+				},
+				Spec: ChannelSpec{},
+			},
+		},
+		"with a BrokerCell": {
+			in: Channel{
+				ObjectMeta: v1.ObjectMeta{
+					Labels: map[string]string{
+						"events.cloud.google.com/broker-cell": "team",
+					},
+				},
+			},
+			want: Channel{
+				ObjectMeta: v1.ObjectMeta{
+					Labels: map[string]string{
+						"events.cloud.google.com/broker-cell": "team",
+					},
 					Annotations: map[string]string{
 						"messaging.knative.dev/subscribable": "v1beta1",
 					},
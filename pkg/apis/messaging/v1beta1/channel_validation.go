@@ -23,11 +23,13 @@ import (
 	brokerv1 "github.com/google/knative-gcp/pkg/apis/broker/v1"
 
 	"github.com/google/knative-gcp/pkg/apis/duck"
+	inteventsv1alpha1 "github.com/google/knative-gcp/pkg/apis/intevents/v1alpha1"
 	"knative.dev/pkg/apis"
 )
 
 func (c *Channel) Validate(ctx context.Context) *apis.FieldError {
-	err := c.Spec.Validate(ctx).ViaField("spec")
+	err := c.Spec.Validate(ctx).ViaField("spec").
+		Also(inteventsv1alpha1.ValidateBrokerCellLabel(c).ViaFieldKey("labels", inteventsv1alpha1.BrokerCellLabelKey).ViaField("metadata"))
 
 	if apis.IsInUpdate(ctx) {
 		original := apis.GetBaseline(ctx).(*Channel)
@@ -0,0 +1,164 @@
+/*
+Copyright 2021 Google LLC
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+// Package claimcheck stores the large payloads of the broker events in Cloud Storage, so that
+// only a reference to the payload goes through Pub/Sub.
+package claimcheck
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"io/ioutil"
+	"strings"
+	"time"
+
+	"cloud.google.com/go/storage"
+	"github.com/cloudevents/sdk-go/v2/event"
+	"github.com/google/uuid"
+	"google.golang.org/api/iterator"
+
+	"github.com/google/knative-gcp/pkg/broker/config"
+	gstorage "github.com/google/knative-gcp/pkg/gclient/storage"
+)
+
+const (
+	// ReferenceExtension is the extension holding the location of the payload of an event stored
+	// in Cloud Storage, as a gs://bucket/object URL. It is removed once the payload is restored.
+	ReferenceExtension = "kgcpclaimcheck"
+
+	referencePrefix = "gs://"
+)
+
+var (
+	// ErrNoStore is returned when restoring the payload of an event without a Store.
+	ErrNoStore = errors.New("no claim check store to restore the payload")
+	// ErrForeignReference is returned when restoring the payload of an event whose reference is
+	// not in the claim check of its broker.
+	ErrForeignReference = errors.New("claim check reference outside of the broker claim check")
+)
+
+// Store offloads the payloads of the events to Cloud Storage and restores them.
+type Store struct {
+	client gstorage.Client
+}
+
+// NewStore creates a Store backed by the storage client.
+func NewStore(client gstorage.Client) *Store {
+	return &Store{client: client}
+}
+
+// Offload stores the payload of the event sent to the broker in the bucket of the claim check if
+// it is larger than the threshold, and replaces it with a reference. The content type of the
+// payload is kept in the event. The reference set by the sender of the event, if any, is always
+// removed, so that only the payloads stored by Offload are restored. Otherwise, a nil Store or
+// claim check leaves the event unchanged.
+func (s *Store) Offload(ctx context.Context, cc *config.ClaimCheck, broker *config.CellTenantKey, e *event.Event) error {
+	e.SetExtension(ReferenceExtension, nil)
+	if s == nil || cc == nil || int64(len(e.Data())) <= cc.ThresholdBytes {
+		return nil
+	}
+	name := objectPrefix(broker) + uuid.New().String()
+	w := s.client.Bucket(cc.Bucket).Object(name).NewWriter(ctx)
+	if _, err := w.Write(e.Data()); err != nil {
+		w.Close()
+		return fmt.Errorf("failed to store the payload of the event: %w", err)
+	}
+	if err := w.Close(); err != nil {
+		return fmt.Errorf("failed to store the payload of the event: %w", err)
+	}
+	e.SetExtension(ReferenceExtension, referencePrefix+cc.Bucket+"/"+name)
+	e.DataEncoded = nil
+	e.DataBase64 = false
+	return nil
+}
+
+// Rehydrate returns the event of the broker with its payload restored from Cloud Storage. Events
+// without a reference are returned as is. The reference must be in the bucket of the claim check
+// of the broker, under the prefix of the broker, so that the events do not read other objects
+// with the credentials of the data plane. The given event is not modified, so that it can be sent
+// again with its reference.
+func (s *Store) Rehydrate(ctx context.Context, cc *config.ClaimCheck, broker *config.CellTenantKey, e *event.Event) (*event.Event, error) {
+	ref, ok := e.Extensions()[ReferenceExtension].(string)
+	if !ok {
+		return e, nil
+	}
+	if s == nil {
+		return nil, ErrNoStore
+	}
+	bucket, name, err := parseReference(ref)
+	if err != nil {
+		return nil, err
+	}
+	// The objects of the broker are directly under its prefix, which may be the prefix of
+	// another cell tenant, e.g. the broker "ns" of the namespace "channel".
+	prefix := objectPrefix(broker)
+	if cc == nil || bucket != cc.Bucket || !strings.HasPrefix(name, prefix) || strings.Contains(name[len(prefix):], "/") {
+		return nil, fmt.Errorf("%w: %q", ErrForeignReference, ref)
+	}
+	r, err := s.client.Bucket(bucket).Object(name).NewReader(ctx)
+	if err != nil {
+		return nil, fmt.Errorf("failed to read the payload of the event from %q: %w", ref, err)
+	}
+	defer r.Close()
+	data, err := ioutil.ReadAll(r)
+	if err != nil {
+		return nil, fmt.Errorf("failed to read the payload of the event from %q: %w", ref, err)
+	}
+	rehydrated := e.Clone()
+	rehydrated.SetExtension(ReferenceExtension, nil)
+	rehydrated.DataEncoded = data
+	return &rehydrated, nil
+}
+
+// DeletePayloads deletes the payloads of the broker stored in the bucket before the given time, or
+// all of them if the time is zero. It returns the number of deleted payloads.
+func DeletePayloads(ctx context.Context, client gstorage.Client, bucket string, broker *config.CellTenantKey, before time.Time) (int, error) {
+	b := client.Bucket(bucket)
+	prefix := objectPrefix(broker)
+	it := b.Objects(ctx, &storage.Query{Prefix: prefix})
+	deleted := 0
+	for {
+		attrs, err := it.Next()
+		if err == iterator.Done {
+			return deleted, nil
+		}
+		if err != nil {
+			return deleted, fmt.Errorf("failed to list the payloads of the broker: %w", err)
+		}
+		// Skip the objects of other cell tenants whose prefix starts with the one of the broker.
+		if strings.Contains(attrs.Name[len(prefix):], "/") || (!before.IsZero() && !attrs.Created.Before(before)) {
+			continue
+		}
+		if err := b.Object(attrs.Name).Delete(ctx); err != nil && !errors.Is(err, storage.ErrObjectNotExist) {
+			return deleted, fmt.Errorf("failed to delete the payload %q: %w", attrs.Name, err)
+		}
+		deleted++
+	}
+}
+
+// objectPrefix returns the prefix of the names of the objects storing the payloads of the broker.
+func objectPrefix(broker *config.CellTenantKey) string {
+	return broker.PersistenceString() + "/"
+}
+
+func parseReference(ref string) (bucket, name string, err error) {
+	parts := strings.SplitN(strings.TrimPrefix(ref, referencePrefix), "/", 2)
+	if !strings.HasPrefix(ref, referencePrefix) || len(parts) != 2 || parts[0] == "" || parts[1] == "" {
+		return "", "", fmt.Errorf("invalid claim check reference %q", ref)
+	}
+	return parts[0], parts[1], nil
+}
@@ -0,0 +1,260 @@
+/*
+Copyright 2021 Google LLC
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package claimcheck
+
+import (
+	"context"
+	"errors"
+	"strings"
+	"testing"
+	"time"
+
+	"cloud.google.com/go/storage"
+	"github.com/cloudevents/sdk-go/v2/event"
+
+	"github.com/google/knative-gcp/pkg/broker/config"
+	gstoragetesting "github.com/google/knative-gcp/pkg/gclient/storage/testing"
+)
+
+func newTestStore(t *testing.T, bucket gstoragetesting.TestBucketData) *Store {
+	client, err := gstoragetesting.TestClientCreator(gstoragetesting.TestClientData{BucketData: bucket})(context.Background())
+	if err != nil {
+		t.Fatal(err)
+	}
+	return NewStore(client)
+}
+
+func newEvent(t *testing.T, data string) *event.Event {
+	e := event.New()
+	e.SetID("id")
+	e.SetSource("source")
+	e.SetType("type")
+	if err := e.SetData(event.TextPlain, data); err != nil {
+		t.Fatal(err)
+	}
+	return &e
+}
+
+func TestOffloadAndRehydrate(t *testing.T) {
+	objects := make(map[string][]byte)
+	s := newTestStore(t, gstoragetesting.TestBucketData{Objects: objects})
+	broker := config.TestOnlyBrokerKey("ns", "broker")
+	cc := &config.ClaimCheck{Bucket: "bucket", ThresholdBytes: 4}
+
+	e := newEvent(t, "large payload")
+	if err := s.Offload(context.Background(), cc, broker, e); err != nil {
+		t.Fatalf("Offload() error = %v", err)
+	}
+	ref, _ := e.Extensions()[ReferenceExtension].(string)
+	if !strings.HasPrefix(ref, "gs://bucket/ns/broker/") {
+		t.Errorf("reference got=%q, want prefix %q", ref, "gs://bucket/ns/broker/")
+	}
+	if len(e.Data()) != 0 {
+		t.Errorf("offloaded event data got=%q, want none", e.Data())
+	}
+	if e.DataContentType() != event.TextPlain {
+		t.Errorf("offloaded event content type got=%q, want=%q", e.DataContentType(), event.TextPlain)
+	}
+	if got := string(objects[strings.TrimPrefix(ref, "gs://bucket/")]); got != "large payload" {
+		t.Errorf("stored payload got=%q, want=%q", got, "large payload")
+	}
+
+	rehydrated, err := s.Rehydrate(context.Background(), cc, broker, e)
+	if err != nil {
+		t.Fatalf("Rehydrate() error = %v", err)
+	}
+	if got := string(rehydrated.Data()); got != "large payload" {
+		t.Errorf("rehydrated data got=%q, want=%q", got, "large payload")
+	}
+	if _, ok := rehydrated.Extensions()[ReferenceExtension]; ok {
+		t.Error("rehydrated event still has the reference")
+	}
+	if _, ok := e.Extensions()[ReferenceExtension]; !ok {
+		t.Error("Rehydrate() modified the given event")
+	}
+}
+
+func TestOffloadBelowThreshold(t *testing.T) {
+	cases := []struct {
+		name string
+		s    *Store
+		cc   *config.ClaimCheck
+	}{{
+		name: "no store",
+		cc:   &config.ClaimCheck{Bucket: "bucket", ThresholdBytes: 1},
+	}, {
+		name: "no claim check",
+		s:    newTestStore(t, gstoragetesting.TestBucketData{Objects: map[string][]byte{}}),
+	}, {
+		name: "small payload",
+		s:    newTestStore(t, gstoragetesting.TestBucketData{Objects: map[string][]byte{}}),
+		cc:   &config.ClaimCheck{Bucket: "bucket", ThresholdBytes: 100},
+	}}
+	for _, tc := range cases {
+		t.Run(tc.name, func(t *testing.T) {
+			e := newEvent(t, "payload")
+			// The reference set by the sender is removed.
+			e.SetExtension(ReferenceExtension, "gs://bucket/ns/broker/object")
+			if err := tc.s.Offload(context.Background(), tc.cc, config.TestOnlyBrokerKey("ns", "broker"), e); err != nil {
+				t.Fatalf("Offload() error = %v", err)
+			}
+			if got := string(e.Data()); got != "payload" {
+				t.Errorf("event data got=%q, want=%q", got, "payload")
+			}
+			if _, ok := e.Extensions()[ReferenceExtension]; ok {
+				t.Error("event has a reference")
+			}
+		})
+	}
+}
+
+func TestOffloadError(t *testing.T) {
+	wantErr := errors.New("write failed")
+	s := newTestStore(t, gstoragetesting.TestBucketData{ObjectErr: wantErr})
+	e := newEvent(t, "large payload")
+	err := s.Offload(context.Background(), &config.ClaimCheck{Bucket: "bucket", ThresholdBytes: 1}, config.TestOnlyBrokerKey("ns", "broker"), e)
+	if !errors.Is(err, wantErr) {
+		t.Errorf("Offload() error = %v, want %v", err, wantErr)
+	}
+	if got := string(e.Data()); got != "large payload" {
+		t.Errorf("event data got=%q, want=%q", got, "large payload")
+	}
+}
+
+func TestRehydrateErrors(t *testing.T) {
+	cc := &config.ClaimCheck{Bucket: "bucket", ThresholdBytes: 1}
+	objects := map[string][]byte{
+		"ns/broker/object":       []byte("payload"),
+		"ns/broker/other/object": []byte("payload"),
+		"ns/other/object":        []byte("payload"),
+	}
+	cases := []struct {
+		name    string
+		s       *Store
+		cc      *config.ClaimCheck
+		ref     string
+		wantErr error
+	}{{
+		name:    "no store",
+		cc:      cc,
+		ref:     "gs://bucket/ns/broker/object",
+		wantErr: ErrNoStore,
+	}, {
+		name:    "missing object",
+		s:       newTestStore(t, gstoragetesting.TestBucketData{}),
+		cc:      cc,
+		ref:     "gs://bucket/ns/broker/object",
+		wantErr: storage.ErrObjectNotExist,
+	}, {
+		name: "invalid reference",
+		s:    newTestStore(t, gstoragetesting.TestBucketData{}),
+		cc:   cc,
+		ref:  "http://bucket/ns/broker/object",
+	}, {
+		name:    "no claim check",
+		s:       newTestStore(t, gstoragetesting.TestBucketData{Objects: objects}),
+		ref:     "gs://bucket/ns/broker/object",
+		wantErr: ErrForeignReference,
+	}, {
+		name:    "other bucket",
+		s:       newTestStore(t, gstoragetesting.TestBucketData{Objects: objects}),
+		cc:      cc,
+		ref:     "gs://other/ns/broker/object",
+		wantErr: ErrForeignReference,
+	}, {
+		name:    "other broker",
+		s:       newTestStore(t, gstoragetesting.TestBucketData{Objects: objects}),
+		cc:      cc,
+		ref:     "gs://bucket/ns/other/object",
+		wantErr: ErrForeignReference,
+	}, {
+		name:    "nested object",
+		s:       newTestStore(t, gstoragetesting.TestBucketData{Objects: objects}),
+		cc:      cc,
+		ref:     "gs://bucket/ns/broker/other/object",
+		wantErr: ErrForeignReference,
+	}}
+	for _, tc := range cases {
+		t.Run(tc.name, func(t *testing.T) {
+			e := newEvent(t, "")
+			e.SetExtension(ReferenceExtension, tc.ref)
+			_, err := tc.s.Rehydrate(context.Background(), tc.cc, config.TestOnlyBrokerKey("ns", "broker"), e)
+			if err == nil || (tc.wantErr != nil && !errors.Is(err, tc.wantErr)) {
+				t.Errorf("Rehydrate() error = %v, want %v", err, tc.wantErr)
+			}
+		})
+	}
+}
+
+func TestRehydrateWithoutReference(t *testing.T) {
+	var s *Store
+	e := newEvent(t, "payload")
+	got, err := s.Rehydrate(context.Background(), nil, config.TestOnlyBrokerKey("ns", "broker"), e)
+	if err != nil {
+		t.Fatalf("Rehydrate() error = %v", err)
+	}
+	if got != e {
+		t.Error("Rehydrate() of an event without reference returned a different event")
+	}
+}
+
+func TestDeletePayloads(t *testing.T) {
+	now := time.Now()
+	objects := map[string][]byte{
+		"ns/broker/old":       []byte("old"),
+		"ns/broker/new":       []byte("new"),
+		"ns/broker/other/old": []byte("other cell tenant"),
+		"ns/broker2/old":      []byte("other broker"),
+	}
+	created := map[string]time.Time{
+		"ns/broker/old":       now.Add(-48 * time.Hour),
+		"ns/broker/new":       now,
+		"ns/broker/other/old": now.Add(-48 * time.Hour),
+		"ns/broker2/old":      now.Add(-48 * time.Hour),
+	}
+	client, err := gstoragetesting.TestClientCreator(gstoragetesting.TestClientData{
+		BucketData: gstoragetesting.TestBucketData{Objects: objects, Created: created},
+	})(context.Background())
+	if err != nil {
+		t.Fatal(err)
+	}
+	broker := config.TestOnlyBrokerKey("ns", "broker")
+
+	deleted, err := DeletePayloads(context.Background(), client, "bucket", broker, now.Add(-24*time.Hour))
+	if err != nil {
+		t.Fatalf("DeletePayloads() error = %v", err)
+	}
+	if deleted != 1 {
+		t.Errorf("DeletePayloads() deleted got=%d, want=1", deleted)
+	}
+	if _, ok := objects["ns/broker/old"]; ok {
+		t.Error("payload older than the retention was not deleted")
+	}
+
+	deleted, err = DeletePayloads(context.Background(), client, "bucket", broker, time.Time{})
+	if err != nil {
+		t.Fatalf("DeletePayloads() error = %v", err)
+	}
+	if deleted != 1 {
+		t.Errorf("DeletePayloads() of all payloads deleted got=%d, want=1", deleted)
+	}
+	for _, name := range []string{"ns/broker/other/old", "ns/broker2/old"} {
+		if _, ok := objects[name]; !ok {
+			t.Errorf("object %q of another cell tenant was deleted", name)
+		}
+	}
+}
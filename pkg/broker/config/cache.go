@@ -108,6 +108,11 @@ func (ct *CachedTargets) RangeCellTenants(f func(*CellTenant) bool) {
 	}
 }
 
+// Generation returns the generation of the targets, or zero if it is unknown.
+func (ct *CachedTargets) Generation() int64 {
+	return ct.Load().GetGeneration()
+}
+
 // Bytes serializes all the targets.
 func (ct *CachedTargets) Bytes() ([]byte, error) {
 	val := ct.Load()
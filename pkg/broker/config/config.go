@@ -16,6 +16,10 @@ limitations under the License.
 
 package config
 
+// TargetsGenerationPath is the HTTP path on which the data plane pods serve the generation of the
+// targets they have loaded, in decimal.
+const TargetsGenerationPath = "/targets/generation"
+
 // ReadonlyTargets provides "read" functions for CellTenants and targets.
 type ReadonlyTargets interface {
 	// RangeAllTargets ranges over all targets.
@@ -30,6 +34,9 @@ type ReadonlyTargets interface {
 	// RangeCellTenants ranges over all CellTenants.
 	// Do not modify the given CellTenant copy.
 	RangeCellTenants(func(*CellTenant) bool)
+	// Generation returns the generation of the targets, which the controller increases whenever
+	// they change. Zero means that the generation is unknown.
+	Generation() int64
 	// Bytes serializes all the targets.
 	Bytes() ([]byte, error)
 	// DebugString returns the text format of all the targets. It is for _debug_ purposes only. The
@@ -48,6 +55,17 @@ type CellTenantMutation interface {
 	SetDecoupleQueue(q *Queue) CellTenantMutation
 	// SetState sets the CellTenant's state.
 	SetState(s State) CellTenantMutation
+	// SetIngressAuth sets the CellTenant's ingress authentication. A nil IngressAuth accepts
+	// events from any caller.
+	SetIngressAuth(a *IngressAuth) CellTenantMutation
+	// SetClaimCheck sets the CellTenant's claim check. A nil ClaimCheck keeps all the payloads
+	// in the events.
+	SetClaimCheck(c *ClaimCheck) CellTenantMutation
+	// SetOrdering sets the CellTenant's ordering. A nil Ordering delivers the events in any
+	// order.
+	SetOrdering(o *Ordering) CellTenantMutation
+	// SetGeneration sets the generation of the CellTenant's object.
+	SetGeneration(generation int64) CellTenantMutation
 	// UpsertTargets upserts Targets to the CellTenant.
 	// The targets' namespace, CellTenantType, and CellTenantName will be set to the CellTenant's
 	// value.
@@ -64,4 +82,20 @@ type Targets interface {
 	// MutateCellTenant mutates a CellTenant by its key.
 	// If the CellTenant doesn't exist, it will be added (unless Delete() is called).
 	MutateCellTenant(key *CellTenantKey, mutate func(CellTenantMutation))
+	// SetGeneration sets the generation of the targets.
+	SetGeneration(generation int64)
+}
+
+// NewTargetsConfig returns the TargetsConfig holding the CellTenants of the targets, along with
+// their generation. The CellTenants are shared with the targets, so they must not be modified.
+func NewTargetsConfig(targets ReadonlyTargets) *TargetsConfig {
+	tc := &TargetsConfig{
+		CellTenants: make(map[string]*CellTenant),
+		Generation:  targets.Generation(),
+	}
+	targets.RangeCellTenants(func(ct *CellTenant) bool {
+		tc.CellTenants[ct.Key().PersistenceString()] = ct
+		return true
+	})
+	return tc
 }
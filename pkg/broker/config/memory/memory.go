@@ -54,6 +54,30 @@ func (m *cellTenantMutation) SetState(s config.State) config.CellTenantMutation
 	return m
 }
 
+func (m *cellTenantMutation) SetIngressAuth(a *config.IngressAuth) config.CellTenantMutation {
+	m.delete = false
+	m.b.IngressAuth = a
+	return m
+}
+
+func (m *cellTenantMutation) SetClaimCheck(c *config.ClaimCheck) config.CellTenantMutation {
+	m.delete = false
+	m.b.ClaimCheck = c
+	return m
+}
+
+func (m *cellTenantMutation) SetOrdering(o *config.Ordering) config.CellTenantMutation {
+	m.delete = false
+	m.b.Ordering = o
+	return m
+}
+
+func (m *cellTenantMutation) SetGeneration(generation int64) config.CellTenantMutation {
+	m.delete = false
+	m.b.Generation = generation
+	return m
+}
+
 func (m *cellTenantMutation) UpsertTargets(targets ...*config.Target) config.CellTenantMutation {
 	m.delete = false
 	if m.b.Targets == nil {
@@ -147,3 +171,16 @@ func (m *memoryTargets) MutateCellTenant(key *config.CellTenantKey, mutate func(
 	// This works like a commit.
 	m.Store(newVal)
 }
+
+// SetGeneration sets the generation of the targets.
+// This function is thread-safe.
+func (m *memoryTargets) SetGeneration(generation int64) {
+	m.mux.Lock()
+	defer m.mux.Unlock()
+
+	// The CellTenants are shared with the existing copy, since mutations never modify them.
+	m.Store(&config.TargetsConfig{
+		CellTenants: m.Load().GetCellTenants(),
+		Generation:  generation,
+	})
+}
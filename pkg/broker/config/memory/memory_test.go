@@ -96,6 +96,48 @@ func TestMutateBroker(t *testing.T) {
 		assertBroker(t, wantBroker, targets)
 	})
 
+	t.Run("set and unset ingress auth", func(t *testing.T) {
+		wantBroker.IngressAuth = &config.IngressAuth{AllowedIdentities: []string{"system:serviceaccount:ns:sender"}}
+		targets.MutateCellTenant(wantBroker.Key(), func(m config.CellTenantMutation) {
+			m.SetIngressAuth(&config.IngressAuth{AllowedIdentities: []string{"system:serviceaccount:ns:sender"}})
+		})
+		assertBroker(t, wantBroker, targets)
+
+		wantBroker.IngressAuth = nil
+		targets.MutateCellTenant(wantBroker.Key(), func(m config.CellTenantMutation) {
+			m.SetIngressAuth(nil)
+		})
+		assertBroker(t, wantBroker, targets)
+	})
+
+	t.Run("set and unset claim check", func(t *testing.T) {
+		wantBroker.ClaimCheck = &config.ClaimCheck{Bucket: "payloads", ThresholdBytes: 1024}
+		targets.MutateCellTenant(wantBroker.Key(), func(m config.CellTenantMutation) {
+			m.SetClaimCheck(&config.ClaimCheck{Bucket: "payloads", ThresholdBytes: 1024})
+		})
+		assertBroker(t, wantBroker, targets)
+
+		wantBroker.ClaimCheck = nil
+		targets.MutateCellTenant(wantBroker.Key(), func(m config.CellTenantMutation) {
+			m.SetClaimCheck(nil)
+		})
+		assertBroker(t, wantBroker, targets)
+	})
+
+	t.Run("set and unset ordering", func(t *testing.T) {
+		wantBroker.Ordering = &config.Ordering{KeyExtension: "partitionkey"}
+		targets.MutateCellTenant(wantBroker.Key(), func(m config.CellTenantMutation) {
+			m.SetOrdering(&config.Ordering{KeyExtension: "partitionkey"})
+		})
+		assertBroker(t, wantBroker, targets)
+
+		wantBroker.Ordering = nil
+		targets.MutateCellTenant(wantBroker.Key(), func(m config.CellTenantMutation) {
+			m.SetOrdering(nil)
+		})
+		assertBroker(t, wantBroker, targets)
+	})
+
 	t1 := &config.Target{
 		Id:             "uid-1",
 		Address:        "consumer1.example.com",
@@ -0,0 +1,142 @@
+/*
+Copyright 2021 Google LLC
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package config
+
+import (
+	"bytes"
+	"compress/gzip"
+	"crypto/sha256"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"hash/fnv"
+	"io/ioutil"
+
+	"google.golang.org/protobuf/proto"
+)
+
+// ErrShardMismatch is returned when merging shards that do not match their manifest, which
+// happens while the shards are being updated.
+var ErrShardMismatch = errors.New("targets shard does not match the manifest")
+
+// ShardOf returns the shard holding the CellTenant with the given key, out of the given number of
+// shards.
+func ShardOf(key *CellTenantKey, shards int) int {
+	h := fnv.New32a()
+	h.Write([]byte(key.PersistenceString()))
+	return int(h.Sum32() % uint32(shards))
+}
+
+// SplitTargetsConfig splits the TargetsConfig into the given number of shards, and returns their
+// manifest along with the serialized shards. The generation of the TargetsConfig is only held by
+// the manifest.
+func SplitTargetsConfig(tc *TargetsConfig, shards int, compression TargetsManifest_Compression) (*TargetsManifest, [][]byte, error) {
+	if shards < 1 {
+		return nil, nil, fmt.Errorf("invalid number of shards: %d", shards)
+	}
+	split := make([]*TargetsConfig, shards)
+	for i := range split {
+		split[i] = &TargetsConfig{CellTenants: make(map[string]*CellTenant)}
+	}
+	for key, ct := range tc.GetCellTenants() {
+		split[ShardOf(ct.Key(), shards)].CellTenants[key] = ct
+	}
+
+	manifest := &TargetsManifest{Compression: compression, Generation: tc.GetGeneration()}
+	data := make([][]byte, 0, shards)
+	for i, shard := range split {
+		// The serialization is deterministic, so that unchanged shards keep the same checksum.
+		b, err := proto.MarshalOptions{Deterministic: true}.Marshal(shard)
+		if err != nil {
+			return nil, nil, fmt.Errorf("error serializing shard %d: %w", i, err)
+		}
+		manifest.Checksums = append(manifest.Checksums, checksum(b))
+		if b, err = compress(b, compression); err != nil {
+			return nil, nil, fmt.Errorf("error compressing shard %d: %w", i, err)
+		}
+		data = append(data, b)
+	}
+	return manifest, data, nil
+}
+
+// MergeTargetsShards merges the serialized shards described by the manifest into a single
+// TargetsConfig of the generation of the manifest. It returns an error wrapping ErrShardMismatch
+// if any shard is from another version of the TargetsConfig than the manifest.
+func MergeTargetsShards(manifest *TargetsManifest, shards [][]byte) (*TargetsConfig, error) {
+	if len(shards) != len(manifest.Checksums) {
+		return nil, fmt.Errorf("%w: got %d shards, want %d", ErrShardMismatch, len(shards), len(manifest.Checksums))
+	}
+	tc := &TargetsConfig{CellTenants: make(map[string]*CellTenant), Generation: manifest.Generation}
+	for i, data := range shards {
+		b, err := decompress(data, manifest.Compression)
+		if err != nil {
+			// A shard compressed differently than the manifest says is from another version.
+			return nil, fmt.Errorf("%w: error decompressing shard %d: %v", ErrShardMismatch, i, err)
+		}
+		if checksum(b) != manifest.Checksums[i] {
+			return nil, fmt.Errorf("%w: shard %d", ErrShardMismatch, i)
+		}
+		shard := &TargetsConfig{}
+		if err := proto.Unmarshal(b, shard); err != nil {
+			return nil, fmt.Errorf("error unmarshalling shard %d: %w", i, err)
+		}
+		for key, ct := range shard.CellTenants {
+			tc.CellTenants[key] = ct
+		}
+	}
+	return tc, nil
+}
+
+func checksum(b []byte) string {
+	sum := sha256.Sum256(b)
+	return hex.EncodeToString(sum[:])
+}
+
+func compress(b []byte, compression TargetsManifest_Compression) ([]byte, error) {
+	switch compression {
+	case TargetsManifest_NONE:
+		return b, nil
+	case TargetsManifest_GZIP:
+		var buf bytes.Buffer
+		w := gzip.NewWriter(&buf)
+		if _, err := w.Write(b); err != nil {
+			return nil, err
+		}
+		if err := w.Close(); err != nil {
+			return nil, err
+		}
+		return buf.Bytes(), nil
+	default:
+		return nil, fmt.Errorf("unknown compression: %v", compression)
+	}
+}
+
+func decompress(b []byte, compression TargetsManifest_Compression) ([]byte, error) {
+	switch compression {
+	case TargetsManifest_NONE:
+		return b, nil
+	case TargetsManifest_GZIP:
+		r, err := gzip.NewReader(bytes.NewReader(b))
+		if err != nil {
+			return nil, err
+		}
+		defer r.Close()
+		return ioutil.ReadAll(r)
+	default:
+		return nil, fmt.Errorf("unknown compression: %v", compression)
+	}
+}
@@ -0,0 +1,135 @@
+/*
+Copyright 2021 Google LLC
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package config
+
+import (
+	"errors"
+	"fmt"
+	"testing"
+
+	"google.golang.org/protobuf/proto"
+)
+
+func shardsTestConfig(brokers int) *TargetsConfig {
+	tc := &TargetsConfig{CellTenants: make(map[string]*CellTenant), Generation: 7}
+	for i := 0; i < brokers; i++ {
+		ct := TestOnlyBrokerKey("ns", fmt.Sprintf("broker%d", i)).CreateEmptyCellTenant()
+		ct.Address = fmt.Sprintf("http://broker%d", i)
+		ct.Targets = map[string]*Target{"trigger": {
+			Name:           "trigger",
+			Namespace:      "ns",
+			CellTenantType: CellTenantType_BROKER,
+			CellTenantName: ct.Name,
+			Address:        "http://trigger",
+		}}
+		tc.CellTenants[ct.Key().PersistenceString()] = ct
+	}
+	return tc
+}
+
+func TestSplitAndMergeTargetsShards(t *testing.T) {
+	for _, compression := range []TargetsManifest_Compression{TargetsManifest_NONE, TargetsManifest_GZIP} {
+		for _, shards := range []int{1, 3, 20} {
+			t.Run(fmt.Sprintf("%v/%d", compression, shards), func(t *testing.T) {
+				tc := shardsTestConfig(10)
+				manifest, data, err := SplitTargetsConfig(tc, shards, compression)
+				if err != nil {
+					t.Fatalf("unexpected error from SplitTargetsConfig: %v", err)
+				}
+				if len(data) != shards || len(manifest.Checksums) != shards {
+					t.Fatalf("got %d shards and %d checksums, want %d", len(data), len(manifest.Checksums), shards)
+				}
+				got, err := MergeTargetsShards(manifest, data)
+				if err != nil {
+					t.Fatalf("unexpected error from MergeTargetsShards: %v", err)
+				}
+				if !proto.Equal(got, tc) {
+					t.Errorf("merged targets got=%v, want=%v", got, tc)
+				}
+			})
+		}
+	}
+}
+
+func TestSplitTargetsConfigIsStable(t *testing.T) {
+	manifest, data, err := SplitTargetsConfig(shardsTestConfig(10), 4, TargetsManifest_GZIP)
+	if err != nil {
+		t.Fatalf("unexpected error from SplitTargetsConfig: %v", err)
+	}
+	tc := shardsTestConfig(10)
+	ct := tc.CellTenants[TestOnlyBrokerKey("ns", "broker0").PersistenceString()]
+	ct.Address = "http://changed"
+	updatedManifest, updatedData, err := SplitTargetsConfig(tc, 4, TargetsManifest_GZIP)
+	if err != nil {
+		t.Fatalf("unexpected error from SplitTargetsConfig: %v", err)
+	}
+	changed := ShardOf(ct.Key(), 4)
+	for i := range data {
+		sameChecksum := manifest.Checksums[i] == updatedManifest.Checksums[i]
+		sameData := string(data[i]) == string(updatedData[i])
+		if want := i != changed; sameChecksum != want || sameData != want {
+			t.Errorf("shard %d unchanged got checksum=%v data=%v, want %v", i, sameChecksum, sameData, want)
+		}
+	}
+}
+
+func TestMergeTargetsShardsMismatch(t *testing.T) {
+	manifest, data, err := SplitTargetsConfig(shardsTestConfig(10), 4, TargetsManifest_NONE)
+	if err != nil {
+		t.Fatalf("unexpected error from SplitTargetsConfig: %v", err)
+	}
+	tc := shardsTestConfig(12)
+	_, updatedData, err := SplitTargetsConfig(tc, 4, TargetsManifest_NONE)
+	if err != nil {
+		t.Fatalf("unexpected error from SplitTargetsConfig: %v", err)
+	}
+	gzipManifest, _, err := SplitTargetsConfig(tc, 4, TargetsManifest_GZIP)
+	if err != nil {
+		t.Fatalf("unexpected error from SplitTargetsConfig: %v", err)
+	}
+
+	partial := append([][]byte{}, data...)
+	for i := range partial {
+		if string(partial[i]) != string(updatedData[i]) {
+			partial[i] = updatedData[i]
+			break
+		}
+	}
+	for _, tc := range []struct {
+		name     string
+		manifest *TargetsManifest
+		data     [][]byte
+	}{{
+		name:     "partially updated shards",
+		manifest: manifest,
+		data:     partial,
+	}, {
+		name:     "missing shard",
+		manifest: manifest,
+		data:     data[:3],
+	}, {
+		name:     "compression changed",
+		manifest: gzipManifest,
+		data:     updatedData,
+	}} {
+		t.Run(tc.name, func(t *testing.T) {
+			if _, err := MergeTargetsShards(tc.manifest, tc.data); !errors.Is(err, ErrShardMismatch) {
+				t.Errorf("MergeTargetsShards error got=%v, want %v", err, ErrShardMismatch)
+			}
+		})
+	}
+}
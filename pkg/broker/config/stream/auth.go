@@ -0,0 +1,133 @@
+/*
+Copyright 2021 Google LLC
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package stream
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"io/ioutil"
+	"strings"
+
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/metadata"
+	"google.golang.org/grpc/status"
+	authv1 "k8s.io/api/authentication/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/types"
+	authv1client "k8s.io/client-go/kubernetes/typed/authentication/v1"
+)
+
+const (
+	// TokenAudience is the audience of the service account tokens authenticating the watches, so
+	// that the tokens sent to the TargetsService are not valid for any other service.
+	TokenAudience = "broker-targets-stream"
+	// DefaultTokenPath is the path of the projected service account token of the data plane,
+	// sent to the TargetsService to authenticate the watches.
+	DefaultTokenPath = "/var/run/cloud-run-events/targets-stream/token"
+
+	authorizationKey = "authorization"
+	bearerPrefix     = "Bearer "
+)
+
+// Authenticator authenticates the watchers of the targets config of the BrokerCells.
+type Authenticator interface {
+	// Authenticate returns an error unless the bearer token belongs to the data plane of the
+	// BrokerCell.
+	Authenticate(ctx context.Context, token string, brokerCell types.NamespacedName) error
+}
+
+// TokenReviewAuthenticator authenticates the Kubernetes service account tokens of the data plane
+// with TokenReviews.
+type TokenReviewAuthenticator struct {
+	Reviews authv1client.TokenReviewInterface
+	// ServiceAccount is the name of the service account of the data plane, in the namespace of
+	// its BrokerCell.
+	ServiceAccount string
+}
+
+var _ Authenticator = (*TokenReviewAuthenticator)(nil)
+
+// Authenticate reviews the token for the TokenAudience, and checks that it belongs to the service
+// account of the data plane in the namespace of the BrokerCell.
+func (a *TokenReviewAuthenticator) Authenticate(ctx context.Context, token string, brokerCell types.NamespacedName) error {
+	review, err := a.Reviews.Create(ctx, &authv1.TokenReview{
+		Spec: authv1.TokenReviewSpec{
+			Token:     token,
+			Audiences: []string{TokenAudience},
+		},
+	}, metav1.CreateOptions{})
+	if err != nil {
+		return fmt.Errorf("failed to review token: %w", err)
+	}
+	if !review.Status.Authenticated {
+		return fmt.Errorf("token is not authenticated: %s", review.Status.Error)
+	}
+	if !hasAudience(review.Status.Audiences, TokenAudience) {
+		return fmt.Errorf("token is not valid for the audience %q", TokenAudience)
+	}
+	want := fmt.Sprintf("system:serviceaccount:%s:%s", brokerCell.Namespace, a.ServiceAccount)
+	if user := review.Status.User.Username; user != want {
+		return fmt.Errorf("user %q is not the data plane of BrokerCell %s", user, brokerCell)
+	}
+	return nil
+}
+
+func hasAudience(audiences []string, audience string) bool {
+	for _, aud := range audiences {
+		if aud == audience {
+			return true
+		}
+	}
+	return false
+}
+
+// authenticate authenticates the caller of the stream with the bearer token of its metadata. A
+// nil Authenticator rejects all callers.
+func (s *Server) authenticate(ctx context.Context, brokerCell types.NamespacedName) error {
+	var token string
+	if md, ok := metadata.FromIncomingContext(ctx); ok {
+		for _, v := range md.Get(authorizationKey) {
+			if strings.HasPrefix(v, bearerPrefix) {
+				token = strings.TrimPrefix(v, bearerPrefix)
+			}
+		}
+	}
+	if token == "" {
+		return status.Error(codes.Unauthenticated, "missing bearer token")
+	}
+	if s.auth == nil {
+		return status.Error(codes.PermissionDenied, "no authenticator")
+	}
+	if err := s.auth.Authenticate(ctx, token, brokerCell); err != nil {
+		return status.Error(codes.PermissionDenied, err.Error())
+	}
+	return nil
+}
+
+// withToken adds the service account token at the path to the metadata of the outgoing requests.
+func withToken(ctx context.Context, path string) (context.Context, error) {
+	b, err := ioutil.ReadFile(path)
+	if err != nil {
+		return nil, fmt.Errorf("failed to read token: %w", err)
+	}
+	token := strings.TrimSpace(string(b))
+	if token == "" {
+		return nil, errors.New("empty token")
+	}
+	return metadata.AppendToOutgoingContext(ctx, authorizationKey, bearerPrefix+token), nil
+}
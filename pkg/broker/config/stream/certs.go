@@ -0,0 +1,191 @@
+/*
+Copyright 2021 Google LLC
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package stream
+
+import (
+	"context"
+	"crypto/tls"
+	"crypto/x509"
+	"encoding/pem"
+	"errors"
+	"sync"
+	"time"
+
+	"go.uber.org/zap"
+	corev1 "k8s.io/api/core/v1"
+	apierrs "k8s.io/apimachinery/pkg/api/errors"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	corev1client "k8s.io/client-go/kubernetes/typed/core/v1"
+	certresources "knative.dev/pkg/webhook/certificates/resources"
+
+	"github.com/google/knative-gcp/pkg/logging"
+)
+
+const (
+	// CertsSecretName is the name of the Secret holding the serving certificate of the
+	// TargetsService, in the namespace of the controller.
+	CertsSecretName = "broker-targets-stream-certs"
+	// CACertKey is the key of the CA certificates in the Secret. Only this key is mounted in the
+	// data plane.
+	CACertKey = certresources.CACert
+	// DefaultCAPath is the path of the CA certificates verifying the TargetsService in the data
+	// plane.
+	DefaultCAPath = "/var/run/cloud-run-events/targets-stream/" + CACertKey
+
+	certsValidity = 7 * 24 * time.Hour
+	// certsRotationPeriod is how long before they expire the certificates are rotated.
+	certsRotationPeriod = 24 * time.Hour
+	// certsRefreshInterval is how often the certificates are read from the Secret, so that all
+	// the replicas of the controller serve the rotated certificate.
+	certsRefreshInterval = 10 * time.Minute
+)
+
+// Certificates are the serving certificates of the TargetsService. They are stored in a Secret,
+// from which the data plane mounts the CA certificates, and are rotated before they expire.
+type Certificates struct {
+	secrets     corev1client.SecretInterface
+	namespace   string
+	serviceName string
+
+	mux  sync.RWMutex
+	cert *tls.Certificate
+}
+
+// NewCertificates creates the certificates of the TargetsService exposed by the Service in the
+// namespace. The certificates are stored in the CertsSecretName Secret of the namespace.
+func NewCertificates(secrets corev1client.SecretInterface, namespace, serviceName string) *Certificates {
+	return &Certificates{
+		secrets:     secrets,
+		namespace:   namespace,
+		serviceName: serviceName,
+	}
+}
+
+// Refresh reads the certificates from the Secret, and creates or rotates them when they are
+// missing or about to expire.
+func (c *Certificates) Refresh(ctx context.Context) error {
+	secret, cert, err := c.get(ctx)
+	if err != nil {
+		return err
+	}
+	if cert == nil {
+		if err := c.rotate(ctx, secret); apierrs.IsAlreadyExists(err) || apierrs.IsConflict(err) {
+			// Another replica of the controller rotated the certificates first.
+			logging.FromContext(ctx).Debug("The targets stream certificates were rotated concurrently", zap.Error(err))
+		} else if err != nil {
+			return err
+		}
+		if _, cert, err = c.get(ctx); err != nil {
+			return err
+		}
+		if cert == nil {
+			return errors.New("no valid certificate after rotation")
+		}
+	}
+	c.mux.Lock()
+	defer c.mux.Unlock()
+	c.cert = cert
+	return nil
+}
+
+// Run refreshes the certificates periodically until the context is done.
+func (c *Certificates) Run(ctx context.Context) {
+	ticker := time.NewTicker(certsRefreshInterval)
+	defer ticker.Stop()
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+			if err := c.Refresh(ctx); err != nil {
+				logging.FromContext(ctx).Warn("Failed to refresh the targets stream certificates", zap.Error(err))
+			}
+		}
+	}
+}
+
+// TLSConfig returns the TLS config serving the last certificate refreshed.
+func (c *Certificates) TLSConfig() *tls.Config {
+	return &tls.Config{
+		MinVersion:     tls.VersionTLS12,
+		GetCertificate: c.getCertificate,
+	}
+}
+
+func (c *Certificates) getCertificate(*tls.ClientHelloInfo) (*tls.Certificate, error) {
+	c.mux.RLock()
+	defer c.mux.RUnlock()
+	if c.cert == nil {
+		return nil, errors.New("no certificate")
+	}
+	return c.cert, nil
+}
+
+// get returns the Secret of the certificates, if it exists, and its certificate unless it is
+// invalid or about to expire.
+func (c *Certificates) get(ctx context.Context) (*corev1.Secret, *tls.Certificate, error) {
+	secret, err := c.secrets.Get(ctx, CertsSecretName, metav1.GetOptions{})
+	if apierrs.IsNotFound(err) {
+		return nil, nil, nil
+	}
+	if err != nil {
+		return nil, nil, err
+	}
+	cert, err := tls.X509KeyPair(secret.Data[certresources.ServerCert], secret.Data[certresources.ServerKey])
+	if err != nil {
+		logging.FromContext(ctx).Warn("Invalid targets stream certificate", zap.Error(err))
+		return secret, nil, nil
+	}
+	leaf, err := x509.ParseCertificate(cert.Certificate[0])
+	if err != nil {
+		logging.FromContext(ctx).Warn("Invalid targets stream certificate", zap.Error(err))
+		return secret, nil, nil
+	}
+	if time.Now().Add(certsRotationPeriod).After(leaf.NotAfter) {
+		return secret, nil, nil
+	}
+	return secret, &cert, nil
+}
+
+// rotate stores new certificates in the Secret, creating it if it is nil.
+func (c *Certificates) rotate(ctx context.Context, secret *corev1.Secret) error {
+	key, cert, ca, err := certresources.CreateCerts(ctx, c.serviceName, c.namespace, time.Now().Add(certsValidity))
+	if err != nil {
+		return err
+	}
+	data := map[string][]byte{
+		certresources.ServerKey:  key,
+		certresources.ServerCert: cert,
+		CACertKey:                ca,
+	}
+	if secret == nil {
+		_, err := c.secrets.Create(ctx, &corev1.Secret{
+			ObjectMeta: metav1.ObjectMeta{Namespace: c.namespace, Name: CertsSecretName},
+			Data:       data,
+		}, metav1.CreateOptions{})
+		return err
+	}
+	// The previous CA stays trusted until the next rotation, since the data plane may connect to
+	// the replicas of the controller still serving the previous certificate.
+	if block, _ := pem.Decode(secret.Data[CACertKey]); block != nil {
+		data[CACertKey] = append(data[CACertKey], pem.EncodeToMemory(block)...)
+	}
+	secret = secret.DeepCopy()
+	secret.Data = data
+	_, err = c.secrets.Update(ctx, secret, metav1.UpdateOptions{})
+	return err
+}
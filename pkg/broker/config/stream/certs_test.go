@@ -0,0 +1,116 @@
+/*
+Copyright 2021 Google LLC
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package stream
+
+import (
+	"bytes"
+	"context"
+	"encoding/pem"
+	"testing"
+	"time"
+
+	corev1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/client-go/kubernetes/fake"
+	certresources "knative.dev/pkg/webhook/certificates/resources"
+)
+
+const (
+	testNamespace   = "knative-testing"
+	testServiceName = "broker-targets-stream"
+)
+
+func TestCertificatesRefresh(t *testing.T) {
+	ctx := context.Background()
+	expiring, err := testCertsSecret(ctx, time.Now().Add(time.Hour))
+	if err != nil {
+		t.Fatalf("unexpected error from creating certificates: %v", err)
+	}
+	valid, err := testCertsSecret(ctx, time.Now().Add(certsValidity))
+	if err != nil {
+		t.Fatalf("unexpected error from creating certificates: %v", err)
+	}
+
+	for _, tc := range []struct {
+		name        string
+		secret      *corev1.Secret
+		wantRotated bool
+	}{{
+		name:        "missing secret",
+		wantRotated: true,
+	}, {
+		name:        "invalid certificate",
+		secret:      &corev1.Secret{ObjectMeta: metav1.ObjectMeta{Namespace: testNamespace, Name: CertsSecretName}},
+		wantRotated: true,
+	}, {
+		name:        "expiring certificate",
+		secret:      expiring,
+		wantRotated: true,
+	}, {
+		name:   "valid certificate",
+		secret: valid,
+	}} {
+		t.Run(tc.name, func(t *testing.T) {
+			client := fake.NewSimpleClientset()
+			if tc.secret != nil {
+				client = fake.NewSimpleClientset(tc.secret)
+			}
+			certs := NewCertificates(client.CoreV1().Secrets(testNamespace), testNamespace, testServiceName)
+			if err := certs.Refresh(ctx); err != nil {
+				t.Fatalf("unexpected error from refreshing the certificates: %v", err)
+			}
+			got, err := client.CoreV1().Secrets(testNamespace).Get(ctx, CertsSecretName, metav1.GetOptions{})
+			if err != nil {
+				t.Fatalf("unexpected error from getting the certificates Secret: %v", err)
+			}
+			cert, err := certs.getCertificate(nil)
+			if err != nil {
+				t.Fatalf("unexpected error from getting the certificate: %v", err)
+			}
+			if !bytes.Contains(got.Data[certresources.ServerCert], certPEM(t, cert.Certificate[0])) {
+				t.Error("served certificate is not the certificate of the Secret")
+			}
+			rotated := tc.secret == nil || !bytes.Equal(got.Data[certresources.ServerCert], tc.secret.Data[certresources.ServerCert])
+			if rotated != tc.wantRotated {
+				t.Errorf("rotated got=%v, want=%v", rotated, tc.wantRotated)
+			}
+			if rotated && tc.secret != nil && len(tc.secret.Data[CACertKey]) > 0 && !bytes.Contains(got.Data[CACertKey], tc.secret.Data[CACertKey]) {
+				t.Error("previous CA certificate is not trusted after the rotation")
+			}
+		})
+	}
+}
+
+func testCertsSecret(ctx context.Context, notAfter time.Time) (*corev1.Secret, error) {
+	key, cert, ca, err := certresources.CreateCerts(ctx, testServiceName, testNamespace, notAfter)
+	if err != nil {
+		return nil, err
+	}
+	return &corev1.Secret{
+		ObjectMeta: metav1.ObjectMeta{Namespace: testNamespace, Name: CertsSecretName},
+		Data: map[string][]byte{
+			certresources.ServerKey:  key,
+			certresources.ServerCert: cert,
+			CACertKey:                ca,
+		},
+	}, nil
+}
+
+func certPEM(t *testing.T, der []byte) []byte {
+	t.Helper()
+	return pem.EncodeToMemory(&pem.Block{Type: "CERTIFICATE", Bytes: der})
+}
@@ -0,0 +1,76 @@
+/*
+Copyright 2021 Google LLC
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package stream
+
+import "time"
+
+// Option is the option to set for the streamed targets.
+type Option func(*Targets)
+
+// WithAddress sets the address of the TargetsService. An empty address disables the stream.
+func WithAddress(address string) Option {
+	return func(t *Targets) {
+		t.address = address
+	}
+}
+
+// WithBrokerCell sets the BrokerCell whose targets are streamed.
+func WithBrokerCell(namespace, name string) Option {
+	return func(t *Targets) {
+		t.namespace = namespace
+		t.brokerCell = name
+	}
+}
+
+// WithTokenPath sets the path of the service account token authenticating the watches. Defaults
+// to DefaultTokenPath.
+func WithTokenPath(path string) Option {
+	return func(t *Targets) {
+		t.tokenPath = path
+	}
+}
+
+// WithCAPath sets the path of the CA certificates verifying the TargetsService. Defaults to
+// DefaultCAPath.
+func WithCAPath(path string) Option {
+	return func(t *Targets) {
+		t.caPath = path
+	}
+}
+
+// WithServerName sets the name verified in the certificate of the TargetsService. Defaults to the
+// host of the address.
+func WithServerName(name string) Option {
+	return func(t *Targets) {
+		t.serverName = name
+	}
+}
+
+// WithNotifyChan sets the channel notified each time the targets change.
+func WithNotifyChan(ch chan<- struct{}) Option {
+	return func(t *Targets) {
+		t.notifyChan = ch
+	}
+}
+
+// WithFallbackDelay sets how long the streamed targets are kept while the stream is reconnecting,
+// before falling back to the targets ConfigMap.
+func WithFallbackDelay(d time.Duration) Option {
+	return func(t *Targets) {
+		t.fallbackDelay = d
+	}
+}
@@ -0,0 +1,194 @@
+/*
+Copyright 2021 Google LLC
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package stream
+
+import (
+	"context"
+	"crypto/tls"
+	"fmt"
+	"net"
+	"sync"
+	"time"
+
+	"go.uber.org/zap"
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/credentials"
+	"google.golang.org/protobuf/proto"
+	"k8s.io/apimachinery/pkg/types"
+
+	"github.com/google/knative-gcp/pkg/broker/config"
+	"github.com/google/knative-gcp/pkg/logging"
+)
+
+// Server serves the targets config published by the BrokerCell reconciler to the data plane. Each
+// watcher receives the whole config first, then the changes since the last version it received.
+type Server struct {
+	auth Authenticator
+
+	mux   sync.Mutex
+	cells map[types.NamespacedName]*cell
+	// version is the last version published. It starts from the start time of the Server, so
+	// that the versions known by the data plane are not reused after a restart of the controller.
+	version int64
+}
+
+// cell is the targets config of a BrokerCell and its watchers.
+type cell struct {
+	config   *config.TargetsConfig
+	version  int64
+	watchers map[chan struct{}]bool
+}
+
+var _ watcher = (*Server)(nil)
+
+// NewServer creates a Server without any targets config. The watchers are authenticated with
+// auth.
+func NewServer(auth Authenticator) *Server {
+	return &Server{
+		auth:    auth,
+		cells:   make(map[types.NamespacedName]*cell),
+		version: time.Now().UnixNano(),
+	}
+}
+
+// Publish publishes the targets of a BrokerCell to its watchers. A nil Server publishes nothing.
+func (s *Server) Publish(brokerCell types.NamespacedName, targets config.ReadonlyTargets) {
+	if s == nil {
+		return
+	}
+	tc := config.NewTargetsConfig(targets)
+
+	s.mux.Lock()
+	defer s.mux.Unlock()
+	c := s.cell(brokerCell)
+	if c.config != nil && proto.Equal(c.config, tc) {
+		return
+	}
+	s.update(c, tc)
+}
+
+// Seed publishes the targets config of a BrokerCell read at startup, unless its targets were
+// already published. The watchers are then served the last known targets until the BrokerCell
+// is reconciled. A nil Server publishes nothing.
+func (s *Server) Seed(brokerCell types.NamespacedName, tc *config.TargetsConfig) {
+	if s == nil {
+		return
+	}
+	s.mux.Lock()
+	defer s.mux.Unlock()
+	if c := s.cell(brokerCell); c.config == nil {
+		s.update(c, tc)
+	}
+}
+
+// update sets the targets config of the cell and notifies its watchers. It must be called with
+// the lock held.
+func (s *Server) update(c *cell, tc *config.TargetsConfig) {
+	s.version++
+	c.config = tc
+	c.version = s.version
+	for w := range c.watchers {
+		select {
+		case w <- struct{}{}:
+		default:
+			// The watcher is already notified.
+		}
+	}
+}
+
+// Watch streams the updates of the targets config of a BrokerCell until the stream is done. No
+// update is sent until the targets of the BrokerCell are published. Only the data plane of the
+// BrokerCell may watch its targets.
+func (s *Server) Watch(req *config.WatchTargetsRequest, stream grpc.ServerStream) error {
+	brokerCell := types.NamespacedName{Namespace: req.BrokercellNamespace, Name: req.BrokercellName}
+	if err := s.authenticate(stream.Context(), brokerCell); err != nil {
+		logging.FromContext(stream.Context()).Warn("Rejected a watch of the targets stream",
+			zap.Stringer("brokerCell", brokerCell), zap.Error(err))
+		return err
+	}
+	notify := make(chan struct{}, 1)
+	s.mux.Lock()
+	s.cell(brokerCell).watchers[notify] = true
+	s.mux.Unlock()
+	defer func() {
+		s.mux.Lock()
+		delete(s.cell(brokerCell).watchers, notify)
+		s.mux.Unlock()
+	}()
+
+	var sent *config.TargetsConfig
+	sentVersion := req.Version
+	for {
+		tc, version := s.current(brokerCell)
+		if tc != nil && version != sentVersion {
+			// Updates published in between are merged into a single update.
+			update := config.NewTargetsUpdate(sent, tc)
+			update.Version = version
+			if err := stream.SendMsg(update); err != nil {
+				return err
+			}
+		}
+		sent, sentVersion = tc, version
+		select {
+		case <-notify:
+		case <-stream.Context().Done():
+			return nil
+		}
+	}
+}
+
+// Start serves the TargetsService over TLS on the port until the context is done.
+func (s *Server) Start(ctx context.Context, port int, tlsConfig *tls.Config) error {
+	lis, err := net.Listen("tcp", fmt.Sprintf(":%d", port))
+	if err != nil {
+		return err
+	}
+	s.serve(ctx, lis, tlsConfig)
+	return nil
+}
+
+func (s *Server) serve(ctx context.Context, lis net.Listener, tlsConfig *tls.Config) {
+	srv := grpc.NewServer(grpc.Creds(credentials.NewTLS(tlsConfig)))
+	srv.RegisterService(&serviceDesc, s)
+	go func() {
+		<-ctx.Done()
+		srv.Stop()
+	}()
+	go func() {
+		if err := srv.Serve(lis); err != nil {
+			logging.FromContext(ctx).Error("Failed to serve the targets stream", zap.Error(err))
+		}
+	}()
+}
+
+func (s *Server) current(brokerCell types.NamespacedName) (*config.TargetsConfig, int64) {
+	s.mux.Lock()
+	defer s.mux.Unlock()
+	c := s.cell(brokerCell)
+	return c.config, c.version
+}
+
+// cell returns the targets config of the BrokerCell, creating it if needed. It must be called
+// with the lock held.
+func (s *Server) cell(brokerCell types.NamespacedName) *cell {
+	c, ok := s.cells[brokerCell]
+	if !ok {
+		c = &cell{watchers: make(map[chan struct{}]bool)}
+		s.cells[brokerCell] = c
+	}
+	return c
+}
@@ -0,0 +1,72 @@
+/*
+Copyright 2021 Google LLC
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+// Package stream distributes the targets config of the BrokerCells from the controller to their
+// data plane through a gRPC watch stream, the TargetsService of targets.proto.
+package stream
+
+import (
+	"context"
+
+	"google.golang.org/grpc"
+
+	"github.com/google/knative-gcp/pkg/broker/config"
+)
+
+// watchMethod is the full name of the Watch method of the TargetsService.
+const watchMethod = "/config.TargetsService/Watch"
+
+// watcher is the server of the TargetsService.
+type watcher interface {
+	Watch(*config.WatchTargetsRequest, grpc.ServerStream) error
+}
+
+// serviceDesc describes the TargetsService defined in targets.proto. The repo only generates the
+// messages of the proto files, so the service is described here.
+var serviceDesc = grpc.ServiceDesc{
+	ServiceName: "config.TargetsService",
+	HandlerType: (*watcher)(nil),
+	Streams: []grpc.StreamDesc{{
+		StreamName:    "Watch",
+		Handler:       watchHandler,
+		ServerStreams: true,
+	}},
+	Metadata: "pkg/broker/config/targets.proto",
+}
+
+func watchHandler(srv interface{}, stream grpc.ServerStream) error {
+	req := &config.WatchTargetsRequest{}
+	if err := stream.RecvMsg(req); err != nil {
+		return err
+	}
+	return srv.(watcher).Watch(req, stream)
+}
+
+// watch starts watching the targets config of a BrokerCell. The updates are received from the
+// returned stream.
+func watch(ctx context.Context, conn *grpc.ClientConn, req *config.WatchTargetsRequest) (grpc.ClientStream, error) {
+	stream, err := conn.NewStream(ctx, &serviceDesc.Streams[0], watchMethod)
+	if err != nil {
+		return nil, err
+	}
+	if err := stream.SendMsg(req); err != nil {
+		return nil, err
+	}
+	if err := stream.CloseSend(); err != nil {
+		return nil, err
+	}
+	return stream, nil
+}
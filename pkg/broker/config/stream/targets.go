@@ -0,0 +1,252 @@
+/*
+Copyright 2021 Google LLC
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package stream
+
+import (
+	"context"
+	"crypto/tls"
+	"crypto/x509"
+	"errors"
+	"fmt"
+	"io/ioutil"
+	"sync"
+	"time"
+
+	"go.uber.org/zap"
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/credentials"
+
+	"github.com/google/knative-gcp/pkg/broker/config"
+	"github.com/google/knative-gcp/pkg/broker/config/volume"
+	"github.com/google/knative-gcp/pkg/logging"
+)
+
+const (
+	// defaultFallbackDelay is how long the streamed targets are kept while the stream is
+	// reconnecting, before falling back to the targets ConfigMap. It matches the propagation delay
+	// of the ConfigMap volume.
+	defaultFallbackDelay = time.Minute
+
+	minRetryDelay = time.Second
+	maxRetryDelay = 10 * time.Second
+)
+
+// Targets are the targets streamed by the controller. The targets mounted from the ConfigMap are
+// used until the first update is received, and whenever the stream is down for longer than the
+// fallback delay.
+type Targets struct {
+	fallback config.ReadonlyTargets
+	streamed config.CachedTargets
+
+	address       string
+	brokerCell    string
+	namespace     string
+	tokenPath     string
+	caPath        string
+	serverName    string
+	notifyChan    chan<- struct{}
+	fallbackDelay time.Duration
+
+	mux       sync.RWMutex
+	streaming bool
+
+	// The last targets config received and its version, only used by the watching goroutine.
+	config  *config.TargetsConfig
+	version int64
+}
+
+var _ config.ReadonlyTargets = (*Targets)(nil)
+
+// NewTargets creates the targets of the data plane. The targets are streamed from the address set
+// with WithAddress, if any, and are otherwise only read from the ConfigMap volume.
+func NewTargets(ctx context.Context, volumeOpts []volume.Option, opts []Option) (config.ReadonlyTargets, error) {
+	fallback, err := volume.NewTargetsFromFile(volumeOpts...)
+	if err != nil {
+		return nil, err
+	}
+	t := &Targets{
+		fallback:      fallback,
+		fallbackDelay: defaultFallbackDelay,
+		tokenPath:     DefaultTokenPath,
+		caPath:        DefaultCAPath,
+	}
+	for _, opt := range opts {
+		opt(t)
+	}
+	if t.address == "" {
+		return fallback, nil
+	}
+	go t.run(ctx)
+	return t, nil
+}
+
+// run watches the targets until the context is done, reconnecting whenever the stream breaks.
+func (t *Targets) run(ctx context.Context) {
+	logger := logging.FromContext(ctx).With(zap.String("address", t.address))
+	retryDelay := minRetryDelay
+	var lost time.Time
+	for {
+		received, err := t.watch(ctx)
+		if ctx.Err() != nil {
+			return
+		}
+		if received || lost.IsZero() {
+			lost = time.Now()
+		}
+		if received {
+			retryDelay = minRetryDelay
+		}
+		logger.Warn("The targets stream is down, retrying", zap.Duration("delay", retryDelay), zap.Error(err))
+		if time.Since(lost) >= t.fallbackDelay && t.setStreaming(false) {
+			logger.Warn("Falling back to the targets ConfigMap")
+			t.notify()
+		}
+		select {
+		case <-ctx.Done():
+			return
+		case <-time.After(retryDelay):
+		}
+		if retryDelay *= 2; retryDelay > maxRetryDelay {
+			retryDelay = maxRetryDelay
+		}
+	}
+}
+
+// dial connects to the TargetsService over TLS. The CA certificates are read for each connection,
+// as they are rotated by the controller.
+func (t *Targets) dial(ctx context.Context) (*grpc.ClientConn, error) {
+	b, err := ioutil.ReadFile(t.caPath)
+	if err != nil {
+		return nil, fmt.Errorf("failed to read CA certificates: %w", err)
+	}
+	roots := x509.NewCertPool()
+	if !roots.AppendCertsFromPEM(b) {
+		return nil, errors.New("no CA certificate")
+	}
+	creds := credentials.NewTLS(&tls.Config{
+		MinVersion: tls.VersionTLS12,
+		RootCAs:    roots,
+		ServerName: t.serverName,
+	})
+	return grpc.DialContext(ctx, t.address, grpc.WithTransportCredentials(creds))
+}
+
+// watch applies the updates of the stream until it breaks. It returns true if any update was
+// received.
+func (t *Targets) watch(ctx context.Context) (bool, error) {
+	// The token is read for each stream, as it is rotated by the kubelet.
+	ctx, err := withToken(ctx, t.tokenPath)
+	if err != nil {
+		return false, err
+	}
+	conn, err := t.dial(ctx)
+	if err != nil {
+		return false, err
+	}
+	defer conn.Close()
+	stream, err := watch(ctx, conn, &config.WatchTargetsRequest{
+		BrokercellNamespace: t.namespace,
+		BrokercellName:      t.brokerCell,
+		Version:             t.version,
+	})
+	if err != nil {
+		return false, err
+	}
+	received := false
+	for {
+		update := &config.TargetsUpdate{}
+		if err := stream.RecvMsg(update); err != nil {
+			return received, err
+		}
+		received = true
+		if !update.Full && t.config == nil {
+			// Only a full update can be applied to no config. It is not expected since the first
+			// update of a stream is full when no version is known.
+			continue
+		}
+		t.config = update.Apply(t.config)
+		t.version = update.Version
+		t.streamed.Store(t.config)
+		t.setStreaming(true)
+		t.notify()
+	}
+}
+
+// setStreaming sets whether the streamed targets are used, and returns true if it changed.
+func (t *Targets) setStreaming(streaming bool) bool {
+	t.mux.Lock()
+	defer t.mux.Unlock()
+	changed := t.streaming != streaming
+	t.streaming = streaming
+	return changed
+}
+
+func (t *Targets) notify() {
+	if t.notifyChan != nil {
+		t.notifyChan <- struct{}{}
+	}
+}
+
+// current returns the targets in use.
+func (t *Targets) current() config.ReadonlyTargets {
+	t.mux.RLock()
+	defer t.mux.RUnlock()
+	if t.streaming {
+		return &t.streamed
+	}
+	return t.fallback
+}
+
+// RangeAllTargets ranges over all targets.
+// Do not modify the given Target copy.
+func (t *Targets) RangeAllTargets(f func(*config.Target) bool) {
+	t.current().RangeAllTargets(f)
+}
+
+// GetTargetByKey returns a target by its trigger key, if it exists.
+// Do not modify the returned Target copy.
+func (t *Targets) GetTargetByKey(key *config.TargetKey) (*config.Target, bool) {
+	return t.current().GetTargetByKey(key)
+}
+
+// GetCellTenantByKey returns a CellTenant and its Targets, if it exists.
+// Do not modify the returned CellTenant copy.
+func (t *Targets) GetCellTenantByKey(key *config.CellTenantKey) (*config.CellTenant, bool) {
+	return t.current().GetCellTenantByKey(key)
+}
+
+// RangeCellTenants ranges over all CellTenants.
+// Do not modify the given CellTenant copy.
+func (t *Targets) RangeCellTenants(f func(*config.CellTenant) bool) {
+	t.current().RangeCellTenants(f)
+}
+
+// Generation returns the generation of the targets, or zero if it is unknown.
+func (t *Targets) Generation() int64 {
+	return t.current().Generation()
+}
+
+// Bytes serializes all the targets.
+func (t *Targets) Bytes() ([]byte, error) {
+	return t.current().Bytes()
+}
+
+// DebugString returns the text format of all the targets. It is for _debug_ purposes only. The
+// output format is not guaranteed to be stable and may change at any time.
+func (t *Targets) DebugString() string {
+	return t.current().DebugString()
+}
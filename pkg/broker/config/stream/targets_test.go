@@ -0,0 +1,335 @@
+/*
+Copyright 2021 Google LLC
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package stream
+
+import (
+	"context"
+	"crypto/tls"
+	"errors"
+	"io/ioutil"
+	"net"
+	"os"
+	"testing"
+	"time"
+
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/metadata"
+	"google.golang.org/grpc/status"
+	"google.golang.org/protobuf/proto"
+	authv1 "k8s.io/api/authentication/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/runtime"
+	"k8s.io/apimachinery/pkg/types"
+	"k8s.io/client-go/kubernetes/fake"
+	clienttesting "k8s.io/client-go/testing"
+
+	"github.com/google/knative-gcp/pkg/broker/config"
+	"github.com/google/knative-gcp/pkg/broker/config/memory"
+	"github.com/google/knative-gcp/pkg/broker/config/volume"
+)
+
+func TestStreamedTargets(t *testing.T) {
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+
+	fallbackKey := config.TestOnlyBrokerKey("ns", "fallback")
+	dir, err := ioutil.TempDir("", "configtest-*")
+	if err != nil {
+		t.Fatalf("unexpected error from creating temp dir: %v", err)
+	}
+	defer os.RemoveAll(dir)
+	b, err := proto.Marshal(&config.TargetsConfig{
+		CellTenants: map[string]*config.CellTenant{
+			fallbackKey.PersistenceString(): fallbackKey.CreateEmptyCellTenant(),
+		},
+	})
+	if err != nil {
+		t.Fatalf("unexpected error from marshalling the fallback config: %v", err)
+	}
+	path := dir + "/targets"
+	if err := ioutil.WriteFile(path, b, 0644); err != nil {
+		t.Fatalf("unexpected error from writing the fallback config: %v", err)
+	}
+
+	serverCtx, stopServer := context.WithCancel(ctx)
+	defer stopServer()
+	lis, err := net.Listen("tcp", "localhost:0")
+	if err != nil {
+		t.Fatalf("unexpected error from listening: %v", err)
+	}
+	server := NewServer(&testAuthenticator{token: "token"})
+	tlsConfig, caPath := testCertificates(t, dir)
+	server.serve(serverCtx, lis, tlsConfig)
+	tokenPath := dir + "/token"
+	if err := ioutil.WriteFile(tokenPath, []byte("token\n"), 0644); err != nil {
+		t.Fatalf("unexpected error from writing the token: %v", err)
+	}
+
+	ch := make(chan struct{}, 10)
+	targets, err := NewTargets(ctx, []volume.Option{volume.WithPath(path)}, []Option{
+		WithAddress(lis.Addr().String()),
+		WithBrokerCell("cell-ns", "cell"),
+		WithTokenPath(tokenPath),
+		WithCAPath(caPath),
+		WithServerName(testServiceName),
+		WithNotifyChan(ch),
+		WithFallbackDelay(time.Second),
+	})
+	if err != nil {
+		t.Fatalf("unexpected error from creating targets: %v", err)
+	}
+	wait := func() {
+		t.Helper()
+		select {
+		case <-ch:
+		case <-time.After(10 * time.Second):
+			t.Fatal("timed out waiting for the targets to change")
+		}
+	}
+
+	t.Run("fallback is used until targets are published", func(t *testing.T) {
+		if _, ok := targets.GetCellTenantByKey(fallbackKey); !ok {
+			t.Error("fallback broker not found")
+		}
+	})
+
+	seededKey := config.TestOnlyBrokerKey("ns", "seeded")
+	seeded := &config.TargetsConfig{
+		CellTenants: map[string]*config.CellTenant{
+			seededKey.PersistenceString(): seededKey.CreateEmptyCellTenant(),
+		},
+	}
+	t.Run("seeded targets are streamed", func(t *testing.T) {
+		server.Seed(types.NamespacedName{Namespace: "cell-ns", Name: "cell"}, seeded)
+		wait()
+		if _, ok := targets.GetCellTenantByKey(seededKey); !ok {
+			t.Error("seeded broker not found")
+		}
+	})
+
+	brokerKey := config.TestOnlyBrokerKey("ns", "broker")
+	published := memory.NewEmptyTargets()
+	t.Run("published targets are streamed", func(t *testing.T) {
+		published.MutateCellTenant(brokerKey, func(m config.CellTenantMutation) {
+			m.SetAddress("http://broker")
+		})
+		server.Publish(types.NamespacedName{Namespace: "cell-ns", Name: "cell"}, published)
+		wait()
+		if _, ok := targets.GetCellTenantByKey(fallbackKey); ok {
+			t.Error("fallback broker found in the streamed targets")
+		}
+		if _, ok := targets.GetCellTenantByKey(brokerKey); !ok {
+			t.Error("streamed broker not found")
+		}
+	})
+
+	t.Run("seeds do not replace published targets", func(t *testing.T) {
+		server.Seed(types.NamespacedName{Namespace: "cell-ns", Name: "cell"}, seeded)
+		select {
+		case <-ch:
+			t.Error("targets changed by a seed")
+		case <-time.After(100 * time.Millisecond):
+		}
+	})
+
+	t.Run("updates are streamed", func(t *testing.T) {
+		published.MutateCellTenant(brokerKey, func(m config.CellTenantMutation) {
+			m.UpsertTargets(&config.Target{Name: "trigger", Address: "http://trigger"})
+		})
+		server.Publish(types.NamespacedName{Namespace: "cell-ns", Name: "cell"}, published)
+		wait()
+		target, ok := targets.GetTargetByKey((&config.Target{
+			Name:           "trigger",
+			Namespace:      "ns",
+			CellTenantName: "broker",
+			CellTenantType: config.CellTenantType_BROKER,
+		}).Key())
+		if !ok || target.Address != "http://trigger" {
+			t.Errorf("streamed target got=%v, want address http://trigger", target)
+		}
+	})
+
+	t.Run("other brokercells are not streamed", func(t *testing.T) {
+		server.Publish(types.NamespacedName{Namespace: "cell-ns", Name: "other"}, memory.NewEmptyTargets())
+		select {
+		case <-ch:
+			t.Error("targets changed by the update of another brokercell")
+		case <-time.After(100 * time.Millisecond):
+		}
+	})
+
+	t.Run("fallback is used once the stream is down", func(t *testing.T) {
+		stopServer()
+		wait()
+		if _, ok := targets.GetCellTenantByKey(fallbackKey); !ok {
+			t.Error("fallback broker not found")
+		}
+	})
+}
+
+func TestNoAddressUsesVolume(t *testing.T) {
+	dir, err := ioutil.TempDir("", "configtest-*")
+	if err != nil {
+		t.Fatalf("unexpected error from creating temp dir: %v", err)
+	}
+	defer os.RemoveAll(dir)
+	path := dir + "/targets"
+	if err := ioutil.WriteFile(path, nil, 0644); err != nil {
+		t.Fatalf("unexpected error from writing the config: %v", err)
+	}
+	targets, err := NewTargets(context.Background(), []volume.Option{volume.WithPath(path)}, nil)
+	if err != nil {
+		t.Fatalf("unexpected error from creating targets: %v", err)
+	}
+	if _, ok := targets.(*volume.Targets); !ok {
+		t.Errorf("targets got=%T, want *volume.Targets", targets)
+	}
+}
+
+func TestWatchAuthentication(t *testing.T) {
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+	lis, err := net.Listen("tcp", "localhost:0")
+	if err != nil {
+		t.Fatalf("unexpected error from listening: %v", err)
+	}
+	dir, err := ioutil.TempDir("", "configtest-*")
+	if err != nil {
+		t.Fatalf("unexpected error from creating temp dir: %v", err)
+	}
+	defer os.RemoveAll(dir)
+	tlsConfig, caPath := testCertificates(t, dir)
+	NewServer(&testAuthenticator{token: "token"}).serve(ctx, lis, tlsConfig)
+	conn, err := (&Targets{address: lis.Addr().String(), caPath: caPath, serverName: testServiceName}).dial(ctx)
+	if err != nil {
+		t.Fatalf("unexpected error from dialing: %v", err)
+	}
+	defer conn.Close()
+
+	for _, tc := range []struct {
+		name       string
+		md         []string
+		brokerCell string
+		want       codes.Code
+	}{{
+		name:       "no token",
+		brokerCell: "cell",
+		want:       codes.Unauthenticated,
+	}, {
+		name:       "invalid token",
+		md:         []string{"authorization", "Bearer other"},
+		brokerCell: "cell",
+		want:       codes.PermissionDenied,
+	}, {
+		name:       "other brokercell",
+		md:         []string{"authorization", "Bearer token"},
+		brokerCell: "other",
+		want:       codes.PermissionDenied,
+	}, {
+		// No targets are published, so the authenticated watch waits for updates.
+		name:       "authenticated",
+		md:         []string{"authorization", "Bearer token"},
+		brokerCell: "cell",
+		want:       codes.DeadlineExceeded,
+	}} {
+		t.Run(tc.name, func(t *testing.T) {
+			ctx, cancel := context.WithTimeout(ctx, 100*time.Millisecond)
+			defer cancel()
+			ctx = metadata.AppendToOutgoingContext(ctx, tc.md...)
+			stream, err := watch(ctx, conn, &config.WatchTargetsRequest{BrokercellNamespace: "cell-ns", BrokercellName: tc.brokerCell})
+			if err == nil {
+				err = stream.RecvMsg(&config.TargetsUpdate{})
+			}
+			if got := status.Code(err); got != tc.want {
+				t.Errorf("watch error got=%v, want code %v", err, tc.want)
+			}
+		})
+	}
+}
+
+func TestTokenReviewAuthenticator(t *testing.T) {
+	client := fake.NewSimpleClientset()
+	client.PrependReactor("create", "tokenreviews", func(action clienttesting.Action) (bool, runtime.Object, error) {
+		review := action.(clienttesting.CreateAction).GetObject().(*authv1.TokenReview)
+		switch review.Spec.Token {
+		case "broker":
+			review.Status.Authenticated = true
+			review.Status.User.Username = "system:serviceaccount:cell-ns:broker"
+			review.Status.Audiences = review.Spec.Audiences
+		case "other":
+			review.Status.Authenticated = true
+			review.Status.User.Username = "system:serviceaccount:cell-ns:other"
+			review.Status.Audiences = review.Spec.Audiences
+		case "default-audience":
+			review.Status.Authenticated = true
+			review.Status.User.Username = "system:serviceaccount:cell-ns:broker"
+			review.Status.Audiences = []string{"https://kubernetes.default.svc"}
+		}
+		return true, review, nil
+	})
+	auth := &TokenReviewAuthenticator{Reviews: client.AuthenticationV1().TokenReviews(), ServiceAccount: "broker"}
+	brokerCell := types.NamespacedName{Namespace: "cell-ns", Name: "cell"}
+
+	for _, tc := range []struct {
+		token      string
+		brokerCell types.NamespacedName
+		wantErr    bool
+	}{
+		{token: "broker", brokerCell: brokerCell},
+		{token: "broker", brokerCell: types.NamespacedName{Namespace: "other-ns", Name: "cell"}, wantErr: true},
+		{token: "other", brokerCell: brokerCell, wantErr: true},
+		{token: "invalid", brokerCell: brokerCell, wantErr: true},
+		{token: "default-audience", brokerCell: brokerCell, wantErr: true},
+	} {
+		err := auth.Authenticate(context.Background(), tc.token, tc.brokerCell)
+		if (err != nil) != tc.wantErr {
+			t.Errorf("Authenticate(%q, %v) error got=%v, want error=%v", tc.token, tc.brokerCell, err, tc.wantErr)
+		}
+	}
+}
+
+// testCertificates creates the certificates of the TargetsService. It returns the TLS config of
+// the server and the path of the CA certificates.
+func testCertificates(t *testing.T, dir string) (*tls.Config, string) {
+	t.Helper()
+	client := fake.NewSimpleClientset()
+	certs := NewCertificates(client.CoreV1().Secrets(testNamespace), testNamespace, testServiceName)
+	if err := certs.Refresh(context.Background()); err != nil {
+		t.Fatalf("unexpected error from refreshing the certificates: %v", err)
+	}
+	secret, err := client.CoreV1().Secrets(testNamespace).Get(context.Background(), CertsSecretName, metav1.GetOptions{})
+	if err != nil {
+		t.Fatalf("unexpected error from getting the certificates Secret: %v", err)
+	}
+	caPath := dir + "/" + CACertKey
+	if err := ioutil.WriteFile(caPath, secret.Data[CACertKey], 0644); err != nil {
+		t.Fatalf("unexpected error from writing the CA certificates: %v", err)
+	}
+	return certs.TLSConfig(), caPath
+}
+
+// testAuthenticator only authenticates the token for the BrokerCell cell-ns/cell.
+type testAuthenticator struct {
+	token string
+}
+
+func (a *testAuthenticator) Authenticate(_ context.Context, token string, brokerCell types.NamespacedName) error {
+	if token != a.token || brokerCell != (types.NamespacedName{Namespace: "cell-ns", Name: "cell"}) {
+		return errors.New("unauthenticated")
+	}
+	return nil
+}
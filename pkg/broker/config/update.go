@@ -0,0 +1,127 @@
+/*
+Copyright 2021 Google LLC
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package config
+
+import (
+	"google.golang.org/protobuf/proto"
+)
+
+// NewTargetsUpdate returns the update from the TargetsConfig from to the TargetsConfig to. If from
+// is nil, the update is a full update. The CellTenants whose own fields are unchanged are updated
+// Target by Target.
+func NewTargetsUpdate(from, to *TargetsConfig) *TargetsUpdate {
+	u := &TargetsUpdate{Full: from == nil, Generation: to.GetGeneration()}
+	for key, ct := range to.GetCellTenants() {
+		old, ok := from.GetCellTenants()[key]
+		switch {
+		case ok && proto.Equal(old, ct):
+		case !ok || !equalIgnoringTargets(old, ct):
+			u.UpsertedCellTenants = append(u.UpsertedCellTenants, ct)
+		default:
+			for name, t := range ct.Targets {
+				if oldTarget, ok := old.Targets[name]; !ok || !proto.Equal(oldTarget, t) {
+					u.UpsertedTargets = append(u.UpsertedTargets, t)
+				}
+			}
+			for name, t := range old.Targets {
+				if _, ok := ct.Targets[name]; !ok {
+					u.DeletedTargets = append(u.DeletedTargets, &Target{
+						Name:           t.Name,
+						Namespace:      t.Namespace,
+						CellTenantName: t.CellTenantName,
+						CellTenantType: t.CellTenantType,
+					})
+				}
+			}
+		}
+	}
+	for key, old := range from.GetCellTenants() {
+		if _, ok := to.GetCellTenants()[key]; !ok {
+			u.DeletedCellTenants = append(u.DeletedCellTenants, &CellTenant{
+				Type:      old.Type,
+				Namespace: old.Namespace,
+				Name:      old.Name,
+			})
+		}
+	}
+	return u
+}
+
+// IsEmpty returns true if the update doesn't change the CellTenants of the TargetsConfig it is
+// applied to.
+func (x *TargetsUpdate) IsEmpty() bool {
+	return !x.Full && len(x.UpsertedCellTenants) == 0 && len(x.DeletedCellTenants) == 0 &&
+		len(x.UpsertedTargets) == 0 && len(x.DeletedTargets) == 0
+}
+
+// Apply returns the TargetsConfig resulting from the update of tc. tc is not modified, and shares
+// the CellTenants left unchanged with the returned TargetsConfig. The Targets of CellTenants that
+// don't exist are ignored.
+func (x *TargetsUpdate) Apply(tc *TargetsConfig) *TargetsConfig {
+	cellTenants := make(map[string]*CellTenant, len(tc.GetCellTenants())+len(x.UpsertedCellTenants))
+	if !x.Full {
+		for key, ct := range tc.GetCellTenants() {
+			cellTenants[key] = ct
+		}
+	}
+	for _, ct := range x.UpsertedCellTenants {
+		cellTenants[ct.Key().PersistenceString()] = ct
+	}
+	for _, ct := range x.DeletedCellTenants {
+		delete(cellTenants, ct.Key().PersistenceString())
+	}
+
+	// The CellTenants of the updated Targets are copied before being modified.
+	updated := make(map[string]*CellTenant)
+	mutable := func(t *Target) *CellTenant {
+		key := t.Key().ParentKey().PersistenceString()
+		if ct, ok := updated[key]; ok {
+			return ct
+		}
+		ct, ok := cellTenants[key]
+		if !ok {
+			return nil
+		}
+		ct = proto.Clone(ct).(*CellTenant)
+		if ct.Targets == nil {
+			ct.Targets = make(map[string]*Target)
+		}
+		cellTenants[key] = ct
+		updated[key] = ct
+		return ct
+	}
+	for _, t := range x.UpsertedTargets {
+		if ct := mutable(t); ct != nil {
+			ct.Targets[t.Name] = t
+		}
+	}
+	for _, t := range x.DeletedTargets {
+		if ct := mutable(t); ct != nil {
+			delete(ct.Targets, t.Name)
+		}
+	}
+	return &TargetsConfig{CellTenants: cellTenants, Generation: x.Generation}
+}
+
+// equalIgnoringTargets returns true if the CellTenants are equal, except for their Targets.
+func equalIgnoringTargets(a, b *CellTenant) bool {
+	a = proto.Clone(a).(*CellTenant)
+	a.Targets = nil
+	b = proto.Clone(b).(*CellTenant)
+	b.Targets = nil
+	return proto.Equal(a, b)
+}
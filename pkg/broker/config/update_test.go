@@ -0,0 +1,142 @@
+/*
+Copyright 2021 Google LLC
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package config
+
+import (
+	"testing"
+
+	"github.com/google/go-cmp/cmp"
+	"google.golang.org/protobuf/proto"
+	"google.golang.org/protobuf/testing/protocmp"
+)
+
+func TestTargetsUpdate(t *testing.T) {
+	broker := func(name, address string, targets ...string) *CellTenant {
+		ct := &CellTenant{
+			Type:      CellTenantType_BROKER,
+			Namespace: "ns",
+			Name:      name,
+			Address:   address,
+			Targets:   make(map[string]*Target),
+		}
+		for _, target := range targets {
+			ct.Targets[target] = &Target{
+				Name:           target,
+				Namespace:      "ns",
+				CellTenantName: name,
+				CellTenantType: CellTenantType_BROKER,
+				Address:        "http://" + target,
+			}
+		}
+		return ct
+	}
+	targetsConfig := func(cts ...*CellTenant) *TargetsConfig {
+		tc := &TargetsConfig{CellTenants: make(map[string]*CellTenant)}
+		for _, ct := range cts {
+			tc.CellTenants[ct.Key().PersistenceString()] = ct
+		}
+		return tc
+	}
+	changedTarget := broker("b1", "http://b1", "t1", "t2")
+	changedTarget.Targets["t1"].Address = "http://changed"
+
+	cases := []struct {
+		name     string
+		from, to *TargetsConfig
+		want     *TargetsUpdate
+	}{{
+		name: "full",
+		to:   targetsConfig(broker("b1", "http://b1", "t1")),
+		want: &TargetsUpdate{
+			Full:                true,
+			UpsertedCellTenants: []*CellTenant{broker("b1", "http://b1", "t1")},
+		},
+	}, {
+		name: "unchanged",
+		from: targetsConfig(broker("b1", "http://b1", "t1")),
+		to:   targetsConfig(broker("b1", "http://b1", "t1")),
+		want: &TargetsUpdate{},
+	}, {
+		name: "cell tenant created and deleted",
+		from: targetsConfig(broker("b1", "http://b1", "t1")),
+		to:   targetsConfig(broker("b2", "http://b2", "t1")),
+		want: &TargetsUpdate{
+			UpsertedCellTenants: []*CellTenant{broker("b2", "http://b2", "t1")},
+			DeletedCellTenants:  []*CellTenant{{Type: CellTenantType_BROKER, Namespace: "ns", Name: "b1"}},
+		},
+	}, {
+		name: "cell tenant changed",
+		from: targetsConfig(broker("b1", "http://b1", "t1")),
+		to:   targetsConfig(broker("b1", "http://changed", "t1", "t2")),
+		want: &TargetsUpdate{
+			UpsertedCellTenants: []*CellTenant{broker("b1", "http://changed", "t1", "t2")},
+		},
+	}, {
+		name: "targets created and deleted",
+		from: targetsConfig(broker("b1", "http://b1", "t1", "t2")),
+		to:   targetsConfig(broker("b1", "http://b1", "t2", "t3")),
+		want: &TargetsUpdate{
+			UpsertedTargets: []*Target{broker("b1", "http://b1", "t3").Targets["t3"]},
+			DeletedTargets: []*Target{{
+				Name:           "t1",
+				Namespace:      "ns",
+				CellTenantName: "b1",
+				CellTenantType: CellTenantType_BROKER,
+			}},
+		},
+	}, {
+		name: "target changed",
+		from: targetsConfig(broker("b1", "http://b1", "t1", "t2")),
+		to:   targetsConfig(changedTarget),
+		want: &TargetsUpdate{
+			UpsertedTargets: []*Target{changedTarget.Targets["t1"]},
+		},
+	}, {
+		name: "generation changed",
+		from: &TargetsConfig{CellTenants: targetsConfig(broker("b1", "http://b1", "t1")).CellTenants, Generation: 1},
+		to:   &TargetsConfig{CellTenants: targetsConfig(broker("b1", "http://b1", "t1")).CellTenants, Generation: 2},
+		want: &TargetsUpdate{Generation: 2},
+	}}
+	for _, tc := range cases {
+		t.Run(tc.name, func(t *testing.T) {
+			from := proto.Clone(tc.from)
+			got := NewTargetsUpdate(tc.from, tc.to)
+			if diff := cmp.Diff(tc.want, got, protocmp.Transform()); diff != "" {
+				t.Errorf("NewTargetsUpdate (-want,+got): %v", diff)
+			}
+			sameCellTenants := proto.Equal(&TargetsConfig{CellTenants: tc.from.GetCellTenants()}, &TargetsConfig{CellTenants: tc.to.GetCellTenants()})
+			if got.IsEmpty() != sameCellTenants {
+				t.Errorf("IsEmpty() got=%v, want=%v", got.IsEmpty(), !got.IsEmpty())
+			}
+			if diff := cmp.Diff(tc.to, got.Apply(tc.from), protocmp.Transform()); diff != "" {
+				t.Errorf("Apply (-want,+got): %v", diff)
+			}
+			if diff := cmp.Diff(from, tc.from, protocmp.Transform()); diff != "" {
+				t.Errorf("Apply modified the TargetsConfig (-want,+got): %v", diff)
+			}
+		})
+	}
+}
+
+func TestTargetsUpdateIgnoresTargetsOfMissingCellTenants(t *testing.T) {
+	u := &TargetsUpdate{
+		UpsertedTargets: []*Target{{Name: "t1", Namespace: "ns", CellTenantName: "b1", CellTenantType: CellTenantType_BROKER}},
+	}
+	if got := u.Apply(&TargetsConfig{}); len(got.CellTenants) != 0 {
+		t.Errorf("Apply got=%v, want no CellTenants", got)
+	}
+}
@@ -17,10 +17,13 @@ limitations under the License.
 package volume
 
 import (
+	"errors"
 	"fmt"
 	"io/ioutil"
 	"log"
+	"os"
 	"path/filepath"
+	"strings"
 
 	"github.com/fsnotify/fsnotify"
 	"github.com/google/knative-gcp/pkg/broker/config"
@@ -29,12 +32,28 @@ import (
 
 const (
 	defaultPath = "/var/run/cloud-run-events/broker/targets"
+
+	manifestSuffix = ".manifest"
 )
 
+// ManifestFile returns the file of the manifest of the targets sharded from the given targets file.
+func ManifestFile(file string) string {
+	return file + manifestSuffix
+}
+
+// ShardFile returns the file of a shard of the targets sharded from the given targets file.
+func ShardFile(file string, shard int) string {
+	return fmt.Sprintf("%s.%d", file, shard)
+}
+
 // Targets implements config.ReadonlyTargets with data
 // loaded from a file.
 // It also watches the file for any changes and will automatically
 // refresh the in memory cache.
+//
+// The targets are either loaded from the file itself, or from the shards of the file when its
+// manifest exists, see config.SplitTargetsConfig. The shards are only loaded once they all match
+// the manifest, so that a partial update of the shards is never used.
 type Targets struct {
 	config.CachedTargets
 	path       string
@@ -72,7 +91,7 @@ func NewTargetsFromFile(opts ...Option) (config.ReadonlyTargets, error) {
 func (t *Targets) watchWith(watcher *fsnotify.Watcher) error {
 	configFile := filepath.Clean(t.path)
 	configDir, _ := filepath.Split(t.path)
-	realConfigFile, _ := filepath.EvalSymlinks(t.path)
+	realConfigFile, _ := filepath.EvalSymlinks(t.watchedFile())
 	if err := watcher.Add(configDir); err != nil {
 		return err
 	}
@@ -86,16 +105,19 @@ func (t *Targets) watchWith(watcher *fsnotify.Watcher) error {
 					// 'Events' channel is closed.
 					return
 				}
-				currentConfigFile, _ := filepath.EvalSymlinks(t.path)
+				currentConfigFile, _ := filepath.EvalSymlinks(t.watchedFile())
 
-				// Re-sync if the file was updated/created or
-				// if the real file was replaced.
+				// Re-sync if the file, its manifest or any of its shards
+				// was updated/created or if the real file was replaced.
 				const writeOrCreateMask = fsnotify.Write | fsnotify.Create
-				if (filepath.Clean(event.Name) == configFile &&
+				if (isTargetsFile(configFile, filepath.Clean(event.Name)) &&
 					event.Op&writeOrCreateMask != 0) ||
 					(currentConfigFile != "" && currentConfigFile != realConfigFile) {
 					realConfigFile = currentConfigFile
-					if err := t.sync(); err != nil {
+					if err := t.sync(); errors.Is(err, config.ErrShardMismatch) {
+						// The remaining shards are synced later on.
+						log.Printf("waiting for the targets shards to be updated: %v\n", err)
+					} else if err != nil {
 						log.Printf("error syncing config: %v\n", err)
 					} else if t.notifyChan != nil {
 						// File got updated and notify the external channel.
@@ -114,7 +136,30 @@ func (t *Targets) watchWith(watcher *fsnotify.Watcher) error {
 	return nil
 }
 
+// isTargetsFile returns true if the file is the targets file, its manifest or one of its shards.
+func isTargetsFile(targetsFile, file string) bool {
+	return file == targetsFile || strings.HasPrefix(file, targetsFile+".")
+}
+
+// watchedFile returns the manifest of the targets if they are sharded, and the targets file
+// otherwise. All the shards are updated along with their manifest when mounted from ConfigMaps
+// of the same volume.
+func (t *Targets) watchedFile() string {
+	if _, err := os.Stat(ManifestFile(t.path)); err == nil {
+		return ManifestFile(t.path)
+	}
+	return t.path
+}
+
 func (t *Targets) sync() error {
+	manifest, err := t.readManifest()
+	if err != nil {
+		return err
+	}
+	if manifest != nil {
+		return t.syncShards(manifest)
+	}
+
 	b, err := t.readFile()
 	if err != nil {
 		return fmt.Errorf("failed to read config file: %w", err)
@@ -129,6 +174,45 @@ func (t *Targets) sync() error {
 	return nil
 }
 
+// syncShards loads the targets from the shards of the manifest. The targets are left unchanged
+// until all the shards match the manifest.
+func (t *Targets) syncShards(manifest *config.TargetsManifest) error {
+	shards := make([][]byte, 0, len(manifest.Checksums))
+	for i := range manifest.Checksums {
+		b, err := ioutil.ReadFile(ShardFile(t.path, i))
+		if os.IsNotExist(err) {
+			return fmt.Errorf("%w: shard %d not found", config.ErrShardMismatch, i)
+		}
+		if err != nil {
+			return fmt.Errorf("failed to read config shard %d: %w", i, err)
+		}
+		shards = append(shards, b)
+	}
+	val, err := config.MergeTargetsShards(manifest, shards)
+	if err != nil {
+		return fmt.Errorf("failed to merge config shards: %w", err)
+	}
+	t.Store(val)
+	return nil
+}
+
+// readManifest reads the manifest of the sharded targets. It returns nil if the targets are not
+// sharded.
+func (t *Targets) readManifest() (*config.TargetsManifest, error) {
+	b, err := ioutil.ReadFile(ManifestFile(t.path))
+	if os.IsNotExist(err) {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, fmt.Errorf("failed to read config manifest: %w", err)
+	}
+	var manifest config.TargetsManifest
+	if err := proto.Unmarshal(b, &manifest); err != nil {
+		return nil, fmt.Errorf("failed to unmarshal config manifest: %w", err)
+	}
+	return &manifest, nil
+}
+
 func (t *Targets) readFile() ([]byte, error) {
 	return ioutil.ReadFile(t.path)
 }
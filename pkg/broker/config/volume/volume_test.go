@@ -181,6 +181,67 @@ func TestSyncConfigFromFile(t *testing.T) {
 	}
 }
 
+func TestSyncShardsFromFiles(t *testing.T) {
+	data := &config.TargetsConfig{CellTenants: make(map[string]*config.CellTenant)}
+	for _, name := range []string{"broker1", "broker2", "broker3", "broker4"} {
+		ct := config.TestOnlyBrokerKey("ns", name).CreateEmptyCellTenant()
+		ct.Address = name + ".ns.example.com"
+		data.CellTenants[ct.Key().PersistenceString()] = ct
+	}
+	dir, err := ioutil.TempDir("", "configtest-*")
+	if err != nil {
+		t.Fatalf("unexpected error from creating temp dir: %v", err)
+	}
+	defer os.RemoveAll(dir)
+	path := dir + "/targets"
+
+	writeShards := func(data *config.TargetsConfig, manifestFirst bool) {
+		t.Helper()
+		manifest, shards, err := config.SplitTargetsConfig(data, 3, config.TargetsManifest_GZIP)
+		if err != nil {
+			t.Fatalf("unexpected error from splitting the config: %v", err)
+		}
+		b, _ := proto.Marshal(manifest)
+		if manifestFirst {
+			atomicWriteFile(t, ManifestFile(path), b)
+		}
+		for i, shard := range shards {
+			if current, err := ioutil.ReadFile(ShardFile(path, i)); err != nil || string(current) != string(shard) {
+				atomicWriteFile(t, ShardFile(path, i), shard)
+			}
+		}
+		if !manifestFirst {
+			atomicWriteFile(t, ManifestFile(path), b)
+		}
+	}
+	writeShards(data, false)
+
+	ch := make(chan struct{}, 1)
+	targets, err := NewTargetsFromFile(WithPath(path), WithNotifyChan(ch))
+	if err != nil {
+		t.Fatalf("unexpected error from NewTargetsFromFile: %v", err)
+	}
+	gotTargets := targets.(*Targets).Load()
+	if !proto.Equal(data, gotTargets) {
+		t.Errorf("initial targets got=%+v, want=%+v", gotTargets, data)
+	}
+
+	// The manifest is updated before the shard, so that the targets are only loaded once the
+	// shard is updated as well.
+	data.CellTenants[config.TestOnlyBrokerKey("ns", "broker1").PersistenceString()].Address = "changed.ns.example.com"
+	writeShards(data, true)
+
+	select {
+	case <-ch:
+	case <-time.After(5 * time.Second):
+		t.Fatalf("Timeout waiting for the notification")
+	}
+	gotTargets = targets.(*Targets).Load()
+	if !proto.Equal(data, gotTargets) {
+		t.Errorf("updated targets got=%+v, want=%+v", gotTargets, data)
+	}
+}
+
 func atomicWriteFile(t *testing.T, file string, bytes []byte) {
 	t.Helper()
 	// In order to more closely replicate how K8s writes ConfigMaps to the file system, we will
@@ -0,0 +1,97 @@
+/*
+Copyright 2021 Google LLC
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package eventfilter
+
+import (
+	"context"
+	"sync"
+
+	"github.com/cloudevents/sdk-go/v2/event"
+	"go.uber.org/zap"
+
+	"github.com/google/knative-gcp/pkg/broker/config"
+	"github.com/google/knative-gcp/pkg/logging"
+)
+
+// Cache holds the compiled filters of targets.
+//
+// Every config sync replaces the Target copies held by config.ReadonlyTargets, so a target's
+// filters are compiled the first time its current copy is seen, and reused until the next sync.
+// A nil *Cache is valid and compiles the filters on every call.
+type Cache struct {
+	mux     sync.RWMutex
+	entries map[config.TargetKey]cacheEntry
+}
+
+type cacheEntry struct {
+	// target is the Target copy the filter was compiled from.
+	target *config.Target
+	filter Filter
+}
+
+// NewCache creates an empty Cache.
+func NewCache() *Cache {
+	return &Cache{entries: make(map[config.TargetKey]cacheEntry)}
+}
+
+// Filter returns true if the event passes all the filters of the target.
+func (c *Cache) Filter(ctx context.Context, target *config.Target, event *event.Event) bool {
+	return c.Get(ctx, target).Filter(ctx, event)
+}
+
+// Get returns the compiled filter of the target.
+func (c *Cache) Get(ctx context.Context, target *config.Target) Filter {
+	if c == nil {
+		return compileTarget(ctx, target)
+	}
+	key := *target.Key()
+	c.mux.RLock()
+	entry, ok := c.entries[key]
+	c.mux.RUnlock()
+	if ok && entry.target == target {
+		return entry.filter
+	}
+
+	f := compileTarget(ctx, target)
+	c.mux.Lock()
+	c.entries[key] = cacheEntry{target: target, filter: f}
+	c.mux.Unlock()
+	return f
+}
+
+// Prune removes the filters of targets that no longer exist in the config.
+func (c *Cache) Prune(targets config.ReadonlyTargets) {
+	if c == nil {
+		return
+	}
+	c.mux.Lock()
+	defer c.mux.Unlock()
+	for key := range c.entries {
+		if _, ok := targets.GetTargetByKey(&key); !ok {
+			delete(c.entries, key)
+		}
+	}
+}
+
+func compileTarget(ctx context.Context, target *config.Target) Filter {
+	f, err := NewTargetFilter(target)
+	if err != nil {
+		logging.FromContext(ctx).Error("Failed to compile target filters, no event will pass", zap.Stringer("target", target.Key()), zap.Error(err))
+		return failAll
+	}
+	return f
+}
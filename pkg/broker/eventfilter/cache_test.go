@@ -0,0 +1,82 @@
+/*
+Copyright 2021 Google LLC
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package eventfilter
+
+import (
+	"context"
+	"testing"
+
+	"github.com/google/knative-gcp/pkg/broker/config"
+	"github.com/google/knative-gcp/pkg/broker/config/memory"
+)
+
+func TestCache(t *testing.T) {
+	ctx := context.Background()
+	target := &config.Target{
+		Name:    "target",
+		Filters: []*config.Filter{{Suffix: map[string]string{"subject": ".jpg"}}},
+	}
+	targets := memory.NewEmptyTargets()
+	brokerKey := config.TestOnlyBrokerKey("ns", "broker")
+	targets.MutateCellTenant(brokerKey, func(m config.CellTenantMutation) {
+		m.UpsertTargets(target)
+	})
+	stored, _ := targets.GetTargetByKey(target.Key())
+
+	c := NewCache()
+	if !c.Filter(ctx, stored, storageEvent()) {
+		t.Error("event should pass the filter")
+	}
+	if first, second := c.Get(ctx, stored), c.Get(ctx, stored); first != second {
+		t.Error("filter should be compiled only once for the same target")
+	}
+
+	// A config sync replaces the target, so the filter is recompiled.
+	targets.MutateCellTenant(brokerKey, func(m config.CellTenantMutation) {
+		m.UpsertTargets(&config.Target{
+			Name:    "target",
+			Filters: []*config.Filter{{Suffix: map[string]string{"subject": ".png"}}},
+		})
+	})
+	updated, _ := targets.GetTargetByKey(target.Key())
+	if c.Filter(ctx, updated, storageEvent()) {
+		t.Error("event should not pass the updated filter")
+	}
+
+	// Targets with invalid filters never pass.
+	invalid := &config.Target{Name: "invalid", Filters: []*config.Filter{{}}}
+	if c.Filter(ctx, invalid, storageEvent()) {
+		t.Error("event should not pass an invalid filter")
+	}
+
+	c.Prune(targets)
+	if _, ok := c.entries[*invalid.Key()]; ok {
+		t.Error("filter of a target that isn't in the config should be pruned")
+	}
+	if _, ok := c.entries[*updated.Key()]; !ok {
+		t.Error("filter of a target in the config should not be pruned")
+	}
+}
+
+func TestNilCache(t *testing.T) {
+	var c *Cache
+	target := &config.Target{Filters: []*config.Filter{{Prefix: map[string]string{"type": "google.cloud.storage."}}}}
+	if !c.Filter(context.Background(), target, storageEvent()) {
+		t.Error("event should pass the filter")
+	}
+	c.Prune(memory.NewEmptyTargets())
+}
@@ -0,0 +1,404 @@
+/*
+Copyright 2021 Google LLC
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+// Package cesql implements the CloudEvents SQL expression language used by trigger filters.
+//
+// Values are one of three types: Boolean (bool), Integer (int32) and String (string). Event
+// attributes are exposed as Strings, except for extensions that are natively Booleans or Integers.
+// See https://github.com/cloudevents/spec/blob/v1.0.1/cesql/spec.md.
+package cesql
+
+import (
+	"errors"
+	"fmt"
+	"regexp"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/cloudevents/sdk-go/v2/event"
+	"github.com/cloudevents/sdk-go/v2/types"
+)
+
+// Expression is a compiled CloudEvents SQL expression.
+type Expression interface {
+	// Evaluate evaluates the expression against the given event. If an error is returned, the
+	// returned value is the zero value of the expected type.
+	Evaluate(e *event.Event) (interface{}, error)
+}
+
+var errDivisionByZero = errors.New("division by zero")
+
+// Matches evaluates the expression against the event and reports whether it evaluated to true
+// without errors.
+func Matches(expr Expression, e *event.Event) (bool, error) {
+	v, err := expr.Evaluate(e)
+	if err != nil {
+		return false, err
+	}
+	b, err := castToBool(v)
+	if err != nil {
+		return false, err
+	}
+	return b, nil
+}
+
+type literal struct {
+	value interface{}
+}
+
+func (l literal) Evaluate(*event.Event) (interface{}, error) {
+	return l.value, nil
+}
+
+type attributeExpression struct {
+	attribute string
+}
+
+func (a *attributeExpression) Evaluate(e *event.Event) (interface{}, error) {
+	v, ok := attribute(e, a.attribute)
+	if !ok {
+		return "", fmt.Errorf("missing attribute %q", a.attribute)
+	}
+	return v, nil
+}
+
+type existsExpression struct {
+	attribute string
+}
+
+func (x *existsExpression) Evaluate(e *event.Event) (interface{}, error) {
+	_, ok := attribute(e, x.attribute)
+	return ok, nil
+}
+
+// attribute looks up a context attribute or extension of the event.
+func attribute(e *event.Event, name string) (interface{}, bool) {
+	switch name {
+	case "specversion":
+		return e.SpecVersion(), true
+	case "id":
+		return e.ID(), true
+	case "source":
+		return e.Source(), true
+	case "type":
+		return e.Type(), true
+	case "subject":
+		return e.Subject(), e.Subject() != ""
+	case "time":
+		return e.Time().UTC().Format(time.RFC3339Nano), !e.Time().IsZero()
+	case "dataschema":
+		return e.DataSchema(), e.DataSchema() != ""
+	case "datacontenttype":
+		return e.DataContentType(), e.DataContentType() != ""
+	}
+	v, ok := e.Extensions()[name]
+	if !ok {
+		return nil, false
+	}
+	switch v := v.(type) {
+	case bool, int32, string:
+		return v, true
+	}
+	s, err := types.Format(v)
+	if err != nil {
+		return nil, false
+	}
+	return s, true
+}
+
+type logicExpression struct {
+	op    string
+	left  Expression
+	right Expression
+}
+
+func (l *logicExpression) Evaluate(e *event.Event) (interface{}, error) {
+	left, err := evaluateBool(l.left, e)
+	if err != nil {
+		return false, err
+	}
+	switch {
+	case l.op == "AND" && !left:
+		return false, nil
+	case l.op == "OR" && left:
+		return true, nil
+	}
+	right, err := evaluateBool(l.right, e)
+	if err != nil {
+		return false, err
+	}
+	if l.op == "XOR" {
+		return left != right, nil
+	}
+	return right, nil
+}
+
+type notExpression struct {
+	value Expression
+}
+
+func (n *notExpression) Evaluate(e *event.Event) (interface{}, error) {
+	v, err := evaluateBool(n.value, e)
+	if err != nil {
+		return false, err
+	}
+	return !v, nil
+}
+
+type negateExpression struct {
+	value Expression
+}
+
+func (n *negateExpression) Evaluate(e *event.Event) (interface{}, error) {
+	v, err := evaluateInt(n.value, e)
+	if err != nil {
+		return int32(0), err
+	}
+	return -v, nil
+}
+
+type equalityExpression struct {
+	negate bool
+	left   Expression
+	right  Expression
+}
+
+func (q *equalityExpression) Evaluate(e *event.Event) (interface{}, error) {
+	left, err := q.left.Evaluate(e)
+	if err != nil {
+		return false, err
+	}
+	right, err := q.right.Evaluate(e)
+	if err != nil {
+		return false, err
+	}
+	eq, err := equal(left, right)
+	if err != nil {
+		return false, err
+	}
+	return eq != q.negate, nil
+}
+
+// equal compares two values. If their types differ, the right value is cast to the type of the
+// left value.
+func equal(left, right interface{}) (bool, error) {
+	right, err := castTo(right, left)
+	if err != nil {
+		return false, err
+	}
+	return left == right, nil
+}
+
+type relationalExpression struct {
+	op    string
+	left  Expression
+	right Expression
+}
+
+func (r *relationalExpression) Evaluate(e *event.Event) (interface{}, error) {
+	left, err := evaluateInt(r.left, e)
+	if err != nil {
+		return false, err
+	}
+	right, err := evaluateInt(r.right, e)
+	if err != nil {
+		return false, err
+	}
+	switch r.op {
+	case "<":
+		return left < right, nil
+	case "<=":
+		return left <= right, nil
+	case ">":
+		return left > right, nil
+	default:
+		return left >= right, nil
+	}
+}
+
+type arithmeticExpression struct {
+	op    string
+	left  Expression
+	right Expression
+}
+
+func (a *arithmeticExpression) Evaluate(e *event.Event) (interface{}, error) {
+	left, err := evaluateInt(a.left, e)
+	if err != nil {
+		return int32(0), err
+	}
+	right, err := evaluateInt(a.right, e)
+	if err != nil {
+		return int32(0), err
+	}
+	switch a.op {
+	case "+":
+		return left + right, nil
+	case "-":
+		return left - right, nil
+	case "*":
+		return left * right, nil
+	case "/":
+		if right == 0 {
+			return int32(0), errDivisionByZero
+		}
+		return left / right, nil
+	default:
+		if right == 0 {
+			return int32(0), errDivisionByZero
+		}
+		return left % right, nil
+	}
+}
+
+type likeExpression struct {
+	negate  bool
+	value   Expression
+	pattern *regexp.Regexp
+}
+
+func (l *likeExpression) Evaluate(e *event.Event) (interface{}, error) {
+	v, err := evaluateString(l.value, e)
+	if err != nil {
+		return false, err
+	}
+	return l.pattern.MatchString(v) != l.negate, nil
+}
+
+type inExpression struct {
+	negate bool
+	value  Expression
+	set    []Expression
+}
+
+func (in *inExpression) Evaluate(e *event.Event) (interface{}, error) {
+	v, err := in.value.Evaluate(e)
+	if err != nil {
+		return false, err
+	}
+	for _, s := range in.set {
+		sv, err := s.Evaluate(e)
+		if err != nil {
+			return false, err
+		}
+		eq, err := equal(v, sv)
+		if err != nil {
+			return false, err
+		}
+		if eq {
+			return !in.negate, nil
+		}
+	}
+	return in.negate, nil
+}
+
+type functionExpression struct {
+	name string
+	fn   function
+	args []Expression
+}
+
+func (f *functionExpression) Evaluate(e *event.Event) (interface{}, error) {
+	args := make([]interface{}, len(f.args))
+	for i, a := range f.args {
+		v, err := a.Evaluate(e)
+		if err != nil {
+			return f.fn.zero, err
+		}
+		args[i] = v
+	}
+	v, err := f.fn.call(args)
+	if err != nil {
+		return f.fn.zero, fmt.Errorf("%s: %w", f.name, err)
+	}
+	return v, nil
+}
+
+func evaluateBool(expr Expression, e *event.Event) (bool, error) {
+	v, err := expr.Evaluate(e)
+	if err != nil {
+		return false, err
+	}
+	return castToBool(v)
+}
+
+func evaluateInt(expr Expression, e *event.Event) (int32, error) {
+	v, err := expr.Evaluate(e)
+	if err != nil {
+		return 0, err
+	}
+	return castToInt(v)
+}
+
+func evaluateString(expr Expression, e *event.Event) (string, error) {
+	v, err := expr.Evaluate(e)
+	if err != nil {
+		return "", err
+	}
+	return castToString(v), nil
+}
+
+// castTo casts v to the type of like.
+func castTo(v, like interface{}) (interface{}, error) {
+	switch like.(type) {
+	case bool:
+		return castToBool(v)
+	case int32:
+		return castToInt(v)
+	default:
+		return castToString(v), nil
+	}
+}
+
+func castToBool(v interface{}) (bool, error) {
+	switch v := v.(type) {
+	case bool:
+		return v, nil
+	case string:
+		switch strings.ToLower(v) {
+		case "true":
+			return true, nil
+		case "false":
+			return false, nil
+		}
+	}
+	return false, fmt.Errorf("cannot cast %v to Boolean", v)
+}
+
+func castToInt(v interface{}) (int32, error) {
+	switch v := v.(type) {
+	case int32:
+		return v, nil
+	case string:
+		if i, err := strconv.ParseInt(v, 10, 32); err == nil {
+			return int32(i), nil
+		}
+	}
+	return 0, fmt.Errorf("cannot cast %v to Integer", v)
+}
+
+func castToString(v interface{}) string {
+	switch v := v.(type) {
+	case bool:
+		return strconv.FormatBool(v)
+	case int32:
+		return strconv.FormatInt(int64(v), 10)
+	case string:
+		return v
+	}
+	return fmt.Sprint(v)
+}
@@ -0,0 +1,161 @@
+/*
+Copyright 2021 Google LLC
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package cesql
+
+import (
+	"testing"
+
+	"github.com/cloudevents/sdk-go/v2/event"
+)
+
+func testEvent() *event.Event {
+	e := event.New()
+	e.SetID("1234")
+	e.SetSource("//storage.googleapis.com/projects/_/buckets/my-bucket")
+	e.SetType("google.cloud.storage.object.v1.finalized")
+	e.SetSubject("objects/photo.jpg")
+	e.SetExtension("sequence", 42)
+	e.SetExtension("important", true)
+	e.SetExtension("region", "us-central1")
+	return &e
+}
+
+func TestEvaluate(t *testing.T) {
+	cases := []struct {
+		expr    string
+		want    interface{}
+		wantErr bool
+	}{
+		{expr: "TRUE", want: true},
+		{expr: "false", want: false},
+		{expr: "'abc'", want: "abc"},
+		{expr: `"it\"s"`, want: `it"s`},
+		{expr: "-5 + 3 * 2", want: int32(1)},
+		{expr: "(1 + 2) * 3", want: int32(9)},
+		{expr: "7 % 4", want: int32(3)},
+		{expr: "7 / 0", want: int32(0), wantErr: true},
+		{expr: "type = 'google.cloud.storage.object.v1.finalized'", want: true},
+		{expr: "type != 'google.cloud.storage.object.v1.finalized'", want: false},
+		{expr: "type <> 'other'", want: true},
+		{expr: "type LIKE 'google.cloud.storage.%'", want: true},
+		{expr: "type NOT LIKE 'google.cloud.storage.%'", want: false},
+		{expr: "subject LIKE 'objects/photo.___'", want: true},
+		{expr: "subject LIKE 'objects/photo\\.jpg'", want: true},
+		{expr: "subject LIKE 'objects/%.png'", want: false},
+		{expr: "region IN ('us-east1', 'us-central1')", want: true},
+		{expr: "region NOT IN ('us-east1', 'us-central1')", want: false},
+		{expr: "sequence > 40 AND sequence <= 42", want: true},
+		{expr: "sequence = '42'", want: true},
+		{expr: "'42' = sequence", want: true},
+		{expr: "sequence + 1", want: int32(43)},
+		{expr: "important", want: true},
+		{expr: "important AND NOT (sequence < 10)", want: true},
+		{expr: "important XOR TRUE", want: false},
+		{expr: "FALSE OR important", want: true},
+		{expr: "EXISTS region", want: true},
+		{expr: "EXISTS missing", want: false},
+		{expr: "EXISTS missing AND missing = 'x'", want: false},
+		{expr: "missing = 'x'", want: false, wantErr: true},
+		{expr: "type > 3", want: false, wantErr: true},
+		{expr: "LENGTH(region)", want: int32(11)},
+		{expr: "UPPER(region) = 'US-CENTRAL1'", want: true},
+		{expr: "LOWER('ABC')", want: "abc"},
+		{expr: "TRIM('  a ')", want: "a"},
+		{expr: "CONCAT(id, '-', sequence)", want: "1234-42"},
+		{expr: "CONCAT_WS('/', 'a', 'b', 'c')", want: "a/b/c"},
+		{expr: "LEFT(region, 2)", want: "us"},
+		{expr: "RIGHT(region, 1)", want: "1"},
+		{expr: "SUBSTRING(region, 4)", want: "central1"},
+		{expr: "SUBSTRING(region, 4, 7)", want: "central"},
+		{expr: "SUBSTRING(region, -1)", want: "1"},
+		{expr: "ABS(-3)", want: int32(3)},
+		{expr: "INT('12') + 1", want: int32(13)},
+		{expr: "INT('abc')", want: int32(0), wantErr: true},
+		{expr: "BOOL('TRUE')", want: true},
+		{expr: "STRING(12)", want: "12"},
+		{expr: "IS_INT('12')", want: true},
+		{expr: "IS_BOOL('12')", want: false},
+	}
+	for _, tc := range cases {
+		t.Run(tc.expr, func(t *testing.T) {
+			expr, err := Parse(tc.expr)
+			if err != nil {
+				t.Fatalf("Parse(%q) returned error: %v", tc.expr, err)
+			}
+			got, err := expr.Evaluate(testEvent())
+			if (err != nil) != tc.wantErr {
+				t.Errorf("Evaluate error = %v, wantErr %v", err, tc.wantErr)
+			}
+			if got != tc.want {
+				t.Errorf("Evaluate = %#v, want %#v", got, tc.want)
+			}
+		})
+	}
+}
+
+func TestParseErrors(t *testing.T) {
+	for _, expr := range []string{
+		"",
+		"type =",
+		"(type = 'a'",
+		"type = 'a')",
+		"'unterminated",
+		"type LIKE 3",
+		"EXISTS 'type'",
+		"type IN 'a'",
+		"UNKNOWN(type)",
+		"LENGTH()",
+		"LEFT('a')",
+		"my_attribute = 'a'",
+		"type # 'a'",
+		"99999999999",
+	} {
+		t.Run(expr, func(t *testing.T) {
+			if _, err := Parse(expr); err == nil {
+				t.Errorf("Parse(%q) succeeded, want error", expr)
+			}
+		})
+	}
+}
+
+func TestMatches(t *testing.T) {
+	cases := []struct {
+		expr    string
+		want    bool
+		wantErr bool
+	}{
+		{expr: "type LIKE '%.finalized'", want: true},
+		{expr: "type LIKE '%.deleted'", want: false},
+		{expr: "sequence", want: false, wantErr: true},
+		{expr: "missing", want: false, wantErr: true},
+	}
+	for _, tc := range cases {
+		t.Run(tc.expr, func(t *testing.T) {
+			expr, err := Parse(tc.expr)
+			if err != nil {
+				t.Fatalf("Parse(%q) returned error: %v", tc.expr, err)
+			}
+			got, err := Matches(expr, testEvent())
+			if (err != nil) != tc.wantErr {
+				t.Errorf("Matches error = %v, wantErr %v", err, tc.wantErr)
+			}
+			if got != tc.want {
+				t.Errorf("Matches = %v, want %v", got, tc.want)
+			}
+		})
+	}
+}
@@ -0,0 +1,150 @@
+/*
+Copyright 2021 Google LLC
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package cesql
+
+import (
+	"errors"
+	"strings"
+)
+
+// function is a built-in CloudEvents SQL function.
+type function struct {
+	// minArgs and maxArgs bound the number of arguments. A negative maxArgs means unbounded.
+	minArgs int
+	maxArgs int
+	// zero is the value returned when the function fails.
+	zero interface{}
+	call func(args []interface{}) (interface{}, error)
+}
+
+// functions are the built-in functions, keyed by their upper-cased name.
+var functions = map[string]function{
+	"LENGTH": {minArgs: 1, maxArgs: 1, zero: int32(0), call: func(args []interface{}) (interface{}, error) {
+		return int32(len([]rune(castToString(args[0])))), nil
+	}},
+	"CONCAT": {minArgs: 0, maxArgs: -1, zero: "", call: func(args []interface{}) (interface{}, error) {
+		return strings.Join(castAllToString(args), ""), nil
+	}},
+	"CONCAT_WS": {minArgs: 1, maxArgs: -1, zero: "", call: func(args []interface{}) (interface{}, error) {
+		s := castAllToString(args)
+		return strings.Join(s[1:], s[0]), nil
+	}},
+	"LOWER": {minArgs: 1, maxArgs: 1, zero: "", call: func(args []interface{}) (interface{}, error) {
+		return strings.ToLower(castToString(args[0])), nil
+	}},
+	"UPPER": {minArgs: 1, maxArgs: 1, zero: "", call: func(args []interface{}) (interface{}, error) {
+		return strings.ToUpper(castToString(args[0])), nil
+	}},
+	"TRIM": {minArgs: 1, maxArgs: 1, zero: "", call: func(args []interface{}) (interface{}, error) {
+		return strings.TrimSpace(castToString(args[0])), nil
+	}},
+	"LEFT": {minArgs: 2, maxArgs: 2, zero: "", call: func(args []interface{}) (interface{}, error) {
+		s := []rune(castToString(args[0]))
+		n, err := castToInt(args[1])
+		if err != nil {
+			return "", err
+		}
+		if n < 0 {
+			return "", errors.New("negative length")
+		}
+		if int(n) > len(s) {
+			n = int32(len(s))
+		}
+		return string(s[:n]), nil
+	}},
+	"RIGHT": {minArgs: 2, maxArgs: 2, zero: "", call: func(args []interface{}) (interface{}, error) {
+		s := []rune(castToString(args[0]))
+		n, err := castToInt(args[1])
+		if err != nil {
+			return "", err
+		}
+		if n < 0 {
+			return "", errors.New("negative length")
+		}
+		if int(n) > len(s) {
+			n = int32(len(s))
+		}
+		return string(s[len(s)-int(n):]), nil
+	}},
+	"SUBSTRING": {minArgs: 2, maxArgs: 3, zero: "", call: substring},
+	"ABS": {minArgs: 1, maxArgs: 1, zero: int32(0), call: func(args []interface{}) (interface{}, error) {
+		i, err := castToInt(args[0])
+		if err != nil {
+			return int32(0), err
+		}
+		if i < 0 {
+			return -i, nil
+		}
+		return i, nil
+	}},
+	"INT": {minArgs: 1, maxArgs: 1, zero: int32(0), call: func(args []interface{}) (interface{}, error) {
+		return castToInt(args[0])
+	}},
+	"BOOL": {minArgs: 1, maxArgs: 1, zero: false, call: func(args []interface{}) (interface{}, error) {
+		return castToBool(args[0])
+	}},
+	"STRING": {minArgs: 1, maxArgs: 1, zero: "", call: func(args []interface{}) (interface{}, error) {
+		return castToString(args[0]), nil
+	}},
+	"IS_BOOL": {minArgs: 1, maxArgs: 1, zero: false, call: func(args []interface{}) (interface{}, error) {
+		_, err := castToBool(args[0])
+		return err == nil, nil
+	}},
+	"IS_INT": {minArgs: 1, maxArgs: 1, zero: false, call: func(args []interface{}) (interface{}, error) {
+		_, err := castToInt(args[0])
+		return err == nil, nil
+	}},
+}
+
+// substring implements SUBSTRING(string, pos [, length]). Positions are 1-based; a negative
+// position counts from the end of the string.
+func substring(args []interface{}) (interface{}, error) {
+	s := []rune(castToString(args[0]))
+	pos, err := castToInt(args[1])
+	if err != nil {
+		return "", err
+	}
+	start := int(pos) - 1
+	if pos < 0 {
+		start = len(s) + int(pos)
+	}
+	if start < 0 || start > len(s) || pos == 0 {
+		return "", errors.New("position out of range")
+	}
+	end := len(s)
+	if len(args) == 3 {
+		n, err := castToInt(args[2])
+		if err != nil {
+			return "", err
+		}
+		if n < 0 {
+			return "", errors.New("negative length")
+		}
+		if start+int(n) < end {
+			end = start + int(n)
+		}
+	}
+	return string(s[start:end]), nil
+}
+
+func castAllToString(args []interface{}) []string {
+	s := make([]string, len(args))
+	for i, a := range args {
+		s[i] = castToString(a)
+	}
+	return s
+}
@@ -0,0 +1,344 @@
+/*
+Copyright 2021 Google LLC
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package cesql
+
+import (
+	"fmt"
+	"regexp"
+	"strconv"
+	"strings"
+)
+
+// Parse compiles a CloudEvents SQL expression so that it can be evaluated against many events.
+//
+// Operators bind, from the loosest to the tightest: AND, OR and XOR; equality (=, !=, <>);
+// relational (<, <=, >, >=); additive (+, -); multiplicative (*, /, %); [NOT] LIKE and [NOT] IN;
+// and finally the unary NOT and -.
+func Parse(expr string) (Expression, error) {
+	tokens, err := lex(expr)
+	if err != nil {
+		return nil, err
+	}
+	p := &parser{tokens: tokens}
+	e, err := p.parseLogic()
+	if err != nil {
+		return nil, err
+	}
+	if t := p.peek(); t.kind != tokenEOF {
+		return nil, fmt.Errorf("unexpected %v", t)
+	}
+	return e, nil
+}
+
+type parser struct {
+	tokens []token
+	pos    int
+}
+
+func (p *parser) peek() token {
+	return p.tokens[p.pos]
+}
+
+func (p *parser) next() token {
+	t := p.tokens[p.pos]
+	if t.kind != tokenEOF {
+		p.pos++
+	}
+	return t
+}
+
+// accept consumes the next token if it is of the given kind and has one of the given texts.
+func (p *parser) accept(kind tokenKind, texts ...string) (token, bool) {
+	t := p.peek()
+	if t.kind != kind {
+		return t, false
+	}
+	for _, text := range texts {
+		if t.text == text {
+			return p.next(), true
+		}
+	}
+	return t, false
+}
+
+func (p *parser) expect(kind tokenKind, text string) error {
+	if _, ok := p.accept(kind, text); !ok {
+		return fmt.Errorf("expected %q, found %v", text, p.peek())
+	}
+	return nil
+}
+
+func (p *parser) parseLogic() (Expression, error) {
+	left, err := p.parseEquality()
+	if err != nil {
+		return nil, err
+	}
+	for {
+		op, ok := p.accept(tokenKeyword, "AND", "OR", "XOR")
+		if !ok {
+			return left, nil
+		}
+		right, err := p.parseEquality()
+		if err != nil {
+			return nil, err
+		}
+		left = &logicExpression{op: op.text, left: left, right: right}
+	}
+}
+
+func (p *parser) parseEquality() (Expression, error) {
+	left, err := p.parseRelational()
+	if err != nil {
+		return nil, err
+	}
+	for {
+		op, ok := p.accept(tokenOperator, "=", "!=", "<>")
+		if !ok {
+			return left, nil
+		}
+		right, err := p.parseRelational()
+		if err != nil {
+			return nil, err
+		}
+		left = &equalityExpression{negate: op.text != "=", left: left, right: right}
+	}
+}
+
+func (p *parser) parseRelational() (Expression, error) {
+	left, err := p.parseAdditive()
+	if err != nil {
+		return nil, err
+	}
+	for {
+		op, ok := p.accept(tokenOperator, "<", "<=", ">", ">=")
+		if !ok {
+			return left, nil
+		}
+		right, err := p.parseAdditive()
+		if err != nil {
+			return nil, err
+		}
+		left = &relationalExpression{op: op.text, left: left, right: right}
+	}
+}
+
+func (p *parser) parseAdditive() (Expression, error) {
+	left, err := p.parseMultiplicative()
+	if err != nil {
+		return nil, err
+	}
+	for {
+		op, ok := p.accept(tokenOperator, "+", "-")
+		if !ok {
+			return left, nil
+		}
+		right, err := p.parseMultiplicative()
+		if err != nil {
+			return nil, err
+		}
+		left = &arithmeticExpression{op: op.text, left: left, right: right}
+	}
+}
+
+func (p *parser) parseMultiplicative() (Expression, error) {
+	left, err := p.parsePostfix()
+	if err != nil {
+		return nil, err
+	}
+	for {
+		op, ok := p.accept(tokenOperator, "*", "/", "%")
+		if !ok {
+			return left, nil
+		}
+		right, err := p.parsePostfix()
+		if err != nil {
+			return nil, err
+		}
+		left = &arithmeticExpression{op: op.text, left: left, right: right}
+	}
+}
+
+// parsePostfix parses the LIKE and IN operators, which may be negated with NOT.
+func (p *parser) parsePostfix() (Expression, error) {
+	left, err := p.parseUnary()
+	if err != nil {
+		return nil, err
+	}
+	for {
+		negate := false
+		if t := p.peek(); t.kind == tokenKeyword && t.text == "NOT" {
+			if n := p.tokens[p.pos+1]; n.kind != tokenKeyword || (n.text != "LIKE" && n.text != "IN") {
+				return left, nil
+			}
+			p.next()
+			negate = true
+		}
+		if _, ok := p.accept(tokenKeyword, "LIKE"); ok {
+			t := p.next()
+			if t.kind != tokenString {
+				return nil, fmt.Errorf("expected a string pattern after LIKE, found %v", t)
+			}
+			left = &likeExpression{negate: negate, value: left, pattern: compileLikePattern(t.text)}
+			continue
+		}
+		if _, ok := p.accept(tokenKeyword, "IN"); ok {
+			set, err := p.parseSet()
+			if err != nil {
+				return nil, err
+			}
+			left = &inExpression{negate: negate, value: left, set: set}
+			continue
+		}
+		return left, nil
+	}
+}
+
+func (p *parser) parseSet() ([]Expression, error) {
+	if err := p.expect(tokenLeftParen, "("); err != nil {
+		return nil, err
+	}
+	var set []Expression
+	for {
+		e, err := p.parseLogic()
+		if err != nil {
+			return nil, err
+		}
+		set = append(set, e)
+		if _, ok := p.accept(tokenComma, ","); !ok {
+			break
+		}
+	}
+	if err := p.expect(tokenRightParen, ")"); err != nil {
+		return nil, err
+	}
+	return set, nil
+}
+
+func (p *parser) parseUnary() (Expression, error) {
+	if _, ok := p.accept(tokenKeyword, "NOT"); ok {
+		e, err := p.parseUnary()
+		if err != nil {
+			return nil, err
+		}
+		return &notExpression{value: e}, nil
+	}
+	if _, ok := p.accept(tokenOperator, "-"); ok {
+		e, err := p.parseUnary()
+		if err != nil {
+			return nil, err
+		}
+		return &negateExpression{value: e}, nil
+	}
+	return p.parsePrimary()
+}
+
+func (p *parser) parsePrimary() (Expression, error) {
+	t := p.next()
+	switch t.kind {
+	case tokenString:
+		return literal{value: t.text}, nil
+	case tokenInteger:
+		i, err := strconv.ParseInt(t.text, 10, 32)
+		if err != nil {
+			return nil, fmt.Errorf("integer literal %v out of range", t)
+		}
+		return literal{value: int32(i)}, nil
+	case tokenLeftParen:
+		e, err := p.parseLogic()
+		if err != nil {
+			return nil, err
+		}
+		if err := p.expect(tokenRightParen, ")"); err != nil {
+			return nil, err
+		}
+		return e, nil
+	case tokenKeyword:
+		switch t.text {
+		case "TRUE":
+			return literal{value: true}, nil
+		case "FALSE":
+			return literal{value: false}, nil
+		case "EXISTS":
+			id := p.next()
+			if id.kind != tokenIdentifier {
+				return nil, fmt.Errorf("expected an attribute name after EXISTS, found %v", id)
+			}
+			return &existsExpression{attribute: strings.ToLower(id.text)}, nil
+		}
+	case tokenIdentifier:
+		if _, ok := p.accept(tokenLeftParen, "("); ok {
+			return p.parseFunction(t)
+		}
+		if strings.Contains(t.text, "_") {
+			return nil, fmt.Errorf("invalid attribute name %v", t)
+		}
+		return &attributeExpression{attribute: strings.ToLower(t.text)}, nil
+	}
+	return nil, fmt.Errorf("unexpected %v", t)
+}
+
+func (p *parser) parseFunction(name token) (Expression, error) {
+	fn, ok := functions[strings.ToUpper(name.text)]
+	if !ok {
+		return nil, fmt.Errorf("unknown function %v", name)
+	}
+	var args []Expression
+	if _, ok := p.accept(tokenRightParen, ")"); !ok {
+		for {
+			e, err := p.parseLogic()
+			if err != nil {
+				return nil, err
+			}
+			args = append(args, e)
+			if _, ok := p.accept(tokenComma, ","); !ok {
+				break
+			}
+		}
+		if err := p.expect(tokenRightParen, ")"); err != nil {
+			return nil, err
+		}
+	}
+	if len(args) < fn.minArgs || (fn.maxArgs >= 0 && len(args) > fn.maxArgs) {
+		return nil, fmt.Errorf("wrong number of arguments for function %v: %d", name, len(args))
+	}
+	return &functionExpression{name: strings.ToUpper(name.text), fn: fn, args: args}, nil
+}
+
+// compileLikePattern translates a LIKE pattern into an anchored regular expression. '%' matches
+// any sequence of characters, '_' matches a single character and '\' escapes the next character.
+func compileLikePattern(pattern string) *regexp.Regexp {
+	var b strings.Builder
+	b.WriteString("(?s)^")
+	runes := []rune(pattern)
+	for i := 0; i < len(runes); i++ {
+		switch c := runes[i]; c {
+		case '%':
+			b.WriteString(".*")
+		case '_':
+			b.WriteString(".")
+		case '\\':
+			if i+1 < len(runes) {
+				i++
+				b.WriteString(regexp.QuoteMeta(string(runes[i])))
+			}
+		default:
+			b.WriteString(regexp.QuoteMeta(string(c)))
+		}
+	}
+	b.WriteString("$")
+	return regexp.MustCompile(b.String())
+}
@@ -0,0 +1,301 @@
+/*
+Copyright 2021 Google LLC
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+// Package eventfilter implements the trigger filter dialects of the CloudEvents Subscriptions API
+// (exact, prefix, suffix, all, any, not and CloudEvents SQL) on top of the targets config.
+package eventfilter
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"strings"
+	"time"
+
+	"github.com/cloudevents/sdk-go/v2/event"
+	"github.com/cloudevents/sdk-go/v2/types"
+	"go.opencensus.io/trace"
+	"go.uber.org/zap"
+
+	"github.com/google/knative-gcp/pkg/broker/config"
+	"github.com/google/knative-gcp/pkg/broker/eventfilter/cesql"
+	"github.com/google/knative-gcp/pkg/logging"
+)
+
+// Filter decides whether an event should be delivered.
+type Filter interface {
+	// Filter returns true if the event passes the filter.
+	Filter(ctx context.Context, event *event.Event) bool
+}
+
+// FilterFunc adapts a function to the Filter interface.
+type FilterFunc func(ctx context.Context, event *event.Event) bool
+
+// Filter calls f(ctx, event).
+func (f FilterFunc) Filter(ctx context.Context, event *event.Event) bool {
+	return f(ctx, event)
+}
+
+// passAll is the filter of targets without any filter.
+var passAll = FilterFunc(func(context.Context, *event.Event) bool { return true })
+
+// failAll is the filter of targets whose filters cannot be compiled. Such targets should have
+// been rejected by the webhook, so it is safer to not deliver anything to them.
+var failAll = FilterFunc(func(context.Context, *event.Event) bool { return false })
+
+// NewTargetFilter compiles all the filters of a target into a single Filter. The event must pass
+// both the target's filter attributes and each of its Subscriptions API filters.
+func NewTargetFilter(target *config.Target) (Filter, error) {
+	var filters []Filter
+	if len(target.FilterAttributes) > 0 {
+		filters = append(filters, NewAttributesFilter(target.FilterAttributes))
+	}
+	for _, f := range target.Filters {
+		compiled, err := Compile(f)
+		if err != nil {
+			return nil, err
+		}
+		filters = append(filters, compiled)
+	}
+	switch len(filters) {
+	case 0:
+		return passAll, nil
+	case 1:
+		return filters[0], nil
+	default:
+		return allFilter(filters), nil
+	}
+}
+
+// Compile compiles a single Subscriptions API filter. Exactly one dialect must be set.
+func Compile(f *config.Filter) (Filter, error) {
+	var compiled []Filter
+	if len(f.Exact) > 0 {
+		compiled = append(compiled, newStringFilter("exact", f.Exact, func(v, want string) bool { return v == want }))
+	}
+	if len(f.Prefix) > 0 {
+		compiled = append(compiled, newStringFilter("prefix", f.Prefix, strings.HasPrefix))
+	}
+	if len(f.Suffix) > 0 {
+		compiled = append(compiled, newStringFilter("suffix", f.Suffix, strings.HasSuffix))
+	}
+	if len(f.All) > 0 {
+		all, err := compileAll(f.All)
+		if err != nil {
+			return nil, fmt.Errorf("all: %w", err)
+		}
+		compiled = append(compiled, allFilter(all))
+	}
+	if len(f.Any) > 0 {
+		any, err := compileAll(f.Any)
+		if err != nil {
+			return nil, fmt.Errorf("any: %w", err)
+		}
+		compiled = append(compiled, anyFilter(any))
+	}
+	if f.Not != nil {
+		not, err := Compile(f.Not)
+		if err != nil {
+			return nil, fmt.Errorf("not: %w", err)
+		}
+		compiled = append(compiled, notFilter{not})
+	}
+	if f.Cesql != "" {
+		expr, err := cesql.Parse(f.Cesql)
+		if err != nil {
+			return nil, fmt.Errorf("cesql: %w", err)
+		}
+		compiled = append(compiled, &cesqlFilter{expression: f.Cesql, parsed: expr})
+	}
+	switch len(compiled) {
+	case 0:
+		return nil, errors.New("filter has no dialect set")
+	case 1:
+		return compiled[0], nil
+	default:
+		return nil, errors.New("filter has more than one dialect set")
+	}
+}
+
+func compileAll(filters []*config.Filter) ([]Filter, error) {
+	compiled := make([]Filter, 0, len(filters))
+	for _, f := range filters {
+		c, err := Compile(f)
+		if err != nil {
+			return nil, err
+		}
+		compiled = append(compiled, c)
+	}
+	return compiled, nil
+}
+
+// NewAttributesFilter returns the filter of the legacy trigger filter attributes. The event
+// attribute must equal the given value, and an empty value matches any value of an existing
+// attribute.
+func NewAttributesFilter(attrs map[string]string) Filter {
+	return FilterFunc(func(ctx context.Context, event *event.Event) bool {
+		return PassFilter(ctx, attrs, event)
+	})
+}
+
+// PassFilter checks given event against attributes available in the attrs map to determine
+// if the event should pass or not.
+func PassFilter(ctx context.Context, attrs map[string]string, event *event.Event) bool {
+	// Set standard context attributes. The attributes available may not be
+	// exactly the same as the attributes defined in the current version of the
+	// CloudEvents spec.
+	ce := map[string]interface{}{
+		"specversion":     event.SpecVersion(),
+		"type":            event.Type(),
+		"source":          event.Source(),
+		"subject":         event.Subject(),
+		"id":              event.ID(),
+		"time":            event.Time().String(),
+		"schemaurl":       event.DataSchema(),
+		"datacontenttype": event.DataContentType(),
+		"datamediatype":   event.DataMediaType(),
+		// TODO: use data_base64 when SDK supports it.
+		"datacontentencoding": event.DeprecatedDataContentEncoding(),
+	}
+	ext := event.Extensions()
+	for k, v := range ext {
+		ce[k] = v
+	}
+
+	for k, v := range attrs {
+		var value interface{}
+		value, ok := ce[k]
+		// If the attribute does not exist in the event, return false.
+		if !ok {
+			logging.FromContext(ctx).Debug("Attribute not found", zap.String("attribute", k))
+			trace.FromContext(ctx).Annotatef(nil, "event missing filter attribute %q", k)
+			return false
+		}
+		// If the attribute is not set to any and is different than the one from the event, return false.
+		if v != "" && v != value {
+			logging.FromContext(ctx).Debug("Attribute had non-matching value", zap.String("attribute", k), zap.String("filter", v), zap.Any("received", value))
+			trace.FromContext(ctx).Annotatef(nil, "event attribute %q does not match filter value %q", k, v)
+			return false
+		}
+	}
+	return true
+}
+
+// stringFilter implements the exact, prefix and suffix dialects.
+type stringFilter struct {
+	dialect string
+	attrs   map[string]string
+	match   func(value, want string) bool
+}
+
+func newStringFilter(dialect string, attrs map[string]string, match func(value, want string) bool) *stringFilter {
+	return &stringFilter{dialect: dialect, attrs: attrs, match: match}
+}
+
+func (f *stringFilter) Filter(ctx context.Context, event *event.Event) bool {
+	for k, want := range f.attrs {
+		value, ok := Attribute(event, k)
+		if !ok {
+			trace.FromContext(ctx).Annotatef(nil, "event missing %s filter attribute %q", f.dialect, k)
+			return false
+		}
+		if !f.match(value, want) {
+			trace.FromContext(ctx).Annotatef(nil, "event attribute %q does not match %s filter value %q", k, f.dialect, want)
+			return false
+		}
+	}
+	return true
+}
+
+type allFilter []Filter
+
+func (f allFilter) Filter(ctx context.Context, event *event.Event) bool {
+	for _, filter := range f {
+		if !filter.Filter(ctx, event) {
+			return false
+		}
+	}
+	return true
+}
+
+type anyFilter []Filter
+
+func (f anyFilter) Filter(ctx context.Context, event *event.Event) bool {
+	for _, filter := range f {
+		if filter.Filter(ctx, event) {
+			return true
+		}
+	}
+	return false
+}
+
+type notFilter struct {
+	filter Filter
+}
+
+func (f notFilter) Filter(ctx context.Context, event *event.Event) bool {
+	return !f.filter.Filter(ctx, event)
+}
+
+type cesqlFilter struct {
+	expression string
+	parsed     cesql.Expression
+}
+
+func (f *cesqlFilter) Filter(ctx context.Context, event *event.Event) bool {
+	pass, err := cesql.Matches(f.parsed, event)
+	if err != nil {
+		logging.FromContext(ctx).Debug("Error evaluating CESQL expression", zap.String("expression", f.expression), zap.Error(err))
+		trace.FromContext(ctx).Annotatef(nil, "error evaluating CESQL filter %q: %v", f.expression, err)
+		return false
+	}
+	if !pass {
+		trace.FromContext(ctx).Annotatef(nil, "event does not match CESQL filter %q", f.expression)
+	}
+	return pass
+}
+
+// Attribute returns the string value of a context attribute or extension of the event. The
+// second return value is false if the event doesn't have the attribute.
+func Attribute(event *event.Event, name string) (string, bool) {
+	switch name {
+	case "specversion":
+		return event.SpecVersion(), true
+	case "id":
+		return event.ID(), true
+	case "source":
+		return event.Source(), true
+	case "type":
+		return event.Type(), true
+	case "subject":
+		return event.Subject(), event.Subject() != ""
+	case "time":
+		return event.Time().UTC().Format(time.RFC3339Nano), !event.Time().IsZero()
+	case "dataschema":
+		return event.DataSchema(), event.DataSchema() != ""
+	case "datacontenttype":
+		return event.DataContentType(), event.DataContentType() != ""
+	}
+	v, ok := event.Extensions()[name]
+	if !ok {
+		return "", false
+	}
+	s, err := types.Format(v)
+	if err != nil {
+		return "", false
+	}
+	return s, true
+}
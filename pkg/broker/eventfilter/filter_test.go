@@ -0,0 +1,182 @@
+/*
+Copyright 2021 Google LLC
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package eventfilter
+
+import (
+	"context"
+	"testing"
+
+	"github.com/cloudevents/sdk-go/v2/event"
+
+	"github.com/google/knative-gcp/pkg/broker/config"
+)
+
+func storageEvent() *event.Event {
+	e := event.New()
+	e.SetID("1234")
+	e.SetSource("//storage.googleapis.com/projects/_/buckets/my-bucket")
+	e.SetType("google.cloud.storage.object.v1.finalized")
+	e.SetSubject("objects/photo.jpg")
+	e.SetExtension("bucket", "my-bucket")
+	return &e
+}
+
+func TestNewTargetFilter(t *testing.T) {
+	cases := []struct {
+		name   string
+		target *config.Target
+		want   bool
+	}{{
+		name:   "no filter",
+		target: &config.Target{},
+		want:   true,
+	}, {
+		name:   "attributes pass",
+		target: &config.Target{FilterAttributes: map[string]string{"type": "google.cloud.storage.object.v1.finalized", "bucket": ""}},
+		want:   true,
+	}, {
+		name:   "attributes fail",
+		target: &config.Target{FilterAttributes: map[string]string{"bucket": "other"}},
+		want:   false,
+	}, {
+		name:   "exact pass",
+		target: &config.Target{Filters: []*config.Filter{{Exact: map[string]string{"bucket": "my-bucket"}}}},
+		want:   true,
+	}, {
+		name:   "exact fail",
+		target: &config.Target{Filters: []*config.Filter{{Exact: map[string]string{"bucket": "my"}}}},
+		want:   false,
+	}, {
+		name:   "exact missing attribute",
+		target: &config.Target{Filters: []*config.Filter{{Exact: map[string]string{"dataschema": ""}}}},
+		want:   false,
+	}, {
+		name:   "prefix pass",
+		target: &config.Target{Filters: []*config.Filter{{Prefix: map[string]string{"type": "google.cloud.storage.object.v1."}}}},
+		want:   true,
+	}, {
+		name:   "prefix fail",
+		target: &config.Target{Filters: []*config.Filter{{Prefix: map[string]string{"type": "google.cloud.pubsub."}}}},
+		want:   false,
+	}, {
+		name:   "suffix pass",
+		target: &config.Target{Filters: []*config.Filter{{Suffix: map[string]string{"subject": ".jpg"}}}},
+		want:   true,
+	}, {
+		name:   "suffix fail",
+		target: &config.Target{Filters: []*config.Filter{{Suffix: map[string]string{"subject": ".png"}}}},
+		want:   false,
+	}, {
+		name: "all pass",
+		target: &config.Target{Filters: []*config.Filter{{All: []*config.Filter{
+			{Prefix: map[string]string{"type": "google.cloud.storage."}},
+			{Suffix: map[string]string{"subject": ".jpg"}},
+		}}}},
+		want: true,
+	}, {
+		name: "all fail",
+		target: &config.Target{Filters: []*config.Filter{{All: []*config.Filter{
+			{Prefix: map[string]string{"type": "google.cloud.storage."}},
+			{Suffix: map[string]string{"subject": ".png"}},
+		}}}},
+		want: false,
+	}, {
+		name: "any pass",
+		target: &config.Target{Filters: []*config.Filter{{Any: []*config.Filter{
+			{Suffix: map[string]string{"subject": ".png"}},
+			{Suffix: map[string]string{"subject": ".jpg"}},
+		}}}},
+		want: true,
+	}, {
+		name: "any fail",
+		target: &config.Target{Filters: []*config.Filter{{Any: []*config.Filter{
+			{Suffix: map[string]string{"subject": ".png"}},
+			{Suffix: map[string]string{"subject": ".gif"}},
+		}}}},
+		want: false,
+	}, {
+		name:   "not pass",
+		target: &config.Target{Filters: []*config.Filter{{Not: &config.Filter{Suffix: map[string]string{"subject": ".png"}}}}},
+		want:   true,
+	}, {
+		name:   "not fail",
+		target: &config.Target{Filters: []*config.Filter{{Not: &config.Filter{Suffix: map[string]string{"subject": ".jpg"}}}}},
+		want:   false,
+	}, {
+		name:   "cesql pass",
+		target: &config.Target{Filters: []*config.Filter{{Cesql: "type LIKE 'google.cloud.storage.%' AND bucket = 'my-bucket'"}}},
+		want:   true,
+	}, {
+		name:   "cesql fail",
+		target: &config.Target{Filters: []*config.Filter{{Cesql: "bucket IN ('a', 'b')"}}},
+		want:   false,
+	}, {
+		name:   "cesql error",
+		target: &config.Target{Filters: []*config.Filter{{Cesql: "missing = 'a'"}}},
+		want:   false,
+	}, {
+		name: "attributes and filters",
+		target: &config.Target{
+			FilterAttributes: map[string]string{"bucket": "my-bucket"},
+			Filters: []*config.Filter{
+				{Prefix: map[string]string{"type": "google.cloud.storage."}},
+				{Suffix: map[string]string{"subject": ".png"}},
+			},
+		},
+		want: false,
+	}}
+	for _, tc := range cases {
+		t.Run(tc.name, func(t *testing.T) {
+			f, err := NewTargetFilter(tc.target)
+			if err != nil {
+				t.Fatalf("NewTargetFilter returned error: %v", err)
+			}
+			if got := f.Filter(context.Background(), storageEvent()); got != tc.want {
+				t.Errorf("Filter = %v, want %v", got, tc.want)
+			}
+		})
+	}
+}
+
+func TestCompileErrors(t *testing.T) {
+	cases := []struct {
+		name   string
+		filter *config.Filter
+	}{{
+		name:   "no dialect",
+		filter: &config.Filter{},
+	}, {
+		name: "multiple dialects",
+		filter: &config.Filter{
+			Exact:  map[string]string{"type": "a"},
+			Prefix: map[string]string{"type": "a"},
+		},
+	}, {
+		name:   "invalid cesql",
+		filter: &config.Filter{Cesql: "type ="},
+	}, {
+		name:   "invalid nested filter",
+		filter: &config.Filter{Any: []*config.Filter{{Not: &config.Filter{}}}},
+	}}
+	for _, tc := range cases {
+		t.Run(tc.name, func(t *testing.T) {
+			if _, err := Compile(tc.filter); err == nil {
+				t.Error("Compile succeeded, want error")
+			}
+		})
+	}
+}
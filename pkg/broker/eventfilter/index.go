@@ -0,0 +1,123 @@
+/*
+Copyright 2021 Google LLC
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package eventfilter
+
+import (
+	"context"
+	"sync/atomic"
+
+	"github.com/cloudevents/sdk-go/v2/event"
+
+	"github.com/google/knative-gcp/pkg/broker/config"
+)
+
+// Index answers whether any target of a CellTenant is interested in an event.
+//
+// The index is rebuilt from the targets config on every Sync. Targets whose filters require an
+// exact event type are indexed by that type, so an event is only checked against the targets of
+// its own CellTenant that may match its type.
+type Index struct {
+	// cache holds the compiled filters across syncs.
+	cache *Cache
+	// tenants holds the current map[config.CellTenantKey]*tenantIndex snapshot.
+	tenants atomic.Value
+}
+
+// tenantIndex holds the filters of the targets of a single CellTenant.
+type tenantIndex struct {
+	// passAll is true if any target has no filter at all.
+	passAll bool
+	// byType holds the filters of the targets requiring an exact event type, keyed by that type.
+	byType map[string][]Filter
+	// others holds the filters of all the other targets.
+	others []Filter
+}
+
+// NewIndex creates an empty Index.
+func NewIndex() *Index {
+	i := &Index{cache: NewCache()}
+	i.tenants.Store(map[config.CellTenantKey]*tenantIndex{})
+	return i
+}
+
+// Sync rebuilds the index from the given targets. It must be called each time the targets
+// config is updated. Concurrent lookups keep using the previous snapshot until Sync returns.
+func (i *Index) Sync(ctx context.Context, targets config.ReadonlyTargets) {
+	tenants := make(map[config.CellTenantKey]*tenantIndex)
+	targets.RangeCellTenants(func(tenant *config.CellTenant) bool {
+		t := &tenantIndex{byType: make(map[string][]Filter)}
+		for _, target := range tenant.Targets {
+			t.add(target, i.cache.Get(ctx, target))
+		}
+		tenants[*tenant.Key()] = t
+		return true
+	})
+	i.cache.Prune(targets)
+	i.tenants.Store(tenants)
+}
+
+// HasTarget returns true if the event passes the filters of at least one target of the
+// CellTenant.
+func (i *Index) HasTarget(ctx context.Context, tenant *config.CellTenantKey, event *event.Event) bool {
+	t, ok := i.tenants.Load().(map[config.CellTenantKey]*tenantIndex)[*tenant]
+	if !ok {
+		return false
+	}
+	if t.passAll {
+		return true
+	}
+	return anyFilter(t.byType[event.Type()]).Filter(ctx, event) || anyFilter(t.others).Filter(ctx, event)
+}
+
+func (t *tenantIndex) add(target *config.Target, filter Filter) {
+	if len(target.FilterAttributes) == 0 && len(target.Filters) == 0 {
+		t.passAll = true
+		return
+	}
+	if eventType, ok := requiredType(target); ok {
+		t.byType[eventType] = append(t.byType[eventType], filter)
+		return
+	}
+	t.others = append(t.others, filter)
+}
+
+// requiredType returns the event type that an event must have to pass the target's filters,
+// if there is one.
+func requiredType(target *config.Target) (string, bool) {
+	// An empty value of a filter attribute matches any value.
+	if eventType := target.FilterAttributes["type"]; eventType != "" {
+		return eventType, true
+	}
+	for _, f := range target.Filters {
+		if eventType, ok := requiredFilterType(f); ok {
+			return eventType, true
+		}
+	}
+	return "", false
+}
+
+func requiredFilterType(f *config.Filter) (string, bool) {
+	if eventType, ok := f.Exact["type"]; ok {
+		return eventType, true
+	}
+	for _, nested := range f.All {
+		if eventType, ok := requiredFilterType(nested); ok {
+			return eventType, true
+		}
+	}
+	return "", false
+}
@@ -0,0 +1,134 @@
+/*
+Copyright 2021 Google LLC
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package eventfilter
+
+import (
+	"context"
+	"testing"
+
+	"github.com/google/knative-gcp/pkg/broker/config"
+	"github.com/google/knative-gcp/pkg/broker/config/memory"
+)
+
+func TestIndex(t *testing.T) {
+	cases := []struct {
+		name    string
+		targets []*config.Target
+		// other holds the targets of another broker, which must never be considered.
+		other []*config.Target
+		want  bool
+	}{{
+		name: "no targets",
+		want: false,
+	}, {
+		name:    "target without filter",
+		targets: []*config.Target{{Name: "t1"}},
+		want:    true,
+	}, {
+		name: "matching type attribute",
+		targets: []*config.Target{
+			{Name: "t1", FilterAttributes: map[string]string{"type": "google.cloud.pubsub.topic.v1.messagePublished"}},
+			{Name: "t2", FilterAttributes: map[string]string{"type": "google.cloud.storage.object.v1.finalized"}},
+		},
+		want: true,
+	}, {
+		name: "matching type but not other attributes",
+		targets: []*config.Target{
+			{Name: "t1", FilterAttributes: map[string]string{"type": "google.cloud.storage.object.v1.finalized", "bucket": "other"}},
+		},
+		want: false,
+	}, {
+		name: "any type attribute",
+		targets: []*config.Target{
+			{Name: "t1", FilterAttributes: map[string]string{"type": "", "bucket": "my-bucket"}},
+		},
+		want: true,
+	}, {
+		name: "no matching type",
+		targets: []*config.Target{
+			{Name: "t1", FilterAttributes: map[string]string{"type": "google.cloud.pubsub.topic.v1.messagePublished"}},
+			{Name: "t2", Filters: []*config.Filter{{Exact: map[string]string{"type": "google.cloud.storage.object.v1.deleted"}}}},
+		},
+		want: false,
+	}, {
+		name: "matching exact type nested in all",
+		targets: []*config.Target{
+			{Name: "t1", Filters: []*config.Filter{{All: []*config.Filter{
+				{Exact: map[string]string{"type": "google.cloud.storage.object.v1.finalized"}},
+				{Suffix: map[string]string{"subject": ".jpg"}},
+			}}}},
+		},
+		want: true,
+	}, {
+		name: "matching unindexed filter",
+		targets: []*config.Target{
+			{Name: "t1", FilterAttributes: map[string]string{"type": "google.cloud.pubsub.topic.v1.messagePublished"}},
+			{Name: "t2", Filters: []*config.Filter{{Prefix: map[string]string{"type": "google.cloud.storage."}}}},
+		},
+		want: true,
+	}, {
+		name:    "matching target of another broker",
+		targets: []*config.Target{{Name: "t1", FilterAttributes: map[string]string{"type": "google.cloud.pubsub.topic.v1.messagePublished"}}},
+		other:   []*config.Target{{Name: "t2"}},
+		want:    false,
+	}}
+	for _, tc := range cases {
+		t.Run(tc.name, func(t *testing.T) {
+			ctx := context.Background()
+			brokerKey := config.TestOnlyBrokerKey("ns", "broker")
+			targets := memory.NewEmptyTargets()
+			targets.MutateCellTenant(brokerKey, func(m config.CellTenantMutation) {
+				m.UpsertTargets(tc.targets...)
+			})
+			targets.MutateCellTenant(config.TestOnlyBrokerKey("ns", "other"), func(m config.CellTenantMutation) {
+				m.UpsertTargets(tc.other...)
+			})
+
+			i := NewIndex()
+			if i.HasTarget(ctx, brokerKey, storageEvent()) {
+				t.Error("HasTarget = true before the first sync, want false")
+			}
+			i.Sync(ctx, targets)
+			if got := i.HasTarget(ctx, brokerKey, storageEvent()); got != tc.want {
+				t.Errorf("HasTarget = %v, want %v", got, tc.want)
+			}
+		})
+	}
+}
+
+func TestIndexSync(t *testing.T) {
+	ctx := context.Background()
+	brokerKey := config.TestOnlyBrokerKey("ns", "broker")
+	targets := memory.NewEmptyTargets()
+	targets.MutateCellTenant(brokerKey, func(m config.CellTenantMutation) {
+		m.UpsertTargets(&config.Target{Name: "t1", Filters: []*config.Filter{{Suffix: map[string]string{"subject": ".jpg"}}}})
+	})
+
+	i := NewIndex()
+	i.Sync(ctx, targets)
+	if !i.HasTarget(ctx, brokerKey, storageEvent()) {
+		t.Error("HasTarget = false, want true")
+	}
+
+	targets.MutateCellTenant(brokerKey, func(m config.CellTenantMutation) {
+		m.DeleteTargets(&config.Target{Name: "t1"})
+	})
+	i.Sync(ctx, targets)
+	if i.HasTarget(ctx, brokerKey, storageEvent()) {
+		t.Error("HasTarget = true after the target was deleted, want false")
+	}
+}
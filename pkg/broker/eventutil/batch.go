@@ -0,0 +1,92 @@
+/*
+Copyright 2021 Google LLC
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package eventutil
+
+import (
+	"encoding/json"
+	"fmt"
+	"io/ioutil"
+	"mime"
+	nethttp "net/http"
+	"time"
+
+	"github.com/cloudevents/sdk-go/v2/event"
+)
+
+// IsBatchRequest returns true if the request holds events in the CloudEvents batch mode of the
+// HTTP protocol binding.
+func IsBatchRequest(request *nethttp.Request) bool {
+	mediaType, _, err := mime.ParseMediaType(request.Header.Get("Content-Type"))
+	return err == nil && mediaType == event.ApplicationCloudEventsBatchJSON
+}
+
+// EventsFromBatchRequest reads the events of a batch request. Like in binary and structured
+// requests, the events without a time are set to the current time. It fails if any of the events
+// is invalid.
+func EventsFromBatchRequest(request *nethttp.Request) ([]*event.Event, error) {
+	body, err := ioutil.ReadAll(request.Body)
+	if err != nil {
+		return nil, err
+	}
+	var events []*event.Event
+	if err := json.Unmarshal(body, &events); err != nil {
+		return nil, fmt.Errorf("malformed batch: %w", err)
+	}
+	now := time.Now()
+	for i, e := range events {
+		if e == nil {
+			return nil, fmt.Errorf("invalid event %d of the batch: null", i)
+		}
+		if err := e.Validate(); err != nil {
+			return nil, fmt.Errorf("invalid event %d of the batch: %w", i, err)
+		}
+		if e.Time().IsZero() {
+			e.SetTime(now)
+		}
+	}
+	return events, nil
+}
+
+// BatchResult is the result of an event of a batch request.
+type BatchResult struct {
+	// ID is the ID of the event.
+	ID string `json:"id"`
+	// StatusCode is the status code the event would have had if it was sent alone.
+	StatusCode int `json:"statusCode"`
+	// Error is the error message of a failed event.
+	Error string `json:"error,omitempty"`
+}
+
+// WriteBatchResponse writes the response of a batch request. If all the events succeeded, it
+// responds with the status code they share. Otherwise, it responds with the highest status code
+// of the failed events, and the results of all the events in a JSON array, so that the sender
+// can retry only the failed events.
+func WriteBatchResponse(response nethttp.ResponseWriter, successCode int, results []BatchResult) error {
+	statusCode := successCode
+	for _, r := range results {
+		if r.StatusCode != successCode && (statusCode == successCode || r.StatusCode > statusCode) {
+			statusCode = r.StatusCode
+		}
+	}
+	if statusCode == successCode {
+		response.WriteHeader(statusCode)
+		return nil
+	}
+	response.Header().Set("Content-Type", "application/json")
+	response.WriteHeader(statusCode)
+	return json.NewEncoder(response).Encode(results)
+}
@@ -0,0 +1,136 @@
+/*
+Copyright 2021 Google LLC
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package eventutil
+
+import (
+	"encoding/json"
+	nethttp "net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/google/go-cmp/cmp"
+)
+
+func TestIsBatchRequest(t *testing.T) {
+	for contentType, want := range map[string]bool{
+		"application/cloudevents-batch+json":                true,
+		"application/cloudevents-batch+json; charset=UTF-8": true,
+		"application/cloudevents+json":                      false,
+		"application/json":                                  false,
+		"":                                                  false,
+	} {
+		request := httptest.NewRequest(nethttp.MethodPost, "/", nil)
+		request.Header.Set("Content-Type", contentType)
+		if got := IsBatchRequest(request); got != want {
+			t.Errorf("IsBatchRequest(%q) = %v, want %v", contentType, got, want)
+		}
+	}
+}
+
+func TestEventsFromBatchRequest(t *testing.T) {
+	cases := []struct {
+		name    string
+		body    string
+		wantIDs []string
+		wantErr bool
+	}{{
+		name:    "events",
+		body:    `[{"specversion":"1.0","id":"1","source":"src","type":"type"},{"specversion":"1.0","id":"2","source":"src","type":"type","time":"2021-01-01T00:00:00Z","data":{"a":"b"}}]`,
+		wantIDs: []string{"1", "2"},
+	}, {
+		name: "empty batch",
+		body: `[]`,
+	}, {
+		name:    "not a batch",
+		body:    `{"specversion":"1.0","id":"1","source":"src","type":"type"}`,
+		wantErr: true,
+	}, {
+		name:    "invalid event",
+		body:    `[{"specversion":"1.0","id":"1","source":"src","type":"type"},{"specversion":"1.0","id":"2"}]`,
+		wantErr: true,
+	}, {
+		name:    "null event",
+		body:    `[null]`,
+		wantErr: true,
+	}}
+	for _, tc := range cases {
+		t.Run(tc.name, func(t *testing.T) {
+			request := httptest.NewRequest(nethttp.MethodPost, "/", strings.NewReader(tc.body))
+			events, err := EventsFromBatchRequest(request)
+			if (err != nil) != tc.wantErr {
+				t.Fatalf("EventsFromBatchRequest() error = %v, wantErr %v", err, tc.wantErr)
+			}
+			var ids []string
+			for _, e := range events {
+				ids = append(ids, e.ID())
+				if e.Time().IsZero() {
+					t.Errorf("event %s has no time", e.ID())
+				}
+			}
+			if diff := cmp.Diff(tc.wantIDs, ids); diff != "" {
+				t.Errorf("EventsFromBatchRequest() IDs (-want,+got): %v", diff)
+			}
+		})
+	}
+}
+
+func TestWriteBatchResponse(t *testing.T) {
+	cases := []struct {
+		name        string
+		results     []BatchResult
+		wantCode    int
+		wantResults []BatchResult
+	}{{
+		name:     "all accepted",
+		results:  []BatchResult{{ID: "1", StatusCode: 202}, {ID: "2", StatusCode: 202}},
+		wantCode: 202,
+	}, {
+		name:     "empty batch",
+		wantCode: 202,
+	}, {
+		name:        "partial failure",
+		results:     []BatchResult{{ID: "1", StatusCode: 202}, {ID: "2", StatusCode: 429, Error: "overflow"}},
+		wantCode:    429,
+		wantResults: []BatchResult{{ID: "1", StatusCode: 202}, {ID: "2", StatusCode: 429, Error: "overflow"}},
+	}, {
+		name:        "highest failure",
+		results:     []BatchResult{{ID: "1", StatusCode: 500, Error: "failed"}, {ID: "2", StatusCode: 429, Error: "overflow"}},
+		wantCode:    500,
+		wantResults: []BatchResult{{ID: "1", StatusCode: 500, Error: "failed"}, {ID: "2", StatusCode: 429, Error: "overflow"}},
+	}}
+	for _, tc := range cases {
+		t.Run(tc.name, func(t *testing.T) {
+			response := httptest.NewRecorder()
+			if err := WriteBatchResponse(response, 202, tc.results); err != nil {
+				t.Fatalf("WriteBatchResponse() error = %v", err)
+			}
+			if response.Code != tc.wantCode {
+				t.Errorf("status code got=%d, want=%d", response.Code, tc.wantCode)
+			}
+			var results []BatchResult
+			if response.Body.Len() > 0 {
+				if err := json.Unmarshal(response.Body.Bytes(), &results); err != nil {
+					t.Fatalf("malformed response body %q: %v", response.Body.String(), err)
+				}
+			}
+			if diff := cmp.Diff(tc.wantResults, results); diff != "" {
+				t.Errorf("results (-want,+got): %v", diff)
+			}
+		})
+	}
+}
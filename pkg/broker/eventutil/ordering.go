@@ -0,0 +1,47 @@
+/*
+Copyright 2021 Google LLC
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package eventutil
+
+import (
+	"github.com/cloudevents/sdk-go/v2/event"
+	cetypes "github.com/cloudevents/sdk-go/v2/types"
+
+	"github.com/google/knative-gcp/pkg/broker/config"
+)
+
+// OrderedRetryExtension holds the token of the events sent in order to the retry topic of a
+// target, and of the notices of their delivery sent back to the decouple topic. The ingress
+// removes it from the events it accepts.
+const OrderedRetryExtension = "kgcporderedretry"
+
+// OrderingKey returns the ordering key of the event, in the canonical string encoding of the key
+// extension of the ordering. Events without the extension, or without an ordering, have no
+// ordering key.
+func OrderingKey(e *event.Event, ordering *config.Ordering) string {
+	if ordering == nil {
+		return ""
+	}
+	v, ok := e.Extensions()[ordering.KeyExtension]
+	if !ok {
+		return ""
+	}
+	key, err := cetypes.Format(v)
+	if err != nil {
+		return ""
+	}
+	return key
+}
@@ -0,0 +1,62 @@
+/*
+Copyright 2021 Google LLC
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package eventutil
+
+import (
+	"testing"
+
+	"github.com/cloudevents/sdk-go/v2/event"
+
+	"github.com/google/knative-gcp/pkg/broker/config"
+)
+
+func TestOrderingKey(t *testing.T) {
+	ordering := &config.Ordering{KeyExtension: "partitionkey"}
+	cases := []struct {
+		name      string
+		extension interface{}
+		ordering  *config.Ordering
+		want      string
+	}{{
+		name:      "no ordering",
+		extension: "key",
+	}, {
+		name:     "no extension",
+		ordering: ordering,
+	}, {
+		name:      "string key",
+		extension: "key",
+		ordering:  ordering,
+		want:      "key",
+	}, {
+		name:      "integer key",
+		extension: 42,
+		ordering:  ordering,
+		want:      "42",
+	}}
+	for _, tc := range cases {
+		t.Run(tc.name, func(t *testing.T) {
+			e := event.New()
+			if tc.extension != nil {
+				e.SetExtension("partitionkey", tc.extension)
+			}
+			if got := OrderingKey(&e, tc.ordering); got != tc.want {
+				t.Errorf("OrderingKey() = %q, want %q", got, tc.want)
+			}
+		})
+	}
+}
@@ -0,0 +1,50 @@
+/*
+Copyright 2021 Google LLC
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package eventutil
+
+import (
+	"time"
+
+	"github.com/cloudevents/sdk-go/v2/event"
+	cetypes "github.com/cloudevents/sdk-go/v2/types"
+)
+
+const (
+	// RedeliverAfterExtension is the time before which an event sent to the retry topic must not
+	// be delivered, because the subscriber requested a delay with a Retry-After header.
+	// Intentionally make it short, like the hops attribute.
+	RedeliverAfterExtension = "kgcpredeliverafter"
+)
+
+// SetRedeliverAfter sets the time before which the event must not be redelivered.
+func SetRedeliverAfter(e *event.Event, t time.Time) {
+	e.SetExtension(RedeliverAfterExtension, cetypes.Timestamp{Time: t.UTC()})
+}
+
+// RedeliveryDelay returns how long the event must still wait before it is redelivered. It returns
+// zero if the event has no valid redelivery time, or if this time has passed.
+func RedeliveryDelay(e *event.Event, now time.Time) time.Duration {
+	v, ok := e.Extensions()[RedeliverAfterExtension]
+	if !ok {
+		return 0
+	}
+	t, err := cetypes.ToTime(v)
+	if err != nil || !t.After(now) {
+		return 0
+	}
+	return t.Sub(now)
+}
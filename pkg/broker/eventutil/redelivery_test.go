@@ -0,0 +1,54 @@
+/*
+Copyright 2021 Google LLC
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package eventutil
+
+import (
+	"testing"
+	"time"
+
+	"github.com/cloudevents/sdk-go/v2/event"
+)
+
+func TestRedeliveryDelay(t *testing.T) {
+	now := time.Date(2021, 6, 1, 12, 0, 0, 0, time.UTC)
+	cases := []struct {
+		name  string
+		value interface{}
+		want  time.Duration
+	}{
+		{name: "no extension", want: 0},
+		{name: "future", value: now.Add(time.Minute), want: time.Minute},
+		{name: "past", value: now.Add(-time.Minute), want: 0},
+		{name: "string", value: "2021-06-01T12:00:30Z", want: 30 * time.Second},
+		{name: "invalid", value: "soon", want: 0},
+	}
+	for _, tc := range cases {
+		t.Run(tc.name, func(t *testing.T) {
+			e := event.New()
+			if tc.value != nil {
+				if ts, ok := tc.value.(time.Time); ok {
+					SetRedeliverAfter(&e, ts)
+				} else {
+					e.SetExtension(RedeliverAfterExtension, tc.value)
+				}
+			}
+			if got := RedeliveryDelay(&e, now); got != tc.want {
+				t.Errorf("RedeliveryDelay() = %v, want %v", got, tc.want)
+			}
+		})
+	}
+}
@@ -19,6 +19,7 @@ package context
 import "errors"
 
 var (
-	ErrTargetKeyNotPresent = errors.New("target key not present in the context")
-	ErrBrokerKeyNotPresent = errors.New("broker key not present in the context")
+	ErrTargetKeyNotPresent   = errors.New("target key not present in the context")
+	ErrBrokerKeyNotPresent   = errors.New("broker key not present in the context")
+	ErrPublishTimeNotPresent = errors.New("publish time not present in the context")
 )
@@ -0,0 +1,39 @@
+/*
+Copyright 2021 Google LLC
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package context
+
+import (
+	"context"
+	"testing"
+	"time"
+)
+
+func TestPublishTime(t *testing.T) {
+	_, err := GetPublishTime(context.Background())
+	if err != ErrPublishTimeNotPresent {
+		t.Errorf("error from GetPublishTime got=%v, want=%v", err, ErrPublishTimeNotPresent)
+	}
+	want := time.Date(2021, 5, 1, 0, 0, 0, 0, time.UTC)
+	ctx := WithPublishTime(context.Background(), want)
+	got, err := GetPublishTime(ctx)
+	if err != nil {
+		t.Errorf("unexpected error from GetPublishTime: %v", err)
+	}
+	if !got.Equal(want) {
+		t.Errorf("GetPublishTime got=%v, want=%v", got, want)
+	}
+}
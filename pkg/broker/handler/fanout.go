@@ -29,11 +29,13 @@ import (
 	"go.uber.org/zap"
 
 	"github.com/google/knative-gcp/pkg/broker/config"
+	"github.com/google/knative-gcp/pkg/broker/eventfilter"
 	handlerctx "github.com/google/knative-gcp/pkg/broker/handler/context"
 	"github.com/google/knative-gcp/pkg/broker/handler/processors"
 	"github.com/google/knative-gcp/pkg/broker/handler/processors/deliver"
 	"github.com/google/knative-gcp/pkg/broker/handler/processors/fanout"
 	"github.com/google/knative-gcp/pkg/broker/handler/processors/filter"
+	"github.com/google/knative-gcp/pkg/broker/shard"
 	"github.com/google/knative-gcp/pkg/metrics"
 )
 
@@ -51,6 +53,8 @@ type FanoutPool struct {
 	options *Options
 	targets config.ReadonlyTargets
 	pool    *syncMapBrokerKey
+	// replays holds the handlers of the replay subscriptions of the targets.
+	replays *syncMapReplayKey
 
 	// Pubsub client used to pull events from decoupling topics.
 	pubsubClient *pubsub.Client
@@ -61,6 +65,19 @@ type FanoutPool struct {
 	// And we can set target address dynamically.
 	deliverClient *http.Client
 	statsReporter *metrics.DeliveryReporter
+	// filters holds the compiled trigger filters shared by all handlers.
+	filters *eventfilter.Cache
+	// breakers holds the circuit breakers of the targets shared by all handlers.
+	breakers *deliver.CircuitBreakers
+	// limiters enforce the delivery limits of the targets shared by all handlers.
+	limiters *deliver.Limiters
+	// authenticator provides the credentials of the deliveries shared by all handlers.
+	authenticator *deliver.Authenticator
+	// orderedRetries sends the events with an ordering key to the retry topics in order. It is nil
+	// without shards: the notices of the retry data plane are pulled from the decouple subscription
+	// of the broker, which only reach the replica holding the ordering keys of the broker if it is
+	// the only one pulling the subscription.
+	orderedRetries *deliver.OrderedRetries
 }
 
 type fanoutHandlerCache struct {
@@ -116,10 +133,18 @@ func NewFanoutPool(
 		targets:            targets,
 		options:            options,
 		pool:               &syncMapBrokerKey{},
+		replays:            &syncMapReplayKey{},
 		pubsubClient:       pubsubClient,
 		deliverClient:      deliverClient,
 		deliverRetryClient: retryClient,
 		statsReporter:      statsReporter,
+		filters:            eventfilter.NewCache(),
+		breakers:           options.newCircuitBreakers(statsReporter),
+		limiters:           deliver.NewLimiters(),
+		authenticator:      deliver.NewAuthenticator(deliver.DefaultTokensPath),
+	}
+	if options.Shards != nil {
+		p.orderedRetries = deliver.NewOrderedRetries(pubsubClient)
 	}
 	return p, nil
 }
@@ -132,14 +157,25 @@ func (p *FanoutPool) SyncOnce(ctx context.Context) error {
 	}
 
 	p.pool.Range(func(key config.CellTenantKey, value *fanoutHandlerCache) bool {
-		if _, ok := p.targets.GetCellTenantByKey(&key); !ok {
+		if _, ok := p.targets.GetCellTenantByKey(&key); !ok || !p.options.Shards.Owns(key.String()) {
 			value.Stop()
 			p.pool.Delete(key)
+			// The replica now handling the broker doesn't know about the events retried by this
+			// one, so they must not hold its events back if it comes back here.
+			p.orderedRetries.Release(&key)
 		}
 		return true
 	})
+	p.filters.Prune(p.targets)
+	p.breakers.Prune(p.targets)
+	p.limiters.Prune(p.targets)
+	p.orderedRetries.Prune(p.targets)
 
 	p.targets.RangeCellTenants(func(b *config.CellTenant) bool {
+		// Skip the brokers handled by other replicas.
+		if !p.options.Shards.Owns(b.Key().String()) {
+			return true
+		}
 		if value, ok := p.pool.Load(*b.Key()); ok {
 			// Skip if we don't need to renew the handler.
 			if !value.shouldRenew(b) {
@@ -155,6 +191,9 @@ func (p *FanoutPool) SyncOnce(ctx context.Context) error {
 		if b.State != config.State_READY {
 			return true
 		}
+		if b.Ordering != nil && p.orderedRetries == nil {
+			logging.FromContext(ctx).Warn("the events of the broker are not retried in order without sharding", zap.Stringer("broker", b.Key()))
+		}
 
 		sub := p.pubsubClient.Subscription(b.DecoupleQueue.Subscription)
 		sub.ReceiveSettings = p.options.PubsubReceiveSettings
@@ -162,15 +201,21 @@ func (p *FanoutPool) SyncOnce(ctx context.Context) error {
 		h := NewHandler(
 			sub,
 			processors.ChainProcessors(
-				&fanout.Processor{MaxConcurrency: p.options.MaxConcurrencyPerEvent, Targets: p.targets},
-				&filter.Processor{Targets: p.targets},
+				&fanout.Processor{MaxConcurrency: p.options.MaxConcurrencyPerEvent, Targets: p.targets, OrderedRetries: p.orderedRetries},
+				&filter.Processor{Targets: p.targets, Filters: p.filters},
 				&deliver.Processor{
-					DeliverClient:      p.deliverClient,
-					Targets:            p.targets,
-					RetryOnFailure:     true,
-					DeliverRetryClient: p.deliverRetryClient,
-					DeliverTimeout:     p.options.DeliveryTimeout,
-					StatsReporter:      p.statsReporter,
+					DeliverClient:       p.deliverClient,
+					Targets:             p.targets,
+					RetryOnFailure:      true,
+					DeliverRetryClient:  p.deliverRetryClient,
+					DeliverTimeout:      p.options.DeliveryTimeout,
+					StatsReporter:       p.statsReporter,
+					Breakers:            p.breakers,
+					Limiters:            p.limiters,
+					RetryLimitedTargets: p.options.Shards != nil,
+					Authenticator:       p.authenticator,
+					ClaimChecks:         p.options.ClaimCheckStore,
+					OrderedRetries:      p.orderedRetries,
 				},
 			),
 			p.options.TimeoutPerEvent,
@@ -192,10 +237,26 @@ func (p *FanoutPool) SyncOnce(ctx context.Context) error {
 		p.pool.Store(*b.Key(), hc)
 		return true
 	})
+	p.syncReplays(ctx)
 
 	return nil
 }
 
+// Targets returns the targets handled by the pool.
+func (p *FanoutPool) Targets() config.ReadonlyTargets {
+	return p.targets
+}
+
+// Assignment returns the brokers handled by this replica of the pool.
+func (p *FanoutPool) Assignment() shard.Assignment {
+	var keys []string
+	p.targets.RangeCellTenants(func(b *config.CellTenant) bool {
+		keys = append(keys, b.Key().String())
+		return true
+	})
+	return p.options.Shards.Assignment(keys)
+}
+
 // syncMapBrokerKey is a typed version of sync.Map.
 type syncMapBrokerKey struct {
 	m sync.Map
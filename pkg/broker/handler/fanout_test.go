@@ -18,10 +18,13 @@ package handler
 
 import (
 	"context"
+	"fmt"
 	"net/http"
 	"testing"
 	"time"
 
+	"cloud.google.com/go/pubsub"
+	"cloud.google.com/go/pubsub/pstest"
 	"github.com/cloudevents/sdk-go/v2/event"
 	"github.com/google/go-cmp/cmp"
 	"golang.org/x/sync/errgroup"
@@ -29,6 +32,7 @@ import (
 	"github.com/google/knative-gcp/pkg/broker/config"
 	"github.com/google/knative-gcp/pkg/broker/eventutil"
 	handlertesting "github.com/google/knative-gcp/pkg/broker/handler/testing"
+	"github.com/google/knative-gcp/pkg/broker/shard"
 	reportertest "github.com/google/knative-gcp/pkg/metrics/testing"
 	"github.com/google/knative-gcp/pkg/utils/authcheck"
 
@@ -395,6 +399,345 @@ func TestFanoutSyncPoolE2E(t *testing.T) {
 	})
 }
 
+func TestFanoutLimitedTarget(t *testing.T) {
+	reportertest.ResetDeliveryMetrics()
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+
+	helper, err := handlertesting.NewHelper(ctx, "test-project")
+	if err != nil {
+		t.Fatalf("failed to create pool testing helper: %v", err)
+	}
+	defer helper.Close()
+
+	b := helper.GenerateBroker(ctx, t, "ns")
+	limited := helper.GenerateTarget(ctx, t, b.Key(), nil)
+	limited.DeliveryLimits = &config.DeliveryLimits{MaxConcurrentDeliveries: 1}
+	helper.Targets.MutateCellTenant(b.Key(), func(m config.CellTenantMutation) {
+		m.UpsertTargets(limited)
+	})
+	other := helper.GenerateTarget(ctx, t, b.Key(), nil)
+
+	syncPool, err := InitializeTestFanoutPool(
+		ctx, fanoutPod, fanoutContainer, helper.Targets, helper.PubsubClient,
+		WithDeliveryTimeout(5*time.Second),
+		// The other target is not held back by the limited one.
+		WithMaxConcurrentPerEvent(2),
+	)
+	if err != nil {
+		t.Fatalf("unexpected error from getting sync pool: %v", err)
+	}
+	p, err := GetFreePort()
+	if err != nil {
+		t.Fatalf("failed to get random free port: %v", err)
+	}
+	if _, err := StartSyncPool(ctx, syncPool, make(chan struct{}), time.Minute, p, &authcheck.FakeAuthenticationCheck{}); err != nil {
+		t.Fatalf("unexpected error from starting sync pool: %v", err)
+	}
+
+	e1, e2 := event.New(), event.New()
+	for i, e := range []*event.Event{&e1, &e2} {
+		e.SetType("type")
+		e.SetID(fmt.Sprintf("id-%d", i))
+		e.SetSource("source")
+		eventutil.UpdateRemainingHops(ctx, e, 123)
+	}
+	// The event over the limits of the target is sent to its retry queue to be delivered later.
+	wantRetry := e2.Clone()
+	eventutil.SetRedeliverAfter(&wantRetry, time.Now())
+
+	ctx, cancel = context.WithTimeout(ctx, 6*time.Second)
+	defer cancel()
+	group, gctx := errgroup.WithContext(ctx)
+	group.Go(func() error {
+		// Hold the first event, so that the target is at its limit for the second one.
+		helper.VerifyNextTargetEventAndDelayResp(gctx, t, limited.Key(), &e1, 3*time.Second)
+		return nil
+	})
+	group.Go(func() error {
+		helper.VerifyNextTargetRetryEvent(gctx, t, limited.Key(), &wantRetry)
+		return nil
+	})
+
+	helper.SendEventToDecoupleQueue(ctx, t, b.Key(), &e1)
+	helper.VerifyNextTargetEvent(ctx, t, other.Key(), &e1)
+	helper.SendEventToDecoupleQueue(ctx, t, b.Key(), &e2)
+	helper.VerifyNextTargetEvent(ctx, t, other.Key(), &e2)
+	// The limits of a target don't hold back the other targets, which receive the events once.
+	helper.VerifyNextTargetEvent(ctx, t, other.Key(), nil)
+
+	if err := group.Wait(); err != nil {
+		t.Error(err)
+	}
+}
+
+func TestFanoutReplayTarget(t *testing.T) {
+	reportertest.ResetDeliveryMetrics()
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+
+	helper, err := handlertesting.NewHelper(ctx, "test-project")
+	if err != nil {
+		t.Fatalf("failed to create pool testing helper: %v", err)
+	}
+	defer helper.Close()
+
+	b := helper.GenerateBroker(ctx, t, "ns")
+	replayed := helper.GenerateTarget(ctx, t, b.Key(), nil)
+	other := helper.GenerateTarget(ctx, t, b.Key(), nil)
+	replaySub, err := helper.PubsubClient.CreateSubscription(ctx, "replay-sub", pubsub.SubscriptionConfig{
+		Topic: helper.PubsubClient.Topic(b.DecoupleQueue.Topic),
+	})
+	if err != nil {
+		t.Fatalf("failed to create replay subscription: %v", err)
+	}
+
+	syncPool, err := InitializeTestFanoutPool(
+		ctx, fanoutPod, fanoutContainer, helper.Targets, helper.PubsubClient,
+		// The targets receive the events in a fixed order below, so the fanout must not wait for
+		// one before delivering to the other.
+		WithMaxConcurrentPerEvent(2),
+	)
+	if err != nil {
+		t.Fatalf("unexpected error from getting sync pool: %v", err)
+	}
+	p, err := GetFreePort()
+	if err != nil {
+		t.Fatalf("failed to get random free port: %v", err)
+	}
+	if _, err := StartSyncPool(ctx, syncPool, make(chan struct{}), time.Minute, p, &authcheck.FakeAuthenticationCheck{}); err != nil {
+		t.Fatalf("unexpected error from starting sync pool: %v", err)
+	}
+
+	e1, e2 := event.New(), event.New()
+	for i, e := range []*event.Event{&e1, &e2} {
+		e.SetType("type")
+		e.SetID(fmt.Sprintf("id-%d", i))
+		e.SetSource("source")
+		eventutil.UpdateRemainingHops(ctx, e, 123)
+	}
+
+	vctx, cancel := context.WithTimeout(ctx, 5*time.Second)
+	defer cancel()
+	helper.SendEventToDecoupleQueue(ctx, t, b.Key(), &e1)
+	helper.VerifyNextTargetEvent(vctx, t, replayed.Key(), &e1)
+	helper.VerifyNextTargetEvent(vctx, t, other.Key(), &e1)
+
+	// The events published before the replay was applied are delivered again to the replayed
+	// target only.
+	replayed.Replay = &config.Replay{
+		Queue:          &config.Queue{Topic: b.DecoupleQueue.Topic, Subscription: replaySub.ID(), State: config.State_READY},
+		UntilUnixNanos: time.Now().UnixNano(),
+	}
+	helper.Targets.MutateCellTenant(b.Key(), func(m config.CellTenantMutation) {
+		m.UpsertTargets(replayed)
+	})
+	if err := syncPool.SyncOnce(ctx); err != nil {
+		t.Fatalf("unexpected error from syncing pool: %v", err)
+	}
+	helper.VerifyNextTargetEvent(vctx, t, replayed.Key(), &e1)
+
+	// The events published after the replay are only delivered by the fanout.
+	helper.SendEventToDecoupleQueue(ctx, t, b.Key(), &e2)
+	helper.VerifyNextTargetEvent(vctx, t, replayed.Key(), &e2)
+	helper.VerifyNextTargetEvent(vctx, t, other.Key(), &e2)
+
+	nctx, cancel := context.WithTimeout(ctx, 2*time.Second)
+	defer cancel()
+	group, gctx := errgroup.WithContext(nctx)
+	for _, k := range []*config.TargetKey{replayed.Key(), other.Key()} {
+		k := k
+		group.Go(func() error {
+			helper.VerifyNextTargetEvent(gctx, t, k, nil)
+			return nil
+		})
+	}
+	if err := group.Wait(); err != nil {
+		t.Error(err)
+	}
+}
+
+func TestFanoutShards(t *testing.T) {
+	reportertest.ResetDeliveryMetrics()
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+	helper, err := handlertesting.NewHelper(ctx, "test-project")
+	if err != nil {
+		t.Fatalf("failed to create pool testing helper: %v", err)
+	}
+	defer helper.Close()
+
+	shards := shard.NewAssigner(fanoutPod)
+	shards.SetMembers([]string{fanoutPod, "other-pod"})
+	syncPool, err := InitializeTestFanoutPool(ctx, fanoutPod, fanoutContainer, helper.Targets, helper.PubsubClient, WithShards(shards))
+	if err != nil {
+		t.Fatalf("unexpected error from getting sync pool: %v", err)
+	}
+
+	var keys []string
+	for i := 0; i < 8; i++ {
+		keys = append(keys, helper.GenerateBroker(ctx, t, "ns").Key().String())
+	}
+	if err := syncPool.SyncOnce(ctx); err != nil {
+		t.Fatalf("unexpected error from syncing pool: %v", err)
+	}
+
+	gotHandlers := make(map[string]bool)
+	syncPool.pool.Range(func(key config.CellTenantKey, _ *fanoutHandlerCache) bool {
+		gotHandlers[key.String()] = true
+		return true
+	})
+	wantHandlers := make(map[string]bool)
+	for _, k := range keys {
+		if shards.Owns(k) {
+			wantHandlers[k] = true
+		}
+	}
+	if diff := cmp.Diff(wantHandlers, gotHandlers); diff != "" {
+		t.Errorf("handlers map (-want,+got): %v", diff)
+	}
+	if diff := cmp.Diff(shards.Assignment(keys), syncPool.Assignment()); diff != "" {
+		t.Errorf("assignment (-want,+got): %v", diff)
+	}
+
+	// The handlers of the other pod are removed once it leaves.
+	shards.SetMembers(nil)
+	if err := syncPool.SyncOnce(ctx); err != nil {
+		t.Fatalf("unexpected error from syncing pool: %v", err)
+	}
+	assertFanoutHandlers(t, syncPool, helper.Targets)
+}
+
+func TestFanoutOrderedRetriesShards(t *testing.T) {
+	reportertest.ResetDeliveryMetrics()
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+	helper, err := handlertesting.NewHelper(ctx, "test-project")
+	if err != nil {
+		t.Fatalf("failed to create pool testing helper: %v", err)
+	}
+	defer helper.Close()
+
+	b := helper.GenerateBroker(ctx, t, "ns")
+	helper.Targets.MutateCellTenant(b.Key(), func(m config.CellTenantMutation) {
+		m.SetOrdering(&config.Ordering{KeyExtension: "partitionkey"})
+	})
+	target := helper.GenerateTarget(ctx, t, b.Key(), nil)
+
+	// Both replicas have their own ordered retries, and only the one owning the broker pulls its
+	// decouple subscription, along with the notices of the retry data plane.
+	members := []string{"fanout-a", "fanout-b"}
+	for _, pod := range members {
+		shards := shard.NewAssigner(pod)
+		shards.SetMembers(members)
+		// The delivery metrics are registered once per pool.
+		reportertest.ResetDeliveryMetrics()
+		syncPool, err := InitializeTestFanoutPool(ctx, fanoutPod, fanoutContainer, helper.Targets, helper.PubsubClient, WithShards(shards))
+		if err != nil {
+			t.Fatalf("unexpected error from getting sync pool: %v", err)
+		}
+		if syncPool.orderedRetries == nil {
+			t.Fatal("sharded fanout pool doesn't retry events in order")
+		}
+		if err := syncPool.SyncOnce(ctx); err != nil {
+			t.Fatalf("unexpected error from syncing pool: %v", err)
+		}
+	}
+	reportertest.ResetDeliveryMetrics()
+	unsharded, err := InitializeTestFanoutPool(ctx, fanoutPod, fanoutContainer, helper.Targets, helper.PubsubClient)
+	if err != nil {
+		t.Fatalf("unexpected error from getting sync pool: %v", err)
+	}
+	if unsharded.orderedRetries != nil {
+		t.Error("fanout pool without shards retries events in order")
+	}
+
+	e1, e2, e3 := event.New(), event.New(), event.New()
+	for i, e := range []*event.Event{&e1, &e2, &e3} {
+		e.SetType("type")
+		e.SetID(fmt.Sprintf("id-%d", i+1))
+		e.SetSource("source")
+		e.SetExtension("partitionkey", "a")
+		eventutil.UpdateRemainingHops(ctx, e, 123)
+	}
+
+	ctx, cancel = context.WithTimeout(ctx, 10*time.Second)
+	defer cancel()
+	// waitFor waits until a published message matches.
+	waitFor := func(desc string, matches func(msg *pstest.Message) bool) *pstest.Message {
+		t.Helper()
+		for {
+			for _, msg := range helper.PubsubServer.Messages() {
+				if matches(msg) {
+					return msg
+				}
+			}
+			select {
+			case <-ctx.Done():
+				t.Fatalf("timed out waiting for %s", desc)
+			case <-time.After(10 * time.Millisecond):
+			}
+		}
+	}
+	retried := func(e event.Event) func(msg *pstest.Message) bool {
+		return func(msg *pstest.Message) bool {
+			return msg.Attributes["ce-id"] == e.ID() && msg.Attributes["ce-kgcporderedretry"] != ""
+		}
+	}
+	// notified waits until the replica owning the broker acked the notice of the delivery of the
+	// retried event.
+	notified := func(e event.Event) {
+		t.Helper()
+		token := waitFor("the retried event", retried(e)).Attributes["ce-kgcporderedretry"]
+		waitFor("the notice of the delivery", func(msg *pstest.Message) bool {
+			return msg.Attributes["ce-type"] != e.Type() && msg.Attributes["ce-kgcporderedretry"] == token && msg.Acks > 0
+		})
+	}
+
+	// The delivery of the first event fails, so it is sent to the retry topic.
+	helper.SendEventToDecoupleQueue(ctx, t, b.Key(), &e1)
+	helper.VerifyAndRespondNextTargetEvent(ctx, t, target.Key(), &e1, nil, http.StatusServiceUnavailable, 0)
+	waitFor("the first event to be retried", retried(e1))
+
+	reportertest.ResetDeliveryMetrics()
+	retryPool, err := InitializeTestRetryPool(helper.Targets, retryPod, retryContainer, helper.PubsubClient,
+		WithPubsubReceiveSettings(pubsub.ReceiveSettings{NumGoroutines: 1, MaxOutstandingMessages: 1}))
+	if err != nil {
+		t.Fatalf("unexpected error from getting retry pool: %v", err)
+	}
+	if err := retryPool.SyncOnce(ctx); err != nil {
+		t.Fatalf("unexpected error from syncing retry pool: %v", err)
+	}
+	group, gctx := errgroup.WithContext(ctx)
+	group.Go(func() error {
+		// Hold the retried event, so that the second event is retried behind it.
+		helper.VerifyNextTargetEventAndDelayResp(gctx, t, target.Key(), &e1, time.Second)
+		return nil
+	})
+	waitFor("the retried event to be pulled", func(msg *pstest.Message) bool {
+		return retried(e1)(msg) && msg.Deliveries > 0
+	})
+	helper.SendEventToDecoupleQueue(ctx, t, b.Key(), &e2)
+	waitFor("the second event to be retried", retried(e2))
+	if err := group.Wait(); err != nil {
+		t.Fatal(err)
+	}
+	helper.VerifyNextTargetEvent(ctx, t, target.Key(), &e2)
+
+	// The retry data plane notifies the fanout through the decouple topic, which is only pulled
+	// by the replica owning the broker. Once it got the notice of the last retried event, the
+	// events of the key are delivered directly again.
+	notified(e1)
+	notified(e2)
+	helper.SendEventToDecoupleQueue(ctx, t, b.Key(), &e3)
+	helper.VerifyNextTargetEvent(ctx, t, target.Key(), &e3)
+	for _, msg := range helper.PubsubServer.Messages() {
+		if retried(e3)(msg) {
+			t.Error("the event was retried after the notice of the delivery of the retried events")
+		}
+	}
+}
+
 func assertFanoutHandlers(t *testing.T, p *FanoutPool, targets config.Targets) {
 	t.Helper()
 	gotHandlers := make(map[config.CellTenantKey]bool)
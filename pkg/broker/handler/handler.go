@@ -17,14 +17,17 @@ limitations under the License.
 package handler
 
 import (
+	"container/heap"
 	"context"
 	"errors"
+	"sync"
 	"sync/atomic"
 	"time"
 
 	"cloud.google.com/go/pubsub"
 	cepubsub "github.com/cloudevents/sdk-go/protocol/pubsub/v2"
 	"github.com/cloudevents/sdk-go/v2/binding"
+	handlerctx "github.com/google/knative-gcp/pkg/broker/handler/context"
 	"github.com/google/knative-gcp/pkg/broker/handler/processors"
 	"github.com/google/knative-gcp/pkg/logging"
 	"github.com/google/knative-gcp/pkg/metrics"
@@ -49,8 +52,15 @@ type Handler struct {
 
 	// alive is a bool indicator that the handler is still alive.
 	alive atomic.Value
+
+	// redeliveries delays the messages whose processing requested it. If nil, the messages are
+	// redelivered according to the backoff of the subscription only.
+	redeliveries *redeliveries
 }
 
+// idleRedeliveryWait is how long the redeliveries wait for a message to hold when none is held.
+const idleRedeliveryWait = time.Minute
+
 // NewHandler creates a new Handler.
 func NewHandler(
 	sub *pubsub.Subscription,
@@ -61,6 +71,7 @@ func NewHandler(
 		Subscription: sub,
 		Processor:    processor,
 		Timeout:      timeout,
+		redeliveries: &redeliveries{wake: make(chan struct{}, 1)},
 	}
 }
 
@@ -70,6 +81,7 @@ func (h *Handler) Start(ctx context.Context, done func(error)) {
 	ctx, h.cancel = context.WithCancel(ctx)
 	h.alive.Store(true)
 
+	go h.redeliveries.run(ctx)
 	go func() {
 		// For any reason if inbound is closed, mark alive as false.
 		defer h.alive.Store(false)
@@ -90,6 +102,7 @@ func (h *Handler) IsAlive() bool {
 // receive converts message to events and invoke processor chain.
 func (h *Handler) receive(ctx context.Context, msg *pubsub.Message) {
 	ctx = metrics.StartEventProcessing(ctx)
+	ctx = handlerctx.WithPublishTime(ctx, msg.PublishTime)
 	event, err := binding.ToEvent(ctx, cepubsub.NewMessage(msg))
 	if isNonRetryable(err) {
 		logEventConversionError(ctx, msg, err, "failed to convert received message to an event, check the msg format")
@@ -104,20 +117,157 @@ func (h *Handler) receive(ctx context.Context, msg *pubsub.Message) {
 		return
 	}
 
+	pctx := ctx
 	if h.Timeout != 0 {
 		var cancel context.CancelFunc
-		ctx, cancel = context.WithTimeout(ctx, h.Timeout)
+		pctx, cancel = context.WithTimeout(ctx, h.Timeout)
 		defer cancel()
 	}
-	if err := h.Processor.Process(ctx, event); err != nil {
+	if err := h.Processor.Process(pctx, event); err != nil {
 		logging.FromContext(ctx).Error("failed to process event", zap.String("eventID", event.ID()), zap.Error(err))
-		msg.Nack()
+		if !h.redeliveries.hold(msg, err, time.Now(), h.maxRedeliveryDelay()) {
+			msg.Nack()
+		}
 		return
 	}
 
 	msg.Ack()
 }
 
+// retryAfter is implemented by processing errors that request a delay before the event is
+// processed again, e.g. because the subscriber responded with a Retry-After header.
+type retryAfter interface {
+	RetryAfter() time.Duration
+}
+
+// maxRedeliveryDelay returns the longest delay a message can be held for. The Pub/Sub client stops
+// extending the ack deadline of a message after the maximum extension of the subscription, which
+// includes the processing of the message.
+func (h *Handler) maxRedeliveryDelay() time.Duration {
+	maxExtension := h.Subscription.ReceiveSettings.MaxExtension
+	if maxExtension <= 0 {
+		maxExtension = pubsub.DefaultReceiveSettings.MaxExtension
+	}
+	return maxExtension - h.Timeout
+}
+
+// redeliveries holds the messages that must not be processed again before a delay, and nacks them
+// once the delay ended. The messages are neither acked nor nacked in the meantime, so the Pub/Sub
+// client keeps extending their ack deadline with ModifyAckDeadline, and Pub/Sub does not redeliver
+// them early. Unlike an early redelivery, this doesn't count a delivery attempt toward the dead
+// letter policy of the subscription, and doesn't hold the receive callback.
+//
+// The held messages count toward the flow control of the subscription until they are nacked,
+// which bounds their number.
+type redeliveries struct {
+	mux  sync.Mutex
+	held heldMessages
+	// stopped is true once the held messages were nacked because the handler stopped. The
+	// subscription only stops once all its messages are acked or nacked, so no message is held
+	// after that.
+	stopped bool
+	// wake is signaled when a message is held, as it may have to be nacked before the others.
+	wake chan struct{}
+}
+
+type heldMessage struct {
+	msg       *pubsub.Message
+	notBefore time.Time
+}
+
+// heldMessages is a heap of the held messages, ordered by the end of their delay.
+type heldMessages []heldMessage
+
+func (h heldMessages) Len() int            { return len(h) }
+func (h heldMessages) Less(i, j int) bool  { return h[i].notBefore.Before(h[j].notBefore) }
+func (h heldMessages) Swap(i, j int)       { h[i], h[j] = h[j], h[i] }
+func (h *heldMessages) Push(x interface{}) { *h = append(*h, x.(heldMessage)) }
+
+func (h *heldMessages) Pop() interface{} {
+	old := *h
+	n := len(old)
+	x := old[n-1]
+	*h = old[:n-1]
+	return x
+}
+
+// hold holds the message if its processing error requested a delay, capped to maxDelay, and
+// returns whether it did.
+func (r *redeliveries) hold(msg *pubsub.Message, err error, now time.Time, maxDelay time.Duration) bool {
+	var ra retryAfter
+	if r == nil || !errors.As(err, &ra) || ra.RetryAfter() <= 0 || maxDelay <= 0 {
+		return false
+	}
+	delay := ra.RetryAfter()
+	if delay > maxDelay {
+		delay = maxDelay
+	}
+	r.mux.Lock()
+	if r.stopped {
+		r.mux.Unlock()
+		return false
+	}
+	heap.Push(&r.held, heldMessage{msg: msg, notBefore: now.Add(delay)})
+	r.mux.Unlock()
+	select {
+	case r.wake <- struct{}{}:
+	default:
+	}
+	return true
+}
+
+// run nacks the held messages at the end of their delay until the context is done, and then nacks
+// the remaining ones so that the subscription can stop.
+func (r *redeliveries) run(ctx context.Context) {
+	if r == nil {
+		return
+	}
+	for {
+		timer := time.NewTimer(r.nackExpired(time.Now()))
+		select {
+		case <-ctx.Done():
+			timer.Stop()
+			r.stop()
+			return
+		case <-r.wake:
+		case <-timer.C:
+		}
+		timer.Stop()
+	}
+}
+
+// nackExpired nacks the messages whose delay ended by now, and returns how long to wait for the
+// end of the next delay.
+func (r *redeliveries) nackExpired(now time.Time) time.Duration {
+	var expired []*pubsub.Message
+	wait := idleRedeliveryWait
+	r.mux.Lock()
+	for r.held.Len() > 0 {
+		if next := r.held[0].notBefore; next.After(now) {
+			wait = next.Sub(now)
+			break
+		}
+		expired = append(expired, heap.Pop(&r.held).(heldMessage).msg)
+	}
+	r.mux.Unlock()
+	for _, msg := range expired {
+		msg.Nack()
+	}
+	return wait
+}
+
+// stop nacks all the held messages, and prevents holding new ones.
+func (r *redeliveries) stop() {
+	r.mux.Lock()
+	held := r.held
+	r.held = nil
+	r.stopped = true
+	r.mux.Unlock()
+	for _, m := range held {
+		m.msg.Nack()
+	}
+}
+
 func isNonRetryable(err error) bool {
 	// The following errors can be returned by ToEvent and are not retryable.
 	// TODO Should binding.ToEvent consolidate them and return the generic ErrCannotConvertToEvent?
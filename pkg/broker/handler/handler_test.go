@@ -18,8 +18,10 @@ package handler
 
 import (
 	"context"
+	"errors"
 	"fmt"
 	"runtime"
+	"sync"
 	"testing"
 	"time"
 
@@ -226,3 +228,131 @@ func nextEventWithTimeout(eventCh <-chan *event.Event) *event.Event {
 		return got
 	}
 }
+
+type retryAfterError time.Duration
+
+func (e retryAfterError) Error() string {
+	return "retry later"
+}
+
+func (e retryAfterError) RetryAfter() time.Duration {
+	return time.Duration(e)
+}
+
+func TestRedeliveries(t *testing.T) {
+	now := time.Now()
+	r := &redeliveries{wake: make(chan struct{}, 1)}
+	if r.hold(&pubsub.Message{ID: "failed"}, errors.New("failed"), now, time.Hour) {
+		t.Error("hold() = true for a message without delay")
+	}
+	for _, d := range []time.Duration{3 * time.Minute, time.Minute, 2 * time.Hour} {
+		err := fmt.Errorf("failed: %w", retryAfterError(d))
+		if !r.hold(&pubsub.Message{ID: d.String()}, err, now, time.Hour) {
+			t.Errorf("hold() = false for a message delayed by %v", d)
+		}
+	}
+
+	if wait := r.nackExpired(now); wait != time.Minute {
+		t.Errorf("nackExpired() = %v, want %v", wait, time.Minute)
+	}
+	if wait := r.nackExpired(now.Add(2 * time.Minute)); wait != time.Minute {
+		t.Errorf("nackExpired() = %v after the first delay, want %v", wait, time.Minute)
+	}
+	// The delay longer than the maximum is capped.
+	if wait := r.nackExpired(now.Add(90 * time.Minute)); wait != idleRedeliveryWait {
+		t.Errorf("nackExpired() = %v after all the delays, want %v", wait, idleRedeliveryWait)
+	}
+	if r.held.Len() != 0 {
+		t.Errorf("redeliveries held %d messages, want 0", r.held.Len())
+	}
+
+	r.hold(&pubsub.Message{ID: "held"}, retryAfterError(time.Minute), now, time.Hour)
+	r.stop()
+	if r.held.Len() != 0 {
+		t.Errorf("redeliveries held %d messages after stop, want 0", r.held.Len())
+	}
+	if r.hold(&pubsub.Message{ID: "stopped"}, retryAfterError(time.Minute), now, time.Hour) {
+		t.Error("hold() = true after stop")
+	}
+}
+
+// delayingProcessor fails the first processing of each event with a delay before it is processed
+// again, and records when the events are processed.
+type delayingProcessor struct {
+	processors.BaseProcessor
+
+	delay     time.Duration
+	processed chan time.Time
+	mux       sync.Mutex
+	seen      map[string]bool
+}
+
+func (p *delayingProcessor) Process(ctx context.Context, e *event.Event) error {
+	p.processed <- time.Now()
+	p.mux.Lock()
+	defer p.mux.Unlock()
+	if p.seen[e.ID()] {
+		return nil
+	}
+	p.seen[e.ID()] = true
+	return retryAfterError(p.delay)
+}
+
+func TestHandlerDelaysRedelivery(t *testing.T) {
+	ctx := context.Background()
+	srv := pstest.NewServer()
+	defer srv.Close()
+	conn, err := grpc.Dial(srv.Addr, grpc.WithInsecure())
+	if err != nil {
+		t.Fatalf("failed to dial test pubsub connection: %v", err)
+	}
+	defer conn.Close()
+	c, err := pubsub.NewClient(ctx, testProjectID, option.WithGRPCConn(conn))
+	if err != nil {
+		t.Fatalf("failed to create test pubsub client: %v", err)
+	}
+
+	topic, err := c.CreateTopic(ctx, testTopic)
+	if err != nil {
+		t.Fatalf("failed to create topic: %v", err)
+	}
+	sub, err := c.CreateSubscription(ctx, testSub, pubsub.SubscriptionConfig{Topic: topic})
+	if err != nil {
+		t.Fatalf("failed to create subscription: %v", err)
+	}
+
+	const delay = 500 * time.Millisecond
+	processor := &delayingProcessor{delay: delay, processed: make(chan time.Time, 10), seen: make(map[string]bool)}
+	h := NewHandler(sub, processor, time.Second)
+	h.Start(ctx, func(err error) {})
+	defer h.Stop()
+
+	testEvent := event.New()
+	testEvent.SetID("id")
+	testEvent.SetSource("source")
+	testEvent.SetType("type")
+	msg := new(pubsub.Message)
+	if err := cepubsub.WritePubSubMessage(ctx, binding.ToMessage(&testEvent), msg); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := topic.Publish(ctx, msg).Get(ctx); err != nil {
+		t.Fatalf("failed to publish message: %v", err)
+	}
+
+	var times []time.Time
+	for i := 0; i < 2; i++ {
+		select {
+		case got := <-processor.processed:
+			times = append(times, got)
+		case <-time.After(5 * time.Second):
+			t.Fatalf("event processed %d times, want 2", i)
+		}
+	}
+	if d := times[1].Sub(times[0]); d < delay {
+		t.Errorf("event processed again after %v, want at least %v", d, delay)
+	}
+	// The message is held rather than redelivered early, so it is only delivered twice.
+	if msgs := srv.Messages(); len(msgs) != 1 || msgs[0].Deliveries != 2 {
+		t.Errorf("unexpected deliveries of the messages %+v, want 1 message delivered twice", msgs)
+	}
+}
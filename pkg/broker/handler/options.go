@@ -21,6 +21,11 @@ import (
 	"time"
 
 	"cloud.google.com/go/pubsub"
+
+	"github.com/google/knative-gcp/pkg/broker/claimcheck"
+	"github.com/google/knative-gcp/pkg/broker/handler/processors/deliver"
+	"github.com/google/knative-gcp/pkg/broker/shard"
+	"github.com/google/knative-gcp/pkg/metrics"
 )
 
 var (
@@ -28,6 +33,9 @@ var (
 	defaultMaxConcurrencyPerEvent = 1
 	defaultTimeout                = 10 * time.Minute
 
+	defaultCircuitBreakerThreshold    = 5
+	defaultCircuitBreakerOpenDuration = 30 * time.Second
+
 	// This is the pubsub default MaxExtension.
 	// It would not make sense for handler timeout per event be greater
 	// than this value because the message would be nacked before the handler
@@ -50,15 +58,29 @@ type Options struct {
 	DeliveryTimeout time.Duration
 	// PubsubReceiveSettings is the pubsub receive settings.
 	PubsubReceiveSettings pubsub.ReceiveSettings
+	// CircuitBreakerThreshold is the number of consecutive delivery failures
+	// that opens the circuit breaker of a target. Zero disables the circuit breakers.
+	CircuitBreakerThreshold int
+	// CircuitBreakerOpenDuration is how long the circuit breaker of a target
+	// stays open before a delivery is attempted again.
+	CircuitBreakerOpenDuration time.Duration
+	// ClaimCheckStore restores the payloads of the events stored in Cloud Storage
+	// before they are delivered.
+	ClaimCheckStore *claimcheck.Store
+	// Shards assigns the subscriptions to the replicas of the pool. Nil means that
+	// each replica handles all the subscriptions.
+	Shards *shard.Assigner
 }
 
 // NewOptions creates a Options.
 func NewOptions(opts ...Option) (*Options, error) {
 	opt := &Options{
-		HandlerConcurrency:     defaultHandlerConcurrency,
-		MaxConcurrencyPerEvent: defaultMaxConcurrencyPerEvent,
-		TimeoutPerEvent:        defaultTimeout,
-		PubsubReceiveSettings:  pubsub.DefaultReceiveSettings,
+		HandlerConcurrency:         defaultHandlerConcurrency,
+		MaxConcurrencyPerEvent:     defaultMaxConcurrencyPerEvent,
+		TimeoutPerEvent:            defaultTimeout,
+		PubsubReceiveSettings:      pubsub.DefaultReceiveSettings,
+		CircuitBreakerThreshold:    defaultCircuitBreakerThreshold,
+		CircuitBreakerOpenDuration: defaultCircuitBreakerOpenDuration,
 	}
 	for _, o := range opts {
 		o(opt)
@@ -107,3 +129,33 @@ func WithDeliveryTimeout(t time.Duration) Option {
 		o.DeliveryTimeout = t
 	}
 }
+
+// WithCircuitBreaker sets the CircuitBreakerThreshold and the CircuitBreakerOpenDuration.
+func WithCircuitBreaker(threshold int, openDuration time.Duration) Option {
+	return func(o *Options) {
+		o.CircuitBreakerThreshold = threshold
+		o.CircuitBreakerOpenDuration = openDuration
+	}
+}
+
+// WithClaimCheckStore sets the ClaimCheckStore.
+func WithClaimCheckStore(s *claimcheck.Store) Option {
+	return func(o *Options) {
+		o.ClaimCheckStore = s
+	}
+}
+
+// WithShards sets the Shards.
+func WithShards(a *shard.Assigner) Option {
+	return func(o *Options) {
+		o.Shards = a
+	}
+}
+
+// newCircuitBreakers creates the circuit breakers of the options, or nil if they are disabled.
+func (o *Options) newCircuitBreakers(reporter *metrics.DeliveryReporter) *deliver.CircuitBreakers {
+	if o.CircuitBreakerThreshold <= 0 {
+		return nil
+	}
+	return deliver.NewCircuitBreakers(o.CircuitBreakerThreshold, o.CircuitBreakerOpenDuration, reporter)
+}
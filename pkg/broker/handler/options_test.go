@@ -22,6 +22,8 @@ import (
 
 	"cloud.google.com/go/pubsub"
 	"github.com/google/go-cmp/cmp"
+
+	"github.com/google/knative-gcp/pkg/broker/shard"
 )
 
 func TestWithHandlerConcurrency(t *testing.T) {
@@ -96,3 +98,32 @@ func TestWithDeliveryTimeout(t *testing.T) {
 		t.Errorf("options timeout per event got=%v, want=%v", opt.DeliveryTimeout, want)
 	}
 }
+
+func TestWithCircuitBreaker(t *testing.T) {
+	opt, err := NewOptions()
+	if err != nil {
+		t.Errorf("NewOptions got unexpected error: %v", err)
+	}
+	if opt.CircuitBreakerThreshold != defaultCircuitBreakerThreshold || opt.CircuitBreakerOpenDuration != defaultCircuitBreakerOpenDuration {
+		t.Errorf("options circuit breaker got=(%d, %v), want=(%d, %v)", opt.CircuitBreakerThreshold, opt.CircuitBreakerOpenDuration, defaultCircuitBreakerThreshold, defaultCircuitBreakerOpenDuration)
+	}
+
+	opt, err = NewOptions(WithCircuitBreaker(0, time.Minute))
+	if err != nil {
+		t.Errorf("NewOptions got unexpected error: %v", err)
+	}
+	if b := opt.newCircuitBreakers(nil); b != nil {
+		t.Errorf("circuit breakers got=%v, want disabled", b)
+	}
+}
+
+func TestWithShards(t *testing.T) {
+	want := shard.NewAssigner("pod")
+	opt, err := NewOptions(WithShards(want))
+	if err != nil {
+		t.Errorf("NewOptions got unexpected error: %v", err)
+	}
+	if opt.Shards != want {
+		t.Errorf("options shards got=%v, want=%v", opt.Shards, want)
+	}
+}
@@ -18,11 +18,15 @@ package handler
 
 import (
 	"context"
+	"encoding/json"
+	"fmt"
 	"net/http"
 	"strconv"
 	"sync"
 	"time"
 
+	"github.com/google/knative-gcp/pkg/broker/config"
+	"github.com/google/knative-gcp/pkg/broker/shard"
 	"github.com/google/knative-gcp/pkg/logging"
 	"github.com/google/knative-gcp/pkg/utils/authcheck"
 
@@ -38,6 +42,19 @@ type SyncPool interface {
 	SyncOnce(ctx context.Context) error
 }
 
+// shardedPool is implemented by the sync pools whose subscriptions are split between their
+// replicas. Their current assignment is served by the probe checker for debugging.
+type shardedPool interface {
+	Assignment() shard.Assignment
+}
+
+// targetsPool is implemented by the sync pools that handle the targets config. The generation of
+// the targets they last synced is served by the probe checker, so that the controller can tell
+// when a change of the targets config has reached the data plane.
+type targetsPool interface {
+	Targets() config.ReadonlyTargets
+}
+
 type probeChecker struct {
 	logger           *zap.Logger
 	mux              sync.RWMutex
@@ -45,6 +62,9 @@ type probeChecker struct {
 	maxStaleDuration time.Duration
 	port             int
 	authCheck        authcheck.AuthenticationCheck
+	shards           shardedPool
+	// generation is the generation of the targets last synced.
+	generation int64
 }
 
 func (c *probeChecker) reportHealth() {
@@ -59,6 +79,18 @@ func (c *probeChecker) lastTime() time.Time {
 	return c.lastReportTime
 }
 
+func (c *probeChecker) reportGeneration(generation int64) {
+	c.mux.Lock()
+	defer c.mux.Unlock()
+	c.generation = generation
+}
+
+func (c *probeChecker) syncedGeneration() int64 {
+	c.mux.RLock()
+	defer c.mux.RUnlock()
+	return c.generation
+}
+
 func (c *probeChecker) start(ctx context.Context) {
 	c.reportHealth()
 	srv := &http.Server{
@@ -80,6 +112,17 @@ func (c *probeChecker) start(ctx context.Context) {
 }
 
 func (c *probeChecker) ServeHTTP(w http.ResponseWriter, req *http.Request) {
+	if req.URL.Path == "/debug/shards" && c.shards != nil {
+		w.Header().Set("Content-Type", "application/json")
+		if err := json.NewEncoder(w).Encode(c.shards.Assignment()); err != nil {
+			c.logger.Error("failed to write the shard assignment", zap.Error(err))
+		}
+		return
+	}
+	if req.URL.Path == config.TargetsGenerationPath {
+		fmt.Fprint(w, c.syncedGeneration())
+		return
+	}
 	if req.URL.Path != "/healthz" {
 		w.WriteHeader(http.StatusNotFound)
 		return
@@ -111,16 +154,18 @@ func StartSyncPool(
 	probeCheckPort int,
 	authCheck authcheck.AuthenticationCheck,
 ) (SyncPool, error) {
-
-	if err := syncPool.SyncOnce(ctx); err != nil {
-		return nil, err
-	}
 	c := &probeChecker{
 		logger:           logging.FromContext(ctx),
 		maxStaleDuration: maxStaleDuration,
 		port:             probeCheckPort,
 		authCheck:        authCheck,
 	}
+	if err := syncOnce(ctx, syncPool, c); err != nil {
+		return nil, err
+	}
+	if s, ok := syncPool.(shardedPool); ok {
+		c.shards = s
+	}
 	go c.start(ctx)
 	if syncSignal != nil {
 		go watch(ctx, syncPool, syncSignal, c)
@@ -134,7 +179,7 @@ func watch(ctx context.Context, syncPool SyncPool, syncSignal <-chan struct{}, c
 		case <-ctx.Done():
 			return
 		case <-syncSignal:
-			if err := syncPool.SyncOnce(ctx); err != nil {
+			if err := syncOnce(ctx, syncPool, c); err != nil {
 				// Currently we don't really expect errors from SyncOnce.
 				logging.FromContext(ctx).Error("failed to sync handlers pool on watch signal", zap.Error(err))
 			} else {
@@ -144,3 +189,17 @@ func watch(ctx context.Context, syncPool SyncPool, syncSignal <-chan struct{}, c
 		}
 	}
 }
+
+// syncOnce syncs the pool, and reports the generation of the targets synced to the probe checker.
+func syncOnce(ctx context.Context, syncPool SyncPool, c *probeChecker) error {
+	// The generation is read first, since the targets may change while the pool is synced.
+	var generation int64
+	if p, ok := syncPool.(targetsPool); ok {
+		generation = p.Targets().Generation()
+	}
+	if err := syncPool.SyncOnce(ctx); err != nil {
+		return err
+	}
+	c.reportGeneration(generation)
+	return nil
+}
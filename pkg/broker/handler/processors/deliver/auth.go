@@ -0,0 +1,177 @@
+/*
+Copyright 2021 Google LLC
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package deliver
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"io/ioutil"
+	"net/url"
+	"path/filepath"
+	"strings"
+	"sync"
+	"time"
+
+	"golang.org/x/oauth2"
+	"google.golang.org/api/idtoken"
+
+	"github.com/google/knative-gcp/pkg/broker/config"
+)
+
+const (
+	// DefaultTokensPath is where the Secret of the static bearer tokens is mounted.
+	DefaultTokensPath = "/var/secrets/delivery-tokens"
+
+	// bearerTokenTTL is how long a static bearer token is cached before it is read again, so
+	// that the updates of the Secret are picked up.
+	bearerTokenTTL = 30 * time.Second
+)
+
+// errAuthorization is returned when the credentials of a delivery could not be obtained.
+var errAuthorization = errors.New("failed to authorize the delivery")
+
+// Authenticator provides the credentials attached to the deliveries of the targets with a
+// delivery authentication.
+type Authenticator struct {
+	// tokensPath is the directory of the static bearer tokens, one file per key.
+	tokensPath string
+	// newIDTokenSource is replaced in tests.
+	newIDTokenSource func(ctx context.Context, audience string) (oauth2.TokenSource, error)
+
+	mux sync.Mutex
+	// idTokens holds the ID token sources by audience. They cache the tokens until they expire.
+	idTokens     map[string]oauth2.TokenSource
+	bearerTokens map[string]bearerToken
+}
+
+type bearerToken struct {
+	value  string
+	readAt time.Time
+}
+
+// NewAuthenticator creates an Authenticator reading the static bearer tokens from tokensPath.
+// The ID tokens are signed for the Google service account of the default credentials, i.e. the
+// service account of the data plane.
+func NewAuthenticator(tokensPath string) *Authenticator {
+	return &Authenticator{
+		tokensPath: tokensPath,
+		newIDTokenSource: func(ctx context.Context, audience string) (oauth2.TokenSource, error) {
+			return idtoken.NewTokenSource(ctx, audience)
+		},
+		idTokens:     make(map[string]oauth2.TokenSource),
+		bearerTokens: make(map[string]bearerToken),
+	}
+}
+
+// authorization returns the Authorization header of a delivery to address, or an empty string if
+// the delivery is not authenticated.
+func (a *Authenticator) authorization(auth *config.DeliveryAuth, address string) (string, error) {
+	if auth == nil {
+		return "", nil
+	}
+	if a == nil {
+		return "", fmt.Errorf("%w: delivery authentication is not supported", errAuthorization)
+	}
+	if auth.Oidc != nil {
+		audience := auth.Oidc.Audience
+		if audience == "" {
+			audience = address
+		} else if !sameOrigin(audience, address) {
+			// The service account of the data plane is shared by all the targets, so a target
+			// must not obtain its tokens for the services of other targets.
+			return "", fmt.Errorf("%w: audience %q is not on the origin of %q", errAuthorization, audience, address)
+		}
+		token, err := a.idToken(audience)
+		if err != nil {
+			return "", fmt.Errorf("%w: failed to get an ID token: %v", errAuthorization, err)
+		}
+		return "Bearer " + token, nil
+	}
+	if auth.BearerTokenKey != "" {
+		token, err := a.bearerToken(auth.BearerTokenKey)
+		if err != nil {
+			return "", fmt.Errorf("%w: failed to read the bearer token: %v", errAuthorization, err)
+		}
+		return "Bearer " + token, nil
+	}
+	return "", nil
+}
+
+// sameOrigin returns true if the URIs have the same scheme and host.
+func sameOrigin(a, b string) bool {
+	ua, err := url.Parse(a)
+	if err != nil {
+		return false
+	}
+	ub, err := url.Parse(b)
+	if err != nil {
+		return false
+	}
+	return ua.Host != "" && strings.EqualFold(ua.Scheme, ub.Scheme) && strings.EqualFold(ua.Host, ub.Host)
+}
+
+func (a *Authenticator) idToken(audience string) (string, error) {
+	a.mux.Lock()
+	ts, ok := a.idTokens[audience]
+	if !ok {
+		var err error
+		// The token source outlives the delivery, so it must not use its context.
+		if ts, err = a.newIDTokenSource(context.Background(), audience); err != nil {
+			a.mux.Unlock()
+			return "", err
+		}
+		a.idTokens[audience] = ts
+	}
+	a.mux.Unlock()
+	// The token source refreshes the token when it expires.
+	token, err := ts.Token()
+	if err != nil {
+		return "", err
+	}
+	return token.AccessToken, nil
+}
+
+func (a *Authenticator) bearerToken(key string) (string, error) {
+	now := time.Now()
+	a.mux.Lock()
+	defer a.mux.Unlock()
+	if t, ok := a.bearerTokens[key]; ok && now.Sub(t.readAt) < bearerTokenTTL {
+		return t.value, nil
+	}
+	// Keys are validated by the webhook, but make sure that they cannot escape the directory.
+	if key != filepath.Base(key) {
+		return "", fmt.Errorf("invalid key %q", key)
+	}
+	b, err := ioutil.ReadFile(filepath.Join(a.tokensPath, key))
+	if err != nil {
+		return "", err
+	}
+	value := strings.TrimSpace(string(b))
+	a.bearerTokens[key] = bearerToken{value: value, readAt: now}
+	return value, nil
+}
+
+// replyAuth returns the authentication of the replies of a target. Only ID tokens are attached
+// to replies, with the reply address as audience: static bearer tokens are secrets of the
+// subscriber.
+func replyAuth(auth *config.DeliveryAuth) *config.DeliveryAuth {
+	if auth.GetOidc() == nil {
+		return nil
+	}
+	return &config.DeliveryAuth{Oidc: &config.OIDCAuth{}}
+}
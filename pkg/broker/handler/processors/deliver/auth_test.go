@@ -0,0 +1,259 @@
+/*
+Copyright 2021 Google LLC
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package deliver
+
+import (
+	"context"
+	"errors"
+	"io/ioutil"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"sync"
+	"testing"
+	"time"
+
+	"golang.org/x/oauth2"
+	logtest "knative.dev/pkg/logging/testing"
+
+	"github.com/google/knative-gcp/pkg/broker/config"
+	reportertest "github.com/google/knative-gcp/pkg/metrics/testing"
+)
+
+// authRecorder records the Authorization headers it receives, and replies with an event if reply
+// is true.
+type authRecorder struct {
+	reply bool
+
+	mux     sync.Mutex
+	headers []string
+}
+
+func (h *authRecorder) ServeHTTP(w http.ResponseWriter, req *http.Request) {
+	h.mux.Lock()
+	h.headers = append(h.headers, req.Header.Get("Authorization"))
+	h.mux.Unlock()
+	if h.reply {
+		w.Header().Set("ce-specversion", "1.0")
+		w.Header().Set("ce-id", "reply")
+		w.Header().Set("ce-source", "subscriber")
+		w.Header().Set("ce-type", "reply")
+	}
+	w.WriteHeader(http.StatusOK)
+}
+
+func (h *authRecorder) received() []string {
+	h.mux.Lock()
+	defer h.mux.Unlock()
+	return h.headers
+}
+
+func TestOIDCAuth(t *testing.T) {
+	cases := []struct {
+		name           string
+		audience       func(subscriber string) string
+		wantSubscriber func(subscriber string) string
+	}{{
+		name:           "subscriber audience",
+		audience:       func(string) string { return "" },
+		wantSubscriber: func(subscriber string) string { return "Bearer token-for-" + subscriber },
+	}, {
+		name:           "same origin audience",
+		audience:       func(subscriber string) string { return subscriber + "/" },
+		wantSubscriber: func(subscriber string) string { return "Bearer token-for-" + subscriber + "/" },
+	}}
+	for _, tc := range cases {
+		t.Run(tc.name, func(t *testing.T) {
+			reportertest.ResetDeliveryMetrics()
+			ctx := logtest.TestContextWithLogger(t)
+			subscriber := &authRecorder{reply: true}
+			subscriberSvr := httptest.NewServer(subscriber)
+			defer subscriberSvr.Close()
+			reply := &authRecorder{}
+			replySvr := httptest.NewServer(reply)
+			defer replySvr.Close()
+
+			p, ctx := newTargetProcessor(ctx, t, &config.Target{
+				Address:      subscriberSvr.URL,
+				ReplyAddress: replySvr.URL,
+				DeliveryAuth: &config.DeliveryAuth{Oidc: &config.OIDCAuth{Audience: tc.audience(subscriberSvr.URL)}},
+			})
+			p.Authenticator = NewAuthenticator(t.TempDir())
+			tokenSources := 0
+			p.Authenticator.newIDTokenSource = func(_ context.Context, audience string) (oauth2.TokenSource, error) {
+				tokenSources++
+				return oauth2.StaticTokenSource(&oauth2.Token{AccessToken: "token-for-" + audience}), nil
+			}
+			for i := 0; i < 2; i++ {
+				if err := p.Process(ctx, newSampleEvent()); err != nil {
+					t.Fatalf("Process() error = %v", err)
+				}
+			}
+
+			want := tc.wantSubscriber(subscriberSvr.URL)
+			for _, got := range subscriber.received() {
+				if got != want {
+					t.Errorf("subscriber Authorization got=%q, want=%q", got, want)
+				}
+			}
+			for _, got := range reply.received() {
+				if want := "Bearer token-for-" + replySvr.URL; got != want {
+					t.Errorf("reply Authorization got=%q, want=%q", got, want)
+				}
+			}
+			// The token sources of the subscriber and reply audiences are reused.
+			if tokenSources != 2 {
+				t.Errorf("token sources got=%d, want=2", tokenSources)
+			}
+		})
+	}
+}
+
+func TestOIDCAuthOtherOrigin(t *testing.T) {
+	reportertest.ResetDeliveryMetrics()
+	ctx := logtest.TestContextWithLogger(t)
+	subscriber := &authRecorder{}
+	subscriberSvr := httptest.NewServer(subscriber)
+	defer subscriberSvr.Close()
+
+	p, ctx := newTargetProcessor(ctx, t, &config.Target{
+		Address:      subscriberSvr.URL,
+		DeliveryAuth: &config.DeliveryAuth{Oidc: &config.OIDCAuth{Audience: "https://other.example.com"}},
+	})
+	p.Authenticator = NewAuthenticator(t.TempDir())
+	p.Authenticator.newIDTokenSource = func(_ context.Context, audience string) (oauth2.TokenSource, error) {
+		t.Errorf("got a token source for audience %q", audience)
+		return oauth2.StaticTokenSource(&oauth2.Token{AccessToken: "token-for-" + audience}), nil
+	}
+	if err := p.Process(ctx, newSampleEvent()); !errors.Is(err, errAuthorization) {
+		t.Errorf("Process() error = %v, want %v", err, errAuthorization)
+	}
+	if got := subscriber.received(); len(got) != 0 {
+		t.Errorf("subscriber received %d events, want 0", len(got))
+	}
+}
+
+func TestSameOrigin(t *testing.T) {
+	cases := []struct {
+		a, b string
+		want bool
+	}{
+		{a: "https://svc.run.app", b: "https://svc.run.app/path", want: true},
+		{a: "HTTPS://SVC.run.app/", b: "https://svc.run.app", want: true},
+		{a: "http://svc.run.app", b: "https://svc.run.app", want: false},
+		{a: "https://other.run.app", b: "https://svc.run.app", want: false},
+		{a: "https://svc.run.app:8443", b: "https://svc.run.app", want: false},
+		{a: "client-id", b: "https://svc.run.app", want: false},
+	}
+	for _, tc := range cases {
+		if got := sameOrigin(tc.a, tc.b); got != tc.want {
+			t.Errorf("sameOrigin(%q, %q) = %v, want %v", tc.a, tc.b, got, tc.want)
+		}
+	}
+}
+
+func TestBearerTokenAuth(t *testing.T) {
+	reportertest.ResetDeliveryMetrics()
+	ctx := logtest.TestContextWithLogger(t)
+	subscriber := &authRecorder{reply: true}
+	subscriberSvr := httptest.NewServer(subscriber)
+	defer subscriberSvr.Close()
+	reply := &authRecorder{}
+	replySvr := httptest.NewServer(reply)
+	defer replySvr.Close()
+
+	dir := t.TempDir()
+	if err := ioutil.WriteFile(filepath.Join(dir, "ns.token"), []byte("secret\n"), 0600); err != nil {
+		t.Fatal(err)
+	}
+	p, ctx := newTargetProcessor(ctx, t, &config.Target{
+		Address:      subscriberSvr.URL,
+		ReplyAddress: replySvr.URL,
+		DeliveryAuth: &config.DeliveryAuth{BearerTokenKey: "ns.token"},
+	})
+	p.Authenticator = NewAuthenticator(dir)
+	if err := p.Process(ctx, newSampleEvent()); err != nil {
+		t.Fatalf("Process() error = %v", err)
+	}
+
+	if got := subscriber.received(); len(got) != 1 || got[0] != "Bearer secret" {
+		t.Errorf("subscriber Authorization got=%q, want=%q", got, "Bearer secret")
+	}
+	if got := reply.received(); len(got) != 1 || got[0] != "" {
+		t.Errorf("reply Authorization got=%q, want none", got)
+	}
+}
+
+func TestBearerTokenRefresh(t *testing.T) {
+	dir := t.TempDir()
+	path := filepath.Join(dir, "ns.token")
+	if err := ioutil.WriteFile(path, []byte("old"), 0600); err != nil {
+		t.Fatal(err)
+	}
+	a := NewAuthenticator(dir)
+	auth := &config.DeliveryAuth{BearerTokenKey: "ns.token"}
+	if got, _ := a.authorization(auth, "http://subscriber"); got != "Bearer old" {
+		t.Errorf("authorization() got=%q, want=%q", got, "Bearer old")
+	}
+
+	if err := ioutil.WriteFile(path, []byte("new"), 0600); err != nil {
+		t.Fatal(err)
+	}
+	if got, _ := a.authorization(auth, "http://subscriber"); got != "Bearer old" {
+		t.Errorf("cached authorization() got=%q, want=%q", got, "Bearer old")
+	}
+	// Expire the cached token.
+	a.bearerTokens["ns.token"] = bearerToken{value: "old", readAt: time.Now().Add(-bearerTokenTTL)}
+	if got, _ := a.authorization(auth, "http://subscriber"); got != "Bearer new" {
+		t.Errorf("refreshed authorization() got=%q, want=%q", got, "Bearer new")
+	}
+
+	if err := os.Remove(path); err != nil {
+		t.Fatal(err)
+	}
+	a.bearerTokens = map[string]bearerToken{}
+	if _, err := a.authorization(auth, "http://subscriber"); !errors.Is(err, errAuthorization) {
+		t.Errorf("authorization() of missing token error = %v, want %v", err, errAuthorization)
+	}
+}
+
+func TestAuthorizationFailure(t *testing.T) {
+	reportertest.ResetDeliveryMetrics()
+	ctx := logtest.TestContextWithLogger(t)
+	subscriber := &authRecorder{}
+	subscriberSvr := httptest.NewServer(subscriber)
+	defer subscriberSvr.Close()
+
+	p, ctx := newTargetProcessor(ctx, t, &config.Target{
+		Address:      subscriberSvr.URL,
+		DeliveryAuth: &config.DeliveryAuth{BearerTokenKey: "ns.missing"},
+	})
+	p.Authenticator = NewAuthenticator(t.TempDir())
+	p.Breakers = NewCircuitBreakers(1, time.Minute, nil)
+	for i := 0; i < 2; i++ {
+		if err := p.Process(ctx, newSampleEvent()); !errors.Is(err, errAuthorization) {
+			t.Errorf("Process() error = %v, want %v", err, errAuthorization)
+		}
+	}
+	if got := len(subscriber.received()); got != 0 {
+		t.Errorf("subscriber calls got=%d, want=0", got)
+	}
+	if len(p.Breakers.breakers) != 0 {
+		t.Error("authorization failures opened the circuit breaker")
+	}
+}
@@ -0,0 +1,216 @@
+/*
+Copyright 2021 Google LLC
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package deliver
+
+import (
+	"context"
+	"errors"
+	"net/http"
+	"sync"
+	"time"
+
+	"go.opencensus.io/trace"
+	"go.uber.org/zap"
+
+	"github.com/google/knative-gcp/pkg/broker/config"
+	"github.com/google/knative-gcp/pkg/logging"
+	"github.com/google/knative-gcp/pkg/metrics"
+)
+
+// errCircuitOpen is returned instead of delivering an event to a target whose circuit breaker is
+// open.
+var errCircuitOpen = errors.New("circuit breaker of the target is open")
+
+// CircuitState is the state of the circuit breaker of a target.
+type CircuitState int64
+
+const (
+	// CircuitClosed targets receive all the events.
+	CircuitClosed CircuitState = iota
+	// CircuitHalfOpen targets receive a single probe event at a time. The circuit closes once a
+	// probe is delivered, and opens again if it fails.
+	CircuitHalfOpen
+	// CircuitOpen targets do not receive any event until the open duration elapsed.
+	CircuitOpen
+)
+
+func (s CircuitState) String() string {
+	switch s {
+	case CircuitClosed:
+		return "closed"
+	case CircuitHalfOpen:
+		return "half-open"
+	case CircuitOpen:
+		return "open"
+	default:
+		return "unknown"
+	}
+}
+
+// CircuitBreakers holds the circuit breakers of the targets. The circuit of a target opens after
+// a number of consecutive delivery failures, so that its events are no longer held until the
+// delivery times out.
+type CircuitBreakers struct {
+	// threshold is the number of consecutive failures that opens a circuit.
+	threshold int
+	// openDuration is how long a circuit stays open before it is probed.
+	openDuration time.Duration
+	reporter     *metrics.DeliveryReporter
+	// now is replaced in tests.
+	now func() time.Time
+
+	mux      sync.Mutex
+	breakers map[config.TargetKey]*breaker
+}
+
+type breaker struct {
+	state CircuitState
+	// failures is the number of consecutive failures while closed.
+	failures int
+	// openUntil is when an open circuit can be probed.
+	openUntil time.Time
+	// probing is true while a probe is in flight.
+	probing bool
+}
+
+// NewCircuitBreakers creates the circuit breakers of the targets. The state transitions are
+// reported to reporter, if not nil.
+func NewCircuitBreakers(threshold int, openDuration time.Duration, reporter *metrics.DeliveryReporter) *CircuitBreakers {
+	return &CircuitBreakers{
+		threshold:    threshold,
+		openDuration: openDuration,
+		reporter:     reporter,
+		now:          time.Now,
+		breakers:     make(map[config.TargetKey]*breaker),
+	}
+}
+
+// allow returns errCircuitOpen if no event can be delivered to the target. Once the open
+// duration elapsed, it lets a single probe through. A nil CircuitBreakers allows everything.
+func (c *CircuitBreakers) allow(ctx context.Context, target *config.Target) error {
+	if c == nil {
+		return nil
+	}
+	c.mux.Lock()
+	defer c.mux.Unlock()
+	b, ok := c.breakers[*target.Key()]
+	if !ok || b.state == CircuitClosed {
+		return nil
+	}
+	if b.state == CircuitOpen && !c.now().Before(b.openUntil) {
+		c.transition(ctx, target, b, CircuitHalfOpen)
+	}
+	if b.state == CircuitHalfOpen && !b.probing {
+		b.probing = true
+		return nil
+	}
+	trace.FromContext(ctx).Annotate(
+		[]trace.Attribute{trace.StringAttribute("circuit_state", b.state.String())},
+		"delivery short-circuited",
+	)
+	return errCircuitOpen
+}
+
+// record records the outcome of a delivery allowed by allow.
+func (c *CircuitBreakers) record(ctx context.Context, target *config.Target, err error) {
+	if c == nil {
+		return
+	}
+	c.mux.Lock()
+	defer c.mux.Unlock()
+	tk := target.Key()
+	b, ok := c.breakers[*tk]
+	if !isTargetFailure(err) {
+		if ok {
+			// Only closed circuits without failures are forgotten, so that the targets of a
+			// pod don't grow the map forever.
+			delete(c.breakers, *tk)
+			if b.state != CircuitClosed {
+				c.transition(ctx, target, b, CircuitClosed)
+			}
+		}
+		return
+	}
+	if !ok {
+		b = &breaker{}
+		c.breakers[*tk] = b
+	}
+	switch b.state {
+	case CircuitClosed:
+		b.failures++
+		if b.failures >= c.threshold {
+			c.open(ctx, target, b)
+		}
+	case CircuitHalfOpen:
+		c.open(ctx, target, b)
+	}
+}
+
+// Prune removes the circuit breakers of the targets that no longer exist.
+func (c *CircuitBreakers) Prune(targets config.ReadonlyTargets) {
+	if c == nil {
+		return
+	}
+	c.mux.Lock()
+	defer c.mux.Unlock()
+	for key := range c.breakers {
+		if _, ok := targets.GetTargetByKey(&key); !ok {
+			delete(c.breakers, key)
+		}
+	}
+}
+
+func (c *CircuitBreakers) open(ctx context.Context, target *config.Target, b *breaker) {
+	b.failures = 0
+	b.probing = false
+	b.openUntil = c.now().Add(c.openDuration)
+	c.transition(ctx, target, b, CircuitOpen)
+}
+
+func (c *CircuitBreakers) transition(ctx context.Context, target *config.Target, b *breaker, state CircuitState) {
+	from := b.state
+	b.state = state
+	logging.FromContext(ctx).Info("target circuit breaker changed state",
+		zap.Stringer("target", target.Key()), zap.Stringer("from", from), zap.Stringer("to", state))
+	trace.FromContext(ctx).Annotate(
+		[]trace.Attribute{
+			trace.StringAttribute("circuit_state_from", from.String()),
+			trace.StringAttribute("circuit_state", state.String()),
+		},
+		"circuit breaker state changed",
+	)
+	if c.reporter != nil {
+		if err := c.reporter.ReportCircuitState(ctx, target, int64(state)); err != nil {
+			logging.FromContext(ctx).Error("failed to report the circuit breaker state", zap.Error(err))
+		}
+	}
+}
+
+// isTargetFailure returns true if the error means that the target is unavailable: either it did
+// not respond, e.g. because of a timeout, or it responded with a 5xx or 429 status code. Other
+// responses show that the target is available, even if it rejected the event. Failures to
+// authorize the delivery are not failures of the target.
+func isTargetFailure(err error) bool {
+	if err == nil {
+		return false
+	}
+	var dErr *deliveryError
+	if !errors.As(err, &dErr) || errors.Is(err, errAuthorization) {
+		return false
+	}
+	return dErr.statusCode == 0 || dErr.statusCode >= 500 || dErr.statusCode == http.StatusTooManyRequests
+}
@@ -0,0 +1,222 @@
+/*
+Copyright 2021 Google LLC
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package deliver
+
+import (
+	"context"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"sync"
+	"testing"
+	"time"
+
+	"cloud.google.com/go/pubsub"
+	cepubsub "github.com/cloudevents/sdk-go/protocol/pubsub/v2"
+	ceclient "github.com/cloudevents/sdk-go/v2/client"
+	logtest "knative.dev/pkg/logging/testing"
+
+	"github.com/google/knative-gcp/pkg/broker/config"
+	"github.com/google/knative-gcp/pkg/broker/config/memory"
+	handlerctx "github.com/google/knative-gcp/pkg/broker/handler/context"
+	reportertest "github.com/google/knative-gcp/pkg/metrics/testing"
+)
+
+func TestCircuitBreakers(t *testing.T) {
+	ctx := logtest.TestContextWithLogger(t)
+	now := time.Now()
+	c := NewCircuitBreakers(2, time.Minute, nil)
+	c.now = func() time.Time { return now }
+	target := &config.Target{Namespace: "ns", Name: "target", CellTenantType: config.CellTenantType_BROKER, CellTenantName: "broker"}
+	unavailable := &deliveryError{statusCode: http.StatusServiceUnavailable, err: errors.New("unavailable")}
+	rejected := &deliveryError{statusCode: http.StatusBadRequest, err: errors.New("bad request")}
+
+	state := func() CircuitState {
+		if b, ok := c.breakers[*target.Key()]; ok {
+			return b.state
+		}
+		return CircuitClosed
+	}
+	steps := []struct {
+		name      string
+		elapsed   time.Duration
+		err       error
+		wantAllow bool
+		wantState CircuitState
+	}{
+		{name: "first failure", err: unavailable, wantAllow: true, wantState: CircuitClosed},
+		{name: "rejected event resets failures", err: rejected, wantAllow: true, wantState: CircuitClosed},
+		{name: "failure after reset", err: unavailable, wantAllow: true, wantState: CircuitClosed},
+		{name: "second consecutive failure opens", err: unavailable, wantAllow: true, wantState: CircuitOpen},
+		{name: "open", elapsed: 30 * time.Second, wantAllow: false, wantState: CircuitOpen},
+		{name: "failed probe opens again", elapsed: 31 * time.Second, err: unavailable, wantAllow: true, wantState: CircuitOpen},
+		{name: "open again", elapsed: 30 * time.Second, wantAllow: false, wantState: CircuitOpen},
+		{name: "successful probe closes", elapsed: 31 * time.Second, wantAllow: true, wantState: CircuitClosed},
+	}
+	for _, s := range steps {
+		now = now.Add(s.elapsed)
+		err := c.allow(ctx, target)
+		if gotAllow := err == nil; gotAllow != s.wantAllow {
+			t.Fatalf("%s: allow() error = %v, want allowed %v", s.name, err, s.wantAllow)
+		}
+		if err == nil {
+			c.record(ctx, target, s.err)
+		}
+		if got := state(); got != s.wantState {
+			t.Fatalf("%s: state got=%v, want=%v", s.name, got, s.wantState)
+		}
+	}
+}
+
+func TestCircuitBreakersSingleProbe(t *testing.T) {
+	ctx := logtest.TestContextWithLogger(t)
+	now := time.Now()
+	c := NewCircuitBreakers(1, time.Minute, nil)
+	c.now = func() time.Time { return now }
+	target := &config.Target{Namespace: "ns", Name: "target", CellTenantType: config.CellTenantType_BROKER, CellTenantName: "broker"}
+
+	c.record(ctx, target, &deliveryError{err: errors.New("timeout")})
+	now = now.Add(time.Minute)
+	if err := c.allow(ctx, target); err != nil {
+		t.Fatalf("probe allow() error = %v", err)
+	}
+	if err := c.allow(ctx, target); !errors.Is(err, errCircuitOpen) {
+		t.Errorf("allow() during probe error = %v, want %v", err, errCircuitOpen)
+	}
+}
+
+func TestCircuitBreakersPrune(t *testing.T) {
+	ctx := logtest.TestContextWithLogger(t)
+	c := NewCircuitBreakers(5, time.Minute, nil)
+	target := &config.Target{Namespace: "ns", Name: "target", CellTenantType: config.CellTenantType_BROKER, CellTenantName: "broker"}
+	deleted := &config.Target{Namespace: "ns", Name: "deleted", CellTenantType: config.CellTenantType_BROKER, CellTenantName: "broker"}
+	targets := memory.NewEmptyTargets()
+	targets.MutateCellTenant(target.Key().ParentKey(), func(m config.CellTenantMutation) {
+		m.UpsertTargets(target)
+	})
+
+	failure := &deliveryError{err: errors.New("timeout")}
+	c.record(ctx, target, failure)
+	c.record(ctx, deleted, failure)
+	c.Prune(targets)
+
+	if _, ok := c.breakers[*target.Key()]; !ok {
+		t.Error("breaker of existing target was pruned")
+	}
+	if _, ok := c.breakers[*deleted.Key()]; ok {
+		t.Error("breaker of deleted target was not pruned")
+	}
+}
+
+func TestCircuitBreakerShortCircuit(t *testing.T) {
+	reportertest.ResetDeliveryMetrics()
+	ctx := logtest.TestContextWithLogger(t)
+	targetHandler := &recordingHandler{t: t, codes: []int{http.StatusInternalServerError}}
+	targetSvr := httptest.NewServer(targetHandler)
+	defer targetSvr.Close()
+	deadLetterHandler := &recordingHandler{t: t, codes: []int{http.StatusOK}}
+	deadLetterSvr := httptest.NewServer(deadLetterHandler)
+	defer deadLetterSvr.Close()
+
+	p, ctx := newDeadLetterProcessor(ctx, t, targetSvr.URL, deadLetterSvr.URL, 3)
+	p.Breakers = NewCircuitBreakers(2, time.Minute, p.StatsReporter)
+	e := newSampleEvent()
+	for i := 0; i < 2; i++ {
+		if err := p.Process(ctx, e); err == nil {
+			t.Fatalf("Process() attempt %d succeeded, want error", i+1)
+		}
+	}
+	for i := 0; i < 3; i++ {
+		if err := p.Process(ctx, e); !errors.Is(err, errCircuitOpen) {
+			t.Fatalf("Process() with open circuit error = %v, want %v", err, errCircuitOpen)
+		}
+	}
+
+	if got := len(targetHandler.received()); got != 2 {
+		t.Errorf("target calls got=%d, want=2", got)
+	}
+	if got := len(deadLetterHandler.received()); got != 0 {
+		t.Errorf("dead letter sink calls got=%d, want=0", got)
+	}
+	// The short-circuited deliveries are not attempts.
+	tk, err := handlerctx.GetTargetKey(ctx)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if got, _ := p.Attempts.Attempt(newAttemptKey(tk, e)); got != 3 {
+		t.Errorf("next attempt got=%d, want=3", got)
+	}
+}
+
+func TestCircuitBreakerEnqueuesForRetry(t *testing.T) {
+	reportertest.ResetDeliveryMetrics()
+	ctx := logtest.TestContextWithLogger(t)
+	targetHandler := &recordingHandler{t: t, codes: []int{http.StatusServiceUnavailable}}
+	targetSvr := httptest.NewServer(targetHandler)
+	defer targetSvr.Close()
+
+	_, c, close := testPubsubClient(ctx, t, "test-project")
+	defer close()
+	topic, err := c.CreateTopic(ctx, "test-retry-topic")
+	if err != nil {
+		t.Fatalf("failed to create test pubsub topic: %v", err)
+	}
+	sub, err := c.CreateSubscription(ctx, "test-retry-sub", pubsub.SubscriptionConfig{Topic: topic})
+	if err != nil {
+		t.Fatalf("failed to create test pubsub subscription: %v", err)
+	}
+	ps, err := cepubsub.New(ctx, cepubsub.WithClient(c), cepubsub.WithProjectID("test-project"))
+	if err != nil {
+		t.Fatalf("failed to create pubsub protocol: %v", err)
+	}
+	deliverRetryClient, err := ceclient.New(ps)
+	if err != nil {
+		t.Fatalf("failed to create cloudevents client: %v", err)
+	}
+
+	p, ctx := newTargetProcessor(ctx, t, &config.Target{
+		Address:    targetSvr.URL,
+		RetryQueue: &config.Queue{Topic: "test-retry-topic"},
+	})
+	p.RetryOnFailure = true
+	p.DeliverRetryClient = deliverRetryClient
+	p.Breakers = NewCircuitBreakers(1, time.Minute, p.StatsReporter)
+	for i := 0; i < 3; i++ {
+		if err := p.Process(ctx, newSampleEvent()); err != nil {
+			t.Fatalf("Process() error = %v", err)
+		}
+	}
+
+	if got := len(targetHandler.received()); got != 1 {
+		t.Errorf("target calls got=%d, want=1", got)
+	}
+	rctx, cancel := context.WithTimeout(ctx, 5*time.Second)
+	defer cancel()
+	var mux sync.Mutex
+	retried := 0
+	sub.Receive(rctx, func(_ context.Context, m *pubsub.Message) {
+		m.Ack()
+		mux.Lock()
+		defer mux.Unlock()
+		if retried++; retried == 3 {
+			cancel()
+		}
+	})
+	if retried != 3 {
+		t.Errorf("retried events got=%d, want=3", retried)
+	}
+}
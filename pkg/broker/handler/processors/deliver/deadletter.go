@@ -0,0 +1,207 @@
+/*
+Copyright 2021 Google LLC
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package deliver
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"io/ioutil"
+	"net/http"
+	"time"
+
+	"github.com/cloudevents/sdk-go/v2/binding"
+	"github.com/cloudevents/sdk-go/v2/binding/transformer"
+	"github.com/cloudevents/sdk-go/v2/event"
+	"go.uber.org/zap"
+
+	"github.com/google/knative-gcp/pkg/broker/config"
+	"github.com/google/knative-gcp/pkg/broker/eventutil"
+	"github.com/google/knative-gcp/pkg/logging"
+	"github.com/google/knative-gcp/pkg/utils/deadletter"
+)
+
+const (
+	// Extensions describing why an event was dead lettered, as defined by the Knative data plane
+	// contract.
+	ErrorDestExtension = deadletter.ErrorDestExtension
+	ErrorCodeExtension = deadletter.ErrorCodeExtension
+	ErrorDataExtension = deadletter.ErrorDataExtension
+)
+
+// deliveryError is the error of an event delivery, along with the information that is attached
+// to the event when it is dead lettered.
+type deliveryError struct {
+	// destination is the address the event was sent to.
+	destination string
+	// statusCode is the status code of the response. It is zero if there was no response.
+	statusCode int
+	// data is the beginning of the response body.
+	data []byte
+	// nonRetryable is true if the response must not be retried.
+	nonRetryable bool
+	// retryAfter is the delay requested by the Retry-After header of the response.
+	retryAfter time.Duration
+	err        error
+}
+
+func (e *deliveryError) Error() string {
+	return e.err.Error()
+}
+
+func (e *deliveryError) Unwrap() error {
+	return e.err
+}
+
+// RetryAfter returns the delay requested by the destination before the event is delivered again.
+func (e *deliveryError) RetryAfter() time.Duration {
+	return e.retryAfter
+}
+
+// newResponseError creates a deliveryError from a non 2xx response, keeping the beginning of
+// its body.
+func newResponseError(destination string, resp *http.Response, err error) *deliveryError {
+	data, _ := ioutil.ReadAll(io.LimitReader(resp.Body, deadletter.MaxErrorDataSize))
+	return &deliveryError{
+		destination: destination,
+		statusCode:  resp.StatusCode,
+		data:        data,
+		retryAfter:  parseRetryAfter(resp.Header, time.Now()),
+		err:         err,
+	}
+}
+
+// transformers returns the transformers that set the error extensions on the dead lettered
+// event.
+func (e *deliveryError) transformers() []binding.Transformer {
+	data := e.data
+	if e.statusCode == 0 {
+		data = []byte(e.err.Error())
+	}
+	var transformers []binding.Transformer
+	for name, value := range deadletter.Extensions(e.destination, e.statusCode, data) {
+		transformers = append(transformers, setExtension(name, value))
+	}
+	return transformers
+}
+
+func setExtension(name string, value interface{}) binding.Transformer {
+	return transformer.SetExtension(name, func(interface{}) (interface{}, error) {
+		return value, nil
+	})
+}
+
+// attemptKey identifies the delivery attempts of an event to a target in the attempt tracker.
+type attemptKey struct {
+	target config.TargetKey
+	source string
+	id     string
+}
+
+func newAttemptKey(target *config.TargetKey, e *event.Event) attemptKey {
+	return attemptKey{target: *target, source: e.Source(), id: e.ID()}
+}
+
+// deadLetterPolicy returns the dead letter policy applied by this processor to the target, if
+// any. Events are only dead lettered from the retry queue.
+func (p *Processor) deadLetterPolicy(target *config.Target) *config.DeadLetterPolicy {
+	if p.RetryOnFailure || p.Attempts == nil {
+		return nil
+	}
+	return target.DeadLetterPolicy
+}
+
+// deliverOrDeadLetter delivers the event, unless it has exhausted its retries, in which case it
+// is sent to the dead letter sink instead. An event is always delivered at least once by this
+// processor, even with no retry, so that the dead lettered event describes its delivery failure.
+func (p *Processor) deliverOrDeadLetter(ctx context.Context, target *config.Target, broker *config.CellTenant, dlp *config.DeadLetterPolicy, e *event.Event, hops int32) error {
+	tk := target.Key()
+	key := newAttemptKey(tk, e)
+	attempt, err := p.Attempts.Attempt(key)
+	// Only the deliveryErrors are recorded.
+	lastErr, _ := err.(*deliveryError)
+	if attempt > dlp.Retry && lastErr != nil {
+		// The retries were exhausted, but the event could not be dead lettered.
+		return p.deadLetterAndForget(ctx, target, dlp, e, lastErr)
+	}
+
+	err = p.deliverWithTimeout(ctx, target, broker, eventutil.NewImmutableEventMessage(e), hops)
+	if err == nil {
+		p.Attempts.Forget(key)
+		return nil
+	}
+	if isNotDelivered(err) {
+		// The event was not delivered, so this is not an attempt.
+		p.Attempts.Retract(key)
+		return err
+	}
+	var dErr *deliveryError
+	if !errors.As(err, &dErr) {
+		dErr = &deliveryError{destination: target.Address, err: err}
+	}
+	p.Attempts.Failed(key, dErr)
+	if dErr.nonRetryable {
+		logging.FromContext(ctx).Warn("target delivery failed with a non retryable response", zap.Stringer("target", tk), zap.Error(err))
+		return p.deadLetterAndForget(ctx, target, dlp, e, dErr)
+	}
+	if attempt < dlp.Retry {
+		return err
+	}
+	logging.FromContext(ctx).Warn("target delivery failed, the event exhausted its retries", zap.Stringer("target", tk), zap.Int32("attempts", attempt), zap.Error(err))
+	return p.deadLetterAndForget(ctx, target, dlp, e, dErr)
+}
+
+// deadLetterAndForget sends the event to the dead letter sink, and forgets its attempts once it
+// was accepted.
+func (p *Processor) deadLetterAndForget(ctx context.Context, target *config.Target, dlp *config.DeadLetterPolicy, e *event.Event, deliveryErr *deliveryError) error {
+	if err := p.deadLetter(ctx, dlp, e, deliveryErr); err != nil {
+		return err
+	}
+	p.Attempts.Forget(newAttemptKey(target.Key(), e))
+	return nil
+}
+
+// deadLetter sends the event to the dead letter sink, along with the error of its last delivery
+// attempt. An error is returned if the dead letter sink did not accept the event, so that it is
+// redelivered.
+func (p *Processor) deadLetter(ctx context.Context, dlp *config.DeadLetterPolicy, e *event.Event, deliveryErr *deliveryError) error {
+	if p.DeliverTimeout > 0 {
+		var cancel context.CancelFunc
+		ctx, cancel = context.WithTimeout(ctx, p.DeliverTimeout)
+		defer cancel()
+	}
+
+	transformers := []binding.Transformer{
+		// Remove hops from dead lettered event.
+		transformer.DeleteExtension(eventutil.HopsAttribute),
+	}
+	if deliveryErr != nil {
+		transformers = append(transformers, deliveryErr.transformers()...)
+	}
+	resp, err := p.sendMsg(ctx, dlp.Address, nil, eventutil.NewImmutableEventMessage(e), transformers...)
+	if err != nil {
+		return fmt.Errorf("failed to send event to dead letter sink: %w", err)
+	}
+	if err := resp.Body.Close(); err != nil {
+		logging.FromContext(ctx).Warn("Failed to close dead letter sink response body", zap.Error(err))
+	}
+	if resp.StatusCode < 200 || resp.StatusCode >= 300 {
+		return fmt.Errorf("failed to send event to dead letter sink: HTTP status code %d", resp.StatusCode)
+	}
+	return nil
+}
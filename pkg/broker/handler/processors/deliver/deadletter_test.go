@@ -0,0 +1,303 @@
+/*
+Copyright 2021 Google LLC
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package deliver
+
+import (
+	"context"
+	"encoding/base64"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/cloudevents/sdk-go/v2/binding"
+	"github.com/cloudevents/sdk-go/v2/event"
+	cehttp "github.com/cloudevents/sdk-go/v2/protocol/http"
+	"github.com/google/go-cmp/cmp"
+	logtest "knative.dev/pkg/logging/testing"
+
+	"github.com/google/knative-gcp/pkg/broker/config"
+	"github.com/google/knative-gcp/pkg/broker/config/memory"
+	"github.com/google/knative-gcp/pkg/broker/eventutil"
+	handlerctx "github.com/google/knative-gcp/pkg/broker/handler/context"
+	"github.com/google/knative-gcp/pkg/metrics"
+	reportertest "github.com/google/knative-gcp/pkg/metrics/testing"
+	"github.com/google/knative-gcp/pkg/utils/deadletter"
+)
+
+// recordingHandler responds with the given status codes in turn, repeating the last one, and
+// records the events it received.
+type recordingHandler struct {
+	t     *testing.T
+	codes []int
+	body  string
+
+	mux    sync.Mutex
+	events []*event.Event
+}
+
+func (h *recordingHandler) ServeHTTP(w http.ResponseWriter, req *http.Request) {
+	e, err := binding.ToEvent(req.Context(), cehttp.NewMessageFromHttpRequest(req))
+	if err != nil {
+		h.t.Errorf("failed to convert request to event: %v", err)
+	}
+	h.mux.Lock()
+	code := h.codes[len(h.codes)-1]
+	if len(h.events) < len(h.codes) {
+		code = h.codes[len(h.events)]
+	}
+	h.events = append(h.events, e)
+	h.mux.Unlock()
+	w.WriteHeader(code)
+	w.Write([]byte(h.body))
+}
+
+func (h *recordingHandler) received() []*event.Event {
+	h.mux.Lock()
+	defer h.mux.Unlock()
+	return h.events
+}
+
+func TestDeadLetter(t *testing.T) {
+	cases := []struct {
+		name            string
+		retry           int32
+		targetCodes     []int
+		targetBody      string
+		deadLetterCodes []int
+		// wantErrs holds whether each successive attempt is expected to fail.
+		wantErrs        []bool
+		wantTargetCalls int
+		wantDeadLetters int
+		wantCode        string
+		wantData        string
+	}{{
+		name:            "delivered before exhausting retries",
+		retry:           3,
+		targetCodes:     []int{http.StatusInternalServerError, http.StatusOK},
+		deadLetterCodes: []int{http.StatusOK},
+		wantErrs:        []bool{true, false},
+		wantTargetCalls: 2,
+	}, {
+		name:            "dead lettered after exhausting retries",
+		retry:           2,
+		targetCodes:     []int{http.StatusInternalServerError},
+		targetBody:      "something went wrong",
+		deadLetterCodes: []int{http.StatusOK},
+		wantErrs:        []bool{true, false},
+		wantTargetCalls: 2,
+		wantDeadLetters: 1,
+		wantCode:        "500",
+		wantData:        "something went wrong",
+	}, {
+		name:            "response body is truncated",
+		retry:           1,
+		targetCodes:     []int{http.StatusBadRequest},
+		targetBody:      strings.Repeat("x", 2*deadletter.MaxErrorDataSize),
+		deadLetterCodes: []int{http.StatusOK},
+		wantErrs:        []bool{false},
+		wantTargetCalls: 1,
+		wantDeadLetters: 1,
+		wantCode:        "400",
+		wantData:        strings.Repeat("x", deadletter.MaxErrorDataSize),
+	}, {
+		name:            "dead letter sink failure",
+		retry:           1,
+		targetCodes:     []int{http.StatusInternalServerError},
+		targetBody:      "something went wrong",
+		deadLetterCodes: []int{http.StatusServiceUnavailable, http.StatusOK},
+		wantErrs:        []bool{true, false},
+		// The event is not delivered again once it exhausted its retries.
+		wantTargetCalls: 1,
+		wantDeadLetters: 2,
+		wantCode:        "500",
+		wantData:        "something went wrong",
+	}, {
+		name:            "delivered without retry",
+		retry:           0,
+		targetCodes:     []int{http.StatusOK},
+		deadLetterCodes: []int{http.StatusOK},
+		wantErrs:        []bool{false},
+		wantTargetCalls: 1,
+	}, {
+		name:            "dead lettered without retry",
+		retry:           0,
+		targetCodes:     []int{http.StatusInternalServerError},
+		targetBody:      "something went wrong",
+		deadLetterCodes: []int{http.StatusOK},
+		wantErrs:        []bool{false},
+		wantTargetCalls: 1,
+		wantDeadLetters: 1,
+		wantCode:        "500",
+		wantData:        "something went wrong",
+	}, {
+		name:            "dead letter sink failure without retry",
+		retry:           0,
+		targetCodes:     []int{http.StatusInternalServerError},
+		targetBody:      "something went wrong",
+		deadLetterCodes: []int{http.StatusServiceUnavailable, http.StatusOK},
+		wantErrs:        []bool{true, false},
+		wantTargetCalls: 1,
+		wantDeadLetters: 2,
+		wantCode:        "500",
+		wantData:        "something went wrong",
+	}}
+
+	for _, tc := range cases {
+		t.Run(tc.name, func(t *testing.T) {
+			reportertest.ResetDeliveryMetrics()
+			ctx := logtest.TestContextWithLogger(t)
+			targetHandler := &recordingHandler{t: t, codes: tc.targetCodes, body: tc.targetBody}
+			targetSvr := httptest.NewServer(targetHandler)
+			defer targetSvr.Close()
+			deadLetterHandler := &recordingHandler{t: t, codes: tc.deadLetterCodes}
+			deadLetterSvr := httptest.NewServer(deadLetterHandler)
+			defer deadLetterSvr.Close()
+
+			p, ctx := newDeadLetterProcessor(ctx, t, targetSvr.URL, deadLetterSvr.URL, tc.retry)
+
+			e := newSampleEvent()
+			eventutil.UpdateRemainingHops(ctx, e, 10)
+			for i, wantErr := range tc.wantErrs {
+				if err := p.Process(ctx, e); (err != nil) != wantErr {
+					t.Errorf("attempt %d: Process() error = %v, wantErr %v", i+1, err, wantErr)
+				}
+			}
+
+			if got := len(targetHandler.received()); got != tc.wantTargetCalls {
+				t.Errorf("target calls got=%d, want=%d", got, tc.wantTargetCalls)
+			}
+			deadLetters := deadLetterHandler.received()
+			if len(deadLetters) != tc.wantDeadLetters {
+				t.Fatalf("dead letter sink calls got=%d, want=%d", len(deadLetters), tc.wantDeadLetters)
+			}
+			if tc.wantDeadLetters == 0 {
+				return
+			}
+			got := deadLetters[len(deadLetters)-1]
+			if got.ID() != e.ID() {
+				t.Errorf("dead lettered event id got=%q, want=%q", got.ID(), e.ID())
+			}
+			if _, ok := got.Extensions()[eventutil.HopsAttribute]; ok {
+				t.Errorf("dead lettered event has the %s extension", eventutil.HopsAttribute)
+			}
+			want := map[string]string{}
+			if tc.wantTargetCalls > 0 {
+				want[ErrorDestExtension] = targetSvr.URL
+			}
+			if tc.wantCode != "" {
+				want[ErrorCodeExtension] = tc.wantCode
+			}
+			if tc.wantData != "" {
+				want[ErrorDataExtension] = base64.StdEncoding.EncodeToString([]byte(tc.wantData))
+			}
+			if diff := cmp.Diff(want, errorExtensions(got)); diff != "" {
+				t.Errorf("dead lettered event extensions (-want,+got): %v", diff)
+			}
+		})
+	}
+}
+
+func TestDeadLetterUnreachableTarget(t *testing.T) {
+	reportertest.ResetDeliveryMetrics()
+	ctx := logtest.TestContextWithLogger(t)
+	targetSvr := httptest.NewServer(http.NotFoundHandler())
+	// Close the target server so that the delivery fails without a response.
+	targetSvr.Close()
+	deadLetterHandler := &recordingHandler{t: t, codes: []int{http.StatusOK}}
+	deadLetterSvr := httptest.NewServer(deadLetterHandler)
+	defer deadLetterSvr.Close()
+
+	p, ctx := newDeadLetterProcessor(ctx, t, targetSvr.URL, deadLetterSvr.URL, 1)
+	if err := p.Process(ctx, newSampleEvent()); err != nil {
+		t.Fatalf("Process() error = %v", err)
+	}
+
+	deadLetters := deadLetterHandler.received()
+	if len(deadLetters) != 1 {
+		t.Fatalf("dead letter sink calls got=%d, want=1", len(deadLetters))
+	}
+	got := errorExtensions(deadLetters[0])
+	if got[ErrorDestExtension] != targetSvr.URL {
+		t.Errorf("%s got=%q, want=%q", ErrorDestExtension, got[ErrorDestExtension], targetSvr.URL)
+	}
+	if _, ok := got[ErrorCodeExtension]; ok {
+		t.Errorf("unexpected %s extension", ErrorCodeExtension)
+	}
+	data, err := base64.StdEncoding.DecodeString(got[ErrorDataExtension])
+	if err != nil {
+		t.Fatalf("failed to decode %s: %v", ErrorDataExtension, err)
+	}
+	if !strings.Contains(string(data), "connection refused") {
+		t.Errorf("%s got=%q, want the connection error", ErrorDataExtension, data)
+	}
+}
+
+func newDeadLetterProcessor(ctx context.Context, t *testing.T, targetAddress, deadLetterAddress string, retry int32) (*Processor, context.Context) {
+	p, ctx := newTargetProcessor(ctx, t, &config.Target{
+		Address: targetAddress,
+		DeadLetterPolicy: &config.DeadLetterPolicy{
+			Address: deadLetterAddress,
+			Retry:   retry,
+		},
+	})
+	p.Attempts = deadletter.NewTracker()
+	return p, ctx
+}
+
+// newTargetProcessor returns a processor of the retry queue of the target, along with the
+// context of the target.
+func newTargetProcessor(ctx context.Context, t *testing.T, target *config.Target) (*Processor, context.Context) {
+	broker := &config.CellTenant{
+		Type:      config.CellTenantType_BROKER,
+		Namespace: "ns",
+		Name:      "broker",
+	}
+	target.Namespace = "ns"
+	target.Name = "target"
+	target.CellTenantType = config.CellTenantType_BROKER
+	target.CellTenantName = "broker"
+	testTargets := memory.NewEmptyTargets()
+	testTargets.MutateCellTenant(broker.Key(), func(bm config.CellTenantMutation) {
+		bm.UpsertTargets(target)
+	})
+	ctx = handlerctx.WithBrokerKey(ctx, broker.Key())
+	ctx = handlerctx.WithTargetKey(ctx, target.Key())
+
+	r, err := metrics.NewDeliveryReporter("pod", "container")
+	if err != nil {
+		t.Fatal(err)
+	}
+	return &Processor{
+		DeliverClient:  http.DefaultClient,
+		Targets:        testTargets,
+		StatsReporter:  r,
+		DeliverTimeout: 500 * time.Millisecond,
+	}, ctx
+}
+
+func errorExtensions(e *event.Event) map[string]string {
+	extensions := map[string]string{}
+	for _, name := range []string{ErrorDestExtension, ErrorCodeExtension, ErrorDataExtension} {
+		if v, ok := e.Extensions()[name]; ok {
+			extensions[name] = v.(string)
+		}
+	}
+	return extensions
+}
@@ -0,0 +1,192 @@
+/*
+Copyright 2021 Google LLC
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package deliver
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"sync"
+	"time"
+
+	"google.golang.org/protobuf/proto"
+
+	"github.com/google/knative-gcp/pkg/broker/config"
+)
+
+// fanoutLimitWait is how long the fanout waits for a target to admit an event within its delivery
+// limits before sending the event to the retry topic of the target.
+const fanoutLimitWait = time.Second
+
+// errLimited is returned when an event could not be delivered within the limits of the target
+// before the context was done or the maximum wait elapsed.
+var errLimited = errors.New("the delivery limits of the target were exceeded")
+
+// limitedError is returned when an event could not be delivered within the limits of the target.
+// It carries the delay after which the target is expected to admit the event, if known.
+type limitedError struct {
+	delay time.Duration
+	err   error
+}
+
+func (e *limitedError) Error() string {
+	if e.err == nil {
+		return errLimited.Error()
+	}
+	return fmt.Sprintf("%v: %v", errLimited, e.err)
+}
+
+func (e *limitedError) Is(target error) bool {
+	return target == errLimited
+}
+
+// RetryAfter returns the delay after which the event should be delivered again.
+func (e *limitedError) RetryAfter() time.Duration {
+	return e.delay
+}
+
+// Limiters enforce the delivery limits of the targets. Events over the limits wait for their
+// turn, so that the Pub/Sub client keeps extending their ack deadline, until their context is
+// done or their maximum wait elapsed.
+type Limiters struct {
+	mux      sync.Mutex
+	limiters map[config.TargetKey]*limiter
+}
+
+// limiter enforces the delivery limits of a target.
+type limiter struct {
+	limits *config.DeliveryLimits
+	// inFlight holds a token per concurrent delivery.
+	inFlight chan struct{}
+
+	mux sync.Mutex
+	// next is when the next event can be delivered.
+	next time.Time
+}
+
+// NewLimiters creates the limiters of the targets.
+func NewLimiters() *Limiters {
+	return &Limiters{limiters: make(map[config.TargetKey]*limiter)}
+}
+
+// acquire waits until an event can be delivered to the target within its limits, for at most
+// maxWait if it is positive. The returned function must be called once the delivery is done. A
+// nil Limiters never limits deliveries.
+func (l *Limiters) acquire(ctx context.Context, target *config.Target, maxWait time.Duration) (func(), error) {
+	if l == nil || target.DeliveryLimits == nil {
+		return func() {}, nil
+	}
+	lim := l.get(target)
+	release := func() {}
+	if lim.inFlight != nil {
+		var expired <-chan time.Time
+		if maxWait > 0 {
+			t := time.NewTimer(maxWait)
+			defer t.Stop()
+			expired = t.C
+		}
+		select {
+		case lim.inFlight <- struct{}{}:
+			release = func() { <-lim.inFlight }
+		case <-expired:
+			// The end of the in flight deliveries is unknown, so try again after the same wait.
+			return nil, &limitedError{delay: maxWait}
+		case <-ctx.Done():
+			return nil, &limitedError{err: ctx.Err()}
+		}
+	}
+	if err := lim.wait(ctx, maxWait); err != nil {
+		release()
+		return nil, err
+	}
+	return release, nil
+}
+
+// get returns the limiter of the target, replacing it if the limits of the target changed.
+func (l *Limiters) get(target *config.Target) *limiter {
+	l.mux.Lock()
+	defer l.mux.Unlock()
+	tk := *target.Key()
+	lim, ok := l.limiters[tk]
+	if !ok || !proto.Equal(lim.limits, target.DeliveryLimits) {
+		lim = newLimiter(target.DeliveryLimits)
+		l.limiters[tk] = lim
+	}
+	return lim
+}
+
+// Prune removes the limiters of the targets that no longer exist.
+func (l *Limiters) Prune(targets config.ReadonlyTargets) {
+	if l == nil {
+		return
+	}
+	l.mux.Lock()
+	defer l.mux.Unlock()
+	for key := range l.limiters {
+		if _, ok := targets.GetTargetByKey(&key); !ok {
+			delete(l.limiters, key)
+		}
+	}
+}
+
+func newLimiter(limits *config.DeliveryLimits) *limiter {
+	lim := &limiter{limits: limits}
+	if limits.MaxConcurrentDeliveries > 0 {
+		lim.inFlight = make(chan struct{}, limits.MaxConcurrentDeliveries)
+	}
+	return lim
+}
+
+// wait waits for the turn of the event according to the rate limit, which spaces the deliveries
+// evenly. The event does not take a turn if it would have to wait for longer than maxWait.
+func (lim *limiter) wait(ctx context.Context, maxWait time.Duration) error {
+	if lim.limits.MaxEventsPerSecond <= 0 {
+		return nil
+	}
+	interval := time.Second / time.Duration(lim.limits.MaxEventsPerSecond)
+	now := time.Now()
+	lim.mux.Lock()
+	turn := lim.next
+	if turn.Before(now) {
+		turn = now
+	}
+	delay := turn.Sub(now)
+	if maxWait > 0 && delay > maxWait {
+		lim.mux.Unlock()
+		return &limitedError{delay: delay}
+	}
+	lim.next = turn.Add(interval)
+	lim.mux.Unlock()
+
+	if delay <= 0 {
+		return nil
+	}
+	t := time.NewTimer(delay)
+	defer t.Stop()
+	select {
+	case <-t.C:
+		return nil
+	case <-ctx.Done():
+		// Give the turn back if no later event took one meanwhile.
+		lim.mux.Lock()
+		if lim.next.Equal(turn.Add(interval)) {
+			lim.next = turn
+		}
+		lim.mux.Unlock()
+		return &limitedError{err: ctx.Err()}
+	}
+}
@@ -0,0 +1,241 @@
+/*
+Copyright 2021 Google LLC
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package deliver
+
+import (
+	"context"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"sync"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	cepubsub "github.com/cloudevents/sdk-go/protocol/pubsub/v2"
+	ceclient "github.com/cloudevents/sdk-go/v2/client"
+	logtest "knative.dev/pkg/logging/testing"
+
+	"github.com/google/knative-gcp/pkg/broker/config"
+	reportertest "github.com/google/knative-gcp/pkg/metrics/testing"
+)
+
+func TestLimitersConcurrency(t *testing.T) {
+	ctx := context.Background()
+	l := NewLimiters()
+	target := &config.Target{Namespace: "ns", Name: "target", DeliveryLimits: &config.DeliveryLimits{MaxConcurrentDeliveries: 1}}
+
+	release, err := l.acquire(ctx, target, 0)
+	if err != nil {
+		t.Fatalf("acquire() error = %v", err)
+	}
+	tctx, cancel := context.WithTimeout(ctx, 50*time.Millisecond)
+	defer cancel()
+	if _, err := l.acquire(tctx, target, 0); !errors.Is(err, errLimited) {
+		t.Errorf("acquire() over the limit error = %v, want %v", err, errLimited)
+	}
+	release()
+	release, err = l.acquire(ctx, target, 0)
+	if err != nil {
+		t.Fatalf("acquire() after release error = %v", err)
+	}
+	release()
+}
+
+func TestLimitersRate(t *testing.T) {
+	ctx := context.Background()
+	l := NewLimiters()
+	target := &config.Target{Namespace: "ns", Name: "target", DeliveryLimits: &config.DeliveryLimits{MaxEventsPerSecond: 20}}
+
+	start := time.Now()
+	for i := 0; i < 3; i++ {
+		release, err := l.acquire(ctx, target, 0)
+		if err != nil {
+			t.Fatalf("acquire() error = %v", err)
+		}
+		release()
+	}
+	if elapsed := time.Since(start); elapsed < 100*time.Millisecond {
+		t.Errorf("3 deliveries at 20 per second took %v, want at least 100ms", elapsed)
+	}
+
+	tctx, cancel := context.WithCancel(ctx)
+	cancel()
+	if _, err := l.acquire(tctx, target, 0); !errors.Is(err, errLimited) {
+		t.Errorf("acquire() with done context error = %v, want %v", err, errLimited)
+	}
+}
+
+func TestLimitersChangedLimits(t *testing.T) {
+	l := NewLimiters()
+	target := &config.Target{Namespace: "ns", Name: "target", DeliveryLimits: &config.DeliveryLimits{MaxConcurrentDeliveries: 1}}
+	if _, err := l.acquire(context.Background(), target, 0); err != nil {
+		t.Fatalf("acquire() error = %v", err)
+	}
+
+	target.DeliveryLimits = &config.DeliveryLimits{MaxConcurrentDeliveries: 2}
+	ctx, cancel := context.WithTimeout(context.Background(), 50*time.Millisecond)
+	defer cancel()
+	if _, err := l.acquire(ctx, target, 0); err != nil {
+		t.Errorf("acquire() with new limits error = %v", err)
+	}
+}
+
+func TestMaxConcurrentDeliveries(t *testing.T) {
+	reportertest.ResetDeliveryMetrics()
+	ctx := logtest.TestContextWithLogger(t)
+	var mux sync.Mutex
+	inFlight, maxInFlight := 0, 0
+	targetSvr := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, req *http.Request) {
+		mux.Lock()
+		inFlight++
+		if inFlight > maxInFlight {
+			maxInFlight = inFlight
+		}
+		mux.Unlock()
+		time.Sleep(20 * time.Millisecond)
+		mux.Lock()
+		inFlight--
+		mux.Unlock()
+		w.WriteHeader(http.StatusAccepted)
+	}))
+	defer targetSvr.Close()
+
+	p, ctx := newTargetProcessor(ctx, t, &config.Target{
+		Address:        targetSvr.URL,
+		DeliveryLimits: &config.DeliveryLimits{MaxConcurrentDeliveries: 2},
+	})
+	p.Limiters = NewLimiters()
+	var wg sync.WaitGroup
+	for i := 0; i < 6; i++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			if err := p.Process(ctx, newSampleEvent()); err != nil {
+				t.Errorf("Process() error = %v", err)
+			}
+		}()
+	}
+	wg.Wait()
+
+	if maxInFlight != 2 {
+		t.Errorf("max concurrent deliveries got=%d, want=2", maxInFlight)
+	}
+}
+
+func TestDeliverTimeoutAfterLimits(t *testing.T) {
+	reportertest.ResetDeliveryMetrics()
+	ctx := logtest.TestContextWithLogger(t)
+	targetSvr := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, req *http.Request) {
+		w.WriteHeader(http.StatusAccepted)
+	}))
+	defer targetSvr.Close()
+
+	p, ctx := newTargetProcessor(ctx, t, &config.Target{
+		Address:        targetSvr.URL,
+		DeliveryLimits: &config.DeliveryLimits{MaxEventsPerSecond: 2},
+	})
+	p.Limiters = NewLimiters()
+	p.DeliverTimeout = 100 * time.Millisecond
+	// The second event waits longer than the delivery timeout for its turn.
+	for i := 0; i < 2; i++ {
+		if err := p.Process(ctx, newSampleEvent()); err != nil {
+			t.Errorf("Process() error = %v", err)
+		}
+	}
+}
+
+func TestRetryLimitedTargets(t *testing.T) {
+	reportertest.ResetDeliveryMetrics()
+	ctx := logtest.TestContextWithLogger(t)
+	var delivered int32
+	targetSvr := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, req *http.Request) {
+		atomic.AddInt32(&delivered, 1)
+		w.WriteHeader(http.StatusAccepted)
+	}))
+	defer targetSvr.Close()
+
+	srv, c, close := testPubsubClient(ctx, t, "test-project")
+	defer close()
+	if _, err := c.CreateTopic(ctx, "test-retry-topic"); err != nil {
+		t.Fatalf("failed to create test pubsub topic: %v", err)
+	}
+	ps, err := cepubsub.New(ctx, cepubsub.WithClient(c), cepubsub.WithProjectID("test-project"))
+	if err != nil {
+		t.Fatalf("failed to create pubsub protocol: %v", err)
+	}
+	deliverRetryClient, err := ceclient.New(ps)
+	if err != nil {
+		t.Fatalf("failed to create cloudevents client: %v", err)
+	}
+
+	p, ctx := newTargetProcessor(ctx, t, &config.Target{
+		Address:        targetSvr.URL,
+		RetryQueue:     &config.Queue{Topic: "test-retry-topic"},
+		DeliveryLimits: &config.DeliveryLimits{MaxConcurrentDeliveries: 1},
+	})
+	p.Limiters = NewLimiters()
+	p.RetryOnFailure = true
+	p.DeliverRetryClient = deliverRetryClient
+	p.RetryLimitedTargets = true
+	if err := p.Process(ctx, newSampleEvent()); err != nil {
+		t.Fatalf("Process() error = %v", err)
+	}
+
+	if got := atomic.LoadInt32(&delivered); got != 0 {
+		t.Errorf("deliveries to the target got=%d, want=0", got)
+	}
+	if got := len(srv.Messages()); got != 1 {
+		t.Errorf("events sent to the retry topic got=%d, want=1", got)
+	}
+}
+
+func TestLimitersMaxWait(t *testing.T) {
+	ctx := context.Background()
+	l := NewLimiters()
+	target := &config.Target{Namespace: "ns", Name: "target", DeliveryLimits: &config.DeliveryLimits{
+		MaxConcurrentDeliveries: 1,
+		MaxEventsPerSecond:      1,
+	}}
+
+	release, err := l.acquire(ctx, target, 50*time.Millisecond)
+	if err != nil {
+		t.Fatalf("acquire() error = %v", err)
+	}
+	_, err = l.acquire(ctx, target, 50*time.Millisecond)
+	var lErr *limitedError
+	if !errors.As(err, &lErr) || lErr.RetryAfter() != 50*time.Millisecond {
+		t.Errorf("acquire() over the concurrency limit error = %v, want retry after 50ms", err)
+	}
+	release()
+
+	// The next turn is in about a second, which is longer than the maximum wait.
+	_, err = l.acquire(ctx, target, 50*time.Millisecond)
+	if !errors.As(err, &lErr) || lErr.RetryAfter() <= 50*time.Millisecond || lErr.RetryAfter() > time.Second {
+		t.Errorf("acquire() over the rate limit error = %v, want retry after at most 1s", err)
+	}
+	// The event that gave up did not take the turn.
+	start := time.Now()
+	release, err = l.acquire(ctx, target, 0)
+	if err != nil {
+		t.Fatalf("acquire() error = %v", err)
+	}
+	release()
+	if elapsed := time.Since(start); elapsed > 1500*time.Millisecond {
+		t.Errorf("acquire() after giving up waited %v, want at most 1s", elapsed)
+	}
+}
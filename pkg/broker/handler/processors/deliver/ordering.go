@@ -0,0 +1,275 @@
+/*
+Copyright 2021 Google LLC
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package deliver
+
+import (
+	"context"
+	"fmt"
+	"sync"
+	"time"
+
+	"cloud.google.com/go/pubsub"
+	cepubsub "github.com/cloudevents/sdk-go/protocol/pubsub/v2"
+	"github.com/cloudevents/sdk-go/v2/binding"
+	"github.com/cloudevents/sdk-go/v2/event"
+	cetypes "github.com/cloudevents/sdk-go/v2/types"
+	"github.com/google/uuid"
+	"go.uber.org/zap"
+
+	"github.com/google/knative-gcp/pkg/broker/config"
+	"github.com/google/knative-gcp/pkg/broker/eventutil"
+	"github.com/google/knative-gcp/pkg/logging"
+)
+
+// orderedRetryWindow is how long the events of an ordering key keep being sent to the retry
+// topic of a target after the delivery of an event with the same key failed, unless the retry data
+// plane notifies the delivery of the last of these events earlier. It must be longer than the time
+// it takes to deliver them from the retry topic.
+const orderedRetryWindow = time.Hour
+
+// orderedRetryDeliveredType is the type of the notices sent to the decouple topic by the retry
+// data plane once it delivered an event sent in order to the retry topic.
+const orderedRetryDeliveredType = "com.google.cloud.knative.broker.orderedretry.delivered"
+
+// OrderedRetries sends the events with an ordering key to the retry topics of the targets, with
+// their ordering key. Once the delivery of an event failed, the following events with the same
+// ordering key are also sent to the retry topic rather than delivered, so that they are not
+// delivered before the event being retried. The events are delivered again once the retry data
+// plane notifies the delivery of the last event sent to the retry topic. The ordering keys are
+// only held in memory, so the notices must be pulled by the replica that sent the events to the
+// retry topic: the decouple subscription of the broker must only be pulled by one replica.
+type OrderedRetries struct {
+	client *pubsub.Client
+	// now is replaced in tests.
+	now func() time.Time
+
+	mux    sync.Mutex
+	topics map[string]*pubsub.Topic
+	// retrying holds the ordering keys whose events are sent to the retry topic of a target.
+	retrying map[orderedRetryKey]orderedRetry
+}
+
+type orderedRetryKey struct {
+	target      config.TargetKey
+	orderingKey string
+}
+
+type orderedRetry struct {
+	// failedAt is when the delivery of an event with the ordering key last failed.
+	failedAt time.Time
+	// token identifies the last event with the ordering key sent to the retry topic.
+	token string
+}
+
+// orderedRetryNotice is the payload of the notices of the retry data plane.
+type orderedRetryNotice struct {
+	Target      string `json:"target"`
+	OrderingKey string `json:"orderingKey"`
+}
+
+// NewOrderedRetries creates the ordered retries publishing to the retry and decouple topics with
+// client.
+func NewOrderedRetries(client *pubsub.Client) *OrderedRetries {
+	return &OrderedRetries{
+		client:   client,
+		now:      time.Now,
+		topics:   make(map[string]*pubsub.Topic),
+		retrying: make(map[orderedRetryKey]orderedRetry),
+	}
+}
+
+// isRetrying returns true if earlier events with the ordering key were sent to the retry topic
+// of the target and are not delivered yet. A nil OrderedRetries never retries in order.
+func (o *OrderedRetries) isRetrying(tk *config.TargetKey, orderingKey string) bool {
+	if o == nil || orderingKey == "" {
+		return false
+	}
+	o.mux.Lock()
+	defer o.mux.Unlock()
+	r, ok := o.retrying[orderedRetryKey{target: *tk, orderingKey: orderingKey}]
+	return ok && o.now().Sub(r.failedAt) < orderedRetryWindow
+}
+
+// send publishes the event to the retry topic of the target with its ordering key. failed is true
+// if the delivery of the event failed, rather than being sent behind an earlier event.
+func (o *OrderedRetries) send(ctx context.Context, target *config.Target, orderingKey string, e *event.Event, failed bool) error {
+	token := uuid.New().String()
+	tokened := e.Clone()
+	tokened.SetExtension(eventutil.OrderedRetryExtension, token)
+	if err := o.publish(ctx, target.RetryQueue.Topic, orderingKey, &tokened); err != nil {
+		return err
+	}
+	o.mux.Lock()
+	defer o.mux.Unlock()
+	key := orderedRetryKey{target: *target.Key(), orderingKey: orderingKey}
+	r := o.retrying[key]
+	if failed {
+		r.failedAt = o.now()
+	}
+	r.token = token
+	o.retrying[key] = r
+	return nil
+}
+
+// notifyDelivered sends a notice of the delivery of the event with the token to the decouple
+// topic of the broker, with the ordering key of the event.
+func (o *OrderedRetries) notifyDelivered(ctx context.Context, broker *config.CellTenant, target *config.Target, orderingKey, token string) error {
+	notice := event.New()
+	notice.SetID(uuid.New().String())
+	notice.SetSource(target.Key().String())
+	notice.SetType(orderedRetryDeliveredType)
+	notice.SetExtension(eventutil.OrderedRetryExtension, token)
+	if err := notice.SetData(event.ApplicationJSON, orderedRetryNotice{Target: target.Name, OrderingKey: orderingKey}); err != nil {
+		return err
+	}
+	return o.publish(ctx, broker.DecoupleQueue.Topic, orderingKey, &notice)
+}
+
+// Delivered forgets the ordering key of a notice of the retry data plane if the notice is for the
+// last event sent to the retry topic. It returns false if the event is not a notice.
+func (o *OrderedRetries) Delivered(broker *config.CellTenant, e *event.Event) bool {
+	v, ok := e.Extensions()[eventutil.OrderedRetryExtension]
+	if !ok {
+		return false
+	}
+	if o == nil {
+		return true
+	}
+	token, err := cetypes.ToString(v)
+	if err != nil {
+		return true
+	}
+	var notice orderedRetryNotice
+	if err := e.DataAs(&notice); err != nil {
+		return true
+	}
+	target, ok := broker.Targets[notice.Target]
+	if !ok {
+		return true
+	}
+	o.mux.Lock()
+	defer o.mux.Unlock()
+	key := orderedRetryKey{target: *target.Key(), orderingKey: notice.OrderingKey}
+	if r, ok := o.retrying[key]; ok && r.token == token {
+		delete(o.retrying, key)
+	}
+	return true
+}
+
+func (o *OrderedRetries) publish(ctx context.Context, topicID, orderingKey string, e *event.Event) error {
+	msg := new(pubsub.Message)
+	if err := cepubsub.WritePubSubMessage(ctx, binding.ToMessage(e), msg); err != nil {
+		return err
+	}
+	msg.OrderingKey = orderingKey
+
+	topic := o.topic(topicID)
+	if _, err := topic.Publish(ctx, msg).Get(ctx); err != nil {
+		// The publishing of the ordering key is paused after a failure. The event is redelivered
+		// by its subscription, so the publishing is resumed.
+		topic.ResumePublish(orderingKey)
+		return err
+	}
+	return nil
+}
+
+func (o *OrderedRetries) topic(id string) *pubsub.Topic {
+	o.mux.Lock()
+	defer o.mux.Unlock()
+	topic, ok := o.topics[id]
+	if !ok {
+		topic = o.client.Topic(id)
+		topic.EnableMessageOrdering = true
+		o.topics[id] = topic
+	}
+	return topic
+}
+
+// Prune forgets the ordering keys whose retry window elapsed, and the topics of the brokers and
+// targets that no longer exist.
+func (o *OrderedRetries) Prune(targets config.ReadonlyTargets) {
+	if o == nil {
+		return
+	}
+	topics := make(map[string]bool)
+	targets.RangeCellTenants(func(b *config.CellTenant) bool {
+		if b.DecoupleQueue != nil {
+			topics[b.DecoupleQueue.Topic] = true
+		}
+		return true
+	})
+	targets.RangeAllTargets(func(t *config.Target) bool {
+		if t.RetryQueue != nil {
+			topics[t.RetryQueue.Topic] = true
+		}
+		return true
+	})
+	o.mux.Lock()
+	defer o.mux.Unlock()
+	now := o.now()
+	for key, r := range o.retrying {
+		if _, ok := targets.GetTargetByKey(&key.target); !ok || now.Sub(r.failedAt) >= orderedRetryWindow {
+			delete(o.retrying, key)
+		}
+	}
+	for id, topic := range o.topics {
+		if !topics[id] {
+			topic.Stop()
+			delete(o.topics, id)
+		}
+	}
+}
+
+// Release forgets the ordering keys of the targets of the broker, once it is handled by another
+// replica.
+func (o *OrderedRetries) Release(broker *config.CellTenantKey) {
+	if o == nil {
+		return
+	}
+	o.mux.Lock()
+	defer o.mux.Unlock()
+	for key := range o.retrying {
+		if *key.target.ParentKey() == *broker {
+			delete(o.retrying, key)
+		}
+	}
+}
+
+// sendToRetryTopicInOrder sends the event to the retry topic of the target with its ordering key.
+func (p *Processor) sendToRetryTopicInOrder(ctx context.Context, target *config.Target, orderingKey string, e *event.Event, failed bool) error {
+	if err := p.OrderedRetries.send(ctx, target, orderingKey, e, failed); err != nil {
+		return fmt.Errorf("failed to send event to retry topic: %w", err)
+	}
+	return nil
+}
+
+// notifyDelivered notifies the fanout that the event sent in order to the retry topic with the
+// token is no longer retried. The fanout delivers the following events of the ordering key again
+// once the retry window elapsed if the notice is lost.
+func (p *Processor) notifyDelivered(ctx context.Context, broker *config.CellTenant, target *config.Target, orderingKey string, token interface{}) {
+	if p.OrderedRetries == nil || broker.DecoupleQueue == nil {
+		return
+	}
+	t, err := cetypes.ToString(token)
+	if err == nil {
+		err = p.OrderedRetries.notifyDelivered(ctx, broker, target, orderingKey, t)
+	}
+	if err != nil {
+		logging.FromContext(ctx).Warn("failed to notify the delivery of an event retried in order",
+			zap.Stringer("target", target.Key()), zap.Error(err))
+	}
+}
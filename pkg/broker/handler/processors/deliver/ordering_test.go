@@ -0,0 +1,239 @@
+/*
+Copyright 2021 Google LLC
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package deliver
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"sync"
+	"testing"
+	"time"
+
+	"cloud.google.com/go/pubsub"
+	cepubsub "github.com/cloudevents/sdk-go/protocol/pubsub/v2"
+	"github.com/cloudevents/sdk-go/v2/binding"
+	"github.com/cloudevents/sdk-go/v2/event"
+	"github.com/google/go-cmp/cmp"
+	logtest "knative.dev/pkg/logging/testing"
+
+	"github.com/google/knative-gcp/pkg/broker/config"
+	"github.com/google/knative-gcp/pkg/broker/config/memory"
+	handlerctx "github.com/google/knative-gcp/pkg/broker/handler/context"
+	"github.com/google/knative-gcp/pkg/metrics"
+	reportertest "github.com/google/knative-gcp/pkg/metrics/testing"
+)
+
+// failingSubscriber fails the deliveries of the events whose ID is in fail, and records the IDs
+// of the events it receives.
+type failingSubscriber struct {
+	fail map[string]bool
+
+	mux      sync.Mutex
+	received []string
+}
+
+func (s *failingSubscriber) ServeHTTP(w http.ResponseWriter, req *http.Request) {
+	id := req.Header.Get("ce-id")
+	s.mux.Lock()
+	s.received = append(s.received, id)
+	fail := s.fail[id]
+	s.mux.Unlock()
+	if fail {
+		w.WriteHeader(http.StatusServiceUnavailable)
+		return
+	}
+	w.WriteHeader(http.StatusOK)
+}
+
+func TestOrderedRetries(t *testing.T) {
+	reportertest.ResetDeliveryMetrics()
+	ctx := logtest.TestContextWithLogger(t)
+	subscriber := &failingSubscriber{fail: map[string]bool{"1": true, "6": true}}
+	subscriberSvr := httptest.NewServer(subscriber)
+	defer subscriberSvr.Close()
+
+	psSrv, psClient, cancel := testPubsubClient(ctx, t, "test-project")
+	defer cancel()
+	for _, topic := range []string{"decouple", "retry"} {
+		if _, err := psClient.CreateTopic(ctx, topic); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	broker := &config.CellTenant{
+		Type:          config.CellTenantType_BROKER,
+		Namespace:     "ns",
+		Name:          "broker",
+		DecoupleQueue: &config.Queue{Topic: "decouple"},
+		Ordering:      &config.Ordering{KeyExtension: "partitionkey"},
+	}
+	target := &config.Target{
+		Namespace:      "ns",
+		Name:           "target",
+		CellTenantType: config.CellTenantType_BROKER,
+		CellTenantName: "broker",
+		Address:        subscriberSvr.URL,
+		RetryQueue:     &config.Queue{Topic: "retry"},
+	}
+	testTargets := memory.NewEmptyTargets()
+	testTargets.MutateCellTenant(broker.Key(), func(m config.CellTenantMutation) {
+		m.SetDecoupleQueue(broker.DecoupleQueue)
+		m.SetOrdering(broker.Ordering)
+		m.UpsertTargets(target)
+	})
+	broker, _ = testTargets.GetCellTenantByKey(broker.Key())
+	ctx = handlerctx.WithBrokerKey(ctx, broker.Key())
+	ctx = handlerctx.WithTargetKey(ctx, target.Key())
+	r, err := metrics.NewDeliveryReporter("pod", "container")
+	if err != nil {
+		t.Fatal(err)
+	}
+	now := time.Now()
+	fanout := &Processor{
+		DeliverClient:  http.DefaultClient,
+		Targets:        testTargets,
+		StatsReporter:  r,
+		RetryOnFailure: true,
+		OrderedRetries: NewOrderedRetries(psClient),
+	}
+	fanout.OrderedRetries.now = func() time.Time { return now }
+	retry := &Processor{
+		DeliverClient:  http.DefaultClient,
+		Targets:        testTargets,
+		StatsReporter:  r,
+		OrderedRetries: NewOrderedRetries(psClient),
+	}
+
+	process := func(id, key string) {
+		e := newSampleEvent()
+		e.SetID(id)
+		if key != "" {
+			e.SetExtension("partitionkey", key)
+		}
+		if err := fanout.Process(ctx, e); err != nil {
+			t.Fatalf("Process(%s) error = %v", id, err)
+		}
+	}
+	// published returns the events published to the retry and decouple topics.
+	published := func() map[string]*event.Event {
+		events := make(map[string]*event.Event)
+		for _, msg := range psSrv.Messages() {
+			e, err := binding.ToEvent(ctx, cepubsub.NewMessage(&pubsub.Message{Data: msg.Data, Attributes: msg.Attributes}))
+			if err != nil {
+				t.Fatal(err)
+			}
+			if msg.OrderingKey != "a" {
+				t.Errorf("published event %s ordering key got=%q, want=%q", e.ID(), msg.OrderingKey, "a")
+			}
+			events[e.ID()] = e
+		}
+		return events
+	}
+	// redeliver delivers the retried event from the retry topic, and passes the notice of its
+	// delivery to the fanout.
+	redeliver := func(id string) {
+		if err := retry.Process(ctx, published()[id]); err != nil {
+			t.Fatalf("retry Process(%s) error = %v", id, err)
+		}
+		var notices int
+		for _, e := range published() {
+			if e.Type() == orderedRetryDeliveredType && fanout.OrderedRetries.Delivered(broker, e) {
+				notices++
+			}
+		}
+		if notices == 0 {
+			t.Fatalf("no notice of the delivery of %s", id)
+		}
+	}
+
+	// The delivery of 1 fails, so 2 is retried behind it, while 3 has another key.
+	process("1", "a")
+	process("2", "a")
+	process("3", "b")
+	// 4 is still retried once 1 is delivered, as 2 is not yet.
+	subscriber.mux.Lock()
+	subscriber.fail["1"] = false
+	subscriber.mux.Unlock()
+	redeliver("1")
+	process("4", "a")
+	// Once the last retried event is delivered, the events of the key are delivered again.
+	redeliver("2")
+	redeliver("4")
+	process("5", "a")
+	// The events retried behind 6 don't extend its retry window.
+	process("6", "a")
+	now = now.Add(orderedRetryWindow - time.Second)
+	process("7", "a")
+	now = now.Add(time.Second)
+	process("8", "a")
+
+	if diff := cmp.Diff([]string{"1", "3", "1", "2", "4", "5", "6", "8"}, subscriber.received); diff != "" {
+		t.Errorf("unexpected delivered events (-want, +got) = %v", diff)
+	}
+	var retried []string
+	for _, msg := range psSrv.Messages() {
+		if msg.Attributes["ce-type"] != orderedRetryDeliveredType {
+			retried = append(retried, msg.Attributes["ce-id"])
+		}
+	}
+	if diff := cmp.Diff([]string{"1", "2", "4", "6", "7"}, retried); diff != "" {
+		t.Errorf("unexpected retried events (-want, +got) = %v", diff)
+	}
+	for _, e := range published() {
+		if _, ok := e.Extensions()["kgcporderedretry"]; !ok {
+			t.Errorf("published event %s has no ordered retry token", e.ID())
+		}
+	}
+
+	fanout.OrderedRetries.Prune(memory.NewEmptyTargets())
+	if len(fanout.OrderedRetries.retrying) != 0 || len(fanout.OrderedRetries.topics) != 0 {
+		t.Error("Prune() kept the ordered retries of deleted targets")
+	}
+}
+
+func TestOrderedRetriesDeliveredIgnoresEvents(t *testing.T) {
+	var o *OrderedRetries
+	if o.Delivered(&config.CellTenant{}, newSampleEvent()) {
+		t.Error("Delivered() got=true for an event that is not a notice")
+	}
+}
+
+func TestOrderedRetriesRelease(t *testing.T) {
+	o := NewOrderedRetries(nil)
+	for _, broker := range []string{"released", "kept"} {
+		target := &config.Target{
+			Namespace:      "ns",
+			Name:           "target",
+			CellTenantType: config.CellTenantType_BROKER,
+			CellTenantName: broker,
+		}
+		o.retrying[orderedRetryKey{target: *target.Key(), orderingKey: "a"}] = orderedRetry{failedAt: o.now()}
+	}
+
+	released := config.TestOnlyBrokerKey("ns", "released")
+	o.Release(released)
+	for key := range o.retrying {
+		if *key.target.ParentKey() == *released {
+			t.Errorf("Release() kept the ordering key of %v", key.target)
+		}
+	}
+	if len(o.retrying) != 1 {
+		t.Errorf("Release() kept %d ordering keys, want 1", len(o.retrying))
+	}
+	var nilRetries *OrderedRetries
+	nilRetries.Release(released)
+}
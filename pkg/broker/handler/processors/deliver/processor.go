@@ -36,11 +36,13 @@ import (
 	"go.opencensus.io/trace"
 	"go.uber.org/zap"
 
+	"github.com/google/knative-gcp/pkg/broker/claimcheck"
 	"github.com/google/knative-gcp/pkg/broker/config"
 	"github.com/google/knative-gcp/pkg/broker/eventutil"
 	handlerctx "github.com/google/knative-gcp/pkg/broker/handler/context"
 	"github.com/google/knative-gcp/pkg/broker/handler/processors"
 	"github.com/google/knative-gcp/pkg/metrics"
+	"github.com/google/knative-gcp/pkg/utils/deadletter"
 )
 
 const defaultEventHopsLimit int32 = 255
@@ -69,12 +71,41 @@ type Processor struct {
 
 	// StatsReporter is used to report delivery metrics.
 	StatsReporter *metrics.DeliveryReporter
+
+	// Attempts counts the delivery attempts of events, to dead letter the events of targets with
+	// a dead letter policy once they exhausted their retries. If nil, events are never dead
+	// lettered by the processor.
+	Attempts *deadletter.Tracker
+
+	// Breakers short-circuits the delivery to the targets that keep failing. If nil, events are
+	// always delivered.
+	Breakers *CircuitBreakers
+
+	// Limiters enforces the delivery limits of the targets. If nil, deliveries are not limited.
+	Limiters *Limiters
+
+	// RetryLimitedTargets sends the events of the targets with delivery limits to their retry
+	// topic without trying to deliver them, if RetryOnFailure is true. With sharding, only the
+	// retry replica that owns a target delivers to it then, so its limits hold across replicas.
+	RetryLimitedTargets bool
+
+	// Authenticator provides the credentials of the targets with a delivery authentication. If
+	// nil, the deliveries to these targets fail.
+	Authenticator *Authenticator
+
+	// ClaimChecks restores the payloads of the events stored in Cloud Storage before they are
+	// delivered. If nil, the deliveries of these events fail.
+	ClaimChecks *claimcheck.Store
+
+	// OrderedRetries sends the events with an ordering key to the retry topic in order. If nil,
+	// the retried events are not ordered.
+	OrderedRetries *OrderedRetries
 }
 
 var _ processors.Interface = (*Processor)(nil)
 
 // Process delivers the event based on the broker/target in the context.
-func (p *Processor) Process(ctx context.Context, e *event.Event) error {
+func (p *Processor) Process(ctx context.Context, e *event.Event) (err error) {
 	bk, err := handlerctx.GetBrokerKey(ctx)
 	if err != nil {
 		return err
@@ -122,37 +153,131 @@ func (p *Processor) Process(ctx context.Context, e *event.Event) error {
 
 	p.StatsReporter.FinishEventProcessing(ctx)
 
-	dctx := ctx
-	if p.DeliverTimeout > 0 {
-		var cancel context.CancelFunc
-		dctx, cancel = context.WithTimeout(dctx, p.DeliverTimeout)
-		defer cancel()
+	// The fanout asks the retry data plane for a delay when the subscriber responded with a
+	// Retry-After header. The fanout ignores the delays set by the senders of the events.
+	if !p.RetryOnFailure {
+		if delay := eventutil.RedeliveryDelay(e, time.Now()); delay > 0 {
+			return retryLaterError(delay)
+		}
+	}
+	token, retriedInOrder := e.Extensions()[eventutil.OrderedRetryExtension]
+	if _, ok := e.Extensions()[eventutil.RedeliverAfterExtension]; ok || retriedInOrder {
+		cleared := e.Clone()
+		cleared.SetExtension(eventutil.RedeliverAfterExtension, nil)
+		cleared.SetExtension(eventutil.OrderedRetryExtension, nil)
+		e = &cleared
 	}
 
-	if err := p.deliver(dctx, target, broker, eventutil.NewImmutableEventMessage(e), hops); err != nil {
+	orderingKey := eventutil.OrderingKey(e, broker.Ordering)
+	if retriedInOrder && !p.RetryOnFailure {
+		// The fanout keeps sending the events of the ordering key to the retry topic until the
+		// last of them is no longer retried.
+		defer func() {
+			if err == nil {
+				p.notifyDelivered(ctx, broker, target, orderingKey, token)
+			}
+		}()
+	}
+	if p.RetryOnFailure && p.OrderedRetries.isRetrying(tk, orderingKey) {
+		// An earlier event with the same ordering key is being retried, so this one must be
+		// delivered after it.
+		trace.FromContext(ctx).Annotate(
+			[]trace.Attribute{trace.StringAttribute("ordering_key", orderingKey)},
+			"enqueueing for retry behind an earlier event",
+		)
+		return p.sendToRetryTopic(ctx, target, orderingKey, e, false)
+	}
+	if p.RetryOnFailure && p.RetryLimitedTargets && target.DeliveryLimits != nil && target.Address != "" {
+		// The retry data plane delivers the event once the limits of the target admit it.
+		trace.FromContext(ctx).Annotate(nil, "enqueueing for delivery within the limits of the target")
+		return p.sendToRetryTopic(ctx, target, orderingKey, e, false)
+	}
+
+	// The event keeps its claim check reference, so that it is sent to the retry topic without
+	// its payload.
+	delivered, err := p.ClaimChecks.Rehydrate(ctx, broker.ClaimCheck, bk, e)
+	if err != nil {
 		if !p.RetryOnFailure {
 			return err
 		}
+		logging.FromContext(ctx).Warn("failed to restore the event payload", zap.Stringer("target", tk), zap.Error(err))
+		trace.FromContext(ctx).Annotate(
+			[]trace.Attribute{trace.StringAttribute("error_message", err.Error())},
+			"enqueueing for retry",
+		)
+		return p.sendToRetryTopic(ctx, target, orderingKey, e, true)
+	}
 
-		logging.FromContext(ctx).Warn("target delivery failed", zap.Stringer("target", tk), zap.Error(err))
+	if dlp := p.deadLetterPolicy(target); dlp != nil {
+		if err := p.deliverOrDeadLetter(ctx, target, broker, dlp, delivered, hops); err != nil {
+			return err
+		}
+		// For post-delivery processing.
+		return p.Next().Process(ctx, e)
+	}
+
+	if err := p.deliverWithTimeout(ctx, target, broker, eventutil.NewImmutableEventMessage(delivered), hops); err != nil {
+		// Without a dead letter sink handled by the data plane, non retryable responses are
+		// retried, so that the dead letter policy of the retry subscription moves the event to
+		// a Pub/Sub topic dead letter sink rather than dropping it.
+		if isNonRetryable(err) && target.DeadLetterPolicy != nil {
+			return p.handleNonRetryable(ctx, target, delivered, err)
+		}
+		if !p.RetryOnFailure {
+			return err
+		}
+
+		// Events over the delivery limits of the target are retried once the target is expected
+		// to admit them, without holding back the delivery to the other targets.
+		if !isNotDelivered(err) {
+			logging.FromContext(ctx).Warn("target delivery failed", zap.Stringer("target", tk), zap.Error(err))
+		}
 		trace.FromContext(ctx).Annotate(
 			[]trace.Attribute{trace.StringAttribute("error_message", err.Error())},
 			"enqueueing for retry",
 		)
 
-		return p.sendToRetryTopic(ctx, target, e)
+		return p.sendToRetryTopic(ctx, target, orderingKey, withRedeliverAfter(e, err), true)
 	}
 	// For post-delivery processing.
 	return p.Next().Process(ctx, e)
 }
 
+// deliverWithTimeout delivers msg to target within its delivery limits, applying the
+// DeliverTimeout if there is one once the limits admit the delivery.
+func (p *Processor) deliverWithTimeout(ctx context.Context, target *config.Target, broker *config.CellTenant, msg binding.Message, hops int32) error {
+	if target.Address != "" {
+		var maxWait time.Duration
+		if p.RetryOnFailure {
+			// The fanout delivers the event to all the targets at once, so it does not wait long
+			// for a single one of them.
+			maxWait = fanoutLimitWait
+		}
+		release, err := p.Limiters.acquire(ctx, target, maxWait)
+		if err != nil {
+			return err
+		}
+		defer release()
+	}
+	if p.DeliverTimeout > 0 {
+		var cancel context.CancelFunc
+		ctx, cancel = context.WithTimeout(ctx, p.DeliverTimeout)
+		defer cancel()
+	}
+	return p.deliver(ctx, target, broker, msg, hops)
+}
+
 // deliver delivers msg to target and sends the target's reply to the broker ingress.
 func (p *Processor) deliver(ctx context.Context, target *config.Target, broker *config.CellTenant, msg binding.Message, hops int32) error {
 	// Channels can have a reply address without a subscriber. So default the replyMessage to the
 	// original message. If there is a subscriber, then replyMessage is overwritten.
 	replyMessage := msg
 	if target.Address != "" {
+		if err := p.Breakers.allow(ctx, target); err != nil {
+			return err
+		}
 		replyMsg, cleanUp, err := p.sendToSubscriber(ctx, target, msg, hops)
+		p.Breakers.record(ctx, target, err)
 		defer cleanUp()
 		if err != nil {
 			return fmt.Errorf("failed to send event to subscriber: %w", err)
@@ -187,37 +312,46 @@ func (p *Processor) deliver(ctx context.Context, target *config.Target, broker *
 		transformers = append(transformers, eventutil.SetRemainingHopsTransformer(hops))
 	}
 
-	replyResp, err := p.sendMsg(ctx, replyAddress, replyMessage, transformers...)
+	replyResp, err := p.sendMsg(ctx, replyAddress, replyAuth(target.DeliveryAuth), replyMessage, transformers...)
 	if err != nil {
-		return fmt.Errorf("failed to send event to reply: %w", err)
-	}
-	if err := replyResp.Body.Close(); err != nil {
-		logging.FromContext(ctx).Warn("Failed to close reply response body", zap.Error(err))
+		return fmt.Errorf("failed to send event to reply: %w", &deliveryError{destination: replyAddress, err: err})
 	}
+	defer func() {
+		if err := replyResp.Body.Close(); err != nil {
+			logging.FromContext(ctx).Warn("Failed to close reply response body", zap.Error(err))
+		}
+	}()
 	// TODO(https://github.com/google/knative-gcp/issues/2117) Add metrics around the reply
 	// requests, as they can lead to redelivery of events through the Trigger, but do not currently
 	// expose any metrics for users to understand why events are redelivered.
 	if replyResp.StatusCode < 200 || replyResp.StatusCode >= 300 {
-		return fmt.Errorf("event delivery failed sending the reply: HTTP status code %d", replyResp.StatusCode)
+		return newResponseError(replyAddress, replyResp,
+			fmt.Errorf("event delivery failed sending the reply: HTTP status code %d", replyResp.StatusCode))
 	}
 
 	return nil
 }
 
+// isNotDelivered returns true if the event was not sent to the target, either because its circuit
+// breaker is open or because of its delivery limits.
+func isNotDelivered(err error) bool {
+	return errors.Is(err, errCircuitOpen) || errors.Is(err, errLimited)
+}
+
 func (p *Processor) sendToSubscriber(ctx context.Context, target *config.Target, msg binding.Message, hops int32) (*cehttp.Message, func(), error) {
 	transformers := []binding.Transformer{
 		// Remove hops from forwarded event.
 		transformer.DeleteExtension(eventutil.HopsAttribute),
 	}
 	startTime := time.Now()
-	resp, err := p.sendMsg(ctx, target.Address, msg, transformers...)
+	resp, err := p.sendMsg(ctx, target.Address, target.DeliveryAuth, msg, transformers...)
 	if err != nil {
 		var result *url.Error
 		if errors.As(err, &result) && result.Timeout() {
 			// If the delivery is cancelled because of timeout, report event dispatch time without resp status code.
 			p.StatsReporter.ReportEventDispatchTime(ctx, time.Since(startTime))
 		}
-		return nil, func() {}, err
+		return nil, func() {}, &deliveryError{destination: target.Address, err: err}
 	}
 
 	closeBody := func() {
@@ -235,7 +369,17 @@ func (p *Processor) sendToSubscriber(ctx context.Context, target *config.Target,
 	p.StatsReporter.ReportEventDispatchTime(cctx, time.Since(startTime))
 
 	if resp.StatusCode < 200 || resp.StatusCode >= 300 {
-		return nil, closeBody, fmt.Errorf("event delivery failed: HTTP status code %d", resp.StatusCode)
+		switch classifyResponse(target.ResponsePolicy, resp.StatusCode) {
+		case responseSuccess:
+			// The response is not a reply.
+			return nil, closeBody, nil
+		case responseDeadLetter:
+			dErr := newResponseError(target.Address, resp, fmt.Errorf("event delivery failed with a non retryable response: HTTP status code %d", resp.StatusCode))
+			dErr.nonRetryable = true
+			return nil, closeBody, dErr
+		default:
+			return nil, closeBody, newResponseError(target.Address, resp, fmt.Errorf("event delivery failed: HTTP status code %d", resp.StatusCode))
+		}
 	}
 
 	// Pre-check the reply response header, if it's not in structured mode/batched mode or binary mode,
@@ -301,18 +445,40 @@ func (p *Processor) sendToSubscriber(ctx context.Context, target *config.Target,
 	return nil, closeBody, nil
 }
 
-func (p *Processor) sendMsg(ctx context.Context, address string, msg binding.Message, transformers ...binding.Transformer) (*http.Response, error) {
+func (p *Processor) sendMsg(ctx context.Context, address string, auth *config.DeliveryAuth, msg binding.Message, transformers ...binding.Transformer) (*http.Response, error) {
 	req, err := http.NewRequestWithContext(ctx, http.MethodPost, address, nil)
 	if err != nil {
 		return nil, err
 	}
+	authorization, err := p.Authenticator.authorization(auth, address)
+	if err != nil {
+		return nil, err
+	}
+	if authorization != "" {
+		req.Header.Set("Authorization", authorization)
+	}
 	if err := cehttp.WriteRequest(ctx, msg, req, transformers...); err != nil {
 		return nil, err
 	}
 	return p.DeliverClient.Do(req)
 }
 
-func (p *Processor) sendToRetryTopic(ctx context.Context, target *config.Target, event *event.Event) error {
+// withRedeliverAfter returns the event to send to the retry topic after the delivery failed with
+// err, with the time before which the retry data plane must not redeliver it, if any.
+func withRedeliverAfter(e *event.Event, err error) *event.Event {
+	var ra interface{ RetryAfter() time.Duration }
+	if !errors.As(err, &ra) || ra.RetryAfter() <= 0 {
+		return e
+	}
+	retried := e.Clone()
+	eventutil.SetRedeliverAfter(&retried, time.Now().Add(ra.RetryAfter()))
+	return &retried
+}
+
+func (p *Processor) sendToRetryTopic(ctx context.Context, target *config.Target, orderingKey string, event *event.Event, failed bool) error {
+	if orderingKey != "" && p.OrderedRetries != nil {
+		return p.sendToRetryTopicInOrder(ctx, target, orderingKey, event, failed)
+	}
 	pctx := cecontext.WithTopic(ctx, target.RetryQueue.Topic)
 	if err := p.DeliverRetryClient.Send(pctx, *event); err != nil {
 		return fmt.Errorf("failed to send event to retry topic: %w", err)
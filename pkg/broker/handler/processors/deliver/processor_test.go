@@ -19,6 +19,7 @@ package deliver
 import (
 	"bytes"
 	"context"
+	"errors"
 	"fmt"
 	"io"
 	"io/ioutil"
@@ -45,10 +46,12 @@ import (
 	"knative.dev/pkg/logging"
 	logtest "knative.dev/pkg/logging/testing"
 
+	"github.com/google/knative-gcp/pkg/broker/claimcheck"
 	"github.com/google/knative-gcp/pkg/broker/config"
 	"github.com/google/knative-gcp/pkg/broker/config/memory"
 	"github.com/google/knative-gcp/pkg/broker/eventutil"
 	handlerctx "github.com/google/knative-gcp/pkg/broker/handler/context"
+	gstoragetesting "github.com/google/knative-gcp/pkg/gclient/storage/testing"
 	"github.com/google/knative-gcp/pkg/metrics"
 	reportertest "github.com/google/knative-gcp/pkg/metrics/testing"
 
@@ -872,3 +875,58 @@ func newSampleEvent() *event.Event {
 	sampleEvent.SetTime(time.Now())
 	return &sampleEvent
 }
+
+func TestClaimCheckRehydration(t *testing.T) {
+	reportertest.ResetDeliveryMetrics()
+	ctx := logtest.TestContextWithLogger(t)
+	var received []byte
+	subscriberSvr := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, req *http.Request) {
+		received, _ = ioutil.ReadAll(req.Body)
+		if req.Header.Get("ce-"+claimcheck.ReferenceExtension) != "" {
+			t.Error("subscriber received the claim check reference")
+		}
+		w.WriteHeader(http.StatusOK)
+	}))
+	defer subscriberSvr.Close()
+
+	p, ctx := newTargetProcessor(ctx, t, &config.Target{Address: subscriberSvr.URL})
+	e := newSampleEvent()
+	e.SetDataContentType(event.TextPlain)
+	e.SetExtension(claimcheck.ReferenceExtension, "gs://bucket/ns/broker/object")
+
+	if err := p.Process(ctx, e); err == nil {
+		t.Error("Process() without claim check store succeeded, want error")
+	}
+	if received != nil {
+		t.Errorf("subscriber received %q without claim check store", received)
+	}
+
+	client, err := gstoragetesting.TestClientCreator(gstoragetesting.TestClientData{
+		BucketData: gstoragetesting.TestBucketData{
+			Objects: map[string][]byte{"ns/broker/object": []byte("payload")},
+		},
+	})(ctx)
+	if err != nil {
+		t.Fatal(err)
+	}
+	p.ClaimChecks = claimcheck.NewStore(client)
+	if err := p.Process(ctx, e); !errors.Is(err, claimcheck.ErrForeignReference) {
+		t.Errorf("Process() of a broker without claim check error = %v, want %v", err, claimcheck.ErrForeignReference)
+	}
+	if received != nil {
+		t.Errorf("subscriber received %q from a broker without claim check", received)
+	}
+
+	p.Targets.(config.Targets).MutateCellTenant(config.TestOnlyBrokerKey("ns", "broker"), func(m config.CellTenantMutation) {
+		m.SetClaimCheck(&config.ClaimCheck{Bucket: "bucket", ThresholdBytes: 1})
+	})
+	if err := p.Process(ctx, e); err != nil {
+		t.Fatalf("Process() error = %v", err)
+	}
+	if string(received) != "payload" {
+		t.Errorf("subscriber received %q, want %q", received, "payload")
+	}
+	if _, ok := e.Extensions()[claimcheck.ReferenceExtension]; !ok {
+		t.Error("Process() removed the claim check reference of the event")
+	}
+}
@@ -0,0 +1,115 @@
+/*
+Copyright 2021 Google LLC
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package deliver
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"math"
+	"net/http"
+	"strconv"
+	"time"
+
+	"github.com/cloudevents/sdk-go/v2/event"
+
+	"github.com/google/knative-gcp/pkg/broker/config"
+)
+
+// responseClass is how a non 2xx response of a target is handled.
+type responseClass int
+
+const (
+	// responseRetry responses are retried.
+	responseRetry responseClass = iota
+	// responseSuccess responses are treated as successful deliveries.
+	responseSuccess
+	// responseDeadLetter responses are not retried.
+	responseDeadLetter
+)
+
+// classifyResponse classifies a non 2xx status code according to the response policy of the
+// target. The status code is classified by the narrowest range that contains it.
+func classifyResponse(policy *config.ResponsePolicy, code int) responseClass {
+	class := responseRetry
+	width := int32(math.MaxInt32)
+	match := func(ranges []*config.StatusCodeRange, c responseClass) {
+		for _, r := range ranges {
+			if int32(code) >= r.Min && int32(code) <= r.Max && r.Max-r.Min < width {
+				class, width = c, r.Max-r.Min
+			}
+		}
+	}
+	match(policy.GetSuccess(), responseSuccess)
+	match(policy.GetDeadLetter(), responseDeadLetter)
+	match(policy.GetRetry(), responseRetry)
+	return class
+}
+
+// maxRetryAfter caps the delays requested by subscribers before an event is redelivered. It is
+// the maximum backoff of Pub/Sub subscriptions.
+const maxRetryAfter = 10 * time.Minute
+
+// parseRetryAfter returns the delay requested by a Retry-After header, which is either a number
+// of seconds or an HTTP date, capped to maxRetryAfter. It returns zero if there is no valid
+// header.
+func parseRetryAfter(header http.Header, now time.Time) time.Duration {
+	v := header.Get("Retry-After")
+	if v == "" {
+		return 0
+	}
+	var delay time.Duration
+	if seconds, err := strconv.Atoi(v); err == nil {
+		if seconds < 0 {
+			return 0
+		}
+		delay = time.Duration(seconds) * time.Second
+	} else if t, err := http.ParseTime(v); err == nil && t.After(now) {
+		delay = t.Sub(now)
+	}
+	if delay > maxRetryAfter {
+		return maxRetryAfter
+	}
+	return delay
+}
+
+// retryLaterError is returned by the retry data plane for the events that must not be
+// redelivered yet, along with the remaining delay.
+type retryLaterError time.Duration
+
+func (e retryLaterError) Error() string {
+	return fmt.Sprintf("event must not be redelivered for %v", time.Duration(e))
+}
+
+// RetryAfter returns the delay before the event can be redelivered.
+func (e retryLaterError) RetryAfter() time.Duration {
+	return time.Duration(e)
+}
+
+// isNonRetryable returns true if the error is a delivery failure that must not be retried.
+func isNonRetryable(err error) bool {
+	var dErr *deliveryError
+	return errors.As(err, &dErr) && dErr.nonRetryable
+}
+
+// handleNonRetryable sends an event whose delivery must not be retried to the dead letter sink
+// of the target.
+func (p *Processor) handleNonRetryable(ctx context.Context, target *config.Target, e *event.Event, err error) error {
+	var dErr *deliveryError
+	errors.As(err, &dErr)
+	return p.deadLetter(ctx, target.DeadLetterPolicy, e, dErr)
+}
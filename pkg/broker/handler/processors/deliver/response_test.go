@@ -0,0 +1,234 @@
+/*
+Copyright 2021 Google LLC
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package deliver
+
+import (
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	cepubsub "github.com/cloudevents/sdk-go/protocol/pubsub/v2"
+	ceclient "github.com/cloudevents/sdk-go/v2/client"
+	cetypes "github.com/cloudevents/sdk-go/v2/types"
+	"github.com/google/go-cmp/cmp"
+	logtest "knative.dev/pkg/logging/testing"
+
+	"github.com/google/knative-gcp/pkg/broker/config"
+	"github.com/google/knative-gcp/pkg/broker/eventutil"
+	reportertest "github.com/google/knative-gcp/pkg/metrics/testing"
+)
+
+func TestClassifyResponse(t *testing.T) {
+	policy := &config.ResponsePolicy{
+		Success:    []*config.StatusCodeRange{{Min: 409, Max: 409}},
+		DeadLetter: []*config.StatusCodeRange{{Min: 400, Max: 499}},
+		Retry:      []*config.StatusCodeRange{{Min: 408, Max: 408}, {Min: 429, Max: 429}},
+	}
+	cases := []struct {
+		name   string
+		policy *config.ResponsePolicy
+		code   int
+		want   responseClass
+	}{
+		{name: "no policy", code: http.StatusBadRequest, want: responseRetry},
+		{name: "unclassified", policy: policy, code: http.StatusInternalServerError, want: responseRetry},
+		{name: "class", policy: policy, code: http.StatusBadRequest, want: responseDeadLetter},
+		{name: "success code in class", policy: policy, code: http.StatusConflict, want: responseSuccess},
+		{name: "retry code in class", policy: policy, code: http.StatusTooManyRequests, want: responseRetry},
+	}
+	for _, tc := range cases {
+		t.Run(tc.name, func(t *testing.T) {
+			if got := classifyResponse(tc.policy, tc.code); got != tc.want {
+				t.Errorf("classifyResponse() = %v, want %v", got, tc.want)
+			}
+		})
+	}
+}
+
+func TestParseRetryAfter(t *testing.T) {
+	now := time.Date(2021, 6, 1, 12, 0, 0, 0, time.UTC)
+	cases := []struct {
+		name  string
+		value string
+		want  time.Duration
+	}{
+		{name: "no header", want: 0},
+		{name: "seconds", value: "120", want: 2 * time.Minute},
+		{name: "negative seconds", value: "-1", want: 0},
+		{name: "capped seconds", value: "3600", want: maxRetryAfter},
+		{name: "date", value: "Tue, 01 Jun 2021 12:00:30 GMT", want: 30 * time.Second},
+		{name: "past date", value: "Tue, 01 Jun 2021 11:00:00 GMT", want: 0},
+		{name: "invalid", value: "soon", want: 0},
+	}
+	for _, tc := range cases {
+		t.Run(tc.name, func(t *testing.T) {
+			header := http.Header{}
+			if tc.value != "" {
+				header.Set("Retry-After", tc.value)
+			}
+			if got := parseRetryAfter(header, now); got != tc.want {
+				t.Errorf("parseRetryAfter() = %v, want %v", got, tc.want)
+			}
+		})
+	}
+}
+
+func TestResponsePolicy(t *testing.T) {
+	policy := &config.ResponsePolicy{
+		Success:    []*config.StatusCodeRange{{Min: 409, Max: 409}},
+		DeadLetter: []*config.StatusCodeRange{{Min: 400, Max: 499}},
+	}
+	cases := []struct {
+		name            string
+		code            int
+		withDeadLetter  bool
+		wantErr         bool
+		wantDeadLetters int
+	}{{
+		name: "treated as success",
+		code: http.StatusConflict,
+	}, {
+		name:            "dead lettered",
+		code:            http.StatusBadRequest,
+		withDeadLetter:  true,
+		wantDeadLetters: 1,
+	}, {
+		// The dead letter policy of the retry subscription handles Pub/Sub topic dead letter
+		// sinks.
+		name:    "retried without dead letter sink",
+		code:    http.StatusBadRequest,
+		wantErr: true,
+	}, {
+		name:           "retried",
+		code:           http.StatusInternalServerError,
+		withDeadLetter: true,
+		wantErr:        true,
+	}}
+	for _, tc := range cases {
+		t.Run(tc.name, func(t *testing.T) {
+			reportertest.ResetDeliveryMetrics()
+			ctx := logtest.TestContextWithLogger(t)
+			targetHandler := &recordingHandler{t: t, codes: []int{tc.code}}
+			targetSvr := httptest.NewServer(targetHandler)
+			defer targetSvr.Close()
+			deadLetterHandler := &recordingHandler{t: t, codes: []int{http.StatusOK}}
+			deadLetterSvr := httptest.NewServer(deadLetterHandler)
+			defer deadLetterSvr.Close()
+
+			target := &config.Target{Address: targetSvr.URL, ResponsePolicy: policy}
+			if tc.withDeadLetter {
+				target.DeadLetterPolicy = &config.DeadLetterPolicy{Address: deadLetterSvr.URL, Retry: 5}
+			}
+			// The processor is the one of the retry queue, so retried events fail to be processed.
+			p, ctx := newTargetProcessor(ctx, t, target)
+
+			if err := p.Process(ctx, newSampleEvent()); (err != nil) != tc.wantErr {
+				t.Errorf("Process() error = %v, wantErr %v", err, tc.wantErr)
+			}
+			if got := len(deadLetterHandler.received()); got != tc.wantDeadLetters {
+				t.Errorf("dead letter sink calls got=%d, want=%d", got, tc.wantDeadLetters)
+			}
+		})
+	}
+}
+
+func TestRetryAfter(t *testing.T) {
+	reportertest.ResetDeliveryMetrics()
+	ctx := logtest.TestContextWithLogger(t)
+	targetSvr := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, req *http.Request) {
+		w.Header().Set("Retry-After", "30")
+		w.WriteHeader(http.StatusTooManyRequests)
+	}))
+	defer targetSvr.Close()
+
+	p, ctx := newTargetProcessor(ctx, t, &config.Target{Address: targetSvr.URL})
+	err := p.Process(ctx, newSampleEvent())
+	var ra interface{ RetryAfter() time.Duration }
+	if !errors.As(err, &ra) {
+		t.Fatalf("Process() error = %v, want an error with a retry delay", err)
+	}
+	if got, want := ra.RetryAfter(), 30*time.Second; got != want {
+		t.Errorf("RetryAfter() = %v, want %v", got, want)
+	}
+}
+
+func TestRedeliverAfter(t *testing.T) {
+	reportertest.ResetDeliveryMetrics()
+	ctx := logtest.TestContextWithLogger(t)
+	var received []string
+	targetSvr := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, req *http.Request) {
+		received = append(received, req.Header.Get("ce-"+eventutil.RedeliverAfterExtension))
+		w.Header().Set("Retry-After", "30")
+		w.WriteHeader(http.StatusTooManyRequests)
+	}))
+	defer targetSvr.Close()
+
+	psSrv, psClient, cancel := testPubsubClient(ctx, t, "test-project")
+	defer cancel()
+	if _, err := psClient.CreateTopic(ctx, "retry"); err != nil {
+		t.Fatal(err)
+	}
+	ps, err := cepubsub.New(ctx, cepubsub.WithClient(psClient), cepubsub.WithProjectID("test-project"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	retryClient, err := ceclient.New(ps)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	// The fanout sends the event to the retry topic with the requested delay, and ignores the
+	// delay set by the sender.
+	p, ctx := newTargetProcessor(ctx, t, &config.Target{Address: targetSvr.URL, RetryQueue: &config.Queue{Topic: "retry"}})
+	p.RetryOnFailure = true
+	p.DeliverRetryClient = retryClient
+	e := newSampleEvent()
+	eventutil.SetRedeliverAfter(e, time.Now().Add(time.Hour))
+	start := time.Now()
+	if err := p.Process(ctx, e); err != nil {
+		t.Fatalf("Process() error = %v", err)
+	}
+	msgs := psSrv.Messages()
+	if len(msgs) != 1 {
+		t.Fatalf("got %d retried events, want 1", len(msgs))
+	}
+	redeliverAfter, err := cetypes.ParseTime(msgs[0].Attributes["ce-"+eventutil.RedeliverAfterExtension])
+	if err != nil {
+		t.Fatalf("invalid redelivery time of the retried event: %v", err)
+	}
+	if min, max := start.Add(29*time.Second), time.Now().Add(31*time.Second); redeliverAfter.Before(min) || redeliverAfter.After(max) {
+		t.Errorf("redelivery time of the retried event = %v, want between %v and %v", redeliverAfter, min, max)
+	}
+
+	// The retry data plane does not deliver the event before its redelivery time.
+	p.RetryOnFailure = false
+	e = newSampleEvent()
+	eventutil.SetRedeliverAfter(e, time.Now().Add(time.Minute))
+	err = p.Process(ctx, e)
+	var ra interface{ RetryAfter() time.Duration }
+	if !errors.As(err, &ra) || ra.RetryAfter() <= 0 || ra.RetryAfter() > time.Minute {
+		t.Errorf("Process() error = %v, want an error with the remaining delay", err)
+	}
+	eventutil.SetRedeliverAfter(e, time.Now().Add(-time.Minute))
+	p.Process(ctx, e)
+
+	if diff := cmp.Diff([]string{"", ""}, received); diff != "" {
+		t.Errorf("unexpected redelivery times received by the subscriber (-want, +got) = %v", diff)
+	}
+}
@@ -29,6 +29,7 @@ import (
 	"github.com/google/knative-gcp/pkg/broker/config"
 	handlerctx "github.com/google/knative-gcp/pkg/broker/handler/context"
 	"github.com/google/knative-gcp/pkg/broker/handler/processors"
+	"github.com/google/knative-gcp/pkg/broker/handler/processors/deliver"
 	"github.com/google/knative-gcp/pkg/metrics"
 )
 
@@ -47,6 +48,10 @@ type Processor struct {
 
 	// Targets is the targets from config.
 	Targets config.ReadonlyTargets
+
+	// OrderedRetries is notified of the deliveries of the events retried in order. The notices
+	// of these deliveries are never fanned out.
+	OrderedRetries *deliver.OrderedRetries
 }
 
 var _ processors.Interface = (*Processor)(nil)
@@ -63,6 +68,9 @@ func (p *Processor) Process(ctx context.Context, event *event.Event) error {
 		logging.FromContext(ctx).Warn("broker no longer exist in the config", zap.Stringer("broker", bk))
 		return nil
 	}
+	if p.OrderedRetries.Delivered(broker, event) {
+		return nil
+	}
 
 	tc := make(chan *config.Target)
 	go func() {
@@ -28,6 +28,7 @@ import (
 
 	"github.com/google/knative-gcp/pkg/broker/config"
 	"github.com/google/knative-gcp/pkg/broker/config/memory"
+	"github.com/google/knative-gcp/pkg/broker/eventutil"
 	handlerctx "github.com/google/knative-gcp/pkg/broker/handler/context"
 	"github.com/google/knative-gcp/pkg/broker/handler/processors"
 )
@@ -113,6 +114,30 @@ func TestFanoutSuccess(t *testing.T) {
 	}
 }
 
+func TestFanoutSkipsOrderedRetryNotices(t *testing.T) {
+	ch := make(chan *event.Event, 4)
+	bk := config.TestOnlyBrokerKey("ns", "broker")
+	next := &processors.FakeProcessor{PrevEventsCh: ch}
+
+	p := &Processor{MaxConcurrency: 2, Targets: newTestTargets(bk, 4)}
+	p.WithNext(next)
+
+	e := event.New()
+	e.SetID("id")
+	e.SetSource("source")
+	e.SetType("type")
+	e.SetExtension(eventutil.OrderedRetryExtension, "token")
+
+	ctx := handlerctx.WithBrokerKey(context.Background(), bk)
+	if err := p.Process(ctx, &e); err != nil {
+		t.Errorf("unexpected error from processing: %v", err)
+	}
+	close(ch)
+	if len(ch) != 0 {
+		t.Errorf("fanout target number got=%d, want=0", len(ch))
+	}
+}
+
 func TestFanoutPartialFailure(t *testing.T) {
 	ch := make(chan *event.Event, 4)
 	ns, broker := "ns", "broker"
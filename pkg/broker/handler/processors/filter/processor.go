@@ -28,6 +28,7 @@ import (
 	kntracing "knative.dev/eventing/pkg/tracing"
 
 	"github.com/google/knative-gcp/pkg/broker/config"
+	"github.com/google/knative-gcp/pkg/broker/eventfilter"
 	handlerctx "github.com/google/knative-gcp/pkg/broker/handler/context"
 	"github.com/google/knative-gcp/pkg/broker/handler/processors"
 	"github.com/google/knative-gcp/pkg/tracing"
@@ -39,6 +40,10 @@ type Processor struct {
 
 	// Targets is the targets from config.
 	Targets config.ReadonlyTargets
+
+	// Filters holds the compiled filters of the targets. If nil, the filters are compiled for
+	// every event.
+	Filters *eventfilter.Cache
 }
 
 var _ processors.Interface = (*Processor)(nil)
@@ -64,11 +69,7 @@ func (p *Processor) Process(ctx context.Context, event *event.Event) error {
 	ctx, span := startSpan(ctx, trigger, event)
 	defer span.End()
 
-	if target.FilterAttributes == nil {
-		return p.Next().Process(ctx, event)
-	}
-
-	if PassFilter(ctx, target.FilterAttributes, event) {
+	if p.Filters.Filter(ctx, target, event) {
 		return p.Next().Process(ctx, event)
 	}
 	logging.FromContext(ctx).Debug("event does not pass filter for target", zap.Any("target", target))
@@ -92,46 +93,3 @@ func startSpan(ctx context.Context, trigger types.NamespacedName, event *event.E
 	}
 	return tracing.WithLogging(ctx, span), span
 }
-
-// PassFilter checks given event against attributes available in the attrs map to determine
-// if the event should pass or not.
-func PassFilter(ctx context.Context, attrs map[string]string, event *event.Event) bool {
-	// Set standard context attributes. The attributes available may not be
-	// exactly the same as the attributes defined in the current version of the
-	// CloudEvents spec.
-	ce := map[string]interface{}{
-		"specversion":     event.SpecVersion(),
-		"type":            event.Type(),
-		"source":          event.Source(),
-		"subject":         event.Subject(),
-		"id":              event.ID(),
-		"time":            event.Time().String(),
-		"schemaurl":       event.DataSchema(),
-		"datacontenttype": event.DataContentType(),
-		"datamediatype":   event.DataMediaType(),
-		// TODO: use data_base64 when SDK supports it.
-		"datacontentencoding": event.DeprecatedDataContentEncoding(),
-	}
-	ext := event.Extensions()
-	for k, v := range ext {
-		ce[k] = v
-	}
-
-	for k, v := range attrs {
-		var value interface{}
-		value, ok := ce[k]
-		// If the attribute does not exist in the event, return false.
-		if !ok {
-			logging.FromContext(ctx).Debug("Attribute not found", zap.String("attribute", k))
-			trace.FromContext(ctx).Annotatef(nil, "event missing filter attribute %q", k)
-			return false
-		}
-		// If the attribute is not set to any and is different than the one from the event, return false.
-		if v != "" && v != value {
-			logging.FromContext(ctx).Debug("Attribute had non-matching value", zap.String("attribute", k), zap.String("filter", v), zap.Any("received", value))
-			trace.FromContext(ctx).Annotatef(nil, "event attribute %q does not match filter value %q", k, v)
-			return false
-		}
-	}
-	return true
-}
@@ -29,6 +29,7 @@ import (
 
 	"github.com/google/knative-gcp/pkg/broker/config"
 	"github.com/google/knative-gcp/pkg/broker/config/memory"
+	"github.com/google/knative-gcp/pkg/broker/eventfilter"
 	handlerctx "github.com/google/knative-gcp/pkg/broker/handler/context"
 	"github.com/google/knative-gcp/pkg/broker/handler/processors"
 )
@@ -84,6 +85,7 @@ func TestFilterProcessor(t *testing.T) {
 		name       string
 		e          event.Event
 		filter     map[string]string
+		filters    []*config.Filter
 		shouldPass bool
 	}{{
 		name: "no filter pass",
@@ -278,13 +280,54 @@ func TestFilterProcessor(t *testing.T) {
 			"source":  "unknown",
 		},
 		shouldPass: false,
+	}, {
+		name: "subscriptions api filters pass",
+		e: func() event.Event {
+			e := event.New()
+			e.SetType("google.cloud.storage.object.v1.finalized")
+			e.SetSubject("objects/photo.jpg")
+			return e
+		}(),
+		filters: []*config.Filter{
+			{Prefix: map[string]string{"type": "google.cloud.storage.object.v1."}},
+			{Cesql: "subject LIKE '%.jpg'"},
+		},
+		shouldPass: true,
+	}, {
+		name: "subscriptions api filters not pass",
+		e: func() event.Event {
+			e := event.New()
+			e.SetType("google.cloud.storage.object.v1.finalized")
+			e.SetSubject("objects/photo.jpg")
+			return e
+		}(),
+		filters: []*config.Filter{
+			{Prefix: map[string]string{"type": "google.cloud.storage.object.v1."}},
+			{Not: &config.Filter{Suffix: map[string]string{"subject": ".jpg"}}},
+		},
+		shouldPass: false,
+	}, {
+		name: "attributes and subscriptions api filters not pass",
+		e: func() event.Event {
+			e := event.New()
+			e.SetType("google.cloud.storage.object.v1.finalized")
+			e.SetSource("foo")
+			return e
+		}(),
+		filter: map[string]string{
+			"source": "bar",
+		},
+		filters: []*config.Filter{
+			{Prefix: map[string]string{"type": "google.cloud.storage.object.v1."}},
+		},
+		shouldPass: false,
 	}}
 
 	for _, tc := range cases {
 		t.Run(tc.name, func(t *testing.T) {
-			ctx, testTargets := newTestTargets(tc.filter)
+			ctx, testTargets := newTestTargets(tc.filter, tc.filters...)
 			next := &processors.FakeProcessor{}
-			p := &Processor{Targets: testTargets}
+			p := &Processor{Targets: testTargets, Filters: eventfilter.NewCache()}
 			p.WithNext(next)
 			ch := make(chan *event.Event, 1)
 			next.PrevEventsCh = ch
@@ -311,13 +354,14 @@ func TestFilterProcessor(t *testing.T) {
 	}
 }
 
-func newTestTargets(filter map[string]string) (context.Context, config.Targets) {
+func newTestTargets(filter map[string]string, filters ...*config.Filter) (context.Context, config.Targets) {
 	testTarget := &config.Target{
 		Name:             "target",
 		CellTenantType:   config.CellTenantType_BROKER,
 		CellTenantName:   "broker",
 		Namespace:        "ns",
 		FilterAttributes: filter,
+		Filters:          filters,
 	}
 	testTargets := memory.NewEmptyTargets()
 	testTargets.MutateCellTenant(testTarget.Key().ParentKey(), func(bm config.CellTenantMutation) {
@@ -0,0 +1,70 @@
+/*
+Copyright 2021 Google LLC
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package replay
+
+import (
+	"context"
+	"time"
+
+	"github.com/cloudevents/sdk-go/v2/event"
+	"github.com/google/knative-gcp/pkg/logging"
+	"go.uber.org/zap"
+
+	"github.com/google/knative-gcp/pkg/broker/config"
+	"github.com/google/knative-gcp/pkg/broker/eventutil"
+	handlerctx "github.com/google/knative-gcp/pkg/broker/handler/context"
+	"github.com/google/knative-gcp/pkg/broker/handler/processors"
+)
+
+// Processor passes the events replayed from the replay subscription of a target to the next
+// processor.
+type Processor struct {
+	processors.BaseProcessor
+
+	// Targets is the targets from config.
+	Targets config.ReadonlyTargets
+}
+
+var _ processors.Interface = (*Processor)(nil)
+
+// Process passes the event to the next processor if it was published before the replay of the
+// target in the context was applied. The later events are delivered by the decoupling
+// subscription, so they are simply acked.
+func (p *Processor) Process(ctx context.Context, event *event.Event) error {
+	tk, err := handlerctx.GetTargetKey(ctx)
+	if err != nil {
+		return err
+	}
+	publishTime, err := handlerctx.GetPublishTime(ctx)
+	if err != nil {
+		return err
+	}
+	target, ok := p.Targets.GetTargetByKey(tk)
+	if !ok || target.Replay == nil {
+		// If the target or its replay no longer exists, then there is nothing to process.
+		logging.FromContext(ctx).Warn("target replay no longer exist in the config", zap.Stringer("target", tk))
+		return nil
+	}
+	// The notices of the events retried in order are only meant for the fanout.
+	if _, ok := event.Extensions()[eventutil.OrderedRetryExtension]; ok {
+		return nil
+	}
+	if !publishTime.Before(time.Unix(0, target.Replay.UntilUnixNanos)) {
+		return nil
+	}
+	return p.Next().Process(ctx, event)
+}
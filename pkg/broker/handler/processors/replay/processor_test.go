@@ -0,0 +1,109 @@
+/*
+Copyright 2021 Google LLC
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package replay
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/cloudevents/sdk-go/v2/event"
+
+	"github.com/google/knative-gcp/pkg/broker/config"
+	"github.com/google/knative-gcp/pkg/broker/config/memory"
+	"github.com/google/knative-gcp/pkg/broker/eventutil"
+	handlerctx "github.com/google/knative-gcp/pkg/broker/handler/context"
+	"github.com/google/knative-gcp/pkg/broker/handler/processors"
+)
+
+func TestInvalidContext(t *testing.T) {
+	p := &Processor{}
+	e := event.New()
+	err := p.Process(context.Background(), &e)
+	if err != handlerctx.ErrTargetKeyNotPresent {
+		t.Errorf("Process error got=%v, want=%v", err, handlerctx.ErrTargetKeyNotPresent)
+	}
+	ctx := handlerctx.WithTargetKey(context.Background(), (&config.Target{Name: "target"}).Key())
+	err = p.Process(ctx, &e)
+	if err != handlerctx.ErrPublishTimeNotPresent {
+		t.Errorf("Process error got=%v, want=%v", err, handlerctx.ErrPublishTimeNotPresent)
+	}
+}
+
+func TestReplayProcessor(t *testing.T) {
+	until := time.Date(2021, 5, 2, 0, 0, 0, 0, time.UTC)
+	cases := []struct {
+		name        string
+		replay      *config.Replay
+		publishTime time.Time
+		notice      bool
+		shouldPass  bool
+	}{{
+		name:        "published before the replay",
+		replay:      &config.Replay{UntilUnixNanos: until.UnixNano()},
+		publishTime: until.Add(-time.Hour),
+		shouldPass:  true,
+	}, {
+		name:        "published when the replay was applied",
+		replay:      &config.Replay{UntilUnixNanos: until.UnixNano()},
+		publishTime: until,
+	}, {
+		name:        "published after the replay",
+		replay:      &config.Replay{UntilUnixNanos: until.UnixNano()},
+		publishTime: until.Add(time.Hour),
+	}, {
+		name:        "ordered retry notice",
+		replay:      &config.Replay{UntilUnixNanos: until.UnixNano()},
+		publishTime: until.Add(-time.Hour),
+		notice:      true,
+	}, {
+		name:        "no replay",
+		publishTime: until.Add(-time.Hour),
+	}}
+	for _, tc := range cases {
+		t.Run(tc.name, func(t *testing.T) {
+			target := &config.Target{
+				Name:           "target",
+				CellTenantType: config.CellTenantType_BROKER,
+				CellTenantName: "broker",
+				Namespace:      "ns",
+				Replay:         tc.replay,
+			}
+			targets := memory.NewEmptyTargets()
+			targets.MutateCellTenant(target.Key().ParentKey(), func(m config.CellTenantMutation) {
+				m.UpsertTargets(target)
+			})
+			ctx := handlerctx.WithTargetKey(context.Background(), target.Key())
+			ctx = handlerctx.WithPublishTime(ctx, tc.publishTime)
+
+			e := event.New()
+			e.SetID("id")
+			if tc.notice {
+				e.SetExtension(eventutil.OrderedRetryExtension, "token")
+			}
+			next := &processors.FakeProcessor{PrevEventsCh: make(chan *event.Event, 1)}
+			p := &Processor{Targets: targets}
+			p.WithNext(next)
+			if err := p.Process(ctx, &e); err != nil {
+				t.Errorf("unexpected error from processing: %v", err)
+			}
+			if got := len(next.PrevEventsCh) > 0; got != tc.shouldPass {
+				t.Errorf("event passed got=%t, want=%t", got, tc.shouldPass)
+			}
+		})
+	}
+}
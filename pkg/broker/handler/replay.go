@@ -0,0 +1,165 @@
+/*
+Copyright 2021 Google LLC
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package handler
+
+import (
+	"context"
+	"sync"
+
+	"github.com/google/knative-gcp/pkg/logging"
+	"go.uber.org/zap"
+
+	"github.com/google/knative-gcp/pkg/broker/config"
+	handlerctx "github.com/google/knative-gcp/pkg/broker/handler/context"
+	"github.com/google/knative-gcp/pkg/broker/handler/processors"
+	"github.com/google/knative-gcp/pkg/broker/handler/processors/deliver"
+	"github.com/google/knative-gcp/pkg/broker/handler/processors/filter"
+	"github.com/google/knative-gcp/pkg/broker/handler/processors/replay"
+	"github.com/google/knative-gcp/pkg/metrics"
+)
+
+type replayHandlerCache struct {
+	Handler
+	t *config.Target
+}
+
+// If somehow the existing handler's setting has deviated from the current target config,
+// we need to renew the handler.
+func (hc *replayHandlerCache) shouldRenew(t *config.Target) bool {
+	if !hc.IsAlive() {
+		return true
+	}
+	if t == nil || t.Replay == nil || t.Replay.Queue == nil {
+		return true
+	}
+	return t.Replay.Queue.Topic != hc.t.Replay.Queue.Topic ||
+		t.Replay.Queue.Subscription != hc.t.Replay.Queue.Subscription
+}
+
+// syncReplays syncs the handlers of the replay subscriptions of the targets of the brokers
+// handled by this replica. The replayed events are only delivered to their target, and are sent
+// to its retry topic if the delivery fails, like the events of the decoupling subscription.
+func (p *FanoutPool) syncReplays(ctx context.Context) {
+	p.replays.Range(func(key config.TargetKey, value *replayHandlerCache) bool {
+		t, ok := p.targets.GetTargetByKey(&key)
+		if !ok || t.Replay == nil || !p.options.Shards.Owns(key.ParentKey().String()) {
+			value.Stop()
+			p.replays.Delete(key)
+		}
+		return true
+	})
+
+	p.targets.RangeAllTargets(func(t *config.Target) bool {
+		if t.Replay == nil || !p.options.Shards.Owns(t.Key().ParentKey().String()) {
+			return true
+		}
+		if value, ok := p.replays.Load(*t.Key()); ok {
+			// Skip if we don't need to renew the handler.
+			if !value.shouldRenew(t) {
+				return true
+			}
+			// Stop and clean up the old handler before we start a new one.
+			value.Stop()
+			p.replays.Delete(*t.Key())
+		}
+
+		// Don't start the handler if the target or its replay subscription is not ready.
+		if t.State != config.State_READY || t.Replay.Queue == nil || t.Replay.Queue.State != config.State_READY {
+			return true
+		}
+
+		sub := p.pubsubClient.Subscription(t.Replay.Queue.Subscription)
+		sub.ReceiveSettings = p.options.PubsubReceiveSettings
+
+		h := NewHandler(
+			sub,
+			processors.ChainProcessors(
+				&replay.Processor{Targets: p.targets},
+				&filter.Processor{Targets: p.targets, Filters: p.filters},
+				&deliver.Processor{
+					DeliverClient:       p.deliverClient,
+					Targets:             p.targets,
+					RetryOnFailure:      true,
+					DeliverRetryClient:  p.deliverRetryClient,
+					DeliverTimeout:      p.options.DeliveryTimeout,
+					StatsReporter:       p.statsReporter,
+					Breakers:            p.breakers,
+					Limiters:            p.limiters,
+					RetryLimitedTargets: p.options.Shards != nil,
+					Authenticator:       p.authenticator,
+					ClaimChecks:         p.options.ClaimCheckStore,
+					OrderedRetries:      p.orderedRetries,
+				},
+			),
+			p.options.TimeoutPerEvent,
+		)
+		hc := &replayHandlerCache{
+			Handler: *h,
+			t:       t,
+		}
+
+		ctx, err := metrics.AddTargetTags(ctx, t)
+		if err != nil {
+			logging.FromContext(ctx).Error("failed to add target tags to context", zap.Error(err))
+		}
+
+		ctx = handlerctx.WithBrokerKey(ctx, t.Key().ParentKey())
+		ctx = handlerctx.WithTargetKey(ctx, t.Key())
+		hc.Start(ctx, func(err error) {
+			if err != nil {
+				logging.FromContext(ctx).Error("replay handler for trigger has stopped with error", zap.Stringer("trigger", t.Key()), zap.Error(err))
+			} else {
+				logging.FromContext(ctx).Info("replay handler for trigger has stopped", zap.Stringer("trigger", t.Key()))
+			}
+		})
+
+		p.replays.Store(*t.Key(), hc)
+		return true
+	})
+}
+
+// syncMapReplayKey is a typed version of sync.Map.
+type syncMapReplayKey struct {
+	m sync.Map
+}
+
+func (m *syncMapReplayKey) Store(k config.TargetKey, v *replayHandlerCache) {
+	m.m.Store(k, v)
+}
+
+func (m *syncMapReplayKey) Load(k config.TargetKey) (*replayHandlerCache, bool) {
+	v, ok := m.m.Load(k)
+	if v == nil {
+		return nil, ok
+	}
+	return v.(*replayHandlerCache), ok
+}
+
+func (m *syncMapReplayKey) Delete(k config.TargetKey) {
+	m.m.Delete(k)
+}
+
+func (m *syncMapReplayKey) Range(f func(key config.TargetKey, value *replayHandlerCache) bool) {
+	wrapped := func(key interface{}, value interface{}) bool {
+		var wrappedValue *replayHandlerCache
+		if value != nil {
+			wrappedValue = value.(*replayHandlerCache)
+		}
+		return f(key.(config.TargetKey), wrappedValue)
+	}
+	m.m.Range(wrapped)
+}
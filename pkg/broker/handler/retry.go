@@ -27,11 +27,14 @@ import (
 	"cloud.google.com/go/pubsub"
 
 	"github.com/google/knative-gcp/pkg/broker/config"
+	"github.com/google/knative-gcp/pkg/broker/eventfilter"
 	handlerctx "github.com/google/knative-gcp/pkg/broker/handler/context"
 	"github.com/google/knative-gcp/pkg/broker/handler/processors"
 	"github.com/google/knative-gcp/pkg/broker/handler/processors/deliver"
 	"github.com/google/knative-gcp/pkg/broker/handler/processors/filter"
+	"github.com/google/knative-gcp/pkg/broker/shard"
 	"github.com/google/knative-gcp/pkg/metrics"
+	"github.com/google/knative-gcp/pkg/utils/deadletter"
 )
 
 // RetryPool is the sync pool for retry handlers.
@@ -48,6 +51,18 @@ type RetryPool struct {
 	// And we can set target address dynamically.
 	deliverClient *http.Client
 	statsReporter *metrics.DeliveryReporter
+	// filters holds the compiled trigger filters shared by all handlers.
+	filters *eventfilter.Cache
+	// attempts counts the delivery attempts of events to dead letter them.
+	attempts *deadletter.Tracker
+	// breakers holds the circuit breakers of the targets shared by all handlers.
+	breakers *deliver.CircuitBreakers
+	// limiters enforce the delivery limits of the targets shared by all handlers.
+	limiters *deliver.Limiters
+	// authenticator provides the credentials of the deliveries shared by all handlers.
+	authenticator *deliver.Authenticator
+	// orderedRetries notifies the fanout of the deliveries of the events retried in order.
+	orderedRetries *deliver.OrderedRetries
 }
 
 type retryHandlerCache struct {
@@ -86,12 +101,18 @@ func NewRetryPool(
 	}
 
 	p := &RetryPool{
-		targets:       targets,
-		options:       options,
-		pool:          &syncMapTargetKey{},
-		pubsubClient:  pubsubClient,
-		deliverClient: deliverClient,
-		statsReporter: statsReporter,
+		targets:        targets,
+		options:        options,
+		pool:           &syncMapTargetKey{},
+		pubsubClient:   pubsubClient,
+		deliverClient:  deliverClient,
+		statsReporter:  statsReporter,
+		filters:        eventfilter.NewCache(),
+		attempts:       deadletter.NewTracker(),
+		breakers:       options.newCircuitBreakers(statsReporter),
+		limiters:       deliver.NewLimiters(),
+		authenticator:  deliver.NewAuthenticator(deliver.DefaultTokensPath),
+		orderedRetries: deliver.NewOrderedRetries(pubsubClient),
 	}
 	return p, nil
 }
@@ -105,14 +126,22 @@ func (p *RetryPool) SyncOnce(ctx context.Context) error {
 
 	p.pool.Range(func(key config.TargetKey, value *retryHandlerCache) bool {
 		// Each target represents a trigger.
-		if _, ok := p.targets.GetTargetByKey(&key); !ok {
+		if _, ok := p.targets.GetTargetByKey(&key); !ok || !p.options.Shards.Owns(key.String()) {
 			value.Stop()
 			p.pool.Delete(key)
 		}
 		return true
 	})
+	p.filters.Prune(p.targets)
+	p.breakers.Prune(p.targets)
+	p.limiters.Prune(p.targets)
+	p.orderedRetries.Prune(p.targets)
 
 	p.targets.RangeAllTargets(func(t *config.Target) bool {
+		// Skip the triggers handled by other replicas.
+		if !p.options.Shards.Owns(t.Key().String()) {
+			return true
+		}
 		if value, ok := p.pool.Load(*t.Key()); ok {
 			// Skip if we don't need to renew the handler.
 			if !value.shouldRenew(t) {
@@ -135,11 +164,17 @@ func (p *RetryPool) SyncOnce(ctx context.Context) error {
 		h := NewHandler(
 			sub,
 			processors.ChainProcessors(
-				&filter.Processor{Targets: p.targets},
+				&filter.Processor{Targets: p.targets, Filters: p.filters},
 				&deliver.Processor{
-					DeliverClient: p.deliverClient,
-					Targets:       p.targets,
-					StatsReporter: p.statsReporter,
+					DeliverClient:  p.deliverClient,
+					Targets:        p.targets,
+					StatsReporter:  p.statsReporter,
+					Attempts:       p.attempts,
+					Breakers:       p.breakers,
+					Limiters:       p.limiters,
+					Authenticator:  p.authenticator,
+					ClaimChecks:    p.options.ClaimCheckStore,
+					OrderedRetries: p.orderedRetries,
 				},
 			),
 			p.options.TimeoutPerEvent,
@@ -174,6 +209,21 @@ func (p *RetryPool) SyncOnce(ctx context.Context) error {
 	return nil
 }
 
+// Targets returns the targets handled by the pool.
+func (p *RetryPool) Targets() config.ReadonlyTargets {
+	return p.targets
+}
+
+// Assignment returns the triggers handled by this replica of the pool.
+func (p *RetryPool) Assignment() shard.Assignment {
+	var keys []string
+	p.targets.RangeAllTargets(func(t *config.Target) bool {
+		keys = append(keys, t.Key().String())
+		return true
+	})
+	return p.options.Shards.Assignment(keys)
+}
+
 // syncMapTargetKey is a typed version of sync.Map.
 type syncMapTargetKey struct {
 	m sync.Map
@@ -0,0 +1,280 @@
+/*
+Copyright 2021 Google LLC
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package ingress
+
+import (
+	"context"
+	"crypto/sha256"
+	"encoding/base64"
+	"encoding/json"
+	"errors"
+	"fmt"
+	nethttp "net/http"
+	"strings"
+	"sync"
+	"time"
+
+	"google.golang.org/api/idtoken"
+	authv1 "k8s.io/api/authentication/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	authv1client "k8s.io/client-go/kubernetes/typed/authentication/v1"
+	kubeclient "knative.dev/pkg/client/injection/kube/client"
+
+	"github.com/google/knative-gcp/pkg/broker/config"
+)
+
+const (
+	// tokenCacheTTL is the longest time a verified token is cached, so that the tokens of the
+	// callers are not verified on each event.
+	tokenCacheTTL = time.Minute
+	// rejectedTokenCacheTTL is how long a rejected token is cached, so that a caller retrying with
+	// an invalid token doesn't have it verified on each event.
+	rejectedTokenCacheTTL = 10 * time.Second
+	// maxCachedTokens bounds the number of cached tokens.
+	maxCachedTokens = 1000
+
+	// maxFailedReviews bounds the new tokens of a broker that fail verification in a
+	// failedReviewsWindow. Once it is reached, the new tokens of the broker are rejected without
+	// being verified until the window ends, so that callers sending random tokens can't flood the
+	// TokenReview API.
+	maxFailedReviews    = 100
+	failedReviewsWindow = time.Minute
+)
+
+var (
+	// ErrUnauthenticated is returned when the caller did not provide a valid token.
+	ErrUnauthenticated = errors.New("caller is not authenticated")
+	// ErrForbidden is returned when the caller is not allowed to send events to the broker.
+	ErrForbidden = errors.New("caller is not allowed to send events to the broker")
+
+	// errReviewFailed is returned when a token could not be verified, rather than was rejected.
+	// Such tokens are not cached.
+	errReviewFailed = errors.New("failed to review the token")
+)
+
+// googleIssuers are the issuers of Google-signed ID tokens. Tokens of other issuers are verified
+// by the Kubernetes API server.
+var googleIssuers = map[string]bool{
+	"https://accounts.google.com": true,
+	"accounts.google.com":         true,
+}
+
+// Authenticator authenticates the callers of the brokers with an ingress authentication, and
+// checks them against the allow list of the broker.
+type Authenticator struct {
+	targets      config.ReadonlyTargets
+	tokenReviews authv1client.TokenReviewInterface
+	// validateIDToken is replaced in tests.
+	validateIDToken func(ctx context.Context, token, audience string) (*idtoken.Payload, error)
+	// now is replaced in tests.
+	now func() time.Time
+
+	mux sync.Mutex
+	// tokens holds the identities of the verified tokens, and the errors of the rejected ones, by
+	// audience and token hash.
+	tokens map[cachedTokenKey]cachedIdentity
+	// failedReviews holds the tokens that failed verification in the current window by audience.
+	failedReviews map[string]*failedReviews
+}
+
+type cachedTokenKey struct {
+	audience string
+	token    [sha256.Size]byte
+}
+
+type cachedIdentity struct {
+	identity string
+	err      error
+	expires  time.Time
+}
+
+type failedReviews struct {
+	start time.Time
+	count int
+}
+
+// NewAuthenticator creates an Authenticator of the brokers in targets. The Kubernetes service
+// account tokens are verified with the TokenReview API.
+func NewAuthenticator(ctx context.Context, targets config.ReadonlyTargets) *Authenticator {
+	return &Authenticator{
+		targets:         targets,
+		tokenReviews:    kubeclient.Get(ctx).AuthenticationV1().TokenReviews(),
+		validateIDToken: idtoken.Validate,
+		now:             time.Now,
+		tokens:          make(map[cachedTokenKey]cachedIdentity),
+		failedReviews:   make(map[string]*failedReviews),
+	}
+}
+
+// authenticate checks that the caller of the request is allowed to send events to the broker. It
+// returns an error wrapping ErrUnauthenticated or ErrForbidden otherwise. Brokers without an
+// ingress authentication accept all the callers, as does a nil Authenticator.
+func (a *Authenticator) authenticate(ctx context.Context, broker *config.CellTenantKey, request *nethttp.Request) error {
+	if a == nil {
+		return nil
+	}
+	b, ok := a.targets.GetCellTenantByKey(broker)
+	if !ok || b.IngressAuth == nil {
+		return nil
+	}
+	token := strings.TrimPrefix(request.Header.Get("Authorization"), "Bearer ")
+	if token == "" || token == request.Header.Get("Authorization") {
+		return fmt.Errorf("%w: missing bearer token", ErrUnauthenticated)
+	}
+	if b.IngressAuth.Audience == "" {
+		// The tokens would be accepted whatever their audience, e.g. while the broker has no
+		// address.
+		return fmt.Errorf("%w: the broker has no audience", ErrUnauthenticated)
+	}
+	identity, err := a.identity(ctx, token, b.IngressAuth.Audience)
+	if err != nil {
+		return fmt.Errorf("%w: %v", ErrUnauthenticated, err)
+	}
+	for _, allowed := range b.IngressAuth.AllowedIdentities {
+		if identity == allowed {
+			return nil
+		}
+	}
+	return fmt.Errorf("%w: %s", ErrForbidden, identity)
+}
+
+// identity verifies the token and returns the identity of its caller. Both the verified and the
+// rejected tokens are cached.
+func (a *Authenticator) identity(ctx context.Context, token, audience string) (string, error) {
+	key := cachedTokenKey{audience: audience, token: sha256.Sum256([]byte(token))}
+	now := a.now()
+	a.mux.Lock()
+	cached, ok := a.tokens[key]
+	throttled := a.throttled(audience, now)
+	a.mux.Unlock()
+	if ok && now.Before(cached.expires) {
+		return cached.identity, cached.err
+	}
+	if throttled {
+		return "", errors.New("too many invalid tokens for the broker")
+	}
+
+	claims, err := parseClaims(token)
+	if err != nil {
+		return "", err
+	}
+	var identity string
+	if googleIssuers[claims.Issuer] {
+		identity, err = a.googleIdentity(ctx, token, audience)
+	} else {
+		identity, err = a.kubernetesIdentity(ctx, token, audience)
+	}
+	if errors.Is(err, errReviewFailed) {
+		return "", err
+	}
+
+	expires := now.Add(tokenCacheTTL)
+	if err != nil {
+		expires = now.Add(rejectedTokenCacheTTL)
+	}
+	if exp := time.Unix(claims.Expires, 0); claims.Expires != 0 && exp.Before(expires) {
+		expires = exp
+	}
+	a.mux.Lock()
+	defer a.mux.Unlock()
+	if err != nil {
+		a.failedReviews[audience].count++
+	}
+	if len(a.tokens) >= maxCachedTokens {
+		for k, v := range a.tokens {
+			if !now.Before(v.expires) {
+				delete(a.tokens, k)
+			}
+		}
+		if len(a.tokens) >= maxCachedTokens {
+			a.tokens = make(map[cachedTokenKey]cachedIdentity)
+		}
+	}
+	a.tokens[key] = cachedIdentity{identity: identity, err: err, expires: expires}
+	return identity, err
+}
+
+// throttled returns whether maxFailedReviews new tokens of the audience failed verification in the
+// current window, starting a new window if it ended. a.mux must be held.
+func (a *Authenticator) throttled(audience string, now time.Time) bool {
+	failed, ok := a.failedReviews[audience]
+	if !ok || !now.Before(failed.start.Add(failedReviewsWindow)) {
+		failed = &failedReviews{start: now}
+		a.failedReviews[audience] = failed
+	}
+	return failed.count >= maxFailedReviews
+}
+
+// googleIdentity verifies a Google-signed ID token and returns the email of its service account.
+func (a *Authenticator) googleIdentity(ctx context.Context, token, audience string) (string, error) {
+	payload, err := a.validateIDToken(ctx, token, audience)
+	if err != nil {
+		return "", err
+	}
+	email, _ := payload.Claims["email"].(string)
+	if verified, _ := payload.Claims["email_verified"].(bool); email == "" || !verified {
+		return "", errors.New("ID token without a verified email")
+	}
+	return email, nil
+}
+
+// kubernetesIdentity verifies a Kubernetes service account token and returns the user name of its
+// service account. The token must be valid for the audience of the broker, which is only known once
+// the authenticator reports it among the audiences of the token: an authenticator that isn't
+// audience-aware accepts tokens minted for any audience, including the API server.
+func (a *Authenticator) kubernetesIdentity(ctx context.Context, token, audience string) (string, error) {
+	review, err := a.tokenReviews.Create(ctx, &authv1.TokenReview{
+		Spec: authv1.TokenReviewSpec{
+			Token:     token,
+			Audiences: []string{audience},
+		},
+	}, metav1.CreateOptions{})
+	if err != nil {
+		return "", fmt.Errorf("%w: %v", errReviewFailed, err)
+	}
+	if !review.Status.Authenticated {
+		return "", fmt.Errorf("token rejected: %s", review.Status.Error)
+	}
+	for _, aud := range review.Status.Audiences {
+		if aud == audience {
+			return review.Status.User.Username, nil
+		}
+	}
+	return "", fmt.Errorf("token not valid for the audience %q", audience)
+}
+
+// tokenClaims are the claims read from a token before it is verified, to choose how to verify it.
+type tokenClaims struct {
+	Issuer  string `json:"iss"`
+	Expires int64  `json:"exp"`
+}
+
+func parseClaims(token string) (*tokenClaims, error) {
+	parts := strings.Split(token, ".")
+	if len(parts) != 3 {
+		return nil, errors.New("malformed token")
+	}
+	payload, err := base64.RawURLEncoding.DecodeString(strings.TrimRight(parts[1], "="))
+	if err != nil {
+		return nil, fmt.Errorf("malformed token: %v", err)
+	}
+	var claims tokenClaims
+	if err := json.Unmarshal(payload, &claims); err != nil {
+		return nil, fmt.Errorf("malformed token: %v", err)
+	}
+	return &claims, nil
+}
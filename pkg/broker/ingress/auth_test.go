@@ -0,0 +1,395 @@
+/*
+Copyright 2021 Google LLC
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package ingress
+
+import (
+	"context"
+	"encoding/base64"
+	"encoding/json"
+	"errors"
+	nethttp "net/http"
+	"net/http/httptest"
+	"strconv"
+	"strings"
+	"testing"
+	"time"
+
+	cev2 "github.com/cloudevents/sdk-go/v2"
+	"github.com/cloudevents/sdk-go/v2/protocol"
+	"google.golang.org/api/idtoken"
+	authv1 "k8s.io/api/authentication/v1"
+	"k8s.io/apimachinery/pkg/runtime"
+	"k8s.io/client-go/kubernetes/fake"
+	clientgotesting "k8s.io/client-go/testing"
+	logtest "knative.dev/pkg/logging/testing"
+	"knative.dev/pkg/metrics/metricskey"
+	"knative.dev/pkg/metrics/metricstest"
+
+	"github.com/google/knative-gcp/pkg/broker/config"
+	"github.com/google/knative-gcp/pkg/broker/config/memory"
+)
+
+const (
+	authAudience  = "http://broker.example.com/ns1/broker1"
+	allowedSA     = "system:serviceaccount:ns1:sender"
+	allowedGoogle = "sender@project.iam.gserviceaccount.com"
+)
+
+// newToken returns an unsigned JWT with the given issuer, and the identity of its caller in place of
+// the signature. The fake verifiers only look at the issuer and the signature.
+func newToken(t testing.TB, issuer, identity string) string {
+	claims, err := json.Marshal(map[string]interface{}{
+		"iss": issuer,
+		"exp": time.Now().Add(time.Hour).Unix(),
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	enc := base64.RawURLEncoding
+	return enc.EncodeToString([]byte(`{"alg":"RS256"}`)) + "." + enc.EncodeToString(claims) + "." + enc.EncodeToString([]byte(identity))
+}
+
+// newTestAuthenticator creates an Authenticator of a broker allowing allowedSA and allowedGoogle.
+// The Kubernetes tokens are authenticated as the service account in their signature, and the
+// Google ID tokens as the email in their signature. It returns the number of token reviews.
+func newTestAuthenticator() (*Authenticator, *int) {
+	targets := memory.NewTargets(&config.TargetsConfig{
+		CellTenants: map[string]*config.CellTenant{
+			"ns1/broker1": {
+				Type:      config.CellTenantType_BROKER,
+				Name:      "broker1",
+				Namespace: "ns1",
+				IngressAuth: &config.IngressAuth{
+					AllowedIdentities: []string{allowedSA, allowedGoogle},
+					Audience:          authAudience,
+				},
+			},
+			"ns2/broker2": {
+				Type:      config.CellTenantType_BROKER,
+				Name:      "broker2",
+				Namespace: "ns2",
+			},
+			"ns3/broker3": {
+				Type:      config.CellTenantType_BROKER,
+				Name:      "broker3",
+				Namespace: "ns3",
+				// The broker has no address yet.
+				IngressAuth: &config.IngressAuth{
+					AllowedIdentities: []string{allowedSA, allowedGoogle},
+				},
+			},
+		},
+	})
+	reviews := 0
+	client := fake.NewSimpleClientset()
+	client.PrependReactor("create", "tokenreviews", func(action clientgotesting.Action) (bool, runtime.Object, error) {
+		reviews++
+		review := action.(clientgotesting.CreateAction).GetObject().(*authv1.TokenReview)
+		claims, err := parseClaims(review.Spec.Token)
+		if err != nil {
+			return true, nil, err
+		}
+		if len(review.Spec.Audiences) != 1 || review.Spec.Audiences[0] != authAudience {
+			review.Status = authv1.TokenReviewStatus{Error: "invalid audience"}
+			return true, review, nil
+		}
+		switch claims.Issuer {
+		case "kubernetes":
+			review.Status = authv1.TokenReviewStatus{
+				Authenticated: true,
+				User:          authv1.UserInfo{Username: "system:serviceaccount:" + identity(review.Spec.Token)},
+				Audiences:     review.Spec.Audiences,
+			}
+		case "kubernetes-audience-unaware":
+			// An authenticator that isn't audience-aware authenticates the token without
+			// reporting its audiences.
+			review.Status = authv1.TokenReviewStatus{
+				Authenticated: true,
+				User:          authv1.UserInfo{Username: "system:serviceaccount:" + identity(review.Spec.Token)},
+			}
+		default:
+			review.Status = authv1.TokenReviewStatus{Error: "invalid issuer"}
+		}
+		return true, review, nil
+	})
+	return &Authenticator{
+		targets:      targets,
+		tokenReviews: client.AuthenticationV1().TokenReviews(),
+		validateIDToken: func(_ context.Context, token, audience string) (*idtoken.Payload, error) {
+			// Like idtoken.Validate, the audience is not checked if it is empty.
+			if audience != "" && audience != authAudience {
+				return nil, errors.New("invalid audience")
+			}
+			if identity(token) == "invalid" {
+				return nil, errors.New("invalid signature")
+			}
+			return &idtoken.Payload{Claims: map[string]interface{}{"email": identity(token), "email_verified": true}}, nil
+		},
+		now:           time.Now,
+		tokens:        make(map[cachedTokenKey]cachedIdentity),
+		failedReviews: make(map[string]*failedReviews),
+	}, &reviews
+}
+
+// identity returns the identity of the caller of a token created by newToken.
+func identity(token string) string {
+	b, _ := base64.RawURLEncoding.DecodeString(strings.SplitN(token, ".", 3)[2])
+	return string(b)
+}
+
+func TestAuthenticate(t *testing.T) {
+	cases := []struct {
+		name          string
+		broker        string
+		authorization func(t testing.TB) string
+		wantErr       error
+	}{{
+		name:   "broker without authentication",
+		broker: "/ns2/broker2",
+	}, {
+		name:    "missing token",
+		broker:  "/ns1/broker1",
+		wantErr: ErrUnauthenticated,
+	}, {
+		name:          "not a bearer token",
+		broker:        "/ns1/broker1",
+		authorization: func(testing.TB) string { return "Basic dXNlcjpwYXNz" },
+		wantErr:       ErrUnauthenticated,
+	}, {
+		name:          "malformed token",
+		broker:        "/ns1/broker1",
+		authorization: func(testing.TB) string { return "Bearer token" },
+		wantErr:       ErrUnauthenticated,
+	}, {
+		name:   "allowed kubernetes service account",
+		broker: "/ns1/broker1",
+		authorization: func(t testing.TB) string {
+			return "Bearer " + newToken(t, "kubernetes", "ns1:sender")
+		},
+	}, {
+		name:   "kubernetes service account not allowed",
+		broker: "/ns1/broker1",
+		authorization: func(t testing.TB) string {
+			return "Bearer " + newToken(t, "kubernetes", "ns1:other")
+		},
+		wantErr: ErrForbidden,
+	}, {
+		name:   "kubernetes token rejected",
+		broker: "/ns1/broker1",
+		authorization: func(t testing.TB) string {
+			return "Bearer " + newToken(t, "other", "ns1:sender")
+		},
+		wantErr: ErrUnauthenticated,
+	}, {
+		name:   "kubernetes token without the broker audience",
+		broker: "/ns1/broker1",
+		authorization: func(t testing.TB) string {
+			return "Bearer " + newToken(t, "kubernetes-audience-unaware", "ns1:sender")
+		},
+		wantErr: ErrUnauthenticated,
+	}, {
+		name:   "allowed google service account",
+		broker: "/ns1/broker1",
+		authorization: func(t testing.TB) string {
+			return "Bearer " + newToken(t, "https://accounts.google.com", allowedGoogle)
+		},
+	}, {
+		name:   "broker without audience",
+		broker: "/ns3/broker3",
+		authorization: func(t testing.TB) string {
+			return "Bearer " + newToken(t, "https://accounts.google.com", allowedGoogle)
+		},
+		wantErr: ErrUnauthenticated,
+	}, {
+		name:   "google service account not allowed",
+		broker: "/ns1/broker1",
+		authorization: func(t testing.TB) string {
+			return "Bearer " + newToken(t, "https://accounts.google.com", "other@project.iam.gserviceaccount.com")
+		},
+		wantErr: ErrForbidden,
+	}, {
+		name:   "invalid google ID token",
+		broker: "/ns1/broker1",
+		authorization: func(t testing.TB) string {
+			return "Bearer " + newToken(t, "accounts.google.com", "invalid")
+		},
+		wantErr: ErrUnauthenticated,
+	}}
+	for _, tc := range cases {
+		t.Run(tc.name, func(t *testing.T) {
+			a, _ := newTestAuthenticator()
+			broker, err := config.CellTenantKeyFromPersistenceString(tc.broker)
+			if err != nil {
+				t.Fatal(err)
+			}
+			request := httptest.NewRequest(nethttp.MethodPost, tc.broker, nil)
+			if tc.authorization != nil {
+				request.Header.Set("Authorization", tc.authorization(t))
+			}
+			if err := a.authenticate(context.Background(), broker, request); !errors.Is(err, tc.wantErr) {
+				t.Errorf("authenticate() error = %v, want %v", err, tc.wantErr)
+			}
+		})
+	}
+}
+
+func TestAuthenticateCachesTokens(t *testing.T) {
+	a, reviews := newTestAuthenticator()
+	now := time.Now()
+	a.now = func() time.Time { return now }
+	broker, _ := config.CellTenantKeyFromPersistenceString("/ns1/broker1")
+	request := httptest.NewRequest(nethttp.MethodPost, "/ns1/broker1", nil)
+	request.Header.Set("Authorization", "Bearer "+newToken(t, "kubernetes", "ns1:sender"))
+
+	for i := 0; i < 2; i++ {
+		if err := a.authenticate(context.Background(), broker, request); err != nil {
+			t.Fatalf("authenticate() error = %v", err)
+		}
+	}
+	if *reviews != 1 {
+		t.Errorf("token reviews got=%d, want=1", *reviews)
+	}
+
+	now = now.Add(tokenCacheTTL)
+	if err := a.authenticate(context.Background(), broker, request); err != nil {
+		t.Fatalf("authenticate() error = %v", err)
+	}
+	if *reviews != 2 {
+		t.Errorf("token reviews after expiration got=%d, want=2", *reviews)
+	}
+}
+
+func TestAuthenticateCachesRejectedTokens(t *testing.T) {
+	a, reviews := newTestAuthenticator()
+	now := time.Now()
+	a.now = func() time.Time { return now }
+	broker, _ := config.CellTenantKeyFromPersistenceString("/ns1/broker1")
+	request := httptest.NewRequest(nethttp.MethodPost, "/ns1/broker1", nil)
+	request.Header.Set("Authorization", "Bearer "+newToken(t, "other", "ns1:sender"))
+
+	for i := 0; i < 2; i++ {
+		if err := a.authenticate(context.Background(), broker, request); !errors.Is(err, ErrUnauthenticated) {
+			t.Fatalf("authenticate() error = %v, want %v", err, ErrUnauthenticated)
+		}
+	}
+	if *reviews != 1 {
+		t.Errorf("token reviews got=%d, want=1", *reviews)
+	}
+
+	now = now.Add(rejectedTokenCacheTTL)
+	if err := a.authenticate(context.Background(), broker, request); !errors.Is(err, ErrUnauthenticated) {
+		t.Fatalf("authenticate() error = %v, want %v", err, ErrUnauthenticated)
+	}
+	if *reviews != 2 {
+		t.Errorf("token reviews after expiration got=%d, want=2", *reviews)
+	}
+}
+
+func TestAuthenticateLimitsFailedReviews(t *testing.T) {
+	a, reviews := newTestAuthenticator()
+	now := time.Now()
+	a.now = func() time.Time { return now }
+	broker, _ := config.CellTenantKeyFromPersistenceString("/ns1/broker1")
+	authenticate := func(token string) error {
+		request := httptest.NewRequest(nethttp.MethodPost, "/ns1/broker1", nil)
+		request.Header.Set("Authorization", "Bearer "+token)
+		return a.authenticate(context.Background(), broker, request)
+	}
+
+	cached := newToken(t, "kubernetes", "ns1:sender")
+	if err := authenticate(cached); err != nil {
+		t.Fatalf("authenticate() error = %v", err)
+	}
+	for i := 0; i < maxFailedReviews; i++ {
+		if err := authenticate(newToken(t, "other", strconv.Itoa(i))); !errors.Is(err, ErrUnauthenticated) {
+			t.Fatalf("authenticate() error = %v, want %v", err, ErrUnauthenticated)
+		}
+	}
+	if *reviews != maxFailedReviews+1 {
+		t.Errorf("token reviews got=%d, want=%d", *reviews, maxFailedReviews+1)
+	}
+
+	// New tokens are rejected without being reviewed, but the cached tokens are still accepted.
+	if err := authenticate(newToken(t, "kubernetes", "ns1:sender2")); !errors.Is(err, ErrUnauthenticated) {
+		t.Errorf("authenticate() error = %v, want %v", err, ErrUnauthenticated)
+	}
+	if err := authenticate(cached); err != nil {
+		t.Errorf("authenticate() of a cached token error = %v", err)
+	}
+	if *reviews != maxFailedReviews+1 {
+		t.Errorf("token reviews once throttled got=%d, want=%d", *reviews, maxFailedReviews+1)
+	}
+
+	now = now.Add(failedReviewsWindow)
+	if err := authenticate(newToken(t, "kubernetes", "ns1:sender")); err != nil {
+		t.Errorf("authenticate() after the window error = %v", err)
+	}
+}
+
+type acceptingDecoupleSink struct{}
+
+func (acceptingDecoupleSink) Send(context.Context, *config.CellTenantKey, cev2.Event) protocol.Result {
+	return nil
+}
+
+func TestHandlerAuthentication(t *testing.T) {
+	cases := []struct {
+		name          string
+		authorization string
+		wantCode      int
+	}{{
+		name:     "unauthenticated",
+		wantCode: nethttp.StatusUnauthorized,
+	}, {
+		name:          "forbidden",
+		authorization: "Bearer " + newToken(t, "kubernetes", "ns1:other"),
+		wantCode:      nethttp.StatusForbidden,
+	}, {
+		name:          "allowed",
+		authorization: "Bearer " + newToken(t, "kubernetes", "ns1:sender"),
+		wantCode:      nethttp.StatusAccepted,
+	}}
+	for _, tc := range cases {
+		t.Run(tc.name, func(t *testing.T) {
+			ctx := logtest.TestContextWithLogger(t)
+			reporter := newTestReporter(t)
+			a, _ := newTestAuthenticator()
+			h := NewHandler(ctx, nil, acceptingDecoupleSink{}, a, nil, reporter, "")
+
+			request := createRequest(testCase{path: "/ns1/broker1", event: createTestEvent("test-event")}, "http://broker.example.com")
+			if tc.authorization != "" {
+				request.Header.Set("Authorization", tc.authorization)
+			}
+			response := httptest.NewRecorder()
+			h.ServeHTTP(response, request)
+
+			if response.Code != tc.wantCode {
+				t.Errorf("response code got=%d, want=%d", response.Code, tc.wantCode)
+			}
+			if tc.wantCode == nethttp.StatusAccepted {
+				metricstest.CheckStatsNotReported(t, "request_rejected_count")
+				return
+			}
+			metricstest.CheckCountData(t, "request_rejected_count", map[string]string{
+				metricskey.LabelResponseCode:      strconv.Itoa(tc.wantCode),
+				metricskey.LabelResponseCodeClass: "4xx",
+				metricskey.PodName:                pod,
+				metricskey.ContainerName:          container,
+			}, 1)
+		})
+	}
+}
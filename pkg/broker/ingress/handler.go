@@ -19,9 +19,12 @@ package ingress
 import (
 	"context"
 	"errors"
+	"fmt"
 	nethttp "net/http"
+	"sync"
 	"time"
 
+	"github.com/google/knative-gcp/pkg/broker/claimcheck"
 	"github.com/google/knative-gcp/pkg/broker/config"
 
 	ceocclient "github.com/cloudevents/sdk-go/observability/opencensus/v2/client"
@@ -40,6 +43,7 @@ import (
 	kntracing "knative.dev/eventing/pkg/tracing"
 	"knative.dev/pkg/metrics/metricskey"
 
+	"github.com/google/knative-gcp/pkg/broker/eventutil"
 	"github.com/google/knative-gcp/pkg/logging"
 	"github.com/google/knative-gcp/pkg/metrics"
 	"github.com/google/knative-gcp/pkg/tracing"
@@ -54,6 +58,10 @@ const (
 	// Limit for request payload in bytes (10Mb -- corresponds to message size limit on PubSub as of 09/2020)
 	maxRequestBodyBytes = 10000000
 
+	// maxBatchConcurrency is the maximum number of events of a batch request sent to the decouple
+	// sink concurrently.
+	maxBatchConcurrency = 100
+
 	// EventArrivalTime is used to access the metadata stored on a
 	// CloudEvent to measure the time difference between when an event is
 	// received on a broker and before it is dispatched to the trigger function.
@@ -73,7 +81,10 @@ var HandlerSet wire.ProviderSet = wire.NewSet(
 	wire.Bind(new(HttpMessageReceiver), new(*kncloudevents.HTTPMessageReceiver)),
 	NewMultiTopicDecoupleSink,
 	wire.Bind(new(DecoupleSink), new(*multiTopicDecoupleSink)),
+	NewAuthenticator,
+	claimcheck.NewStore,
 	clients.NewPubsubClient,
+	clients.NewStorageClient,
 	metrics.NewIngressReporter,
 )
 
@@ -94,16 +105,22 @@ type Handler struct {
 	httpReceiver HttpMessageReceiver
 	// decouple is the client to send events to a decouple sink.
 	decouple DecoupleSink
+	// auth authenticates the callers of the brokers with an ingress authentication.
+	auth *Authenticator
+	// targets are the targets config, whose generation is served for the controller.
+	targets  config.ReadonlyTargets
 	logger   *zap.Logger
 	reporter *metrics.IngressReporter
 	authType authcheck.AuthType
 }
 
 // NewHandler creates a new ingress handler.
-func NewHandler(ctx context.Context, httpReceiver HttpMessageReceiver, decouple DecoupleSink, reporter *metrics.IngressReporter, authType authcheck.AuthType) *Handler {
+func NewHandler(ctx context.Context, httpReceiver HttpMessageReceiver, decouple DecoupleSink, auth *Authenticator, targets config.ReadonlyTargets, reporter *metrics.IngressReporter, authType authcheck.AuthType) *Handler {
 	return &Handler{
 		httpReceiver: httpReceiver,
 		decouple:     decouple,
+		auth:         auth,
+		targets:      targets,
 		reporter:     reporter,
 		logger:       logging.FromContext(ctx),
 		authType:     authType,
@@ -118,13 +135,19 @@ func (h *Handler) Start(ctx context.Context) error {
 // ServeHTTP implements net/http Handler interface method.
 // 1. Performs basic validation of the request.
 // 2. Parse request URL to get namespace and broker.
-// 3. Convert request to event.
-// 4. Send event to decouple sink.
+// 3. Authenticate the caller, if the broker requires it.
+// 4. Convert request to event, or to events in batch mode.
+// 5. Send events to decouple sink.
 func (h *Handler) ServeHTTP(response nethttp.ResponseWriter, request *nethttp.Request) {
 	ctx := request.Context()
 	ctx = logging.WithLogger(ctx, h.logger)
 	ctx = tracing.WithLogging(ctx, trace.FromContext(ctx))
 	logging.FromContext(ctx).Debug("Serving http", zap.Any("headers", request.Header))
+	// Events are only POSTed, so the path of the targets generation can't be the one of a broker.
+	if request.Method == nethttp.MethodGet && request.URL.Path == config.TargetsGenerationPath && h.targets != nil {
+		fmt.Fprint(response, h.targets.Generation())
+		return
+	}
 	if request.Method != nethttp.MethodPost {
 		response.WriteHeader(nethttp.StatusMethodNotAllowed)
 		return
@@ -145,12 +168,28 @@ func (h *Handler) ServeHTTP(response nethttp.ResponseWriter, request *nethttp.Re
 	ctx = logging.With(ctx, zap.Stringer("broker", broker))
 	ctx = metricskey.WithResource(ctx, broker.MetricsResource())
 
+	if err := h.auth.authenticate(ctx, broker, request); err != nil {
+		logging.FromContext(ctx).Debug("Rejected request", zap.Error(err))
+		statusCode := nethttp.StatusForbidden
+		if errors.Is(err, ErrUnauthenticated) {
+			statusCode = nethttp.StatusUnauthorized
+			response.Header().Set("WWW-Authenticate", "Bearer")
+		}
+		nethttp.Error(response, err.Error(), statusCode)
+		if err := h.reporter.ReportRejectedRequestCount(ctx, statusCode); err != nil {
+			logging.FromContext(ctx).Warn("Failed to record metrics.", zap.Error(err))
+		}
+		return
+	}
+
+	if eventutil.IsBatchRequest(request) {
+		h.serveBatch(ctx, response, request, broker)
+		return
+	}
+
 	event, err := h.toEvent(ctx, request)
 	if err != nil {
-		httpStatus := nethttp.StatusBadRequest
-		if err.Error() == "http: request body too large" {
-			httpStatus = nethttp.StatusRequestEntityTooLarge
-		}
+		httpStatus := toEventErrorStatusCode(err)
 		nethttp.Error(response, err.Error(), httpStatus)
 		h.reportMetrics(ctx, "_invalid_cloud_event_", httpStatus)
 		return
@@ -172,35 +211,101 @@ func (h *Handler) ServeHTTP(response nethttp.ResponseWriter, request *nethttp.Re
 		)
 	}
 
-	// Optimistically set status code to StatusAccepted. It will be updated if there is an error.
-	// According to the data plane spec (https://github.com/knative/eventing/blob/master/docs/spec/data-plane.md), a
-	// non-callable SINK (which broker is) MUST respond with 202 Accepted if the request is accepted.
-	statusCode := nethttp.StatusAccepted
 	ctx, cancel := context.WithTimeout(ctx, decoupleSinkTimeout)
 	defer cancel()
+	statusCode, msg := h.send(ctx, broker, event)
 	defer func() { h.reportMetrics(ctx, event.Type(), statusCode) }()
-	if res := h.decouple.Send(ctx, broker, *event); !cev2.IsACK(res) {
-		logging.FromContext(ctx).Error("Error publishing to PubSub", zap.Error(res))
-		statusCode = nethttp.StatusInternalServerError
-
-		switch {
-		case errors.Is(res, ErrNotFound):
-			statusCode = nethttp.StatusNotFound
-		case errors.Is(res, ErrNotReady):
-			statusCode = nethttp.StatusServiceUnavailable
-		case errors.Is(res, bundler.ErrOverflow):
-			statusCode = nethttp.StatusTooManyRequests
-		case grpcstatus.Code(res) == grpccode.PermissionDenied:
-			nethttp.Error(response, deniedErrMsg, statusCode)
-			return
-		}
-		nethttp.Error(response, "Failed to publish to PubSub", statusCode)
+	if msg != "" {
+		nethttp.Error(response, msg, statusCode)
 		return
 	}
-
 	response.WriteHeader(statusCode)
 }
 
+// serveBatch sends each event of a batch request to the decouple sink. Up to
+// maxBatchConcurrency events are sent concurrently, so that they are bundled by the Pub/Sub
+// publisher, and each event has its own result.
+func (h *Handler) serveBatch(ctx context.Context, response nethttp.ResponseWriter, request *nethttp.Request, broker *config.CellTenantKey) {
+	events, err := eventutil.EventsFromBatchRequest(request)
+	if err != nil {
+		logging.FromContext(ctx).Debug("Failed to read batch request", zap.Error(err))
+		httpStatus := toEventErrorStatusCode(err)
+		nethttp.Error(response, err.Error(), httpStatus)
+		h.reportMetrics(ctx, "_invalid_cloud_event_", httpStatus)
+		return
+	}
+
+	span := trace.FromContext(ctx)
+	span.SetName(broker.SpanMessagingDestination())
+	if span.IsRecordingEvents() {
+		span.AddAttributes(
+			kntracing.MessagingSystemAttribute,
+			tracing.PubSubProtocolAttribute,
+			broker.SpanMessagingDestinationAttribute(),
+			trace.Int64Attribute("messaging.batch_size", int64(len(events))),
+		)
+	}
+
+	ctx, cancel := context.WithTimeout(ctx, decoupleSinkTimeout)
+	defer cancel()
+	arrivalTime := cev2.Timestamp{Time: time.Now()}
+	results := make([]eventutil.BatchResult, len(events))
+	var wg sync.WaitGroup
+	sem := make(chan struct{}, maxBatchConcurrency)
+	for i, event := range events {
+		event.SetExtension(EventArrivalTime, arrivalTime)
+		wg.Add(1)
+		sem <- struct{}{}
+		go func(i int, event *cev2.Event) {
+			defer func() {
+				<-sem
+				wg.Done()
+			}()
+			statusCode, msg := h.send(ctx, broker, event)
+			h.reportMetrics(ctx, event.Type(), statusCode)
+			results[i] = eventutil.BatchResult{ID: event.ID(), StatusCode: statusCode, Error: msg}
+		}(i, event)
+	}
+	wg.Wait()
+
+	if err := eventutil.WriteBatchResponse(response, nethttp.StatusAccepted, results); err != nil {
+		logging.FromContext(ctx).Warn("Failed to write batch response", zap.Error(err))
+	}
+}
+
+// send sends an event to the decouple sink. It returns the status code of the result, and an
+// error message if the event was not accepted.
+func (h *Handler) send(ctx context.Context, broker *config.CellTenantKey, event *cev2.Event) (int, string) {
+	// According to the data plane spec (https://github.com/knative/eventing/blob/master/docs/spec/data-plane.md), a
+	// non-callable SINK (which broker is) MUST respond with 202 Accepted if the request is accepted.
+	res := h.decouple.Send(ctx, broker, *event)
+	if cev2.IsACK(res) {
+		return nethttp.StatusAccepted, ""
+	}
+	logging.FromContext(ctx).Error("Error publishing to PubSub", zap.Error(res))
+	switch {
+	case errors.Is(res, ErrNotFound):
+		return nethttp.StatusNotFound, "Failed to publish to PubSub"
+	case errors.Is(res, ErrNotReady):
+		return nethttp.StatusServiceUnavailable, "Failed to publish to PubSub"
+	case errors.Is(res, bundler.ErrOverflow):
+		return nethttp.StatusTooManyRequests, "Failed to publish to PubSub"
+	case grpcstatus.Code(res) == grpccode.PermissionDenied:
+		return nethttp.StatusInternalServerError, deniedErrMsg
+	default:
+		return nethttp.StatusInternalServerError, "Failed to publish to PubSub"
+	}
+}
+
+// toEventErrorStatusCode returns the status code of a request that could not be converted to
+// events.
+func toEventErrorStatusCode(err error) int {
+	if err.Error() == "http: request body too large" {
+		return nethttp.StatusRequestEntityTooLarge
+	}
+	return nethttp.StatusBadRequest
+}
+
 // toEvent converts an http request to an event.
 func (h *Handler) toEvent(ctx context.Context, request *nethttp.Request) (*cev2.Event, error) {
 	message := http.NewMessageFromHttpRequest(request)
@@ -23,6 +23,9 @@ import (
 	"fmt"
 	nethttp "net/http"
 	"net/http/httptest"
+	"sort"
+	"strings"
+	"sync"
 	"testing"
 	"time"
 
@@ -38,8 +41,10 @@ import (
 	"github.com/cloudevents/sdk-go/v2/extensions"
 	"github.com/cloudevents/sdk-go/v2/protocol"
 	"github.com/cloudevents/sdk-go/v2/protocol/http"
+	"github.com/google/go-cmp/cmp"
 	"github.com/google/knative-gcp/pkg/broker/config"
 	"github.com/google/knative-gcp/pkg/broker/config/memory"
+	"github.com/google/knative-gcp/pkg/broker/eventutil"
 	"github.com/google/knative-gcp/pkg/metrics"
 	reportertest "github.com/google/knative-gcp/pkg/metrics/testing"
 	kgcptesting "github.com/google/knative-gcp/pkg/testing"
@@ -318,7 +323,7 @@ func TestHandler(t *testing.T) {
 	defer client.CloseIdleConnections()
 	for _, tc := range tests {
 		t.Run(tc.name, func(t *testing.T) {
-			reportertest.ResetIngressMetrics()
+			reporter := newTestReporter(t)
 			ctx := logging.WithLogger(context.Background(), logtest.TestLogger(t))
 			timeout := 5 * time.Second
 			if tc.timeout > 0 {
@@ -332,10 +337,10 @@ func TestHandler(t *testing.T) {
 
 			decouple := tc.decouple
 			if decouple == nil {
-				decouple = NewMultiTopicDecoupleSink(ctx, memory.NewTargets(brokerConfig), createPubsubClient(ctx, t, psSrv), pubsub.DefaultPublishSettings)
+				decouple = NewMultiTopicDecoupleSink(ctx, memory.NewTargets(brokerConfig), createPubsubClient(ctx, t, psSrv), pubsub.DefaultPublishSettings, reporter, nil, nil)
 			}
 
-			url := createAndStartIngress(ctx, t, psSrv, decouple)
+			url := createAndStartIngress(ctx, t, psSrv, decouple, reporter)
 			rec := setupTestReceiver(ctx, t, psSrv)
 			req := createRequest(tc, url)
 			if tc.contentLength != nil {
@@ -383,6 +388,131 @@ func TestHandler(t *testing.T) {
 	}
 }
 
+// fakeBatchDecoupleSink records the IDs of the events it accepts, and fails the events in fail.
+type fakeBatchDecoupleSink struct {
+	fail map[string]error
+
+	mux sync.Mutex
+	ids []string
+}
+
+func (m *fakeBatchDecoupleSink) Send(_ context.Context, _ *config.CellTenantKey, event cev2.Event) protocol.Result {
+	if err, ok := m.fail[event.ID()]; ok {
+		return err
+	}
+	if _, ok := event.Extensions()[EventArrivalTime]; !ok {
+		return fmt.Errorf("event %s without arrival time", event.ID())
+	}
+	m.mux.Lock()
+	defer m.mux.Unlock()
+	m.ids = append(m.ids, event.ID())
+	return nil
+}
+
+func TestHandlerBatch(t *testing.T) {
+	batch := `[{"specversion":"1.0","id":"1","source":"src","type":"type"},{"specversion":"1.0","id":"2","source":"src","type":"type"},{"specversion":"1.0","id":"3","source":"src","type":"type"}]`
+	tests := []struct {
+		name        string
+		body        string
+		fail        map[string]error
+		wantCode    int
+		wantIDs     []string
+		wantResults []eventutil.BatchResult
+	}{{
+		name:     "all accepted",
+		body:     batch,
+		wantCode: nethttp.StatusAccepted,
+		wantIDs:  []string{"1", "2", "3"},
+	}, {
+		name:     "partial failure",
+		body:     batch,
+		fail:     map[string]error{"2": bundler.ErrOverflow},
+		wantCode: nethttp.StatusTooManyRequests,
+		wantIDs:  []string{"1", "3"},
+		wantResults: []eventutil.BatchResult{
+			{ID: "1", StatusCode: nethttp.StatusAccepted},
+			{ID: "2", StatusCode: nethttp.StatusTooManyRequests, Error: "Failed to publish to PubSub"},
+			{ID: "3", StatusCode: nethttp.StatusAccepted},
+		},
+	}, {
+		name:     "invalid event",
+		body:     `[{"specversion":"1.0","id":"1","source":"src","type":"type"},{"specversion":"1.0","id":"2"}]`,
+		wantCode: nethttp.StatusBadRequest,
+	}}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			ctx := logtest.TestContextWithLogger(t)
+			decouple := &fakeBatchDecoupleSink{fail: tt.fail}
+			h := NewHandler(ctx, nil, decouple, nil, nil, newTestReporter(t), "")
+
+			request := httptest.NewRequest(nethttp.MethodPost, "/ns1/broker1", bytes.NewBufferString(tt.body))
+			request.Header.Set("Content-Type", "application/cloudevents-batch+json")
+			response := httptest.NewRecorder()
+			h.ServeHTTP(response, request)
+
+			if response.Code != tt.wantCode {
+				t.Errorf("response code got=%d, want=%d", response.Code, tt.wantCode)
+			}
+			sort.Strings(decouple.ids)
+			if diff := cmp.Diff(tt.wantIDs, decouple.ids); diff != "" {
+				t.Errorf("sent events (-want,+got): %v", diff)
+			}
+			if tt.wantResults != nil {
+				var results []eventutil.BatchResult
+				if err := json.Unmarshal(response.Body.Bytes(), &results); err != nil {
+					t.Fatalf("malformed response body %q: %v", response.Body.String(), err)
+				}
+				if diff := cmp.Diff(tt.wantResults, results); diff != "" {
+					t.Errorf("results (-want,+got): %v", diff)
+				}
+			}
+		})
+	}
+}
+
+// slowDecoupleSink records the maximum number of events it is sent concurrently.
+type slowDecoupleSink struct {
+	mux         sync.Mutex
+	inFlight    int
+	maxInFlight int
+}
+
+func (m *slowDecoupleSink) Send(_ context.Context, _ *config.CellTenantKey, _ cev2.Event) protocol.Result {
+	m.mux.Lock()
+	m.inFlight++
+	if m.inFlight > m.maxInFlight {
+		m.maxInFlight = m.inFlight
+	}
+	m.mux.Unlock()
+	time.Sleep(10 * time.Millisecond)
+	m.mux.Lock()
+	m.inFlight--
+	m.mux.Unlock()
+	return nil
+}
+
+func TestHandlerBatchConcurrency(t *testing.T) {
+	ctx := logtest.TestContextWithLogger(t)
+	events := make([]string, 3*maxBatchConcurrency)
+	for i := range events {
+		events[i] = fmt.Sprintf(`{"specversion":"1.0","id":"%d","source":"src","type":"type"}`, i)
+	}
+	decouple := &slowDecoupleSink{}
+	h := NewHandler(ctx, nil, decouple, nil, nil, newTestReporter(t), "")
+
+	request := httptest.NewRequest(nethttp.MethodPost, "/ns1/broker1", bytes.NewBufferString("["+strings.Join(events, ",")+"]"))
+	request.Header.Set("Content-Type", "application/cloudevents-batch+json")
+	response := httptest.NewRecorder()
+	h.ServeHTTP(response, request)
+
+	if response.Code != nethttp.StatusAccepted {
+		t.Errorf("response code got=%d, want=%d", response.Code, nethttp.StatusAccepted)
+	}
+	if decouple.maxInFlight > maxBatchConcurrency {
+		t.Errorf("concurrent events got=%d, want at most %d", decouple.maxInFlight, maxBatchConcurrency)
+	}
+}
+
 func BenchmarkIngressHandler(b *testing.B) {
 	for _, targetCounts := range []int{1, 5, 10, 50, 100} {
 		for _, eventSize := range kgcptesting.BenchmarkEventSizes {
@@ -410,12 +540,12 @@ func runIngressHandlerBenchmark(b *testing.B, eventSize int, targetCounts int) {
 	setBrokerConfigTargets(targetCounts)
 	defer restoreBrokerConfigTargets()
 
-	decouple := NewMultiTopicDecoupleSink(ctx, memory.NewTargets(brokerConfig), psClient, pubsub.DefaultPublishSettings)
 	statsReporter, err := metrics.NewIngressReporter(metrics.PodName(pod), metrics.ContainerName(container))
 	if err != nil {
 		b.Fatal(err)
 	}
-	h := NewHandler(ctx, nil, decouple, statsReporter, "")
+	decouple := NewMultiTopicDecoupleSink(ctx, memory.NewTargets(brokerConfig), psClient, pubsub.DefaultPublishSettings, statsReporter, nil, nil)
+	h := NewHandler(ctx, nil, decouple, nil, nil, statsReporter, "")
 
 	if _, err := psClient.CreateTopic(ctx, topicID); err != nil {
 		b.Fatal(err)
@@ -516,14 +646,20 @@ func setupTestReceiver(ctx context.Context, t testing.TB, psSrv *pstest.Server)
 	return p
 }
 
-// createAndStartIngress creates an ingress and calls its Start() method in a goroutine.
-func createAndStartIngress(ctx context.Context, t testing.TB, psSrv *pstest.Server, decouple DecoupleSink) string {
-	receiver := &testHttpMessageReceiver{urlCh: make(chan string)}
-	statsReporter, err := metrics.NewIngressReporter(metrics.PodName(pod), metrics.ContainerName(container))
+// newTestReporter resets the ingress metrics and creates a new reporter.
+func newTestReporter(t testing.TB) *metrics.IngressReporter {
+	reportertest.ResetIngressMetrics()
+	r, err := metrics.NewIngressReporter(metrics.PodName(pod), metrics.ContainerName(container))
 	if err != nil {
 		t.Fatal(err)
 	}
-	h := NewHandler(ctx, receiver, decouple, statsReporter, "")
+	return r
+}
+
+// createAndStartIngress creates an ingress and calls its Start() method in a goroutine.
+func createAndStartIngress(ctx context.Context, t testing.TB, psSrv *pstest.Server, decouple DecoupleSink, statsReporter *metrics.IngressReporter) string {
+	receiver := &testHttpMessageReceiver{urlCh: make(chan string)}
+	h := NewHandler(ctx, receiver, decouple, nil, nil, statsReporter, "")
 
 	errCh := make(chan error, 1)
 	go func() {
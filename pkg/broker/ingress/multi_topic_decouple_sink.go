@@ -30,30 +30,51 @@ import (
 	cev2 "github.com/cloudevents/sdk-go/v2"
 	"github.com/cloudevents/sdk-go/v2/binding"
 	"github.com/cloudevents/sdk-go/v2/protocol"
+	"github.com/google/knative-gcp/pkg/broker/claimcheck"
 	"github.com/google/knative-gcp/pkg/broker/config"
-	"github.com/google/knative-gcp/pkg/broker/handler/processors/filter"
+	"github.com/google/knative-gcp/pkg/broker/eventfilter"
+	"github.com/google/knative-gcp/pkg/broker/eventutil"
 	"github.com/google/knative-gcp/pkg/logging"
+	"github.com/google/knative-gcp/pkg/metrics"
 	"github.com/google/knative-gcp/pkg/tracing"
 )
 
 const projectEnvKey = "PROJECT_ID"
 
-// NewMultiTopicDecoupleSink creates a new multiTopicDecoupleSink.
+// TargetsUpdates receives a signal each time the broker config is updated.
+type TargetsUpdates <-chan struct{}
+
+// NewMultiTopicDecoupleSink creates a new multiTopicDecoupleSink. The trigger filter index is
+// rebuilt each time targetsUpdates receives a signal, until the context is done. A nil
+// targetsUpdates is valid and means the broker config never changes. The large payloads of the
+// brokers with a claim check are offloaded to claimChecks; a nil claimChecks keeps them in the
+// events.
 func NewMultiTopicDecoupleSink(
 	ctx context.Context,
 	brokerConfig config.ReadonlyTargets,
 	client *pubsub.Client,
-	publishSettings pubsub.PublishSettings) *multiTopicDecoupleSink {
+	publishSettings pubsub.PublishSettings,
+	reporter *metrics.IngressReporter,
+	claimChecks *claimcheck.Store,
+	targetsUpdates TargetsUpdates) *multiTopicDecoupleSink {
 
-	return &multiTopicDecoupleSink{
+	m := &multiTopicDecoupleSink{
 		pubsub:          client,
 		publishSettings: publishSettings,
 		brokerConfig:    brokerConfig,
+		filters:         eventfilter.NewIndex(),
+		reporter:        reporter,
+		claimChecks:     claimChecks,
 		// TODO(#1118): remove Topic when broker config is removed
 		topics: make(map[config.CellTenantKey]*pubsub.Topic),
 		// TODO(#1804): remove this field when enabling the feature by default.
 		enableEventFiltering: enableEventFilterFunc(),
 	}
+	m.filters.Sync(ctx, brokerConfig)
+	if targetsUpdates != nil {
+		go m.syncFilters(ctx, targetsUpdates)
+	}
+	return m
 }
 
 // multiTopicDecoupleSink implements DecoupleSink and routes events to pubsub topics corresponding
@@ -68,6 +89,11 @@ type multiTopicDecoupleSink struct {
 	// brokerConfig holds configurations for all brokers. It's a view of a configmap populated by
 	// the broker controller.
 	brokerConfig config.ReadonlyTargets
+	// filters indexes the trigger filters of each broker.
+	filters  *eventfilter.Index
+	reporter *metrics.IngressReporter
+	// claimChecks stores the large payloads of the brokers with a claim check.
+	claimChecks *claimcheck.Store
 	// TODO(#1804): remove this field when enabling the feature by default.
 	enableEventFiltering bool
 }
@@ -88,24 +114,44 @@ func (m *multiTopicDecoupleSink) Send(ctx context.Context, broker *config.CellTe
 	// Check to see if there are any triggers interested in this event. If not, no need to send this
 	// to the decouple topic.
 	// TODO(#1804): remove first check when enabling the feature by default.
-	if m.enableEventFiltering && !m.hasTrigger(ctx, &event) {
+	if m.enableEventFiltering && !m.hasTrigger(ctx, broker, &event) {
 		logging.FromContext(ctx).Debug("Filtering target-less event at ingress", zap.String("Eventid", event.ID()))
+		if err := m.reporter.ReportDroppedEventCount(ctx, event.Type()); err != nil {
+			logging.FromContext(ctx).Warn("Failed to record metrics.", zap.Error(err))
+		}
 		return nil
 	}
 
+	b, ok := m.brokerConfig.GetCellTenantByKey(broker)
+	// Only the retry data plane may send ordered retry notices to the decouple topic.
+	event.SetExtension(eventutil.OrderedRetryExtension, nil)
+	// Offload also removes the claim check reference set by the sender, if any.
+	if err := m.claimChecks.Offload(ctx, b.GetClaimCheck(), broker, &event); err != nil {
+		return err
+	}
+
 	dt := tracing.FromSpanContext(trace.FromContext(ctx).SpanContext())
 	msg := new(pubsub.Message)
 	if err := cepubsub.WritePubSubMessage(ctx, binding.ToMessage(&event), msg, dt.WriteTransformer()); err != nil {
 		return err
 	}
+	if ok {
+		msg.OrderingKey = eventutil.OrderingKey(&event, b.Ordering)
+	}
 
 	_, err = topic.Publish(ctx, msg).Get(ctx)
+	if err != nil && msg.OrderingKey != "" {
+		// The publishing of an ordering key is paused after a failure, so that the following
+		// events are not published before the failed one. The sender retries the failed event,
+		// so the publishing is resumed.
+		topic.ResumePublish(msg.OrderingKey)
+	}
 	return err
 }
 
-// eventFilterFunc is used to see if a target is interested in an event.
+// eventFilterFunc is used to see if any target of a broker is interested in an event.
 // It is used as a vaiable to allow stubbing out in unit tests.
-var eventFilterFunc = filter.PassFilter
+var eventFilterFunc = (*eventfilter.Index).HasTarget
 
 // enableEventFilterFunc is a temporary function to control enabling and
 // disabling trigger-less event filtering in ingress.
@@ -117,20 +163,22 @@ func isEventFilteringEnabled() bool {
 	return os.Getenv("ENABLE_INGRESS_EVENT_FILTERING") == "true"
 }
 
-// hasTrigger checks given event against all targets to see if it will pass any of their filters.
-// If one is fouund, hasTrigger returns true.
-func (m *multiTopicDecoupleSink) hasTrigger(ctx context.Context, event *cev2.Event) bool {
-	hasTrigger := false
-	m.brokerConfig.RangeAllTargets(func(target *config.Target) bool {
-		if eventFilterFunc(ctx, target.FilterAttributes, event) {
-			hasTrigger = true
-			return false
-		}
-
-		return true
-	})
+// hasTrigger checks given event against the targets of the broker to see if it will pass any of
+// their filters.
+func (m *multiTopicDecoupleSink) hasTrigger(ctx context.Context, broker *config.CellTenantKey, event *cev2.Event) bool {
+	return eventFilterFunc(m.filters, ctx, broker, event)
+}
 
-	return hasTrigger
+// syncFilters rebuilds the trigger filter index each time the broker config is updated.
+func (m *multiTopicDecoupleSink) syncFilters(ctx context.Context, targetsUpdates TargetsUpdates) {
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case <-targetsUpdates:
+			m.filters.Sync(ctx, m.brokerConfig)
+		}
+	}
 }
 
 // getTopicForBroker finds the corresponding decouple topic for the broker from the mounted broker configmap volume.
@@ -170,6 +218,8 @@ func (m *multiTopicDecoupleSink) updateTopicForBroker(ctx context.Context, broke
 	}
 	topic := m.pubsub.Topic(topicID)
 	topic.PublishSettings = m.publishSettings
+	// Events without an ordering key are still published concurrently.
+	topic.EnableMessageOrdering = true
 	m.topics[*broker] = topic
 	return topic, nil
 }
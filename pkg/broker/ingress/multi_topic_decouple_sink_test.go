@@ -29,9 +29,15 @@ import (
 	"github.com/cloudevents/sdk-go/v2/binding"
 	"github.com/cloudevents/sdk-go/v2/event"
 	"github.com/google/go-cmp/cmp"
+	"github.com/google/knative-gcp/pkg/broker/claimcheck"
 	"github.com/google/knative-gcp/pkg/broker/config"
 	"github.com/google/knative-gcp/pkg/broker/config/memory"
+	"github.com/google/knative-gcp/pkg/broker/eventfilter"
+	"github.com/google/knative-gcp/pkg/broker/eventutil"
+	gstoragetesting "github.com/google/knative-gcp/pkg/gclient/storage/testing"
 	logtest "knative.dev/pkg/logging/testing"
+	"knative.dev/pkg/metrics/metricskey"
+	"knative.dev/pkg/metrics/metricstest"
 )
 
 func TestMultiTopicDecoupleSink(t *testing.T) {
@@ -64,7 +70,9 @@ func TestMultiTopicDecoupleSink(t *testing.T) {
 			brokerConfig: &config.TargetsConfig{
 				CellTenants: map[string]*config.CellTenant{
 					"test_ns_1/test_broker_1": {
-						Type: config.CellTenantType_BROKER,
+						Namespace: "test_ns_1",
+						Name:      "test_broker_1",
+						Type:      config.CellTenantType_BROKER,
 						DecoupleQueue: &config.Queue{
 							Topic: "test_topic_1",
 							State: config.State_READY,
@@ -84,14 +92,18 @@ func TestMultiTopicDecoupleSink(t *testing.T) {
 			brokerConfig: &config.TargetsConfig{
 				CellTenants: map[string]*config.CellTenant{
 					"test_ns_1/test_broker_1": {
-						Type: config.CellTenantType_BROKER,
+						Namespace: "test_ns_1",
+						Name:      "test_broker_1",
+						Type:      config.CellTenantType_BROKER,
 						DecoupleQueue: &config.Queue{
 							Topic: "test_topic_1",
 							State: config.State_READY,
 						},
 						Targets: brokerTargets},
 					"test_ns_2/test_broker_2": {
-						Type: config.CellTenantType_BROKER,
+						Namespace: "test_ns_2",
+						Name:      "test_broker_2",
+						Type:      config.CellTenantType_BROKER,
 						DecoupleQueue: &config.Queue{
 							Topic: "test_topic_2",
 							State: config.State_READY,
@@ -128,7 +140,9 @@ func TestMultiTopicDecoupleSink(t *testing.T) {
 			brokerConfig: &config.TargetsConfig{
 				CellTenants: map[string]*config.CellTenant{
 					"test_ns_1/test_broker_1": {
-						Type: config.CellTenantType_BROKER,
+						Namespace: "test_ns_1",
+						Name:      "test_broker_1",
+						Type:      config.CellTenantType_BROKER,
 						DecoupleQueue: &config.Queue{
 							Topic: "test_topic_1",
 							State: config.State_UNKNOWN,
@@ -149,6 +163,8 @@ func TestMultiTopicDecoupleSink(t *testing.T) {
 			brokerConfig: &config.TargetsConfig{
 				CellTenants: map[string]*config.CellTenant{
 					"test_ns_1/test_broker_1": {
+						Namespace:     "test_ns_1",
+						Name:          "test_broker_1",
 						Type:          config.CellTenantType_BROKER,
 						DecoupleQueue: nil,
 						Targets:       brokerTargets,
@@ -168,6 +184,8 @@ func TestMultiTopicDecoupleSink(t *testing.T) {
 			brokerConfig: &config.TargetsConfig{
 				CellTenants: map[string]*config.CellTenant{
 					"test_ns_1/test_broker_1": {
+						Namespace:     "test_ns_1",
+						Name:          "test_broker_1",
 						Type:          config.CellTenantType_BROKER,
 						DecoupleQueue: &config.Queue{Topic: ""},
 						Targets:       brokerTargets,
@@ -206,7 +224,7 @@ func TestMultiTopicDecoupleSink(t *testing.T) {
 					t.Fatal(err)
 				}
 
-				sink := NewMultiTopicDecoupleSink(ctx, brokerConfig, psClient, pubsub.DefaultPublishSettings)
+				sink := NewMultiTopicDecoupleSink(ctx, brokerConfig, psClient, pubsub.DefaultPublishSettings, newTestReporter(t), nil, nil)
 				// Send events
 				event := createTestEvent(uuid.New().String())
 				err = sink.Send(context.Background(), testCase.broker, *event)
@@ -269,7 +287,9 @@ func TestMultiTopicDecoupleSinkWithoutIngressFiltering(t *testing.T) {
 			brokerConfig: &config.TargetsConfig{
 				CellTenants: map[string]*config.CellTenant{
 					"test_ns_1/test_broker_1": {
-						Type: config.CellTenantType_BROKER,
+						Namespace: "test_ns_1",
+						Name:      "test_broker_1",
+						Type:      config.CellTenantType_BROKER,
 						DecoupleQueue: &config.Queue{
 							Topic: "test_topic_1",
 							State: config.State_READY,
@@ -290,7 +310,9 @@ func TestMultiTopicDecoupleSinkWithoutIngressFiltering(t *testing.T) {
 			brokerConfig: &config.TargetsConfig{
 				CellTenants: map[string]*config.CellTenant{
 					"test_ns_1/test_broker_1": {
-						Type: config.CellTenantType_BROKER,
+						Namespace: "test_ns_1",
+						Name:      "test_broker_1",
+						Type:      config.CellTenantType_BROKER,
 						DecoupleQueue: &config.Queue{
 							Topic: "test_topic_1",
 							State: config.State_READY,
@@ -298,7 +320,9 @@ func TestMultiTopicDecoupleSinkWithoutIngressFiltering(t *testing.T) {
 						Targets: brokerTargets,
 					},
 					"test_ns_2/test_broker_2": {
-						Type: config.CellTenantType_BROKER,
+						Namespace: "test_ns_2",
+						Name:      "test_broker_2",
+						Type:      config.CellTenantType_BROKER,
 						DecoupleQueue: &config.Queue{
 							Topic: "test_topic_2",
 							State: config.State_READY,
@@ -336,7 +360,9 @@ func TestMultiTopicDecoupleSinkWithoutIngressFiltering(t *testing.T) {
 			brokerConfig: &config.TargetsConfig{
 				CellTenants: map[string]*config.CellTenant{
 					"test_ns_1/test_broker_1": {
-						Type: config.CellTenantType_BROKER,
+						Namespace: "test_ns_1",
+						Name:      "test_broker_1",
+						Type:      config.CellTenantType_BROKER,
 						DecoupleQueue: &config.Queue{
 							Topic: "test_topic_1",
 							State: config.State_UNKNOWN,
@@ -358,6 +384,8 @@ func TestMultiTopicDecoupleSinkWithoutIngressFiltering(t *testing.T) {
 			brokerConfig: &config.TargetsConfig{
 				CellTenants: map[string]*config.CellTenant{
 					"test_ns_1/test_broker_1": {
+						Namespace:     "test_ns_1",
+						Name:          "test_broker_1",
 						Type:          config.CellTenantType_BROKER,
 						DecoupleQueue: nil,
 						Targets:       brokerTargets,
@@ -377,7 +405,9 @@ func TestMultiTopicDecoupleSinkWithoutIngressFiltering(t *testing.T) {
 			brokerConfig: &config.TargetsConfig{
 				CellTenants: map[string]*config.CellTenant{
 					"test_ns_1/test_broker_1": {
-						Type: config.CellTenantType_BROKER,
+						Namespace: "test_ns_1",
+						Name:      "test_broker_1",
+						Type:      config.CellTenantType_BROKER,
 						DecoupleQueue: &config.Queue{
 							Topic: "",
 						},
@@ -417,7 +447,7 @@ func TestMultiTopicDecoupleSinkWithoutIngressFiltering(t *testing.T) {
 					t.Fatal(err)
 				}
 
-				sink := NewMultiTopicDecoupleSink(ctx, brokerConfig, psClient, pubsub.DefaultPublishSettings)
+				sink := NewMultiTopicDecoupleSink(ctx, brokerConfig, psClient, pubsub.DefaultPublishSettings, newTestReporter(t), nil, nil)
 				// Send events
 				event := createTestEvent(uuid.New().String())
 				err = sink.Send(context.Background(), testCase.broker, *event)
@@ -460,7 +490,9 @@ func TestHasTrigger(t *testing.T) {
 	tests := []struct {
 		name          string
 		brokerTargets map[string]*config.Target
-		hasTrigger    bool
+		// otherBrokerTargets are the targets of another broker in the same cell.
+		otherBrokerTargets map[string]*config.Target
+		hasTrigger         bool
 	}{
 		{
 			name:       "broker with no target",
@@ -556,6 +588,15 @@ func TestHasTrigger(t *testing.T) {
 			},
 			hasTrigger: false,
 		},
+		{
+			name: "broker with no target and other broker with matching target",
+			otherBrokerTargets: map[string]*config.Target{
+				"target_1": {
+					CellTenantType: config.CellTenantType_BROKER,
+				},
+			},
+			hasTrigger: false,
+		},
 	}
 	for _, test := range tests {
 		t.Run(test.name, func(t *testing.T) {
@@ -567,19 +608,28 @@ func TestHasTrigger(t *testing.T) {
 			testBrokerConfig := &config.TargetsConfig{
 				CellTenants: map[string]*config.CellTenant{
 					"test_ns_1/test_broker_1": {
+						Namespace:     "test_ns_1",
+						Name:          "test_broker_1",
 						Type:          config.CellTenantType_BROKER,
 						DecoupleQueue: DecoupleQueue,
 						Targets:       test.brokerTargets,
 					},
+					"test_ns_1/test_broker_2": {
+						Namespace:     "test_ns_1",
+						Name:          "test_broker_2",
+						Type:          config.CellTenantType_BROKER,
+						DecoupleQueue: DecoupleQueue,
+						Targets:       test.otherBrokerTargets,
+					},
 				},
 			}
 
 			brokerConfig := memory.NewTargets(testBrokerConfig)
-			sink := NewMultiTopicDecoupleSink(ctx, brokerConfig, psClient, pubsub.DefaultPublishSettings)
+			sink := NewMultiTopicDecoupleSink(ctx, brokerConfig, psClient, pubsub.DefaultPublishSettings, newTestReporter(t), nil, nil)
 
 			event := createTestEvent(uuid.New().String())
 
-			hasTrigger := sink.hasTrigger(ctx, event)
+			hasTrigger := sink.hasTrigger(ctx, config.TestOnlyBrokerKey("test_ns_1", "test_broker_1"), event)
 			if hasTrigger != test.hasTrigger {
 				t.Errorf("Sink says event has trigger %t which should be %t", hasTrigger, test.hasTrigger)
 			}
@@ -598,7 +648,7 @@ func TestMultiTopicDecoupleSinkSendChecksFilter(t *testing.T) {
 	filterCalled := false
 	origEventFilterFunc := eventFilterFunc
 	defer func() { eventFilterFunc = origEventFilterFunc }()
-	eventFilterFunc = func(ctx context.Context, attrs map[string]string, event *event.Event) bool {
+	eventFilterFunc = func(_ *eventfilter.Index, ctx context.Context, broker *config.CellTenantKey, event *event.Event) bool {
 		filterCalled = true
 		return true
 	}
@@ -611,6 +661,8 @@ func TestMultiTopicDecoupleSinkSendChecksFilter(t *testing.T) {
 	testBrokerConfig := &config.TargetsConfig{
 		CellTenants: map[string]*config.CellTenant{
 			"test_ns_1/test_broker_1": {
+				Namespace:     "test_ns_1",
+				Name:          "test_broker_1",
 				Type:          config.CellTenantType_BROKER,
 				DecoupleQueue: &config.Queue{Topic: "test_topic_1", State: config.State_READY},
 				Targets: map[string]*config.Target{"target_1": {
@@ -632,7 +684,7 @@ func TestMultiTopicDecoupleSinkSendChecksFilter(t *testing.T) {
 		}
 	}
 
-	sink := NewMultiTopicDecoupleSink(ctx, brokerConfig, psClient, pubsub.DefaultPublishSettings)
+	sink := NewMultiTopicDecoupleSink(ctx, brokerConfig, psClient, pubsub.DefaultPublishSettings, newTestReporter(t), nil, nil)
 	// Send event.
 	event := createTestEvent(uuid.New().String())
 
@@ -648,13 +700,56 @@ func TestMultiTopicDecoupleSinkSendChecksFilter(t *testing.T) {
 	}
 }
 
+func TestMultiTopicDecoupleSinkSendDropsEventWithoutTrigger(t *testing.T) {
+	// TODO(#1804): remove this mock when enabling the feature by default.
+	origEnableEventFilterFunc := enableEventFilterFunc
+	defer func() { enableEventFilterFunc = origEnableEventFilterFunc }()
+	enableEventFilterFunc = func() bool {
+		return true
+	}
+
+	ctx := logtest.TestContextWithLogger(t)
+	psSrv := pstest.NewServer()
+	defer psSrv.Close()
+	psClient := createPubsubClient(ctx, t, psSrv)
+
+	testBrokerConfig := &config.TargetsConfig{
+		CellTenants: map[string]*config.CellTenant{
+			"test_ns_1/test_broker_1": {
+				Namespace:     "test_ns_1",
+				Name:          "test_broker_1",
+				Type:          config.CellTenantType_BROKER,
+				DecoupleQueue: &config.Queue{Topic: "test_topic_1", State: config.State_READY},
+				Targets: map[string]*config.Target{"target_1": {
+					CellTenantType:   config.CellTenantType_BROKER,
+					FilterAttributes: map[string]string{"type": "some-other-type"},
+				}},
+			},
+		},
+	}
+
+	brokerConfig := memory.NewTargets(testBrokerConfig)
+	sink := NewMultiTopicDecoupleSink(ctx, brokerConfig, psClient, pubsub.DefaultPublishSettings, newTestReporter(t), nil, nil)
+	// The topic doesn't exist, so the event would fail to be published if it wasn't dropped.
+	namespace := config.TestOnlyBrokerKey("test_ns_1", "test_broker_1")
+	if err := sink.Send(context.Background(), namespace, *createTestEvent(uuid.New().String())); err != nil {
+		t.Fatal(err)
+	}
+
+	metricstest.CheckCountData(t, "event_dropped_count", map[string]string{
+		metricskey.LabelEventType: eventType,
+		metricskey.PodName:        pod,
+		metricskey.ContainerName:  container,
+	}, 1)
+}
+
 // Temoporary test to ensure the filtering feature is disabled by default.
 // TODO(#1804): remove this test when enabling the feature by default.
 func TestMultiTopicDecoupleSinkSendDoesNotChecksFilterWhenFeatureDisabled(t *testing.T) {
 	filterCalled := false
 	origEventFilterFunc := eventFilterFunc
 	defer func() { eventFilterFunc = origEventFilterFunc }()
-	eventFilterFunc = func(ctx context.Context, attrs map[string]string, event *event.Event) bool {
+	eventFilterFunc = func(_ *eventfilter.Index, ctx context.Context, broker *config.CellTenantKey, event *event.Event) bool {
 		filterCalled = true
 		return true
 	}
@@ -667,6 +762,8 @@ func TestMultiTopicDecoupleSinkSendDoesNotChecksFilterWhenFeatureDisabled(t *tes
 	testBrokerConfig := &config.TargetsConfig{
 		CellTenants: map[string]*config.CellTenant{
 			"test_ns_1/test_broker_1": {
+				Namespace:     "test_ns_1",
+				Name:          "test_broker_1",
 				Type:          config.CellTenantType_BROKER,
 				DecoupleQueue: &config.Queue{Topic: "test_topic_1", State: config.State_READY},
 				Targets: map[string]*config.Target{"target_1": {
@@ -688,7 +785,7 @@ func TestMultiTopicDecoupleSinkSendDoesNotChecksFilterWhenFeatureDisabled(t *tes
 		}
 	}
 
-	sink := NewMultiTopicDecoupleSink(ctx, brokerConfig, psClient, pubsub.DefaultPublishSettings)
+	sink := NewMultiTopicDecoupleSink(ctx, brokerConfig, psClient, pubsub.DefaultPublishSettings, newTestReporter(t), nil, nil)
 	// Send event.
 	event := createTestEvent(uuid.New().String())
 
@@ -713,6 +810,8 @@ func TestMultiTopicDecoupleSinkUsesPublishSettings(t *testing.T) {
 	testBrokerConfig := &config.TargetsConfig{
 		CellTenants: map[string]*config.CellTenant{
 			"test_ns_1/test_broker_1": {
+				Namespace:     "test_ns_1",
+				Name:          "test_broker_1",
 				Type:          config.CellTenantType_BROKER,
 				DecoupleQueue: &config.Queue{Topic: "test_topic_1", State: config.State_READY},
 				Targets: map[string]*config.Target{"target_1": {
@@ -739,7 +838,7 @@ func TestMultiTopicDecoupleSinkUsesPublishSettings(t *testing.T) {
 	publishSettings := pubsub.DefaultPublishSettings
 	// This is a purposely smaller than the event's data to cause an error.
 	publishSettings.BufferedByteLimit = len(ce.Data()) - 1
-	sink := NewMultiTopicDecoupleSink(ctx, brokerConfig, psClient, publishSettings)
+	sink := NewMultiTopicDecoupleSink(ctx, brokerConfig, psClient, publishSettings, newTestReporter(t), nil, nil)
 	// Send event.
 
 	namespace := config.TestOnlyBrokerKey("test_ns_1", "test_broker_1")
@@ -751,3 +850,153 @@ func TestMultiTopicDecoupleSinkUsesPublishSettings(t *testing.T) {
 		t.Fatalf("Unexpected error, expected %q, actually %q", want, got)
 	}
 }
+
+func TestMultiTopicDecoupleSinkSendOffloadsLargePayload(t *testing.T) {
+	ctx := logtest.TestContextWithLogger(t)
+	psSrv := pstest.NewServer()
+	defer psSrv.Close()
+	psClient := createPubsubClient(ctx, t, psSrv)
+	if _, err := psClient.CreateTopic(ctx, "test_topic_1"); err != nil {
+		t.Fatal(err)
+	}
+
+	brokerConfig := memory.NewTargets(&config.TargetsConfig{
+		CellTenants: map[string]*config.CellTenant{
+			"test_ns_1/test_broker_1": {
+				Namespace:     "test_ns_1",
+				Name:          "test_broker_1",
+				Type:          config.CellTenantType_BROKER,
+				DecoupleQueue: &config.Queue{Topic: "test_topic_1", State: config.State_READY},
+				ClaimCheck:    &config.ClaimCheck{Bucket: "bucket", ThresholdBytes: 4},
+			},
+		},
+	})
+	objects := make(map[string][]byte)
+	storageClient, err := gstoragetesting.TestClientCreator(gstoragetesting.TestClientData{
+		BucketData: gstoragetesting.TestBucketData{Objects: objects},
+	})(ctx)
+	if err != nil {
+		t.Fatal(err)
+	}
+	sink := NewMultiTopicDecoupleSink(ctx, brokerConfig, psClient, pubsub.DefaultPublishSettings, newTestReporter(t), claimcheck.NewStore(storageClient), nil)
+
+	e := createTestEvent(uuid.New().String())
+	if err := e.SetData(event.TextPlain, "large payload"); err != nil {
+		t.Fatal(err)
+	}
+	if err := sink.Send(ctx, config.TestOnlyBrokerKey("test_ns_1", "test_broker_1"), *e); err != nil {
+		t.Fatalf("Send() error = %v", err)
+	}
+
+	msgs := psSrv.Messages()
+	if len(msgs) != 1 {
+		t.Fatalf("published messages got=%d, want=1", len(msgs))
+	}
+	if len(msgs[0].Data) != 0 {
+		t.Errorf("published message data got=%q, want none", msgs[0].Data)
+	}
+	ref := msgs[0].Attributes["ce-"+claimcheck.ReferenceExtension]
+	if ref == "" {
+		t.Fatal("published message has no claim check reference")
+	}
+	if len(objects) != 1 {
+		t.Fatalf("stored objects got=%d, want=1", len(objects))
+	}
+	for name, data := range objects {
+		if want := "gs://bucket/" + name; ref != want {
+			t.Errorf("claim check reference got=%q, want=%q", ref, want)
+		}
+		if string(data) != "large payload" {
+			t.Errorf("stored payload got=%q, want=%q", data, "large payload")
+		}
+	}
+}
+
+func TestMultiTopicDecoupleSinkSendStripsInternalExtensions(t *testing.T) {
+	ctx := logtest.TestContextWithLogger(t)
+	psSrv := pstest.NewServer()
+	defer psSrv.Close()
+	psClient := createPubsubClient(ctx, t, psSrv)
+	if _, err := psClient.CreateTopic(ctx, "test_topic_1"); err != nil {
+		t.Fatal(err)
+	}
+
+	brokerConfig := memory.NewTargets(&config.TargetsConfig{
+		CellTenants: map[string]*config.CellTenant{
+			"test_ns_1/test_broker_1": {
+				Namespace:     "test_ns_1",
+				Name:          "test_broker_1",
+				Type:          config.CellTenantType_BROKER,
+				DecoupleQueue: &config.Queue{Topic: "test_topic_1", State: config.State_READY},
+			},
+		},
+	})
+	sink := NewMultiTopicDecoupleSink(ctx, brokerConfig, psClient, pubsub.DefaultPublishSettings, newTestReporter(t), claimcheck.NewStore(nil), nil)
+
+	e := createTestEvent(uuid.New().String())
+	e.SetExtension(claimcheck.ReferenceExtension, "gs://other-bucket/secret")
+	e.SetExtension(eventutil.OrderedRetryExtension, "token")
+	if err := sink.Send(ctx, config.TestOnlyBrokerKey("test_ns_1", "test_broker_1"), *e); err != nil {
+		t.Fatalf("Send() error = %v", err)
+	}
+
+	msgs := psSrv.Messages()
+	if len(msgs) != 1 {
+		t.Fatalf("published messages got=%d, want=1", len(msgs))
+	}
+	if ref, ok := msgs[0].Attributes["ce-"+claimcheck.ReferenceExtension]; ok {
+		t.Errorf("published message has claim check reference %q set by the sender", ref)
+	}
+	if token, ok := msgs[0].Attributes["ce-"+eventutil.OrderedRetryExtension]; ok {
+		t.Errorf("published message has ordered retry token %q set by the sender", token)
+	}
+}
+
+func TestMultiTopicDecoupleSinkSendSetsOrderingKey(t *testing.T) {
+	ctx := logtest.TestContextWithLogger(t)
+	psSrv := pstest.NewServer()
+	defer psSrv.Close()
+	psClient := createPubsubClient(ctx, t, psSrv)
+	for _, topic := range []string{"test_topic_1", "test_topic_2"} {
+		if _, err := psClient.CreateTopic(ctx, topic); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	brokerConfig := memory.NewTargets(&config.TargetsConfig{
+		CellTenants: map[string]*config.CellTenant{
+			"test_ns_1/ordered": {
+				Namespace:     "test_ns_1",
+				Name:          "ordered",
+				Type:          config.CellTenantType_BROKER,
+				DecoupleQueue: &config.Queue{Topic: "test_topic_1", State: config.State_READY},
+				Ordering:      &config.Ordering{KeyExtension: "partitionkey"},
+			},
+			"test_ns_1/unordered": {
+				Namespace:     "test_ns_1",
+				Name:          "unordered",
+				Type:          config.CellTenantType_BROKER,
+				DecoupleQueue: &config.Queue{Topic: "test_topic_2", State: config.State_READY},
+			},
+		},
+	})
+	sink := NewMultiTopicDecoupleSink(ctx, brokerConfig, psClient, pubsub.DefaultPublishSettings, newTestReporter(t), nil, nil)
+
+	want := map[string]string{}
+	for broker, key := range map[string]string{"ordered": "key", "unordered": ""} {
+		e := createTestEvent(uuid.New().String())
+		e.SetExtension("partitionkey", "key")
+		if err := sink.Send(ctx, config.TestOnlyBrokerKey("test_ns_1", broker), *e); err != nil {
+			t.Fatalf("Send() to %s error = %v", broker, err)
+		}
+		want[e.ID()] = key
+	}
+
+	got := map[string]string{}
+	for _, msg := range psSrv.Messages() {
+		got[msg.Attributes["ce-id"]] = msg.OrderingKey
+	}
+	if diff := cmp.Diff(want, got); diff != "" {
+		t.Errorf("unexpected ordering keys (-want, +got) = %v", diff)
+	}
+}
@@ -0,0 +1,176 @@
+/*
+Copyright 2021 Google LLC
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package shard
+
+import (
+	"context"
+	"time"
+
+	"go.uber.org/zap"
+	coordinationv1 "k8s.io/api/coordination/v1"
+	apierrs "k8s.io/apimachinery/pkg/api/errors"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/labels"
+	coordinationv1client "k8s.io/client-go/kubernetes/typed/coordination/v1"
+
+	"github.com/google/knative-gcp/pkg/logging"
+)
+
+const (
+	// GroupLabelKey is the label of the Leases holding the name of their shard group.
+	GroupLabelKey = "events.cloud.google.com/shardGroup"
+
+	// DefaultLeaseDuration is how long a member stays in its group without renewing its Lease.
+	DefaultLeaseDuration = 30 * time.Second
+	// DefaultRenewInterval is how often the members renew their Lease and list the members of
+	// their group.
+	DefaultRenewInterval = 10 * time.Second
+)
+
+// LeaseMembership maintains the members of a shard group with a Kubernetes Lease per member.
+// Each member renews its own Lease, and the members whose Lease expired leave the group.
+type LeaseMembership struct {
+	leases   coordinationv1client.LeaseInterface
+	group    string
+	assigner *Assigner
+	// leaseDuration and renewInterval are replaced in tests.
+	leaseDuration time.Duration
+	renewInterval time.Duration
+	// now is replaced in tests.
+	now func() time.Time
+}
+
+// NewLeaseMembership creates the membership of the Assigner's member in the group, with Leases
+// in the given client's namespace.
+func NewLeaseMembership(leases coordinationv1client.LeaseInterface, group string, assigner *Assigner) *LeaseMembership {
+	return &LeaseMembership{
+		leases:        leases,
+		group:         group,
+		assigner:      assigner,
+		leaseDuration: DefaultLeaseDuration,
+		renewInterval: DefaultRenewInterval,
+		now:           time.Now,
+	}
+}
+
+// Start joins the group, and keeps the members of the Assigner up to date until the context is
+// done. A signal is sent to changed each time the members change, so that the keys can be
+// rebalanced. The member leaves the group when the context is done.
+func (m *LeaseMembership) Start(ctx context.Context, changed chan<- struct{}) error {
+	if _, err := m.sync(ctx); err != nil {
+		return err
+	}
+	go func() {
+		ticker := time.NewTicker(m.renewInterval)
+		defer ticker.Stop()
+		for {
+			select {
+			case <-ctx.Done():
+				m.leave(logging.FromContext(ctx))
+				return
+			case <-ticker.C:
+				updated, err := m.sync(ctx)
+				if err != nil {
+					logging.FromContext(ctx).Error("failed to sync the shard group members", zap.String("group", m.group), zap.Error(err))
+					continue
+				}
+				if updated {
+					select {
+					case changed <- struct{}{}:
+					case <-ctx.Done():
+					}
+				}
+			}
+		}
+	}()
+	return nil
+}
+
+// sync renews the Lease of the member and updates the members of the Assigner. It returns true
+// if the members changed.
+func (m *LeaseMembership) sync(ctx context.Context) (bool, error) {
+	if err := m.renew(ctx); err != nil {
+		return false, err
+	}
+	list, err := m.leases.List(ctx, metav1.ListOptions{
+		LabelSelector: labels.SelectorFromSet(map[string]string{GroupLabelKey: m.group}).String(),
+	})
+	if err != nil {
+		return false, err
+	}
+	now := m.now()
+	var members []string
+	for _, l := range list.Items {
+		if l.Spec.HolderIdentity == nil || l.Spec.RenewTime == nil || l.Spec.LeaseDurationSeconds == nil {
+			continue
+		}
+		expires := l.Spec.RenewTime.Add(time.Duration(*l.Spec.LeaseDurationSeconds) * time.Second)
+		if now.Before(expires) {
+			members = append(members, *l.Spec.HolderIdentity)
+		} else if now.After(expires.Add(m.leaseDuration)) {
+			// Members that crashed don't delete their Lease, so the other members clean it up.
+			if err := m.leases.Delete(ctx, l.Name, metav1.DeleteOptions{}); err != nil && !apierrs.IsNotFound(err) {
+				logging.FromContext(ctx).Warn("failed to delete expired shard lease", zap.String("lease", l.Name), zap.Error(err))
+			}
+		}
+	}
+	if !m.assigner.SetMembers(members) {
+		return false, nil
+	}
+	logging.FromContext(ctx).Info("shard group members changed", zap.String("group", m.group), zap.Strings("members", members))
+	return true, nil
+}
+
+// renew creates or renews the Lease of the member.
+func (m *LeaseMembership) renew(ctx context.Context) error {
+	now := metav1.NewMicroTime(m.now())
+	durationSeconds := int32(m.leaseDuration / time.Second)
+	lease, err := m.leases.Get(ctx, m.assigner.self, metav1.GetOptions{})
+	if apierrs.IsNotFound(err) {
+		_, err = m.leases.Create(ctx, &coordinationv1.Lease{
+			ObjectMeta: metav1.ObjectMeta{
+				Name:   m.assigner.self,
+				Labels: map[string]string{GroupLabelKey: m.group},
+			},
+			Spec: coordinationv1.LeaseSpec{
+				HolderIdentity:       &m.assigner.self,
+				LeaseDurationSeconds: &durationSeconds,
+				AcquireTime:          &now,
+				RenewTime:            &now,
+			},
+		}, metav1.CreateOptions{})
+		return err
+	}
+	if err != nil {
+		return err
+	}
+	lease.Spec.RenewTime = &now
+	lease.Spec.LeaseDurationSeconds = &durationSeconds
+	_, err = m.leases.Update(ctx, lease, metav1.UpdateOptions{})
+	return err
+}
+
+// leave deletes the Lease of the member, so that the other members take over its keys without
+// waiting for the Lease to expire.
+func (m *LeaseMembership) leave(logger *zap.Logger) {
+	// The context of the member is already done.
+	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+	defer cancel()
+	if err := m.leases.Delete(ctx, m.assigner.self, metav1.DeleteOptions{}); err != nil && !apierrs.IsNotFound(err) {
+		logger.Warn("failed to delete shard lease", zap.String("lease", m.assigner.self), zap.Error(err))
+	}
+}
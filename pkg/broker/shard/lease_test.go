@@ -0,0 +1,106 @@
+/*
+Copyright 2021 Google LLC
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package shard
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/google/go-cmp/cmp"
+	apierrs "k8s.io/apimachinery/pkg/api/errors"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/client-go/kubernetes/fake"
+	logtest "knative.dev/pkg/logging/testing"
+)
+
+func newTestMembership(client *fake.Clientset, self string, now *time.Time) *LeaseMembership {
+	m := NewLeaseMembership(client.CoordinationV1().Leases("cloud-run-events"), "default-brokercell-fanout", NewAssigner(self))
+	m.now = func() time.Time { return *now }
+	return m
+}
+
+func TestLeaseMembershipSync(t *testing.T) {
+	ctx := logtest.TestContextWithLogger(t)
+	client := fake.NewSimpleClientset()
+	now := time.Now()
+	a := newTestMembership(client, "pod-a", &now)
+	b := newTestMembership(client, "pod-b", &now)
+	other := NewLeaseMembership(client.CoordinationV1().Leases("cloud-run-events"), "default-brokercell-retry", NewAssigner("pod-c"))
+
+	for _, m := range []*LeaseMembership{a, b, other} {
+		if _, err := m.sync(ctx); err != nil {
+			t.Fatalf("sync() error = %v", err)
+		}
+	}
+	if updated, err := a.sync(ctx); err != nil || !updated {
+		t.Errorf("sync() after a member joined = (%v, %v), want (true, nil)", updated, err)
+	}
+	if diff := cmp.Diff([]string{"pod-a", "pod-b"}, a.assigner.Assignment(nil).Members); diff != "" {
+		t.Errorf("members (-want,+got): %v", diff)
+	}
+
+	// pod-b stops renewing its lease.
+	now = now.Add(DefaultLeaseDuration)
+	if updated, err := a.sync(ctx); err != nil || !updated {
+		t.Errorf("sync() after a lease expired = (%v, %v), want (true, nil)", updated, err)
+	}
+	if diff := cmp.Diff([]string{"pod-a"}, a.assigner.Assignment(nil).Members); diff != "" {
+		t.Errorf("members after expiration (-want,+got): %v", diff)
+	}
+
+	// The lease of pod-b is deleted once it expired for long enough.
+	now = now.Add(2 * DefaultLeaseDuration)
+	if _, err := a.sync(ctx); err != nil {
+		t.Fatalf("sync() error = %v", err)
+	}
+	if _, err := client.CoordinationV1().Leases("cloud-run-events").Get(ctx, "pod-b", metav1.GetOptions{}); !apierrs.IsNotFound(err) {
+		t.Errorf("expired lease Get() error = %v, want not found", err)
+	}
+}
+
+func TestLeaseMembershipLeave(t *testing.T) {
+	ctx, cancel := context.WithCancel(logtest.TestContextWithLogger(t))
+	client := fake.NewSimpleClientset()
+	now := time.Now()
+	m := newTestMembership(client, "pod-a", &now)
+	if err := m.Start(ctx, make(chan struct{})); err != nil {
+		t.Fatalf("Start() error = %v", err)
+	}
+	if _, err := client.CoordinationV1().Leases("cloud-run-events").Get(ctx, "pod-a", metav1.GetOptions{}); err != nil {
+		t.Fatalf("lease Get() error = %v", err)
+	}
+
+	cancel()
+	err := wait(func() bool {
+		_, err := client.CoordinationV1().Leases("cloud-run-events").Get(context.Background(), "pod-a", metav1.GetOptions{})
+		return apierrs.IsNotFound(err)
+	})
+	if err != nil {
+		t.Error("the lease was not deleted after the member left")
+	}
+}
+
+func wait(done func() bool) error {
+	for i := 0; i < 50; i++ {
+		if done() {
+			return nil
+		}
+		time.Sleep(20 * time.Millisecond)
+	}
+	return context.DeadlineExceeded
+}
@@ -0,0 +1,149 @@
+/*
+Copyright 2021 Google LLC
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+// Package shard splits the subscriptions handled by a data plane component between its replicas.
+package shard
+
+import (
+	"hash/fnv"
+	"sort"
+	"sync"
+)
+
+// Assigner assigns keys to the members of a shard group with rendezvous hashing, so that each
+// key is owned by a single member, and only the keys of the members that join or leave the group
+// move to other members.
+type Assigner struct {
+	self string
+
+	mux     sync.RWMutex
+	members []string
+}
+
+// Assignment is the current assignment of a member, as shown by the debug endpoint of the
+// component.
+type Assignment struct {
+	// Self is the identity of the member.
+	Self string `json:"self"`
+	// Members are the identities of all the members of the group.
+	Members []string `json:"members"`
+	// Owned are the keys owned by the member.
+	Owned []string `json:"owned"`
+}
+
+// NewAssigner creates an Assigner for the member with the given identity. Until the other members
+// are known, it owns all the keys.
+func NewAssigner(self string) *Assigner {
+	return &Assigner{
+		self:    self,
+		members: []string{self},
+	}
+}
+
+// Owns returns true if the key is owned by this member. A nil Assigner owns all the keys.
+func (a *Assigner) Owns(key string) bool {
+	if a == nil {
+		return true
+	}
+	a.mux.RLock()
+	defer a.mux.RUnlock()
+	return owner(a.members, key) == a.self
+}
+
+// SetMembers replaces the members of the group, and returns true if they changed. The Assigner's
+// own member is always kept.
+func (a *Assigner) SetMembers(members []string) bool {
+	set := map[string]bool{a.self: true}
+	for _, m := range members {
+		set[m] = true
+	}
+	sorted := make([]string, 0, len(set))
+	for m := range set {
+		sorted = append(sorted, m)
+	}
+	sort.Strings(sorted)
+
+	a.mux.Lock()
+	defer a.mux.Unlock()
+	if equal(a.members, sorted) {
+		return false
+	}
+	a.members = sorted
+	return true
+}
+
+// Assignment returns the current assignment of the keys owned by this member among keys. A nil
+// Assigner owns all the keys.
+func (a *Assigner) Assignment(keys []string) Assignment {
+	if a == nil {
+		owned := append([]string(nil), keys...)
+		sort.Strings(owned)
+		return Assignment{Owned: owned}
+	}
+	a.mux.RLock()
+	defer a.mux.RUnlock()
+	owned := make([]string, 0, len(keys))
+	for _, k := range keys {
+		if owner(a.members, k) == a.self {
+			owned = append(owned, k)
+		}
+	}
+	sort.Strings(owned)
+	return Assignment{
+		Self:    a.self,
+		Members: append([]string(nil), a.members...),
+		Owned:   owned,
+	}
+}
+
+// owner returns the member with the highest hash of the member and the key.
+func owner(members []string, key string) string {
+	var best string
+	var bestScore uint64
+	for _, m := range members {
+		h := fnv.New64a()
+		h.Write([]byte(m))
+		h.Write([]byte{0})
+		h.Write([]byte(key))
+		if score := mix(h.Sum64()); best == "" || score > bestScore {
+			best, bestScore = m, score
+		}
+	}
+	return best
+}
+
+// mix is the finalizer of MurmurHash3. FNV hashes of strings sharing a long suffix are too close
+// to be compared on their own.
+func mix(h uint64) uint64 {
+	h ^= h >> 33
+	h *= 0xff51afd7ed558ccd
+	h ^= h >> 33
+	h *= 0xc4ceb9fe1a85ec53
+	h ^= h >> 33
+	return h
+}
+
+func equal(a, b []string) bool {
+	if len(a) != len(b) {
+		return false
+	}
+	for i := range a {
+		if a[i] != b[i] {
+			return false
+		}
+	}
+	return true
+}
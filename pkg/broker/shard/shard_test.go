@@ -0,0 +1,105 @@
+/*
+Copyright 2021 Google LLC
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package shard
+
+import (
+	"fmt"
+	"testing"
+
+	"github.com/google/go-cmp/cmp"
+)
+
+func testKeys(n int) []string {
+	keys := make([]string, n)
+	for i := range keys {
+		keys[i] = fmt.Sprintf("ns/broker-%d", i)
+	}
+	return keys
+}
+
+func TestAssignerOwnsEachKeyOnce(t *testing.T) {
+	members := []string{"pod-a", "pod-b", "pod-c"}
+	assigners := make([]*Assigner, len(members))
+	for i, m := range members {
+		assigners[i] = NewAssigner(m)
+		assigners[i].SetMembers(members)
+	}
+	keys := testKeys(300)
+	owned := make(map[string]int)
+	for _, k := range keys {
+		owners := 0
+		for _, a := range assigners {
+			if a.Owns(k) {
+				owners++
+				owned[a.self]++
+			}
+		}
+		if owners != 1 {
+			t.Errorf("key %q has %d owners, want 1", k, owners)
+		}
+	}
+	for _, m := range members {
+		// Each member should get roughly a third of the keys.
+		if owned[m] < 50 {
+			t.Errorf("member %q owns %d keys, want about 100", m, owned[m])
+		}
+	}
+}
+
+func TestAssignerRebalance(t *testing.T) {
+	a := NewAssigner("pod-a")
+	keys := testKeys(300)
+	for _, k := range keys {
+		if !a.Owns(k) {
+			t.Fatalf("single member does not own key %q", k)
+		}
+	}
+
+	if !a.SetMembers([]string{"pod-a", "pod-b"}) {
+		t.Error("SetMembers() = false after a member joined, want true")
+	}
+	if a.SetMembers([]string{"pod-b"}) {
+		t.Error("SetMembers() = true without change, want false")
+	}
+	before := a.Assignment(keys).Owned
+
+	// A third member only takes keys from the others, so no key moves to pod-a.
+	a.SetMembers([]string{"pod-a", "pod-b", "pod-c"})
+	after := a.Assignment(keys)
+	wasOwned := make(map[string]bool)
+	for _, k := range before {
+		wasOwned[k] = true
+	}
+	for _, k := range after.Owned {
+		if !wasOwned[k] {
+			t.Errorf("key %q moved from another member to pod-a", k)
+		}
+	}
+	if len(after.Owned) >= len(before) {
+		t.Errorf("pod-a owns %d keys after a member joined, want less than %d", len(after.Owned), len(before))
+	}
+	if diff := cmp.Diff([]string{"pod-a", "pod-b", "pod-c"}, after.Members); diff != "" {
+		t.Errorf("Assignment().Members (-want,+got): %v", diff)
+	}
+}
+
+func TestNilAssignerOwnsAllKeys(t *testing.T) {
+	var a *Assigner
+	if !a.Owns("ns/broker") {
+		t.Error("nil Assigner does not own the key")
+	}
+}
@@ -29,3 +29,12 @@ type PubSubable interface {
 	// PubSubStatus returns the PubSubStatus portion of the Status.
 	PubSubStatus() *duckv1.PubSubStatus
 }
+
+// Convertible is implemented by the PubSubables whose messages may be converted into events in a
+// user-defined way.
+type Convertible interface {
+	// EventMapping returns the mapping of the messages into events, nil if there is none.
+	EventMapping() *duckv1.EventMapping
+	// RawPayload returns how the raw payload of the messages is sent, nil if it is wrapped.
+	RawPayload() *duckv1.RawPayload
+}
@@ -18,6 +18,7 @@ package storage
 
 import (
 	"context"
+	"io"
 
 	"cloud.google.com/go/storage"
 )
@@ -45,3 +46,32 @@ func (b *storageBucket) DeleteNotification(ctx context.Context, id string) error
 func (b *storageBucket) Attrs(ctx context.Context) (attrs *storage.BucketAttrs, err error) {
 	return b.handle.Attrs(ctx)
 }
+
+func (b *storageBucket) Object(name string) Object {
+	return &storageObject{handle: b.handle.Object(name)}
+}
+
+func (b *storageBucket) Objects(ctx context.Context, q *storage.Query) ObjectIterator {
+	return b.handle.Objects(ctx, q)
+}
+
+// storageObject wraps storage.ObjectHandle. Is the object that will be used everywhere except unit
+// tests.
+type storageObject struct {
+	handle *storage.ObjectHandle
+}
+
+// Verify that it satisfies the storage.Object interface.
+var _ Object = &storageObject{}
+
+func (o *storageObject) NewWriter(ctx context.Context) io.WriteCloser {
+	return o.handle.NewWriter(ctx)
+}
+
+func (o *storageObject) NewReader(ctx context.Context) (io.ReadCloser, error) {
+	return o.handle.NewReader(ctx)
+}
+
+func (o *storageObject) Delete(ctx context.Context) error {
+	return o.handle.Delete(ctx)
+}
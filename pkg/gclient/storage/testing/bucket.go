@@ -17,10 +17,17 @@ limitations under the License.
 package testing
 
 import (
+	"bytes"
 	"context"
+	"io"
+	"io/ioutil"
+	"sort"
+	"strings"
+	"time"
 
 	. "cloud.google.com/go/storage"
 	"github.com/google/knative-gcp/pkg/gclient/storage"
+	"google.golang.org/api/iterator"
 )
 
 // testBucket is a test Storage bucket.
@@ -37,6 +44,13 @@ type TestBucketData struct {
 	DeleteErr          error
 	Attrs              *BucketAttrs
 	AttrsError         error
+	// Objects holds the content of the objects by name. Objects written to the bucket are added
+	// to it, so it must not be nil to write objects.
+	Objects   map[string][]byte
+	ObjectErr error
+	// Created holds the creation time of the objects by name. Objects without one were created at
+	// the zero time.
+	Created map[string]time.Time
 }
 
 // Verify that it satisfies the storage.Bucket interface.
@@ -65,3 +79,95 @@ func (b *testBucket) DeleteNotification(ctx context.Context, id string) error {
 func (b *testBucket) Attrs(ctx context.Context) (*BucketAttrs, error) {
 	return b.data.Attrs, b.data.AttrsError
 }
+
+// Object implements bucket.Object
+func (b *testBucket) Object(name string) storage.Object {
+	return &testObject{bucket: b, name: name}
+}
+
+// Objects implements bucket.Objects. It lists the Objects of the bucket with the prefix of the
+// query in lexical order.
+func (b *testBucket) Objects(ctx context.Context, q *Query) storage.ObjectIterator {
+	var attrs []*ObjectAttrs
+	for name, data := range b.data.Objects {
+		if q == nil || strings.HasPrefix(name, q.Prefix) {
+			attrs = append(attrs, &ObjectAttrs{Name: name, Size: int64(len(data)), Created: b.data.Created[name]})
+		}
+	}
+	sort.Slice(attrs, func(i, j int) bool { return attrs[i].Name < attrs[j].Name })
+	return &testObjectIterator{attrs: attrs, err: b.data.ObjectErr}
+}
+
+// testObjectIterator is a test Storage object iterator.
+type testObjectIterator struct {
+	attrs []*ObjectAttrs
+	err   error
+}
+
+// Verify that it satisfies the storage.ObjectIterator interface.
+var _ storage.ObjectIterator = &testObjectIterator{}
+
+// Next implements objectIterator.Next
+func (it *testObjectIterator) Next() (*ObjectAttrs, error) {
+	if it.err != nil {
+		return nil, it.err
+	}
+	if len(it.attrs) == 0 {
+		return nil, iterator.Done
+	}
+	next := it.attrs[0]
+	it.attrs = it.attrs[1:]
+	return next, nil
+}
+
+// testObject is a test Storage object.
+type testObject struct {
+	bucket *testBucket
+	name   string
+}
+
+// Verify that it satisfies the storage.Object interface.
+var _ storage.Object = &testObject{}
+
+// NewWriter implements object.NewWriter. The object is added to the Objects of the bucket when
+// the writer is closed.
+func (o *testObject) NewWriter(ctx context.Context) io.WriteCloser {
+	return &testWriter{object: o}
+}
+
+// NewReader implements object.NewReader
+func (o *testObject) NewReader(ctx context.Context) (io.ReadCloser, error) {
+	if o.bucket.data.ObjectErr != nil {
+		return nil, o.bucket.data.ObjectErr
+	}
+	data, ok := o.bucket.data.Objects[o.name]
+	if !ok {
+		return nil, ErrObjectNotExist
+	}
+	return ioutil.NopCloser(bytes.NewReader(data)), nil
+}
+
+// Delete implements object.Delete
+func (o *testObject) Delete(ctx context.Context) error {
+	if o.bucket.data.ObjectErr != nil {
+		return o.bucket.data.ObjectErr
+	}
+	if _, ok := o.bucket.data.Objects[o.name]; !ok {
+		return ErrObjectNotExist
+	}
+	delete(o.bucket.data.Objects, o.name)
+	return nil
+}
+
+type testWriter struct {
+	bytes.Buffer
+	object *testObject
+}
+
+func (w *testWriter) Close() error {
+	if err := w.object.bucket.data.ObjectErr; err != nil {
+		return err
+	}
+	w.object.bucket.data.Objects[w.object.name] = w.Bytes()
+	return nil
+}
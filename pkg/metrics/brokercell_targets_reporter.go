@@ -0,0 +1,91 @@
+/*
+Copyright 2021 Google LLC
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package metrics
+
+import (
+	"context"
+	"fmt"
+	"time"
+
+	"go.opencensus.io/stats"
+	"go.opencensus.io/stats/view"
+	"go.opencensus.io/tag"
+	"knative.dev/pkg/metrics"
+)
+
+const (
+	TargetsConfigLatencyMetricName string = "brokercell_targets_config_latencies"
+
+	// ReconcileTypeFull is the type of the reconciles rebuilding the whole targets config.
+	ReconcileTypeFull = "full"
+	// ReconcileTypeIncremental is the type of the reconciles only updating the changed Brokers
+	// and Channels of the targets config.
+	ReconcileTypeIncremental = "incremental"
+)
+
+// TargetsConfigLatencyReporter reports the latency of the reconciles of the targets config of the
+// BrokerCells, so that full and incremental reconciles can be compared.
+type TargetsConfigLatencyReporter struct {
+	latencyInMsecM *stats.Float64Measure
+}
+
+func (r *TargetsConfigLatencyReporter) register() error {
+	return metrics.RegisterResourceView(
+		&view.View{
+			Name:        r.latencyInMsecM.Name(),
+			Description: r.latencyInMsecM.Description(),
+			Measure:     r.latencyInMsecM,
+			Aggregation: view.Distribution(metrics.Buckets125(1, 100000)...), // 1, 2, 5, 10, 20, 50, 100, 1000, 5000, 10000, 20000, 50000, 1000000
+			TagKeys: []tag.Key{
+				NamespaceNameKey,
+				ResourceNameKey,
+				ReconcileTypeKey,
+			},
+		},
+	)
+}
+
+// NewTargetsConfigLatencyReporter creates a new TargetsConfigLatencyReporter.
+func NewTargetsConfigLatencyReporter() (*TargetsConfigLatencyReporter, error) {
+	r := &TargetsConfigLatencyReporter{
+		latencyInMsecM: stats.Float64(
+			TargetsConfigLatencyMetricName,
+			"The time spent reconciling the targets config of a BrokerCell in milliseconds",
+			stats.UnitMilliseconds,
+		),
+	}
+	if err := r.register(); err != nil {
+		return nil, fmt.Errorf("failed to register TargetsConfigLatencyReporter: %w", err)
+	}
+	return r, nil
+}
+
+// ReportLatency records the latency of a reconcile of the targets config of a BrokerCell.
+// reconcileType is either ReconcileTypeFull or ReconcileTypeIncremental.
+func (r *TargetsConfigLatencyReporter) ReportLatency(ctx context.Context, latency time.Duration, brokerCellName, namespace, reconcileType string) error {
+	tag, err := tag.New(
+		ctx,
+		tag.Insert(NamespaceNameKey, namespace),
+		tag.Insert(ResourceNameKey, brokerCellName),
+		tag.Insert(ReconcileTypeKey, reconcileType),
+	)
+	if err != nil {
+		return fmt.Errorf("failed to create metrics tag: %w", err)
+	}
+	metrics.Record(tag, r.latencyInMsecM.M(float64(latency/time.Millisecond)))
+	return nil
+}
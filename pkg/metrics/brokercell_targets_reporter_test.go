@@ -0,0 +1,50 @@
+/*
+Copyright 2021 Google LLC
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package metrics
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	reportertest "github.com/google/knative-gcp/pkg/metrics/testing"
+
+	"knative.dev/pkg/metrics/metricskey"
+	"knative.dev/pkg/metrics/metricstest"
+)
+
+func TestReportTargetsConfigLatency(t *testing.T) {
+	for _, reconcileType := range []string{ReconcileTypeFull, ReconcileTypeIncremental} {
+		t.Run(reconcileType, func(t *testing.T) {
+			reportertest.ResetBrokerCellMetrics()
+			r, err := NewTargetsConfigLatencyReporter()
+			if err != nil {
+				t.Fatal(err)
+			}
+			for _, latency := range []time.Duration{10 * time.Millisecond, 100 * time.Millisecond, 1000 * time.Millisecond} {
+				reportertest.ExpectMetrics(t, func() error {
+					return r.ReportLatency(context.Background(), latency, "default", "cloud-run-events", reconcileType)
+				})
+			}
+			metricstest.CheckDistributionData(t, TargetsConfigLatencyMetricName, map[string]string{
+				metricskey.LabelNamespaceName: "cloud-run-events",
+				labelResourceName:             "default",
+				labelReconcileType:            reconcileType,
+			}, 3, 10.0, 1000.0)
+		})
+	}
+}
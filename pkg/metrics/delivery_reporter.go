@@ -45,6 +45,7 @@ type DeliveryReporter struct {
 	containerName         ContainerName
 	dispatchTimeInMsecM   *stats.Float64Measure
 	processingTimeInMsecM *stats.Float64Measure
+	circuitStateM         *stats.Int64Measure
 }
 
 func (r *DeliveryReporter) register() error {
@@ -86,6 +87,17 @@ func (r *DeliveryReporter) register() error {
 				ContainerNameKey,
 			},
 		},
+		&view.View{
+			Name:        r.circuitStateM.Name(),
+			Description: r.circuitStateM.Description(),
+			Measure:     r.circuitStateM,
+			Aggregation: view.LastValue(),
+			TagKeys: []tag.Key{
+				TriggerFilterTypeKey,
+				PodNameKey,
+				ContainerNameKey,
+			},
+		},
 	)
 }
 
@@ -108,6 +120,13 @@ func NewDeliveryReporter(podName PodName, containerName ContainerName) (*Deliver
 			"The time spent processing an event before it is dispatched to a Trigger subscriber",
 			stats.UnitMilliseconds,
 		),
+		// circuitStateM records the state of the circuit breaker of a Trigger subscriber:
+		// 0 when closed, 1 when half-open and 2 when open.
+		circuitStateM: stats.Int64(
+			"circuit_breaker_state",
+			"The state of the circuit breaker of a Trigger subscriber, 0 when closed, 1 when half-open and 2 when open",
+			stats.UnitDimensionless,
+		),
 	}
 
 	if err := r.register(); err != nil {
@@ -123,6 +142,22 @@ func (r *DeliveryReporter) ReportEventDispatchTime(ctx context.Context, d time.D
 	metrics.Record(ctx, r.dispatchTimeInMsecM.M(float64(d/time.Millisecond)), stats.WithAttachments(attachments))
 }
 
+// ReportCircuitState captures the state of the circuit breaker of target. It tags the state with
+// the pod, the container and the Trigger itself, since state changes are not tied to the delivery
+// of the event in ctx.
+func (r *DeliveryReporter) ReportCircuitState(ctx context.Context, target *config.Target, state int64) error {
+	ctx, err := r.AddTags(ctx)
+	if err != nil {
+		return err
+	}
+	ctx, err = AddTargetTags(ctx, target)
+	if err != nil {
+		return err
+	}
+	metrics.Record(ctx, r.circuitStateM.M(state))
+	return nil
+}
+
 // StartEventProcessing records the start of event processing for delivery within the given context.
 func StartEventProcessing(ctx context.Context) context.Context {
 	return context.WithValue(ctx, startDeliveryProcessingTime, time.Now())
@@ -25,6 +25,7 @@ import (
 
 	"github.com/google/knative-gcp/pkg/broker/config"
 	reportertest "github.com/google/knative-gcp/pkg/metrics/testing"
+	"go.opencensus.io/resource"
 	"knative.dev/pkg/metrics/metricskey"
 	"knative.dev/pkg/metrics/metricstest"
 )
@@ -130,6 +131,48 @@ func TestReportEventProcessingTime(t *testing.T) {
 	metricstest.CheckDistributionData(t, "event_dispatch_latencies", wantTags, 2, 1100.0, 9100.0)
 }
 
+func TestReportCircuitState(t *testing.T) {
+	reportertest.ResetDeliveryMetrics()
+
+	wantTags := map[string]string{
+		metricskey.LabelFilterType: "testeventtype",
+		metricskey.PodName:         "testpod",
+		metricskey.ContainerName:   "testcontainer",
+	}
+	wantResource := &resource.Resource{
+		Type: metricskey.ResourceTypeKnativeTrigger,
+		Labels: map[string]string{
+			metricskey.LabelNamespaceName: "testns",
+			metricskey.LabelTriggerName:   "testtrigger",
+			metricskey.LabelBrokerName:    "testbroker",
+		},
+	}
+
+	r, err := NewDeliveryReporter("testpod", "testcontainer")
+	if err != nil {
+		t.Fatal(err)
+	}
+	target := &config.Target{
+		Namespace:      "testns",
+		CellTenantType: config.CellTenantType_BROKER,
+		CellTenantName: "testbroker",
+		Name:           "testtrigger",
+		FilterAttributes: map[string]string{
+			"type": "testeventtype",
+		},
+	}
+
+	// The state is reported from a context without any tag.
+	if err := r.ReportCircuitState(context.Background(), target, 2); err != nil {
+		t.Fatal(err)
+	}
+	metricstest.AssertMetric(t, metricstest.IntMetric("circuit_breaker_state", 2, wantTags).WithResource(wantResource))
+	if err := r.ReportCircuitState(context.Background(), target, 0); err != nil {
+		t.Fatal(err)
+	}
+	metricstest.AssertMetric(t, metricstest.IntMetric("circuit_breaker_state", 0, wantTags).WithResource(wantResource))
+}
+
 func TestMetricsWithEmptySourceAndTypeFilter(t *testing.T) {
 	reportertest.ResetDeliveryMetrics()
 
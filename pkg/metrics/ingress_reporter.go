@@ -55,6 +55,29 @@ func (r *IngressReporter) register() error {
 			Aggregation: view.Count(),
 			TagKeys:     tagKeys,
 		},
+		&view.View{
+			Name:        r.droppedEventCountM.Name(),
+			Description: r.droppedEventCountM.Description(),
+			Measure:     r.droppedEventCountM,
+			Aggregation: view.Count(),
+			TagKeys: []tag.Key{
+				EventTypeKey,
+				PodNameKey,
+				ContainerNameKey,
+			},
+		},
+		&view.View{
+			Name:        r.rejectedRequestCountM.Name(),
+			Description: r.rejectedRequestCountM.Description(),
+			Measure:     r.rejectedRequestCountM,
+			Aggregation: view.Count(),
+			TagKeys: []tag.Key{
+				ResponseCodeKey,
+				ResponseCodeClassKey,
+				PodNameKey,
+				ContainerNameKey,
+			},
+		},
 	)
 }
 
@@ -68,6 +91,16 @@ func NewIngressReporter(podName PodName, containerName ContainerName) (*IngressR
 			"Number of events received by a Broker",
 			stats.UnitDimensionless,
 		),
+		droppedEventCountM: stats.Int64(
+			"event_dropped_count",
+			"Number of events dropped by a Broker because none of its Triggers is interested in them",
+			stats.UnitDimensionless,
+		),
+		rejectedRequestCountM: stats.Int64(
+			"request_rejected_count",
+			"Number of requests rejected by a Broker because the caller is not authenticated or not allowed",
+			stats.UnitDimensionless,
+		),
 	}
 	if err := r.register(); err != nil {
 		return nil, fmt.Errorf("failed to register ingress stats: %w", err)
@@ -80,6 +113,10 @@ type IngressReporter struct {
 	podName       PodName
 	containerName ContainerName
 	eventCountM   *stats.Int64Measure
+	// droppedEventCountM counts the events filtered out at ingress.
+	droppedEventCountM *stats.Int64Measure
+	// rejectedRequestCountM counts the requests rejected by the ingress authentication.
+	rejectedRequestCountM *stats.Int64Measure
 }
 
 func (r *IngressReporter) ReportEventCount(ctx context.Context, args IngressReportArgs) error {
@@ -98,3 +135,32 @@ func (r *IngressReporter) ReportEventCount(ctx context.Context, args IngressRepo
 	)
 	return nil
 }
+
+// ReportDroppedEventCount records an event that was dropped because no trigger of its broker is
+// interested in it.
+func (r *IngressReporter) ReportDroppedEventCount(ctx context.Context, eventType string) error {
+	metrics.Record(
+		ctx, r.droppedEventCountM.M(1),
+		stats.WithTags(
+			tag.Insert(PodNameKey, string(r.podName)),
+			tag.Insert(ContainerNameKey, string(r.containerName)),
+			tag.Insert(EventTypeKey, EventTypeMetricValue(eventType)),
+		),
+	)
+	return nil
+}
+
+// ReportRejectedRequestCount records a request rejected by the ingress authentication of its
+// broker with the given response code.
+func (r *IngressReporter) ReportRejectedRequestCount(ctx context.Context, responseCode int) error {
+	metrics.Record(
+		ctx, r.rejectedRequestCountM.M(1),
+		stats.WithTags(
+			tag.Insert(PodNameKey, string(r.podName)),
+			tag.Insert(ContainerNameKey, string(r.containerName)),
+			tag.Insert(ResponseCodeKey, strconv.Itoa(responseCode)),
+			tag.Insert(ResponseCodeClassKey, metrics.ResponseCodeClass(responseCode)),
+		),
+	)
+	return nil
+}
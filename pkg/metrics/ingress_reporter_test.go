@@ -86,3 +86,47 @@ func TestStatsReporterWithCustomEventType(t *testing.T) {
 	})
 	metricstest.CheckCountData(t, "event_count", wantTags, 2)
 }
+
+func TestStatsReporterDroppedEventCount(t *testing.T) {
+	reportertest.ResetIngressMetrics()
+
+	wantTags := map[string]string{
+		metricskey.LabelEventType: "google.cloud.scheduler.job.v1.executed",
+		metricskey.ContainerName:  "testcontainer",
+		metricskey.PodName:        "testpod",
+	}
+
+	r, err := NewIngressReporter(PodName("testpod"), ContainerName("testcontainer"))
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	reportertest.ExpectMetrics(t, func() error {
+		return r.ReportDroppedEventCount(context.Background(), "google.cloud.scheduler.job.v1.executed")
+	})
+	metricstest.CheckCountData(t, "event_dropped_count", wantTags, 1)
+}
+
+func TestStatsReporterRejectedRequestCount(t *testing.T) {
+	reportertest.ResetIngressMetrics()
+
+	wantTags := map[string]string{
+		metricskey.LabelResponseCode:      "403",
+		metricskey.LabelResponseCodeClass: "4xx",
+		metricskey.ContainerName:          "testcontainer",
+		metricskey.PodName:                "testpod",
+	}
+
+	r, err := NewIngressReporter(PodName("testpod"), ContainerName("testcontainer"))
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	reportertest.ExpectMetrics(t, func() error {
+		return r.ReportRejectedRequestCount(context.Background(), 403)
+	})
+	reportertest.ExpectMetrics(t, func() error {
+		return r.ReportRejectedRequestCount(context.Background(), 403)
+	})
+	metricstest.CheckCountData(t, "request_rejected_count", wantTags, 2)
+}
@@ -27,6 +27,8 @@ const (
 	defaultEventType  = "custom"
 	labelResourceKind = "resource_kind"
 	labelResourceName = "resource_name"
+
+	labelReconcileType = "reconcile_type"
 )
 
 type PodName string
@@ -44,6 +46,7 @@ var (
 	EventTypeKey         = tag.MustNewKey(metricskey.LabelEventType)
 	ResourceKindKey      = tag.MustNewKey(labelResourceKind)
 	ResourceNameKey      = tag.MustNewKey(labelResourceName)
+	ReconcileTypeKey     = tag.MustNewKey(labelReconcileType)
 	TriggerNameKey       = tag.MustNewKey(metricskey.LabelTriggerName)
 	TriggerFilterTypeKey = tag.MustNewKey(metricskey.LabelFilterType)
 
@@ -8,17 +8,17 @@ import (
 
 func ResetIngressMetrics() {
 	// OpenCensus metrics carry global state that need to be reset between unit tests.
-	metricstest.Unregister("event_count", "event_dispatch_latencies")
+	metricstest.Unregister("event_count", "event_dropped_count", "request_rejected_count", "event_dispatch_latencies")
 }
 
 func ResetDeliveryMetrics() {
 	// OpenCensus metrics carry global state that need to be reset between unit tests.
-	metricstest.Unregister("event_count", "event_dispatch_latencies", "event_processing_latencies")
+	metricstest.Unregister("event_count", "event_dispatch_latencies", "event_processing_latencies", "circuit_breaker_state")
 }
 
 func ResetBrokerCellMetrics() {
 	// OpenCensus metrics carry global state that need to be reset between unit tests.
-	metricstest.Unregister("brokercell_delay")
+	metricstest.Unregister("brokercell_delay", "brokercell_targets_config_latencies")
 }
 
 func ExpectMetrics(t *testing.T, f func() error) {
@@ -18,6 +18,8 @@ package adapter
 
 import (
 	"context"
+	"io"
+	"io/ioutil"
 	nethttp "net/http"
 
 	"go.uber.org/zap"
@@ -38,6 +40,7 @@ import (
 	"github.com/google/knative-gcp/pkg/tracing"
 	"github.com/google/knative-gcp/pkg/utils/authcheck"
 	"github.com/google/knative-gcp/pkg/utils/clients"
+	"github.com/google/knative-gcp/pkg/utils/deadletter"
 )
 
 // AdapterArgs has a bundle of arguments needed to create an Adapter.
@@ -52,12 +55,24 @@ type AdapterArgs struct {
 	// Used for channels.
 	TransformerURI string
 
+	// DeadLetterSinkURI is the URI where the messages that can't be converted into events, and
+	// the events the sink failed to accept DeadLetterRetry times, are sent. Optional.
+	DeadLetterSinkURI string
+
+	// DeadLetterRetry is the number of failed delivery attempts after which an event is sent to
+	// the dead letter sink. It is only used with a DeadLetterSinkURI.
+	DeadLetterRetry int32
+
 	// Extensions is the converted ExtensionsBased64 value.
 	Extensions map[string]string
 
 	// ConverterType use to select which converter to use.
 	ConverterType converters.ConverterType
 
+	// Converter converts the messages instead of the converter of ConverterType, if set. E.g.,
+	// the converter of a user-defined mapping, or of the raw payload mode.
+	Converter converters.Converter
+
 	// AuthType is the authentication configuration mode the Pod uses.
 	AuthType authcheck.AuthType
 }
@@ -92,6 +107,10 @@ type Adapter struct {
 	// cancel is function to stop pulling messages.
 	cancel context.CancelFunc
 
+	// attempts counts the failed delivery attempts of the messages. It is only used with a dead
+	// letter sink.
+	attempts *deadletter.Tracker
+
 	logger *zap.Logger
 }
 
@@ -116,6 +135,7 @@ func NewAdapter(
 		converter:      converter,
 		reporter:       reporter,
 		args:           args,
+		attempts:       deadletter.NewTracker(),
 		logger:         logging.FromContext(ctx),
 	}
 }
@@ -123,14 +143,19 @@ func NewAdapter(
 func (a *Adapter) Start(ctx context.Context) error {
 	ctx, a.cancel = context.WithCancel(ctx)
 
+	// Initialize probe checker to run authentication check.
+	pc := authcheck.NewProbeChecker(logging.FromContext(ctx), a.args.AuthType)
+	go pc.Start(ctx)
+	return a.Receive(ctx)
+}
+
+// Receive delivers the messages of the subscription until the context is done. Unlike Start, it
+// doesn't serve the authentication probe, so that many adapters can run in the same pod.
+func (a *Adapter) Receive(ctx context.Context) error {
 	// Augment context so that we can use it to create CE attributes.
 	ctx = WithProjectKey(ctx, a.projectID)
 	ctx = WithTopicKey(ctx, a.args.TopicID)
 	ctx = WithSubscriptionKey(ctx, a.subscription.ID())
-
-	// Initialize probe checker to run authentication check.
-	pc := authcheck.NewProbeChecker(logging.FromContext(ctx), a.args.AuthType)
-	go pc.Start(ctx)
 	return a.subscription.Receive(ctx, a.receive)
 }
 
@@ -142,11 +167,10 @@ func (a *Adapter) Stop() {
 // TODO refactor this method. As our RA code is used both for Sources and our Channel, it also supports replies
 //  (in the case of Channels) and the logic is more convoluted.
 func (a *Adapter) receive(ctx context.Context, msg *pubsub.Message) {
-	event, err := a.converter.Convert(ctx, msg, a.args.ConverterType)
+	event, err := a.convert(ctx, msg)
 	if err != nil {
 		a.logger.Debug("Failed to convert received message to an event, check the msg format: %v", zap.Error(err))
-		// Ack the message so it won't be retried, we consider all errors to be non-retryable.
-		msg.Ack()
+		a.handleConversionFailure(ctx, msg, err)
 		return
 	}
 
@@ -169,7 +193,7 @@ func (a *Adapter) receive(ctx context.Context, msg *pubsub.Message) {
 		resp, err := a.sendMsg(ctx, a.args.TransformerURI, (*binding.EventMessage)(event))
 		if err != nil {
 			a.logger.Error("Failed to send message to transformer", zap.String("address", a.args.TransformerURI), zap.Error(err))
-			msg.Nack()
+			a.nack(ctx, msg)
 			return
 		}
 
@@ -183,14 +207,14 @@ func (a *Adapter) receive(ctx context.Context, msg *pubsub.Message) {
 
 		if resp.StatusCode/100 != 2 {
 			a.logger.Error("Event delivery failed", zap.Int("StatusCode", resp.StatusCode))
-			msg.Nack()
+			a.nack(ctx, msg)
 			return
 		}
 
 		respMsg := cehttp.NewMessageFromHttpResponse(resp)
 		if respMsg.ReadEncoding() == binding.EncodingUnknown {
 			// No reply
-			msg.Ack()
+			a.ack(ctx, msg)
 			return
 		}
 
@@ -200,7 +224,7 @@ func (a *Adapter) receive(ctx context.Context, msg *pubsub.Message) {
 		if err != nil {
 			a.logger.Error("Failed to convert response message to event",
 				zap.Any("response", respMsg), zap.Error(err))
-			msg.Nack()
+			a.nack(ctx, msg)
 			return
 		}
 
@@ -223,7 +247,7 @@ func (a *Adapter) receive(ctx context.Context, msg *pubsub.Message) {
 	response, err := a.sendMsg(ctx, a.args.SinkURI, (*binding.EventMessage)(event))
 	if err != nil {
 		a.logger.Error("Failed to send message to sink", zap.String("address", a.args.SinkURI), zap.Error(err))
-		msg.Nack()
+		a.handleDeliveryFailure(ctx, msg, event, 0, []byte(err.Error()))
 		return
 	}
 
@@ -237,11 +261,47 @@ func (a *Adapter) receive(ctx context.Context, msg *pubsub.Message) {
 
 	if response.StatusCode/100 != 2 {
 		a.logger.Error("Event delivery failed", zap.Int("StatusCode", response.StatusCode))
-		msg.Nack()
+		data, _ := ioutil.ReadAll(io.LimitReader(response.Body, deadletter.MaxErrorDataSize))
+		a.handleDeliveryFailure(ctx, msg, event, response.StatusCode, data)
 		return
 	}
 
+	if a.args.DeadLetterSinkURI != "" {
+		a.attempts.Forget(msg.ID)
+	}
+	a.ack(ctx, msg)
+}
+
+// ack acknowledges the message. With exactly-once delivery, it waits for Cloud Pub/Sub to confirm
+// the acknowledgement, which may fail, e.g. if the ack deadline of the message expired and it is
+// redelivered. Without exactly-once delivery, the acknowledgement is not confirmed.
+func (a *Adapter) ack(ctx context.Context, msg *pubsub.Message) {
+	result := msg.AckWithResult()
+	// Without exactly-once delivery, AckWithResult doesn't acknowledge the message. With it, Ack
+	// does nothing more.
 	msg.Ack()
+	if _, err := result.Get(ctx); err != nil {
+		a.logger.Warn("Failed to acknowledge the message, it may be redelivered", zap.String("messageID", msg.ID), zap.Error(err))
+	}
+}
+
+// nack negatively acknowledges the message so that Cloud Pub/Sub redelivers it. If the negative
+// acknowledgement fails, the message is redelivered once its ack deadline expires.
+func (a *Adapter) nack(ctx context.Context, msg *pubsub.Message) {
+	result := msg.NackWithResult()
+	// As with ack, NackWithResult doesn't nack the message without exactly-once delivery.
+	msg.Nack()
+	if _, err := result.Get(ctx); err != nil {
+		a.logger.Warn("Failed to nack the message", zap.String("messageID", msg.ID), zap.Error(err))
+	}
+}
+
+// convert converts the message into an event, with the user-defined converter if there is one.
+func (a *Adapter) convert(ctx context.Context, msg *pubsub.Message) (*cev2.Event, error) {
+	if a.args.Converter != nil {
+		return a.args.Converter.Convert(ctx, msg, a.args.ConverterType)
+	}
+	return a.converter.Convert(ctx, msg, a.args.ConverterType)
 }
 
 func (a *Adapter) sendMsg(ctx context.Context, address string, msg binding.Message) (*nethttp.Response, error) {
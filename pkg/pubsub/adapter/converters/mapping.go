@@ -0,0 +1,253 @@
+/*
+Copyright 2021 Google LLC
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package converters
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"sync"
+	"time"
+
+	"cloud.google.com/go/pubsub"
+	cev2 "github.com/cloudevents/sdk-go/v2"
+	"k8s.io/client-go/util/jsonpath"
+
+	gcpduckv1 "github.com/google/knative-gcp/pkg/apis/duck/v1"
+)
+
+// MappingConverter converts messages into events as defined by an EventMapping, regardless of the
+// converter type.
+type MappingConverter struct {
+	input           func(*pubsub.Message) map[string]interface{}
+	eventType       value
+	source          value
+	subject         value
+	id              value
+	extensions      map[string]value
+	data            value
+	dataContentType string
+}
+
+var _ Converter = (*MappingConverter)(nil)
+
+// value is a compiled value of a mapping.
+type value interface {
+	// execute returns the text of the value for the input.
+	execute(input map[string]interface{}) (string, error)
+	// find returns the single value the data value refers to in the input.
+	find(input map[string]interface{}) (interface{}, error)
+}
+
+// NewMappingConverter compiles the values of the mapping into a converter.
+func NewMappingConverter(mapping *gcpduckv1.EventMapping) (*MappingConverter, error) {
+	c := &MappingConverter{
+		input:           mappingInput,
+		extensions:      make(map[string]value, len(mapping.Extensions)),
+		dataContentType: mapping.DataContentType,
+	}
+	compile := func(name, text string, allowMissingKeys, _ bool) (value, error) {
+		return compileTemplate(name, text, allowMissingKeys)
+	}
+	switch mapping.Language {
+	case "", gcpduckv1.MappingLanguageJSONPath:
+	case gcpduckv1.MappingLanguageCEL:
+		env, err := gcpduckv1.NewEventMappingCELEnv()
+		if err != nil {
+			return nil, err
+		}
+		c.input = celInput
+		compile = func(name, text string, allowMissingKeys, data bool) (value, error) {
+			return compileExpression(env, name, text, allowMissingKeys, data)
+		}
+	default:
+		return nil, fmt.Errorf("unknown mapping language %q", mapping.Language)
+	}
+	var err error
+	if c.eventType, err = compile("type", mapping.Type, false, false); err != nil {
+		return nil, err
+	}
+	if c.source, err = compile("source", mapping.Source, false, false); err != nil {
+		return nil, err
+	}
+	if c.subject, err = compile("subject", mapping.Subject, true, false); err != nil {
+		return nil, err
+	}
+	if c.id, err = compile("id", mapping.ID, false, false); err != nil {
+		return nil, err
+	}
+	for name, text := range mapping.Extensions {
+		if c.extensions[name], err = compile(name, text, true, false); err != nil {
+			return nil, err
+		}
+	}
+	if c.data, err = compile("data", mapping.Data, false, true); err != nil {
+		return nil, err
+	}
+	if c.data != nil && c.dataContentType == "" {
+		c.dataContentType = cev2.ApplicationJSON
+	}
+	return c, nil
+}
+
+// Convert converts the message into an event. It fails if a required value refers to a field that
+// the message doesn't have.
+func (c *MappingConverter) Convert(ctx context.Context, msg *pubsub.Message, _ ConverterType) (*cev2.Event, error) {
+	if msg == nil {
+		return nil, fmt.Errorf("nil pubsub message")
+	}
+	input := c.input(msg)
+
+	event := cev2.NewEvent(cev2.VersionV1)
+	event.SetID(msg.ID)
+	event.SetTime(msg.PublishTime)
+	eventType, err := c.eventType.execute(input)
+	if err != nil {
+		return nil, err
+	}
+	event.SetType(eventType)
+	source, err := c.source.execute(input)
+	if err != nil {
+		return nil, err
+	}
+	event.SetSource(source)
+	if c.subject != nil {
+		subject, err := c.subject.execute(input)
+		if err != nil {
+			return nil, err
+		}
+		if subject != "" {
+			event.SetSubject(subject)
+		}
+	}
+	if c.id != nil {
+		id, err := c.id.execute(input)
+		if err != nil {
+			return nil, err
+		}
+		event.SetID(id)
+	}
+	for name, t := range c.extensions {
+		value, err := t.execute(input)
+		if err != nil {
+			return nil, err
+		}
+		if value != "" {
+			event.SetExtension(name, value)
+		}
+	}
+
+	data := msg.Data
+	if c.data != nil {
+		value, err := c.data.find(input)
+		if err != nil {
+			return nil, err
+		}
+		if data, err = json.Marshal(value); err != nil {
+			return nil, err
+		}
+	}
+	if err := event.SetData(c.dataContentType, data); err != nil {
+		return nil, err
+	}
+	if err := event.Validate(); err != nil {
+		return nil, err
+	}
+	return &event, nil
+}
+
+// mappingInput returns the message as the JSONPath templates see it.
+func mappingInput(msg *pubsub.Message) map[string]interface{} {
+	attributes := make(map[string]interface{}, len(msg.Attributes))
+	for k, v := range msg.Attributes {
+		attributes[k] = v
+	}
+	var data interface{} = string(msg.Data)
+	if json.Valid(msg.Data) {
+		// Numbers are kept as they are written rather than converted to floats.
+		decoder := json.NewDecoder(bytes.NewReader(msg.Data))
+		decoder.UseNumber()
+		if err := decoder.Decode(&data); err != nil {
+			data = string(msg.Data)
+		}
+	}
+	return map[string]interface{}{
+		"id":          msg.ID,
+		"publishTime": msg.PublishTime.Format(time.RFC3339Nano),
+		"orderingKey": msg.OrderingKey,
+		"attributes":  attributes,
+		"data":        data,
+	}
+}
+
+// template is a compiled JSONPath template. JSONPath keeps state while it is executed, so each
+// concurrent execution uses a copy of its own.
+type template struct {
+	pool sync.Pool
+}
+
+// compileTemplate compiles the text of a template. It returns nil if the text is empty.
+func compileTemplate(name, text string, allowMissingKeys bool) (value, error) {
+	if text == "" {
+		return nil, nil
+	}
+	parse := func() (*jsonpath.JSONPath, error) {
+		j := jsonpath.New(name).AllowMissingKeys(allowMissingKeys)
+		if err := j.Parse(text); err != nil {
+			return nil, fmt.Errorf("invalid %s template %q: %w", name, text, err)
+		}
+		return j, nil
+	}
+	j, err := parse()
+	if err != nil {
+		return nil, err
+	}
+	t := &template{}
+	t.pool.New = func() interface{} {
+		// The template was already parsed successfully.
+		j, _ := parse()
+		return j
+	}
+	t.pool.Put(j)
+	return t, nil
+}
+
+// execute returns the text of the template for the input.
+func (t *template) execute(input map[string]interface{}) (string, error) {
+	j := t.pool.Get().(*jsonpath.JSONPath)
+	defer t.pool.Put(j)
+	var buf bytes.Buffer
+	if err := j.Execute(&buf, input); err != nil {
+		return "", err
+	}
+	return buf.String(), nil
+}
+
+// find returns the single value the template refers to in the input.
+func (t *template) find(input map[string]interface{}) (interface{}, error) {
+	j := t.pool.Get().(*jsonpath.JSONPath)
+	defer t.pool.Put(j)
+	results, err := j.FindResults(input)
+	if err != nil {
+		return nil, err
+	}
+	if len(results) != 1 || len(results[0]) != 1 {
+		return nil, fmt.Errorf("the data template must refer to a single value")
+	}
+	return results[0][0].Interface(), nil
+}
@@ -0,0 +1,178 @@
+/*
+Copyright 2021 Google LLC
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package converters
+
+import (
+	"encoding/json"
+	"fmt"
+	"reflect"
+
+	"cloud.google.com/go/pubsub"
+	"github.com/google/cel-go/cel"
+	"github.com/google/cel-go/common/types"
+	"github.com/google/cel-go/common/types/ref"
+	"github.com/google/cel-go/common/types/traits"
+	"google.golang.org/protobuf/types/known/structpb"
+
+	gcpduckv1 "github.com/google/knative-gcp/pkg/apis/duck/v1"
+)
+
+var (
+	stringType    = reflect.TypeOf("")
+	jsonValueType = reflect.TypeOf(&structpb.Value{})
+)
+
+// expression is a compiled CEL expression. Unlike JSONPath templates, CEL programs can be
+// evaluated concurrently.
+type expression struct {
+	name             string
+	program          cel.Program
+	allowMissingKeys bool
+}
+
+// compileExpression compiles the text of a CEL expression. It returns nil if the text is empty.
+func compileExpression(env *cel.Env, name, text string, allowMissingKeys, data bool) (value, error) {
+	if text == "" {
+		return nil, nil
+	}
+	ast, err := gcpduckv1.CompileEventMappingExpression(env, text, data)
+	if err != nil {
+		return nil, fmt.Errorf("invalid %s expression %q: %w", name, text, err)
+	}
+	program, err := env.Program(ast)
+	if err != nil {
+		return nil, fmt.Errorf("invalid %s expression %q: %w", name, text, err)
+	}
+	return &expression{name: name, program: program, allowMissingKeys: allowMissingKeys}, nil
+}
+
+// execute returns the string the expression evaluates to for the input. If missing keys are
+// allowed, it returns an empty string when the evaluation failed after looking up a key missing
+// from a map of the input.
+func (e *expression) execute(input map[string]interface{}) (string, error) {
+	missing := &missingKeys{}
+	out, _, err := e.program.Eval(missing.activation(input))
+	if err != nil {
+		if e.allowMissingKeys && missing.found {
+			return "", nil
+		}
+		return "", fmt.Errorf("failed to evaluate the %s expression: %w", e.name, err)
+	}
+	v, err := out.ConvertToNative(stringType)
+	if err != nil {
+		return "", fmt.Errorf("the %s expression must evaluate to a string: %w", e.name, err)
+	}
+	return v.(string), nil
+}
+
+// find returns the JSON value the expression evaluates to for the input.
+func (e *expression) find(input map[string]interface{}) (interface{}, error) {
+	out, _, err := e.program.Eval(input)
+	if err != nil {
+		return nil, fmt.Errorf("failed to evaluate the %s expression: %w", e.name, err)
+	}
+	v, err := out.ConvertToNative(jsonValueType)
+	if err != nil {
+		return nil, fmt.Errorf("the %s expression must evaluate to a JSON value: %w", e.name, err)
+	}
+	return v.(*structpb.Value).AsInterface(), nil
+}
+
+// missingKeys records whether an evaluation looked up a key missing from a map of its input.
+// Testing a key with has() or the in operator is not a lookup.
+type missingKeys struct {
+	found bool
+}
+
+// activation returns the input of an evaluation with its maps and lists tracked.
+func (m *missingKeys) activation(input map[string]interface{}) map[string]interface{} {
+	vars := make(map[string]interface{}, len(input))
+	for k, v := range input {
+		vars[k] = m.track(types.DefaultTypeAdapter.NativeToValue(v))
+	}
+	return vars
+}
+
+// track returns v recording the keys missing from it and from the values it contains.
+func (m *missingKeys) track(v ref.Val) ref.Val {
+	switch v := v.(type) {
+	case traits.Mapper:
+		return trackedMap{Mapper: v, missing: m}
+	case traits.Lister:
+		return trackedList{Lister: v, missing: m}
+	}
+	return v
+}
+
+// trackedMap is a map of the input of an evaluation.
+type trackedMap struct {
+	traits.Mapper
+	missing *missingKeys
+}
+
+// Find implements traits.Mapper.
+func (t trackedMap) Find(key ref.Val) (ref.Val, bool) {
+	v, found := t.Mapper.Find(key)
+	if !found {
+		if v == nil {
+			t.missing.found = true
+		}
+		return v, false
+	}
+	return t.missing.track(v), true
+}
+
+// Get implements traits.Indexer.
+func (t trackedMap) Get(key ref.Val) ref.Val {
+	if t.Mapper.Contains(key) == types.False {
+		t.missing.found = true
+	}
+	return t.missing.track(t.Mapper.Get(key))
+}
+
+// trackedList is a list of the input of an evaluation, which may contain maps.
+type trackedList struct {
+	traits.Lister
+	missing *missingKeys
+}
+
+// Get implements traits.Indexer.
+func (t trackedList) Get(index ref.Val) ref.Val {
+	return t.missing.track(t.Lister.Get(index))
+}
+
+// celInput returns the message as the CEL expressions see it. As in CEL, JSON numbers are
+// doubles.
+func celInput(msg *pubsub.Message) map[string]interface{} {
+	attributes := make(map[string]string, len(msg.Attributes))
+	for k, v := range msg.Attributes {
+		attributes[k] = v
+	}
+	var data interface{} = string(msg.Data)
+	if json.Valid(msg.Data) {
+		if err := json.Unmarshal(msg.Data, &data); err != nil {
+			data = string(msg.Data)
+		}
+	}
+	return map[string]interface{}{
+		"id":          msg.ID,
+		"publishTime": msg.PublishTime,
+		"orderingKey": msg.OrderingKey,
+		"attributes":  attributes,
+		"data":        data,
+	}
+}
@@ -0,0 +1,209 @@
+/*
+Copyright 2021 Google LLC
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package converters
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"cloud.google.com/go/pubsub"
+	cev2 "github.com/cloudevents/sdk-go/v2"
+	"github.com/google/go-cmp/cmp"
+
+	gcpduckv1 "github.com/google/knative-gcp/pkg/apis/duck/v1"
+)
+
+func TestMappingConverter(t *testing.T) {
+	publishTime := time.Date(2021, 3, 4, 5, 6, 7, 0, time.UTC)
+	orderMessage := &pubsub.Message{
+		ID:          "id",
+		PublishTime: publishTime,
+		Data:        []byte(`{"order":{"id":12345678901234567890,"items":["book"]}}`),
+		Attributes: map[string]string{
+			"action":  "created",
+			"region":  "eu",
+			"content": "ignored",
+		},
+	}
+
+	tests := []struct {
+		name        string
+		mapping     *gcpduckv1.EventMapping
+		message     *pubsub.Message
+		wantEventFn func() *cev2.Event
+		wantErr     bool
+	}{{
+		name: "attributes and JSON fields",
+		mapping: &gcpduckv1.EventMapping{
+			Type:       "com.example.order.{.attributes.action}",
+			Source:     "//orders/{.attributes.region}",
+			Subject:    "{.data.order.id}",
+			Extensions: map[string]string{"region": "{.attributes.region}", "missing": "{.attributes.missing}"},
+		},
+		message: orderMessage,
+		wantEventFn: func() *cev2.Event {
+			e := mappedEvent("com.example.order.created", "//orders/eu", "", orderMessage.Data)
+			e.SetSubject("12345678901234567890")
+			e.SetExtension("region", "eu")
+			return e
+		},
+	}, {
+		name: "data selected from the payload",
+		mapping: &gcpduckv1.EventMapping{
+			Type:   "com.example.order",
+			Source: "//orders",
+			ID:     "{.data.order.id}",
+			Data:   "{.data.order.items}",
+		},
+		message: orderMessage,
+		wantEventFn: func() *cev2.Event {
+			e := mappedEvent("com.example.order", "//orders", cev2.ApplicationJSON, []byte(`["book"]`))
+			e.SetID("12345678901234567890")
+			return e
+		},
+	}, {
+		name: "non JSON payload",
+		mapping: &gcpduckv1.EventMapping{
+			Type:            "com.example.text",
+			Source:          "{.data}",
+			DataContentType: "text/plain",
+		},
+		message: &pubsub.Message{ID: "id", PublishTime: publishTime, Data: []byte("hello")},
+		wantEventFn: func() *cev2.Event {
+			return mappedEvent("com.example.text", "hello", "text/plain", []byte("hello"))
+		},
+	}, {
+		name: "missing required field",
+		mapping: &gcpduckv1.EventMapping{
+			Type:   "com.example.order.{.attributes.missing}",
+			Source: "//orders",
+		},
+		message: orderMessage,
+		wantErr: true,
+	}, {
+		name: "empty type",
+		mapping: &gcpduckv1.EventMapping{
+			Type:   "{.orderingKey}",
+			Source: "//orders",
+		},
+		message: orderMessage,
+		wantErr: true,
+	}, {
+		name: "CEL expressions",
+		mapping: &gcpduckv1.EventMapping{
+			Language: gcpduckv1.MappingLanguageCEL,
+			Type:     `"com.example.order." + attributes.action`,
+			Source:   `attributes.region == "eu" ? "//orders/europe" : "//orders"`,
+			Subject:  "data.order.items[0]",
+			ID:       `id + "-" + string(publishTime.getFullYear())`,
+			Extensions: map[string]string{
+				"region":   "attributes.region",
+				"missing":  "attributes.missing",
+				"customer": "data.order.customer.name",
+				"buyer":    `has(data.order.customer) ? data.order.customer.name : "anonymous"`,
+			},
+			Data: `{"item": data.order.items[0], "action": attributes.action}`,
+		},
+		message: orderMessage,
+		wantEventFn: func() *cev2.Event {
+			e := mappedEvent("com.example.order.created", "//orders/europe", cev2.ApplicationJSON, []byte(`{"action":"created","item":"book"}`))
+			e.SetSubject("book")
+			e.SetID("id-2021")
+			e.SetExtension("region", "eu")
+			e.SetExtension("buyer", "anonymous")
+			return e
+		},
+	}, {
+		name: "CEL expression not evaluating to a string",
+		mapping: &gcpduckv1.EventMapping{
+			Language: gcpduckv1.MappingLanguageCEL,
+			Type:     "data.order.id",
+			Source:   `"//orders"`,
+		},
+		message: orderMessage,
+		wantErr: true,
+	}, {
+		name: "CEL expression referring to a missing required field",
+		mapping: &gcpduckv1.EventMapping{
+			Language: gcpduckv1.MappingLanguageCEL,
+			Type:     "attributes.missing",
+			Source:   `"//orders"`,
+		},
+		message: orderMessage,
+		wantErr: true,
+	}, {
+		name: "CEL expression of an optional field failing without a missing field",
+		mapping: &gcpduckv1.EventMapping{
+			Language: gcpduckv1.MappingLanguageCEL,
+			Type:     `"com.example.order"`,
+			Source:   `"//orders"`,
+			Subject:  "string(1 / (size(attributes) - 3))",
+		},
+		message: orderMessage,
+		wantErr: true,
+	}}
+
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			c, err := NewMappingConverter(test.mapping)
+			if err != nil {
+				t.Fatalf("NewMappingConverter failed: %v", err)
+			}
+			gotEvent, err := c.Convert(context.Background(), test.message, CloudPubSub)
+			if err != nil {
+				if !test.wantErr {
+					t.Errorf("MappingConverter.Convert got error %v want error=%v", err, test.wantErr)
+				}
+				return
+			}
+			if test.wantErr {
+				t.Fatalf("MappingConverter.Convert got event %v, want error", gotEvent)
+			}
+			if diff := cmp.Diff(test.wantEventFn(), gotEvent); diff != "" {
+				t.Errorf("MappingConverter.Convert got unexpected cloudevents.Event (-want +got) %s", diff)
+			}
+		})
+	}
+}
+
+func TestNewMappingConverterInvalidTemplate(t *testing.T) {
+	if _, err := NewMappingConverter(&gcpduckv1.EventMapping{Type: "{.attributes", Source: "//orders"}); err == nil {
+		t.Error("NewMappingConverter got no error for an invalid template")
+	}
+}
+
+func TestNewMappingConverterInvalidExpression(t *testing.T) {
+	for _, mapping := range []*gcpduckv1.EventMapping{
+		{Language: gcpduckv1.MappingLanguageCEL, Type: `"com.example" +`, Source: `"//orders"`},
+		{Language: gcpduckv1.MappingLanguageCEL, Type: "size(attributes)", Source: `"//orders"`},
+	} {
+		if _, err := NewMappingConverter(mapping); err == nil {
+			t.Errorf("NewMappingConverter got no error for the invalid expression %q", mapping.Type)
+		}
+	}
+}
+
+func mappedEvent(eventType, source, contentType string, data []byte) *cev2.Event {
+	e := cev2.NewEvent(cev2.VersionV1)
+	e.SetID("id")
+	e.SetTime(time.Date(2021, 3, 4, 5, 6, 7, 0, time.UTC))
+	e.SetType(eventType)
+	e.SetSource(source)
+	e.SetData(contentType, data)
+	return &e
+}
@@ -0,0 +1,137 @@
+/*
+Copyright 2021 Google LLC
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package converters
+
+import (
+	"context"
+	"fmt"
+	"sort"
+	"strings"
+
+	"cloud.google.com/go/pubsub"
+	cev2 "github.com/cloudevents/sdk-go/v2"
+
+	gcpduckv1 "github.com/google/knative-gcp/pkg/apis/duck/v1"
+	. "github.com/google/knative-gcp/pkg/pubsub/adapter/context"
+	schemasv1 "github.com/google/knative-gcp/pkg/schemas/v1"
+)
+
+const (
+	defaultContentTypeAttribute = "content-type"
+	defaultRawContentType       = "application/octet-stream"
+)
+
+// reservedAttributes are the CloudEvents context attributes, which the attributes of the messages
+// must not override.
+var reservedAttributes = map[string]bool{
+	"specversion":     true,
+	"id":              true,
+	"source":          true,
+	"type":            true,
+	"subject":         true,
+	"time":            true,
+	"datacontenttype": true,
+	"dataschema":      true,
+	"data":            true,
+}
+
+// RawPayloadConverter converts messages into Pub/Sub events whose data is the payload of the
+// messages, rather than the push message envelope, regardless of the converter type.
+type RawPayloadConverter struct {
+	contentTypeAttribute string
+	defaultContentType   string
+}
+
+var _ Converter = (*RawPayloadConverter)(nil)
+
+// NewRawPayloadConverter creates the converter of the raw payload mode.
+func NewRawPayloadConverter(spec *gcpduckv1.RawPayload) *RawPayloadConverter {
+	c := &RawPayloadConverter{
+		contentTypeAttribute: spec.ContentTypeAttribute,
+		defaultContentType:   spec.DefaultContentType,
+	}
+	if c.contentTypeAttribute == "" {
+		c.contentTypeAttribute = defaultContentTypeAttribute
+	}
+	if c.defaultContentType == "" {
+		c.defaultContentType = defaultRawContentType
+	}
+	return c
+}
+
+// Convert converts the message into an event with the payload of the message as data and its
+// attributes as extensions.
+func (c *RawPayloadConverter) Convert(ctx context.Context, msg *pubsub.Message, _ ConverterType) (*cev2.Event, error) {
+	if msg == nil {
+		return nil, fmt.Errorf("nil pubsub message")
+	}
+	event := cev2.NewEvent(cev2.VersionV1)
+	event.SetID(msg.ID)
+	event.SetTime(msg.PublishTime)
+
+	project, err := GetProjectKey(ctx)
+	if err != nil {
+		return nil, err
+	}
+	topic, err := GetTopicKey(ctx)
+	if err != nil {
+		return nil, err
+	}
+	event.SetSource(schemasv1.CloudPubSubEventSource(project, topic))
+	event.SetType(schemasv1.CloudPubSubMessagePublishedEventType)
+
+	// The attributes are sorted so that the same extension is kept when several attributes have
+	// the same sanitized name.
+	keys := make([]string, 0, len(msg.Attributes))
+	for k := range msg.Attributes {
+		if k != c.contentTypeAttribute {
+			keys = append(keys, k)
+		}
+	}
+	sort.Strings(keys)
+	for _, k := range keys {
+		name := extensionName(k)
+		if name == "" || reservedAttributes[name] {
+			continue
+		}
+		if _, ok := event.Extensions()[name]; ok {
+			continue
+		}
+		event.SetExtension(name, msg.Attributes[k])
+	}
+
+	contentType := c.defaultContentType
+	if ct, ok := msg.Attributes[c.contentTypeAttribute]; ok && ct != "" {
+		contentType = ct
+	}
+	if err := event.SetData(contentType, msg.Data); err != nil {
+		return nil, err
+	}
+	return &event, nil
+}
+
+// extensionName sanitizes the name of a message attribute into a valid CloudEvents extension
+// name, made of lower-case letters and digits only.
+func extensionName(attribute string) string {
+	var b strings.Builder
+	for _, r := range strings.ToLower(attribute) {
+		if (r >= 'a' && r <= 'z') || (r >= '0' && r <= '9') {
+			b.WriteRune(r)
+		}
+	}
+	return b.String()
+}
@@ -0,0 +1,152 @@
+/*
+Copyright 2021 Google LLC
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package converters
+
+import (
+	"context"
+	"testing"
+
+	"cloud.google.com/go/pubsub"
+	cev2 "github.com/cloudevents/sdk-go/v2"
+	"github.com/google/go-cmp/cmp"
+	"google.golang.org/protobuf/proto"
+	"google.golang.org/protobuf/types/known/wrapperspb"
+
+	gcpduckv1 "github.com/google/knative-gcp/pkg/apis/duck/v1"
+	. "github.com/google/knative-gcp/pkg/pubsub/adapter/context"
+	schemasv1 "github.com/google/knative-gcp/pkg/schemas/v1"
+)
+
+func TestRawPayloadConverter(t *testing.T) {
+	protoData, err := proto.Marshal(wrapperspb.String("test data"))
+	if err != nil {
+		t.Fatalf("Failed to marshal the proto payload: %v", err)
+	}
+
+	tests := []struct {
+		name               string
+		spec               *gcpduckv1.RawPayload
+		message            *pubsub.Message
+		wantEventFn        func() *cev2.Event
+		wantErr            bool
+		wantInvalidContext bool
+	}{{
+		name: "binary payload",
+		spec: &gcpduckv1.RawPayload{},
+		message: &pubsub.Message{
+			ID:   "id",
+			Data: []byte{0x00, 0xff, 0x10},
+		},
+		wantEventFn: func() *cev2.Event {
+			return rawPayloadCloudEvent("application/octet-stream", []byte{0x00, 0xff, 0x10}, nil)
+		},
+	}, {
+		name: "JSON payload with content type attribute",
+		spec: &gcpduckv1.RawPayload{},
+		message: &pubsub.Message{
+			ID:   "id",
+			Data: []byte(`{"order":"1234"}`),
+			Attributes: map[string]string{
+				"content-type": "application/json",
+				"region":       "eu",
+			},
+		},
+		wantEventFn: func() *cev2.Event {
+			return rawPayloadCloudEvent("application/json", []byte(`{"order":"1234"}`), map[string]string{"region": "eu"})
+		},
+	}, {
+		name: "proto payload with default content type",
+		spec: &gcpduckv1.RawPayload{DefaultContentType: "application/protobuf"},
+		message: &pubsub.Message{
+			ID:   "id",
+			Data: protoData,
+		},
+		wantEventFn: func() *cev2.Event {
+			return rawPayloadCloudEvent("application/protobuf", protoData, nil)
+		},
+	}, {
+		name: "custom content type attribute",
+		spec: &gcpduckv1.RawPayload{ContentTypeAttribute: "format", DefaultContentType: "application/json"},
+		message: &pubsub.Message{
+			ID:   "id",
+			Data: []byte("hello"),
+			Attributes: map[string]string{
+				"format":       "text/plain",
+				"content-type": "ignored/type",
+			},
+		},
+		wantEventFn: func() *cev2.Event {
+			return rawPayloadCloudEvent("text/plain", []byte("hello"), map[string]string{"contenttype": "ignored/type"})
+		},
+	}, {
+		name: "sanitized attributes",
+		spec: &gcpduckv1.RawPayload{},
+		message: &pubsub.Message{
+			ID:   "id",
+			Data: []byte("hello"),
+			Attributes: map[string]string{
+				"Order-ID": "first",
+				"order_id": "second",
+				"Type":     "reserved",
+				"#$^":      "empty",
+			},
+		},
+		wantEventFn: func() *cev2.Event {
+			return rawPayloadCloudEvent("application/octet-stream", []byte("hello"), map[string]string{"orderid": "first"})
+		},
+	}, {
+		name:               "invalid context",
+		spec:               &gcpduckv1.RawPayload{},
+		message:            &pubsub.Message{ID: "id", Data: []byte("hello")},
+		wantInvalidContext: true,
+		wantErr:            true,
+	}}
+
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			ctx := context.Background()
+			if !test.wantInvalidContext {
+				ctx = WithProjectKey(ctx, "testproject")
+				ctx = WithTopicKey(ctx, "testtopic")
+				ctx = WithSubscriptionKey(ctx, "testsubscription")
+			}
+
+			gotEvent, err := NewRawPayloadConverter(test.spec).Convert(ctx, test.message, CloudPubSub)
+			if err != nil {
+				if !test.wantErr {
+					t.Errorf("RawPayloadConverter.Convert got error %v want error=%v", err, test.wantErr)
+				}
+				return
+			}
+			if diff := cmp.Diff(test.wantEventFn(), gotEvent); diff != "" {
+				t.Errorf("RawPayloadConverter.Convert got unexpected cloudevents.Event (-want +got) %s", diff)
+			}
+		})
+	}
+}
+
+func rawPayloadCloudEvent(contentType string, data []byte, extensions map[string]string) *cev2.Event {
+	e := cev2.NewEvent(cev2.VersionV1)
+	e.SetID("id")
+	e.SetSource(schemasv1.CloudPubSubEventSource("testproject", "testtopic"))
+	e.SetType(schemasv1.CloudPubSubMessagePublishedEventType)
+	for k, v := range extensions {
+		e.SetExtension(k, v)
+	}
+	e.SetData(contentType, data)
+	return &e
+}
@@ -0,0 +1,108 @@
+/*
+Copyright 2021 Google LLC
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package adapter
+
+import (
+	"context"
+	"fmt"
+
+	"cloud.google.com/go/pubsub"
+	cev2 "github.com/cloudevents/sdk-go/v2"
+	"github.com/cloudevents/sdk-go/v2/binding"
+	"go.uber.org/zap"
+
+	"github.com/google/knative-gcp/pkg/pubsub/adapter/converters"
+	"github.com/google/knative-gcp/pkg/utils/deadletter"
+)
+
+// handleConversionFailure settles a message that can't be converted into an event. The message is
+// sent as is to the dead letter sink if there is one, left to the dead letter policy of the
+// subscription if it has one, and dropped otherwise, as retrying it can't succeed.
+func (a *Adapter) handleConversionFailure(ctx context.Context, msg *pubsub.Message, convErr error) {
+	if a.args.DeadLetterSinkURI != "" {
+		if err := a.sendRawToDeadLetterSink(ctx, msg, convErr); err != nil {
+			a.logger.Error("Failed to send message to dead letter sink", zap.String("address", a.args.DeadLetterSinkURI), zap.Error(err))
+			a.nack(ctx, msg)
+			return
+		}
+		a.ack(ctx, msg)
+		return
+	}
+	// The delivery attempt is only set when the subscription has a dead letter policy.
+	if msg.DeliveryAttempt != nil {
+		a.nack(ctx, msg)
+		return
+	}
+	// Ack the message so it won't be retried, we consider all errors to be non-retryable.
+	a.ack(ctx, msg)
+}
+
+// handleDeliveryFailure settles a message whose event the sink failed to accept. With a dead
+// letter sink, the event is sent to it once it failed Retry times. Otherwise, or until then, the
+// message is nacked so that Pub/Sub redelivers it. statusCode is the status code of the response
+// of the sink, zero if there was none, and data describes the failure.
+func (a *Adapter) handleDeliveryFailure(ctx context.Context, msg *pubsub.Message, event *cev2.Event, statusCode int, data []byte) {
+	if a.args.DeadLetterSinkURI == "" {
+		a.nack(ctx, msg)
+		return
+	}
+	attempt, _ := a.attempts.Attempt(msg.ID)
+	if attempt < a.args.DeadLetterRetry {
+		a.nack(ctx, msg)
+		return
+	}
+	a.logger.Warn("Event delivery failed, the event exhausted its retries", zap.String("messageID", msg.ID), zap.Int32("attempts", attempt))
+	if err := a.sendToDeadLetterSink(ctx, event, statusCode, data); err != nil {
+		a.logger.Error("Failed to send event to dead letter sink", zap.String("address", a.args.DeadLetterSinkURI), zap.Error(err))
+		a.nack(ctx, msg)
+		return
+	}
+	a.attempts.Forget(msg.ID)
+	a.ack(ctx, msg)
+}
+
+// sendRawToDeadLetterSink sends the raw Pub/Sub message to the dead letter sink, along with the
+// conversion error.
+func (a *Adapter) sendRawToDeadLetterSink(ctx context.Context, msg *pubsub.Message, convErr error) error {
+	event, err := a.converter.Convert(ctx, msg, converters.CloudPubSub)
+	if err != nil {
+		return err
+	}
+	return a.sendToDeadLetterSink(ctx, event, 0, []byte(convErr.Error()))
+}
+
+// sendToDeadLetterSink sends the event to the dead letter sink, with the extensions describing why
+// it could not be delivered.
+func (a *Adapter) sendToDeadLetterSink(ctx context.Context, event *cev2.Event, statusCode int, data []byte) error {
+	for name, value := range deadletter.Extensions(a.args.SinkURI, statusCode, data) {
+		event.SetExtension(name, value)
+	}
+
+	resp, err := a.sendMsg(ctx, a.args.DeadLetterSinkURI, (*binding.EventMessage)(event))
+	if err != nil {
+		return err
+	}
+	defer func() {
+		if err := resp.Body.Close(); err != nil {
+			a.logger.Warn("Failed to close response body", zap.Error(err))
+		}
+	}()
+	if resp.StatusCode/100 != 2 {
+		return fmt.Errorf("dead letter sink responded with status code %d", resp.StatusCode)
+	}
+	return nil
+}
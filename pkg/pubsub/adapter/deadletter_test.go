@@ -0,0 +1,198 @@
+/*
+Copyright 2021 Google LLC
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package adapter
+
+import (
+	"context"
+	"encoding/base64"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"cloud.google.com/go/pubsub"
+	cev2 "github.com/cloudevents/sdk-go/v2"
+	"github.com/cloudevents/sdk-go/v2/binding"
+	cehttp "github.com/cloudevents/sdk-go/v2/protocol/http"
+	logtest "knative.dev/pkg/logging/testing"
+
+	"github.com/google/knative-gcp/pkg/pubsub/adapter/converters"
+	schemasv1 "github.com/google/knative-gcp/pkg/schemas/v1"
+	"github.com/google/knative-gcp/pkg/utils/clients"
+	"github.com/google/knative-gcp/pkg/utils/deadletter"
+)
+
+// failingConverter fails to convert the messages, except into Pub/Sub events.
+type failingConverter struct{}
+
+func (failingConverter) Convert(ctx context.Context, msg *pubsub.Message, converterType converters.ConverterType) (*cev2.Event, error) {
+	if converterType == converters.CloudPubSub {
+		return converters.NewPubSubConverter().Convert(ctx, msg, converterType)
+	}
+	return nil, errors.New("induced error")
+}
+
+func TestAdapterDeadLettersConversionFailures(t *testing.T) {
+	ctx := logtest.TestContextWithLogger(t)
+
+	deadLetterClient, err := cehttp.New()
+	if err != nil {
+		t.Fatalf("failed to create dead letter sink cloudevents client: %v", err)
+	}
+	deadLetterSvr := httptest.NewServer(deadLetterClient)
+	defer deadLetterSvr.Close()
+
+	c, close := testPubsubClient(ctx, t, testProjectID)
+	defer close()
+	topic, err := c.CreateTopic(ctx, testTopic)
+	if err != nil {
+		t.Fatalf("failed to create topic: %v", err)
+	}
+	sub, err := c.CreateSubscription(ctx, testSub, pubsub.SubscriptionConfig{Topic: topic})
+	if err != nil {
+		t.Fatalf("failed to create subscription: %v", err)
+	}
+
+	args := &AdapterArgs{
+		TopicID:           testTopic,
+		SinkURI:           "http://sink.example.com",
+		DeadLetterSinkURI: deadLetterSvr.URL,
+		ConverterType:     converters.ConverterType(testConverterType),
+	}
+	adapter := NewAdapter(ctx,
+		clients.ProjectID(testProjectID),
+		Namespace(testNamespace),
+		Name(testName),
+		ResourceGroup(testResourceGroup),
+		sub,
+		deadLetterSvr.Client(),
+		failingConverter{},
+		&statsReporterRecorder{},
+		args)
+
+	rctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+	defer cancel()
+	go adapter.Start(rctx)
+	defer adapter.Stop()
+
+	if _, err := topic.Publish(ctx, &pubsub.Message{Data: []byte("not an event")}).Get(ctx); err != nil {
+		t.Fatalf("failed to publish message: %v", err)
+	}
+
+	msg, err := deadLetterClient.Receive(rctx)
+	if err != nil {
+		t.Fatalf("dead letter sink failed to receive the message: %v", err)
+	}
+	defer msg.Finish(nil)
+	got, err := binding.ToEvent(rctx, msg)
+	if err != nil {
+		t.Fatalf("dead letter sink received message that cannot be converted to an event: %v", err)
+	}
+	if got.Type() != schemasv1.CloudPubSubMessagePublishedEventType {
+		t.Errorf("unexpected dead lettered event type %q, wanted %q", got.Type(), schemasv1.CloudPubSubMessagePublishedEventType)
+	}
+	if dest := got.Extensions()[deadletter.ErrorDestExtension]; dest != args.SinkURI {
+		t.Errorf("unexpected %s extension %v, wanted %q", deadletter.ErrorDestExtension, dest, args.SinkURI)
+	}
+	if data := got.Extensions()[deadletter.ErrorDataExtension]; data != base64.StdEncoding.EncodeToString([]byte("induced error")) {
+		t.Errorf("unexpected %s extension %v", deadletter.ErrorDataExtension, data)
+	}
+}
+
+func TestAdapterDeadLettersRejectedEvents(t *testing.T) {
+	ctx := logtest.TestContextWithLogger(t)
+
+	var attempts int32
+	sinkSvr := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		atomic.AddInt32(&attempts, 1)
+		w.WriteHeader(http.StatusInternalServerError)
+		w.Write([]byte("induced failure"))
+	}))
+	defer sinkSvr.Close()
+
+	deadLetterClient, err := cehttp.New()
+	if err != nil {
+		t.Fatalf("failed to create dead letter sink cloudevents client: %v", err)
+	}
+	deadLetterSvr := httptest.NewServer(deadLetterClient)
+	defer deadLetterSvr.Close()
+
+	c, close := testPubsubClient(ctx, t, testProjectID)
+	defer close()
+	topic, err := c.CreateTopic(ctx, testTopic)
+	if err != nil {
+		t.Fatalf("failed to create topic: %v", err)
+	}
+	sub, err := c.CreateSubscription(ctx, testSub, pubsub.SubscriptionConfig{Topic: topic})
+	if err != nil {
+		t.Fatalf("failed to create subscription: %v", err)
+	}
+
+	args := &AdapterArgs{
+		TopicID:           testTopic,
+		SinkURI:           sinkSvr.URL,
+		DeadLetterSinkURI: deadLetterSvr.URL,
+		DeadLetterRetry:   3,
+		ConverterType:     converters.ConverterType(testConverterType),
+	}
+	adapter := NewAdapter(ctx,
+		clients.ProjectID(testProjectID),
+		Namespace(testNamespace),
+		Name(testName),
+		ResourceGroup(testResourceGroup),
+		sub,
+		sinkSvr.Client(),
+		dataConverter{},
+		&statsReporterRecorder{},
+		args)
+
+	rctx, cancel := context.WithTimeout(context.Background(), 10*time.Second)
+	defer cancel()
+	go adapter.Start(rctx)
+	defer adapter.Stop()
+
+	if _, err := topic.Publish(ctx, &pubsub.Message{Data: []byte("id")}).Get(ctx); err != nil {
+		t.Fatalf("failed to publish message: %v", err)
+	}
+
+	msg, err := deadLetterClient.Receive(rctx)
+	if err != nil {
+		t.Fatalf("dead letter sink failed to receive the event: %v", err)
+	}
+	defer msg.Finish(nil)
+	got, err := binding.ToEvent(rctx, msg)
+	if err != nil {
+		t.Fatalf("dead letter sink received message that cannot be converted to an event: %v", err)
+	}
+	if got.ID() != "id" {
+		t.Errorf("unexpected dead lettered event ID %q, wanted %q", got.ID(), "id")
+	}
+	if n := atomic.LoadInt32(&attempts); n != args.DeadLetterRetry {
+		t.Errorf("got %d delivery attempts before the event was dead lettered, wanted %d", n, args.DeadLetterRetry)
+	}
+	if dest := got.Extensions()[deadletter.ErrorDestExtension]; dest != args.SinkURI {
+		t.Errorf("unexpected %s extension %v, wanted %q", deadletter.ErrorDestExtension, dest, args.SinkURI)
+	}
+	if code := got.Extensions()[deadletter.ErrorCodeExtension]; code != "500" {
+		t.Errorf("unexpected %s extension %v, wanted %q", deadletter.ErrorCodeExtension, code, "500")
+	}
+	if data := got.Extensions()[deadletter.ErrorDataExtension]; data != base64.StdEncoding.EncodeToString([]byte("induced failure")) {
+		t.Errorf("unexpected %s extension %v", deadletter.ErrorDataExtension, data)
+	}
+}
@@ -0,0 +1,99 @@
+/*
+Copyright 2021 Google LLC
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package adapter
+
+import (
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"cloud.google.com/go/pubsub"
+	logtest "knative.dev/pkg/logging/testing"
+
+	"github.com/google/knative-gcp/pkg/pubsub/adapter/converters"
+	"github.com/google/knative-gcp/pkg/utils/clients"
+)
+
+func TestAdapterAcksWithExactlyOnceDelivery(t *testing.T) {
+	ctx := logtest.TestContextWithLogger(t)
+
+	delivered := make(chan string, 10)
+	sinkSvr := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		delivered <- r.Header.Get("ce-id")
+		w.WriteHeader(http.StatusAccepted)
+	}))
+	defer sinkSvr.Close()
+
+	c, close := testPubsubClient(ctx, t, testProjectID)
+	defer close()
+	topic, err := c.CreateTopic(ctx, testTopic)
+	if err != nil {
+		t.Fatalf("failed to create topic: %v", err)
+	}
+	sub, err := c.CreateSubscription(ctx, testSub, pubsub.SubscriptionConfig{
+		Topic:                     topic,
+		EnableExactlyOnceDelivery: true,
+	})
+	if err != nil {
+		t.Fatalf("failed to create subscription: %v", err)
+	}
+	if _, err := topic.Publish(ctx, &pubsub.Message{Data: []byte("id")}).Get(ctx); err != nil {
+		t.Fatalf("failed to publish message: %v", err)
+	}
+
+	adapter := NewAdapter(ctx,
+		clients.ProjectID(testProjectID),
+		Namespace(testNamespace),
+		Name(testName),
+		ResourceGroup(testResourceGroup),
+		sub,
+		sinkSvr.Client(),
+		dataConverter{},
+		&statsReporterRecorder{},
+		&AdapterArgs{
+			TopicID:       testTopic,
+			SinkURI:       sinkSvr.URL,
+			ConverterType: converters.ConverterType(testConverterType),
+		})
+
+	rctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+	defer cancel()
+	errCh := make(chan error, 1)
+	go func() {
+		errCh <- adapter.Start(rctx)
+	}()
+	select {
+	case <-delivered:
+	case <-rctx.Done():
+		t.Fatal("timed out waiting for the event")
+	}
+	// The message is acknowledged before the adapter stops, so it is not redelivered.
+	time.Sleep(500 * time.Millisecond)
+	adapter.Stop()
+	<-errCh
+
+	rctx, cancel = context.WithTimeout(context.Background(), time.Second)
+	defer cancel()
+	if err := sub.Receive(rctx, func(_ context.Context, msg *pubsub.Message) {
+		t.Errorf("message %q redelivered after it was acknowledged", msg.Data)
+		msg.Ack()
+	}); err != nil {
+		t.Fatalf("failed to receive messages: %v", err)
+	}
+}
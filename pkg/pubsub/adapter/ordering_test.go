@@ -0,0 +1,158 @@
+/*
+Copyright 2021 Google LLC
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package adapter
+
+import (
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"sort"
+	"strconv"
+	"sync"
+	"testing"
+	"time"
+
+	"cloud.google.com/go/pubsub"
+	cev2 "github.com/cloudevents/sdk-go/v2"
+	"github.com/google/go-cmp/cmp"
+	logtest "knative.dev/pkg/logging/testing"
+
+	"github.com/google/knative-gcp/pkg/pubsub/adapter/converters"
+	"github.com/google/knative-gcp/pkg/utils/clients"
+)
+
+// dataConverter converts the messages into events whose ID is the payload of the message.
+type dataConverter struct{}
+
+func (dataConverter) Convert(ctx context.Context, msg *pubsub.Message, converterType converters.ConverterType) (*cev2.Event, error) {
+	event := cev2.NewEvent()
+	event.SetID(string(msg.Data))
+	event.SetType("com.example.ordered")
+	event.SetSource("//example.com/ordered")
+	return &event, nil
+}
+
+// orderedSink records the IDs of the events it receives, and the maximum number of events it
+// received concurrently.
+type orderedSink struct {
+	mu          sync.Mutex
+	ids         []string
+	inFlight    int
+	maxInFlight int
+	done        chan struct{}
+	want        int
+}
+
+func (s *orderedSink) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	s.mu.Lock()
+	s.inFlight++
+	if s.inFlight > s.maxInFlight {
+		s.maxInFlight = s.inFlight
+	}
+	s.mu.Unlock()
+
+	// Leave time for the next events to be sent concurrently, if they were.
+	time.Sleep(20 * time.Millisecond)
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.inFlight--
+	// The events received after all the expected ones are ignored.
+	if len(s.ids) < s.want {
+		s.ids = append(s.ids, r.Header.Get("ce-id"))
+		if len(s.ids) == s.want {
+			close(s.done)
+		}
+	}
+	w.WriteHeader(http.StatusAccepted)
+}
+
+func TestAdapterDeliversOrderedMessagesSerially(t *testing.T) {
+	ctx := logtest.TestContextWithLogger(t)
+
+	const count = 5
+	sink := &orderedSink{done: make(chan struct{}), want: count}
+	sinkSvr := httptest.NewServer(sink)
+	defer sinkSvr.Close()
+
+	c, close := testPubsubClient(ctx, t, testProjectID)
+	defer close()
+	topic, err := c.CreateTopic(ctx, testTopic)
+	if err != nil {
+		t.Fatalf("failed to create topic: %v", err)
+	}
+	topic.EnableMessageOrdering = true
+	sub, err := c.CreateSubscription(ctx, testSub, pubsub.SubscriptionConfig{
+		Topic:                 topic,
+		EnableMessageOrdering: true,
+	})
+	if err != nil {
+		t.Fatalf("failed to create subscription: %v", err)
+	}
+
+	var want []string
+	for i := 0; i < count; i++ {
+		id := strconv.Itoa(i)
+		want = append(want, id)
+		if _, err := topic.Publish(ctx, &pubsub.Message{Data: []byte(id), OrderingKey: "key"}).Get(ctx); err != nil {
+			t.Fatalf("failed to publish message: %v", err)
+		}
+	}
+
+	adapter := NewAdapter(ctx,
+		clients.ProjectID(testProjectID),
+		Namespace(testNamespace),
+		Name(testName),
+		ResourceGroup(testResourceGroup),
+		sub,
+		sinkSvr.Client(),
+		dataConverter{},
+		&statsReporterRecorder{},
+		&AdapterArgs{
+			TopicID:       testTopic,
+			SinkURI:       sinkSvr.URL,
+			ConverterType: converters.ConverterType(testConverterType),
+		})
+
+	rctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+	defer cancel()
+	errCh := make(chan error, 1)
+	go func() {
+		errCh <- adapter.Start(rctx)
+	}()
+
+	select {
+	case <-sink.done:
+	case <-rctx.Done():
+		t.Fatal("timed out waiting for the events")
+	}
+	// Wait for the adapter to stop, so that it doesn't log after the test.
+	adapter.Stop()
+	<-errCh
+
+	sink.mu.Lock()
+	defer sink.mu.Unlock()
+	// The fake Pub/Sub server doesn't deliver the messages in order, so only the serial delivery
+	// of the messages with the same ordering key is checked.
+	sort.Strings(sink.ids)
+	if diff := cmp.Diff(want, sink.ids); diff != "" {
+		t.Errorf("unexpected events (-want, +got) = %v", diff)
+	}
+	if sink.maxInFlight != 1 {
+		t.Errorf("got %d events with the same ordering key sent concurrently, wanted 1", sink.maxInFlight)
+	}
+}
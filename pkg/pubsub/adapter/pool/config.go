@@ -0,0 +1,160 @@
+/*
+Copyright 2021 Google LLC
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+// Package pool runs the receive adapters of many PullSubscriptions in the same pods.
+package pool
+
+import (
+	"encoding/json"
+	"fmt"
+
+	"cloud.google.com/go/pubsub"
+
+	gcpduckv1 "github.com/google/knative-gcp/pkg/apis/duck/v1"
+)
+
+const (
+	// Name is the name of the Deployment, HorizontalPodAutoscaler and ConfigMap of the receive
+	// adapter pool, in the system namespace.
+	Name = "pubsub-adapter-pool"
+
+	// ConfigKey is the key of the config in the ConfigMap of the pool.
+	ConfigKey = "entries.json"
+
+	// AllowListName is the name of the ConfigMap, in the system namespace, holding the namespaces
+	// whose PullSubscriptions may use the pool. The pool pulls their subscriptions with its own
+	// credentials, so only the cluster admins can allow a namespace.
+	AllowListName = "config-adapter-pool"
+
+	// AllowedNamespacesKey is the key of the allowed namespaces in the allow list ConfigMap,
+	// separated by whitespace or commas.
+	AllowedNamespacesKey = "allowed-namespaces"
+
+	// MaxOutstandingMessagesKey and MaxOutstandingBytesKey are the keys of the receive settings of
+	// each entry in the allow list ConfigMap.
+	MaxOutstandingMessagesKey = "max-outstanding-messages"
+	MaxOutstandingBytesKey    = "max-outstanding-bytes"
+
+	// DefaultMaxOutstandingMessages and DefaultMaxOutstandingBytes bound the messages each entry
+	// holds, so that the memory of the pool grows with the number of its entries, rather than with
+	// the pubsub.DefaultReceiveSettings of a dedicated receive adapter.
+	DefaultMaxOutstandingMessages = 100
+	DefaultMaxOutstandingBytes    = 10 << 20
+)
+
+// Entry is a subscription whose events are delivered by the pool. It holds the arguments that a
+// dedicated receive adapter gets from its environment.
+type Entry struct {
+	// Namespace and Name are the namespace and name of the resource reported in the metrics.
+	Namespace string `json:"namespace"`
+	Name      string `json:"name"`
+
+	// ResourceGroup is the resource group reported in the metrics. E.g.,
+	// cloudstoragesources.events.cloud.google.com.
+	ResourceGroup string `json:"resourceGroup"`
+
+	// Project is the project of the subscription.
+	Project string `json:"project"`
+
+	// Topic is the ID of the topic of the subscription.
+	Topic string `json:"topic"`
+
+	// Subscription is the ID of the subscription.
+	Subscription string `json:"subscription"`
+
+	// Sink is the URI where the events are sent.
+	Sink string `json:"sink"`
+
+	// Transformer is the URI where the events are sent before the sink, for Channels.
+	Transformer string `json:"transformer,omitempty"`
+
+	// DeadLetterSink is the URI where the messages that can't be converted into events, and the
+	// events the sink failed to accept DeadLetterRetry times, are sent.
+	DeadLetterSink string `json:"deadLetterSink,omitempty"`
+
+	// DeadLetterRetry is the number of failed delivery attempts after which an event is sent to
+	// the DeadLetterSink.
+	DeadLetterRetry int32 `json:"deadLetterRetry,omitempty"`
+
+	// AdapterType selects the converter of the messages into events.
+	AdapterType string `json:"adapterType,omitempty"`
+
+	// Mapping converts the messages into events instead of the converter of AdapterType, if set.
+	Mapping *gcpduckv1.EventMapping `json:"mapping,omitempty"`
+
+	// RawPayload sends the payload of the messages as the data of the events, if set.
+	RawPayload *gcpduckv1.RawPayload `json:"rawPayload,omitempty"`
+
+	// Extensions are the CloudEvents extensions overridden on the events.
+	Extensions map[string]string `json:"extensions,omitempty"`
+}
+
+// Config is the config of the pool.
+type Config struct {
+	// Entries are the subscriptions delivered by the pool, keyed by the namespace/name of their
+	// PullSubscription.
+	Entries map[string]*Entry `json:"entries,omitempty"`
+
+	// ReceiveSettings are the receive settings of each entry. The defaults are used if it is nil.
+	ReceiveSettings *ReceiveSettings `json:"receiveSettings,omitempty"`
+}
+
+// ReceiveSettings bound the messages pulled by the adapter of each entry of the pool.
+type ReceiveSettings struct {
+	// MaxOutstandingMessages is the maximum number of messages being handled by an entry.
+	// DefaultMaxOutstandingMessages is used if it is zero.
+	MaxOutstandingMessages int `json:"maxOutstandingMessages,omitempty"`
+
+	// MaxOutstandingBytes is the maximum size of the messages being handled by an entry.
+	// DefaultMaxOutstandingBytes is used if it is zero.
+	MaxOutstandingBytes int `json:"maxOutstandingBytes,omitempty"`
+}
+
+// PubsubReceiveSettings returns the Pub/Sub receive settings of each entry of the config. Each
+// entry pulls its subscription with a single stream, since the pool runs many entries.
+func (c *Config) PubsubReceiveSettings() pubsub.ReceiveSettings {
+	rs := pubsub.DefaultReceiveSettings
+	rs.NumGoroutines = 1
+	rs.MaxOutstandingMessages = DefaultMaxOutstandingMessages
+	rs.MaxOutstandingBytes = DefaultMaxOutstandingBytes
+	if c.ReceiveSettings != nil {
+		if c.ReceiveSettings.MaxOutstandingMessages > 0 {
+			rs.MaxOutstandingMessages = c.ReceiveSettings.MaxOutstandingMessages
+		}
+		if c.ReceiveSettings.MaxOutstandingBytes > 0 {
+			rs.MaxOutstandingBytes = c.ReceiveSettings.MaxOutstandingBytes
+		}
+	}
+	return rs
+}
+
+// ParseConfig parses the config of the pool. Empty data is an empty config.
+func ParseConfig(data []byte) (*Config, error) {
+	cfg := &Config{}
+	if len(data) == 0 {
+		return cfg, nil
+	}
+	if err := json.Unmarshal(data, cfg); err != nil {
+		return nil, fmt.Errorf("failed to unmarshal the adapter pool config: %w", err)
+	}
+	return cfg, nil
+}
+
+// Marshal marshals the config of the pool. The entries are sorted by key, so that the same entries
+// always have the same data.
+func (c *Config) Marshal() ([]byte, error) {
+	return json.Marshal(c)
+}
@@ -0,0 +1,105 @@
+/*
+Copyright 2021 Google LLC
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package pool
+
+import (
+	"testing"
+
+	"cloud.google.com/go/pubsub"
+	"github.com/google/go-cmp/cmp"
+)
+
+func TestConfigRoundTrip(t *testing.T) {
+	cfg := &Config{Entries: map[string]*Entry{
+		"ns/storage": {
+			Namespace:     "ns",
+			Name:          "storage",
+			ResourceGroup: "cloudstoragesources.events.cloud.google.com",
+			Project:       "project",
+			Topic:         "topic",
+			Subscription:  "sub",
+			Sink:          "http://sink.ns.svc.cluster.local",
+			AdapterType:   "google.cloud.storage",
+			Extensions:    map[string]string{"foo": "bar"},
+		},
+		"ns/pubsub": {
+			Namespace:    "ns",
+			Name:         "pubsub",
+			Project:      "project",
+			Topic:        "topic",
+			Subscription: "sub-2",
+			Sink:         "http://sink.ns.svc.cluster.local",
+		},
+	}, ReceiveSettings: &ReceiveSettings{MaxOutstandingMessages: 10}}
+	data, err := cfg.Marshal()
+	if err != nil {
+		t.Fatalf("Failed to marshal the config: %v", err)
+	}
+	got, err := ParseConfig(data)
+	if err != nil {
+		t.Fatalf("Failed to parse the config: %v", err)
+	}
+	if diff := cmp.Diff(cfg, got); diff != "" {
+		t.Errorf("Unexpected config (-want, +got) = %v", diff)
+	}
+}
+
+func TestParseConfig(t *testing.T) {
+	got, err := ParseConfig(nil)
+	if err != nil {
+		t.Fatalf("Failed to parse an empty config: %v", err)
+	}
+	if len(got.Entries) != 0 {
+		t.Errorf("Unexpected entries %v in an empty config", got.Entries)
+	}
+	if _, err := ParseConfig([]byte("{")); err == nil {
+		t.Error("Expected an error parsing an invalid config")
+	}
+}
+
+func TestPubsubReceiveSettings(t *testing.T) {
+	for _, tc := range []struct {
+		name                    string
+		settings                *ReceiveSettings
+		wantMessages, wantBytes int
+	}{{
+		name:         "defaults",
+		wantMessages: DefaultMaxOutstandingMessages,
+		wantBytes:    DefaultMaxOutstandingBytes,
+	}, {
+		name:         "messages",
+		settings:     &ReceiveSettings{MaxOutstandingMessages: 10},
+		wantMessages: 10,
+		wantBytes:    DefaultMaxOutstandingBytes,
+	}, {
+		name:         "bytes",
+		settings:     &ReceiveSettings{MaxOutstandingBytes: 1 << 20},
+		wantMessages: DefaultMaxOutstandingMessages,
+		wantBytes:    1 << 20,
+	}} {
+		t.Run(tc.name, func(t *testing.T) {
+			got := (&Config{ReceiveSettings: tc.settings}).PubsubReceiveSettings()
+			want := pubsub.DefaultReceiveSettings
+			want.NumGoroutines = 1
+			want.MaxOutstandingMessages = tc.wantMessages
+			want.MaxOutstandingBytes = tc.wantBytes
+			if diff := cmp.Diff(want, got); diff != "" {
+				t.Errorf("Unexpected receive settings (-want, +got) = %v", diff)
+			}
+		})
+	}
+}
@@ -0,0 +1,283 @@
+/*
+Copyright 2021 Google LLC
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package pool
+
+import (
+	"bytes"
+	"context"
+	"io/ioutil"
+	nethttp "net/http"
+	"path/filepath"
+	"reflect"
+	"sync"
+	"time"
+
+	"cloud.google.com/go/pubsub"
+	"github.com/fsnotify/fsnotify"
+	"go.uber.org/zap"
+
+	"github.com/google/knative-gcp/pkg/logging"
+	"github.com/google/knative-gcp/pkg/pubsub/adapter"
+	"github.com/google/knative-gcp/pkg/pubsub/adapter/converters"
+	"github.com/google/knative-gcp/pkg/utils/clients"
+)
+
+// receiveRetryDelay is the delay before an entry whose adapter stopped with an error is started
+// again, e.g. while its subscription is being recreated.
+var receiveRetryDelay = 10 * time.Second
+
+// receiver delivers the events of an entry until its context is done.
+type receiver interface {
+	Receive(ctx context.Context) error
+}
+
+// running is an entry whose adapter is running.
+type running struct {
+	entry  *Entry
+	cancel context.CancelFunc
+	done   chan struct{}
+}
+
+// Pool runs a receive adapter for each entry of its config. The adapters share the HTTP client, and
+// the Pub/Sub client of their project.
+type Pool struct {
+	httpClient *nethttp.Client
+
+	// newReceiver makes the adapter of an entry. It is replaced in tests.
+	newReceiver func(ctx context.Context, entry *Entry, settings pubsub.ReceiveSettings) (receiver, error)
+
+	// mu guards running and settings.
+	mu      sync.Mutex
+	running map[string]*running
+	// settings are the receive settings of the running adapters.
+	settings pubsub.ReceiveSettings
+
+	// draining tracks the stopped adapters that are still handling their outstanding messages.
+	draining sync.WaitGroup
+
+	// clientsMu guards clients. It is separate from mu, since an adapter may be getting its client
+	// while mu is held.
+	clientsMu sync.Mutex
+	clients   map[string]*pubsub.Client
+}
+
+// New creates an empty pool, sending the events with the given HTTP client.
+func New(httpClient *nethttp.Client) *Pool {
+	p := &Pool{
+		httpClient: httpClient,
+		clients:    make(map[string]*pubsub.Client),
+		running:    make(map[string]*running),
+	}
+	p.newReceiver = p.newAdapter
+	return p
+}
+
+// Watch runs the adapters of the config in the given file, and keeps them in sync with the file
+// until the context is done. The file is expected to be mounted from the ConfigMap of the pool.
+func (p *Pool) Watch(ctx context.Context, path string) error {
+	defer p.stopAll()
+	watcher, err := fsnotify.NewWatcher()
+	if err != nil {
+		return err
+	}
+	defer watcher.Close()
+	dir, _ := filepath.Split(path)
+	if err := watcher.Add(dir); err != nil {
+		return err
+	}
+
+	var current []byte
+	load := func() {
+		data, err := ioutil.ReadFile(path)
+		if err != nil {
+			logging.FromContext(ctx).Error("Failed to read the adapter pool config", zap.Error(err))
+			return
+		}
+		if current != nil && bytes.Equal(data, current) {
+			return
+		}
+		cfg, err := ParseConfig(data)
+		if err != nil {
+			logging.FromContext(ctx).Error("Failed to parse the adapter pool config", zap.Error(err))
+			return
+		}
+		current = data
+		p.Sync(ctx, cfg)
+	}
+	load()
+	for {
+		select {
+		case <-ctx.Done():
+			return nil
+		case _, ok := <-watcher.Events:
+			if !ok {
+				return nil
+			}
+			// A ConfigMap volume is updated by swapping a symlink, so any event in the
+			// directory may have changed the file.
+			load()
+		case err, ok := <-watcher.Errors:
+			if ok {
+				logging.FromContext(ctx).Error("Adapter pool config watcher error", zap.Error(err))
+			}
+			return err
+		}
+	}
+}
+
+// Sync starts the adapters of the new and changed entries of the config, and stops the ones of the
+// changed and removed entries. All the entries are restarted if the receive settings changed. It doesn't wait for the stopped adapters to handle their outstanding
+// messages, so that an adapter with a slow sink doesn't hold back the changes of the other entries.
+func (p *Pool) Sync(ctx context.Context, cfg *Config) {
+	p.mu.Lock()
+	var stopped []*running
+	settings := cfg.PubsubReceiveSettings()
+	settingsChanged := !reflect.DeepEqual(settings, p.settings)
+	p.settings = settings
+	for key, r := range p.running {
+		if entry, ok := cfg.Entries[key]; !ok || settingsChanged || !reflect.DeepEqual(entry, r.entry) {
+			logging.FromContext(ctx).Info("Stopping pooled adapter", zap.String("key", key))
+			r.cancel()
+			stopped = append(stopped, r)
+			delete(p.running, key)
+		}
+	}
+	for key, entry := range cfg.Entries {
+		if _, ok := p.running[key]; !ok {
+			logging.FromContext(ctx).Info("Starting pooled adapter", zap.String("key", key), zap.String("subscriptionID", entry.Subscription))
+			p.running[key] = p.start(logging.With(ctx, zap.String("key", key)), entry, settings)
+		}
+	}
+	p.mu.Unlock()
+
+	for _, r := range stopped {
+		p.draining.Add(1)
+		go func(r *running) {
+			defer p.draining.Done()
+			<-r.done
+		}(r)
+	}
+}
+
+// start runs the adapter of the entry until it is stopped. The adapter is made again and restarted
+// after receiveRetryDelay if it fails.
+func (p *Pool) start(ctx context.Context, entry *Entry, settings pubsub.ReceiveSettings) *running {
+	ctx, cancel := context.WithCancel(ctx)
+	r := &running{entry: entry, cancel: cancel, done: make(chan struct{})}
+	go func() {
+		defer close(r.done)
+		for {
+			rec, err := p.newReceiver(ctx, entry, settings)
+			if err == nil {
+				err = rec.Receive(ctx)
+			}
+			if ctx.Err() != nil {
+				return
+			}
+			logging.FromContext(ctx).Error("Pooled adapter has stopped, restarting it", zap.String("subscriptionID", entry.Subscription), zap.Error(err))
+			select {
+			case <-ctx.Done():
+				return
+			case <-time.After(receiveRetryDelay):
+			}
+		}
+	}()
+	return r
+}
+
+// stopAll stops all the adapters, waits for them and for the adapters stopped by Sync to handle
+// their outstanding messages, and closes the Pub/Sub clients.
+func (p *Pool) stopAll() {
+	p.mu.Lock()
+	stopped := make([]*running, 0, len(p.running))
+	for key, r := range p.running {
+		r.cancel()
+		stopped = append(stopped, r)
+		delete(p.running, key)
+	}
+	p.mu.Unlock()
+	for _, r := range stopped {
+		<-r.done
+	}
+	p.draining.Wait()
+
+	p.clientsMu.Lock()
+	defer p.clientsMu.Unlock()
+	for project, client := range p.clients {
+		client.Close()
+		delete(p.clients, project)
+	}
+}
+
+// newAdapter makes the adapter of the entry, as a dedicated receive adapter would, but pulling its
+// subscription with the bounded receive settings of the pool.
+func (p *Pool) newAdapter(ctx context.Context, entry *Entry, settings pubsub.ReceiveSettings) (receiver, error) {
+	client, err := p.pubsubClient(ctx, entry.Project)
+	if err != nil {
+		return nil, err
+	}
+	reporter, err := adapter.NewStatsReporter(adapter.Name(entry.Name), adapter.Namespace(entry.Namespace), adapter.ResourceGroup(entry.ResourceGroup))
+	if err != nil {
+		return nil, err
+	}
+	args := &adapter.AdapterArgs{
+		TopicID:           entry.Topic,
+		ConverterType:     converters.ConverterType(entry.AdapterType),
+		SinkURI:           entry.Sink,
+		TransformerURI:    entry.Transformer,
+		DeadLetterSinkURI: entry.DeadLetterSink,
+		DeadLetterRetry:   entry.DeadLetterRetry,
+		Extensions:        entry.Extensions,
+	}
+	if entry.Mapping != nil {
+		if args.Converter, err = converters.NewMappingConverter(entry.Mapping); err != nil {
+			return nil, err
+		}
+	}
+	if entry.RawPayload != nil {
+		args.Converter = converters.NewRawPayloadConverter(entry.RawPayload)
+	}
+	sub := client.Subscription(entry.Subscription)
+	sub.ReceiveSettings = settings
+	return adapter.NewAdapter(ctx,
+		clients.ProjectID(entry.Project),
+		adapter.Namespace(entry.Namespace),
+		adapter.Name(entry.Name),
+		adapter.ResourceGroup(entry.ResourceGroup),
+		sub,
+		p.httpClient,
+		converters.NewPubSubConverter(),
+		reporter,
+		args), nil
+}
+
+// pubsubClient returns the Pub/Sub client of the project, shared by the adapters of its entries.
+// The clients are only closed when the pool stops.
+func (p *Pool) pubsubClient(ctx context.Context, project string) (*pubsub.Client, error) {
+	p.clientsMu.Lock()
+	defer p.clientsMu.Unlock()
+	if client, ok := p.clients[project]; ok {
+		return client, nil
+	}
+	// The client outlives the context of the adapter that created it.
+	client, err := clients.NewPubsubClient(context.Background(), clients.ProjectID(project))
+	if err != nil {
+		return nil, err
+	}
+	p.clients[project] = client
+	return client, nil
+}
@@ -0,0 +1,268 @@
+/*
+Copyright 2021 Google LLC
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package pool
+
+import (
+	"context"
+	"errors"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"sync"
+	"testing"
+	"time"
+
+	"cloud.google.com/go/pubsub"
+	"github.com/google/go-cmp/cmp"
+)
+
+// fakeReceivers records the entries whose adapters are receiving.
+type fakeReceivers struct {
+	mu        sync.Mutex
+	receiving map[string]int
+	started   int
+	fail      bool
+	// settings are the receive settings of the last adapter made.
+	settings pubsub.ReceiveSettings
+
+	// The adapter of the slow subscription only returns once release is closed after it is
+	// stopped, as if its sink was slow to handle its outstanding messages.
+	slow    string
+	release chan struct{}
+}
+
+type fakeReceiver struct {
+	receivers *fakeReceivers
+	entry     *Entry
+}
+
+func (r *fakeReceiver) Receive(ctx context.Context) error {
+	r.receivers.mu.Lock()
+	r.receivers.started++
+	if r.receivers.fail {
+		r.receivers.mu.Unlock()
+		return errors.New("subscription not found")
+	}
+	r.receivers.receiving[r.entry.Subscription]++
+	release := r.receivers.release
+	slow := r.entry.Subscription == r.receivers.slow
+	r.receivers.mu.Unlock()
+
+	<-ctx.Done()
+	if slow {
+		<-release
+	}
+	r.receivers.mu.Lock()
+	r.receivers.receiving[r.entry.Subscription]--
+	if r.receivers.receiving[r.entry.Subscription] == 0 {
+		delete(r.receivers.receiving, r.entry.Subscription)
+	}
+	r.receivers.mu.Unlock()
+	return nil
+}
+
+func newTestPool() (*Pool, *fakeReceivers) {
+	receivers := &fakeReceivers{receiving: make(map[string]int)}
+	p := New(nil)
+	p.newReceiver = func(_ context.Context, entry *Entry, settings pubsub.ReceiveSettings) (receiver, error) {
+		receivers.mu.Lock()
+		receivers.settings = settings
+		receivers.mu.Unlock()
+		return &fakeReceiver{receivers: receivers, entry: entry}, nil
+	}
+	return p, receivers
+}
+
+// waitFor waits until the subscriptions being received are the wanted ones.
+func (r *fakeReceivers) waitFor(t *testing.T, want map[string]int) {
+	t.Helper()
+	var got map[string]int
+	for i := 0; i < 100; i++ {
+		r.mu.Lock()
+		got = make(map[string]int, len(r.receiving))
+		for k, v := range r.receiving {
+			got[k] = v
+		}
+		r.mu.Unlock()
+		if cmp.Equal(want, got) {
+			return
+		}
+		time.Sleep(10 * time.Millisecond)
+	}
+	t.Fatalf("Unexpected receiving subscriptions (-want, +got) = %v", cmp.Diff(want, got))
+}
+
+func TestSync(t *testing.T) {
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+	p, receivers := newTestPool()
+	defer p.stopAll()
+
+	p.Sync(ctx, &Config{Entries: map[string]*Entry{
+		"ns/a": {Subscription: "sub-a", Sink: "http://a"},
+		"ns/b": {Subscription: "sub-b", Sink: "http://b"},
+	}})
+	receivers.waitFor(t, map[string]int{"sub-a": 1, "sub-b": 1})
+
+	// A changed entry is restarted, a removed one is stopped and a new one is started.
+	p.Sync(ctx, &Config{Entries: map[string]*Entry{
+		"ns/a": {Subscription: "sub-a", Sink: "http://a"},
+		"ns/b": {Subscription: "sub-b2", Sink: "http://b"},
+		"ns/c": {Subscription: "sub-c", Sink: "http://c"},
+	}})
+	receivers.waitFor(t, map[string]int{"sub-a": 1, "sub-b2": 1, "sub-c": 1})
+	receivers.mu.Lock()
+	if receivers.started != 4 {
+		t.Errorf("Unexpected started adapters %d, wanted 4", receivers.started)
+	}
+	receivers.mu.Unlock()
+
+	p.Sync(ctx, &Config{})
+	receivers.waitFor(t, map[string]int{})
+}
+
+func TestSyncReceiveSettings(t *testing.T) {
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+	p, receivers := newTestPool()
+	defer p.stopAll()
+
+	entries := map[string]*Entry{"ns/a": {Subscription: "sub-a"}}
+	p.Sync(ctx, &Config{Entries: entries})
+	receivers.waitFor(t, map[string]int{"sub-a": 1})
+	receivers.mu.Lock()
+	if got := receivers.settings.MaxOutstandingMessages; got != DefaultMaxOutstandingMessages {
+		t.Errorf("Unexpected MaxOutstandingMessages %d, wanted %d", got, DefaultMaxOutstandingMessages)
+	}
+	receivers.mu.Unlock()
+
+	// The entries are restarted with the changed receive settings.
+	p.Sync(ctx, &Config{Entries: entries, ReceiveSettings: &ReceiveSettings{MaxOutstandingMessages: 10}})
+	for i := 0; i < 100; i++ {
+		receivers.mu.Lock()
+		started := receivers.started
+		receivers.mu.Unlock()
+		if started == 2 {
+			break
+		}
+		time.Sleep(10 * time.Millisecond)
+	}
+	receivers.waitFor(t, map[string]int{"sub-a": 1})
+	receivers.mu.Lock()
+	defer receivers.mu.Unlock()
+	if receivers.started != 2 {
+		t.Errorf("Unexpected started adapters %d, wanted 2", receivers.started)
+	}
+	if got := receivers.settings.MaxOutstandingMessages; got != 10 {
+		t.Errorf("Unexpected MaxOutstandingMessages %d, wanted 10", got)
+	}
+}
+
+func TestSyncDoesNotWaitForStoppedAdapters(t *testing.T) {
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+	p, receivers := newTestPool()
+	receivers.slow = "sub-a"
+	receivers.release = make(chan struct{})
+
+	p.Sync(ctx, &Config{Entries: map[string]*Entry{"ns/a": {Subscription: "sub-a"}}})
+	receivers.waitFor(t, map[string]int{"sub-a": 1})
+
+	synced := make(chan struct{})
+	go func() {
+		p.Sync(ctx, &Config{Entries: map[string]*Entry{"ns/b": {Subscription: "sub-b"}}})
+		close(synced)
+	}()
+	select {
+	case <-synced:
+	case <-time.After(time.Second):
+		t.Fatal("Sync waited for the stopped adapter")
+	}
+	// The new entry is started while the stopped adapter still handles its messages.
+	receivers.waitFor(t, map[string]int{"sub-a": 1, "sub-b": 1})
+
+	// Stopping the pool waits for the stopped adapter.
+	stopped := make(chan struct{})
+	go func() {
+		p.stopAll()
+		close(stopped)
+	}()
+	select {
+	case <-stopped:
+		t.Fatal("stopAll didn't wait for the stopped adapter")
+	case <-time.After(50 * time.Millisecond):
+	}
+	close(receivers.release)
+	<-stopped
+	receivers.waitFor(t, map[string]int{})
+}
+
+func TestSyncRestartsFailedAdapters(t *testing.T) {
+	defer func(d time.Duration) { receiveRetryDelay = d }(receiveRetryDelay)
+	receiveRetryDelay = time.Millisecond
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+	p, receivers := newTestPool()
+	defer p.stopAll()
+
+	receivers.mu.Lock()
+	receivers.fail = true
+	receivers.mu.Unlock()
+	p.Sync(ctx, &Config{Entries: map[string]*Entry{"ns/a": {Subscription: "sub-a"}}})
+	time.Sleep(20 * time.Millisecond)
+
+	receivers.mu.Lock()
+	receivers.fail = false
+	receivers.mu.Unlock()
+	receivers.waitFor(t, map[string]int{"sub-a": 1})
+}
+
+func TestWatch(t *testing.T) {
+	dir, err := ioutil.TempDir("", "adapter-pool")
+	if err != nil {
+		t.Fatalf("Failed to create a temp dir: %v", err)
+	}
+	defer os.RemoveAll(dir)
+	path := filepath.Join(dir, ConfigKey)
+	write := func(cfg *Config) {
+		t.Helper()
+		data, err := cfg.Marshal()
+		if err != nil {
+			t.Fatalf("Failed to marshal the config: %v", err)
+		}
+		if err := ioutil.WriteFile(path, data, 0644); err != nil {
+			t.Fatalf("Failed to write the config: %v", err)
+		}
+	}
+	write(&Config{Entries: map[string]*Entry{"ns/a": {Subscription: "sub-a"}}})
+
+	ctx, cancel := context.WithCancel(context.Background())
+	p, receivers := newTestPool()
+	done := make(chan error)
+	go func() { done <- p.Watch(ctx, path) }()
+	receivers.waitFor(t, map[string]int{"sub-a": 1})
+
+	write(&Config{Entries: map[string]*Entry{"ns/b": {Subscription: "sub-b"}}})
+	receivers.waitFor(t, map[string]int{"sub-b": 1})
+
+	// The adapters are stopped along with the pool.
+	cancel()
+	if err := <-done; err != nil {
+		t.Errorf("Unexpected error watching the config: %v", err)
+	}
+	receivers.waitFor(t, map[string]int{})
+}
@@ -27,6 +27,7 @@ import (
 	"github.com/cloudevents/sdk-go/v2/binding"
 	"github.com/cloudevents/sdk-go/v2/binding/transformer"
 	"github.com/cloudevents/sdk-go/v2/protocol/http"
+	"github.com/google/knative-gcp/pkg/broker/eventutil"
 	"github.com/google/knative-gcp/pkg/logging"
 	"github.com/google/knative-gcp/pkg/tracing"
 	"github.com/google/knative-gcp/pkg/utils/authcheck"
@@ -84,8 +85,8 @@ func (p *Publisher) Start(ctx context.Context) error {
 
 // ServeHTTP implements net/http Publisher interface method.
 // 1. Performs basic validation of the request.
-// 2. Converts the request to an event.
-// 3. Sends the event to pubsub.
+// 2. Converts the request to an event, or to events in batch mode.
+// 3. Sends the events to pubsub.
 func (p *Publisher) ServeHTTP(response nethttp.ResponseWriter, request *nethttp.Request) {
 	ctx := request.Context()
 	p.logger.Debug("Serving http", zap.Any("headers", request.Header))
@@ -94,6 +95,11 @@ func (p *Publisher) ServeHTTP(response nethttp.ResponseWriter, request *nethttp.
 		return
 	}
 
+	if eventutil.IsBatchRequest(request) {
+		p.serveBatch(ctx, response, request)
+		return
+	}
+
 	event, err := p.toEvent(request)
 	if err != nil {
 		nethttp.Error(response, err.Error(), nethttp.StatusBadRequest)
@@ -116,15 +122,62 @@ func (p *Publisher) ServeHTTP(response nethttp.ResponseWriter, request *nethttp.
 	response.WriteHeader(statusCode)
 }
 
+// serveBatch publishes each event of a batch request to pubsub. All the events are published
+// before waiting for their results, so that they are bundled by the pubsub publisher, and each
+// event has its own result.
+func (p *Publisher) serveBatch(ctx context.Context, response nethttp.ResponseWriter, request *nethttp.Request) {
+	events, err := eventutil.EventsFromBatchRequest(request)
+	if err != nil {
+		p.logger.Debug("Failed to read batch request", zap.Error(err))
+		nethttp.Error(response, err.Error(), nethttp.StatusBadRequest)
+		return
+	}
+
+	ctx, cancel := context.WithTimeout(ctx, sinkTimeout)
+	defer cancel()
+	results := make([]eventutil.BatchResult, len(events))
+	published := make([]*pubsub.PublishResult, len(events))
+	for i, event := range events {
+		results[i] = eventutil.BatchResult{ID: event.ID(), StatusCode: nethttp.StatusAccepted}
+		if published[i], err = p.publish(ctx, event); err != nil {
+			results[i].StatusCode = nethttp.StatusBadRequest
+			results[i].Error = err.Error()
+		}
+	}
+	for i, r := range published {
+		if r == nil {
+			continue
+		}
+		if _, err := r.Get(ctx); err != nil {
+			p.logger.Error("Error publishing to PubSub", zap.String("id", results[i].ID), zap.Error(err))
+			results[i].StatusCode = nethttp.StatusInternalServerError
+			results[i].Error = fmt.Sprintf("Error publishing to PubSub: %v", err)
+		}
+	}
+
+	if err := eventutil.WriteBatchResponse(response, nethttp.StatusAccepted, results); err != nil {
+		p.logger.Warn("Failed to write batch response", zap.Error(err))
+	}
+}
+
 // Publish publishes an incoming event to a pubsub topic.
 func (p *Publisher) Publish(ctx context.Context, event *cev2.Event) protocol.Result {
+	r, err := p.publish(ctx, event)
+	if err != nil {
+		return err
+	}
+	_, err = r.Get(ctx)
+	return err
+}
+
+// publish starts publishing an event to the pubsub topic, without waiting for the result.
+func (p *Publisher) publish(ctx context.Context, event *cev2.Event) (*pubsub.PublishResult, error) {
 	dt := tracing.FromSpanContext(trace.FromContext(ctx).SpanContext())
 	msg := new(pubsub.Message)
 	if err := cepubsub.WritePubSubMessage(ctx, binding.ToMessage(event), msg, dt.WriteTransformer()); err != nil {
-		return err
+		return nil, err
 	}
-	_, err := p.topic.Publish(ctx, msg).Get(ctx)
-	return err
+	return p.topic.Publish(ctx, msg), nil
 }
 
 // toEvent converts an http request to an event.
@@ -0,0 +1,149 @@
+/*
+Copyright 2021 Google LLC
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package publisher
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	nethttp "net/http"
+	"net/http/httptest"
+	"sort"
+	"testing"
+
+	"cloud.google.com/go/pubsub"
+	"cloud.google.com/go/pubsub/pstest"
+	"github.com/google/go-cmp/cmp"
+	"google.golang.org/api/option"
+	pubsubpb "google.golang.org/genproto/googleapis/pubsub/v1"
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
+	logtest "knative.dev/pkg/logging/testing"
+
+	"github.com/google/knative-gcp/pkg/broker/eventutil"
+)
+
+const (
+	testProjectID = "test-project"
+	testTopic     = "test-topic"
+)
+
+// failingPublish fails the publish requests containing an event whose ID is in fail.
+type failingPublish struct {
+	fail map[string]bool
+}
+
+func (r failingPublish) React(req interface{}) (bool, interface{}, error) {
+	for _, msg := range req.(*pubsubpb.PublishRequest).Messages {
+		if r.fail[msg.Attributes["ce-id"]] {
+			return true, nil, status.Error(codes.InvalidArgument, "injected error")
+		}
+	}
+	return false, nil, nil
+}
+
+// newTestPublisher creates a Publisher to a fake Pub/Sub topic. Each event is published in its own
+// request, so that the publish requests failing the events in fail do not fail other events.
+func newTestPublisher(ctx context.Context, t *testing.T, fail map[string]bool) (*Publisher, *pstest.Server) {
+	srv := pstest.NewServer(pstest.ServerReactorOption{FuncName: "Publish", Reactor: failingPublish{fail: fail}})
+	t.Cleanup(func() { srv.Close() })
+	conn, err := grpc.Dial(srv.Addr, grpc.WithInsecure())
+	if err != nil {
+		t.Fatal(err)
+	}
+	t.Cleanup(func() { conn.Close() })
+	c, err := pubsub.NewClient(ctx, testProjectID, option.WithGRPCConn(conn))
+	if err != nil {
+		t.Fatal(err)
+	}
+	topic, err := c.CreateTopic(ctx, testTopic)
+	if err != nil {
+		t.Fatal(err)
+	}
+	topic.PublishSettings.CountThreshold = 1
+	t.Cleanup(topic.Stop)
+	return NewPublisher(ctx, nil, topic, ""), srv
+}
+
+func TestServeBatch(t *testing.T) {
+	batch := `[{"specversion":"1.0","id":"1","source":"src","type":"type"},{"specversion":"1.0","id":"2","source":"src","type":"type"},{"specversion":"1.0","id":"3","source":"src","type":"type"}]`
+	tests := []struct {
+		name        string
+		body        string
+		fail        map[string]bool
+		wantCode    int
+		wantIDs     []string
+		wantResults []eventutil.BatchResult
+	}{{
+		name:     "valid batch",
+		body:     batch,
+		wantCode: nethttp.StatusAccepted,
+		wantIDs:  []string{"1", "2", "3"},
+	}, {
+		name:     "partial publish failure",
+		body:     batch,
+		fail:     map[string]bool{"2": true},
+		wantCode: nethttp.StatusInternalServerError,
+		wantIDs:  []string{"1", "3"},
+		wantResults: []eventutil.BatchResult{
+			{ID: "1", StatusCode: nethttp.StatusAccepted},
+			{ID: "2", StatusCode: nethttp.StatusInternalServerError, Error: "Error publishing to PubSub: rpc error: code = InvalidArgument desc = injected error"},
+			{ID: "3", StatusCode: nethttp.StatusAccepted},
+		},
+	}, {
+		name:     "malformed batch",
+		body:     `[{"specversion":"1.0","id":"1","source":"src","type":"type"},{"specversion":"1.0","id":"2"}]`,
+		wantCode: nethttp.StatusBadRequest,
+	}, {
+		name:     "not a batch",
+		body:     `{"specversion":"1.0","id":"1","source":"src","type":"type"}`,
+		wantCode: nethttp.StatusBadRequest,
+	}}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			ctx := logtest.TestContextWithLogger(t)
+			p, srv := newTestPublisher(ctx, t, tt.fail)
+
+			request := httptest.NewRequest(nethttp.MethodPost, "/", bytes.NewBufferString(tt.body))
+			request.Header.Set("Content-Type", "application/cloudevents-batch+json")
+			response := httptest.NewRecorder()
+			p.ServeHTTP(response, request)
+
+			if response.Code != tt.wantCode {
+				t.Errorf("response code got=%d, want=%d", response.Code, tt.wantCode)
+			}
+			var ids []string
+			for _, msg := range srv.Messages() {
+				ids = append(ids, msg.Attributes["ce-id"])
+			}
+			sort.Strings(ids)
+			if diff := cmp.Diff(tt.wantIDs, ids); diff != "" {
+				t.Errorf("published events (-want,+got): %v", diff)
+			}
+			if tt.wantResults != nil {
+				var results []eventutil.BatchResult
+				if err := json.Unmarshal(response.Body.Bytes(), &results); err != nil {
+					t.Fatalf("malformed response body %q: %v", response.Body.String(), err)
+				}
+				if diff := cmp.Diff(tt.wantResults, results); diff != "" {
+					t.Errorf("results (-want,+got): %v", diff)
+				}
+			}
+		})
+	}
+}
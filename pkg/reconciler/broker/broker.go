@@ -20,26 +20,56 @@ package broker
 
 import (
 	"context"
+	"errors"
 	"fmt"
+	"time"
 
+	"cloud.google.com/go/storage"
+	"github.com/google/knative-gcp/pkg/broker/claimcheck"
+	"github.com/google/knative-gcp/pkg/broker/config"
+	gstorage "github.com/google/knative-gcp/pkg/gclient/storage"
 	"github.com/google/knative-gcp/pkg/logging"
 	"github.com/google/knative-gcp/pkg/reconciler/celltenant"
+	reconcilerutils "github.com/google/knative-gcp/pkg/reconciler/utils"
 	"go.uber.org/zap"
 	corev1 "k8s.io/api/core/v1"
+	apierrs "k8s.io/apimachinery/pkg/api/errors"
+	"k8s.io/apimachinery/pkg/labels"
 	pkgreconciler "knative.dev/pkg/reconciler"
+	"knative.dev/pkg/system"
 
 	brokerv1 "github.com/google/knative-gcp/pkg/apis/broker/v1"
+	inteventsv1alpha1 "github.com/google/knative-gcp/pkg/apis/intevents/v1alpha1"
 	brokerreconciler "github.com/google/knative-gcp/pkg/client/injection/reconciler/broker/v1/broker"
+	brokerlisters "github.com/google/knative-gcp/pkg/client/listers/broker/v1"
 )
 
 const (
 	// Name of the corev1.Events emitted from the Broker reconciliation process.
 	brokerReconciled = "BrokerReconciled"
 	brokerFinalized  = "BrokerFinalized"
+	// Name of the warning corev1.Events emitted when the claim check payloads of a Broker can't
+	// be deleted.
+	claimCheckSweepFailed  = "ClaimCheckSweepFailed"
+	claimCheckDeleteFailed = "ClaimCheckDeleteFailed"
+	// Name of the warning corev1.Event emitted when the BrokerCell of a Broker doesn't allow the
+	// bucket of its claim check.
+	claimCheckBucketNotAllowed = "ClaimCheckBucketNotAllowed"
+
+	// claimCheckSweepPeriod is how often the payloads of the claim checks of the Brokers that are
+	// older than their retention are deleted.
+	claimCheckSweepPeriod = time.Hour
 )
 
 type Reconciler struct {
 	celltenant.Reconciler
+
+	// brokerLister lists the Brokers whose claim checks are swept.
+	brokerLister brokerlisters.BrokerLister
+
+	// createStorageClientFn is the function used to create the Storage client that deletes the
+	// payloads of the claim checks.
+	createStorageClientFn gstorage.CreateFn
 }
 
 // Check that Reconciler implements Interface
@@ -60,6 +90,16 @@ func (r *Reconciler) ReconcileKind(ctx context.Context, b *brokerv1.Broker) pkgr
 		// whatever info is available. or put this in a defer?
 	}
 
+	// The payloads are swept by runClaimCheckSweeper, so that listing them doesn't hold back the
+	// reconciliation.
+	if cc, allowed, err := r.claimCheck(b); err == nil && cc != nil && !allowed {
+		r.Recorder.Eventf(b, corev1.EventTypeWarning, claimCheckBucketNotAllowed,
+			"Claim check bucket %q is not allowed by BrokerCell %q, the payloads are kept in the events", cc.Bucket, inteventsv1alpha1.BrokerCellName(b))
+	}
+
+	// The BrokerCell controller annotates the Broker once its data plane has loaded it.
+	b.Status.PropagateDataPlaneObservedGeneration(brokerv1.DataPlaneObservedGeneration(b), b.Generation)
+
 	return pkgreconciler.NewEvent(corev1.EventTypeNormal, brokerReconciled, "Broker reconciled: \"%s/%s\"", b.Namespace, b.Name)
 }
 
@@ -70,6 +110,114 @@ func (r *Reconciler) FinalizeKind(ctx context.Context, b *brokerv1.Broker) pkgre
 	if err := r.Reconciler.FinalizeGCPCellTenant(ctx, bcs); err != nil {
 		return err
 	}
+	if err := r.deleteClaimCheckPayloads(ctx, b); err != nil {
+		// The deletion is best effort so that it never blocks the deletion of the Broker. The
+		// payloads left behind must be deleted by a lifecycle rule of the bucket.
+		logging.FromContext(ctx).Error("Problem deleting claim check payloads", zap.Error(err))
+		r.Recorder.Eventf(b, corev1.EventTypeWarning, claimCheckDeleteFailed, "Failed to delete claim check payloads: %v", err)
+	}
 
 	return pkgreconciler.NewEvent(corev1.EventTypeNormal, brokerFinalized, "Broker finalized: \"%s/%s\"", b.Namespace, b.Name)
 }
+
+// claimCheck returns the claim check of the Broker, if any, and whether the BrokerCell of the
+// Broker allows its bucket. The payloads are only deleted from the allowed buckets, since they are
+// deleted with the credentials of the controller.
+func (r *Reconciler) claimCheck(b *brokerv1.Broker) (*brokerv1.ClaimCheck, bool, error) {
+	cc, err := b.GetClaimCheck()
+	if err != nil || cc == nil {
+		// The webhook rejects malformed claim checks.
+		return nil, false, nil
+	}
+	bc, err := r.BrokerCellLister.BrokerCells(system.Namespace()).Get(inteventsv1alpha1.BrokerCellName(b))
+	if apierrs.IsNotFound(err) {
+		return cc, false, nil
+	}
+	if err != nil {
+		return nil, false, err
+	}
+	return cc, bc.ClaimCheckBucketAllowed(cc.Bucket), nil
+}
+
+// runClaimCheckSweeper sweeps the claim checks of all the Brokers every claimCheckSweepPeriod
+// until the context is done. It runs apart from the reconciliation of the Brokers, since listing
+// the payloads of a Broker may take long.
+func (r *Reconciler) runClaimCheckSweeper(ctx context.Context) {
+	ticker := time.NewTicker(claimCheckSweepPeriod)
+	defer ticker.Stop()
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+			r.sweepClaimChecks(ctx)
+		}
+	}
+}
+
+// sweepClaimChecks deletes the payloads of the claim checks of the Brokers that are older than
+// their retention. A failed sweep is retried at the next period, so it doesn't affect the readiness
+// of the Broker.
+func (r *Reconciler) sweepClaimChecks(ctx context.Context) {
+	brokers, err := r.brokerLister.List(labels.Everything())
+	if err != nil {
+		logging.FromContext(ctx).Error("Failed to list brokers", zap.Error(err))
+		return
+	}
+	for _, b := range brokers {
+		if ctx.Err() != nil {
+			return
+		}
+		if b.DeletionTimestamp != nil || !reconcilerutils.BrokerClassFilter(b) {
+			continue
+		}
+		if err := r.sweepClaimCheck(ctx, b); err != nil {
+			logging.FromContext(ctx).Error("Problem sweeping claim check payloads", zap.String("broker", b.Namespace+"/"+b.Name), zap.Error(err))
+			r.Recorder.Eventf(b, corev1.EventTypeWarning, claimCheckSweepFailed, "Failed to sweep claim check payloads: %v", err)
+		}
+	}
+}
+
+// sweepClaimCheck deletes the payloads of the claim check of the Broker, if any, that are older
+// than its retention.
+func (r *Reconciler) sweepClaimCheck(ctx context.Context, b *brokerv1.Broker) error {
+	cc, allowed, err := r.claimCheck(b)
+	if err != nil || cc == nil || !allowed {
+		return err
+	}
+	deleted, err := r.deletePayloads(ctx, cc.Bucket, b, time.Now().Add(-cc.Retention()))
+	if err != nil {
+		return err
+	}
+	if deleted > 0 {
+		logging.FromContext(ctx).Info("Deleted expired claim check payloads",
+			zap.String("broker", b.Namespace+"/"+b.Name), zap.String("bucket", cc.Bucket), zap.Int("count", deleted))
+	}
+	return nil
+}
+
+// deleteClaimCheckPayloads deletes all the payloads of the claim check of the deleted Broker, if
+// any.
+func (r *Reconciler) deleteClaimCheckPayloads(ctx context.Context, b *brokerv1.Broker) error {
+	cc, allowed, err := r.claimCheck(b)
+	if err != nil || cc == nil || !allowed {
+		return err
+	}
+	_, err = r.deletePayloads(ctx, cc.Bucket, b, time.Time{})
+	return err
+}
+
+// deletePayloads deletes the payloads of the Broker stored in the bucket before the given time,
+// or all of them if the time is zero. A bucket that no longer exists has no payloads.
+func (r *Reconciler) deletePayloads(ctx context.Context, bucket string, b *brokerv1.Broker, before time.Time) (int, error) {
+	client, err := r.createStorageClientFn(ctx)
+	if err != nil {
+		return 0, err
+	}
+	defer client.Close()
+	deleted, err := claimcheck.DeletePayloads(ctx, client, bucket, config.KeyFromBroker(b), before)
+	if errors.Is(err, storage.ErrBucketNotExist) {
+		return deleted, nil
+	}
+	return deleted, err
+}
@@ -18,11 +18,16 @@ package broker
 
 import (
 	"context"
+	"errors"
 	"fmt"
 	"testing"
+	"time"
 
 	"github.com/google/knative-gcp/pkg/reconciler/celltenant"
 
+	"cloud.google.com/go/storage"
+	gstoragetesting "github.com/google/knative-gcp/pkg/gclient/storage/testing"
+
 	"cloud.google.com/go/pubsub"
 	"cloud.google.com/go/pubsub/pstest"
 	"github.com/google/knative-gcp/pkg/broker/ingress"
@@ -31,6 +36,7 @@ import (
 	"k8s.io/apimachinery/pkg/runtime"
 	"k8s.io/client-go/kubernetes/scheme"
 	clientgotesting "k8s.io/client-go/testing"
+	"k8s.io/client-go/tools/record"
 
 	eventingduckv1 "knative.dev/eventing/pkg/apis/duck/v1"
 	"knative.dev/pkg/apis"
@@ -71,6 +77,8 @@ var (
 
 	testKey = fmt.Sprintf("%s/%s", testNS, brokerName)
 
+	testReplay = `{"id":"fix-1","time":"2021-05-01T00:00:00Z"}`
+
 	brokerFinalizerUpdatedEvent = Eventf(corev1.EventTypeNormal, "FinalizerUpdate", `Updated "test-broker" finalizers`)
 	brokerReconciledEvent       = Eventf(corev1.EventTypeNormal, "BrokerReconciled", `Broker reconciled: "testnamespace/test-broker"`)
 	brokerFinalizedEvent        = Eventf(corev1.EventTypeNormal, "BrokerFinalized", `Broker finalized: "testnamespace/test-broker"`)
@@ -153,6 +161,33 @@ func TestAllCases(t *testing.T) {
 			NoTopicsExist(),
 			NoSubscriptionsExist(),
 		},
+	}, {
+		Name: "Broker is being deleted, claim check payload deletion fails",
+		Key:  testKey,
+		Objects: []runtime.Object{
+			NewBroker(brokerName, testNS,
+				WithBrokerClass(brokerv1.BrokerClass),
+				WithBrokerAnnotations(map[string]string{brokerv1.ClaimCheckAnnotation: `{"bucket":"payloads"}`}),
+				WithInitBrokerConditions,
+				WithBrokerDeletionTimestamp,
+				WithBrokerSetDefaults,
+			),
+			NewBrokerCell(resources.DefaultBrokerCellName, systemNS,
+				WithBrokerCellClaimCheckBuckets("payloads"),
+				WithBrokerCellSetDefaults),
+		},
+		WantEvents: []string{
+			Eventf(corev1.EventTypeWarning, claimCheckDeleteFailed, "Failed to delete claim check payloads: create client failed"),
+			brokerFinalizedEvent,
+		},
+		OtherTestData: map[string]interface{}{
+			"pre":               []PubsubAction{},
+			"storageClientData": gstoragetesting.TestClientData{CreateClientErr: errors.New("create client failed")},
+		},
+		PostConditions: []func(*testing.T, *TableRow){
+			NoTopicsExist(),
+			NoSubscriptionsExist(),
+		},
 	}, {
 		Name: "Create broker with ready brokercell, broker is created",
 		Key:  testKey,
@@ -173,6 +208,7 @@ func TestAllCases(t *testing.T) {
 				WithBrokerDeliverySpec(brokerDeliverySpec),
 				WithBrokerReadyURI(brokerAddress),
 				WithBrokerSetDefaults,
+				WithBrokerDataPlaneObservedGeneration(0),
 			),
 		}},
 		WantEvents: []string{
@@ -191,6 +227,92 @@ func TestAllCases(t *testing.T) {
 			TopicExists("cre-bkr_testnamespace_test-broker_abc123"),
 			SubscriptionExists("cre-bkr_testnamespace_test-broker_abc123"),
 		},
+	}, {
+		Name: "Create broker with a claim check bucket not allowed by the brokercell",
+		Key:  testKey,
+		Objects: []runtime.Object{
+			NewBroker(brokerName, testNS,
+				WithBrokerClass(brokerv1.BrokerClass),
+				WithBrokerAnnotations(map[string]string{brokerv1.ClaimCheckAnnotation: `{"bucket":"payloads"}`}),
+				WithBrokerUID(testUID),
+				WithBrokerDeliverySpec(brokerDeliverySpec),
+				WithBrokerSetDefaults),
+			NewBrokerCell(resources.DefaultBrokerCellName, systemNS,
+				WithBrokerCellReady,
+				WithBrokerCellClaimCheckBuckets("other"),
+				WithBrokerCellSetDefaults),
+		},
+		WantStatusUpdates: []clientgotesting.UpdateActionImpl{{
+			Object: NewBroker(brokerName, testNS,
+				WithBrokerClass(brokerv1.BrokerClass),
+				WithBrokerAnnotations(map[string]string{brokerv1.ClaimCheckAnnotation: `{"bucket":"payloads"}`}),
+				WithBrokerUID(testUID),
+				WithBrokerDeliverySpec(brokerDeliverySpec),
+				WithBrokerReadyURI(brokerAddress),
+				WithBrokerSetDefaults,
+				WithBrokerDataPlaneObservedGeneration(0),
+			),
+		}},
+		WantEvents: []string{
+			brokerFinalizerUpdatedEvent,
+			Eventf(corev1.EventTypeNormal, "TopicCreated", `Created PubSub topic "cre-bkr_testnamespace_test-broker_abc123"`),
+			Eventf(corev1.EventTypeNormal, "SubscriptionCreated", `Created PubSub subscription "cre-bkr_testnamespace_test-broker_abc123"`),
+			Eventf(corev1.EventTypeWarning, claimCheckBucketNotAllowed, `Claim check bucket "payloads" is not allowed by BrokerCell %q, the payloads are kept in the events`, resources.DefaultBrokerCellName),
+			brokerReconciledEvent,
+		},
+		WantPatches: []clientgotesting.PatchActionImpl{
+			patchFinalizers(testNS, brokerName, brokerFinalizerName),
+		},
+		OtherTestData: map[string]interface{}{
+			"pre": []PubsubAction{},
+		},
+		PostConditions: []func(*testing.T, *TableRow){
+			TopicExists("cre-bkr_testnamespace_test-broker_abc123"),
+			SubscriptionExists("cre-bkr_testnamespace_test-broker_abc123"),
+		},
+	}, {
+		Name: "Create broker with replay, subscription is seeked",
+		Key:  testKey,
+		Objects: []runtime.Object{
+			NewBroker(brokerName, testNS,
+				WithBrokerClass(brokerv1.BrokerClass),
+				WithBrokerAnnotations(map[string]string{brokerv1.ReplayAnnotation: testReplay}),
+				WithBrokerUID(testUID),
+				WithBrokerDeliverySpec(brokerDeliverySpec),
+				WithBrokerSetDefaults),
+			NewBrokerCell(resources.DefaultBrokerCellName, systemNS,
+				WithBrokerCellReady,
+				WithBrokerCellSetDefaults),
+		},
+		WantStatusUpdates: []clientgotesting.UpdateActionImpl{{
+			Object: NewBroker(brokerName, testNS,
+				WithBrokerClass(brokerv1.BrokerClass),
+				WithBrokerAnnotations(map[string]string{brokerv1.ReplayAnnotation: testReplay}),
+				WithBrokerUID(testUID),
+				WithBrokerDeliverySpec(brokerDeliverySpec),
+				WithBrokerReadyURI(brokerAddress),
+				WithBrokerLastReplay(`{"id":"fix-1","time":"2021-05-01T00:00:00Z"}`),
+				WithBrokerSetDefaults,
+				WithBrokerDataPlaneObservedGeneration(0),
+			),
+		}},
+		WantEvents: []string{
+			brokerFinalizerUpdatedEvent,
+			Eventf(corev1.EventTypeNormal, "TopicCreated", `Created PubSub topic "cre-bkr_testnamespace_test-broker_abc123"`),
+			Eventf(corev1.EventTypeNormal, "SubscriptionCreated", `Created PubSub subscription "cre-bkr_testnamespace_test-broker_abc123"`),
+			Eventf(corev1.EventTypeNormal, "SubscriptionSeeked", `Seeked PubSub subscription "cre-bkr_testnamespace_test-broker_abc123" to 2021-05-01T00:00:00Z`),
+			brokerReconciledEvent,
+		},
+		WantPatches: []clientgotesting.PatchActionImpl{
+			patchFinalizers(testNS, brokerName, brokerFinalizerName),
+		},
+		OtherTestData: map[string]interface{}{
+			"pre": []PubsubAction{},
+		},
+		PostConditions: []func(*testing.T, *TableRow){
+			TopicExists("cre-bkr_testnamespace_test-broker_abc123"),
+			SubscriptionHasRetention("cre-bkr_testnamespace_test-broker_abc123", true, brokerv1.DefaultReplayRetention),
+		},
 	}, {
 		Name: "Create broker with unready brokercell, broker is created",
 		Key:  testKey,
@@ -214,6 +336,7 @@ func TestAllCases(t *testing.T) {
 				WithBrokerReadyURI(brokerAddress),
 				WithBrokerBrokerCellUnknown("BrokerCellNotReady", "BrokerCell knative-testing/default is not ready"),
 				WithBrokerSetDefaults,
+				WithBrokerDataPlaneObservedGeneration(0),
 			),
 		}},
 		WantEvents: []string{
@@ -258,7 +381,7 @@ func TestAllCases(t *testing.T) {
 				),
 			},
 		},
-		WantCreates:             []runtime.Object{resources.CreateBrokerCell(nil) /*Currently brokercell doesn't require broker information*/},
+		WantCreates:             []runtime.Object{resources.CreateBrokerCell(resources.DefaultBrokerCellName)},
 		SkipNamespaceValidation: true, // The brokercell resource is created in a different namespace (system namespace) than the broker
 		WantEvents: []string{
 			brokerFinalizerUpdatedEvent,
@@ -288,10 +411,11 @@ func TestAllCases(t *testing.T) {
 					WithBrokerReadyURI(brokerAddress),
 					WithBrokerBrokerCellUnknown("BrokerCellNotReady", "BrokerCell knative-testing/default is not ready"),
 					WithBrokerSetDefaults,
+					WithBrokerDataPlaneObservedGeneration(0),
 				),
 			},
 		},
-		WantCreates:             []runtime.Object{resources.CreateBrokerCell(nil) /*Currently brokercell doesn't require broker information*/},
+		WantCreates:             []runtime.Object{resources.CreateBrokerCell(resources.DefaultBrokerCellName)},
 		SkipNamespaceValidation: true, // The brokercell resource is created in a different namespace (system namespace) than the broker
 		WantEvents: []string{
 			brokerFinalizerUpdatedEvent,
@@ -334,6 +458,7 @@ func TestAllCases(t *testing.T) {
 				WithBrokerDeliverySpec(brokerDeliverySpec),
 				WithBrokerReadyURI(brokerAddress),
 				WithBrokerSetDefaults,
+				WithBrokerDataPlaneObservedGeneration(0),
 			),
 		}},
 		WantEvents: []string{
@@ -379,6 +504,7 @@ func TestAllCases(t *testing.T) {
 				WithBrokerDeliverySpec(brokerDeliverySpec),
 				WithBrokerReadyURI(brokerAddress),
 				WithBrokerSetDefaults,
+				WithBrokerDataPlaneObservedGeneration(0),
 			),
 		}},
 		WantEvents: []string{
@@ -485,6 +611,9 @@ func TestAllCases(t *testing.T) {
 				ClusterRegion:      testClusterRegion,
 			},
 		}
+		if data, ok := testData["storageClientData"]; ok {
+			r.createStorageClientFn = gstoragetesting.TestClientCreator(data.(gstoragetesting.TestClientData))
+		}
 		return brokerreconciler.NewReconciler(ctx, r.Logger, r.RunClientSet, listers.GetBrokerLister(), r.Recorder, r, brokerv1.BrokerClass)
 	}))
 }
@@ -497,3 +626,135 @@ func patchFinalizers(namespace, name, finalizer string) clientgotesting.PatchAct
 	action.Patch = []byte(patch)
 	return action
 }
+
+func TestSweepClaimChecks(t *testing.T) {
+	now := time.Now()
+	cases := []struct {
+		name        string
+		claimCheck  string
+		buckets     []string
+		deleted     bool
+		data        gstoragetesting.TestClientData
+		wantObjects []string
+		wantEvent   bool
+	}{{
+		name:        "no claim check",
+		wantObjects: []string{"new", "old"},
+	}, {
+		name:        "deletes expired payloads",
+		claimCheck:  `{"bucket":"payloads","retentionDays":1}`,
+		buckets:     []string{"payloads"},
+		wantObjects: []string{"new"},
+	}, {
+		name:        "default retention",
+		claimCheck:  `{"bucket":"payloads"}`,
+		buckets:     []string{"payloads"},
+		wantObjects: []string{"new", "old"},
+	}, {
+		name:        "bucket not allowed",
+		claimCheck:  `{"bucket":"payloads","retentionDays":1}`,
+		buckets:     []string{"other"},
+		wantObjects: []string{"new", "old"},
+	}, {
+		name:        "broker being deleted",
+		claimCheck:  `{"bucket":"payloads","retentionDays":1}`,
+		buckets:     []string{"payloads"},
+		deleted:     true,
+		wantObjects: []string{"new", "old"},
+	}, {
+		name:        "bucket does not exist",
+		claimCheck:  `{"bucket":"payloads","retentionDays":1}`,
+		buckets:     []string{"payloads"},
+		data:        gstoragetesting.TestClientData{BucketData: gstoragetesting.TestBucketData{ObjectErr: storage.ErrBucketNotExist}},
+		wantObjects: []string{"new", "old"},
+	}, {
+		name:        "client creation error",
+		claimCheck:  `{"bucket":"payloads"}`,
+		buckets:     []string{"payloads"},
+		data:        gstoragetesting.TestClientData{CreateClientErr: errors.New("create client failed")},
+		wantObjects: []string{"new", "old"},
+		wantEvent:   true,
+	}, {
+		name:       "list error",
+		claimCheck: `{"bucket":"payloads"}`,
+		buckets:    []string{"payloads"},
+		data: gstoragetesting.TestClientData{
+			BucketData: gstoragetesting.TestBucketData{ObjectErr: errors.New("list failed")},
+		},
+		wantObjects: []string{"new", "old"},
+		wantEvent:   true,
+	}}
+	for _, tc := range cases {
+		t.Run(tc.name, func(t *testing.T) {
+			prefix := testNS + "/" + brokerName + "/"
+			objects := map[string][]byte{prefix + "old": nil, prefix + "new": nil}
+			tc.data.BucketData.Objects = objects
+			tc.data.BucketData.Created = map[string]time.Time{
+				prefix + "old": now.Add(-48 * time.Hour),
+				prefix + "new": now,
+			}
+			opts := []BrokerOption{WithBrokerClass(brokerv1.BrokerClass)}
+			if tc.claimCheck != "" {
+				opts = append(opts, WithBrokerAnnotations(map[string]string{brokerv1.ClaimCheckAnnotation: tc.claimCheck}))
+			}
+			if tc.deleted {
+				opts = append(opts, WithBrokerDeletionTimestamp)
+			}
+			listers := NewListers([]runtime.Object{
+				NewBroker(brokerName, testNS, opts...),
+				NewBrokerCell(resources.DefaultBrokerCellName, systemNS, WithBrokerCellClaimCheckBuckets(tc.buckets...)),
+			})
+			recorder := record.NewFakeRecorder(1)
+			r := &Reconciler{
+				Reconciler: celltenant.Reconciler{
+					Base:             &reconciler.Base{Recorder: recorder},
+					BrokerCellLister: listers.GetBrokerCellLister(),
+				},
+				brokerLister:          listers.GetBrokerLister(),
+				createStorageClientFn: gstoragetesting.TestClientCreator(tc.data),
+			}
+			r.sweepClaimChecks(context.Background())
+			for _, name := range []string{"new", "old"} {
+				_, got := objects[prefix+name]
+				want := false
+				for _, w := range tc.wantObjects {
+					want = want || w == name
+				}
+				if got != want {
+					t.Errorf("payload %q kept got=%t, want=%t", name, got, want)
+				}
+			}
+			if gotEvent := len(recorder.Events) > 0; gotEvent != tc.wantEvent {
+				t.Errorf("sweep failed event got=%t, want=%t", gotEvent, tc.wantEvent)
+			}
+		})
+	}
+}
+
+func TestDeleteClaimCheckPayloads(t *testing.T) {
+	prefix := testNS + "/" + brokerName + "/"
+	objects := map[string][]byte{prefix + "new": nil, "other/" + brokerName + "/new": nil}
+	listers := NewListers([]runtime.Object{
+		NewBrokerCell(resources.DefaultBrokerCellName, systemNS, WithBrokerCellClaimCheckBuckets("payloads")),
+	})
+	r := &Reconciler{
+		Reconciler: celltenant.Reconciler{BrokerCellLister: listers.GetBrokerCellLister()},
+		createStorageClientFn: gstoragetesting.TestClientCreator(gstoragetesting.TestClientData{
+			BucketData: gstoragetesting.TestBucketData{
+				Objects: objects,
+				Created: map[string]time.Time{prefix + "new": time.Now()},
+			},
+		}),
+	}
+	b := NewBroker(brokerName, testNS)
+	b.SetAnnotations(map[string]string{brokerv1.ClaimCheckAnnotation: `{"bucket":"payloads"}`})
+	if err := r.deleteClaimCheckPayloads(context.Background(), b); err != nil {
+		t.Fatalf("deleteClaimCheckPayloads() error = %v", err)
+	}
+	if _, ok := objects[prefix+"new"]; ok {
+		t.Error("payload of the deleted Broker was not deleted")
+	}
+	if _, ok := objects["other/"+brokerName+"/new"]; !ok {
+		t.Error("payload of another Broker was deleted")
+	}
+}
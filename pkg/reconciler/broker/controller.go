@@ -39,6 +39,7 @@ import (
 	brokerinformer "github.com/google/knative-gcp/pkg/client/injection/informers/broker/v1/broker"
 	brokercellinformer "github.com/google/knative-gcp/pkg/client/injection/informers/intevents/v1alpha1/brokercell"
 	brokerreconciler "github.com/google/knative-gcp/pkg/client/injection/reconciler/broker/v1/broker"
+	gstorage "github.com/google/knative-gcp/pkg/gclient/storage"
 	"github.com/google/knative-gcp/pkg/reconciler"
 	reconcilerutils "github.com/google/knative-gcp/pkg/reconciler/utils"
 	"github.com/google/knative-gcp/pkg/utils"
@@ -93,6 +94,8 @@ func newController(ctx context.Context, cmw configmap.Watcher, brds *brokerdeliv
 			PubsubClient:       client,
 			DataresidencyStore: drs,
 		},
+		brokerLister:          brokerInformer.Lister(),
+		createStorageClientFn: gstorage.NewClient,
 	}
 
 	impl := brokerreconciler.NewImpl(ctx, r, brokerv1.BrokerClass,
@@ -102,6 +105,8 @@ func newController(ctx context.Context, cmw configmap.Watcher, brds *brokerdeliv
 			}
 		})
 
+	go r.runClaimCheckSweeper(ctx)
+
 	r.Logger.Info("Setting up event handlers")
 
 	brokerInformer.Informer().AddEventHandlerWithResyncPeriod(
@@ -115,17 +120,18 @@ func newController(ctx context.Context, cmw configmap.Watcher, brds *brokerdeliv
 
 	bcInformer.Informer().AddEventHandler(controller.HandleAll(
 		func(obj interface{}) {
-			if _, ok := obj.(*inteventsv1alpha1.BrokerCell); ok {
-				// TODO(#866) Only select brokers that point to this brokercell by label selector once the
-				// webhook assigns the brokercell label, i.e.,
-				// r.brokerLister.List(labels.SelectorFromSet(map[string]string{"brokercell":bc.Name, "brokercellns":bc.Namespace}))
+			if bc, ok := obj.(*inteventsv1alpha1.BrokerCell); ok {
+				// Brokers without the BrokerCell label belong to the default BrokerCell, so they
+				// can't be selected by label.
 				brokers, err := brokerInformer.Lister().List(labels.Everything())
 				if err != nil {
 					r.Logger.Error("Failed to list brokers", zap.Error(err))
 					return
 				}
 				for _, broker := range brokers {
-					impl.Enqueue(broker)
+					if reconcilerutils.InBrokerCell(broker, bc) {
+						impl.Enqueue(broker)
+					}
 				}
 			}
 		},
@@ -17,25 +17,23 @@ limitations under the License.
 package resources
 
 import (
-	brokerv1 "github.com/google/knative-gcp/pkg/apis/broker/v1"
 	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
 	"knative.dev/pkg/system"
 
 	inteventsv1alpha1 "github.com/google/knative-gcp/pkg/apis/intevents/v1alpha1"
 )
 
-// Right now we only support one brokercell in the system namespace in the cluster.
-// TODO(#866) Delete hard-coded brokercell once we can dynamically assign brokercell to brokers.
-const DefaultBrokerCellName = "default"
+// DefaultBrokerCellName is the name of the BrokerCell of the Brokers and Channels that don't
+// select one.
+const DefaultBrokerCellName = inteventsv1alpha1.DefaultBrokerCellName
 
-// TODO Replace the Broker parameter with a generic CellTenant interface that has a method to get
-// the name of the BrokerCell to create.
-func CreateBrokerCell(_ *brokerv1.Broker) *inteventsv1alpha1.BrokerCell {
-	// TODO(#866) Get brokercell from the label (or annotation) from the broker.
+// CreateBrokerCell returns the BrokerCell with the given name to create in the system namespace
+// when a Broker or Channel selects it, and it doesn't exist.
+func CreateBrokerCell(name string) *inteventsv1alpha1.BrokerCell {
 	return &inteventsv1alpha1.BrokerCell{
 		ObjectMeta: metav1.ObjectMeta{
 			Namespace:   system.Namespace(),
-			Name:        DefaultBrokerCellName,
+			Name:        name,
 			Annotations: map[string]string{inteventsv1alpha1.CreatorKey: inteventsv1alpha1.Creator},
 		},
 	}
@@ -196,6 +196,35 @@ func TestGenerateRetrySubscriptionName(t *testing.T) {
 	}
 }
 
+func TestGenerateReplaySubscriptionName(t *testing.T) {
+	testCases := []struct {
+		ns   string
+		n    string
+		uid  string
+		want string
+	}{{
+		ns:   "default",
+		n:    "default",
+		uid:  testUID,
+		want: fmt.Sprintf("cre-tgr-replay_default_default_%s", testUID),
+	}, {
+		ns:   "default",
+		n:    maxName,
+		uid:  testUID,
+		want: fmt.Sprintf("cre-tgr-replay_default_%s_%s", strings.Repeat("n", truncatedNameMaxForBkrTgr+(naming.K8sNamespaceMax-7)-7), testUID),
+	}}
+
+	for _, tc := range testCases {
+		got := GenerateReplaySubscriptionName(trigger(tc.ns, tc.n, tc.uid))
+		if len(got) > naming.PubsubMax {
+			t.Errorf("name length %d is greater than %d", len(got), naming.PubsubMax)
+		}
+		if diff := cmp.Diff(tc.want, got); diff != "" {
+			t.Errorf("unexpected (want, +got) = %v", diff)
+		}
+	}
+}
+
 func broker(ns, n, uid string) *brokerv1.Broker {
 	return &brokerv1.Broker{
 		ObjectMeta: metav1.ObjectMeta{
@@ -0,0 +1,153 @@
+/*
+Copyright 2021 Google LLC
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package brokercell
+
+import (
+	"context"
+	"fmt"
+	"sort"
+
+	appsv1 "k8s.io/api/apps/v1"
+	corev1 "k8s.io/api/core/v1"
+	"k8s.io/apimachinery/pkg/api/equality"
+	apierrs "k8s.io/apimachinery/pkg/api/errors"
+	"k8s.io/apimachinery/pkg/api/meta"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/apis/meta/v1/unstructured"
+	"k8s.io/apimachinery/pkg/types"
+
+	intv1alpha1 "github.com/google/knative-gcp/pkg/apis/intevents/v1alpha1"
+	"github.com/google/knative-gcp/pkg/reconciler/brokercell/resources"
+	kedaresources "github.com/google/knative-gcp/pkg/reconciler/intevents/pullsubscription/keda/resources"
+)
+
+// reconcileComponentAutoscaling reconciles the autoscaler of a component deployment: a KEDA
+// ScaledObject if the component is scaled on its backlog by KEDA, or an HPA otherwise. The
+// autoscaler that is not used is deleted, so that they don't both scale the deployment.
+func (r *Reconciler) reconcileComponentAutoscaling(ctx context.Context, bc *intv1alpha1.BrokerCell, d *appsv1.Deployment, args resources.AutoscalingArgs) error {
+	if args.UseKEDA() {
+		if err := r.reconcileScaledObject(ctx, bc, resources.MakeScaledObject(d, args)); err != nil {
+			return err
+		}
+		return r.deleteHorizontalPodAutoscaler(ctx, bc, d.Namespace, resources.HorizontalPodAutoscalerName(d))
+	}
+	if err := r.reconcileAutoscaling(ctx, bc, resources.MakeHorizontalPodAutoscaler(d, args)); err != nil {
+		return err
+	}
+	// KEDA creates an HPA for each ScaledObject, so there is no ScaledObject to delete
+	// without it. This saves a request to the API server on every reconcile.
+	if _, err := r.hpaLister.HorizontalPodAutoscalers(d.Namespace).Get(resources.KEDAHorizontalPodAutoscalerName(d)); apierrs.IsNotFound(err) {
+		return nil
+	}
+	return r.deleteScaledObject(ctx, bc, d.Namespace, resources.ScaledObjectName(d))
+}
+
+func (r *Reconciler) reconcileScaledObject(ctx context.Context, bc *intv1alpha1.BrokerCell, desired *unstructured.Unstructured) error {
+	gvr, _ := meta.UnsafeGuessKindToResource(kedaresources.ScaledObjectGVK)
+	client := r.DynamicClientSet.Resource(gvr).Namespace(desired.GetNamespace())
+	existing, err := client.Get(ctx, desired.GetName(), metav1.GetOptions{})
+	if apierrs.IsNotFound(err) {
+		_, err = client.Create(ctx, desired, metav1.CreateOptions{})
+		if err != nil {
+			return fmt.Errorf("failed to create ScaledObject: %w", err)
+		}
+		r.Recorder.Eventf(bc, corev1.EventTypeNormal, "ScaledObjectCreated", "Created ScaledObject %s/%s", desired.GetNamespace(), desired.GetName())
+		return nil
+	}
+	if err != nil {
+		return fmt.Errorf("failed to get ScaledObject: %w", err)
+	}
+
+	if !equality.Semantic.DeepDerivative(desired.Object["spec"], existing.Object["spec"]) {
+		copy := existing.DeepCopy()
+		copy.Object["spec"] = desired.Object["spec"]
+		if _, err := client.Update(ctx, copy, metav1.UpdateOptions{}); err != nil {
+			return fmt.Errorf("failed to update ScaledObject: %w", err)
+		}
+		r.Recorder.Eventf(bc, corev1.EventTypeNormal, "ScaledObjectUpdated", "Updated ScaledObject %s/%s", desired.GetNamespace(), desired.GetName())
+	}
+	return nil
+}
+
+func (r *Reconciler) deleteScaledObject(ctx context.Context, bc *intv1alpha1.BrokerCell, namespace, name string) error {
+	gvr, _ := meta.UnsafeGuessKindToResource(kedaresources.ScaledObjectGVK)
+	err := r.DynamicClientSet.Resource(gvr).Namespace(namespace).Delete(ctx, name, metav1.DeleteOptions{})
+	if apierrs.IsNotFound(err) {
+		return nil
+	}
+	if err != nil {
+		return fmt.Errorf("failed to delete ScaledObject: %w", err)
+	}
+	r.Recorder.Eventf(bc, corev1.EventTypeNormal, "ScaledObjectDeleted", "Deleted ScaledObject %s/%s", namespace, name)
+	return nil
+}
+
+func (r *Reconciler) deleteHorizontalPodAutoscaler(ctx context.Context, bc *intv1alpha1.BrokerCell, namespace, name string) error {
+	if _, err := r.hpaLister.HorizontalPodAutoscalers(namespace).Get(name); apierrs.IsNotFound(err) {
+		return nil
+	}
+	err := r.KubeClientSet.AutoscalingV2beta2().HorizontalPodAutoscalers(namespace).Delete(ctx, name, metav1.DeleteOptions{})
+	if apierrs.IsNotFound(err) {
+		return nil
+	}
+	if err != nil {
+		return err
+	}
+	r.Recorder.Eventf(bc, corev1.EventTypeNormal, "HorizontalPodAutoscalerDeleted", "Deleted HPA %s/%s", namespace, name)
+	return nil
+}
+
+// backlogSubscriptions returns the sorted IDs of the decouple subscriptions, or of the retry
+// subscriptions if retry is true, in the targets config of the BrokerCell.
+func (r *Reconciler) backlogSubscriptions(bc *intv1alpha1.BrokerCell, retry bool) []string {
+	tc := r.targetsTracker.committed(types.NamespacedName{Namespace: bc.Namespace, Name: bc.Name})
+	var subs []string
+	for _, tenant := range tc.GetCellTenants() {
+		if !retry {
+			if sub := tenant.GetDecoupleQueue().GetSubscription(); sub != "" {
+				subs = append(subs, sub)
+			}
+			continue
+		}
+		for _, target := range tenant.GetTargets() {
+			if sub := target.GetRetryQueue().GetSubscription(); sub != "" {
+				subs = append(subs, sub)
+			}
+		}
+	}
+	sort.Strings(subs)
+	return subs
+}
+
+// backlogAutoscalingArgs sets the backlog autoscaling of the component in args.
+func (r *Reconciler) backlogAutoscalingArgs(bc *intv1alpha1.BrokerCell, params *intv1alpha1.ComponentParameters, retry bool, args resources.AutoscalingArgs) resources.AutoscalingArgs {
+	if params.BacklogAutoscaling == nil {
+		return args
+	}
+	args.BacklogAutoscaling = params.BacklogAutoscaling
+	args.BacklogSubscriptions = r.backlogSubscriptions(bc, retry)
+	args.BacklogQueue = resources.BacklogQueueDecouple
+	if retry {
+		args.BacklogQueue = resources.BacklogQueueRetry
+	}
+	return args
+}
+
+// usesKEDA returns whether the component may be scaled by KEDA.
+func usesKEDA(params *intv1alpha1.ComponentParameters) bool {
+	return params.BacklogAutoscaling != nil && params.BacklogAutoscaling.Class == intv1alpha1.BacklogAutoscalingKEDA
+}
@@ -0,0 +1,133 @@
+/*
+Copyright 2021 Google LLC
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package brokercell
+
+import (
+	"context"
+	"testing"
+
+	"github.com/google/go-cmp/cmp"
+	appsv1 "k8s.io/api/apps/v1"
+	hpav2beta2 "k8s.io/api/autoscaling/v2beta2"
+	apierrs "k8s.io/apimachinery/pkg/api/errors"
+	"k8s.io/apimachinery/pkg/api/meta"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/apis/meta/v1/unstructured"
+	"k8s.io/apimachinery/pkg/runtime"
+	"k8s.io/apimachinery/pkg/types"
+	fakekubeclient "knative.dev/pkg/client/injection/kube/client/fake"
+	"knative.dev/pkg/configmap"
+	fakedynamicclient "knative.dev/pkg/injection/clients/dynamicclient/fake"
+	"knative.dev/pkg/ptr"
+	. "knative.dev/pkg/reconciler/testing"
+
+	intv1alpha1 "github.com/google/knative-gcp/pkg/apis/intevents/v1alpha1"
+	"github.com/google/knative-gcp/pkg/broker/config"
+	"github.com/google/knative-gcp/pkg/broker/config/memory"
+	"github.com/google/knative-gcp/pkg/reconciler"
+	"github.com/google/knative-gcp/pkg/reconciler/brokercell/resources"
+	kedaresources "github.com/google/knative-gcp/pkg/reconciler/intevents/pullsubscription/keda/resources"
+	. "github.com/google/knative-gcp/pkg/reconciler/testing"
+)
+
+func TestReconcileComponentAutoscaling(t *testing.T) {
+	setReconcilerEnv()
+	bc := NewBrokerCell(brokerCellName, testNS)
+	bc.Spec.Components.Fanout = &intv1alpha1.ComponentParameters{
+		AvgCPUUtilization:  ptr.Int32(50),
+		BacklogAutoscaling: &intv1alpha1.BacklogAutoscalingSpec{Class: intv1alpha1.BacklogAutoscalingKEDA, TargetBacklog: ptr.Int64(10)},
+	}
+	bc.SetDefaults(context.Background())
+	d := &appsv1.Deployment{ObjectMeta: metav1.ObjectMeta{Namespace: testNS, Name: resources.Name(brokerCellName, resources.FanoutName)}}
+	hpa := &hpav2beta2.HorizontalPodAutoscaler{ObjectMeta: metav1.ObjectMeta{Namespace: testNS, Name: resources.HorizontalPodAutoscalerName(d)}}
+	kedaHPA := &hpav2beta2.HorizontalPodAutoscaler{ObjectMeta: metav1.ObjectMeta{Namespace: testNS, Name: resources.KEDAHorizontalPodAutoscalerName(d)}}
+
+	ctx, _ := SetupFakeContext(t)
+	ctx, kubeClient := fakekubeclient.With(ctx, hpa)
+	ctx, dynamicClient := fakedynamicclient.With(ctx, runtime.NewScheme())
+	r, err := NewReconciler(reconciler.NewBase(ctx, controllerAgentName, configmap.NewStaticWatcher()), listers{})
+	if err != nil {
+		t.Fatalf("Failed to create BrokerCell reconciler: %v", err)
+	}
+	bcKey := types.NamespacedName{Namespace: testNS, Name: brokerCellName}
+	r.targetsTracker.begin(bcKey)
+	r.targetsTracker.commit(bcKey, memory.NewTargets(&config.TargetsConfig{CellTenants: map[string]*config.CellTenant{
+		"broker1": {
+			Name:          "broker1",
+			DecoupleQueue: &config.Queue{Subscription: "decouple-2"},
+			Targets: map[string]*config.Target{
+				"trigger": {Name: "trigger", RetryQueue: &config.Queue{Subscription: "retry-1"}},
+			},
+		},
+		"broker2": {Name: "broker2", DecoupleQueue: &config.Queue{Subscription: "decouple-1"}},
+	}}), true)
+
+	// KEDA replaces the HPA.
+	hpaListers := NewListers([]runtime.Object{hpa})
+	r.hpaLister = hpaListers.GetHPALister()
+	if err := r.reconcileComponentAutoscaling(ctx, bc, d, r.makeFanoutHPAArgs(bc)); err != nil {
+		t.Fatalf("Failed to reconcile the autoscaling: %v", err)
+	}
+	gvr, _ := meta.UnsafeGuessKindToResource(kedaresources.ScaledObjectGVK)
+	so, err := dynamicClient.Resource(gvr).Namespace(testNS).Get(ctx, resources.ScaledObjectName(d), metav1.GetOptions{})
+	if err != nil {
+		t.Fatalf("Failed to get the ScaledObject: %v", err)
+	}
+	triggers, _, _ := unstructured.NestedSlice(so.Object, "spec", "triggers")
+	var subs []string
+	for _, trigger := range triggers {
+		sub, _, _ := unstructured.NestedString(trigger.(map[string]interface{}), "metadata", "subscriptionName")
+		subs = append(subs, sub)
+	}
+	if diff := cmp.Diff([]string{"decouple-1", "decouple-2"}, subs); diff != "" {
+		t.Errorf("Unexpected ScaledObject subscriptions (-want, +got) = %v", diff)
+	}
+	if _, err := kubeClient.AutoscalingV2beta2().HorizontalPodAutoscalers(testNS).Get(ctx, hpa.Name, metav1.GetOptions{}); !apierrs.IsNotFound(err) {
+		t.Errorf("Unexpected HPA, wanted it deleted: %v", err)
+	}
+
+	// The external metrics HPA replaces KEDA.
+	bc.Spec.Components.Fanout.BacklogAutoscaling.Class = intv1alpha1.BacklogAutoscalingExternalMetrics
+	kedaListers := NewListers([]runtime.Object{kedaHPA})
+	r.hpaLister = kedaListers.GetHPALister()
+	if err := r.reconcileComponentAutoscaling(ctx, bc, d, r.makeFanoutHPAArgs(bc)); err != nil {
+		t.Fatalf("Failed to reconcile the autoscaling: %v", err)
+	}
+	if _, err := dynamicClient.Resource(gvr).Namespace(testNS).Get(ctx, resources.ScaledObjectName(d), metav1.GetOptions{}); !apierrs.IsNotFound(err) {
+		t.Errorf("Unexpected ScaledObject, wanted it deleted: %v", err)
+	}
+	got, err := kubeClient.AutoscalingV2beta2().HorizontalPodAutoscalers(testNS).Get(ctx, hpa.Name, metav1.GetOptions{})
+	if err != nil {
+		t.Fatalf("Failed to get the HPA: %v", err)
+	}
+	if len(got.Spec.Metrics) != 2 || got.Spec.Metrics[1].External == nil {
+		t.Fatalf("Unexpected HPA metrics %v, wanted CPU and backlog", got.Spec.Metrics)
+	}
+	// The HPA selects the decouple subscriptions of the BrokerCell by their labels.
+	wantSelector := map[string]string{
+		"metadata.user_labels.brokercell":       brokerCellName,
+		"metadata.user_labels.brokercell_queue": "decouple",
+	}
+	if diff := cmp.Diff(wantSelector, got.Spec.Metrics[1].External.Metric.Selector.MatchLabels); diff != "" {
+		t.Errorf("Unexpected HPA subscription selector (-want, +got) = %v", diff)
+	}
+
+	// The retry component scales on the retry subscriptions.
+	if diff := cmp.Diff([]string{"retry-1"}, r.backlogSubscriptions(bc, true)); diff != "" {
+		t.Errorf("Unexpected retry subscriptions (-want, +got) = %v", diff)
+	}
+}
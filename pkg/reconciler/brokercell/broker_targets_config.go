@@ -19,14 +19,22 @@ package brokercell
 import (
 	"context"
 	"fmt"
+	"time"
 
 	"github.com/google/knative-gcp/pkg/apis/messaging/v1beta1"
 
 	"github.com/google/knative-gcp/pkg/logging"
+	"github.com/google/knative-gcp/pkg/metrics"
 	"go.uber.org/zap"
+	"google.golang.org/protobuf/proto"
+	corev1 "k8s.io/api/core/v1"
+	apierrs "k8s.io/apimachinery/pkg/api/errors"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
 	"k8s.io/apimachinery/pkg/labels"
+	"k8s.io/apimachinery/pkg/types"
 	"k8s.io/client-go/tools/cache"
 	"knative.dev/eventing/pkg/apis/eventing"
+	"knative.dev/pkg/apis"
 
 	brokerv1 "github.com/google/knative-gcp/pkg/apis/broker/v1"
 	intv1alpha1 "github.com/google/knative-gcp/pkg/apis/intevents/v1alpha1"
@@ -36,20 +44,129 @@ import (
 	"github.com/google/knative-gcp/pkg/reconciler/brokercell/resources"
 	channelresources "github.com/google/knative-gcp/pkg/reconciler/messaging/channel/resources"
 	"github.com/google/knative-gcp/pkg/reconciler/utils"
+	reconcilerutilspubsub "github.com/google/knative-gcp/pkg/reconciler/utils/pubsub"
 	"github.com/google/knative-gcp/pkg/reconciler/utils/volume"
 )
 
 const (
 	configFailed = "BrokerTargetsConfigFailed"
+
+	// defaultDeadLetterRetry is the number of delivery attempts from the retry queue before an
+	// event is dead lettered when the delivery spec does not set one. It matches the default
+	// of Pub/Sub dead letter policies.
+	defaultDeadLetterRetry = 5
 )
 
 func (r *Reconciler) reconcileConfig(ctx context.Context, bc *intv1alpha1.BrokerCell) error {
-	// Start with a fresh config and add into it. This approach is straightforward and reliable,
-	// however not efficient if there are too many triggers/subscriptions. If performance becomes
-	// an issue, we can consider maintaining 2 queues for updated brokers and triggers, and only
-	// update the config for updated triggers/subscriptions.
-	targets := memory.NewEmptyTargets()
+	start := time.Now()
+	bcKey := types.NamespacedName{Namespace: bc.Namespace, Name: bc.Name}
+	// Only the Brokers and Channels that changed since the last reconcile are updated, unless the
+	// targets must be rebuilt from scratch.
+	targets, dirty := r.targetsTracker.begin(bcKey)
+	full := targets == nil
+	if full {
+		targets = memory.NewEmptyTargets()
+		if err := r.addAllToTargets(ctx, bc, targets); err != nil {
+			r.targetsTracker.reset(bcKey)
+			return err
+		}
+	} else if err := r.updateDirtyTargets(ctx, bc, targets, dirty); err != nil {
+		r.targetsTracker.reset(bcKey)
+		return err
+	}
+
+	targets.SetGeneration(r.targetsGeneration(ctx, bc, targets))
 
+	// The targets are streamed first, so that they reach the data plane even if the ConfigMap is
+	// too large to be updated.
+	r.targetsStream.Publish(bcKey, targets)
+
+	if err := r.updateTargetsConfig(ctx, bc, targets); err != nil {
+		r.targetsTracker.reset(bcKey)
+		logging.FromContext(ctx).Error("Failed to update broker targets configmap", zap.Error(err))
+		bc.Status.MarkTargetsConfigFailed(configFailed, "failed to update configmap: %v", err)
+		return err
+	}
+	r.targetsTracker.commit(bcKey, targets, full)
+	bc.Status.MarkTargetsConfigReady()
+	r.reportTargetsLatency(ctx, bc, full, time.Since(start))
+	return nil
+}
+
+// targetsGeneration returns the generation of the targets. It is the generation of the previous
+// targets if they have the same CellTenants, or the next one otherwise. The previous targets are
+// the ones of the last reconcile, or the ones in the targets ConfigMap after a restart.
+func (r *Reconciler) targetsGeneration(ctx context.Context, bc *intv1alpha1.BrokerCell, targets config.Targets) int64 {
+	previous := r.targetsTracker.committed(types.NamespacedName{Namespace: bc.Namespace, Name: bc.Name})
+	if previous == nil {
+		previous = r.currentTargetsConfig(ctx, bc)
+	}
+	current := &config.TargetsConfig{CellTenants: config.NewTargetsConfig(targets).CellTenants}
+	if previous != nil && proto.Equal(current, &config.TargetsConfig{CellTenants: previous.CellTenants}) {
+		return previous.Generation
+	}
+	return previous.GetGeneration() + 1
+}
+
+// currentTargetsConfig returns the targets config in the targets ConfigMap, or nil if there is
+// none or it can't be read.
+func (r *Reconciler) currentTargetsConfig(ctx context.Context, bc *intv1alpha1.BrokerCell) *config.TargetsConfig {
+	cm, err := r.configMapLister.ConfigMaps(bc.Namespace).Get(resources.TargetsConfigMapName(bc.Name))
+	if err != nil {
+		return nil
+	}
+	tc, err := resources.ParseTargetsConfig(cm, func(shard int) (*corev1.ConfigMap, error) {
+		return r.configMapLister.ConfigMaps(bc.Namespace).Get(resources.TargetsShardName(bc.Name, shard))
+	})
+	if err != nil {
+		// The generation starts over, which is only an issue while a stale data plane reports
+		// a later generation.
+		logging.FromContext(ctx).Warn("Failed to read the current targets config", zap.Error(err))
+		return nil
+	}
+	return tc
+}
+
+// seedTargetsStream publishes the targets configs read from the targets ConfigMaps of the
+// BrokerCells to the targets stream, so that the data plane is served the targets it knew before
+// a restart of the controller until the BrokerCells are reconciled.
+func (r *Reconciler) seedTargetsStream(ctx context.Context) {
+	bcs, err := r.RunClientSet.InternalV1alpha1().BrokerCells(metav1.NamespaceAll).List(ctx, metav1.ListOptions{})
+	if err != nil {
+		logging.FromContext(ctx).Warn("Failed to list the BrokerCells to seed the targets stream", zap.Error(err))
+		return
+	}
+	for _, bc := range bcs.Items {
+		tc, err := r.readTargetsConfig(ctx, bc.Namespace, bc.Name)
+		if err != nil {
+			logging.FromContext(ctx).Warn("Failed to read the targets config to seed the targets stream",
+				zap.String("brokerCell", bc.Name), zap.Error(err))
+			continue
+		}
+		if tc != nil {
+			r.targetsStream.Seed(types.NamespacedName{Namespace: bc.Namespace, Name: bc.Name}, tc)
+		}
+	}
+}
+
+// readTargetsConfig reads the targets config of a BrokerCell from the API server, as the listers
+// are not synced yet at startup. It returns nil if there is no targets ConfigMap.
+func (r *Reconciler) readTargetsConfig(ctx context.Context, namespace, brokerCell string) (*config.TargetsConfig, error) {
+	configMaps := r.KubeClientSet.CoreV1().ConfigMaps(namespace)
+	cm, err := configMaps.Get(ctx, resources.TargetsConfigMapName(brokerCell), metav1.GetOptions{})
+	if apierrs.IsNotFound(err) {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, err
+	}
+	return resources.ParseTargetsConfig(cm, func(shard int) (*corev1.ConfigMap, error) {
+		return configMaps.Get(ctx, resources.TargetsShardName(brokerCell, shard), metav1.GetOptions{})
+	})
+}
+
+// addAllToTargets adds all the Brokers, Triggers and Channels of the BrokerCell to `targets`.
+func (r *Reconciler) addAllToTargets(ctx context.Context, bc *intv1alpha1.BrokerCell, targets config.Targets) error {
 	err := r.addBrokersAndTriggersToTargets(ctx, bc, targets)
 	if err != nil {
 		return fmt.Errorf("unable to add Broker and Triggers to targets: %w", err)
@@ -59,22 +176,81 @@ func (r *Reconciler) reconcileConfig(ctx context.Context, bc *intv1alpha1.Broker
 	if err != nil {
 		return fmt.Errorf("unable to add Channels to targets: %w", err)
 	}
+	return nil
+}
 
-	if err := r.updateTargetsConfig(ctx, bc, targets); err != nil {
-		logging.FromContext(ctx).Error("Failed to update broker targets configmap", zap.Error(err))
-		bc.Status.MarkTargetsConfigFailed(configFailed, "failed to update configmap: %v", err)
+// updateDirtyTargets updates the CellTenants of the given Brokers and Channels in `targets`, and
+// deletes the ones of the Brokers and Channels that no longer exist.
+func (r *Reconciler) updateDirtyTargets(ctx context.Context, bc *intv1alpha1.BrokerCell, targets config.Targets, dirty []tenantRef) error {
+	for _, ref := range dirty {
+		var err error
+		if ref.tenantType == config.CellTenantType_CHANNEL {
+			err = r.updateChannelTargets(ctx, bc, targets, ref)
+		} else {
+			err = r.updateBrokerTargets(ctx, bc, targets, ref)
+		}
+		if err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+func (r *Reconciler) updateBrokerTargets(ctx context.Context, bc *intv1alpha1.BrokerCell, targets config.Targets, ref tenantRef) error {
+	broker, err := r.brokerLister.Brokers(ref.Namespace).Get(ref.Name)
+	if apierrs.IsNotFound(err) || (err == nil && (!utils.BrokerClassFilter(broker) || !utils.InBrokerCell(broker, bc))) {
+		targets.MutateCellTenant(ref.key(), func(m config.CellTenantMutation) { m.Delete() })
+		return nil
+	}
+	if err != nil {
+		logging.FromContext(ctx).Error("Failed to get broker", zap.String("broker", ref.Name), zap.Error(err))
+		bc.Status.MarkTargetsConfigFailed(configFailed, "failed to get broker %v: %v", ref.Name, err)
 		return err
 	}
-	bc.Status.MarkTargetsConfigReady()
+	triggers, err := r.triggerLister.Triggers(broker.Namespace).List(labels.SelectorFromSet(map[string]string{eventing.BrokerLabelKey: broker.Name}))
+	if err != nil {
+		logging.FromContext(ctx).Error("Failed to list triggers", zap.String("Broker", broker.Name), zap.Error(err))
+		bc.Status.MarkTargetsConfigFailed(configFailed, "failed to list triggers for broker %v: %v", broker.Name, err)
+		return err
+	}
+	addBrokerAndTriggersToConfig(ctx, bc, broker, triggers, r.triggerDeadLetterPolicy(ctx, broker), targets)
+	return nil
+}
+
+func (r *Reconciler) updateChannelTargets(ctx context.Context, bc *intv1alpha1.BrokerCell, targets config.Targets, ref tenantRef) error {
+	channel, err := r.channelLister.Channels(ref.Namespace).Get(ref.Name)
+	if apierrs.IsNotFound(err) || (err == nil && (channel.Status.Address == nil || !utils.InBrokerCell(channel, bc))) {
+		// Channels without an address are not in the targets, see addChannelToConfig.
+		targets.MutateCellTenant(ref.key(), func(m config.CellTenantMutation) { m.Delete() })
+		return nil
+	}
+	if err != nil {
+		logging.FromContext(ctx).Error("Failed to get Channel", zap.String("channel", ref.Name), zap.Error(err))
+		bc.Status.MarkTargetsConfigFailed(configFailed, "failed to get channel %v: %v", ref.Name, err)
+		return err
+	}
+	addChannelToConfig(ctx, channel, targets)
 	return nil
 }
 
+func (r *Reconciler) reportTargetsLatency(ctx context.Context, bc *intv1alpha1.BrokerCell, full bool, latency time.Duration) {
+	if r.targetsLatencyReporter == nil {
+		return
+	}
+	reconcileType := metrics.ReconcileTypeIncremental
+	if full {
+		reconcileType = metrics.ReconcileTypeFull
+	}
+	if err := r.targetsLatencyReporter.ReportLatency(ctx, latency, bc.Name, bc.Namespace, reconcileType); err != nil {
+		logging.FromContext(ctx).Error("Failed to report targets config latency", zap.Error(err))
+	}
+}
+
 // addBrokersAndTriggersToTargets adds all Brokers that are associated with the `bc` BrokerCell to
 // `targets`, along with all Triggers that target those Brokers.
 func (r *Reconciler) addBrokersAndTriggersToTargets(ctx context.Context, bc *intv1alpha1.BrokerCell, targets config.Targets) error {
-	// TODO(#866) Only select brokers that point to this brokercell by label selector once the
-	// webhook assigns the brokercell label, i.e.,
-	// r.brokerLister.List(labels.SelectorFromSet(map[string]string{"brokercell":bc.Name, "brokercellns":bc.Namespace}))
+	// Brokers without the BrokerCell label belong to the default BrokerCell, so they can't be
+	// selected by label.
 	brokers, err := r.brokerLister.List(labels.Everything())
 	if err != nil {
 		logging.FromContext(ctx).Error("Failed to list brokers", zap.Error(err))
@@ -82,7 +258,7 @@ func (r *Reconciler) addBrokersAndTriggersToTargets(ctx context.Context, bc *int
 		return err
 	}
 	for _, broker := range brokers {
-		if !utils.BrokerClassFilter(broker) {
+		if !utils.BrokerClassFilter(broker) || !utils.InBrokerCell(broker, bc) {
 			continue
 		}
 		// Filter by `eventing.knative.dev/broker: <name>` here
@@ -94,13 +270,50 @@ func (r *Reconciler) addBrokersAndTriggersToTargets(ctx context.Context, bc *int
 			bc.Status.MarkTargetsConfigFailed(configFailed, "failed to list triggers for broker %v: %v", broker.Name, err)
 			return err
 		}
-		addBrokerAndTriggersToConfig(ctx, broker, triggers, targets)
+		addBrokerAndTriggersToConfig(ctx, bc, broker, triggers, r.triggerDeadLetterPolicy(ctx, broker), targets)
 	}
 	return nil
 }
 
+// triggerDeadLetterPolicy returns the function resolving the dead letter sink of a Trigger of the
+// Broker, or of the Broker if the Trigger has none, into the dead letter policy of the Trigger.
+// Dead letter sinks that are Pub/Sub topics are handled by the Pub/Sub dead letter policy of the
+// Trigger's retry subscription instead, so nil is returned for them.
+func (r *Reconciler) triggerDeadLetterPolicy(ctx context.Context, b *brokerv1.Broker) func(*brokerv1.Trigger) *config.DeadLetterPolicy {
+	return func(t *brokerv1.Trigger) *config.DeadLetterPolicy {
+		delivery := t.GetDeliverySpec(b)
+		if delivery == nil || delivery.DeadLetterSink == nil {
+			return nil
+		}
+		sink := delivery.DeadLetterSink
+		if reconcilerutilspubsub.IsDeadLetterTopic(sink) {
+			return nil
+		}
+		address, err := r.uriResolver.URIFromDestinationV1(ctx, *sink, t)
+		if err != nil {
+			// The resolver tracks the sink, so the BrokerCell is reconciled again once the sink
+			// becomes addressable. Until then, events are retried rather than dead lettered.
+			logging.FromContext(ctx).Error("Failed to resolve the Trigger dead letter sink", zap.String("trigger", t.Name), zap.Error(err))
+			return nil
+		}
+		return makeDeadLetterPolicy(address, delivery.Retry)
+	}
+}
+
+func makeDeadLetterPolicy(address *apis.URL, retry *int32) *config.DeadLetterPolicy {
+	dlp := &config.DeadLetterPolicy{
+		Address: address.String(),
+		Retry:   defaultDeadLetterRetry,
+	}
+	if retry != nil {
+		dlp.Retry = *retry
+	}
+	return dlp
+}
+
 // addBrokerAndTriggersToConfig reconstructs the data entry for the given broker and adds it to targets-config.
-func addBrokerAndTriggersToConfig(_ context.Context, b *brokerv1.Broker, triggers []*brokerv1.Trigger, brokerTargets config.Targets) {
+// deadLetterPolicy resolves the dead letter policy of each trigger.
+func addBrokerAndTriggersToConfig(ctx context.Context, bc *intv1alpha1.BrokerCell, b *brokerv1.Broker, triggers []*brokerv1.Trigger, deadLetterPolicy func(*brokerv1.Trigger) *config.DeadLetterPolicy, brokerTargets config.Targets) {
 	// TODO Maybe get rid of GCPCellAddressableMutation and add Delete() and Upsert(broker) methods to TargetsConfig. Now we always
 	//  delete or update the entire broker entry and we don't need partial updates per trigger.
 	// The code can be simplified to r.targetsConfig.Upsert(brokerConfigEntry)
@@ -116,6 +329,7 @@ func addBrokerAndTriggersToConfig(_ context.Context, b *brokerv1.Broker, trigger
 		}
 		// Then reconstruct the broker entry and insert it
 		m.SetID(string(b.UID))
+		m.SetGeneration(b.Generation)
 		m.SetAddress(b.Status.Address.URL.String())
 		m.SetDecoupleQueue(&config.Queue{
 			Topic:        brokerresources.GenerateDecouplingTopicName(b),
@@ -127,12 +341,16 @@ func addBrokerAndTriggersToConfig(_ context.Context, b *brokerv1.Broker, trigger
 		} else {
 			m.SetState(config.State_UNKNOWN)
 		}
+		m.SetIngressAuth(ingressAuth(ctx, b))
+		m.SetClaimCheck(claimCheck(ctx, bc, b))
+		m.SetOrdering(ordering(ctx, b))
 
 		// Insert each Trigger to the config.
 		for _, t := range triggers {
 			if t.Spec.Broker == b.Name {
 				target := &config.Target{
 					Id:             string(t.UID),
+					Generation:     t.Generation,
 					Name:           t.Name,
 					Namespace:      t.Namespace,
 					CellTenantType: config.CellTenantType_BROKER,
@@ -143,12 +361,26 @@ func addBrokerAndTriggersToConfig(_ context.Context, b *brokerv1.Broker, trigger
 						Topic:        brokerresources.GenerateRetryTopicName(t),
 						Subscription: brokerresources.GenerateRetrySubscriptionName(t),
 					},
+					DeadLetterPolicy: deadLetterPolicy(t),
 				}
 				if t.Spec.Filter != nil && t.Spec.Filter.Attributes != nil {
 					target.FilterAttributes = t.Spec.Filter.Attributes
 				}
-				// TODO(#939) May need to use "data plane readiness" for trigger in stead of the
-				//  overall status, see https://github.com/google/knative-gcp/issues/939#issuecomment-644337937
+				if filters, err := t.GetFilters(); err != nil {
+					// The webhook rejects malformed filters, so this should never happen. Use a
+					// filter without any dialect, which the data plane never passes, rather than
+					// delivering every event to the subscriber.
+					logging.FromContext(ctx).Error("Failed to parse Trigger filters", zap.String("trigger", t.Name), zap.Error(err))
+					target.Filters = []*config.Filter{{}}
+				} else {
+					target.Filters = toConfigFilters(filters)
+				}
+				target.ResponsePolicy = responsePolicy(ctx, b, t)
+				target.DeliveryLimits = deliveryLimits(ctx, t)
+				target.DeliveryAuth = deliveryAuth(ctx, b, t)
+				target.Replay = replay(b, t)
+				// The data plane readiness of the Trigger is reported by its DataPlaneReady condition,
+				// which doesn't affect its overall status, once the data plane has loaded it as ready.
 				if t.Status.IsReady() {
 					target.State = config.State_READY
 				} else {
@@ -159,10 +391,191 @@ func addBrokerAndTriggersToConfig(_ context.Context, b *brokerv1.Broker, trigger
 		}
 	})
 }
+
+// replay returns the replay of the events of the decoupling topic of the Broker to the Trigger,
+// once a replay was applied to its replay subscription.
+func replay(b *brokerv1.Broker, t *brokerv1.Trigger) *config.Replay {
+	if r, err := t.GetReplay(); err != nil || r == nil {
+		return nil
+	}
+	until, ok := t.Status.GetLastReplayTime()
+	if !ok {
+		return nil
+	}
+	return &config.Replay{
+		Queue: &config.Queue{
+			Topic:        brokerresources.GenerateDecouplingTopicName(b),
+			Subscription: brokerresources.GenerateReplaySubscriptionName(t),
+			State:        config.State_READY,
+		},
+		UntilUnixNanos: until.UnixNano(),
+	}
+}
+
+// toConfigFilters converts the Trigger's Subscriptions API filters to their targets-config form.
+func toConfigFilters(filters []brokerv1.SubscriptionsAPIFilter) []*config.Filter {
+	if len(filters) == 0 {
+		return nil
+	}
+	converted := make([]*config.Filter, 0, len(filters))
+	for i := range filters {
+		converted = append(converted, toConfigFilter(&filters[i]))
+	}
+	return converted
+}
+
+func toConfigFilter(f *brokerv1.SubscriptionsAPIFilter) *config.Filter {
+	converted := &config.Filter{
+		Exact:  f.Exact,
+		Prefix: f.Prefix,
+		Suffix: f.Suffix,
+		All:    toConfigFilters(f.All),
+		Any:    toConfigFilters(f.Any),
+		Cesql:  f.SQL,
+	}
+	if f.Not != nil {
+		converted.Not = toConfigFilter(f.Not)
+	}
+	return converted
+}
+
+// responsePolicy returns the response policy of the Trigger. The response classification of a
+// Trigger replaces the one of its Broker.
+func responsePolicy(ctx context.Context, b *brokerv1.Broker, t *brokerv1.Trigger) *config.ResponsePolicy {
+	c, err := t.GetResponseClassification()
+	if err == nil && c == nil {
+		c, err = b.GetResponseClassification()
+	}
+	if err != nil {
+		// The webhook rejects malformed response classifications, so this should never happen.
+		// Retry all the responses, as if there was no classification.
+		logging.FromContext(ctx).Error("Failed to parse response classification", zap.String("trigger", t.Name), zap.Error(err))
+		return nil
+	}
+	if c == nil {
+		return nil
+	}
+	return &config.ResponsePolicy{
+		Success:    toStatusCodeRanges(c.Success),
+		DeadLetter: toStatusCodeRanges(c.DeadLetter),
+		Retry:      toStatusCodeRanges(c.Retry),
+	}
+}
+
+// deliveryLimits returns the delivery limits of the Trigger, if any.
+func deliveryLimits(ctx context.Context, t *brokerv1.Trigger) *config.DeliveryLimits {
+	limits, err := t.GetDeliveryLimits()
+	if err != nil {
+		// The webhook rejects malformed limits, so this should never happen. Don't limit the
+		// deliveries rather than blocking them.
+		logging.FromContext(ctx).Error("Failed to parse delivery limits", zap.String("trigger", t.Name), zap.Error(err))
+		return nil
+	}
+	if limits == (brokerv1.DeliveryLimits{}) {
+		return nil
+	}
+	return &config.DeliveryLimits{
+		MaxConcurrentDeliveries: limits.MaxConcurrentDeliveries,
+		MaxEventsPerSecond:      limits.MaxEventsPerSecond,
+	}
+}
+
+// deliveryAuth returns the delivery authentication of the Trigger. The delivery authentication
+// of a Trigger replaces the one of its Broker.
+func deliveryAuth(ctx context.Context, b *brokerv1.Broker, t *brokerv1.Trigger) *config.DeliveryAuth {
+	a, err := t.GetDeliveryAuth()
+	if err == nil && a == nil {
+		a, err = b.GetDeliveryAuth()
+	}
+	if err != nil {
+		// The webhook rejects malformed delivery authentications, so this should never happen.
+		// Deliver the events without authentication, which the subscriber will reject.
+		logging.FromContext(ctx).Error("Failed to parse delivery authentication", zap.String("trigger", t.Name), zap.Error(err))
+		return nil
+	}
+	switch {
+	case a == nil:
+		return nil
+	case a.OIDC != nil:
+		return &config.DeliveryAuth{Oidc: &config.OIDCAuth{Audience: a.OIDC.Audience}}
+	case a.BearerToken != nil:
+		// Tokens are scoped by namespace, so that a Trigger cannot send the tokens of other
+		// namespaces to its subscriber.
+		return &config.DeliveryAuth{BearerTokenKey: t.Namespace + "." + a.BearerToken.Key}
+	default:
+		return nil
+	}
+}
+
+// ingressAuth converts the ingress authentication of the Broker, if any.
+func ingressAuth(ctx context.Context, b *brokerv1.Broker) *config.IngressAuth {
+	a, err := b.GetIngressAuth()
+	if err != nil {
+		// The webhook rejects malformed ingress authentications, so this should never happen.
+		// Reject all the callers rather than accepting events from anyone.
+		logging.FromContext(ctx).Error("Failed to parse ingress authentication", zap.String("broker", b.Name), zap.Error(err))
+		return &config.IngressAuth{Audience: b.Status.Address.URL.String()}
+	}
+	if a == nil {
+		return nil
+	}
+	audience := a.Audience
+	if audience == "" {
+		audience = b.Status.Address.URL.String()
+	}
+	return &config.IngressAuth{AllowedIdentities: a.AllowedIdentities, Audience: audience}
+}
+
+// claimCheck converts the claim check of the Broker, if any. Claim checks of buckets the BrokerCell
+// doesn't allow are ignored, so the payloads stay in the events.
+func claimCheck(ctx context.Context, bc *intv1alpha1.BrokerCell, b *brokerv1.Broker) *config.ClaimCheck {
+	c, err := b.GetClaimCheck()
+	if err != nil {
+		// The webhook rejects malformed claim checks, so this should never happen.
+		logging.FromContext(ctx).Error("Failed to parse claim check", zap.String("broker", b.Name), zap.Error(err))
+		return nil
+	}
+	if c == nil {
+		return nil
+	}
+	if !bc.ClaimCheckBucketAllowed(c.Bucket) {
+		logging.FromContext(ctx).Warn("Ignoring claim check of a bucket not allowed by the BrokerCell",
+			zap.String("broker", b.Name), zap.String("bucket", c.Bucket))
+		return nil
+	}
+	return &config.ClaimCheck{Bucket: c.Bucket, ThresholdBytes: c.ThresholdBytes}
+}
+
+// ordering converts the ordering of the Broker, if any.
+func ordering(ctx context.Context, b *brokerv1.Broker) *config.Ordering {
+	o, err := b.GetOrdering()
+	if err != nil {
+		// The webhook rejects malformed orderings, so this should never happen.
+		logging.FromContext(ctx).Error("Failed to parse ordering", zap.String("broker", b.Name), zap.Error(err))
+		return nil
+	}
+	if o == nil {
+		return nil
+	}
+	return &config.Ordering{KeyExtension: o.KeyExtension}
+}
+
+func toStatusCodeRanges(codes []string) []*config.StatusCodeRange {
+	var ranges []*config.StatusCodeRange
+	for _, s := range codes {
+		min, max, err := brokerv1.ParseStatusCodes(s)
+		if err != nil {
+			// The webhook rejects malformed status codes.
+			continue
+		}
+		ranges = append(ranges, &config.StatusCodeRange{Min: int32(min), Max: int32(max)})
+	}
+	return ranges
+}
+
 func (r *Reconciler) addChannelsToTargets(ctx context.Context, bc *intv1alpha1.BrokerCell, targets config.Targets) error {
-	// TODO(#866) Only select Channels that point to this brokercell by label selector once the
-	// webhook assigns the brokercell label, i.e.,
-	// r.brokerLister.List(labels.SelectorFromSet(map[string]string{"brokercell":bc.Name, "brokercellns":bc.Namespace}))
+	// Channels without the BrokerCell label belong to the default BrokerCell, so they can't be
+	// selected by label.
 	channels, err := r.channelLister.List(labels.Everything())
 	if err != nil {
 		logging.FromContext(ctx).Error("Failed to list Channels", zap.Error(err))
@@ -170,7 +583,9 @@ func (r *Reconciler) addChannelsToTargets(ctx context.Context, bc *intv1alpha1.B
 		return err
 	}
 	for _, channel := range channels {
-		addChannelToConfig(ctx, channel, targets)
+		if utils.InBrokerCell(channel, bc) {
+			addChannelToConfig(ctx, channel, targets)
+		}
 	}
 	return nil
 }
@@ -200,6 +615,7 @@ func addChannelToConfig(_ context.Context, c *v1beta1.Channel, targets config.Ta
 
 		// Then reconstruct the tenant entry and insert it.
 		m.SetID(string(c.UID))
+		m.SetGeneration(c.Generation)
 		m.SetAddress(c.Status.Address.URL.String())
 		m.SetDecoupleQueue(&config.Queue{
 			Topic:        channelresources.GenerateDecouplingTopicName(c),
@@ -235,18 +651,18 @@ func addChannelToConfig(_ context.Context, c *v1beta1.Channel, targets config.Ta
 				//  overall status, see https://github.com/google/knative-gcp/issues/939#issuecomment-644337937
 				State: config.State_READY,
 			}
+			// The Subscription reconciler has already resolved the dead letter sink of the
+			// subscriber into a URI.
+			if d := s.Delivery; d != nil && d.DeadLetterSink != nil && d.DeadLetterSink.URI != nil && !reconcilerutilspubsub.IsDeadLetterTopic(d.DeadLetterSink) {
+				target.DeadLetterPolicy = makeDeadLetterPolicy(d.DeadLetterSink.URI, d.Retry)
+			}
 			m.UpsertTargets(target)
 		}
 	})
 }
 
-//TODO all this stuff should be in a configmap variant of the config object
+// TODO all this stuff should be in a configmap variant of the config object
 func (r *Reconciler) updateTargetsConfig(ctx context.Context, bc *intv1alpha1.BrokerCell, brokerTargets config.Targets) error {
-	desired, err := resources.MakeTargetsConfig(bc, brokerTargets)
-	if err != nil {
-		return fmt.Errorf("error creating targets config: %w", err)
-	}
-
 	logging.FromContext(ctx).Debug("Current targets config", zap.Any("targetsConfig", brokerTargets.DebugString()))
 
 	handlerFuncs := cache.ResourceEventHandlerFuncs{
@@ -254,10 +670,62 @@ func (r *Reconciler) updateTargetsConfig(ctx context.Context, bc *intv1alpha1.Br
 		UpdateFunc: func(oldObj, newObj interface{}) { r.refreshPodVolume(ctx, bc) },
 		DeleteFunc: nil,
 	}
-	_, err = r.cmRec.ReconcileConfigMap(ctx, bc, desired, resources.TargetsConfigMapEqual, handlerFuncs)
+	if shards, _ := resources.TargetsConfigShards(bc); shards > 0 {
+		if err := r.updateTargetsConfigShards(ctx, bc, brokerTargets, handlerFuncs); err != nil {
+			return err
+		}
+	} else {
+		desired, err := resources.MakeTargetsConfig(bc, brokerTargets)
+		if err != nil {
+			return fmt.Errorf("error creating targets config: %w", err)
+		}
+		if _, err := r.cmRec.ReconcileConfigMap(ctx, bc, desired, resources.TargetsConfigMapEqual, handlerFuncs); err != nil {
+			return err
+		}
+	}
+	return r.deleteStaleTargetsConfigShards(ctx, bc)
+}
+
+// updateTargetsConfigShards updates the shards of the targets config before their manifest, so
+// that the data plane only loads the shards once they are all updated.
+func (r *Reconciler) updateTargetsConfigShards(ctx context.Context, bc *intv1alpha1.BrokerCell, brokerTargets config.Targets, handlerFuncs cache.ResourceEventHandlerFuncs) error {
+	manifest, shards, err := resources.MakeTargetsConfigShards(bc, brokerTargets)
+	if err != nil {
+		return fmt.Errorf("error creating targets config shards: %w", err)
+	}
+	for _, shard := range shards {
+		if _, err := r.cmRec.ReconcileConfigMap(ctx, bc, shard, resources.TargetsShardConfigMapEqual); err != nil {
+			return err
+		}
+	}
+	_, err = r.cmRec.ReconcileConfigMap(ctx, bc, manifest, resources.TargetsManifestConfigMapEqual, handlerFuncs)
 	return err
 }
 
+// deleteStaleTargetsConfigShards deletes the shards of the targets config that are no longer
+// used, after the number of shards is reduced or the targets config is no longer sharded.
+func (r *Reconciler) deleteStaleTargetsConfigShards(ctx context.Context, bc *intv1alpha1.BrokerCell) error {
+	cms, err := r.configMapLister.ConfigMaps(bc.Namespace).List(labels.SelectorFromSet(resources.TargetsShardLabels(bc.Name)))
+	if err != nil {
+		return err
+	}
+	shards, _ := resources.TargetsConfigShards(bc)
+	used := make(map[string]bool, shards)
+	for i := 0; i < shards; i++ {
+		used[resources.TargetsShardName(bc.Name, i)] = true
+	}
+	for _, cm := range cms {
+		if used[cm.Name] || !metav1.IsControlledBy(cm, bc) {
+			continue
+		}
+		err := r.KubeClientSet.CoreV1().ConfigMaps(cm.Namespace).Delete(ctx, cm.Name, metav1.DeleteOptions{})
+		if err != nil && !apierrs.IsNotFound(err) {
+			return fmt.Errorf("error deleting targets config shard %s: %w", cm.Name, err)
+		}
+	}
+	return nil
+}
+
 func (r *Reconciler) refreshPodVolume(ctx context.Context, bc *intv1alpha1.BrokerCell) {
 	if err := volume.UpdateVolumeGeneration(ctx, r.KubeClientSet, r.podLister, bc.Namespace, resources.CommonLabels(bc.Name)); err != nil {
 		// Failing to update the annotation on the data plane pods means there
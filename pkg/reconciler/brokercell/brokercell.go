@@ -19,28 +19,37 @@ package brokercell
 import (
 	"context"
 	"fmt"
+	"net/http"
+	"time"
 
 	"github.com/kelseyhightower/envconfig"
 	"go.uber.org/zap"
 
 	channellisters "github.com/google/knative-gcp/pkg/client/listers/messaging/v1beta1"
+	appsv1 "k8s.io/api/apps/v1"
 	hpav2beta2 "k8s.io/api/autoscaling/v2beta2"
 	corev1 "k8s.io/api/core/v1"
 	"k8s.io/apimachinery/pkg/api/equality"
 	apierrs "k8s.io/apimachinery/pkg/api/errors"
 	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
 	"k8s.io/apimachinery/pkg/labels"
+	"k8s.io/apimachinery/pkg/types"
 	appsv1listers "k8s.io/client-go/listers/apps/v1"
 	hpav2beta2listers "k8s.io/client-go/listers/autoscaling/v2beta2"
 	corev1listers "k8s.io/client-go/listers/core/v1"
+	policyv1beta1listers "k8s.io/client-go/listers/policy/v1beta1"
 	"knative.dev/pkg/network"
+	"knative.dev/pkg/system"
 
 	pkgreconciler "knative.dev/pkg/reconciler"
+	"knative.dev/pkg/resolver"
 
 	intv1alpha1 "github.com/google/knative-gcp/pkg/apis/intevents/v1alpha1"
+	"github.com/google/knative-gcp/pkg/broker/config/stream"
 	bcreconciler "github.com/google/knative-gcp/pkg/client/injection/reconciler/intevents/v1alpha1/brokercell"
 	brokerlisters "github.com/google/knative-gcp/pkg/client/listers/broker/v1"
 	"github.com/google/knative-gcp/pkg/logging"
+	"github.com/google/knative-gcp/pkg/metrics"
 	"github.com/google/knative-gcp/pkg/reconciler"
 	"github.com/google/knative-gcp/pkg/reconciler/brokercell/resources"
 	reconcilerutils "github.com/google/knative-gcp/pkg/reconciler/utils"
@@ -55,12 +64,20 @@ type envConfig struct {
 	IngressPort            int    `envconfig:"INGRESS_PORT" default:"8080"`
 	MetricsPort            int    `envconfig:"METRICS_PORT" default:"9090"`
 	InternalMetricsEnabled bool   `envconfig:"INTERNAL_METRICS_ENABLED" default:"false"`
+	// TargetsStreamPort is the port on which the targets config is streamed to the data plane,
+	// through the TargetsStreamServiceName Service. Zero disables the stream.
+	TargetsStreamPort int `envconfig:"TARGETS_STREAM_PORT" default:"0"`
+	// TargetsResyncPeriod is the period of the full rebuilds of the targets config. In between,
+	// only the Brokers and Channels that changed are updated. Zero rebuilds the targets config
+	// on every reconcile.
+	TargetsResyncPeriod time.Duration `envconfig:"TARGETS_RESYNC_PERIOD" default:"10m"`
 }
 
 type listers struct {
 	brokerLister         brokerlisters.BrokerLister
 	channelLister        channellisters.ChannelLister
 	hpaLister            hpav2beta2listers.HorizontalPodAutoscalerLister
+	pdbLister            policyv1beta1listers.PodDisruptionBudgetLister
 	triggerLister        brokerlisters.TriggerLister
 	configMapLister      corev1listers.ConfigMapLister
 	secretLister         corev1listers.SecretLister
@@ -94,12 +111,14 @@ func NewReconciler(base *reconciler.Base, ls listers) (*Reconciler, error) {
 		Recorder:   base.Recorder,
 	}
 	r := &Reconciler{
-		Base:          base,
-		env:           env,
-		listers:       ls,
-		svcRec:        svcRec,
-		deploymentRec: deploymentRec,
-		cmRec:         cmRec,
+		Base:            base,
+		env:             env,
+		listers:         ls,
+		svcRec:          svcRec,
+		deploymentRec:   deploymentRec,
+		cmRec:           cmRec,
+		targetsTracker:  newTargetsTracker(env.TargetsResyncPeriod),
+		dataPlaneClient: &http.Client{Timeout: dataPlaneScrapeTimeout},
 	}
 	return r, nil
 }
@@ -114,6 +133,25 @@ type Reconciler struct {
 	deploymentRec *reconcilerutils.DeploymentReconciler
 	cmRec         *reconcilerutils.ConfigMapReconciler
 
+	// uriResolver resolves the dead letter sinks of Triggers, tracking them with the Trigger as parent.
+	uriResolver *resolver.URIResolver
+
+	// targetsStream streams the targets config to the data plane, if enabled.
+	targetsStream *stream.Server
+
+	// targetsTracker tracks the Brokers and Channels to update in the targets config.
+	targetsTracker *targetsTracker
+
+	// targetsLatencyReporter reports the latency of the reconciles of the targets config, if
+	// internal metrics are enabled.
+	targetsLatencyReporter *metrics.TargetsConfigLatencyReporter
+
+	// dataPlaneClient scrapes the generation of the targets loaded by the data plane pods.
+	dataPlaneClient *http.Client
+
+	// enqueueAfter reconciles the BrokerCell again after a delay, to poll the data plane.
+	enqueueAfter func(key types.NamespacedName, delay time.Duration)
+
 	env envConfig
 }
 
@@ -131,6 +169,7 @@ func (r *Reconciler) ReconcileKind(ctx context.Context, bc *intv1alpha1.BrokerCe
 	// TODO(https://github.com/google/knative-gcp/issues/1196) It's cleaner to make this a separate controller.
 	if r.shouldGC(ctx, bc) {
 		logging.FromContext(ctx).Info("Garbage collecting brokercell", zap.String("brokercell", bc.Name), zap.String("Namespace", bc.Namespace))
+		r.targetsTracker.reset(types.NamespacedName{Namespace: bc.Namespace, Name: bc.Name})
 		return r.delete(ctx, bc)
 	}
 
@@ -171,6 +210,12 @@ func (r *Reconciler) ReconcileKind(ctx context.Context, bc *intv1alpha1.BrokerCe
 		return err
 	}
 
+	if err := r.reconcilePodDisruptionBudget(ctx, bc, ind, resources.IngressName, bc.Spec.Components.Ingress.PodDisruptionBudget); err != nil {
+		logging.FromContext(ctx).Error("Failed to reconcile ingress PodDisruptionBudget", zap.Any("namespace", bc.Namespace), zap.Any("name", bc.Name), zap.Error(err))
+		bc.Status.MarkIngressFailed("PodDisruptionBudgetFailed", "Failed to reconcile ingress PodDisruptionBudget: %v", err)
+		return err
+	}
+
 	endpoints, err := r.svcRec.ReconcileService(ctx, bc, resources.MakeIngressService(ingressArgs))
 	if err != nil {
 		logging.FromContext(ctx).Error("Failed to reconcile ingress service", zap.Any("namespace", bc.Namespace), zap.Any("name", bc.Name), zap.Error(err))
@@ -191,7 +236,7 @@ func (r *Reconciler) ReconcileKind(ctx context.Context, bc *intv1alpha1.BrokerCe
 	hostName := network.GetServiceHostname(endpoints.GetName(), endpoints.GetNamespace())
 	bc.Status.IngressTemplate = fmt.Sprintf("http://%s/{namespace}/{name}", hostName)
 
-	// Reconcile fanout deployment and HPA.
+	// Reconcile fanout deployment and autoscaler.
 	fd, err := r.deploymentRec.ReconcileDeployment(ctx, bc, resources.MakeFanoutDeployment(r.makeFanoutArgs(bc, authType)))
 	if err != nil {
 		logging.FromContext(ctx).Error("Failed to reconcile fanout deployment", zap.Any("namespace", bc.Namespace), zap.Any("name", bc.Name), zap.Error(err))
@@ -199,12 +244,16 @@ func (r *Reconciler) ReconcileKind(ctx context.Context, bc *intv1alpha1.BrokerCe
 		return err
 	}
 
-	fanoutHPA := resources.MakeHorizontalPodAutoscaler(fd, r.makeFanoutHPAArgs(bc))
-	if err := r.reconcileAutoscaling(ctx, bc, fanoutHPA); err != nil {
+	if err := r.reconcileComponentAutoscaling(ctx, bc, fd, r.makeFanoutHPAArgs(bc)); err != nil {
 		logging.FromContext(ctx).Error("Failed to reconcile fanout HPA", zap.Any("namespace", bc.Namespace), zap.Any("name", bc.Name), zap.Error(err))
 		bc.Status.MarkFanoutFailed("HorizontalPodAutoscalerFailed", "Failed to reconcile fanout HorizontalPodAutoscaler: %v", err)
 		return err
 	}
+	if err := r.reconcilePodDisruptionBudget(ctx, bc, fd, resources.FanoutName, bc.Spec.Components.Fanout.PodDisruptionBudget); err != nil {
+		logging.FromContext(ctx).Error("Failed to reconcile fanout PodDisruptionBudget", zap.Any("namespace", bc.Namespace), zap.Any("name", bc.Name), zap.Error(err))
+		bc.Status.MarkFanoutFailed("PodDisruptionBudgetFailed", "Failed to reconcile fanout PodDisruptionBudget: %v", err)
+		return err
+	}
 	// If deployment has replicaUnavailable error, it potentially has authentication configuration issues.
 	if replicaAvailable := bc.Status.PropagateFanoutAvailability(fd); !replicaAvailable {
 		podList, err := authcheck.GetPodList(ctx, resources.GetLabelSelector(bc.Name, resources.FanoutName), r.KubeClientSet, bc.Namespace)
@@ -216,7 +265,7 @@ func (r *Reconciler) ReconcileKind(ctx context.Context, bc *intv1alpha1.BrokerCe
 			bc.Status.MarkFanoutUnknown(authcheck.AuthenticationCheckUnknownReason, authenticationCheckMessage)
 		}
 	}
-	// Reconcile retry deployment and HPA.
+	// Reconcile retry deployment and autoscaler.
 	rd, err := r.deploymentRec.ReconcileDeployment(ctx, bc, resources.MakeRetryDeployment(r.makeRetryArgs(bc, authType)))
 	if err != nil {
 		logging.FromContext(ctx).Error("Failed to reconcile retry deployment", zap.Any("namespace", bc.Namespace), zap.Any("name", bc.Name), zap.Error(err))
@@ -224,12 +273,16 @@ func (r *Reconciler) ReconcileKind(ctx context.Context, bc *intv1alpha1.BrokerCe
 		return err
 	}
 
-	retryHPA := resources.MakeHorizontalPodAutoscaler(rd, r.makeRetryHPAArgs(bc))
-	if err := r.reconcileAutoscaling(ctx, bc, retryHPA); err != nil {
+	if err := r.reconcileComponentAutoscaling(ctx, bc, rd, r.makeRetryHPAArgs(bc)); err != nil {
 		logging.FromContext(ctx).Error("Failed to reconcile retry HPA", zap.Any("namespace", bc.Namespace), zap.Any("name", bc.Name), zap.Error(err))
 		bc.Status.MarkRetryFailed("HorizontalPodAutoscalerFailed", "Failed to reconcile retry HorizontalPodAutoscaler: %v", err)
 		return err
 	}
+	if err := r.reconcilePodDisruptionBudget(ctx, bc, rd, resources.RetryName, bc.Spec.Components.Retry.PodDisruptionBudget); err != nil {
+		logging.FromContext(ctx).Error("Failed to reconcile retry PodDisruptionBudget", zap.Any("namespace", bc.Namespace), zap.Any("name", bc.Name), zap.Error(err))
+		bc.Status.MarkRetryFailed("PodDisruptionBudgetFailed", "Failed to reconcile retry PodDisruptionBudget: %v", err)
+		return err
+	}
 	// If deployment has replicaUnavailable error, it potentially has authentication configuration issues.
 	if replicaAvailable := bc.Status.PropagateRetryAvailability(rd); !replicaAvailable {
 		podList, err := authcheck.GetPodList(ctx, resources.GetLabelSelector(bc.Name, resources.RetryName), r.KubeClientSet, bc.Namespace)
@@ -242,6 +295,8 @@ func (r *Reconciler) ReconcileKind(ctx context.Context, bc *intv1alpha1.BrokerCe
 		}
 	}
 
+	r.reconcileDataPlaneGeneration(ctx, bc)
+
 	bc.Status.ObservedGeneration = bc.Generation
 	return pkgreconciler.NewEvent(corev1.EventTypeNormal, "BrokerCellReconciled", "BrokerCell reconciled: \"%s/%s\"", bc.Namespace, bc.Name)
 }
@@ -249,7 +304,7 @@ func (r *Reconciler) ReconcileKind(ctx context.Context, bc *intv1alpha1.BrokerCe
 // shouldGC returns true if
 // 1. the brokercell was automatically created by GCP broker controller (with annotation
 // internal.events.cloud.google.com/creator: googlecloud), and
-// 2. there is no brokers pointing to it
+// 2. there is no brokers or channels pointing to it
 func (r *Reconciler) shouldGC(ctx context.Context, bc *intv1alpha1.BrokerCell) bool {
 	// TODO use the constants in #1132 once it's merged
 	// We only garbage collect brokercells that were automatically created by the GCP broker controller.
@@ -257,31 +312,30 @@ func (r *Reconciler) shouldGC(ctx context.Context, bc *intv1alpha1.BrokerCell) b
 		return false
 	}
 
-	// TODO(#866) Only select brokers that point to this brokercell by label selector once the
-	// webhook assigns the brokercell label, i.e.,
-	// r.brokerLister.List(labels.SelectorFromSet(map[string]string{"brokercell":bc.Name, "brokercellns":bc.Namespace}))
+	// Brokers and Channels without the BrokerCell label belong to the default BrokerCell, so they
+	// can't be selected by label.
 	brokers, err := r.brokerLister.List(labels.Everything())
 	if err != nil {
 		logging.FromContext(ctx).Error("Failed to list brokers, skipping garbage collection logic", zap.String("brokercell", bc.Name), zap.String("Namespace", bc.Namespace))
 		return false
 	}
-
-	if len(brokers) > 0 {
-		// There are still Brokers using this BrokerCell, do not garbage collect it.
-		return false
+	for _, b := range brokers {
+		if reconcilerutils.InBrokerCell(b, bc) {
+			// There are still Brokers using this BrokerCell, do not garbage collect it.
+			return false
+		}
 	}
 
-	// TODO(#866) Only select Channels that point to this brokercell by label selector once the
-	// webhook assigns the brokercell label, i.e.,
-	// r.brokerLister.List(labels.SelectorFromSet(map[string]string{"brokercell":bc.Name, "brokercellns":bc.Namespace}))
 	channels, err := r.channelLister.List(labels.Everything())
 	if err != nil {
 		logging.FromContext(ctx).Error("Failed to list Channels, skipping garbage collection logic", zap.String("brokercell", bc.Name), zap.String("Namespace", bc.Namespace))
 		return false
 	}
-	if len(channels) > 0 {
-		// There are still Channels using this BrokerCell, do not garbage collect it.
-		return false
+	for _, c := range channels {
+		if reconcilerutils.InBrokerCell(c, bc) {
+			// There are still Channels using this BrokerCell, do not garbage collect it.
+			return false
+		}
 	}
 
 	return true
@@ -297,18 +351,23 @@ func (r *Reconciler) delete(ctx context.Context, bc *intv1alpha1.BrokerCell) pkg
 func (r *Reconciler) makeIngressArgs(bc *intv1alpha1.BrokerCell, authType authcheck.AuthType) resources.IngressArgs {
 	return resources.IngressArgs{
 		Args: resources.Args{
-			ComponentName:      resources.IngressName,
-			BrokerCell:         bc,
-			Image:              r.env.IngressImage,
-			ServiceAccountName: r.env.ServiceAccountName,
-			MetricsPort:        r.env.MetricsPort,
-			AllowIstioSidecar:  true,
-			CPURequest:         bc.Spec.Components.Ingress.CPURequest,
-			CPULimit:           bc.Spec.Components.Ingress.CPULimit,
-			MemoryRequest:      bc.Spec.Components.Ingress.MemoryRequest,
-			MemoryLimit:        bc.Spec.Components.Ingress.MemoryLimit,
-			RolloutRestartTime: bc.GetAnnotations()[resources.IngressRestartTimeAnnotationKey],
-			AuthType:           authType,
+			ComponentName:             resources.IngressName,
+			BrokerCell:                bc,
+			Image:                     r.env.IngressImage,
+			ServiceAccountName:        r.env.ServiceAccountName,
+			MetricsPort:               r.env.MetricsPort,
+			AllowIstioSidecar:         true,
+			CPURequest:                bc.Spec.Components.Ingress.CPURequest,
+			CPULimit:                  bc.Spec.Components.Ingress.CPULimit,
+			MemoryRequest:             bc.Spec.Components.Ingress.MemoryRequest,
+			MemoryLimit:               bc.Spec.Components.Ingress.MemoryLimit,
+			PriorityClassName:         bc.Spec.Components.Ingress.PriorityClassName,
+			NodeSelector:              bc.Spec.Components.Ingress.NodeSelector,
+			Tolerations:               bc.Spec.Components.Ingress.Tolerations,
+			TopologySpreadConstraints: bc.Spec.Components.Ingress.TopologySpreadConstraints,
+			RolloutRestartTime:        bc.GetAnnotations()[resources.IngressRestartTimeAnnotationKey],
+			AuthType:                  authType,
+			TargetsStreamAddress:      r.targetsStreamAddress(),
 		},
 		Port: r.env.IngressPort,
 		// TODO(#1804): remove this arg when enabling the feature by default.
@@ -316,8 +375,13 @@ func (r *Reconciler) makeIngressArgs(bc *intv1alpha1.BrokerCell, authType authch
 	}
 }
 
+// getIngressFilteringEnabled returns whether ingress filtering is enabled. The BrokerCell spec
+// takes precedence over the legacy annotation.
 // TODO(#1804): remove this function when enabling the feature by default.
 func getIngressFilteringEnabled(bc *intv1alpha1.BrokerCell) bool {
+	if bc.Spec.IngressFiltering != nil {
+		return *bc.Spec.IngressFiltering
+	}
 	if val, ok := bc.GetAnnotations()[resources.IngressFilteringEnabledAnnotationKey]; ok {
 		return val == "true"
 	}
@@ -325,6 +389,15 @@ func getIngressFilteringEnabled(bc *intv1alpha1.BrokerCell) bool {
 	return false
 }
 
+// targetsStreamAddress returns the address of the targets stream, or an empty address if the
+// stream is disabled.
+func (r *Reconciler) targetsStreamAddress() string {
+	if r.targetsStream == nil {
+		return ""
+	}
+	return fmt.Sprintf("%s:%d", network.GetServiceHostname(resources.TargetsStreamServiceName, system.Namespace()), r.env.TargetsStreamPort)
+}
+
 func (r *Reconciler) makeIngressHPAArgs(bc *intv1alpha1.BrokerCell) resources.AutoscalingArgs {
 	return resources.AutoscalingArgs{
 		ComponentName:     resources.IngressName,
@@ -339,61 +412,75 @@ func (r *Reconciler) makeIngressHPAArgs(bc *intv1alpha1.BrokerCell) resources.Au
 func (r *Reconciler) makeFanoutArgs(bc *intv1alpha1.BrokerCell, authType authcheck.AuthType) resources.FanoutArgs {
 	return resources.FanoutArgs{
 		Args: resources.Args{
-			ComponentName:      resources.FanoutName,
-			BrokerCell:         bc,
-			Image:              r.env.FanoutImage,
-			ServiceAccountName: r.env.ServiceAccountName,
-			MetricsPort:        r.env.MetricsPort,
-			AllowIstioSidecar:  true,
-			CPURequest:         bc.Spec.Components.Fanout.CPURequest,
-			CPULimit:           bc.Spec.Components.Fanout.CPULimit,
-			MemoryRequest:      bc.Spec.Components.Fanout.MemoryRequest,
-			MemoryLimit:        bc.Spec.Components.Fanout.MemoryLimit,
-			RolloutRestartTime: bc.GetAnnotations()[resources.FanoutRestartTimeAnnotationKey],
-			AuthType:           authType,
+			ComponentName:             resources.FanoutName,
+			BrokerCell:                bc,
+			Image:                     r.env.FanoutImage,
+			ServiceAccountName:        r.env.ServiceAccountName,
+			MetricsPort:               r.env.MetricsPort,
+			AllowIstioSidecar:         true,
+			CPURequest:                bc.Spec.Components.Fanout.CPURequest,
+			CPULimit:                  bc.Spec.Components.Fanout.CPULimit,
+			MemoryRequest:             bc.Spec.Components.Fanout.MemoryRequest,
+			MemoryLimit:               bc.Spec.Components.Fanout.MemoryLimit,
+			PriorityClassName:         bc.Spec.Components.Fanout.PriorityClassName,
+			NodeSelector:              bc.Spec.Components.Fanout.NodeSelector,
+			Tolerations:               bc.Spec.Components.Fanout.Tolerations,
+			TopologySpreadConstraints: bc.Spec.Components.Fanout.TopologySpreadConstraints,
+			RolloutRestartTime:        bc.GetAnnotations()[resources.FanoutRestartTimeAnnotationKey],
+			AuthType:                  authType,
+			TargetsStreamAddress:      r.targetsStreamAddress(),
+			KEDACredentials:           usesKEDA(bc.Spec.Components.Fanout),
 		},
+		EnableSharding: bc.Spec.Sharding != nil && *bc.Spec.Sharding,
 	}
 }
 
 func (r *Reconciler) makeFanoutHPAArgs(bc *intv1alpha1.BrokerCell) resources.AutoscalingArgs {
-	return resources.AutoscalingArgs{
+	return r.backlogAutoscalingArgs(bc, bc.Spec.Components.Fanout, false, resources.AutoscalingArgs{
 		ComponentName:     resources.FanoutName,
 		BrokerCell:        bc,
 		AvgCPUUtilization: bc.Spec.Components.Fanout.AvgCPUUtilization,
 		AvgMemoryUsage:    bc.Spec.Components.Fanout.AvgMemoryUsage,
 		MaxReplicas:       *bc.Spec.Components.Fanout.MaxReplicas,
 		MinReplicas:       *bc.Spec.Components.Fanout.MinReplicas,
-	}
+	})
 }
 
 func (r *Reconciler) makeRetryArgs(bc *intv1alpha1.BrokerCell, authType authcheck.AuthType) resources.RetryArgs {
 	return resources.RetryArgs{
 		Args: resources.Args{
-			ComponentName:      resources.RetryName,
-			BrokerCell:         bc,
-			Image:              r.env.RetryImage,
-			ServiceAccountName: r.env.ServiceAccountName,
-			MetricsPort:        r.env.MetricsPort,
-			AllowIstioSidecar:  true,
-			CPURequest:         bc.Spec.Components.Retry.CPURequest,
-			CPULimit:           bc.Spec.Components.Retry.CPULimit,
-			MemoryRequest:      bc.Spec.Components.Retry.MemoryRequest,
-			MemoryLimit:        bc.Spec.Components.Retry.MemoryLimit,
-			RolloutRestartTime: bc.GetAnnotations()[resources.RetryRestartTimeAnnotationKey],
-			AuthType:           authType,
+			ComponentName:             resources.RetryName,
+			BrokerCell:                bc,
+			Image:                     r.env.RetryImage,
+			ServiceAccountName:        r.env.ServiceAccountName,
+			MetricsPort:               r.env.MetricsPort,
+			AllowIstioSidecar:         true,
+			CPURequest:                bc.Spec.Components.Retry.CPURequest,
+			CPULimit:                  bc.Spec.Components.Retry.CPULimit,
+			MemoryRequest:             bc.Spec.Components.Retry.MemoryRequest,
+			MemoryLimit:               bc.Spec.Components.Retry.MemoryLimit,
+			PriorityClassName:         bc.Spec.Components.Retry.PriorityClassName,
+			NodeSelector:              bc.Spec.Components.Retry.NodeSelector,
+			Tolerations:               bc.Spec.Components.Retry.Tolerations,
+			TopologySpreadConstraints: bc.Spec.Components.Retry.TopologySpreadConstraints,
+			RolloutRestartTime:        bc.GetAnnotations()[resources.RetryRestartTimeAnnotationKey],
+			AuthType:                  authType,
+			TargetsStreamAddress:      r.targetsStreamAddress(),
+			KEDACredentials:           usesKEDA(bc.Spec.Components.Retry),
 		},
+		EnableSharding: bc.Spec.Sharding != nil && *bc.Spec.Sharding,
 	}
 }
 
 func (r *Reconciler) makeRetryHPAArgs(bc *intv1alpha1.BrokerCell) resources.AutoscalingArgs {
-	return resources.AutoscalingArgs{
+	return r.backlogAutoscalingArgs(bc, bc.Spec.Components.Retry, true, resources.AutoscalingArgs{
 		ComponentName:     resources.RetryName,
 		BrokerCell:        bc,
 		AvgCPUUtilization: bc.Spec.Components.Retry.AvgCPUUtilization,
 		AvgMemoryUsage:    bc.Spec.Components.Retry.AvgMemoryUsage,
 		MaxReplicas:       *bc.Spec.Components.Retry.MaxReplicas,
 		MinReplicas:       *bc.Spec.Components.Retry.MinReplicas,
-	}
+	})
 }
 
 func (r *Reconciler) reconcileAutoscaling(ctx context.Context, bc *intv1alpha1.BrokerCell, desired *hpav2beta2.HorizontalPodAutoscaler) error {
@@ -424,3 +511,54 @@ func (r *Reconciler) reconcileAutoscaling(ctx context.Context, bc *intv1alpha1.B
 	}
 	return nil
 }
+
+// reconcilePodDisruptionBudget reconciles the PodDisruptionBudget of a component deployment, or
+// deletes it if the component has none.
+func (r *Reconciler) reconcilePodDisruptionBudget(ctx context.Context, bc *intv1alpha1.BrokerCell, d *appsv1.Deployment, componentName string, spec *intv1alpha1.PodDisruptionBudgetSpec) error {
+	name := resources.PodDisruptionBudgetName(d)
+	existing, err := r.pdbLister.PodDisruptionBudgets(d.Namespace).Get(name)
+	if err != nil && !apierrs.IsNotFound(err) {
+		return err
+	}
+	if spec == nil {
+		if existing == nil {
+			return nil
+		}
+		err := r.KubeClientSet.PolicyV1beta1().PodDisruptionBudgets(d.Namespace).Delete(ctx, name, metav1.DeleteOptions{})
+		if err == nil {
+			r.Recorder.Eventf(bc, corev1.EventTypeNormal, "PodDisruptionBudgetDeleted", "Deleted PodDisruptionBudget %s/%s", d.Namespace, name)
+		}
+		if apierrs.IsNotFound(err) {
+			return nil
+		}
+		return err
+	}
+
+	desired := resources.MakePodDisruptionBudget(d, resources.PodDisruptionBudgetArgs{
+		ComponentName:  componentName,
+		BrokerCell:     bc,
+		MinAvailable:   spec.MinAvailable,
+		MaxUnavailable: spec.MaxUnavailable,
+	})
+	if existing == nil {
+		_, err := r.KubeClientSet.PolicyV1beta1().PodDisruptionBudgets(d.Namespace).Create(ctx, desired, metav1.CreateOptions{})
+		if apierrs.IsAlreadyExists(err) {
+			return nil
+		}
+		if err == nil {
+			r.Recorder.Eventf(bc, corev1.EventTypeNormal, "PodDisruptionBudgetCreated", "Created PodDisruptionBudget %s/%s", d.Namespace, name)
+		}
+		return err
+	}
+	if !equality.Semantic.DeepEqual(desired.Spec, existing.Spec) {
+		// Don't modify the informers copy.
+		copy := existing.DeepCopy()
+		copy.Spec = desired.Spec
+		_, err := r.KubeClientSet.PolicyV1beta1().PodDisruptionBudgets(d.Namespace).Update(ctx, copy, metav1.UpdateOptions{})
+		if err == nil {
+			r.Recorder.Eventf(bc, corev1.EventTypeNormal, "PodDisruptionBudgetUpdated", "Updated PodDisruptionBudget %s/%s", d.Namespace, name)
+		}
+		return err
+	}
+	return nil
+}
@@ -20,9 +20,11 @@ import (
 	"context"
 	"fmt"
 	"testing"
+	"time"
 
 	eventingduckv1 "knative.dev/eventing/pkg/apis/duck/v1"
 	"knative.dev/pkg/apis"
+	duckv1 "knative.dev/pkg/apis/duck/v1"
 
 	"github.com/google/knative-gcp/pkg/apis/messaging/v1beta1"
 
@@ -31,12 +33,17 @@ import (
 	corev1 "k8s.io/api/core/v1"
 	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
 	"k8s.io/apimachinery/pkg/runtime"
+	"k8s.io/apimachinery/pkg/runtime/schema"
+	"k8s.io/apimachinery/pkg/types"
 	"k8s.io/client-go/kubernetes/scheme"
 	clientgotesting "k8s.io/client-go/testing"
+	"knative.dev/pkg/client/injection/ducks/duck/v1/addressable"
 	fakekubeclient "knative.dev/pkg/client/injection/kube/client/fake"
 	"knative.dev/pkg/configmap"
 	"knative.dev/pkg/controller"
+	"knative.dev/pkg/ptr"
 	. "knative.dev/pkg/reconciler/testing"
+	"knative.dev/pkg/resolver"
 
 	"github.com/google/go-cmp/cmp"
 	"google.golang.org/protobuf/proto"
@@ -44,6 +51,7 @@ import (
 	brokerv1 "github.com/google/knative-gcp/pkg/apis/broker/v1"
 	intv1alpha1 "github.com/google/knative-gcp/pkg/apis/intevents/v1alpha1"
 	"github.com/google/knative-gcp/pkg/broker/config"
+	"github.com/google/knative-gcp/pkg/broker/config/stream"
 	bcreconciler "github.com/google/knative-gcp/pkg/client/injection/reconciler/intevents/v1alpha1/brokercell"
 	"github.com/google/knative-gcp/pkg/reconciler"
 	"github.com/google/knative-gcp/pkg/reconciler/brokercell/resources"
@@ -57,6 +65,9 @@ const (
 	brokerCellName = "test-brokercell"
 	targetsCMName  = "broker-targets"
 	targetsCMKey   = "targets"
+
+	// targetsStreamKey is the key of the test data holding the targets stream of the reconciler.
+	targetsStreamKey = "targetsStream"
 )
 
 var (
@@ -155,7 +166,7 @@ func TestAllCases(t *testing.T) {
 			Objects: []runtime.Object{
 				NewBrokerCell(brokerCellName, testNS, WithBrokerCellSetDefaults),
 				testingdata.EmptyConfig(t, NewBrokerCell(brokerCellName, testNS, WithBrokerCellSetDefaults)),
-				NewBroker("broker", testNS, WithBrokerSetDefaults),
+				NewBroker("broker", testNS, WithBrokerSetDefaults, WithBrokerBrokerCell(brokerCellName)),
 			},
 			WithReactors: []clientgotesting.ReactionFunc{InduceFailure("update", "configmaps")},
 			WantStatusUpdates: []clientgotesting.UpdateActionImpl{{
@@ -170,8 +181,9 @@ func TestAllCases(t *testing.T) {
 				NewBrokerCell(brokerCellName, testNS, WithBrokerCellSetDefaults),
 				testingdata.BrokerCellObjects{
 					BrokersToTriggers: map[*brokerv1.Broker][]*brokerv1.Trigger{
-						NewBroker("broker", testNS, WithBrokerSetDefaults): {},
+						NewBroker("broker", testNS, WithBrokerSetDefaults, WithBrokerBrokerCell(brokerCellName)): {},
 					},
+					Generation: 2,
 				},
 			)}},
 			WantErr: true,
@@ -721,7 +733,7 @@ func TestAllCases(t *testing.T) {
 			Objects: []runtime.Object{
 				NewBrokerCell(brokerCellName, testNS, WithBrokerCellSetDefaults),
 				NewBrokerCell(brokerCellName, testNS, WithBrokerCellSetDefaults),
-				NewBroker("broker", testNS, WithBrokerSetDefaults),
+				NewBroker("broker", testNS, WithBrokerSetDefaults, WithBrokerBrokerCell(brokerCellName)),
 				testingdata.EmptyConfig(t, NewBrokerCell(brokerCellName, testNS, WithBrokerCellSetDefaults)),
 				NewEndpoints(brokerCellName+"-brokercell-ingress", testNS),
 				NewDeployment(brokerCellName+"-brokercell-ingress", testNS,
@@ -756,8 +768,9 @@ func TestAllCases(t *testing.T) {
 					NewBrokerCell(brokerCellName, testNS, WithBrokerCellSetDefaults),
 					testingdata.BrokerCellObjects{
 						BrokersToTriggers: map[*brokerv1.Broker][]*brokerv1.Trigger{
-							NewBroker("broker", testNS, WithBrokerSetDefaults): {},
+							NewBroker("broker", testNS, WithBrokerSetDefaults, WithBrokerBrokerCell(brokerCellName)): {},
 						},
+						Generation: 2,
 					})},
 				{Object: testingdata.IngressDeployment(t)},
 				{Object: testingdata.IngressHPA(t)},
@@ -881,6 +894,114 @@ func TestAllCases(t *testing.T) {
 				brokerCellReconciledEvent,
 			},
 		},
+		{
+			Name: "BrokerCell with targets stream created successfully",
+			Key:  testKey,
+			Objects: []runtime.Object{
+				NewBrokerCell(brokerCellName, testNS, WithBrokerCellSetDefaults),
+				testingdata.EmptyConfig(t, NewBrokerCell(brokerCellName, testNS, WithBrokerCellSetDefaults)),
+				NewEndpoints(brokerCellName+"-brokercell-ingress", testNS,
+					WithEndpointsAddresses(corev1.EndpointAddress{IP: "127.0.0.1"})),
+				testingdata.IngressDeploymentWithTargetsStream(t),
+				testingdata.IngressServiceWithStatus(t),
+				testingdata.FanoutDeploymentWithTargetsStream(t),
+				testingdata.RetryDeploymentWithTargetsStream(t),
+				testingdata.IngressHPA(t),
+				testingdata.FanoutHPA(t),
+				testingdata.RetryHPA(t),
+			},
+			OtherTestData: map[string]interface{}{
+				targetsStreamKey: stream.NewServer(nil),
+			},
+			WantStatusUpdates: []clientgotesting.UpdateActionImpl{
+				{Object: NewBrokerCell(brokerCellName, testNS,
+					WithBrokerCellReady,
+					WithIngressTemplate("http://test-brokercell-brokercell-ingress.testnamespace.svc.cluster.local/{namespace}/{name}"),
+					WithBrokerCellSetDefaults,
+				)},
+			},
+			WantEvents: []string{
+				brokerCellReconciledEvent,
+			},
+		},
+		{
+			Name: "BrokerCell with sharded targets config created successfully",
+			Key:  testKey,
+			Objects: []runtime.Object{
+				NewBrokerCell(brokerCellName, testNS, WithBrokerCellSetDefaults,
+					WithBrokerCellTargetsConfig(2, intv1alpha1.TargetsConfigCompressionGzip)),
+				testingdata.EmptyConfig(t, NewBrokerCell(brokerCellName, testNS, WithBrokerCellSetDefaults)),
+				// The third shard is no longer used.
+				testingdata.EmptyConfigShards(t, NewBrokerCell(brokerCellName, testNS, WithBrokerCellSetDefaults,
+					WithBrokerCellTargetsConfig(3, intv1alpha1.TargetsConfigCompressionGzip)))[3],
+				NewEndpoints(brokerCellName+"-brokercell-ingress", testNS,
+					WithEndpointsAddresses(corev1.EndpointAddress{IP: "127.0.0.1"})),
+				testingdata.IngressDeploymentWithTargetsShards(t),
+				testingdata.IngressServiceWithStatus(t),
+				testingdata.FanoutDeploymentWithTargetsShards(t),
+				testingdata.RetryDeploymentWithTargetsShards(t),
+				testingdata.IngressHPA(t),
+				testingdata.FanoutHPA(t),
+				testingdata.RetryHPA(t),
+			},
+			WantCreates: []runtime.Object{
+				testingdata.EmptyConfigShards(t, NewBrokerCell(brokerCellName, testNS, WithBrokerCellSetDefaults,
+					WithBrokerCellTargetsConfig(2, intv1alpha1.TargetsConfigCompressionGzip)))[1],
+				testingdata.EmptyConfigShards(t, NewBrokerCell(brokerCellName, testNS, WithBrokerCellSetDefaults,
+					WithBrokerCellTargetsConfig(2, intv1alpha1.TargetsConfigCompressionGzip)))[2],
+			},
+			WantUpdates: []clientgotesting.UpdateActionImpl{{
+				Object: testingdata.EmptyConfigShards(t, NewBrokerCell(brokerCellName, testNS, WithBrokerCellSetDefaults,
+					WithBrokerCellTargetsConfig(2, intv1alpha1.TargetsConfigCompressionGzip)))[0],
+			}},
+			WantDeletes: []clientgotesting.DeleteActionImpl{{
+				ActionImpl: clientgotesting.ActionImpl{
+					Namespace: testNS, Verb: "delete", Resource: schema.GroupVersionResource{Version: "v1", Resource: "configmaps"}},
+				Name: brokerCellName + "-brokercell-broker-targets-2",
+			}},
+			WantStatusUpdates: []clientgotesting.UpdateActionImpl{
+				{Object: NewBrokerCell(brokerCellName, testNS,
+					WithBrokerCellReady,
+					WithBrokerCellTargetsConfig(2, intv1alpha1.TargetsConfigCompressionGzip),
+					WithIngressTemplate("http://test-brokercell-brokercell-ingress.testnamespace.svc.cluster.local/{namespace}/{name}"),
+					WithBrokerCellSetDefaults,
+				)},
+			},
+			WantEvents: []string{
+				Eventf(corev1.EventTypeNormal, "ConfigMapCreated", "Created configmap testnamespace/test-brokercell-brokercell-broker-targets-0"),
+				Eventf(corev1.EventTypeNormal, "ConfigMapCreated", "Created configmap testnamespace/test-brokercell-brokercell-broker-targets-1"),
+				configmapUpdatedEvent,
+				brokerCellReconciledEvent,
+			},
+		},
+		{
+			Name: "BrokerCell with sharding created successfully",
+			Key:  testKey,
+			Objects: []runtime.Object{
+				NewBrokerCell(brokerCellName, testNS, WithBrokerCellSetDefaults, WithBrokerCellSharding(true)),
+				testingdata.EmptyConfig(t, NewBrokerCell(brokerCellName, testNS, WithBrokerCellSetDefaults)),
+				NewEndpoints(brokerCellName+"-brokercell-ingress", testNS,
+					WithEndpointsAddresses(corev1.EndpointAddress{IP: "127.0.0.1"})),
+				testingdata.IngressDeploymentWithStatus(t),
+				testingdata.IngressServiceWithStatus(t),
+				testingdata.FanoutDeploymentWithSharding(t),
+				testingdata.RetryDeploymentWithSharding(t),
+				testingdata.IngressHPA(t),
+				testingdata.FanoutHPA(t),
+				testingdata.RetryHPA(t),
+			},
+			WantStatusUpdates: []clientgotesting.UpdateActionImpl{
+				{Object: NewBrokerCell(brokerCellName, testNS,
+					WithBrokerCellReady,
+					WithBrokerCellSharding(true),
+					WithIngressTemplate("http://test-brokercell-brokercell-ingress.testnamespace.svc.cluster.local/{namespace}/{name}"),
+					WithBrokerCellSetDefaults,
+				)},
+			},
+			WantEvents: []string{
+				brokerCellReconciledEvent,
+			},
+		},
 		{
 			Name: "googlecloud created BrokerCell shouldn't be gc'ed because there are brokers",
 			Key:  testKey,
@@ -891,10 +1012,10 @@ func TestAllCases(t *testing.T) {
 				testingdata.Config(NewBrokerCell(brokerCellName, testNS, WithBrokerCellSetDefaults),
 					testingdata.BrokerCellObjects{
 						BrokersToTriggers: map[*brokerv1.Broker][]*brokerv1.Trigger{
-							NewBroker("broker", testNS, WithBrokerSetDefaults): {},
+							NewBroker("broker", testNS, WithBrokerSetDefaults, WithBrokerBrokerCell(brokerCellName)): {},
 						},
 					}),
-				NewBroker("broker", testNS, WithBrokerSetDefaults),
+				NewBroker("broker", testNS, WithBrokerSetDefaults, WithBrokerBrokerCell(brokerCellName)),
 				NewEndpoints(brokerCellName+"-brokercell-ingress", testNS,
 					WithEndpointsAddresses(corev1.EndpointAddress{IP: "127.0.0.1"})),
 				testingdata.IngressDeploymentWithStatus(t),
@@ -927,10 +1048,10 @@ func TestAllCases(t *testing.T) {
 				testingdata.Config(NewBrokerCell(brokerCellName, testNS, WithBrokerCellSetDefaults),
 					testingdata.BrokerCellObjects{
 						Channels: []*v1beta1.Channel{
-							NewChannel("channel", testNS, WithChannelSetDefaults, WithChannelAddress("http://example.com")),
+							NewChannel("channel", testNS, WithChannelSetDefaults, WithChannelBrokerCell(brokerCellName), WithChannelAddress("http://example.com")),
 						},
 					}),
-				NewChannel("channel", testNS, WithChannelSetDefaults, WithChannelAddress("http://example.com")),
+				NewChannel("channel", testNS, WithChannelSetDefaults, WithChannelBrokerCell(brokerCellName), WithChannelAddress("http://example.com")),
 				NewEndpoints(brokerCellName+"-brokercell-ingress", testNS,
 					WithEndpointsAddresses(corev1.EndpointAddress{IP: "127.0.0.1"})),
 				testingdata.IngressDeploymentWithStatus(t),
@@ -1041,6 +1162,7 @@ func TestAllCases(t *testing.T) {
 			brokerLister:         testingListers.GetBrokerLister(),
 			channelLister:        testingListers.GetChannelLister(),
 			hpaLister:            testingListers.GetHPALister(),
+			pdbLister:            testingListers.GetPodDisruptionBudgetLister(),
 			triggerLister:        testingListers.GetTriggerLister(),
 			configMapLister:      testingListers.GetConfigMapLister(),
 			secretLister:         testingListers.GetSecretLister(),
@@ -1055,6 +1177,11 @@ func TestAllCases(t *testing.T) {
 		if err != nil {
 			t.Fatalf("Failed to created BrokerCell reconciler: %v", err)
 		}
+		r.uriResolver = resolver.NewURIResolver(addressable.WithDuck(ctx), func(types.NamespacedName) {})
+		if s, ok := testData[targetsStreamKey].(*stream.Server); ok {
+			r.targetsStream = s
+			r.env.TargetsStreamPort = 9091
+		}
 		return bcreconciler.NewReconciler(ctx, r.Logger, r.RunClientSet, testingListers.GetBrokerCellLister(), r.Recorder, r)
 	}))
 }
@@ -1078,7 +1205,7 @@ func TestBrokerTargetsReconcileConfig(t *testing.T) {
 	}{
 		{
 			name:   "reconcile config of one broker and its triggers",
-			broker: NewBroker("broker", testNS, WithBrokerClass(brokerv1.BrokerClass)),
+			broker: NewBroker("broker", testNS, WithBrokerClass(brokerv1.BrokerClass), WithBrokerBrokerCell(brokerCellName)),
 			triggers: []*brokerv1.Trigger{
 				NewTrigger("trigger1", testNS, "broker", WithTriggerSetDefaults),
 				NewTrigger("trigger2", testNS, "broker", WithTriggerSetDefaults),
@@ -1087,6 +1214,45 @@ func TestBrokerTargetsReconcileConfig(t *testing.T) {
 			expectEmptyMap: false,
 		},
 
+		{
+			name: "reconcile config of one broker with a dead letter sink",
+			broker: NewBroker("broker", testNS, WithBrokerClass(brokerv1.BrokerClass), WithBrokerBrokerCell(brokerCellName),
+				WithBrokerDeliverySpec(&eventingduckv1.DeliverySpec{
+					DeadLetterSink: &duckv1.Destination{URI: uri("http://example.com/dead-letter")},
+					Retry:          ptr.Int32(3),
+				})),
+			triggers: []*brokerv1.Trigger{
+				NewTrigger("trigger1", testNS, "broker", WithTriggerSetDefaults),
+			},
+			bc: NewBrokerCell(brokerCellName, testNS, WithBrokerCellSetDefaults),
+		},
+		{
+			name: "reconcile config of one broker with a trigger dead letter sink",
+			broker: NewBroker("broker", testNS, WithBrokerClass(brokerv1.BrokerClass), WithBrokerBrokerCell(brokerCellName),
+				WithBrokerDeliverySpec(&eventingduckv1.DeliverySpec{
+					DeadLetterSink: &duckv1.Destination{URI: uri("http://example.com/dead-letter")},
+					Retry:          ptr.Int32(3),
+				})),
+			triggers: []*brokerv1.Trigger{
+				NewTrigger("trigger1", testNS, "broker", WithTriggerSetDefaults,
+					WithTriggerDeliverySpec(&eventingduckv1.DeliverySpec{
+						DeadLetterSink: &duckv1.Destination{URI: uri("http://example.com/trigger-dead-letter")},
+					})),
+				NewTrigger("trigger2", testNS, "broker", WithTriggerSetDefaults),
+			},
+			bc: NewBrokerCell(brokerCellName, testNS, WithBrokerCellSetDefaults),
+		},
+		{
+			name: "reconcile config of one broker with a pubsub dead letter sink",
+			broker: NewBroker("broker", testNS, WithBrokerClass(brokerv1.BrokerClass), WithBrokerBrokerCell(brokerCellName),
+				WithBrokerDeliverySpec(&eventingduckv1.DeliverySpec{
+					DeadLetterSink: &duckv1.Destination{URI: uri("pubsub://dead-letter-topic")},
+				})),
+			triggers: []*brokerv1.Trigger{
+				NewTrigger("trigger1", testNS, "broker", WithTriggerSetDefaults),
+			},
+			bc: NewBrokerCell(brokerCellName, testNS, WithBrokerCellSetDefaults),
+		},
 		{
 			name:   "reconcile config when the broker is not gcp broker",
 			broker: NewBroker("broker", testNS, WithBrokerClass("some-other-broker-class")),
@@ -1097,18 +1263,42 @@ func TestBrokerTargetsReconcileConfig(t *testing.T) {
 			bc:             NewBrokerCell(brokerCellName, testNS, WithBrokerCellSetDefaults),
 			expectEmptyMap: true,
 		},
+		{
+			name:   "reconcile config when the broker is in another BrokerCell",
+			broker: NewBroker("broker", testNS, WithBrokerClass(brokerv1.BrokerClass), WithBrokerBrokerCell("other-brokercell")),
+			triggers: []*brokerv1.Trigger{
+				NewTrigger("trigger1", testNS, "broker", WithTriggerSetDefaults),
+			},
+			bc:             NewBrokerCell(brokerCellName, testNS, WithBrokerCellSetDefaults),
+			expectEmptyMap: true,
+		},
 		{
 			name: "Channels",
 			channels: []*v1beta1.Channel{
-				NewChannel("channel1", testNS, WithChannelSetDefaults, WithChannelAddress("http://example.com/1")),
-				NewChannel("channel2", testNS, WithChannelSetDefaults, WithChannelAddress("http://example.com/2")),
+				NewChannel("channel1", testNS, WithChannelSetDefaults, WithChannelBrokerCell(brokerCellName), WithChannelAddress("http://example.com/1")),
+				NewChannel("channel2", testNS, WithChannelSetDefaults, WithChannelBrokerCell(brokerCellName), WithChannelAddress("http://example.com/2")),
+			},
+			bc: NewBrokerCell(brokerCellName, testNS, WithBrokerCellSetDefaults),
+		},
+		{
+			name: "Channels with dead lettered Subscribers",
+			channels: []*v1beta1.Channel{
+				NewChannel("channel1", testNS, WithChannelSetDefaults, WithChannelBrokerCell(brokerCellName), WithChannelAddress("http://example.com/1"),
+					WithChannelSubscribers(eventingduckv1.SubscriberSpec{
+						UID:           "subscriber-1-uid",
+						SubscriberURI: uri("http://example.com/subscriber-1-uri"),
+						Delivery: &eventingduckv1.DeliverySpec{
+							DeadLetterSink: &duckv1.Destination{URI: uri("http://example.com/dead-letter")},
+						},
+					}),
+				),
 			},
 			bc: NewBrokerCell(brokerCellName, testNS, WithBrokerCellSetDefaults),
 		},
 		{
 			name: "Channels with Subscribers",
 			channels: []*v1beta1.Channel{
-				NewChannel("channel1", testNS, WithChannelSetDefaults, WithChannelAddress("http://example.com/1"),
+				NewChannel("channel1", testNS, WithChannelSetDefaults, WithChannelBrokerCell(brokerCellName), WithChannelAddress("http://example.com/1"),
 					WithChannelSubscribers(eventingduckv1.SubscriberSpec{
 						UID:           "subscriber-1-uid",
 						SubscriberURI: uri("http://example.com/subscriber-1-uri"),
@@ -1120,7 +1310,7 @@ func TestBrokerTargetsReconcileConfig(t *testing.T) {
 						ReplyURI:      uri("http://example.com/subscriber-2-reply"),
 					}),
 				),
-				NewChannel("channel2", testNS, WithChannelSetDefaults, WithChannelAddress("http://example.com/2"),
+				NewChannel("channel2", testNS, WithChannelSetDefaults, WithChannelBrokerCell(brokerCellName), WithChannelAddress("http://example.com/2"),
 					WithChannelSubscribers(eventingduckv1.SubscriberSpec{
 						UID:           "subscriber-3-uid",
 						SubscriberURI: uri("http://example.com/subscriber-3-uri"),
@@ -1137,14 +1327,14 @@ func TestBrokerTargetsReconcileConfig(t *testing.T) {
 		},
 		{
 			name:   "Brokers and Channels",
-			broker: NewBroker("broker", testNS, WithBrokerClass(brokerv1.BrokerClass)),
+			broker: NewBroker("broker", testNS, WithBrokerClass(brokerv1.BrokerClass), WithBrokerBrokerCell(brokerCellName)),
 			triggers: []*brokerv1.Trigger{
 				NewTrigger("trigger1", testNS, "broker", WithTriggerSetDefaults),
 				NewTrigger("trigger2", testNS, "broker", WithTriggerSetDefaults),
 			},
 			bc: NewBrokerCell(brokerCellName, testNS, WithBrokerCellSetDefaults),
 			channels: []*v1beta1.Channel{
-				NewChannel("channel1", testNS, WithChannelSetDefaults, WithChannelAddress("http://example.com/1"),
+				NewChannel("channel1", testNS, WithChannelSetDefaults, WithChannelBrokerCell(brokerCellName), WithChannelAddress("http://example.com/1"),
 					WithChannelSubscribers(eventingduckv1.SubscriberSpec{
 						UID:           "subscriber-1-uid",
 						SubscriberURI: uri("http://example.com/subscriber-1-uri"),
@@ -1156,7 +1346,7 @@ func TestBrokerTargetsReconcileConfig(t *testing.T) {
 						ReplyURI:      uri("http://example.com/subscriber-2-reply"),
 					}),
 				),
-				NewChannel("channel2", testNS, WithChannelSetDefaults, WithChannelAddress("http://example.com/2"),
+				NewChannel("channel2", testNS, WithChannelSetDefaults, WithChannelBrokerCell(brokerCellName), WithChannelAddress("http://example.com/2"),
 					WithChannelSubscribers(eventingduckv1.SubscriberSpec{
 						UID:           "subscriber-3-uid",
 						SubscriberURI: uri("http://example.com/subscriber-3-uri"),
@@ -1195,6 +1385,7 @@ func TestBrokerTargetsReconcileConfig(t *testing.T) {
 				brokerLister:     testingListers.GetBrokerLister(),
 				channelLister:    testingListers.GetChannelLister(),
 				hpaLister:        testingListers.GetHPALister(),
+				pdbLister:        testingListers.GetPodDisruptionBudgetLister(),
 				triggerLister:    testingListers.GetTriggerLister(),
 				configMapLister:  testingListers.GetConfigMapLister(),
 				serviceLister:    testingListers.GetK8sServiceLister(),
@@ -1206,6 +1397,7 @@ func TestBrokerTargetsReconcileConfig(t *testing.T) {
 			if err != nil {
 				t.Fatalf("Failed to create BrokerCell reconciler: %v", err)
 			}
+			r.uriResolver = resolver.NewURIResolver(addressable.WithDuck(ctx), func(types.NamespacedName) {})
 			// here we only want to test the functionality of the reconcileConfig that it should create a brokerTargets config successfully
 			r.reconcileConfig(ctx, bc)
 			var wantMap *corev1.ConfigMap
@@ -1249,3 +1441,335 @@ func uri(uri string) *apis.URL {
 	url, _ := apis.ParseURL(uri)
 	return url
 }
+
+func TestToConfigFilters(t *testing.T) {
+	filters := []brokerv1.SubscriptionsAPIFilter{
+		{Exact: map[string]string{"type": "a"}},
+		{Any: []brokerv1.SubscriptionsAPIFilter{
+			{Prefix: map[string]string{"source": "b"}},
+			{Not: &brokerv1.SubscriptionsAPIFilter{Suffix: map[string]string{"subject": "c"}}},
+		}},
+		{All: []brokerv1.SubscriptionsAPIFilter{{SQL: "EXISTS d"}}},
+	}
+	want := []*config.Filter{
+		{Exact: map[string]string{"type": "a"}},
+		{Any: []*config.Filter{
+			{Prefix: map[string]string{"source": "b"}},
+			{Not: &config.Filter{Suffix: map[string]string{"subject": "c"}}},
+		}},
+		{All: []*config.Filter{{Cesql: "EXISTS d"}}},
+	}
+	got := toConfigFilters(filters)
+	if len(got) != len(want) {
+		t.Fatalf("toConfigFilters returned %d filters, want %d", len(got), len(want))
+	}
+	for i := range want {
+		if !proto.Equal(got[i], want[i]) {
+			t.Errorf("toConfigFilters()[%d] = %v, want %v", i, got[i], want[i])
+		}
+	}
+	if got := toConfigFilters(nil); got != nil {
+		t.Errorf("toConfigFilters(nil) = %v, want nil", got)
+	}
+}
+
+func TestResponsePolicy(t *testing.T) {
+	brokerClassification := `{"deadLetter":["4xx"],"retry":["429"]}`
+	brokerPolicy := &config.ResponsePolicy{
+		DeadLetter: []*config.StatusCodeRange{{Min: 400, Max: 499}},
+		Retry:      []*config.StatusCodeRange{{Min: 429, Max: 429}},
+	}
+	cases := []struct {
+		name                  string
+		brokerClassification  string
+		triggerClassification string
+		want                  *config.ResponsePolicy
+	}{{
+		name: "no classification",
+	}, {
+		name:                 "broker classification",
+		brokerClassification: brokerClassification,
+		want:                 brokerPolicy,
+	}, {
+		name:                  "trigger classification replaces broker classification",
+		brokerClassification:  brokerClassification,
+		triggerClassification: `{"success":["409"]}`,
+		want: &config.ResponsePolicy{
+			Success: []*config.StatusCodeRange{{Min: 409, Max: 409}},
+		},
+	}, {
+		name:                  "malformed trigger classification",
+		brokerClassification:  brokerClassification,
+		triggerClassification: `{`,
+	}}
+	for _, tc := range cases {
+		t.Run(tc.name, func(t *testing.T) {
+			b := NewBroker("broker", testNS)
+			tr := NewTrigger("trigger", testNS, "broker")
+			if tc.brokerClassification != "" {
+				b.SetAnnotations(map[string]string{brokerv1.ResponseClassificationAnnotation: tc.brokerClassification})
+			}
+			if tc.triggerClassification != "" {
+				tr.SetAnnotations(map[string]string{brokerv1.ResponseClassificationAnnotation: tc.triggerClassification})
+			}
+			if got := responsePolicy(context.Background(), b, tr); !proto.Equal(got, tc.want) {
+				t.Errorf("responsePolicy() = %v, want %v", got, tc.want)
+			}
+		})
+	}
+}
+
+func TestDeliveryLimits(t *testing.T) {
+	cases := []struct {
+		name        string
+		annotations map[string]string
+		want        *config.DeliveryLimits
+	}{{
+		name: "no limits",
+	}, {
+		name: "limits",
+		annotations: map[string]string{
+			brokerv1.MaxConcurrentDeliveriesAnnotation: "10",
+			brokerv1.MaxEventsPerSecondAnnotation:      "100",
+		},
+		want: &config.DeliveryLimits{MaxConcurrentDeliveries: 10, MaxEventsPerSecond: 100},
+	}, {
+		name:        "malformed limit",
+		annotations: map[string]string{brokerv1.MaxEventsPerSecondAnnotation: "fast"},
+	}}
+	for _, tc := range cases {
+		t.Run(tc.name, func(t *testing.T) {
+			tr := NewTrigger("trigger", testNS, "broker")
+			tr.SetAnnotations(tc.annotations)
+			if got := deliveryLimits(context.Background(), tr); !proto.Equal(got, tc.want) {
+				t.Errorf("deliveryLimits() = %v, want %v", got, tc.want)
+			}
+		})
+	}
+}
+
+func TestDeliveryAuth(t *testing.T) {
+	cases := []struct {
+		name        string
+		brokerAuth  string
+		triggerAuth string
+		want        *config.DeliveryAuth
+	}{{
+		name: "no authentication",
+	}, {
+		name:       "broker oidc",
+		brokerAuth: `{"oidc":{}}`,
+		want:       &config.DeliveryAuth{Oidc: &config.OIDCAuth{}},
+	}, {
+		name:        "trigger authentication replaces broker authentication",
+		brokerAuth:  `{"oidc":{}}`,
+		triggerAuth: `{"oidc":{"audience":"https://subscriber.example.com"}}`,
+		want:        &config.DeliveryAuth{Oidc: &config.OIDCAuth{Audience: "https://subscriber.example.com"}},
+	}, {
+		name:        "bearer token scoped by namespace",
+		triggerAuth: `{"bearerToken":{"key":"token"}}`,
+		want:        &config.DeliveryAuth{BearerTokenKey: testNS + ".token"},
+	}, {
+		name:        "malformed trigger authentication",
+		brokerAuth:  `{"oidc":{}}`,
+		triggerAuth: `{`,
+	}}
+	for _, tc := range cases {
+		t.Run(tc.name, func(t *testing.T) {
+			b := NewBroker("broker", testNS)
+			tr := NewTrigger("trigger", testNS, "broker")
+			if tc.brokerAuth != "" {
+				b.SetAnnotations(map[string]string{brokerv1.DeliveryAuthAnnotation: tc.brokerAuth})
+			}
+			if tc.triggerAuth != "" {
+				tr.SetAnnotations(map[string]string{brokerv1.DeliveryAuthAnnotation: tc.triggerAuth})
+			}
+			if got := deliveryAuth(context.Background(), b, tr); !proto.Equal(got, tc.want) {
+				t.Errorf("deliveryAuth() = %v, want %v", got, tc.want)
+			}
+		})
+	}
+}
+
+func TestIngressAuth(t *testing.T) {
+	const address = "http://broker.example.com"
+	cases := []struct {
+		name string
+		auth string
+		want *config.IngressAuth
+	}{{
+		name: "no authentication",
+	}, {
+		name: "default audience",
+		auth: `{"allowedIdentities":["system:serviceaccount:ns:sender"]}`,
+		want: &config.IngressAuth{AllowedIdentities: []string{"system:serviceaccount:ns:sender"}, Audience: address},
+	}, {
+		name: "custom audience",
+		auth: `{"allowedIdentities":["sender@project.iam.gserviceaccount.com"],"audience":"aud"}`,
+		want: &config.IngressAuth{AllowedIdentities: []string{"sender@project.iam.gserviceaccount.com"}, Audience: "aud"},
+	}, {
+		name: "malformed authentication rejects all callers",
+		auth: `{`,
+		want: &config.IngressAuth{Audience: address},
+	}}
+	for _, tc := range cases {
+		t.Run(tc.name, func(t *testing.T) {
+			b := NewBroker("broker", testNS, WithBrokerAddress("broker.example.com"))
+			if tc.auth != "" {
+				b.SetAnnotations(map[string]string{brokerv1.IngressAuthAnnotation: tc.auth})
+			}
+			if got := ingressAuth(context.Background(), b); !proto.Equal(got, tc.want) {
+				t.Errorf("ingressAuth() = %v, want %v", got, tc.want)
+			}
+		})
+	}
+}
+
+func TestGetIngressFilteringEnabled(t *testing.T) {
+	enabled, disabled := true, false
+	tests := []struct {
+		name        string
+		annotations map[string]string
+		spec        *bool
+		want        bool
+	}{{
+		name: "not set",
+		want: false,
+	}, {
+		name:        "annotation",
+		annotations: enableIngressFilteringAnnotation,
+		want:        true,
+	}, {
+		name: "spec",
+		spec: &enabled,
+		want: true,
+	}, {
+		name:        "spec takes precedence over annotation",
+		annotations: enableIngressFilteringAnnotation,
+		spec:        &disabled,
+		want:        false,
+	}}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			bc := NewBrokerCell(brokerCellName, testNS, WithBrokerCellAnnotations(tt.annotations))
+			bc.Spec.IngressFiltering = tt.spec
+			if got := getIngressFilteringEnabled(bc); got != tt.want {
+				t.Errorf("getIngressFilteringEnabled() = %v, want %v", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestClaimCheck(t *testing.T) {
+	cases := []struct {
+		name       string
+		claimCheck string
+		want       *config.ClaimCheck
+	}{{
+		name: "no claim check",
+	}, {
+		name:       "default threshold",
+		claimCheck: `{"bucket":"payloads"}`,
+		want:       &config.ClaimCheck{Bucket: "payloads", ThresholdBytes: brokerv1.DefaultClaimCheckThresholdBytes},
+	}, {
+		name:       "custom threshold",
+		claimCheck: `{"bucket":"payloads","thresholdBytes":1024}`,
+		want:       &config.ClaimCheck{Bucket: "payloads", ThresholdBytes: 1024},
+	}, {
+		name:       "bucket not allowed",
+		claimCheck: `{"bucket":"other"}`,
+	}, {
+		name:       "malformed claim check",
+		claimCheck: `{`,
+	}}
+	bc := NewBrokerCell(brokerCellName, testNS, WithBrokerCellClaimCheckBuckets("payloads"))
+	for _, tc := range cases {
+		t.Run(tc.name, func(t *testing.T) {
+			b := NewBroker("broker", testNS)
+			if tc.claimCheck != "" {
+				b.SetAnnotations(map[string]string{brokerv1.ClaimCheckAnnotation: tc.claimCheck})
+			}
+			if got := claimCheck(context.Background(), bc, b); !proto.Equal(got, tc.want) {
+				t.Errorf("claimCheck() = %v, want %v", got, tc.want)
+			}
+		})
+	}
+}
+
+func TestOrdering(t *testing.T) {
+	cases := []struct {
+		name     string
+		ordering string
+		want     *config.Ordering
+	}{{
+		name: "no ordering",
+	}, {
+		name:     "default key extension",
+		ordering: `{}`,
+		want:     &config.Ordering{KeyExtension: brokerv1.DefaultOrderingKeyExtension},
+	}, {
+		name:     "custom key extension",
+		ordering: `{"keyExtension":"subject"}`,
+		want:     &config.Ordering{KeyExtension: "subject"},
+	}, {
+		name:     "malformed ordering",
+		ordering: `{`,
+	}}
+	for _, tc := range cases {
+		t.Run(tc.name, func(t *testing.T) {
+			b := NewBroker("broker", testNS)
+			if tc.ordering != "" {
+				b.SetAnnotations(map[string]string{brokerv1.OrderingAnnotation: tc.ordering})
+			}
+			if got := ordering(context.Background(), b); !proto.Equal(got, tc.want) {
+				t.Errorf("ordering() = %v, want %v", got, tc.want)
+			}
+		})
+	}
+}
+
+func TestReplay(t *testing.T) {
+	applied := time.Date(2021, 5, 2, 10, 30, 0, 0, time.UTC)
+	cases := []struct {
+		name    string
+		replay  string
+		applied bool
+		want    *config.Replay
+	}{{
+		name: "no replay",
+	}, {
+		name:   "replay not applied yet",
+		replay: `{"retention":"24h"}`,
+	}, {
+		name:    "replay applied",
+		replay:  `{"retention":"24h","time":"2021-05-01T00:00:00Z"}`,
+		applied: true,
+		want: &config.Replay{
+			Queue: &config.Queue{
+				Topic:        "cre-bkr_testnamespace_broker_broker-uid",
+				Subscription: "cre-tgr-replay_testnamespace_trigger_trigger-uid",
+				State:        config.State_READY,
+			},
+			UntilUnixNanos: applied.UnixNano(),
+		},
+	}, {
+		name:    "replay removed",
+		applied: true,
+	}}
+	for _, tc := range cases {
+		t.Run(tc.name, func(t *testing.T) {
+			b := NewBroker("broker", testNS, WithBrokerUID("broker-uid"))
+			tr := NewTrigger("trigger", testNS, "broker", WithTriggerUID("trigger-uid"))
+			if tc.replay != "" {
+				tr.SetAnnotations(map[string]string{brokerv1.ReplayAnnotation: tc.replay})
+			}
+			if tc.applied {
+				tr.Status.SetLastReplay(`{"time":"2021-05-01T00:00:00Z"}`)
+				tr.Status.SetLastReplayTime(applied)
+			}
+			if got := replay(b, tr); !proto.Equal(got, tc.want) {
+				t.Errorf("replay() = %v, want %v", got, tc.want)
+			}
+		})
+	}
+}
@@ -27,16 +27,19 @@ import (
 	"go.uber.org/zap"
 
 	brokerv1 "github.com/google/knative-gcp/pkg/apis/broker/v1"
+	intv1alpha1 "github.com/google/knative-gcp/pkg/apis/intevents/v1alpha1"
 	"github.com/google/knative-gcp/pkg/apis/messaging/v1beta1"
+	"github.com/google/knative-gcp/pkg/broker/config/stream"
 	brokerinformer "github.com/google/knative-gcp/pkg/client/injection/informers/broker/v1/broker"
 	triggerinformer "github.com/google/knative-gcp/pkg/client/injection/informers/broker/v1/trigger"
 	brokercellinformer "github.com/google/knative-gcp/pkg/client/injection/informers/intevents/v1alpha1/brokercell"
 	hpainformer "github.com/google/knative-gcp/pkg/client/injection/kube/informers/autoscaling/v2beta2/horizontalpodautoscaler"
+	pdbinformer "github.com/google/knative-gcp/pkg/client/injection/kube/informers/policy/v1beta1/poddisruptionbudget"
 	v1alpha1brokercell "github.com/google/knative-gcp/pkg/client/injection/reconciler/intevents/v1alpha1/brokercell"
+	brokerlisters "github.com/google/knative-gcp/pkg/client/listers/broker/v1"
 	"github.com/google/knative-gcp/pkg/logging"
 	"github.com/google/knative-gcp/pkg/metrics"
 	"github.com/google/knative-gcp/pkg/reconciler"
-	brokerresources "github.com/google/knative-gcp/pkg/reconciler/broker/resources"
 	"github.com/google/knative-gcp/pkg/reconciler/brokercell/resources"
 	"github.com/google/knative-gcp/pkg/utils/authcheck"
 	customresourceutil "github.com/google/knative-gcp/pkg/utils/customresource"
@@ -56,6 +59,7 @@ import (
 	"knative.dev/pkg/injection"
 	systemnamespacesecretinformer "knative.dev/pkg/injection/clients/namespacedkube/informers/core/v1/secret"
 	pkgreconciler "knative.dev/pkg/reconciler"
+	"knative.dev/pkg/resolver"
 	"knative.dev/pkg/system"
 )
 
@@ -87,6 +91,7 @@ func NewController(
 		brokerLister:         brokerinformer.Get(ctx).Lister(),
 		channelLister:        channelinformer.Get(ctx).Lister(),
 		hpaLister:            hpainformer.Get(ctx).Lister(),
+		pdbLister:            pdbinformer.Get(ctx).Lister(),
 		triggerLister:        triggerinformer.Get(ctx).Lister(),
 		configMapLister:      configmapinformer.Get(ctx).Lister(),
 		secretLister:         systemnamespacesecretinformer.Get(ctx).Lister(),
@@ -102,7 +107,38 @@ func NewController(
 	if err != nil {
 		logger.Fatal("Failed to create BrokerCell reconciler", zap.Error(err))
 	}
+	if r.env.TargetsStreamPort > 0 {
+		targetsStream := stream.NewServer(&stream.TokenReviewAuthenticator{
+			Reviews:        r.KubeClientSet.AuthenticationV1().TokenReviews(),
+			ServiceAccount: r.env.ServiceAccountName,
+		})
+		r.targetsStream = targetsStream
+		r.seedTargetsStream(ctx)
+		certs := stream.NewCertificates(r.KubeClientSet.CoreV1().Secrets(system.Namespace()), system.Namespace(), resources.TargetsStreamServiceName)
+		if err := certs.Refresh(ctx); err != nil {
+			logger.Fatal("Failed to refresh the targets stream certificates", zap.Error(err))
+		}
+		go certs.Run(ctx)
+		if err := targetsStream.Start(ctx, r.env.TargetsStreamPort, certs.TLSConfig()); err != nil {
+			logger.Fatal("Failed to start the targets stream", zap.Error(err))
+		}
+	}
 	impl := v1alpha1brokercell.NewImpl(ctx, r)
+	r.enqueueAfter = impl.EnqueueKeyAfter
+	// Dead letter sinks are resolved for the targets config, so their changes must reconcile
+	// the BrokerCell rather than the Trigger they belong to.
+	// enqueueBrokerCellOf enqueues the BrokerCell serving the Broker or Channel.
+	enqueueBrokerCellOf := func(obj metav1.Object) {
+		impl.EnqueueKey(types.NamespacedName{Namespace: system.Namespace(), Name: intv1alpha1.BrokerCellName(obj)})
+	}
+	// enqueueBrokerCellOfBroker enqueues the BrokerCell serving the Broker with the given key, if
+	// it still exists.
+	enqueueBrokerCellOfBroker := func(key types.NamespacedName) {
+		if b, err := ls.brokerLister.Brokers(key.Namespace).Get(key.Name); err == nil {
+			enqueueBrokerCellOf(b)
+		}
+	}
+	r.uriResolver = resolver.NewURIResolver(ctx, handleDeadLetterSinkChange(ls.triggerLister, r.targetsTracker, enqueueBrokerCellOfBroker))
 
 	var latencyReporter *metrics.BrokerCellLatencyReporter
 	if r.env.InternalMetricsEnabled {
@@ -110,6 +146,10 @@ func NewController(
 		if err != nil {
 			logger.Error("Failed to create latency reporter", zap.Error(err))
 		}
+		r.targetsLatencyReporter, err = metrics.NewTargetsConfigLatencyReporter()
+		if err != nil {
+			logger.Error("Failed to create targets config latency reporter", zap.Error(err))
+		}
 	}
 
 	logger.Info("Setting up event handlers.")
@@ -117,32 +157,33 @@ func NewController(
 	brokerCellInformer.Informer().AddEventHandlerWithResyncPeriod(controller.HandleAll(impl.Enqueue), reconciler.DefaultResyncPeriod)
 	brokerCellLister := brokerCellInformer.Lister()
 
-	// Watch brokers and triggers to invoke configmap update immediately.
-	brokerinformer.Get(ctx).Informer().AddEventHandler(controller.HandleAll(
+	// Watch brokers and triggers to invoke configmap update immediately. Only the changed brokers
+	// are updated in the targets config.
+	brokerinformer.Get(ctx).Informer().AddEventHandler(handleCellTenant(
 		func(obj interface{}) {
-			if b, ok := obj.(*brokerv1.Broker); ok {
-				// TODO(#866) Select the brokercell that's associated with the given broker.
-				impl.EnqueueKey(types.NamespacedName{Namespace: system.Namespace(), Name: brokerresources.DefaultBrokerCellName})
+			if b, ok := unwrapTombstone(obj).(*brokerv1.Broker); ok {
+				r.targetsTracker.markDirty(brokerRef(b.Namespace, b.Name))
+				enqueueBrokerCellOf(b)
 				reportLatency(ctx, b, latencyReporter, "Broker", b.Name, b.Namespace)
 			}
 		},
 	))
 	triggerinformer.Get(ctx).Informer().AddEventHandler(controller.HandleAll(
 		func(obj interface{}) {
-			if t, ok := obj.(*brokerv1.Trigger); ok {
-				// TODO(#866) Select the brokercell that's associated with the given broker.
-				impl.EnqueueKey(types.NamespacedName{Namespace: system.Namespace(), Name: brokerresources.DefaultBrokerCellName})
+			if t, ok := unwrapTombstone(obj).(*brokerv1.Trigger); ok {
+				r.targetsTracker.markDirty(brokerRef(t.Namespace, t.Spec.Broker))
+				enqueueBrokerCellOfBroker(types.NamespacedName{Namespace: t.Namespace, Name: t.Spec.Broker})
 				reportLatency(ctx, t, latencyReporter, "Trigger", t.Name, t.Namespace)
 			}
 		},
 	))
 
 	// Watch GCP Channels and subscriptions on those channels to invoke configmap update immediately.
-	channelinformer.Get(ctx).Informer().AddEventHandler(controller.HandleAll(
+	channelinformer.Get(ctx).Informer().AddEventHandler(handleCellTenant(
 		func(obj interface{}) {
-			if c, ok := obj.(*v1beta1.Channel); ok {
-				// TODO(#866) Select the brokercell that's associated with the given broker.
-				impl.EnqueueKey(types.NamespacedName{Namespace: system.Namespace(), Name: brokerresources.DefaultBrokerCellName})
+			if c, ok := unwrapTombstone(obj).(*v1beta1.Channel); ok {
+				r.targetsTracker.markDirty(channelRef(c.Namespace, c.Name))
+				enqueueBrokerCellOf(c)
 				reportLatency(ctx, c, latencyReporter, "Channel", c.Name, c.Namespace)
 			}
 		},
@@ -153,8 +194,9 @@ func NewController(
 	deploymentinformer.Get(ctx).Informer().AddEventHandler(handleResourceUpdate(impl))
 	// 2. Watch ingress endpoints
 	endpointsinformer.Get(ctx).Informer().AddEventHandler(handleResourceUpdate(impl))
-	// 3. Watch hpa for ingress, fanout and retry deployments
+	// 3. Watch hpa and pdb for ingress, fanout and retry deployments
 	hpainformer.Get(ctx).Informer().AddEventHandler(handleResourceUpdate(impl))
+	pdbinformer.Get(ctx).Informer().AddEventHandler(handleResourceUpdate(impl))
 	// 4. Watch the broker targets configmap.
 	configmapinformer.Get(ctx).Informer().AddEventHandler(handleResourceUpdate(impl))
 
@@ -174,6 +216,39 @@ func NewController(
 	return impl
 }
 
+// handleCellTenant returns an event handler for Brokers or Channels that calls h with the new
+// object, and also with the old one when the update moves it to another BrokerCell, so that it's
+// removed from the targets of the previous BrokerCell.
+func handleCellTenant(h func(obj interface{})) cache.ResourceEventHandler {
+	return cache.ResourceEventHandlerFuncs{
+		AddFunc: h,
+		UpdateFunc: func(oldObj, newObj interface{}) {
+			oldTenant, oldOK := oldObj.(metav1.Object)
+			newTenant, newOK := newObj.(metav1.Object)
+			if oldOK && newOK && intv1alpha1.BrokerCellName(oldTenant) != intv1alpha1.BrokerCellName(newTenant) {
+				h(oldObj)
+			}
+			h(newObj)
+		},
+		DeleteFunc: h,
+	}
+}
+
+// handleDeadLetterSinkChange returns the callback of the resolver of the dead letter sinks. The
+// sinks are tracked with their Trigger as parent, so the Broker of the Trigger is marked dirty and
+// its BrokerCell is enqueued.
+func handleDeadLetterSinkChange(triggerLister brokerlisters.TriggerLister, tracker *targetsTracker, enqueueBrokerCellOfBroker func(types.NamespacedName)) func(types.NamespacedName) {
+	return func(key types.NamespacedName) {
+		t, err := triggerLister.Triggers(key.Namespace).Get(key.Name)
+		if err != nil {
+			// A deleted Trigger is removed from the targets by the Trigger informer.
+			return
+		}
+		tracker.markDirty(brokerRef(t.Namespace, t.Spec.Broker))
+		enqueueBrokerCellOfBroker(types.NamespacedName{Namespace: t.Namespace, Name: t.Spec.Broker})
+	}
+}
+
 // handleResourceUpdate returns an event handler for resources created by brokercell such as the ingress deployment.
 func handleResourceUpdate(impl *controller.Impl) cache.ResourceEventHandler {
 	// Since resources created by brokercell live in the same namespace as the brokercell, we use an
@@ -202,6 +277,14 @@ func reportLatency(ctx context.Context, resourceObj metav1.ObjectMetaAccessor, l
 	}
 }
 
+// unwrapTombstone returns the last known state of a deleted object whose deletion was missed.
+func unwrapTombstone(obj interface{}) interface{} {
+	if tombstone, ok := obj.(cache.DeletedFinalStateUnknown); ok {
+		return tombstone.Obj
+	}
+	return obj
+}
+
 // filterWithNamespace filters object based on a namespace.
 func filterWithNamespace(namespace string) func(obj interface{}) bool {
 	return func(obj interface{}) bool {
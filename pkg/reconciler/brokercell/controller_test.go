@@ -19,9 +19,12 @@ package brokercell
 import (
 	"os"
 	"testing"
+	"time"
 
 	corev1 "k8s.io/api/core/v1"
 	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/runtime"
+	"k8s.io/apimachinery/pkg/types"
 
 	"knative.dev/pkg/configmap"
 	"knative.dev/pkg/logging"
@@ -30,6 +33,7 @@ import (
 	"knative.dev/pkg/system"
 	tracingconfig "knative.dev/pkg/tracing/config"
 
+	_ "knative.dev/pkg/client/injection/ducks/duck/v1/addressable/fake"
 	_ "knative.dev/pkg/client/injection/ducks/duck/v1/conditions/fake"
 	_ "knative.dev/pkg/client/injection/kube/informers/apps/v1/deployment/fake"
 	_ "knative.dev/pkg/client/injection/kube/informers/core/v1/configmap/fake"
@@ -39,12 +43,16 @@ import (
 	_ "knative.dev/pkg/client/injection/kube/informers/core/v1/serviceaccount/fake"
 	_ "knative.dev/pkg/injection/clients/namespacedkube/informers/core/v1/secret/fake"
 
+	"github.com/google/knative-gcp/pkg/broker/config/memory"
+	. "github.com/google/knative-gcp/pkg/reconciler/testing"
+
 	// Fake injection informers
 	_ "github.com/google/knative-gcp/pkg/client/injection/informers/broker/v1/broker/fake"
 	_ "github.com/google/knative-gcp/pkg/client/injection/informers/broker/v1/trigger/fake"
 	_ "github.com/google/knative-gcp/pkg/client/injection/informers/intevents/v1alpha1/brokercell/fake"
 	_ "github.com/google/knative-gcp/pkg/client/injection/informers/messaging/v1beta1/channel/fake"
 	_ "github.com/google/knative-gcp/pkg/client/injection/kube/informers/autoscaling/v2beta2/horizontalpodautoscaler/fake"
+	_ "github.com/google/knative-gcp/pkg/client/injection/kube/informers/policy/v1beta1/poddisruptionbudget/fake"
 )
 
 func TestNew(t *testing.T) {
@@ -81,6 +89,36 @@ func TestNew(t *testing.T) {
 	}
 }
 
+func TestHandleDeadLetterSinkChange(t *testing.T) {
+	bc := types.NamespacedName{Namespace: testNS, Name: brokerCellName}
+	tracker := newTargetsTracker(time.Hour)
+	tracker.begin(bc)
+	tracker.commit(bc, memory.NewEmptyTargets(), true)
+
+	ls := NewListers([]runtime.Object{NewTrigger("trigger", testNS, "broker")})
+	var enqueued []types.NamespacedName
+	handle := handleDeadLetterSinkChange(ls.GetTriggerLister(), tracker, func(key types.NamespacedName) {
+		enqueued = append(enqueued, key)
+	})
+
+	// The dead letter sinks are tracked with their Trigger as parent.
+	handle(types.NamespacedName{Namespace: testNS, Name: "trigger"})
+	wantBroker := types.NamespacedName{Namespace: testNS, Name: "broker"}
+	if len(enqueued) != 1 || enqueued[0] != wantBroker {
+		t.Errorf("Unexpected enqueued Brokers %v, wanted [%v]", enqueued, wantBroker)
+	}
+	if _, dirty := tracker.begin(bc); len(dirty) != 1 || dirty[0] != brokerRef(testNS, "broker") {
+		t.Errorf("Unexpected dirty CellTenants %v, wanted the Broker of the Trigger", dirty)
+	}
+
+	// A deleted Trigger enqueues nothing.
+	enqueued = nil
+	handle(types.NamespacedName{Namespace: testNS, Name: "deleted"})
+	if len(enqueued) != 0 {
+		t.Errorf("Unexpected enqueued Brokers %v for a deleted Trigger", enqueued)
+	}
+}
+
 func setReconcilerEnv() {
 	_ = os.Setenv("BROKER_CELL_INGRESS_IMAGE", "ingress")
 	_ = os.Setenv("BROKER_CELL_FANOUT_IMAGE", "fanout")
@@ -0,0 +1,198 @@
+/*
+Copyright 2021 Google LLC
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package brokercell
+
+import (
+	"context"
+	"fmt"
+	"io/ioutil"
+	"net"
+	"net/http"
+	"strconv"
+	"strings"
+	"time"
+
+	"go.uber.org/zap"
+	corev1 "k8s.io/api/core/v1"
+	apierrs "k8s.io/apimachinery/pkg/api/errors"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/labels"
+	"k8s.io/apimachinery/pkg/types"
+
+	brokerv1 "github.com/google/knative-gcp/pkg/apis/broker/v1"
+	intv1alpha1 "github.com/google/knative-gcp/pkg/apis/intevents/v1alpha1"
+	"github.com/google/knative-gcp/pkg/broker/config"
+	"github.com/google/knative-gcp/pkg/logging"
+	"github.com/google/knative-gcp/pkg/reconciler/brokercell/resources"
+)
+
+const (
+	// dataPlanePollPeriod is the period at which the generation of the targets loaded by the
+	// data plane is polled, while some Brokers or Triggers are not loaded yet.
+	dataPlanePollPeriod = 5 * time.Second
+
+	// dataPlaneScrapeTimeout is the timeout of the requests to the data plane pods.
+	dataPlaneScrapeTimeout = 2 * time.Second
+)
+
+// reconcileDataPlaneGeneration annotates the Brokers and Triggers of the BrokerCell that all the
+// data plane pods have loaded with their generation, so that their reconcilers mark them as
+// DataPlaneReady. It polls the data plane again later while some are not loaded yet.
+func (r *Reconciler) reconcileDataPlaneGeneration(ctx context.Context, bc *intv1alpha1.BrokerCell) {
+	bcKey := types.NamespacedName{Namespace: bc.Namespace, Name: bc.Name}
+	objects := r.targetsTracker.unacknowledged(bcKey)
+	if len(objects) == 0 {
+		return
+	}
+	observed, err := r.dataPlaneGeneration(ctx, bc)
+	if err != nil {
+		logging.FromContext(ctx).Debug("Failed to get the targets generation loaded by the data plane", zap.Error(err))
+	}
+	pending := false
+	for ref, og := range objects {
+		if og.targetsGeneration > observed {
+			pending = true
+			continue
+		}
+		if err := r.annotateDataPlaneGeneration(ctx, ref, og.generation); err != nil {
+			logging.FromContext(ctx).Error("Failed to annotate the data plane generation", zap.String("kind", ref.kind), zap.Stringer("name", ref.NamespacedName), zap.Error(err))
+			pending = true
+			continue
+		}
+		r.targetsTracker.acknowledge(bcKey, ref, og.generation)
+	}
+	if pending && r.enqueueAfter != nil {
+		r.enqueueAfter(bcKey, dataPlanePollPeriod)
+	}
+}
+
+// dataPlaneGeneration returns the lowest generation of the targets loaded by the ready data plane
+// pods of the BrokerCell, or zero if there is no ready pod.
+func (r *Reconciler) dataPlaneGeneration(ctx context.Context, bc *intv1alpha1.BrokerCell) (int64, error) {
+	pods, err := r.podLister.Pods(bc.Namespace).List(labels.SelectorFromSet(resources.CommonLabels(bc.Name)))
+	if err != nil {
+		return 0, err
+	}
+	var observed int64
+	scraped := false
+	for _, pod := range pods {
+		if pod.Status.PodIP == "" || !isPodReady(pod) {
+			continue
+		}
+		generation, err := r.scrapeTargetsGeneration(ctx, pod)
+		if err != nil {
+			return 0, fmt.Errorf("error scraping pod %s: %w", pod.Name, err)
+		}
+		if !scraped || generation < observed {
+			observed = generation
+		}
+		scraped = true
+	}
+	return observed, nil
+}
+
+// scrapeTargetsGeneration returns the generation of the targets loaded by the data plane pod.
+func (r *Reconciler) scrapeTargetsGeneration(ctx context.Context, pod *corev1.Pod) (int64, error) {
+	port := targetsGenerationPort(pod)
+	if port == 0 {
+		return 0, fmt.Errorf("no port serving the targets generation")
+	}
+	url := fmt.Sprintf("http://%s%s", net.JoinHostPort(pod.Status.PodIP, strconv.Itoa(int(port))), config.TargetsGenerationPath)
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet, url, nil)
+	if err != nil {
+		return 0, err
+	}
+	resp, err := r.dataPlaneClient.Do(req)
+	if err != nil {
+		return 0, err
+	}
+	defer resp.Body.Close()
+	if resp.StatusCode != http.StatusOK {
+		return 0, fmt.Errorf("unexpected status code %d", resp.StatusCode)
+	}
+	body, err := ioutil.ReadAll(resp.Body)
+	if err != nil {
+		return 0, err
+	}
+	return strconv.ParseInt(strings.TrimSpace(string(body)), 10, 64)
+}
+
+// targetsGenerationPort returns the port serving the targets generation of the pod: the health
+// port of fanout and retry pods, or the http port of ingress pods.
+func targetsGenerationPort(pod *corev1.Pod) int32 {
+	var port int32
+	for _, c := range pod.Spec.Containers {
+		for _, p := range c.Ports {
+			switch p.Name {
+			case "http-health":
+				return p.ContainerPort
+			case "http":
+				port = p.ContainerPort
+			}
+		}
+	}
+	return port
+}
+
+func isPodReady(pod *corev1.Pod) bool {
+	for _, c := range pod.Status.Conditions {
+		if c.Type == corev1.PodReady {
+			return c.Status == corev1.ConditionTrue
+		}
+	}
+	return false
+}
+
+// annotateDataPlaneGeneration annotates the Broker or Trigger with the generation loaded by the
+// data plane, unless it already has a later one or no longer exists.
+func (r *Reconciler) annotateDataPlaneGeneration(ctx context.Context, ref objectRef, generation int64) error {
+	var obj metav1.Object
+	var err error
+	if ref.kind == brokerKind {
+		obj, err = r.brokerLister.Brokers(ref.Namespace).Get(ref.Name)
+	} else {
+		obj, err = r.triggerLister.Triggers(ref.Namespace).Get(ref.Name)
+	}
+	if apierrs.IsNotFound(err) {
+		return nil
+	}
+	if err != nil {
+		return err
+	}
+	if brokerv1.DataPlaneObservedGeneration(obj) >= generation {
+		return nil
+	}
+	annotate := func(o metav1.Object) {
+		annotations := o.GetAnnotations()
+		if annotations == nil {
+			annotations = make(map[string]string)
+		}
+		annotations[brokerv1.DataPlaneObservedGenerationAnnotation] = strconv.FormatInt(generation, 10)
+		o.SetAnnotations(annotations)
+	}
+	switch o := obj.(type) {
+	case *brokerv1.Broker:
+		b := o.DeepCopy()
+		annotate(b)
+		_, err = r.RunClientSet.EventingV1().Brokers(b.Namespace).Update(ctx, b, metav1.UpdateOptions{})
+	case *brokerv1.Trigger:
+		t := o.DeepCopy()
+		annotate(t)
+		_, err = r.RunClientSet.EventingV1().Triggers(t.Namespace).Update(ctx, t, metav1.UpdateOptions{})
+	}
+	return err
+}
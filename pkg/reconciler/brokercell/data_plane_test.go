@@ -0,0 +1,153 @@
+/*
+Copyright 2021 Google LLC
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package brokercell
+
+import (
+	"context"
+	"fmt"
+	"net"
+	"net/http"
+	"net/http/httptest"
+	"strconv"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	corev1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/runtime"
+	"k8s.io/apimachinery/pkg/types"
+	"knative.dev/pkg/client/injection/ducks/duck/v1/addressable"
+	fakekubeclient "knative.dev/pkg/client/injection/kube/client/fake"
+	"knative.dev/pkg/configmap"
+	. "knative.dev/pkg/reconciler/testing"
+	"knative.dev/pkg/resolver"
+
+	brokerv1 "github.com/google/knative-gcp/pkg/apis/broker/v1"
+	"github.com/google/knative-gcp/pkg/broker/config"
+	fakerunclient "github.com/google/knative-gcp/pkg/client/injection/client/fake"
+	"github.com/google/knative-gcp/pkg/reconciler"
+	"github.com/google/knative-gcp/pkg/reconciler/brokercell/resources"
+	. "github.com/google/knative-gcp/pkg/reconciler/testing"
+)
+
+func TestReconcileDataPlaneGeneration(t *testing.T) {
+	setReconcilerEnv()
+	bc := NewBrokerCell(brokerCellName, testNS, WithBrokerCellSetDefaults)
+	broker := NewBroker("broker", testNS,
+		WithBrokerClass(brokerv1.BrokerClass), WithBrokerBrokerCell(brokerCellName),
+		WithBrokerGeneration(2),
+		WithBrokerReady("broker.example.com"),
+	)
+	trigger := NewTrigger("trigger", testNS, "broker",
+		WithTriggerGeneration(3),
+		WithTriggerBrokerReady,
+		WithTriggerDependencyReady,
+		WithTriggerSubscriberResolvedSucceeded,
+		WithTriggerSubscriptionReady,
+		WithTriggerTopicReady,
+		WithTriggerStatusSubscriberURI("http://subscriber.example.com"),
+	)
+
+	// The data plane pod serves the generation of the targets it has loaded.
+	var loaded int64
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, req *http.Request) {
+		if req.URL.Path != config.TargetsGenerationPath {
+			w.WriteHeader(http.StatusNotFound)
+			return
+		}
+		fmt.Fprint(w, atomic.LoadInt64(&loaded))
+	}))
+	defer server.Close()
+	host, port, err := net.SplitHostPort(server.Listener.Addr().String())
+	if err != nil {
+		t.Fatalf("Failed to parse the server address: %v", err)
+	}
+	portNumber, _ := strconv.Atoi(port)
+	pod := &corev1.Pod{
+		ObjectMeta: metav1.ObjectMeta{
+			Name:      "fanout",
+			Namespace: testNS,
+			Labels:    resources.CommonLabels(brokerCellName),
+		},
+		Spec: corev1.PodSpec{Containers: []corev1.Container{{
+			Ports: []corev1.ContainerPort{{Name: "http-health", ContainerPort: int32(portNumber)}},
+		}}},
+		Status: corev1.PodStatus{
+			PodIP:      host,
+			Conditions: []corev1.PodCondition{{Type: corev1.PodReady, Status: corev1.ConditionTrue}},
+		},
+	}
+
+	ctx, _ := SetupFakeContext(t)
+	ctx, _ = fakekubeclient.With(ctx)
+	ctx, runClient := fakerunclient.With(ctx, broker, trigger)
+	r, err := NewReconciler(reconciler.NewBase(ctx, controllerAgentName, configmap.NewStaticWatcher()), listers{})
+	if err != nil {
+		t.Fatalf("Failed to create BrokerCell reconciler: %v", err)
+	}
+	testingListers := NewListers([]runtime.Object{bc, broker, trigger, pod})
+	r.listers = listers{
+		brokerLister:     testingListers.GetBrokerLister(),
+		channelLister:    testingListers.GetChannelLister(),
+		triggerLister:    testingListers.GetTriggerLister(),
+		configMapLister:  testingListers.GetConfigMapLister(),
+		deploymentLister: testingListers.GetDeploymentLister(),
+		podLister:        testingListers.GetPodLister(),
+	}
+	r.cmRec.Lister = r.configMapLister
+	r.uriResolver = resolver.NewURIResolver(addressable.WithDuck(ctx), func(types.NamespacedName) {})
+	var requeued int
+	r.enqueueAfter = func(types.NamespacedName, time.Duration) { requeued++ }
+
+	if err := r.reconcileConfig(ctx, bc); err != nil {
+		t.Fatalf("Failed to reconcile the targets config: %v", err)
+	}
+	observedGenerations := func() (int64, int64) {
+		t.Helper()
+		b, err := runClient.EventingV1().Brokers(testNS).Get(context.Background(), broker.Name, metav1.GetOptions{})
+		if err != nil {
+			t.Fatalf("Failed to get the Broker: %v", err)
+		}
+		tr, err := runClient.EventingV1().Triggers(testNS).Get(context.Background(), trigger.Name, metav1.GetOptions{})
+		if err != nil {
+			t.Fatalf("Failed to get the Trigger: %v", err)
+		}
+		return brokerv1.DataPlaneObservedGeneration(b), brokerv1.DataPlaneObservedGeneration(tr)
+	}
+
+	// The data plane hasn't loaded the targets yet.
+	r.reconcileDataPlaneGeneration(ctx, bc)
+	if b, tr := observedGenerations(); b != 0 || tr != 0 {
+		t.Errorf("Unexpected observed generations broker=%d trigger=%d, wanted none", b, tr)
+	}
+	if requeued != 1 {
+		t.Errorf("Unexpected requeues %d, wanted 1", requeued)
+	}
+
+	atomic.StoreInt64(&loaded, 1)
+	r.reconcileDataPlaneGeneration(ctx, bc)
+	if b, tr := observedGenerations(); b != 2 || tr != 3 {
+		t.Errorf("Unexpected observed generations broker=%d trigger=%d, wanted broker=2 trigger=3", b, tr)
+	}
+	if requeued != 1 {
+		t.Errorf("Unexpected requeues %d, wanted no more", requeued)
+	}
+	if pending := r.targetsTracker.unacknowledged(types.NamespacedName{Namespace: testNS, Name: brokerCellName}); len(pending) != 0 {
+		t.Errorf("Unexpected unacknowledged objects %v", pending)
+	}
+}
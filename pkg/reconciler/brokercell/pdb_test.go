@@ -0,0 +1,94 @@
+/*
+Copyright 2021 Google LLC
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package brokercell
+
+import (
+	"testing"
+
+	"github.com/google/go-cmp/cmp"
+	appsv1 "k8s.io/api/apps/v1"
+	apierrs "k8s.io/apimachinery/pkg/api/errors"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/runtime"
+	"k8s.io/apimachinery/pkg/util/intstr"
+	fakekubeclient "knative.dev/pkg/client/injection/kube/client/fake"
+	"knative.dev/pkg/configmap"
+	. "knative.dev/pkg/reconciler/testing"
+
+	intv1alpha1 "github.com/google/knative-gcp/pkg/apis/intevents/v1alpha1"
+	"github.com/google/knative-gcp/pkg/reconciler"
+	"github.com/google/knative-gcp/pkg/reconciler/brokercell/resources"
+	. "github.com/google/knative-gcp/pkg/reconciler/testing"
+)
+
+func TestReconcilePodDisruptionBudget(t *testing.T) {
+	setReconcilerEnv()
+	bc := NewBrokerCell(brokerCellName, testNS)
+	d := &appsv1.Deployment{ObjectMeta: metav1.ObjectMeta{Namespace: testNS, Name: resources.Name(brokerCellName, resources.FanoutName)}}
+	name := resources.PodDisruptionBudgetName(d)
+
+	ctx, _ := SetupFakeContext(t)
+	ctx, kubeClient := fakekubeclient.With(ctx)
+	r, err := NewReconciler(reconciler.NewBase(ctx, controllerAgentName, configmap.NewStaticWatcher()), listers{})
+	if err != nil {
+		t.Fatalf("Failed to create BrokerCell reconciler: %v", err)
+	}
+	pdbs := kubeClient.PolicyV1beta1().PodDisruptionBudgets(testNS)
+
+	// The PodDisruptionBudget is created.
+	maxUnavailable := intstr.FromInt(1)
+	emptyListers := NewListers(nil)
+	r.pdbLister = emptyListers.GetPodDisruptionBudgetLister()
+	if err := r.reconcilePodDisruptionBudget(ctx, bc, d, resources.FanoutName, &intv1alpha1.PodDisruptionBudgetSpec{MaxUnavailable: &maxUnavailable}); err != nil {
+		t.Fatalf("Failed to reconcile the PodDisruptionBudget: %v", err)
+	}
+	created, err := pdbs.Get(ctx, name, metav1.GetOptions{})
+	if err != nil {
+		t.Fatalf("Failed to get the PodDisruptionBudget: %v", err)
+	}
+	if diff := cmp.Diff(resources.Labels(brokerCellName, resources.FanoutName), created.Spec.Selector.MatchLabels); diff != "" {
+		t.Errorf("Unexpected PodDisruptionBudget selector (-want, +got) = %v", diff)
+	}
+	if diff := cmp.Diff(&maxUnavailable, created.Spec.MaxUnavailable); diff != "" {
+		t.Errorf("Unexpected PodDisruptionBudget maxUnavailable (-want, +got) = %v", diff)
+	}
+
+	// The PodDisruptionBudget is updated.
+	minAvailable := intstr.FromString("50%")
+	createdListers := NewListers([]runtime.Object{created})
+	r.pdbLister = createdListers.GetPodDisruptionBudgetLister()
+	if err := r.reconcilePodDisruptionBudget(ctx, bc, d, resources.FanoutName, &intv1alpha1.PodDisruptionBudgetSpec{MinAvailable: &minAvailable}); err != nil {
+		t.Fatalf("Failed to reconcile the PodDisruptionBudget: %v", err)
+	}
+	updated, err := pdbs.Get(ctx, name, metav1.GetOptions{})
+	if err != nil {
+		t.Fatalf("Failed to get the PodDisruptionBudget: %v", err)
+	}
+	if updated.Spec.MaxUnavailable != nil || cmp.Diff(&minAvailable, updated.Spec.MinAvailable) != "" {
+		t.Errorf("Unexpected PodDisruptionBudget spec %v, wanted minAvailable 50%%", updated.Spec)
+	}
+
+	// The PodDisruptionBudget is deleted.
+	updatedListers := NewListers([]runtime.Object{updated})
+	r.pdbLister = updatedListers.GetPodDisruptionBudgetLister()
+	if err := r.reconcilePodDisruptionBudget(ctx, bc, d, resources.FanoutName, nil); err != nil {
+		t.Fatalf("Failed to reconcile the PodDisruptionBudget: %v", err)
+	}
+	if _, err := pdbs.Get(ctx, name, metav1.GetOptions{}); !apierrs.IsNotFound(err) {
+		t.Errorf("Unexpected PodDisruptionBudget, wanted it deleted: %v", err)
+	}
+}
@@ -19,7 +19,9 @@ package resources
 import (
 	"fmt"
 
+	corev1 "k8s.io/api/core/v1"
 	"k8s.io/apimachinery/pkg/labels"
+	"k8s.io/apimachinery/pkg/util/intstr"
 	"knative.dev/pkg/kmeta"
 
 	intv1alpha1 "github.com/google/knative-gcp/pkg/apis/intevents/v1alpha1"
@@ -43,6 +45,19 @@ const (
 	// IngressFilteringEnabledAnnotationKey is the annotation key for enabling ingress filtering.
 	// TODO(#1804): remove this constant when enabling the feature by default.
 	IngressFilteringEnabledAnnotationKey = "events.cloud.google.com/ingressFilteringEnabled"
+
+	// TargetsStreamServiceName is the name of the Service of the controller streaming the targets
+	// config to the data plane.
+	TargetsStreamServiceName = "broker-targets-stream"
+
+	// targetsStreamTokenExpirationSeconds is the lifetime of the service account tokens of the
+	// targets stream. The kubelet rotates them before they expire.
+	targetsStreamTokenExpirationSeconds = 3600
+	targetsStreamVolumeName             = "broker-targets-stream"
+
+	// DeliveryTokensSecretName is the name of the Secret holding the static bearer tokens
+	// attached to the deliveries to subscribers.
+	DeliveryTokensSecretName = "broker-delivery-tokens"
 )
 
 var (
@@ -63,6 +78,17 @@ type Args struct {
 	MemoryLimit        string
 	RolloutRestartTime string
 	AuthType           authcheck.AuthType
+	// TargetsStreamAddress is the address from which the targets config is streamed. The targets
+	// config is only read from the ConfigMap when it is empty.
+	TargetsStreamAddress string
+	// KEDACredentials exposes the key of the broker service account to KEDA, which reads it from
+	// the environment of the containers it scales.
+	KEDACredentials bool
+	// Scheduling controls of the pods.
+	PriorityClassName         string
+	NodeSelector              map[string]string
+	Tolerations               []corev1.Toleration
+	TopologySpreadConstraints []corev1.TopologySpreadConstraint
 }
 
 // IngressArgs are the arguments to create a Broker's ingress Deployment.
@@ -76,11 +102,15 @@ type IngressArgs struct {
 // FanoutArgs are the arguments to create a Broker's fanout Deployment.
 type FanoutArgs struct {
 	Args
+	// EnableSharding splits the Brokers between the replicas of the Deployment.
+	EnableSharding bool
 }
 
 // RetryArgs are the arguments to create a Broker's retry Deployment.
 type RetryArgs struct {
 	Args
+	// EnableSharding splits the Triggers between the replicas of the Deployment.
+	EnableSharding bool
 }
 
 // AutoscalingArgs are the arguments to create HPA for deployments.
@@ -91,6 +121,25 @@ type AutoscalingArgs struct {
 	AvgMemoryUsage    *string
 	MaxReplicas       int32
 	MinReplicas       int32
+	// BacklogAutoscaling scales the deployment on the number of undelivered messages of
+	// BacklogSubscriptions, if set. The HPA selects them by the BacklogQueue label.
+	BacklogAutoscaling   *intv1alpha1.BacklogAutoscalingSpec
+	BacklogSubscriptions []string
+	BacklogQueue         string
+}
+
+// PodDisruptionBudgetArgs are the arguments to create a PodDisruptionBudget for deployments.
+type PodDisruptionBudgetArgs struct {
+	ComponentName  string
+	BrokerCell     *intv1alpha1.BrokerCell
+	MinAvailable   *intstr.IntOrString
+	MaxUnavailable *intstr.IntOrString
+}
+
+// UseKEDA returns whether the deployment is scaled by KEDA instead of an HPA. KEDA only takes
+// over once there are subscriptions to scale on.
+func (args AutoscalingArgs) UseKEDA() bool {
+	return args.BacklogAutoscaling != nil && args.BacklogAutoscaling.Class == intv1alpha1.BacklogAutoscalingKEDA && len(args.BacklogSubscriptions) > 0
 }
 
 // Labels generates the labels present on all resources representing the
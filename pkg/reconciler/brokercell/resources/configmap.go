@@ -17,6 +17,7 @@ limitations under the License.
 package resources
 
 import (
+	"bytes"
 	"fmt"
 
 	"google.golang.org/protobuf/proto"
@@ -31,8 +32,50 @@ import (
 const (
 	targetsCMName = "broker-targets"
 	targetsCMKey  = "targets"
+
+	// targetsShardCMName is the role of the ConfigMaps holding the shards of the targets config.
+	targetsShardCMName = "broker-targets-shard"
+	// targetsManifestCMKey is the key of the manifest of the sharded targets config, which is
+	// held by the targets ConfigMap in place of the targets config.
+	targetsManifestCMKey = "manifest"
 )
 
+// TargetsConfigShards returns the number of ConfigMaps holding the shards of the targets config
+// of the BrokerCell, along with their compression. Zero shards means that the targets config is
+// not sharded and held uncompressed by the targets ConfigMap.
+func TargetsConfigShards(bc *intv1alpha1.BrokerCell) (int, config.TargetsManifest_Compression) {
+	tcs := bc.Spec.TargetsConfig
+	if tcs == nil {
+		return 0, config.TargetsManifest_NONE
+	}
+	shards := 1
+	if tcs.Shards != nil {
+		shards = int(*tcs.Shards)
+	}
+	compression := config.TargetsManifest_NONE
+	if tcs.Compression == intv1alpha1.TargetsConfigCompressionGzip {
+		compression = config.TargetsManifest_GZIP
+	}
+	return shards, compression
+}
+
+// TargetsConfigMapName returns the name of the targets ConfigMap, which holds either the targets
+// config or the manifest of its shards.
+func TargetsConfigMapName(brokerCellName string) string {
+	return Name(brokerCellName, targetsCMName)
+}
+
+// TargetsShardName returns the name of the ConfigMap holding a shard of the targets config.
+func TargetsShardName(brokerCellName string, shard int) string {
+	return Name(brokerCellName, fmt.Sprintf("%s-%d", targetsCMName, shard))
+}
+
+// TargetsShardLabels returns the labels of the ConfigMaps holding the shards of the targets
+// config.
+func TargetsShardLabels(brokerCellName string) map[string]string {
+	return Labels(brokerCellName, targetsShardCMName)
+}
+
 // TargetsConfigMapEqual compares the binary data contained in two TargetsConfig
 // ConfigMaps and returns true if and only if the inputs are valid and the
 // unmarshaled binary data are equal.
@@ -59,6 +102,42 @@ func TargetsConfigMapEqual(cm1, cm2 *corev1.ConfigMap) bool {
 	return proto.Equal(proto1, proto2)
 }
 
+// TargetsManifestConfigMapEqual compares the manifests of the sharded targets config contained in
+// two ConfigMaps.
+func TargetsManifestConfigMapEqual(cm1, cm2 *corev1.ConfigMap) bool {
+	v1, ok := cm1.BinaryData[targetsManifestCMKey]
+	if !ok {
+		return false
+	}
+	v2, ok := cm2.BinaryData[targetsManifestCMKey]
+	if !ok {
+		return false
+	}
+	proto1 := &config.TargetsManifest{}
+	proto2 := &config.TargetsManifest{}
+	if err := proto.Unmarshal(v1, proto1); err != nil {
+		return false
+	}
+	if err := proto.Unmarshal(v2, proto2); err != nil {
+		return false
+	}
+	return proto.Equal(proto1, proto2)
+}
+
+// TargetsShardConfigMapEqual compares the shards of the targets config contained in two
+// ConfigMaps. The shards are serialized deterministically, so they are compared byte to byte.
+func TargetsShardConfigMapEqual(cm1, cm2 *corev1.ConfigMap) bool {
+	v1, ok := cm1.BinaryData[targetsCMKey]
+	if !ok {
+		return false
+	}
+	v2, ok := cm2.BinaryData[targetsCMKey]
+	if !ok {
+		return false
+	}
+	return bytes.Equal(v1, v2)
+}
+
 func MakeTargetsConfig(bc *intv1alpha1.BrokerCell, brokerTargets config.Targets) (*corev1.ConfigMap, error) {
 	data, err := brokerTargets.Bytes()
 	if err != nil {
@@ -66,7 +145,7 @@ func MakeTargetsConfig(bc *intv1alpha1.BrokerCell, brokerTargets config.Targets)
 	}
 	return &corev1.ConfigMap{
 		ObjectMeta: metav1.ObjectMeta{
-			Name:            Name(bc.Name, targetsCMName),
+			Name:            TargetsConfigMapName(bc.Name),
 			Namespace:       bc.Namespace,
 			OwnerReferences: []metav1.OwnerReference{*kmeta.NewControllerRef(bc)},
 			Labels:          Labels(bc.Name, "broker-targets"),
@@ -76,3 +155,70 @@ func MakeTargetsConfig(bc *intv1alpha1.BrokerCell, brokerTargets config.Targets)
 		Data: map[string]string{"debugOnlyTargets.txt": brokerTargets.DebugString()},
 	}, nil
 }
+
+// MakeTargetsConfigShards splits the targets config into the ConfigMaps of its shards, and returns
+// the targets ConfigMap holding their manifest along with them. The shards don't hold the text
+// version of the targets config, as it would take as much space as the targets config itself.
+func MakeTargetsConfigShards(bc *intv1alpha1.BrokerCell, brokerTargets config.Targets) (*corev1.ConfigMap, []*corev1.ConfigMap, error) {
+	shards, compression := TargetsConfigShards(bc)
+	manifest, data, err := config.SplitTargetsConfig(config.NewTargetsConfig(brokerTargets), shards, compression)
+	if err != nil {
+		return nil, nil, fmt.Errorf("error splitting targets config: %w", err)
+	}
+	manifestData, err := proto.Marshal(manifest)
+	if err != nil {
+		return nil, nil, fmt.Errorf("error serializing targets manifest: %w", err)
+	}
+
+	manifestCM := &corev1.ConfigMap{
+		ObjectMeta: metav1.ObjectMeta{
+			Name:            TargetsConfigMapName(bc.Name),
+			Namespace:       bc.Namespace,
+			OwnerReferences: []metav1.OwnerReference{*kmeta.NewControllerRef(bc)},
+			Labels:          Labels(bc.Name, "broker-targets"),
+		},
+		BinaryData: map[string][]byte{targetsManifestCMKey: manifestData},
+	}
+	shardCMs := make([]*corev1.ConfigMap, 0, len(data))
+	for i, b := range data {
+		shardCMs = append(shardCMs, &corev1.ConfigMap{
+			ObjectMeta: metav1.ObjectMeta{
+				Name:            TargetsShardName(bc.Name, i),
+				Namespace:       bc.Namespace,
+				OwnerReferences: []metav1.OwnerReference{*kmeta.NewControllerRef(bc)},
+				Labels:          TargetsShardLabels(bc.Name),
+			},
+			BinaryData: map[string][]byte{targetsCMKey: b},
+		})
+	}
+	return manifestCM, shardCMs, nil
+}
+
+// ParseTargetsConfig returns the targets config held by the targets ConfigMap, either directly or
+// through the shards described by its manifest. getShard returns the ConfigMap of a shard.
+func ParseTargetsConfig(cm *corev1.ConfigMap, getShard func(shard int) (*corev1.ConfigMap, error)) (*config.TargetsConfig, error) {
+	if data, ok := cm.BinaryData[targetsCMKey]; ok {
+		tc := &config.TargetsConfig{}
+		if err := proto.Unmarshal(data, tc); err != nil {
+			return nil, fmt.Errorf("error unmarshalling targets config: %w", err)
+		}
+		return tc, nil
+	}
+	data, ok := cm.BinaryData[targetsManifestCMKey]
+	if !ok {
+		return nil, fmt.Errorf("ConfigMap %s/%s holds no targets config", cm.Namespace, cm.Name)
+	}
+	manifest := &config.TargetsManifest{}
+	if err := proto.Unmarshal(data, manifest); err != nil {
+		return nil, fmt.Errorf("error unmarshalling targets manifest: %w", err)
+	}
+	shards := make([][]byte, 0, len(manifest.Checksums))
+	for i := range manifest.Checksums {
+		shard, err := getShard(i)
+		if err != nil {
+			return nil, err
+		}
+		shards = append(shards, shard.BinaryData[targetsCMKey])
+	}
+	return config.MergeTargetsShards(manifest, shards)
+}
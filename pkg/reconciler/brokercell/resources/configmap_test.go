@@ -21,6 +21,7 @@ import (
 	"testing"
 
 	"github.com/google/go-cmp/cmp"
+	intv1alpha1 "github.com/google/knative-gcp/pkg/apis/intevents/v1alpha1"
 	"github.com/google/knative-gcp/pkg/broker/config"
 	"github.com/google/knative-gcp/pkg/broker/config/memory"
 	. "github.com/google/knative-gcp/pkg/reconciler/testing"
@@ -115,3 +116,93 @@ func TestMakeTargetsConfig(t *testing.T) {
 		t.Errorf("Error making TargetsConfig: %v", err)
 	}
 }
+
+func TestMakeTargetsConfigShards(t *testing.T) {
+	bc := NewBrokerCell("name", "ns", WithBrokerCellTargetsConfig(3, intv1alpha1.TargetsConfigCompressionGzip))
+	targets := memory.NewEmptyTargets()
+	for i := 0; i < 10; i++ {
+		targets.MutateCellTenant(config.TestOnlyBrokerKey("ns", fmt.Sprintf("broker%d", i)), func(m config.CellTenantMutation) {
+			m.SetAddress("http://broker")
+		})
+	}
+	manifestCM, shardCMs, err := MakeTargetsConfigShards(bc, targets)
+	if err != nil {
+		t.Fatalf("Error making TargetsConfig shards: %v", err)
+	}
+	if got, want := manifestCM.Name, "name-brokercell-broker-targets"; got != want {
+		t.Errorf("Unexpected manifest ConfigMap name %q, wanted %q", got, want)
+	}
+	manifest := &config.TargetsManifest{}
+	if err := proto.Unmarshal(manifestCM.BinaryData[targetsManifestCMKey], manifest); err != nil {
+		t.Fatalf("Error unmarshalling the manifest: %v", err)
+	}
+	if manifest.Compression != config.TargetsManifest_GZIP {
+		t.Errorf("Unexpected compression %v, wanted %v", manifest.Compression, config.TargetsManifest_GZIP)
+	}
+	if len(shardCMs) != 3 {
+		t.Fatalf("Unexpected number of shards %d, wanted 3", len(shardCMs))
+	}
+	var shards [][]byte
+	for i, cm := range shardCMs {
+		if got, want := cm.Name, fmt.Sprintf("name-brokercell-broker-targets-%d", i); got != want {
+			t.Errorf("Unexpected shard ConfigMap name %q, wanted %q", got, want)
+		}
+		shards = append(shards, cm.BinaryData[targetsCMKey])
+	}
+	got, err := config.MergeTargetsShards(manifest, shards)
+	if err != nil {
+		t.Fatalf("Error merging the shards: %v", err)
+	}
+	want := &config.TargetsConfig{CellTenants: make(map[string]*config.CellTenant)}
+	targets.RangeCellTenants(func(ct *config.CellTenant) bool {
+		want.CellTenants[ct.Key().PersistenceString()] = ct
+		return true
+	})
+	if !proto.Equal(got, want) {
+		t.Errorf("Unexpected merged targets %v, wanted %v", got, want)
+	}
+}
+
+func TestParseTargetsConfig(t *testing.T) {
+	targets := memory.NewEmptyTargets()
+	for i := 0; i < 10; i++ {
+		targets.MutateCellTenant(config.TestOnlyBrokerKey("ns", fmt.Sprintf("broker%d", i)), func(m config.CellTenantMutation) {
+			m.SetAddress("http://broker")
+		})
+	}
+	targets.SetGeneration(3)
+	want := config.NewTargetsConfig(targets)
+
+	t.Run("targets config", func(t *testing.T) {
+		cm, err := MakeTargetsConfig(NewBrokerCell("name", "ns"), targets)
+		if err != nil {
+			t.Fatalf("Error making TargetsConfig: %v", err)
+		}
+		got, err := ParseTargetsConfig(cm, func(int) (*corev1.ConfigMap, error) {
+			t.Fatal("Unexpected shard of an unsharded targets config")
+			return nil, nil
+		})
+		if err != nil {
+			t.Fatalf("Error parsing the targets config: %v", err)
+		}
+		if !proto.Equal(got, want) {
+			t.Errorf("Unexpected targets %v, wanted %v", got, want)
+		}
+	})
+	t.Run("sharded targets config", func(t *testing.T) {
+		bc := NewBrokerCell("name", "ns", WithBrokerCellTargetsConfig(3, intv1alpha1.TargetsConfigCompressionGzip))
+		manifestCM, shardCMs, err := MakeTargetsConfigShards(bc, targets)
+		if err != nil {
+			t.Fatalf("Error making TargetsConfig shards: %v", err)
+		}
+		got, err := ParseTargetsConfig(manifestCM, func(shard int) (*corev1.ConfigMap, error) {
+			return shardCMs[shard], nil
+		})
+		if err != nil {
+			t.Fatalf("Error parsing the targets config: %v", err)
+		}
+		if !proto.Equal(got, want) {
+			t.Errorf("Unexpected targets %v, wanted %v", got, want)
+		}
+	})
+}
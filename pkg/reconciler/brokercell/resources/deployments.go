@@ -17,9 +17,14 @@ limitations under the License.
 package resources
 
 import (
+	"path/filepath"
 	"strconv"
 
+	intv1alpha1 "github.com/google/knative-gcp/pkg/apis/intevents/v1alpha1"
+	"github.com/google/knative-gcp/pkg/broker/config/stream"
+	"github.com/google/knative-gcp/pkg/broker/config/volume"
 	"github.com/google/knative-gcp/pkg/broker/handler"
+	"github.com/google/knative-gcp/pkg/broker/handler/processors/deliver"
 	resourceutil "github.com/google/knative-gcp/pkg/utils/resource"
 	appsv1 "k8s.io/api/apps/v1"
 	corev1 "k8s.io/api/core/v1"
@@ -103,7 +108,10 @@ func MakeFanoutDeployment(args FanoutArgs) *appsv1.Deployment {
 		SuccessThreshold:    1,
 		TimeoutSeconds:      5,
 	}
-	return deploymentTemplate(args.Args, []corev1.Container{container})
+	if args.EnableSharding {
+		container.Env = append(container.Env, shardGroupEnv(args.Args))
+	}
+	return withDeliveryTokens(deploymentTemplate(args.Args, []corev1.Container{container}))
 }
 
 // MakeRetryDeployment creates the retry Deployment object.
@@ -130,7 +138,19 @@ func MakeRetryDeployment(args RetryArgs) *appsv1.Deployment {
 		SuccessThreshold:    1,
 		TimeoutSeconds:      5,
 	}
-	return deploymentTemplate(args.Args, []corev1.Container{container})
+	if args.EnableSharding {
+		container.Env = append(container.Env, shardGroupEnv(args.Args))
+	}
+	return withDeliveryTokens(deploymentTemplate(args.Args, []corev1.Container{container}))
+}
+
+// shardGroupEnv is the environment variable enabling sharding in the fanout and retry components.
+// The replicas of each component of a BrokerCell form their own group.
+func shardGroupEnv(args Args) corev1.EnvVar {
+	return corev1.EnvVar{
+		Name:  "SHARD_GROUP",
+		Value: Name(args.BrokerCell.Name, args.ComponentName),
+	}
 }
 
 // deploymentTemplate creates a template for data plane deployments.
@@ -141,7 +161,7 @@ func deploymentTemplate(args Args, containers []corev1.Container) *appsv1.Deploy
 	if args.RolloutRestartTime != "" {
 		annotation[RolloutRestartTimeAnnotationKey] = args.RolloutRestartTime
 	}
-	return &appsv1.Deployment{
+	d := &appsv1.Deployment{
 		ObjectMeta: metav1.ObjectMeta{
 			Namespace:       args.BrokerCell.Namespace,
 			Name:            Name(args.BrokerCell.Name, args.ComponentName),
@@ -167,7 +187,7 @@ func deploymentTemplate(args Args, containers []corev1.Container) *appsv1.Deploy
 					Volumes: []corev1.Volume{
 						{
 							Name:         "broker-config",
-							VolumeSource: corev1.VolumeSource{ConfigMap: &corev1.ConfigMapVolumeSource{LocalObjectReference: corev1.LocalObjectReference{Name: Name(args.BrokerCell.Name, targetsCMName)}}},
+							VolumeSource: targetsVolumeSource(args.BrokerCell),
 						},
 						{
 							Name:         "google-broker-key",
@@ -176,15 +196,106 @@ func deploymentTemplate(args Args, containers []corev1.Container) *appsv1.Deploy
 					},
 					Containers:                    containers,
 					TerminationGracePeriodSeconds: ptr.Int64(60),
+					PriorityClassName:             args.PriorityClassName,
+					NodeSelector:                  args.NodeSelector,
+					Tolerations:                   args.Tolerations,
+					TopologySpreadConstraints:     topologySpreadConstraints(args),
 				},
 			},
 		},
 	}
+	if args.TargetsStreamAddress != "" {
+		spec := &d.Spec.Template.Spec
+		spec.Volumes = append(spec.Volumes, targetsStreamVolume())
+	}
+	return d
+}
+
+// topologySpreadConstraints returns the topology spread constraints of the pods. Constraints
+// without a label selector select the pods of the component.
+func topologySpreadConstraints(args Args) []corev1.TopologySpreadConstraint {
+	if len(args.TopologySpreadConstraints) == 0 {
+		return nil
+	}
+	constraints := make([]corev1.TopologySpreadConstraint, 0, len(args.TopologySpreadConstraints))
+	for _, c := range args.TopologySpreadConstraints {
+		if c.LabelSelector == nil {
+			c.LabelSelector = &metav1.LabelSelector{MatchLabels: Labels(args.BrokerCell.Name, args.ComponentName)}
+		}
+		constraints = append(constraints, c)
+	}
+	return constraints
+}
+
+// targetsVolumeSource returns the volume of the targets config. The shards of a sharded targets
+// config are projected in a single volume along with their manifest, so that the kubelet updates
+// them all at once.
+func targetsVolumeSource(bc *intv1alpha1.BrokerCell) corev1.VolumeSource {
+	shards, _ := TargetsConfigShards(bc)
+	if shards == 0 {
+		return corev1.VolumeSource{ConfigMap: &corev1.ConfigMapVolumeSource{LocalObjectReference: corev1.LocalObjectReference{Name: Name(bc.Name, targetsCMName)}}}
+	}
+	sources := []corev1.VolumeProjection{{
+		ConfigMap: &corev1.ConfigMapProjection{
+			LocalObjectReference: corev1.LocalObjectReference{Name: Name(bc.Name, targetsCMName)},
+			Items:                []corev1.KeyToPath{{Key: targetsManifestCMKey, Path: volume.ManifestFile(targetsCMKey)}},
+		},
+	}}
+	for i := 0; i < shards; i++ {
+		sources = append(sources, corev1.VolumeProjection{
+			ConfigMap: &corev1.ConfigMapProjection{
+				LocalObjectReference: corev1.LocalObjectReference{Name: TargetsShardName(bc.Name, i)},
+				Items:                []corev1.KeyToPath{{Key: targetsCMKey, Path: volume.ShardFile(targetsCMKey, i)}},
+			},
+		})
+	}
+	return corev1.VolumeSource{Projected: &corev1.ProjectedVolumeSource{Sources: sources}}
+}
+
+// targetsStreamVolume returns the volume of the credentials of the targets stream: a service
+// account token only valid for the targets stream, and the CA certificates of the controller.
+func targetsStreamVolume() corev1.Volume {
+	return corev1.Volume{
+		Name: targetsStreamVolumeName,
+		VolumeSource: corev1.VolumeSource{Projected: &corev1.ProjectedVolumeSource{
+			Sources: []corev1.VolumeProjection{{
+				ServiceAccountToken: &corev1.ServiceAccountTokenProjection{
+					Audience:          stream.TokenAudience,
+					ExpirationSeconds: ptr.Int64(targetsStreamTokenExpirationSeconds),
+					Path:              filepath.Base(stream.DefaultTokenPath),
+				},
+			}, {
+				Secret: &corev1.SecretProjection{
+					LocalObjectReference: corev1.LocalObjectReference{Name: stream.CertsSecretName},
+					Items:                []corev1.KeyToPath{{Key: stream.CACertKey, Path: filepath.Base(stream.DefaultCAPath)}},
+					Optional:             &optionalSecretVolume,
+				},
+			}},
+		}},
+	}
+}
+
+// withDeliveryTokens mounts the Secret of the delivery tokens in the containers of the
+// deployment. The Secret is optional, so that it is only created when needed.
+func withDeliveryTokens(d *appsv1.Deployment) *appsv1.Deployment {
+	spec := &d.Spec.Template.Spec
+	spec.Volumes = append(spec.Volumes, corev1.Volume{
+		Name:         DeliveryTokensSecretName,
+		VolumeSource: corev1.VolumeSource{Secret: &corev1.SecretVolumeSource{SecretName: DeliveryTokensSecretName, Optional: &optionalSecretVolume}},
+	})
+	for i := range spec.Containers {
+		spec.Containers[i].VolumeMounts = append(spec.Containers[i].VolumeMounts, corev1.VolumeMount{
+			Name:      DeliveryTokensSecretName,
+			MountPath: deliver.DefaultTokensPath,
+			ReadOnly:  true,
+		})
+	}
+	return d
 }
 
 // containerTemplate returns a common template for broker data plane containers.
 func containerTemplate(args Args) corev1.Container {
-	return corev1.Container{
+	container := corev1.Container{
 		Image: args.Image,
 		Name:  args.ComponentName,
 		Env: []corev1.EnvVar{
@@ -238,4 +349,27 @@ func containerTemplate(args Args) corev1.Container {
 			},
 		},
 	}
+	if args.KEDACredentials {
+		container.Env = append(container.Env, corev1.EnvVar{
+			Name: kedaCredentialsEnvKey,
+			ValueFrom: &corev1.EnvVarSource{SecretKeyRef: &corev1.SecretKeySelector{
+				LocalObjectReference: corev1.LocalObjectReference{Name: "google-broker-key"},
+				Key:                  "key.json",
+				Optional:             &optionalSecretVolume,
+			}},
+		})
+	}
+	if args.TargetsStreamAddress != "" {
+		container.Env = append(container.Env,
+			corev1.EnvVar{Name: "TARGETS_STREAM_ADDRESS", Value: args.TargetsStreamAddress},
+			corev1.EnvVar{Name: "BROKER_CELL_NAMESPACE", Value: args.BrokerCell.Namespace},
+			corev1.EnvVar{Name: "BROKER_CELL_NAME", Value: args.BrokerCell.Name},
+		)
+		container.VolumeMounts = append(container.VolumeMounts, corev1.VolumeMount{
+			Name:      targetsStreamVolumeName,
+			MountPath: filepath.Dir(stream.DefaultTokenPath),
+			ReadOnly:  true,
+		})
+	}
+	return container
 }
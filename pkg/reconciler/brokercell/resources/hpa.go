@@ -17,14 +17,61 @@ limitations under the License.
 package resources
 
 import (
+	"strings"
+
 	appsv1 "k8s.io/api/apps/v1"
 	hpav2beta2 "k8s.io/api/autoscaling/v2beta2"
 	corev1 "k8s.io/api/core/v1"
 	"k8s.io/apimachinery/pkg/api/resource"
 	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
 	"knative.dev/pkg/kmeta"
+
+	intv1alpha1 "github.com/google/knative-gcp/pkg/apis/intevents/v1alpha1"
+)
+
+const (
+	// undeliveredMessagesMetric is the external metric of the number of undelivered messages of
+	// Pub/Sub subscriptions, as served by the Stackdriver custom metrics adapter.
+	undeliveredMessagesMetric = "pubsub.googleapis.com|subscription|num_undelivered_messages"
+	// userLabelPrefix prefixes the Pub/Sub labels of the subscriptions in the selectors of the
+	// external metrics.
+	userLabelPrefix = "metadata.user_labels."
+
+	// BacklogBrokerCellLabel is the Pub/Sub label of the subscriptions consumed by the data plane
+	// of a BrokerCell. Its value is the name of the BrokerCell.
+	BacklogBrokerCellLabel = "brokercell"
+	// BacklogQueueLabel is the Pub/Sub label telling which component of the BrokerCell consumes
+	// the subscription, either BacklogQueueDecouple or BacklogQueueRetry.
+	BacklogQueueLabel = "brokercell_queue"
+	// BacklogQueueDecouple labels the decouple subscriptions, consumed by the fanout.
+	BacklogQueueDecouple = "decouple"
+	// BacklogQueueRetry labels the retry subscriptions, consumed by the retry component.
+	BacklogQueueRetry = "retry"
 )
 
+// WithBacklogLabels returns a copy of the Pub/Sub labels of a subscription along with the labels
+// selecting it in the backlog of the BrokerCell's queue.
+func WithBacklogLabels(labels map[string]string, brokerCell, queue string) map[string]string {
+	backlogLabels := make(map[string]string, len(labels)+2)
+	for k, v := range labels {
+		backlogLabels[k] = v
+	}
+	backlogLabels[BacklogBrokerCellLabel] = backlogLabelValue(brokerCell)
+	backlogLabels[BacklogQueueLabel] = queue
+	return backlogLabels
+}
+
+// backlogLabelValue converts the name of a BrokerCell to a Pub/Sub label value, which can't
+// contain dots.
+func backlogLabelValue(brokerCell string) string {
+	return strings.ReplaceAll(brokerCell, ".", "_")
+}
+
+// HorizontalPodAutoscalerName returns the name of the HPA of the deployment.
+func HorizontalPodAutoscalerName(deployment *appsv1.Deployment) string {
+	return deployment.Name + "-hpa"
+}
+
 // MakeHorizontalPodAutoscaler makes an HPA for the given arguments.
 func MakeHorizontalPodAutoscaler(deployment *appsv1.Deployment, args AutoscalingArgs) *hpav2beta2.HorizontalPodAutoscaler {
 	autoscalingMetrics := []hpav2beta2.MetricSpec{}
@@ -56,10 +103,33 @@ func MakeHorizontalPodAutoscaler(deployment *appsv1.Deployment, args Autoscaling
 			autoscalingMetrics = append(autoscalingMetrics, memoryMetric)
 		}
 	}
+	if ba := args.BacklogAutoscaling; ba != nil && ba.Class == intv1alpha1.BacklogAutoscalingExternalMetrics && len(args.BacklogSubscriptions) > 0 {
+		// The HPA sums the backlog of all the subscriptions matching the selector. The selector
+		// matches the Pub/Sub labels of the queue, so that it does not grow with the Triggers.
+		backlogMetric := hpav2beta2.MetricSpec{
+			Type: hpav2beta2.ExternalMetricSourceType,
+			External: &hpav2beta2.ExternalMetricSource{
+				Metric: hpav2beta2.MetricIdentifier{
+					Name: undeliveredMessagesMetric,
+					Selector: &metav1.LabelSelector{
+						MatchLabels: map[string]string{
+							userLabelPrefix + BacklogBrokerCellLabel: backlogLabelValue(args.BrokerCell.Name),
+							userLabelPrefix + BacklogQueueLabel:      args.BacklogQueue,
+						},
+					},
+				},
+				Target: hpav2beta2.MetricTarget{
+					Type:         hpav2beta2.AverageValueMetricType,
+					AverageValue: resource.NewQuantity(*ba.TargetBacklog, resource.DecimalSI),
+				},
+			},
+		}
+		autoscalingMetrics = append(autoscalingMetrics, backlogMetric)
+	}
 
 	return &hpav2beta2.HorizontalPodAutoscaler{
 		ObjectMeta: metav1.ObjectMeta{
-			Name:            deployment.Name + "-hpa",
+			Name:            HorizontalPodAutoscalerName(deployment),
 			Namespace:       deployment.Namespace,
 			OwnerReferences: []metav1.OwnerReference{*kmeta.NewControllerRef(args.BrokerCell)},
 			Labels:          Labels(args.BrokerCell.Name, args.ComponentName),
@@ -0,0 +1,46 @@
+/*
+Copyright 2021 Google LLC
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package resources
+
+import (
+	appsv1 "k8s.io/api/apps/v1"
+	policyv1beta1 "k8s.io/api/policy/v1beta1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"knative.dev/pkg/kmeta"
+)
+
+// PodDisruptionBudgetName returns the name of the PodDisruptionBudget of the deployment.
+func PodDisruptionBudgetName(deployment *appsv1.Deployment) string {
+	return deployment.Name + "-pdb"
+}
+
+// MakePodDisruptionBudget makes a PodDisruptionBudget for the pods of the deployment.
+func MakePodDisruptionBudget(deployment *appsv1.Deployment, args PodDisruptionBudgetArgs) *policyv1beta1.PodDisruptionBudget {
+	return &policyv1beta1.PodDisruptionBudget{
+		ObjectMeta: metav1.ObjectMeta{
+			Name:            PodDisruptionBudgetName(deployment),
+			Namespace:       deployment.Namespace,
+			OwnerReferences: []metav1.OwnerReference{*kmeta.NewControllerRef(args.BrokerCell)},
+			Labels:          Labels(args.BrokerCell.Name, args.ComponentName),
+		},
+		Spec: policyv1beta1.PodDisruptionBudgetSpec{
+			Selector:       &metav1.LabelSelector{MatchLabels: Labels(args.BrokerCell.Name, args.ComponentName)},
+			MinAvailable:   args.MinAvailable,
+			MaxUnavailable: args.MaxUnavailable,
+		},
+	}
+}
@@ -0,0 +1,99 @@
+/*
+Copyright 2021 Google LLC
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package resources
+
+import (
+	"strconv"
+
+	appsv1 "k8s.io/api/apps/v1"
+	"k8s.io/apimachinery/pkg/apis/meta/v1/unstructured"
+
+	kedaresources "github.com/google/knative-gcp/pkg/reconciler/intevents/pullsubscription/keda/resources"
+)
+
+const (
+	// kedaCredentialsEnvKey is the environment variable holding the service account key that
+	// KEDA uses to read the backlog of the subscriptions.
+	kedaCredentialsEnvKey = "GOOGLE_APPLICATION_CREDENTIALS_JSON"
+
+	// kedaHPAPrefix is the prefix of the name of the HPAs created by KEDA for the deployments
+	// it scales.
+	kedaHPAPrefix = "keda-hpa-"
+)
+
+// ScaledObjectName returns the name of the KEDA ScaledObject of the deployment.
+func ScaledObjectName(deployment *appsv1.Deployment) string {
+	return deployment.Name + "-scaledobject"
+}
+
+// KEDAHorizontalPodAutoscalerName returns the name of the HPA created by KEDA for the
+// deployment, which only exists while the deployment has a ScaledObject.
+func KEDAHorizontalPodAutoscalerName(deployment *appsv1.Deployment) string {
+	return kedaHPAPrefix + deployment.Name
+}
+
+// MakeScaledObject makes a KEDA ScaledObject scaling the deployment on the backlog of its
+// subscriptions. The gcp-pubsub scaler of KEDA reads a single subscription, so there is one
+// trigger per subscription, and the deployment is scaled on the largest of their backlogs.
+func MakeScaledObject(deployment *appsv1.Deployment, args AutoscalingArgs) *unstructured.Unstructured {
+	subscriptionSize := strconv.FormatInt(*args.BacklogAutoscaling.TargetBacklog, 10)
+	triggers := make([]interface{}, 0, len(args.BacklogSubscriptions))
+	for _, sub := range args.BacklogSubscriptions {
+		triggers = append(triggers, map[string]interface{}{
+			"type": "gcp-pubsub",
+			"metadata": map[string]interface{}{
+				"subscriptionSize": subscriptionSize,
+				"subscriptionName": sub,
+				"credentials":      kedaCredentialsEnvKey,
+			},
+		})
+	}
+	labels := make(map[string]interface{})
+	for k, v := range Labels(args.BrokerCell.Name, args.ComponentName) {
+		labels[k] = v
+	}
+
+	// Like for PullSubscriptions, the ScaledObject is unstructured to avoid depending on KEDA.
+	return &unstructured.Unstructured{
+		Object: map[string]interface{}{
+			"apiVersion": kedaresources.KedaSchemeGroupVersion.String(),
+			"kind":       kedaresources.ScaledObjectGVK.Kind,
+			"metadata": map[string]interface{}{
+				"namespace": deployment.Namespace,
+				"name":      ScaledObjectName(deployment),
+				"labels":    labels,
+				"ownerReferences": []interface{}{
+					map[string]interface{}{
+						"apiVersion":         args.BrokerCell.GetGroupVersionKind().GroupVersion().String(),
+						"kind":               args.BrokerCell.GetGroupVersionKind().Kind,
+						"blockOwnerDeletion": true,
+						"controller":         true,
+						"name":               args.BrokerCell.Name,
+						"uid":                string(args.BrokerCell.UID),
+					}},
+			},
+			"spec": map[string]interface{}{
+				"scaleTargetRef": map[string]interface{}{
+					"deploymentName": deployment.Name,
+				},
+				"minReplicaCount": int64(args.MinReplicas),
+				"maxReplicaCount": int64(args.MaxReplicas),
+				"triggers":        triggers,
+			},
+		},
+	}
+}
@@ -0,0 +1,233 @@
+/*
+Copyright 2021 Google LLC
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package brokercell
+
+import (
+	"sync"
+	"time"
+
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/types"
+
+	brokerv1 "github.com/google/knative-gcp/pkg/apis/broker/v1"
+	"github.com/google/knative-gcp/pkg/apis/messaging/v1beta1"
+	"github.com/google/knative-gcp/pkg/broker/config"
+)
+
+// tenantRef references the Broker or Channel of a CellTenant.
+type tenantRef struct {
+	tenantType config.CellTenantType
+	types.NamespacedName
+}
+
+func brokerRef(namespace, name string) tenantRef {
+	return tenantRef{tenantType: config.CellTenantType_BROKER, NamespacedName: types.NamespacedName{Namespace: namespace, Name: name}}
+}
+
+func channelRef(namespace, name string) tenantRef {
+	return tenantRef{tenantType: config.CellTenantType_CHANNEL, NamespacedName: types.NamespacedName{Namespace: namespace, Name: name}}
+}
+
+// key returns the key of the CellTenant.
+func (ref tenantRef) key() *config.CellTenantKey {
+	meta := metav1.ObjectMeta{Namespace: ref.Namespace, Name: ref.Name}
+	if ref.tenantType == config.CellTenantType_CHANNEL {
+		return config.KeyFromChannel(&v1beta1.Channel{ObjectMeta: meta})
+	}
+	return config.KeyFromBroker(&brokerv1.Broker{ObjectMeta: meta})
+}
+
+// objectRef references a Broker or a Trigger whose generation loaded by the data plane is tracked.
+type objectRef struct {
+	kind string
+	types.NamespacedName
+}
+
+const (
+	brokerKind  = "Broker"
+	triggerKind = "Trigger"
+)
+
+// objectGeneration is the generation of a Broker or a Trigger in the targets.
+type objectGeneration struct {
+	// generation is the metadata.generation of the object.
+	generation int64
+	// targetsGeneration is the generation of the targets in which the object first appeared
+	// ready at this generation. The data plane has loaded the object once it has loaded them.
+	targetsGeneration int64
+	// acknowledged is true once the object is annotated with the generation.
+	acknowledged bool
+}
+
+// targetsTracker keeps the targets of each BrokerCell between reconciles, along with the
+// CellTenants changed since the last reconcile, so that only those are rebuilt. The targets are
+// rebuilt from scratch on the first reconcile, after a failed reconcile, and once per resync
+// period in case a change was missed.
+//
+// It also keeps the generations of the ready Brokers and Triggers in the targets, until the
+// data plane has loaded them.
+type targetsTracker struct {
+	// resyncPeriod is the period of the full rebuilds. Zero rebuilds the targets on every
+	// reconcile.
+	resyncPeriod time.Duration
+
+	mux   sync.Mutex
+	cells map[types.NamespacedName]*trackedTargets
+}
+
+// trackedTargets are the targets of a BrokerCell and the CellTenants changed since.
+type trackedTargets struct {
+	// targets are nil until the first full rebuild is committed.
+	targets  config.Targets
+	dirty    map[tenantRef]bool
+	lastFull time.Time
+	// committed is the TargetsConfig of the targets as of the last commit.
+	committed *config.TargetsConfig
+	objects   map[objectRef]objectGeneration
+}
+
+func newTargetsTracker(resyncPeriod time.Duration) *targetsTracker {
+	return &targetsTracker{
+		resyncPeriod: resyncPeriod,
+		cells:        make(map[types.NamespacedName]*trackedTargets),
+	}
+}
+
+// markDirty marks the CellTenant as changed in the targets of all the BrokerCells, since a
+// CellTenant moved to another BrokerCell must also be removed from the previous one.
+func (t *targetsTracker) markDirty(ref tenantRef) {
+	t.mux.Lock()
+	defer t.mux.Unlock()
+	for _, c := range t.cells {
+		c.dirty[ref] = true
+	}
+}
+
+// begin starts the reconcile of the targets of the BrokerCell. It returns the targets of the last
+// reconcile along with the CellTenants changed since, or nil targets if they must be rebuilt from
+// scratch. The changes that happen from then on are returned by the next call.
+func (t *targetsTracker) begin(bc types.NamespacedName) (config.Targets, []tenantRef) {
+	t.mux.Lock()
+	defer t.mux.Unlock()
+	c, ok := t.cells[bc]
+	if !ok {
+		c = &trackedTargets{}
+		t.cells[bc] = c
+	}
+	dirty := make([]tenantRef, 0, len(c.dirty))
+	for ref := range c.dirty {
+		dirty = append(dirty, ref)
+	}
+	c.dirty = make(map[tenantRef]bool)
+	if c.targets == nil || time.Since(c.lastFull) >= t.resyncPeriod {
+		return nil, nil
+	}
+	return c.targets, dirty
+}
+
+// commit stores the targets of a successful reconcile. full is true if the targets were rebuilt
+// from scratch.
+func (t *targetsTracker) commit(bc types.NamespacedName, targets config.Targets, full bool) {
+	t.mux.Lock()
+	defer t.mux.Unlock()
+	c, ok := t.cells[bc]
+	if !ok {
+		// The BrokerCell was reset in between, the targets may have missed some changes.
+		return
+	}
+	c.targets = targets
+	if full {
+		c.lastFull = time.Now()
+	}
+	c.committed = config.NewTargetsConfig(targets)
+	c.objects = objectGenerations(c.committed, c.objects)
+}
+
+// committed returns the TargetsConfig of the last reconcile, or nil if there is none.
+func (t *targetsTracker) committed(bc types.NamespacedName) *config.TargetsConfig {
+	t.mux.Lock()
+	defer t.mux.Unlock()
+	if c, ok := t.cells[bc]; ok {
+		return c.committed
+	}
+	return nil
+}
+
+// unacknowledged returns the generations of the Brokers and Triggers of the BrokerCell that are
+// not yet annotated with the generation loaded by the data plane.
+func (t *targetsTracker) unacknowledged(bc types.NamespacedName) map[objectRef]objectGeneration {
+	t.mux.Lock()
+	defer t.mux.Unlock()
+	objects := make(map[objectRef]objectGeneration)
+	if c, ok := t.cells[bc]; ok {
+		for ref, og := range c.objects {
+			if !og.acknowledged {
+				objects[ref] = og
+			}
+		}
+	}
+	return objects
+}
+
+// acknowledge records that the Broker or Trigger is annotated with the given generation.
+func (t *targetsTracker) acknowledge(bc types.NamespacedName, ref objectRef, generation int64) {
+	t.mux.Lock()
+	defer t.mux.Unlock()
+	c, ok := t.cells[bc]
+	if !ok {
+		return
+	}
+	if og, ok := c.objects[ref]; ok && og.generation == generation {
+		og.acknowledged = true
+		c.objects[ref] = og
+	}
+}
+
+// objectGenerations returns the generations of the ready Brokers and Triggers in the
+// TargetsConfig. The objects whose generation is unchanged since the previous ones keep the
+// generation of the targets in which they first appeared ready.
+func objectGenerations(tc *config.TargetsConfig, previous map[objectRef]objectGeneration) map[objectRef]objectGeneration {
+	objects := make(map[objectRef]objectGeneration)
+	track := func(ref objectRef, generation int64, state config.State) {
+		if state != config.State_READY {
+			return
+		}
+		if og, ok := previous[ref]; ok && og.generation == generation {
+			objects[ref] = og
+			return
+		}
+		objects[ref] = objectGeneration{generation: generation, targetsGeneration: tc.Generation}
+	}
+	for _, ct := range tc.CellTenants {
+		if ct.Type != config.CellTenantType_BROKER {
+			continue
+		}
+		track(objectRef{kind: brokerKind, NamespacedName: types.NamespacedName{Namespace: ct.Namespace, Name: ct.Name}}, ct.Generation, ct.State)
+		for _, target := range ct.Targets {
+			track(objectRef{kind: triggerKind, NamespacedName: types.NamespacedName{Namespace: target.Namespace, Name: target.Name}}, target.Generation, target.State)
+		}
+	}
+	return objects
+}
+
+// reset drops the targets of the BrokerCell, so that they are rebuilt from scratch on the next
+// reconcile.
+func (t *targetsTracker) reset(bc types.NamespacedName) {
+	t.mux.Lock()
+	defer t.mux.Unlock()
+	delete(t.cells, bc)
+}
@@ -0,0 +1,141 @@
+/*
+Copyright 2021 Google LLC
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package brokercell
+
+import (
+	"testing"
+	"time"
+
+	"k8s.io/apimachinery/pkg/runtime"
+	"k8s.io/apimachinery/pkg/types"
+	"knative.dev/pkg/client/injection/ducks/duck/v1/addressable"
+	fakekubeclient "knative.dev/pkg/client/injection/kube/client/fake"
+	"knative.dev/pkg/configmap"
+	. "knative.dev/pkg/reconciler/testing"
+	"knative.dev/pkg/resolver"
+
+	brokerv1 "github.com/google/knative-gcp/pkg/apis/broker/v1"
+	"github.com/google/knative-gcp/pkg/broker/config/memory"
+	"github.com/google/knative-gcp/pkg/reconciler"
+	. "github.com/google/knative-gcp/pkg/reconciler/testing"
+)
+
+func TestTargetsTracker(t *testing.T) {
+	bc := types.NamespacedName{Namespace: testNS, Name: brokerCellName}
+	tracker := newTargetsTracker(time.Hour)
+
+	if targets, _ := tracker.begin(bc); targets != nil {
+		t.Fatalf("Unexpected targets on the first reconcile: %v", targets)
+	}
+	// Changes made during the first reconcile are returned by the next one.
+	tracker.markDirty(brokerRef(testNS, "broker"))
+	targets := memory.NewEmptyTargets()
+	tracker.commit(bc, targets, true)
+
+	gotTargets, dirty := tracker.begin(bc)
+	if gotTargets != targets {
+		t.Errorf("Unexpected targets %v, wanted the committed targets", gotTargets)
+	}
+	if len(dirty) != 1 || dirty[0] != brokerRef(testNS, "broker") {
+		t.Errorf("Unexpected dirty CellTenants %v", dirty)
+	}
+	tracker.commit(bc, gotTargets, false)
+
+	if _, dirty := tracker.begin(bc); len(dirty) != 0 {
+		t.Errorf("Unexpected dirty CellTenants %v, wanted none", dirty)
+	}
+	tracker.reset(bc)
+	if targets, _ := tracker.begin(bc); targets != nil {
+		t.Errorf("Unexpected targets after a reset: %v", targets)
+	}
+	tracker.commit(bc, targets, true)
+
+	tracker.resyncPeriod = 0
+	if targets, _ := tracker.begin(bc); targets != nil {
+		t.Errorf("Unexpected targets once the resync period elapsed: %v", targets)
+	}
+}
+
+func TestIncrementalTargetsReconcile(t *testing.T) {
+	setReconcilerEnv()
+	bc := NewBrokerCell(brokerCellName, testNS, WithBrokerCellSetDefaults)
+	ctx, _ := SetupFakeContext(t)
+	ctx, _ = fakekubeclient.With(ctx)
+	setListers := func(r *Reconciler, objects ...runtime.Object) {
+		testingListers := NewListers(append(objects, bc))
+		r.listers = listers{
+			brokerLister:     testingListers.GetBrokerLister(),
+			channelLister:    testingListers.GetChannelLister(),
+			triggerLister:    testingListers.GetTriggerLister(),
+			configMapLister:  testingListers.GetConfigMapLister(),
+			deploymentLister: testingListers.GetDeploymentLister(),
+			podLister:        testingListers.GetPodLister(),
+		}
+		r.cmRec.Lister = r.configMapLister
+	}
+	r, err := NewReconciler(reconciler.NewBase(ctx, controllerAgentName, configmap.NewStaticWatcher()), listers{})
+	if err != nil {
+		t.Fatalf("Failed to create BrokerCell reconciler: %v", err)
+	}
+	r.uriResolver = resolver.NewURIResolver(addressable.WithDuck(ctx), func(types.NamespacedName) {})
+
+	broker1 := NewBroker("broker1", testNS, WithBrokerClass(brokerv1.BrokerClass), WithBrokerBrokerCell(brokerCellName))
+	broker2 := NewBroker("broker2", testNS, WithBrokerClass(brokerv1.BrokerClass), WithBrokerBrokerCell(brokerCellName))
+	channel := NewChannel("channel", testNS, WithChannelSetDefaults, WithChannelBrokerCell(brokerCellName), WithChannelAddress("http://example.com"))
+	reconcileAndCheck := func(want map[tenantRef]bool) {
+		t.Helper()
+		if err := r.reconcileConfig(ctx, bc); err != nil {
+			t.Fatalf("Failed to reconcile the targets config: %v", err)
+		}
+		targets := r.targetsTracker.cells[types.NamespacedName{Namespace: testNS, Name: brokerCellName}].targets
+		for ref, wantFound := range want {
+			if _, found := targets.GetCellTenantByKey(ref.key()); found != wantFound {
+				t.Errorf("Unexpected CellTenant %v found=%v, wanted %v", ref, found, wantFound)
+			}
+		}
+	}
+
+	setListers(r, broker1, channel)
+	reconcileAndCheck(map[tenantRef]bool{
+		brokerRef(testNS, "broker1"):  true,
+		channelRef(testNS, "channel"): true,
+	})
+
+	// Only the dirty Broker is updated, the deletion of the Channel is missed.
+	setListers(r, broker1, broker2)
+	r.targetsTracker.markDirty(brokerRef(testNS, "broker2"))
+	reconcileAndCheck(map[tenantRef]bool{
+		brokerRef(testNS, "broker1"):  true,
+		brokerRef(testNS, "broker2"):  true,
+		channelRef(testNS, "channel"): true,
+	})
+
+	r.targetsTracker.markDirty(channelRef(testNS, "channel"))
+	reconcileAndCheck(map[tenantRef]bool{
+		brokerRef(testNS, "broker1"):  true,
+		brokerRef(testNS, "broker2"):  true,
+		channelRef(testNS, "channel"): false,
+	})
+
+	// The full resync catches up with the missed deletion of the Broker.
+	setListers(r, broker2)
+	r.targetsTracker.resyncPeriod = 0
+	reconcileAndCheck(map[tenantRef]bool{
+		brokerRef(testNS, "broker1"): false,
+		brokerRef(testNS, "broker2"): true,
+	})
+}
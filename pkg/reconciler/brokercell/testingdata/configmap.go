@@ -30,21 +30,42 @@ import (
 	brokerresources "github.com/google/knative-gcp/pkg/reconciler/broker/resources"
 	"github.com/google/knative-gcp/pkg/reconciler/brokercell/resources"
 	corev1 "k8s.io/api/core/v1"
+	eventingduckv1 "knative.dev/eventing/pkg/apis/duck/v1"
 )
 
 func EmptyConfig(t *testing.T, bc *intv1alpha1.BrokerCell) *corev1.ConfigMap {
-	cm, _ := resources.MakeTargetsConfig(bc, memory.NewEmptyTargets())
+	targets := memory.NewEmptyTargets()
+	targets.SetGeneration(1)
+	cm, _ := resources.MakeTargetsConfig(bc, targets)
 	return cm
 }
 
+// EmptyConfigShards returns the targets ConfigMap holding the manifest of an empty sharded
+// targets config, followed by the ConfigMaps of its shards.
+func EmptyConfigShards(t *testing.T, bc *intv1alpha1.BrokerCell) []*corev1.ConfigMap {
+	targets := memory.NewEmptyTargets()
+	targets.SetGeneration(1)
+	manifest, shards, err := resources.MakeTargetsConfigShards(bc, targets)
+	if err != nil {
+		t.Fatalf("Failed to make the targets config shards: %v", err)
+	}
+	return append([]*corev1.ConfigMap{manifest}, shards...)
+}
+
 type BrokerCellObjects struct {
 	BrokersToTriggers map[*brokerv1.Broker][]*brokerv1.Trigger
 	Channels          []*v1beta1.Channel
+	// Generation is the generation of the targets config, 1 if unset.
+	Generation int64
 }
 
 func Config(bc *intv1alpha1.BrokerCell, bco BrokerCellObjects) *corev1.ConfigMap {
 	targets := &config.TargetsConfig{
 		CellTenants: map[string]*config.CellTenant{},
+		Generation:  bco.Generation,
+	}
+	if targets.Generation == 0 {
+		targets.Generation = 1
 	}
 
 	for broker, triggers := range bco.BrokersToTriggers {
@@ -72,11 +93,12 @@ func addBroker(targets *config.TargetsConfig, broker *brokerv1.Broker, triggers
 		brokerQueueState = config.State_READY
 	}
 	brokerConfig := &config.CellTenant{
-		Id:        string(broker.UID),
-		Type:      config.CellTenantType_BROKER,
-		Name:      broker.Name,
-		Namespace: broker.Namespace,
-		Address:   broker.Status.Address.URL.String(),
+		Id:         string(broker.UID),
+		Generation: broker.Generation,
+		Type:       config.CellTenantType_BROKER,
+		Name:       broker.Name,
+		Namespace:  broker.Namespace,
+		Address:    broker.Status.Address.URL.String(),
 		DecoupleQueue: &config.Queue{
 			Topic:        brokerresources.GenerateDecouplingTopicName(broker),
 			Subscription: brokerresources.GenerateDecouplingSubscriptionName(broker),
@@ -92,6 +114,7 @@ func addBroker(targets *config.TargetsConfig, broker *brokerv1.Broker, triggers
 		}
 		brokerConfig.Targets[trigger.Name] = &config.Target{
 			Id:             string(trigger.UID),
+			Generation:     trigger.Generation,
 			Name:           trigger.Name,
 			Namespace:      trigger.Namespace,
 			CellTenantType: config.CellTenantType_BROKER,
@@ -104,6 +127,7 @@ func addBroker(targets *config.TargetsConfig, broker *brokerv1.Broker, triggers
 			},
 			State:            state,
 			FilterAttributes: filterAttributes,
+			DeadLetterPolicy: deadLetterPolicy(trigger.GetDeliverySpec(broker)),
 		}
 	}
 	targets.CellTenants[brokerConfig.Key().PersistenceString()] = brokerConfig
@@ -122,11 +146,12 @@ func addChannel(targets *config.TargetsConfig, channel *v1beta1.Channel) {
 		queueState = config.State_READY
 	}
 	cellTenant := &config.CellTenant{
-		Id:        string(channel.UID),
-		Type:      config.CellTenantType_CHANNEL,
-		Name:      channel.Name,
-		Namespace: channel.Namespace,
-		Address:   channel.Status.Address.URL.String(),
+		Id:         string(channel.UID),
+		Generation: channel.Generation,
+		Type:       config.CellTenantType_CHANNEL,
+		Name:       channel.Name,
+		Namespace:  channel.Namespace,
+		Address:    channel.Status.Address.URL.String(),
 		DecoupleQueue: &config.Queue{
 			Topic:        channelresources.GenerateDecouplingTopicName(channel),
 			Subscription: channelresources.GenerateDecouplingSubscriptionName(channel),
@@ -148,10 +173,27 @@ func addChannel(targets *config.TargetsConfig, channel *v1beta1.Channel) {
 					Topic:        channelresources.GenerateSubscriberRetryTopicName(channel, s.UID),
 					Subscription: channelresources.GenerateSubscriberRetrySubscriptionName(channel, s.UID),
 				},
-				State:        config.State_READY,
-				ReplyAddress: s.ReplyURI.String(),
+				State:            config.State_READY,
+				ReplyAddress:     s.ReplyURI.String(),
+				DeadLetterPolicy: deadLetterPolicy(s.Delivery),
 			}
 		}
 	}
 	targets.CellTenants[cellTenant.Key().PersistenceString()] = cellTenant
 }
+
+// deadLetterPolicy returns the dead letter policy of a delivery spec whose dead letter sink is
+// already resolved to a non Pub/Sub URI.
+func deadLetterPolicy(delivery *eventingduckv1.DeliverySpec) *config.DeadLetterPolicy {
+	if delivery == nil || delivery.DeadLetterSink == nil || delivery.DeadLetterSink.URI == nil || delivery.DeadLetterSink.URI.Scheme == "pubsub" {
+		return nil
+	}
+	dlp := &config.DeadLetterPolicy{
+		Address: delivery.DeadLetterSink.URI.String(),
+		Retry:   5,
+	}
+	if delivery.Retry != nil {
+		dlp.Retry = *delivery.Retry
+	}
+	return dlp
+}
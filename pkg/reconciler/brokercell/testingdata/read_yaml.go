@@ -74,6 +74,38 @@ func RetryDeploymentWithRestartAnnotation(t *testing.T) *appsv1.Deployment {
 	return getDeployment(t, "testingdata/retry_deployment_with_restart_annotation.yaml")
 }
 
+func FanoutDeploymentWithSharding(t *testing.T) *appsv1.Deployment {
+	return getDeployment(t, "testingdata/fanout_deployment_with_sharding.yaml")
+}
+
+func RetryDeploymentWithSharding(t *testing.T) *appsv1.Deployment {
+	return getDeployment(t, "testingdata/retry_deployment_with_sharding.yaml")
+}
+
+func IngressDeploymentWithTargetsStream(t *testing.T) *appsv1.Deployment {
+	return getDeployment(t, "testingdata/ingress_deployment_with_targets_stream.yaml")
+}
+
+func FanoutDeploymentWithTargetsStream(t *testing.T) *appsv1.Deployment {
+	return getDeployment(t, "testingdata/fanout_deployment_with_targets_stream.yaml")
+}
+
+func RetryDeploymentWithTargetsStream(t *testing.T) *appsv1.Deployment {
+	return getDeployment(t, "testingdata/retry_deployment_with_targets_stream.yaml")
+}
+
+func IngressDeploymentWithTargetsShards(t *testing.T) *appsv1.Deployment {
+	return getDeployment(t, "testingdata/ingress_deployment_with_targets_shards.yaml")
+}
+
+func FanoutDeploymentWithTargetsShards(t *testing.T) *appsv1.Deployment {
+	return getDeployment(t, "testingdata/fanout_deployment_with_targets_shards.yaml")
+}
+
+func RetryDeploymentWithTargetsShards(t *testing.T) *appsv1.Deployment {
+	return getDeployment(t, "testingdata/retry_deployment_with_targets_shards.yaml")
+}
+
 func IngressServiceWithStatus(t *testing.T) *corev1.Service {
 	return getService(t, "testingdata/ingress_service_with_status.yaml")
 }
@@ -139,13 +139,23 @@ func (r *Reconciler) reconcileDecouplingTopicAndSubscription(ctx context.Context
 	// Check if PullSub exists, and if not, create it.
 	subID := b.GetSubscriptionName()
 	subConfig := pubsub.SubscriptionConfig{
-		Topic:  topic,
-		Labels: b.GetLabels(),
+		Topic:                 topic,
+		Labels:                brokercellresources.WithBacklogLabels(b.GetLabels(), b.BrokerCellName(), brokercellresources.BacklogQueueDecouple),
+		EnableMessageOrdering: b.MessageOrdering(),
 		//TODO(grantr): configure these settings?
 		// AckDeadline
-		// RetentionDuration
 	}
-	if _, err := pubsubReconciler.ReconcileSubscription(ctx, subID, subConfig, b.Object(), b.StatusUpdater()); err != nil {
+	replay := b.Replay()
+	if replay != nil {
+		// The webhook rejects malformed retentions.
+		subConfig.RetainAckedMessages = true
+		subConfig.RetentionDuration, _ = replay.RetentionDuration()
+	}
+	sub, err := pubsubReconciler.ReconcileSubscription(ctx, subID, subConfig, b.Object(), b.StatusUpdater())
+	if err != nil {
+		return err
+	}
+	if err := reconcileReplay(ctx, pubsubReconciler, sub, b); err != nil {
 		return err
 	}
 
@@ -208,13 +218,13 @@ func (r *Reconciler) getClientOrCreateNew(ctx context.Context, projectID string,
 	return client, nil
 }
 
-// ensureBrokerCellExists creates a BrokerCell if it doesn't exist, and update broker status based on brokercell status.
+// ensureBrokerCellExists creates the BrokerCell selected by the CellTenant if it doesn't exist, and
+// update broker status based on brokercell status.
 func (r *Reconciler) ensureBrokerCellExists(ctx context.Context, s Statusable) error {
 	var bc *inteventsv1alpha1.BrokerCell
 	var err error
-	// TODO(#866) Get brokercell based on the label (or annotation) on the broker.
 	bcNS := system.Namespace()
-	bcName := resources.DefaultBrokerCellName
+	bcName := s.BrokerCellName()
 	bc, err = r.BrokerCellLister.BrokerCells(bcNS).Get(bcName)
 	if err != nil && !apierrs.IsNotFound(err) {
 		logging.FromContext(ctx).Error("Error getting BrokerCell", zap.String("namespace", bcNS), zap.String("brokerCell", bcName), zap.Error(err))
@@ -223,7 +233,7 @@ func (r *Reconciler) ensureBrokerCellExists(ctx context.Context, s Statusable) e
 	}
 
 	if apierrs.IsNotFound(err) {
-		want := resources.CreateBrokerCell(nil)
+		want := resources.CreateBrokerCell(bcName)
 		bc, err = r.RunClientSet.InternalV1alpha1().BrokerCells(want.Namespace).Create(ctx, want, metav1.CreateOptions{})
 		if err != nil && !apierrs.IsAlreadyExists(err) {
 			logging.FromContext(ctx).Error("Error creating brokerCell", zap.String("namespace", want.Namespace), zap.String("brokerCell", want.Name), zap.Error(err))
@@ -21,6 +21,7 @@ import (
 
 	brokerv1 "github.com/google/knative-gcp/pkg/apis/broker/v1"
 	"github.com/google/knative-gcp/pkg/apis/duck"
+	inteventsv1alpha1 "github.com/google/knative-gcp/pkg/apis/intevents/v1alpha1"
 	"github.com/google/knative-gcp/pkg/apis/messaging/v1beta1"
 	"github.com/google/knative-gcp/pkg/broker/config"
 	brokerresources "github.com/google/knative-gcp/pkg/reconciler/broker/resources"
@@ -40,23 +41,43 @@ type Target interface {
 	GetTopicID() string
 	GetSubscriptionName() string
 	GetLabels() map[string]string
+	// BrokerCellName returns the name of the BrokerCell delivering to the Target.
+	BrokerCellName() string
 	DeliverySpec() *eventingduckv1.DeliverySpec
 	SetStatusProjectID(projectID string)
+	// MessageOrdering returns true if the retry subscription delivers the messages with the
+	// same ordering key in order.
+	MessageOrdering() bool
+	// Replay returns the replay requested on the subscription, if any.
+	Replay() *brokerv1.Replay
+	// LastReplay returns the last replay applied to the subscription.
+	LastReplay() string
+	// SetLastReplay records the last replay applied to the subscription.
+	SetLastReplay(applied string)
+	// ReplaySubscription returns the ID of the replay subscription, if it may exist.
+	ReplaySubscription() string
+	// SetReplaySubscription records the ID of the replay subscription before it is created, or an
+	// empty ID once it is deleted.
+	SetReplaySubscription(id string)
 }
 
 var _ Target = (*targetForTrigger)(nil)
 
 type targetForTrigger struct {
-	trigger      *brokerv1.Trigger
-	deliverySpec *eventingduckv1.DeliverySpec
+	trigger         *brokerv1.Trigger
+	brokerCell      string
+	deliverySpec    *eventingduckv1.DeliverySpec
+	messageOrdering bool
 }
 
 // TargetFromTrigger creates a Target for the given Trigger and associated
-// Broker's deliverySpec.
-func TargetFromTrigger(t *brokerv1.Trigger, deliverySpec *eventingduckv1.DeliverySpec) Target {
+// Broker's BrokerCell, deliverySpec and ordering.
+func TargetFromTrigger(t *brokerv1.Trigger, brokerCell string, deliverySpec *eventingduckv1.DeliverySpec, messageOrdering bool) Target {
 	return &targetForTrigger{
-		trigger:      t,
-		deliverySpec: deliverySpec,
+		trigger:         t,
+		brokerCell:      brokerCell,
+		deliverySpec:    deliverySpec,
+		messageOrdering: messageOrdering,
 	}
 }
 
@@ -77,6 +98,10 @@ func (t *targetForTrigger) GetLabels() map[string]string {
 	}
 }
 
+func (t *targetForTrigger) BrokerCellName() string {
+	return t.brokerCell
+}
+
 func (t *targetForTrigger) GetTopicID() string {
 	return brokerresources.GenerateRetryTopicName(t.trigger)
 }
@@ -94,6 +119,32 @@ func (t *targetForTrigger) SetStatusProjectID(_ string) {
 	// t.trigger.Status.ProjectID = projectID
 }
 
+func (t *targetForTrigger) MessageOrdering() bool {
+	return t.messageOrdering
+}
+
+func (t *targetForTrigger) Replay() *brokerv1.Replay {
+	// The webhook rejects malformed replays.
+	r, _ := t.trigger.GetReplay()
+	return r
+}
+
+func (t *targetForTrigger) LastReplay() string {
+	return t.trigger.Status.GetLastReplay()
+}
+
+func (t *targetForTrigger) SetLastReplay(applied string) {
+	t.trigger.Status.SetLastReplay(applied)
+}
+
+func (t *targetForTrigger) ReplaySubscription() string {
+	return t.trigger.Status.GetReplaySubscription()
+}
+
+func (t *targetForTrigger) SetReplaySubscription(id string) {
+	t.trigger.Status.SetReplaySubscription(id)
+}
+
 var _ Target = (*targetForSubscriberSpec)(nil)
 
 type targetForSubscriberSpec struct {
@@ -123,6 +174,10 @@ func (s *targetForSubscriberSpec) GetLabels() map[string]string {
 	return getLabelsForChannel(s.channel, s.subscriberSpec.UID)
 }
 
+func (s *targetForSubscriberSpec) BrokerCellName() string {
+	return inteventsv1alpha1.BrokerCellName(s.channel)
+}
+
 func getLabelsForChannel(channel *v1beta1.Channel, subscriberUID types.UID) map[string]string {
 	labels := map[string]string{
 		"resource":          "subscriptions",
@@ -153,6 +208,30 @@ func (s *targetForSubscriberSpec) SetStatusProjectID(_ string) {
 	// ProjectID is stored on the Channel's status, not each subscriber's, so this is a noop.
 }
 
+func (s *targetForSubscriberSpec) MessageOrdering() bool {
+	return false
+}
+
+func (s *targetForSubscriberSpec) Replay() *brokerv1.Replay {
+	return nil
+}
+
+func (s *targetForSubscriberSpec) LastReplay() string {
+	return ""
+}
+
+func (s *targetForSubscriberSpec) SetLastReplay(_ string) {
+	// Channels don't support replays, so this is a noop.
+}
+
+func (s *targetForSubscriberSpec) ReplaySubscription() string {
+	return ""
+}
+
+func (s *targetForSubscriberSpec) SetReplaySubscription(_ string) {
+	// Channels don't support replays, so this is a noop.
+}
+
 var _ Target = (*targetForSubscriberStatus)(nil)
 
 type targetForSubscriberStatus struct {
@@ -165,6 +244,10 @@ func (s *targetForSubscriberStatus) GetLabels() map[string]string {
 	return getLabelsForChannel(s.channel, s.subscriberStatus.UID)
 }
 
+func (s *targetForSubscriberStatus) BrokerCellName() string {
+	return inteventsv1alpha1.BrokerCellName(s.channel)
+}
+
 func (s *targetForSubscriberStatus) DeliverySpec() *eventingduckv1.DeliverySpec {
 	// SubscriberStatus does not contain the DeliverySpec. This should only be used for deleting a
 	// Target, not creating one, so this shouldn't be needed.
@@ -175,6 +258,30 @@ func (s *targetForSubscriberStatus) SetStatusProjectID(_ string) {
 	// ProjectID is stored on the Channel's status, not each subscriber's, so this is a noop.
 }
 
+func (s *targetForSubscriberStatus) MessageOrdering() bool {
+	return false
+}
+
+func (s *targetForSubscriberStatus) Replay() *brokerv1.Replay {
+	return nil
+}
+
+func (s *targetForSubscriberStatus) LastReplay() string {
+	return ""
+}
+
+func (s *targetForSubscriberStatus) SetLastReplay(_ string) {
+	// Channels don't support replays, so this is a noop.
+}
+
+func (s *targetForSubscriberStatus) ReplaySubscription() string {
+	return ""
+}
+
+func (s *targetForSubscriberStatus) SetReplaySubscription(_ string) {
+	// Channels don't support replays, so this is a noop.
+}
+
 func TargetFromSubscriberStatus(channel *v1beta1.Channel, subscriberStatus eventingduckv1.SubscriberStatus) (Target, *SubscriberStatus) {
 	status := &SubscriberStatus{}
 	return &targetForSubscriberStatus{
@@ -262,6 +369,9 @@ func (s *SubscriberStatus) Message() string {
 // Statusable is the interface used by the Reconciler.
 type Statusable interface {
 	Key() *config.CellTenantKey
+	// BrokerCellName returns the name of the BrokerCell serving the CellTenant, in the system
+	// namespace.
+	BrokerCellName() string
 	MarkBrokerCellReady()
 	MarkBrokerCellUnknown(reason, format string, args ...interface{})
 	MarkBrokerCellFailed(reason, format string, args ...interface{})
@@ -271,6 +381,15 @@ type Statusable interface {
 	GetLabels() map[string]string
 	GetTopicID() string
 	GetSubscriptionName() string
+	// MessageOrdering returns true if the decoupling subscription delivers the messages with
+	// the same ordering key in order.
+	MessageOrdering() bool
+	// Replay returns the replay requested on the subscription, if any.
+	Replay() *brokerv1.Replay
+	// LastReplay returns the last replay applied to the subscription.
+	LastReplay() string
+	// SetLastReplay records the last replay applied to the subscription.
+	SetLastReplay(applied string)
 }
 
 var _ Statusable = (*statusableForBroker)(nil)
@@ -289,6 +408,10 @@ func (b *statusableForBroker) Key() *config.CellTenantKey {
 	return config.KeyFromBroker(b.broker)
 }
 
+func (b *statusableForBroker) BrokerCellName() string {
+	return inteventsv1alpha1.BrokerCellName(b.broker)
+}
+
 func (b *statusableForBroker) MarkBrokerCellReady() {
 	b.broker.Status.MarkBrokerCellReady()
 }
@@ -331,6 +454,26 @@ func (b *statusableForBroker) GetSubscriptionName() string {
 	return brokerresources.GenerateDecouplingSubscriptionName(b.broker)
 }
 
+func (b *statusableForBroker) MessageOrdering() bool {
+	// The webhook rejects malformed orderings.
+	o, _ := b.broker.GetOrdering()
+	return o != nil
+}
+
+func (b *statusableForBroker) Replay() *brokerv1.Replay {
+	// The webhook rejects malformed replays.
+	r, _ := b.broker.GetReplay()
+	return r
+}
+
+func (b *statusableForBroker) LastReplay() string {
+	return b.broker.Status.GetLastReplay()
+}
+
+func (b *statusableForBroker) SetLastReplay(applied string) {
+	b.broker.Status.SetLastReplay(applied)
+}
+
 var _ Statusable = (*statusableForChannel)(nil)
 
 type statusableForChannel struct {
@@ -347,6 +490,10 @@ func (c *statusableForChannel) Key() *config.CellTenantKey {
 	return config.KeyFromChannel(c.ch)
 }
 
+func (c *statusableForChannel) BrokerCellName() string {
+	return inteventsv1alpha1.BrokerCellName(c.ch)
+}
+
 func (c *statusableForChannel) MarkBrokerCellReady() {
 	c.ch.Status.MarkBrokerCellReady()
 }
@@ -387,3 +534,19 @@ func (c *statusableForChannel) GetTopicID() string {
 func (c *statusableForChannel) GetSubscriptionName() string {
 	return channelresources.GenerateDecouplingSubscriptionName(c.ch)
 }
+
+func (c *statusableForChannel) MessageOrdering() bool {
+	return false
+}
+
+func (c *statusableForChannel) Replay() *brokerv1.Replay {
+	return nil
+}
+
+func (c *statusableForChannel) LastReplay() string {
+	return ""
+}
+
+func (c *statusableForChannel) SetLastReplay(_ string) {
+	// Channels don't support replays, so this is a noop.
+}
@@ -0,0 +1,56 @@
+/*
+Copyright 2021 Google LLC
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package celltenant
+
+import (
+	"context"
+	"time"
+
+	"cloud.google.com/go/pubsub"
+	"k8s.io/apimachinery/pkg/runtime"
+
+	brokerv1 "github.com/google/knative-gcp/pkg/apis/broker/v1"
+	reconcilerutilspubsub "github.com/google/knative-gcp/pkg/reconciler/utils/pubsub"
+)
+
+// replayable is implemented by both the Statusables and the Targets, whose subscriptions can be
+// replayed.
+type replayable interface {
+	Object() runtime.Object
+	Replay() *brokerv1.Replay
+	LastReplay() string
+	SetLastReplay(applied string)
+}
+
+// reconcileReplay seeks the subscription to the time or the snapshot of the requested replay,
+// unless it was already applied. Each replay is only applied once, so that the events are not
+// replayed again on every reconciliation.
+func reconcileReplay(ctx context.Context, pubsubReconciler *reconcilerutilspubsub.Reconciler, sub *pubsub.Subscription, r replayable) error {
+	replay := r.Replay()
+	if replay == nil || !replay.Seeks() || replay.Applied() == r.LastReplay() {
+		return nil
+	}
+	var t time.Time
+	if replay.Time != nil {
+		t = replay.Time.Time
+	}
+	if err := pubsubReconciler.SeekSubscription(ctx, sub, t, replay.Snapshot, r.Object()); err != nil {
+		return err
+	}
+	r.SetLastReplay(replay.Applied())
+	return nil
+}
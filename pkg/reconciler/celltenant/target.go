@@ -18,33 +18,23 @@ package celltenant
 
 import (
 	"context"
-	"fmt"
-	"time"
 
 	metadataClient "github.com/google/knative-gcp/pkg/gclient/metadata"
 
 	"k8s.io/client-go/tools/record"
 
-	"github.com/rickb777/date/period"
 	"go.uber.org/multierr"
 	"go.uber.org/zap"
 
 	"github.com/google/knative-gcp/pkg/logging"
-	eventingduckv1 "knative.dev/eventing/pkg/apis/duck/v1"
 
 	"cloud.google.com/go/pubsub"
 	"github.com/google/knative-gcp/pkg/apis/configs/dataresidency"
+	brokercellresources "github.com/google/knative-gcp/pkg/reconciler/brokercell/resources"
 	reconcilerutilspubsub "github.com/google/knative-gcp/pkg/reconciler/utils/pubsub"
 	"github.com/google/knative-gcp/pkg/utils"
 )
 
-const (
-	defaultMinimumBackoff = 1 * time.Second
-	// Default maximum backoff duration used in the backoff retry policy for
-	// pubsub subscriptions. 600 seconds is the longest supported time.
-	defaultMaximumBackoff = 600 * time.Second
-)
-
 // TargetReconciler implements controller.Reconciler for CellTenant Targets.
 type TargetReconciler struct {
 	ProjectID string
@@ -102,21 +92,22 @@ func (r *TargetReconciler) ReconcileRetryTopicAndSubscription(ctx context.Contex
 	//TODO uncomment when eventing webhook allows this
 	//trig.Status.TopicID = topic.ID()
 
-	retryPolicy := getPubsubRetryPolicy(ctx, t.DeliverySpec())
-	deadLetterPolicy := getPubsubDeadLetterPolicy(projectID, t.DeliverySpec())
+	retryPolicy := reconcilerutilspubsub.RetryPolicy(ctx, t.DeliverySpec())
+	deadLetterPolicy := reconcilerutilspubsub.DeadLetterPolicy(projectID, t.DeliverySpec())
 
 	// Check if PullSub exists, and if not, create it.
 	subID := t.GetSubscriptionName()
 	subConfig := pubsub.SubscriptionConfig{
-		Topic:            topic,
-		Labels:           t.GetLabels(),
-		RetryPolicy:      retryPolicy,
-		DeadLetterPolicy: deadLetterPolicy,
+		Topic:                 topic,
+		Labels:                brokercellresources.WithBacklogLabels(t.GetLabels(), t.BrokerCellName(), brokercellresources.BacklogQueueRetry),
+		RetryPolicy:           retryPolicy,
+		DeadLetterPolicy:      deadLetterPolicy,
+		EnableMessageOrdering: t.MessageOrdering(),
 		//TODO(grantr): configure these settings?
 		// AckDeadline
-		// RetentionDuration
 	}
-	if _, err := pubsubReconciler.ReconcileSubscription(ctx, subID, subConfig, t.Object(), t.StatusUpdater()); err != nil {
+	_, err = pubsubReconciler.ReconcileSubscription(ctx, subID, subConfig, t.Object(), t.StatusUpdater())
+	if err != nil {
 		return err
 	}
 	// TODO(grantr): this isn't actually persisted due to webhook issues.
@@ -126,68 +117,58 @@ func (r *TargetReconciler) ReconcileRetryTopicAndSubscription(ctx context.Contex
 	return nil
 }
 
-// getPubsubRetryPolicy gets the eventing retry policy from the Broker delivery
-// spec and translates it to a pubsub retry policy.
-func getPubsubRetryPolicy(ctx context.Context, spec *eventingduckv1.DeliverySpec) *pubsub.RetryPolicy {
-	if spec == nil {
-		return &pubsub.RetryPolicy{
-			MinimumBackoff: defaultMinimumBackoff,
-			MaximumBackoff: defaultMaximumBackoff,
+// ReconcileReplaySubscription reconciles the replay subscription of the Target on the decoupling
+// topic of its CellTenant while the Target requests a replay, and deletes it otherwise. The
+// subscription keeps the acknowledged events for the retention of the replay, and is seeked once
+// to the time or the snapshot of each replay. The data plane delivers the events it replays to
+// the Target only. The subscription is only deleted if the Target recorded that it was created,
+// so that the Targets that never requested a replay don't look it up.
+func (r *TargetReconciler) ReconcileReplaySubscription(ctx context.Context, recorder record.EventRecorder, t Target, topicID, subID string) error {
+	replay := t.Replay()
+	if replay == nil {
+		if t.ReplaySubscription() == "" {
+			return nil
 		}
-	}
-	// The Broker delivery spec is translated to a pubsub retry policy in the
-	// manner defined in the following post:
-	// https://github.com/google/knative-gcp/issues/1392#issuecomment-655617873
-
-	var minimumBackoff time.Duration
-	if spec.BackoffDelay != nil {
-		p, err := period.Parse(*spec.BackoffDelay)
-		if err != nil {
-			// Not actually fatal, we will just use zero, rather than the stored value. But log an
-			// error so that we are aware of the issue.
-			logging.FromContext(ctx).Error("Unable to parse DeliverySpec.BackoffDelay",
-				zap.Error(err), zap.Stringp("backoffDelay", spec.BackoffDelay))
-		} else {
-			minimumBackoff, _ = p.Duration()
+		// The replays applied to a deleted subscription can't be resumed.
+		t.SetLastReplay("")
+		if err := r.DeleteReplaySubscription(ctx, recorder, t, subID); err != nil {
+			return err
 		}
+		t.SetReplaySubscription("")
+		return nil
 	}
 
-	var backoffPolicy eventingduckv1.BackoffPolicyType
-	if spec.BackoffPolicy != nil {
-		backoffPolicy = *spec.BackoffPolicy
-	} else {
-		// Default to Exponential.
-		backoffPolicy = eventingduckv1.BackoffPolicyExponential
-	}
-
-	var maximumBackoff time.Duration
-	switch backoffPolicy {
-	case eventingduckv1.BackoffPolicyLinear:
-		maximumBackoff = minimumBackoff
-	case eventingduckv1.BackoffPolicyExponential:
-		maximumBackoff = defaultMaximumBackoff
-	}
-	return &pubsub.RetryPolicy{
-		MinimumBackoff: minimumBackoff,
-		MaximumBackoff: maximumBackoff,
+	logger := logging.FromContext(ctx)
+	logger.Debug("Reconciling replay subscription")
+	projectID, err := utils.ProjectIDOrDefault(r.ProjectID)
+	if err != nil {
+		logger.Error("Failed to find project id", zap.Error(err))
+		t.StatusUpdater().MarkSubscriptionUnknown("ProjectIdNotFound", "Failed to find project id: %v", err)
+		return err
 	}
-}
 
-// getPubsubDeadLetterPolicy gets the eventing dead letter policy from the
-// Broker delivery spec and translates it to a pubsub dead letter policy.
-func getPubsubDeadLetterPolicy(projectID string, spec *eventingduckv1.DeliverySpec) *pubsub.DeadLetterPolicy {
-	if spec == nil || spec.DeadLetterSink == nil {
-		return nil
+	client, err := r.getClientOrCreateNew(ctx, projectID, t.StatusUpdater())
+	if err != nil {
+		logger.Error("Failed to create Pub/Sub client", zap.Error(err))
+		return err
 	}
-	// Translate to the pubsub dead letter policy format.
+	pubsubReconciler := reconcilerutilspubsub.NewReconciler(client, recorder)
 
-	dlp := &pubsub.DeadLetterPolicy{
-		DeadLetterTopic: fmt.Sprintf("projects/%s/topics/%s", projectID, spec.DeadLetterSink.URI.Host),
-	}
-	if spec.Retry != nil {
-		dlp.MaxDeliveryAttempts = int(*spec.Retry)
+	subConfig := pubsub.SubscriptionConfig{
+		Topic:               client.Topic(topicID),
+		Labels:              t.GetLabels(),
+		RetainAckedMessages: true,
+	}
+	// The webhook rejects malformed retentions.
+	subConfig.RetentionDuration, _ = replay.RetentionDuration()
+	// The subscription is recorded before it is created, so that it is deleted even if the
+	// status isn't updated once it is.
+	t.SetReplaySubscription(subID)
+	sub, err := pubsubReconciler.ReconcileSubscription(ctx, subID, subConfig, t.Object(), t.StatusUpdater())
+	if err != nil {
+		return err
 	}
-	return dlp
+	return reconcileReplay(ctx, pubsubReconciler, sub, t)
 }
 
 func (r *TargetReconciler) DeleteRetryTopicAndSubscription(ctx context.Context, recorder record.EventRecorder, t Target) error {
@@ -221,6 +202,26 @@ func (r *TargetReconciler) DeleteRetryTopicAndSubscription(ctx context.Context,
 	return err
 }
 
+// DeleteReplaySubscription deletes the replay subscription of the Target, if it exists.
+func (r *TargetReconciler) DeleteReplaySubscription(ctx context.Context, recorder record.EventRecorder, t Target, subID string) error {
+	logger := logging.FromContext(ctx)
+	logger.Debug("Deleting replay subscription")
+	projectID, err := utils.ProjectIDOrDefault(r.ProjectID)
+	if err != nil {
+		logger.Error("Failed to find project id", zap.Error(err))
+		t.StatusUpdater().MarkSubscriptionUnknown("FinalizeSubscriptionProjectIdNotFound", "Failed to find project id: %v", err)
+		return err
+	}
+
+	client, err := r.getClientOrCreateNew(ctx, projectID, t.StatusUpdater())
+	if err != nil {
+		logger.Error("Failed to create Pub/Sub client", zap.Error(err))
+		return err
+	}
+	pubsubReconciler := reconcilerutilspubsub.NewReconciler(client, recorder)
+	return pubsubReconciler.DeleteSubscription(ctx, subID, t.Object(), t.StatusUpdater())
+}
+
 // getClientOrCreateNew Return the pubsubCient if it is valid, otherwise it tries to create a new client
 // and register it for later usage.
 func (r *TargetReconciler) getClientOrCreateNew(ctx context.Context, projectID string, b reconcilerutilspubsub.StatusUpdater) (*pubsub.Client, error) {
@@ -0,0 +1,217 @@
+/*
+Copyright 2021 Google LLC
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package pullsubscription
+
+import (
+	"context"
+	"fmt"
+
+	"go.uber.org/zap"
+	appsv1 "k8s.io/api/apps/v1"
+	corev1 "k8s.io/api/core/v1"
+	"k8s.io/apimachinery/pkg/api/equality"
+	apierrors "k8s.io/apimachinery/pkg/api/errors"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"knative.dev/pkg/logging"
+	"knative.dev/pkg/system"
+
+	v1 "github.com/google/knative-gcp/pkg/apis/intevents/v1"
+	"github.com/google/knative-gcp/pkg/pubsub/adapter/pool"
+	"github.com/google/knative-gcp/pkg/reconciler/intevents/pullsubscription/resources"
+)
+
+const (
+	reconciledAdapterPoolFailedReason = "AdapterPoolReconcileFailed"
+	adapterPoolNotAllowedReason       = "AdapterPoolNotAllowed"
+)
+
+// reconcileAdapterPool adds the PullSubscription to the receive adapter pool config, makes sure
+// that the pool is deployed, and deletes the receive adapter the PullSubscription may have had
+// before. The PullSubscription is removed from the pool instead if its namespace isn't allowed to
+// use it, since the pool pulls the subscriptions with its own credentials.
+func (r *Base) reconcileAdapterPool(ctx context.Context, ps *v1.PullSubscription) error {
+	allowList, err := r.adapterPoolAllowList()
+	if err != nil {
+		logging.FromContext(ctx).Desugar().Error("Error reading the adapter pool allow list", zap.Error(err))
+		ps.Status.MarkDeployedFailed(reconciledAdapterPoolFailedReason, "Error reading the adapter pool allow list: %s", err.Error())
+		return err
+	}
+	if !resources.AdapterPoolAllowed(allowList, ps.Namespace) {
+		if err := r.updateAdapterPoolEntry(ctx, ps, nil, nil); err != nil {
+			logging.FromContext(ctx).Desugar().Error("Error updating the adapter pool config", zap.Error(err))
+		}
+		ps.Status.MarkDeployedFailed(adapterPoolNotAllowedReason, "Namespace %q is not allowed to use the adapter pool", ps.Namespace)
+		return fmt.Errorf("namespace %q is not in the %q allow list of the adapter pool", ps.Namespace, pool.AllowListName)
+	}
+	settings, err := resources.AdapterPoolReceiveSettings(allowList)
+	if err != nil {
+		logging.FromContext(ctx).Desugar().Error("Invalid adapter pool receive settings", zap.Error(err))
+		ps.Status.MarkDeployedFailed(reconciledAdapterPoolFailedReason, "Invalid adapter pool receive settings: %s", err.Error())
+		return err
+	}
+
+	entry := resources.MakeAdapterPoolEntry(&resources.ReceiveAdapterArgs{
+		PullSubscription:  ps,
+		SubscriptionID:    ps.Status.SubscriptionID,
+		SinkURI:           ps.Status.SinkURI,
+		TransformerURI:    ps.Status.TransformerURI,
+		DeadLetterSinkURI: ps.Status.DeadLetterSinkURI,
+		DeadLetterRetry:   deadLetterRetry(ps),
+	})
+	if err := r.updateAdapterPoolEntry(ctx, ps, entry, settings); err != nil {
+		logging.FromContext(ctx).Desugar().Error("Error updating the adapter pool config", zap.Error(err))
+		ps.Status.MarkDeployedFailed(reconciledAdapterPoolFailedReason, "Error updating the adapter pool config: %s", err.Error())
+		return err
+	}
+
+	// The pool is shared by Sources and Channels, so its metrics are reported as the ones of a source.
+	loggingConfig, metricsConfig, tracingConfig := r.observabilityConfigs(ctx, sourceComponent)
+	d, err := r.reconcileAdapterPoolDeployment(ctx, resources.MakeAdapterPool(&resources.AdapterPoolArgs{
+		Image:         r.ReceiveAdapterImage,
+		Namespace:     system.Namespace(),
+		MetricsConfig: metricsConfig,
+		LoggingConfig: loggingConfig,
+		TracingConfig: tracingConfig,
+	}))
+	if err != nil {
+		logging.FromContext(ctx).Desugar().Error("Error reconciling the adapter pool", zap.Error(err))
+		ps.Status.MarkDeployedFailed(reconciledAdapterPoolFailedReason, "Error reconciling the adapter pool: %s", err.Error())
+		return err
+	}
+	if err := r.reconcileAdapterPoolHPA(ctx, d); err != nil {
+		logging.FromContext(ctx).Desugar().Error("Error reconciling the adapter pool HPA", zap.Error(err))
+		ps.Status.MarkDeployedFailed(reconciledAdapterPoolFailedReason, "Error reconciling the adapter pool HPA: %s", err.Error())
+		return err
+	}
+	if err := r.deleteReceiveAdapter(ctx, ps); err != nil {
+		logging.FromContext(ctx).Desugar().Error("Error deleting the Receive Adapter", zap.Error(err))
+		ps.Status.MarkDeployedFailed("ReceiveAdapterDeleteFailed", "Error deleting the Receive Adapter: %s", err.Error())
+		return err
+	}
+	ps.Status.PropagateDeploymentAvailability(d)
+	return nil
+}
+
+// adapterPoolAllowList returns the allow list of the adapter pool, or nil if it doesn't exist.
+func (r *Base) adapterPoolAllowList() (*corev1.ConfigMap, error) {
+	allowList, err := r.ConfigMapLister.ConfigMaps(system.Namespace()).Get(pool.AllowListName)
+	if apierrors.IsNotFound(err) {
+		return nil, nil
+	}
+	return allowList, err
+}
+
+// updateAdapterPoolEntry sets the entry of the PullSubscription in the adapter pool config, or
+// removes it if entry is nil. The receive settings of the pool are set along with an entry, and
+// kept when one is removed. The config is updated with the resource version it was read at, so
+// that concurrent updates of other entries conflict and are retried rather than lost.
+func (r *Base) updateAdapterPoolEntry(ctx context.Context, ps *v1.PullSubscription, entry *pool.Entry, settings *pool.ReceiveSettings) error {
+	namespace := system.Namespace()
+	key := resources.AdapterPoolKey(ps)
+	existing, err := r.ConfigMapLister.ConfigMaps(namespace).Get(pool.Name)
+	if apierrors.IsNotFound(err) {
+		if entry == nil {
+			return nil
+		}
+		cm, err := resources.MakeAdapterPoolConfigMap(namespace, &pool.Config{Entries: map[string]*pool.Entry{key: entry}, ReceiveSettings: settings})
+		if err != nil {
+			return err
+		}
+		_, err = r.KubeClientSet.CoreV1().ConfigMaps(namespace).Create(ctx, cm, metav1.CreateOptions{})
+		return err
+	}
+	if err != nil {
+		return err
+	}
+
+	cfg, err := pool.ParseConfig([]byte(existing.Data[pool.ConfigKey]))
+	if err != nil {
+		return err
+	}
+	if entry == nil {
+		if _, ok := cfg.Entries[key]; !ok {
+			return nil
+		}
+		delete(cfg.Entries, key)
+	} else {
+		if cfg.Entries == nil {
+			cfg.Entries = make(map[string]*pool.Entry, 1)
+		}
+		cfg.Entries[key] = entry
+		cfg.ReceiveSettings = settings
+	}
+	desired, err := resources.MakeAdapterPoolConfigMap(namespace, cfg)
+	if err != nil {
+		return err
+	}
+	if equality.Semantic.DeepEqual(desired.Data, existing.Data) {
+		return nil
+	}
+	// Don't modify the informers copy.
+	cm := existing.DeepCopy()
+	cm.Data = desired.Data
+	_, err = r.KubeClientSet.CoreV1().ConfigMaps(namespace).Update(ctx, cm, metav1.UpdateOptions{})
+	return err
+}
+
+func (r *Base) reconcileAdapterPoolDeployment(ctx context.Context, desired *appsv1.Deployment) (*appsv1.Deployment, error) {
+	existing, err := r.DeploymentLister.Deployments(desired.Namespace).Get(desired.Name)
+	if apierrors.IsNotFound(err) {
+		return r.KubeClientSet.AppsV1().Deployments(desired.Namespace).Create(ctx, desired, metav1.CreateOptions{})
+	}
+	if err != nil {
+		return nil, err
+	}
+	// The replicas are left to the HPA.
+	if equality.Semantic.DeepDerivative(desired.Spec.Template, existing.Spec.Template) {
+		return existing, nil
+	}
+	// Don't modify the informers copy.
+	d := existing.DeepCopy()
+	d.Spec.Template = desired.Spec.Template
+	return r.KubeClientSet.AppsV1().Deployments(d.Namespace).Update(ctx, d, metav1.UpdateOptions{})
+}
+
+// reconcileAdapterPoolHPA creates the HPA of the adapter pool if it doesn't exist. It is never
+// updated, so that its bounds can be tuned in the cluster.
+func (r *Base) reconcileAdapterPoolHPA(ctx context.Context, d *appsv1.Deployment) error {
+	desired := resources.MakeAdapterPoolHPA(d)
+	_, err := r.KubeClientSet.AutoscalingV2beta2().HorizontalPodAutoscalers(desired.Namespace).Get(ctx, desired.Name, metav1.GetOptions{})
+	if apierrors.IsNotFound(err) {
+		_, err = r.KubeClientSet.AutoscalingV2beta2().HorizontalPodAutoscalers(desired.Namespace).Create(ctx, desired, metav1.CreateOptions{})
+	}
+	return err
+}
+
+// deleteReceiveAdapter deletes the receive adapter of the PullSubscription, if it has one.
+func (r *Base) deleteReceiveAdapter(ctx context.Context, ps *v1.PullSubscription) error {
+	existing, err := r.DeploymentLister.Deployments(ps.Namespace).Get(resources.GenerateReceiveAdapterName(ps))
+	if apierrors.IsNotFound(err) {
+		return nil
+	}
+	if err != nil {
+		return err
+	}
+	if !metav1.IsControlledBy(existing, ps) {
+		return fmt.Errorf("deployment %q is not owned by PullSubscription %q", existing.Name, ps.Name)
+	}
+	err = r.KubeClientSet.AppsV1().Deployments(ps.Namespace).Delete(ctx, existing.Name, metav1.DeleteOptions{})
+	if apierrors.IsNotFound(err) {
+		return nil
+	}
+	return err
+}
@@ -1225,6 +1225,7 @@ func TestAllCases(t *testing.T) {
 				Base:                   reconciler.NewBase(ctx, controllerAgentName, cmw),
 				DeploymentLister:       listers.GetDeploymentLister(),
 				PullSubscriptionLister: listers.GetPullSubscriptionLister(),
+				ConfigMapLister:        listers.GetConfigMapLister(),
 				UriResolver:            resolver.NewURIResolver(ctx, func(types.NamespacedName) {}),
 				ReceiveAdapterImage:    testImage,
 				CreateClientFn:         createClientFn,
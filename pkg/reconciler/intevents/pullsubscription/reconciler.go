@@ -26,6 +26,7 @@ import (
 
 	appsv1 "k8s.io/api/apps/v1"
 	corev1 "k8s.io/api/core/v1"
+	"k8s.io/apimachinery/pkg/api/equality"
 	apierrors "k8s.io/apimachinery/pkg/api/errors"
 	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
 	appsv1listers "k8s.io/client-go/listers/apps/v1"
@@ -58,6 +59,7 @@ const (
 
 	deletePubSubFailedReason        = "SubscriptionDeleteFailed"
 	deleteWorkloadIdentityFailed    = "WorkloadIdentityDeleteFailed"
+	deleteAdapterPoolEntryFailed    = "AdapterPoolEntryDeleteFailed"
 	reconciledPubSubFailedReason    = "SubscriptionReconcileFailed"
 	reconciledDataPlaneFailedReason = "DataPlaneReconcileFailed"
 	reconciledSuccessReason         = "PullSubscriptionReconciled"
@@ -66,6 +68,11 @@ const (
 	// If the topic of the subscription has been deleted, the value of its topic becomes "_deleted-topic_".
 	// See https://cloud.google.com/pubsub/docs/reference/rpc/google.pubsub.v1#subscription
 	deletedTopic = "_deleted-topic_"
+
+	// The defaults of Pub/Sub for the minimum backoff of a retry policy and the delivery attempts
+	// of a dead letter policy.
+	defaultMinimumBackoff      = 10 * time.Second
+	defaultMaxDeliveryAttempts = 5
 )
 
 // Base implements the core controller logic for pullsubscription.
@@ -79,6 +86,8 @@ type Base struct {
 	PullSubscriptionLister listers.PullSubscriptionLister
 	// serviceAccountLister for reading serviceAccounts.
 	ServiceAccountLister corev1listers.ServiceAccountLister
+	// ConfigMapLister for reading the receive adapter pool config.
+	ConfigMapLister corev1listers.ConfigMapLister
 
 	UriResolver *resolver.URIResolver
 
@@ -138,6 +147,18 @@ func (r *Base) ReconcileKind(ctx context.Context, ps *v1.PullSubscription) pkgre
 		ps.Status.TransformerURI = nil
 	}
 
+	// Dead letter sink is optional. A Pub/Sub dead letter topic is set on the subscription instead.
+	if d := ps.Spec.Delivery; d != nil && d.DeadLetterSink != nil && !reconcilerutilspubsub.IsDeadLetterTopic(d.DeadLetterSink) {
+		deadLetterSinkURI, err := r.resolveDestination(ctx, *d.DeadLetterSink, ps)
+		if err != nil {
+			ps.Status.MarkNoSink("InvalidDeadLetterSink", err.Error())
+			return pkgreconciler.NewEvent(corev1.EventTypeWarning, "InvalidDeadLetterSink", "InvalidDeadLetterSink: %s", err.Error())
+		}
+		ps.Status.DeadLetterSinkURI = deadLetterSinkURI
+	} else {
+		ps.Status.DeadLetterSinkURI = nil
+	}
+
 	subscriptionID, err := r.reconcileSubscription(ctx, ps)
 	if err != nil {
 		ps.Status.MarkNoSubscription(reconciledPubSubFailedReason, "Failed to reconcile Pub/Sub subscription: %s", err.Error())
@@ -145,7 +166,15 @@ func (r *Base) ReconcileKind(ctx context.Context, ps *v1.PullSubscription) pkgre
 	}
 	ps.Status.MarkSubscribed(subscriptionID)
 
-	err = r.reconcileDataPlaneResources(ctx, ps, r.ReconcileDataPlaneFn)
+	if resources.UsesAdapterPool(ps) {
+		err = r.reconcileAdapterPool(ctx, ps)
+	} else {
+		err = r.reconcileDataPlaneResources(ctx, ps, r.ReconcileDataPlaneFn)
+		if err == nil {
+			// The PullSubscription may have used the adapter pool before.
+			err = r.updateAdapterPoolEntry(ctx, ps, nil, nil)
+		}
+	}
 	if err != nil {
 		return pkgreconciler.NewEvent(corev1.EventTypeWarning, reconciledDataPlaneFailedReason, "Failed to reconcile Data Plane resource(s): %s", err.Error())
 	}
@@ -197,8 +226,12 @@ func (r *Base) reconcileSubscription(ctx context.Context, ps *v1.PullSubscriptio
 
 	// subConfig is the wanted config based on settings.
 	subConfig := pubsub.SubscriptionConfig{
-		Topic:               t,
-		RetainAckedMessages: ps.Spec.RetainAckedMessages,
+		Topic:                     t,
+		RetainAckedMessages:       ps.Spec.RetainAckedMessages,
+		EnableMessageOrdering:     ps.Spec.EnableMessageOrdering,
+		EnableExactlyOnceDelivery: ps.Spec.EnableExactlyOnceDelivery,
+		Filter:                    ps.Spec.Filter,
+		ExpirationPolicy:          ps.Spec.GetExpirationTTL(),
 	}
 
 	if ps.Spec.AckDeadline != nil {
@@ -219,6 +252,10 @@ func (r *Base) reconcileSubscription(ctx context.Context, ps *v1.PullSubscriptio
 		subConfig.RetentionDuration = retentionDuration
 	}
 
+	if ps.Spec.Delivery != nil {
+		subConfig.RetryPolicy, subConfig.DeadLetterPolicy = deliveryPolicies(ctx, ps)
+	}
+
 	// Check if the topic of the subscription is "_deleted-topic_"
 	if subExists {
 		config, err := sub.Config(ctx)
@@ -239,6 +276,9 @@ func (r *Base) reconcileSubscription(ctx context.Context, ps *v1.PullSubscriptio
 				logging.FromContext(ctx).Desugar().Error("Failed to create subscription", zap.Error(err))
 				return "", err
 			}
+		} else if err := updateSubscription(ctx, sub, &config, subConfig); err != nil {
+			logging.FromContext(ctx).Desugar().Error("Failed to update the subscription", zap.Error(err))
+			return "", err
 		}
 	} else {
 		sub, err = client.CreateSubscription(ctx, subID, subConfig)
@@ -251,6 +291,78 @@ func (r *Base) reconcileSubscription(ctx context.Context, ps *v1.PullSubscriptio
 	return subID, nil
 }
 
+// deliveryPolicies translates the delivery spec of the PullSubscription to the retry and dead
+// letter policies of its subscription. The defaults of Pub/Sub are set explicitly, so that the
+// policies don't differ from the ones read back from the subscription.
+func deliveryPolicies(ctx context.Context, ps *v1.PullSubscription) (*pubsub.RetryPolicy, *pubsub.DeadLetterPolicy) {
+	retryPolicy := reconcilerutilspubsub.RetryPolicy(ctx, ps.Spec.Delivery)
+	minimumBackoff, _ := retryPolicy.MinimumBackoff.(time.Duration)
+	if minimumBackoff == 0 {
+		minimumBackoff = defaultMinimumBackoff
+	}
+	maximumBackoff, _ := retryPolicy.MaximumBackoff.(time.Duration)
+	if maximumBackoff < minimumBackoff {
+		maximumBackoff = minimumBackoff
+	}
+	retryPolicy = &pubsub.RetryPolicy{
+		MinimumBackoff: minimumBackoff,
+		MaximumBackoff: maximumBackoff,
+	}
+	deadLetterPolicy := reconcilerutilspubsub.DeadLetterPolicy(ps.Status.ProjectID, ps.Spec.Delivery)
+	if deadLetterPolicy != nil && deadLetterPolicy.MaxDeliveryAttempts == 0 {
+		deadLetterPolicy.MaxDeliveryAttempts = defaultMaxDeliveryAttempts
+	}
+	return retryPolicy, deadLetterPolicy
+}
+
+// deadLetterRetry returns the number of failed delivery attempts after which the receive adapter
+// sends an event to the dead letter sink, with the same default as the dead letter policy.
+func deadLetterRetry(ps *v1.PullSubscription) int32 {
+	if d := ps.Spec.Delivery; d != nil && d.Retry != nil {
+		return *d.Retry
+	}
+	return defaultMaxDeliveryAttempts
+}
+
+// updateSubscription updates the retry, dead letter and expiration policies and the exactly-once
+// delivery of the existing subscription if they differ from the desired ones. A nil retry or dead
+// letter policy removes the policy of the subscription, as the delivery spec of the
+// PullSubscription was removed. Message ordering and the filter can't be updated, the
+// PullSubscription validation rejects their changes.
+func updateSubscription(ctx context.Context, sub *pubsub.Subscription, existing *pubsub.SubscriptionConfig, desired pubsub.SubscriptionConfig) error {
+	var update pubsub.SubscriptionConfigToUpdate
+	changed := false
+	if existing.ExpirationPolicy != desired.ExpirationPolicy {
+		update.ExpirationPolicy = desired.ExpirationPolicy
+		changed = true
+	}
+	if existing.EnableExactlyOnceDelivery != desired.EnableExactlyOnceDelivery {
+		update.EnableExactlyOnceDelivery = desired.EnableExactlyOnceDelivery
+		changed = true
+	}
+	if !equality.Semantic.DeepEqual(existing.RetryPolicy, desired.RetryPolicy) {
+		// The empty policy removes the retry policy.
+		update.RetryPolicy = &pubsub.RetryPolicy{}
+		if desired.RetryPolicy != nil {
+			update.RetryPolicy = desired.RetryPolicy
+		}
+		changed = true
+	}
+	if !equality.Semantic.DeepEqual(existing.DeadLetterPolicy, desired.DeadLetterPolicy) {
+		// The empty policy removes the dead letter policy.
+		update.DeadLetterPolicy = &pubsub.DeadLetterPolicy{}
+		if desired.DeadLetterPolicy != nil {
+			update.DeadLetterPolicy = desired.DeadLetterPolicy
+		}
+		changed = true
+	}
+	if !changed {
+		return nil
+	}
+	_, err := sub.Update(ctx, update)
+	return err
+}
+
 // deleteSubscription looks at the status.SubscriptionID and if non-empty,
 // hence indicating that we have created a subscription successfully
 // in the PullSubscription, remove it.
@@ -285,17 +397,51 @@ func (r *Base) deleteSubscription(ctx context.Context, ps *v1.PullSubscription)
 }
 
 func (r *Base) reconcileDataPlaneResources(ctx context.Context, ps *v1.PullSubscription, f ReconcileDataPlaneFunc) error {
+	component := sourceComponent
+	// Set the metric component based on the channel label.
+	if _, ok := ps.Labels["events.cloud.google.com/channel"]; ok {
+		component = channelComponent
+	}
+	loggingConfig, metricsConfig, tracingConfig := r.observabilityConfigs(ctx, component)
+
+	authType, err := authcheck.GetAuthTypeForSources(ctx, r.ServiceAccountLister, authcheck.AuthTypeArgs{
+		Namespace:          ps.Namespace,
+		ServiceAccountName: ps.IdentitySpec().ServiceAccountName,
+		Secret:             ps.Spec.Secret,
+	})
+	if err != nil {
+		ps.Status.MarkDeployedUnknown(authcheck.AuthenticationCheckUnknownReason, err.Error())
+		logging.FromContext(ctx).Desugar().Error("Error getting authType", zap.Error(err))
+		return err
+	}
+
+	desired := resources.MakeReceiveAdapter(ctx, &resources.ReceiveAdapterArgs{
+		Image:             r.ReceiveAdapterImage,
+		PullSubscription:  ps,
+		Labels:            resources.GetLabels(r.ControllerAgentName, ps.Name),
+		SubscriptionID:    ps.Status.SubscriptionID,
+		SinkURI:           ps.Status.SinkURI,
+		TransformerURI:    ps.Status.TransformerURI,
+		DeadLetterSinkURI: ps.Status.DeadLetterSinkURI,
+		DeadLetterRetry:   deadLetterRetry(ps),
+		LoggingConfig:     loggingConfig,
+		MetricsConfig:     metricsConfig,
+		TracingConfig:     tracingConfig,
+		AuthType:          authType,
+	})
+
+	return f(ctx, desired, ps)
+}
+
+// observabilityConfigs returns the logging, metrics and tracing configs passed to the receive
+// adapters as JSON, with the given metrics component.
+func (r *Base) observabilityConfigs(ctx context.Context, component string) (string, string, string) {
 	loggingConfig, err := logging.ConfigToJSON(r.LoggingConfig)
 	if err != nil {
 		logging.FromContext(ctx).Desugar().Error("Error serializing existing logging config", zap.Error(err))
 	}
 
 	if r.MetricsConfig != nil {
-		component := sourceComponent
-		// Set the metric component based on the channel label.
-		if _, ok := ps.Labels["events.cloud.google.com/channel"]; ok {
-			component = channelComponent
-		}
 		r.MetricsConfig.Component = component
 	}
 
@@ -308,32 +454,7 @@ func (r *Base) reconcileDataPlaneResources(ctx context.Context, ps *v1.PullSubsc
 	if err != nil {
 		logging.FromContext(ctx).Desugar().Error("Error serializing tracing config", zap.Error(err))
 	}
-
-	authType, err := authcheck.GetAuthTypeForSources(ctx, r.ServiceAccountLister, authcheck.AuthTypeArgs{
-		Namespace:          ps.Namespace,
-		ServiceAccountName: ps.IdentitySpec().ServiceAccountName,
-		Secret:             ps.Spec.Secret,
-	})
-	if err != nil {
-		ps.Status.MarkDeployedUnknown(authcheck.AuthenticationCheckUnknownReason, err.Error())
-		logging.FromContext(ctx).Desugar().Error("Error getting authType", zap.Error(err))
-		return err
-	}
-
-	desired := resources.MakeReceiveAdapter(ctx, &resources.ReceiveAdapterArgs{
-		Image:            r.ReceiveAdapterImage,
-		PullSubscription: ps,
-		Labels:           resources.GetLabels(r.ControllerAgentName, ps.Name),
-		SubscriptionID:   ps.Status.SubscriptionID,
-		SinkURI:          ps.Status.SinkURI,
-		TransformerURI:   ps.Status.TransformerURI,
-		LoggingConfig:    loggingConfig,
-		MetricsConfig:    metricsConfig,
-		TracingConfig:    tracingConfig,
-		AuthType:         authType,
-	})
-
-	return f(ctx, desired, ps)
+	return loggingConfig, metricsConfig, tracingConfig
 }
 
 func (r *Base) GetOrCreateReceiveAdapter(ctx context.Context, desired *appsv1.Deployment, ps *v1.PullSubscription) (*appsv1.Deployment, error) {
@@ -427,6 +548,11 @@ func (r *Base) FinalizeKind(ctx context.Context, ps *v1.PullSubscription) pkgrec
 		}
 	}
 
+	// Stop delivering the events before the subscription is gone.
+	if err := r.updateAdapterPoolEntry(ctx, ps, nil, nil); err != nil {
+		return pkgreconciler.NewEvent(corev1.EventTypeWarning, deleteAdapterPoolEntryFailed, "Failed to remove the PullSubscription from the adapter pool: %s", err.Error())
+	}
+
 	logging.FromContext(ctx).Desugar().Debug("Deleting Pub/Sub subscription")
 	if err := r.deleteSubscription(ctx, ps); err != nil {
 		return pkgreconciler.NewEvent(corev1.EventTypeWarning, deletePubSubFailedReason, "Failed to delete Pub/Sub subscription: %s", err.Error())
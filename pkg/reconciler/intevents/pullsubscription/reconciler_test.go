@@ -0,0 +1,150 @@
+/*
+Copyright 2021 Google LLC
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package pullsubscription
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"cloud.google.com/go/pubsub"
+	"github.com/google/go-cmp/cmp"
+	eventingduckv1 "knative.dev/eventing/pkg/apis/duck/v1"
+	"knative.dev/pkg/apis"
+	duckv1 "knative.dev/pkg/apis/duck/v1"
+	"knative.dev/pkg/ptr"
+
+	gcpduckv1 "github.com/google/knative-gcp/pkg/apis/duck/v1"
+	v1 "github.com/google/knative-gcp/pkg/apis/intevents/v1"
+	. "github.com/google/knative-gcp/pkg/reconciler/testing"
+)
+
+func TestDeliveryPolicies(t *testing.T) {
+	topic := apis.HTTP("dead-letter")
+	topic.Scheme = "pubsub"
+	linear := eventingduckv1.BackoffPolicyLinear
+
+	testCases := []struct {
+		name      string
+		delivery  *eventingduckv1.DeliverySpec
+		wantRetry *pubsub.RetryPolicy
+		wantDLP   *pubsub.DeadLetterPolicy
+	}{{
+		name: "dead letter topic with defaults",
+		delivery: &eventingduckv1.DeliverySpec{
+			DeadLetterSink: &duckv1.Destination{URI: topic},
+		},
+		wantRetry: &pubsub.RetryPolicy{MinimumBackoff: 10 * time.Second, MaximumBackoff: 600 * time.Second},
+		wantDLP:   &pubsub.DeadLetterPolicy{DeadLetterTopic: "projects/project/topics/dead-letter", MaxDeliveryAttempts: 5},
+	}, {
+		name: "dead letter topic with linear backoff",
+		delivery: &eventingduckv1.DeliverySpec{
+			DeadLetterSink: &duckv1.Destination{URI: topic},
+			Retry:          ptr.Int32(20),
+			BackoffPolicy:  &linear,
+			BackoffDelay:   ptr.String("PT30S"),
+		},
+		wantRetry: &pubsub.RetryPolicy{MinimumBackoff: 30 * time.Second, MaximumBackoff: 30 * time.Second},
+		wantDLP:   &pubsub.DeadLetterPolicy{DeadLetterTopic: "projects/project/topics/dead-letter", MaxDeliveryAttempts: 20},
+	}, {
+		name: "dead letter sink handled by the receive adapter",
+		delivery: &eventingduckv1.DeliverySpec{
+			DeadLetterSink: &duckv1.Destination{URI: apis.HTTP("dead-letter.example.com")},
+			BackoffDelay:   ptr.String("PT1S"),
+		},
+		wantRetry: &pubsub.RetryPolicy{MinimumBackoff: time.Second, MaximumBackoff: 600 * time.Second},
+	}}
+
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			ps := &v1.PullSubscription{
+				Spec: v1.PullSubscriptionSpec{
+					PubSubSpec: gcpduckv1.PubSubSpec{Delivery: tc.delivery},
+				},
+			}
+			ps.Status.ProjectID = "project"
+			gotRetry, gotDLP := deliveryPolicies(context.Background(), ps)
+			if diff := cmp.Diff(tc.wantRetry, gotRetry); diff != "" {
+				t.Errorf("unexpected retry policy (-want, +got) = %v", diff)
+			}
+			if diff := cmp.Diff(tc.wantDLP, gotDLP); diff != "" {
+				t.Errorf("unexpected dead letter policy (-want, +got) = %v", diff)
+			}
+		})
+	}
+}
+
+func TestDeadLetterRetry(t *testing.T) {
+	sink := &duckv1.Destination{URI: apis.HTTP("dead-letter.example.com")}
+	ps := &v1.PullSubscription{}
+	ps.Spec.Delivery = &eventingduckv1.DeliverySpec{DeadLetterSink: sink}
+	if got := deadLetterRetry(ps); got != 5 {
+		t.Errorf("Unexpected default retry %d, wanted 5", got)
+	}
+	ps.Spec.Delivery.Retry = ptr.Int32(20)
+	if got := deadLetterRetry(ps); got != 20 {
+		t.Errorf("Unexpected retry %d, wanted 20", got)
+	}
+}
+
+func TestUpdateSubscription(t *testing.T) {
+	ctx := context.Background()
+	client, close := TestPubsubClient(ctx, "project")
+	defer close()
+	topic, err := client.CreateTopic(ctx, "topic")
+	if err != nil {
+		t.Fatalf("Failed to create the topic: %v", err)
+	}
+	sub, err := client.CreateSubscription(ctx, "sub", pubsub.SubscriptionConfig{
+		Topic:                 topic,
+		EnableMessageOrdering: true,
+		Filter:                `attributes.region = "eu"`,
+		ExpirationPolicy:      31 * 24 * time.Hour,
+	})
+	if err != nil {
+		t.Fatalf("Failed to create the subscription: %v", err)
+	}
+	existing, err := sub.Config(ctx)
+	if err != nil {
+		t.Fatalf("Failed to get the subscription config: %v", err)
+	}
+
+	desired := existing
+	desired.ExpirationPolicy = time.Duration(0)
+	desired.EnableExactlyOnceDelivery = true
+	if err := updateSubscription(ctx, sub, &existing, desired); err != nil {
+		t.Fatalf("Failed to update the subscription: %v", err)
+	}
+	got, err := sub.Config(ctx)
+	if err != nil {
+		t.Fatalf("Failed to get the subscription config: %v", err)
+	}
+	if got.ExpirationPolicy != time.Duration(0) {
+		t.Errorf("Unexpected expiration policy %v, wanted never", got.ExpirationPolicy)
+	}
+	if !got.EnableExactlyOnceDelivery {
+		t.Error("Unexpected exactly-once delivery disabled, wanted it enabled")
+	}
+	if !got.EnableMessageOrdering || got.Filter != existing.Filter {
+		t.Errorf("Unexpected ordering %t and filter %q, wanted them unchanged", got.EnableMessageOrdering, got.Filter)
+	}
+
+	// Nothing to update.
+	if err := updateSubscription(ctx, sub, &got, got); err != nil {
+		t.Errorf("Unexpected error with nothing to update: %v", err)
+	}
+}
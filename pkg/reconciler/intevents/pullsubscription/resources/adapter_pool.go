@@ -0,0 +1,311 @@
+/*
+Copyright 2021 Google LLC
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package resources
+
+import (
+	"fmt"
+	"strconv"
+	"strings"
+	"unicode"
+
+	appsv1 "k8s.io/api/apps/v1"
+	hpav2beta2 "k8s.io/api/autoscaling/v2beta2"
+	corev1 "k8s.io/api/core/v1"
+	"k8s.io/apimachinery/pkg/api/resource"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/util/intstr"
+	"knative.dev/pkg/ptr"
+
+	"github.com/google/knative-gcp/pkg/apis/duck"
+	intereventsv1 "github.com/google/knative-gcp/pkg/apis/intevents/v1"
+	"github.com/google/knative-gcp/pkg/pubsub/adapter/pool"
+	"github.com/google/knative-gcp/pkg/utils/authcheck"
+)
+
+const (
+	// AdapterPoolServiceAccountName is the Kubernetes service account of the receive adapter pool.
+	AdapterPoolServiceAccountName = "pubsub-adapter-pool"
+
+	adapterPoolLabelKey     = "internal.events.cloud.google.com/adapter-pool"
+	adapterPoolConfigVolume = "config"
+	adapterPoolConfigPath   = "/var/run/cloud-run-events/adapter-pool"
+	adapterPoolMinReplicas  = 1
+	adapterPoolMaxReplicas  = 10
+	adapterPoolCPUTarget    = 50
+)
+
+// AdapterPoolArgs are the arguments needed to create the receive adapter pool. Every field is
+// required.
+type AdapterPoolArgs struct {
+	Image         string
+	Namespace     string
+	MetricsConfig string
+	LoggingConfig string
+	TracingConfig string
+}
+
+// UsesAdapterPool returns whether the events of the PullSubscription are delivered by the receive
+// adapter pool rather than by a receive adapter of its own.
+func UsesAdapterPool(ps *intereventsv1.PullSubscription) bool {
+	return ps.Annotations[duck.AdapterModeAnnotation] == duck.AdapterModePool
+}
+
+// AdapterPoolAllowed returns whether the allow list of the adapter pool lets the PullSubscriptions
+// of the namespace use the pool. A missing allow list allows no namespace.
+func AdapterPoolAllowed(allowList *corev1.ConfigMap, namespace string) bool {
+	if allowList == nil {
+		return false
+	}
+	allowed := strings.FieldsFunc(allowList.Data[pool.AllowedNamespacesKey], func(r rune) bool {
+		return r == ',' || unicode.IsSpace(r)
+	})
+	for _, ns := range allowed {
+		if ns == namespace {
+			return true
+		}
+	}
+	return false
+}
+
+// AdapterPoolReceiveSettings returns the receive settings of the entries of the adapter pool set in
+// its allow list, or nil if none is set.
+func AdapterPoolReceiveSettings(allowList *corev1.ConfigMap) (*pool.ReceiveSettings, error) {
+	if allowList == nil {
+		return nil, nil
+	}
+	messages, err := positiveInt(allowList.Data, pool.MaxOutstandingMessagesKey)
+	if err != nil {
+		return nil, err
+	}
+	bytes, err := positiveInt(allowList.Data, pool.MaxOutstandingBytesKey)
+	if err != nil {
+		return nil, err
+	}
+	if messages == 0 && bytes == 0 {
+		return nil, nil
+	}
+	return &pool.ReceiveSettings{MaxOutstandingMessages: messages, MaxOutstandingBytes: bytes}, nil
+}
+
+// positiveInt parses the value of the key in data as a positive integer, or returns 0 if it is unset.
+func positiveInt(data map[string]string, key string) (int, error) {
+	raw, ok := data[key]
+	if !ok {
+		return 0, nil
+	}
+	v, err := strconv.Atoi(strings.TrimSpace(raw))
+	if err != nil || v <= 0 {
+		return 0, fmt.Errorf("%s must be a positive integer, got %q", key, raw)
+	}
+	return v, nil
+}
+
+// AdapterPoolKey returns the key of the entry of the PullSubscription in the adapter pool config.
+func AdapterPoolKey(ps *intereventsv1.PullSubscription) string {
+	return ps.Namespace + "/" + ps.Name
+}
+
+// AdapterPoolLabels returns the labels of the receive adapter pool.
+func AdapterPoolLabels() map[string]string {
+	return map[string]string{adapterPoolLabelKey: pool.Name}
+}
+
+// MakeAdapterPoolEntry makes the entry of the PullSubscription in the adapter pool config, with the
+// arguments its own receive adapter would get. The Image and observability configs are unused.
+func MakeAdapterPoolEntry(args *ReceiveAdapterArgs) *pool.Entry {
+	ps := args.PullSubscription
+	entry := &pool.Entry{
+		Namespace:     ps.Namespace,
+		Name:          metricsResourceName(ps),
+		ResourceGroup: metricsResourceGroup(ps),
+		Project:       ps.Status.ProjectID,
+		Topic:         ps.Spec.Topic,
+		Subscription:  args.SubscriptionID,
+		Sink:          args.SinkURI.String(),
+		AdapterType:   receiveAdapterType(ps),
+		Mapping:       ps.Spec.Mapping,
+		RawPayload:    ps.Spec.RawPayload,
+	}
+	if args.TransformerURI != nil {
+		entry.Transformer = args.TransformerURI.String()
+	}
+	if args.DeadLetterSinkURI != nil {
+		entry.DeadLetterSink = args.DeadLetterSinkURI.String()
+		entry.DeadLetterRetry = args.DeadLetterRetry
+	}
+	if ps.Spec.CloudEventOverrides != nil {
+		entry.Extensions = ps.Spec.CloudEventOverrides.Extensions
+	}
+	return entry
+}
+
+// MakeAdapterPoolConfigMap makes the ConfigMap holding the adapter pool config.
+func MakeAdapterPoolConfigMap(namespace string, cfg *pool.Config) (*corev1.ConfigMap, error) {
+	data, err := cfg.Marshal()
+	if err != nil {
+		return nil, err
+	}
+	return &corev1.ConfigMap{
+		ObjectMeta: metav1.ObjectMeta{
+			Namespace: namespace,
+			Name:      pool.Name,
+			Labels:    AdapterPoolLabels(),
+		},
+		Data: map[string]string{pool.ConfigKey: string(data)},
+	}, nil
+}
+
+// MakeAdapterPool makes the Deployment of the receive adapter pool, which delivers the events of
+// all the entries of the adapter pool config mounted from its ConfigMap. Its replicas are left to
+// its HorizontalPodAutoscaler.
+func MakeAdapterPool(args *AdapterPoolArgs) *appsv1.Deployment {
+	labels := AdapterPoolLabels()
+	container := corev1.Container{
+		Name:  "receive-adapter",
+		Image: args.Image,
+		// The pool delivers the events of many subscriptions, so it gets more resources than a
+		// receive adapter of its own.
+		Resources: corev1.ResourceRequirements{
+			Limits: corev1.ResourceList{
+				corev1.ResourceMemory: resource.MustParse("2Gi"),
+				corev1.ResourceCPU:    resource.MustParse("2000m"),
+			},
+			Requests: corev1.ResourceList{
+				corev1.ResourceMemory: resource.MustParse("256Mi"),
+				corev1.ResourceCPU:    resource.MustParse("500m"),
+			},
+		},
+		Env: []corev1.EnvVar{{
+			Name:  "ADAPTER_POOL_CONFIG",
+			Value: fmt.Sprintf("%s/%s", adapterPoolConfigPath, pool.ConfigKey),
+		}, {
+			Name:  "K_METRICS_CONFIG",
+			Value: args.MetricsConfig,
+		}, {
+			Name:  "K_LOGGING_CONFIG",
+			Value: args.LoggingConfig,
+		}, {
+			Name:  "K_TRACING_CONFIG",
+			Value: args.TracingConfig,
+		}, {
+			Name:  "METRICS_DOMAIN",
+			Value: metricsDomain,
+		}, {
+			Name:  "GOOGLE_APPLICATION_CREDENTIALS",
+			Value: fmt.Sprintf("%s/%s", credsMountPath, "key.json"),
+		}},
+		Ports: []corev1.ContainerPort{{
+			Name:          "metrics",
+			ContainerPort: 9090,
+		}, {
+			Name:          "http",
+			ContainerPort: authcheck.DefaultProbeCheckPort,
+		}},
+		VolumeMounts: []corev1.VolumeMount{{
+			Name:      adapterPoolConfigVolume,
+			MountPath: adapterPoolConfigPath,
+			ReadOnly:  true,
+		}, {
+			Name:      credsVolume,
+			MountPath: credsMountPath,
+		}},
+		LivenessProbe: &corev1.Probe{
+			Handler: corev1.Handler{
+				HTTPGet: &corev1.HTTPGetAction{
+					Path:   "/healthz",
+					Port:   intstr.FromInt(authcheck.DefaultProbeCheckPort),
+					Scheme: corev1.URISchemeHTTP,
+				},
+			},
+			FailureThreshold:    3,
+			PeriodSeconds:       15,
+			InitialDelaySeconds: 5,
+			SuccessThreshold:    1,
+			TimeoutSeconds:      5,
+		},
+	}
+
+	return &appsv1.Deployment{
+		ObjectMeta: metav1.ObjectMeta{
+			Namespace: args.Namespace,
+			Name:      pool.Name,
+			Labels:    labels,
+		},
+		Spec: appsv1.DeploymentSpec{
+			Selector: &metav1.LabelSelector{
+				MatchLabels: labels,
+			},
+			Template: corev1.PodTemplateSpec{
+				ObjectMeta: metav1.ObjectMeta{
+					Labels: labels,
+				},
+				Spec: corev1.PodSpec{
+					ServiceAccountName: AdapterPoolServiceAccountName,
+					Containers:         []corev1.Container{container},
+					Volumes: []corev1.Volume{{
+						Name: adapterPoolConfigVolume,
+						VolumeSource: corev1.VolumeSource{
+							ConfigMap: &corev1.ConfigMapVolumeSource{
+								LocalObjectReference: corev1.LocalObjectReference{Name: pool.Name},
+							},
+						},
+					}, {
+						// The key is optional, as the pool may use workload identity instead.
+						Name: credsVolume,
+						VolumeSource: corev1.VolumeSource{
+							Secret: &corev1.SecretVolumeSource{
+								SecretName: duck.DefaultSecretName,
+								Optional:   ptr.Bool(true),
+							},
+						},
+					}},
+				},
+			},
+		},
+	}
+}
+
+// MakeAdapterPoolHPA makes the HorizontalPodAutoscaler of the receive adapter pool, scaling it on
+// its CPU usage. Each replica pulls all the subscriptions of the pool.
+func MakeAdapterPoolHPA(deployment *appsv1.Deployment) *hpav2beta2.HorizontalPodAutoscaler {
+	return &hpav2beta2.HorizontalPodAutoscaler{
+		ObjectMeta: metav1.ObjectMeta{
+			Namespace: deployment.Namespace,
+			Name:      deployment.Name,
+			Labels:    AdapterPoolLabels(),
+		},
+		Spec: hpav2beta2.HorizontalPodAutoscalerSpec{
+			ScaleTargetRef: hpav2beta2.CrossVersionObjectReference{
+				APIVersion: "apps/v1",
+				Kind:       "Deployment",
+				Name:       deployment.Name,
+			},
+			MinReplicas: ptr.Int32(adapterPoolMinReplicas),
+			MaxReplicas: adapterPoolMaxReplicas,
+			Metrics: []hpav2beta2.MetricSpec{{
+				Type: hpav2beta2.ResourceMetricSourceType,
+				Resource: &hpav2beta2.ResourceMetricSource{
+					Name: corev1.ResourceCPU,
+					Target: hpav2beta2.MetricTarget{
+						Type:               hpav2beta2.UtilizationMetricType,
+						AverageUtilization: ptr.Int32(adapterPoolCPUTarget),
+					},
+				},
+			}},
+		},
+	}
+}
@@ -0,0 +1,200 @@
+/*
+Copyright 2021 Google LLC
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package resources
+
+import (
+	"testing"
+
+	"github.com/google/go-cmp/cmp"
+	corev1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"knative.dev/pkg/apis"
+	duckv1 "knative.dev/pkg/apis/duck/v1"
+
+	"github.com/google/knative-gcp/pkg/apis/duck"
+	gcpduckv1 "github.com/google/knative-gcp/pkg/apis/duck/v1"
+	v1 "github.com/google/knative-gcp/pkg/apis/intevents/v1"
+	"github.com/google/knative-gcp/pkg/pubsub/adapter/pool"
+)
+
+func TestUsesAdapterPool(t *testing.T) {
+	ps := &v1.PullSubscription{}
+	if UsesAdapterPool(ps) {
+		t.Errorf("UsesAdapterPool() = true without the annotation, wanted false")
+	}
+	ps.Annotations = map[string]string{duck.AdapterModeAnnotation: duck.AdapterModePool}
+	if !UsesAdapterPool(ps) {
+		t.Errorf("UsesAdapterPool() = false with the annotation, wanted true")
+	}
+}
+
+func TestAdapterPoolAllowed(t *testing.T) {
+	allowList := &corev1.ConfigMap{
+		Data: map[string]string{pool.AllowedNamespacesKey: "team-a, team-b\nteam-c"},
+	}
+	for _, ns := range []string{"team-a", "team-b", "team-c"} {
+		if !AdapterPoolAllowed(allowList, ns) {
+			t.Errorf("AdapterPoolAllowed(%q) = false, wanted true", ns)
+		}
+	}
+	if AdapterPoolAllowed(allowList, "team") {
+		t.Errorf("AdapterPoolAllowed(%q) = true for a namespace not in the allow list, wanted false", "team")
+	}
+	if AdapterPoolAllowed(nil, "team-a") {
+		t.Errorf("AdapterPoolAllowed() = true without an allow list, wanted false")
+	}
+}
+
+func TestAdapterPoolReceiveSettings(t *testing.T) {
+	for _, tc := range []struct {
+		name    string
+		data    map[string]string
+		want    *pool.ReceiveSettings
+		wantErr bool
+	}{{
+		name: "unset",
+		data: map[string]string{pool.AllowedNamespacesKey: "team-a"},
+	}, {
+		name: "set",
+		data: map[string]string{pool.MaxOutstandingMessagesKey: "10", pool.MaxOutstandingBytesKey: " 1048576\n"},
+		want: &pool.ReceiveSettings{MaxOutstandingMessages: 10, MaxOutstandingBytes: 1 << 20},
+	}, {
+		name:    "invalid",
+		data:    map[string]string{pool.MaxOutstandingMessagesKey: "many"},
+		wantErr: true,
+	}, {
+		name:    "not positive",
+		data:    map[string]string{pool.MaxOutstandingBytesKey: "0"},
+		wantErr: true,
+	}} {
+		t.Run(tc.name, func(t *testing.T) {
+			got, err := AdapterPoolReceiveSettings(&corev1.ConfigMap{Data: tc.data})
+			if (err != nil) != tc.wantErr {
+				t.Fatalf("AdapterPoolReceiveSettings() error = %v, wantErr %v", err, tc.wantErr)
+			}
+			if diff := cmp.Diff(tc.want, got); diff != "" {
+				t.Errorf("Unexpected receive settings (-want, +got) = %v", diff)
+			}
+		})
+	}
+}
+
+func TestMakeAdapterPoolEntry(t *testing.T) {
+	ps := &v1.PullSubscription{
+		ObjectMeta: metav1.ObjectMeta{
+			Name:      "source-name",
+			Namespace: "source-namespace",
+			Labels: map[string]string{
+				"events.cloud.google.com/source-name": "storage-source",
+			},
+			Annotations: map[string]string{
+				"metrics-resource-group": "cloudstoragesources.events.cloud.google.com",
+				"metrics-resource-name":  "storage-source",
+			},
+		},
+		Spec: v1.PullSubscriptionSpec{
+			Topic:       "topic",
+			AdapterType: "storage",
+			Mapping:     &gcpduckv1.EventMapping{Type: "{.attributes.type}", Source: "//storage"},
+		},
+	}
+	ps.Spec.CloudEventOverrides = &duckv1.CloudEventOverrides{
+		Extensions: map[string]string{"foo": "bar"},
+	}
+	ps.Status.ProjectID = "project"
+
+	got := MakeAdapterPoolEntry(&ReceiveAdapterArgs{
+		PullSubscription:  ps,
+		SubscriptionID:    "sub-id",
+		SinkURI:           apis.HTTP("sink"),
+		TransformerURI:    apis.HTTP("transformer"),
+		DeadLetterSinkURI: apis.HTTP("dead-letter-sink"),
+		DeadLetterRetry:   5,
+	})
+	want := &pool.Entry{
+		Namespace:       "source-namespace",
+		Name:            "storage-source",
+		ResourceGroup:   "cloudstoragesources.events.cloud.google.com",
+		Project:         "project",
+		Topic:           "topic",
+		Subscription:    "sub-id",
+		Sink:            "http://sink",
+		Transformer:     "http://transformer",
+		DeadLetterSink:  "http://dead-letter-sink",
+		DeadLetterRetry: 5,
+		AdapterType:     "storage",
+		Mapping:         &gcpduckv1.EventMapping{Type: "{.attributes.type}", Source: "//storage"},
+		Extensions:      map[string]string{"foo": "bar"},
+	}
+	if diff := cmp.Diff(want, got); diff != "" {
+		t.Errorf("unexpected entry (-want, +got) = %v", diff)
+	}
+}
+
+func TestMakeAdapterPoolConfigMap(t *testing.T) {
+	cfg := &pool.Config{Entries: map[string]*pool.Entry{
+		"ns/ps": {Namespace: "ns", Name: "ps", Subscription: "sub-id", Sink: "http://sink"},
+	}}
+	cm, err := MakeAdapterPoolConfigMap("system", cfg)
+	if err != nil {
+		t.Fatalf("MakeAdapterPoolConfigMap() = %v", err)
+	}
+	if cm.Namespace != "system" || cm.Name != pool.Name {
+		t.Errorf("unexpected ConfigMap %s/%s, wanted system/%s", cm.Namespace, cm.Name, pool.Name)
+	}
+	got, err := pool.ParseConfig([]byte(cm.Data[pool.ConfigKey]))
+	if err != nil {
+		t.Fatalf("ParseConfig() = %v", err)
+	}
+	if diff := cmp.Diff(cfg, got); diff != "" {
+		t.Errorf("unexpected config (-want, +got) = %v", diff)
+	}
+}
+
+func TestMakeAdapterPool(t *testing.T) {
+	d := MakeAdapterPool(&AdapterPoolArgs{
+		Image:     "image",
+		Namespace: "system",
+	})
+	if d.Namespace != "system" || d.Name != pool.Name {
+		t.Errorf("unexpected Deployment %s/%s, wanted system/%s", d.Namespace, d.Name, pool.Name)
+	}
+	if d.Spec.Replicas != nil {
+		t.Errorf("unexpected replicas %d, wanted them left to the HPA", *d.Spec.Replicas)
+	}
+	spec := d.Spec.Template.Spec
+	if spec.ServiceAccountName != AdapterPoolServiceAccountName {
+		t.Errorf("unexpected service account %q, wanted %q", spec.ServiceAccountName, AdapterPoolServiceAccountName)
+	}
+	if got := spec.Volumes[0].ConfigMap; got == nil || got.Name != pool.Name {
+		t.Errorf("unexpected config volume %v, wanted the %q ConfigMap", spec.Volumes[0], pool.Name)
+	}
+	var configPath string
+	for _, env := range spec.Containers[0].Env {
+		if env.Name == "ADAPTER_POOL_CONFIG" {
+			configPath = env.Value
+		}
+	}
+	if want := adapterPoolConfigPath + "/" + pool.ConfigKey; configPath != want {
+		t.Errorf("unexpected ADAPTER_POOL_CONFIG %q, wanted %q", configPath, want)
+	}
+
+	hpa := MakeAdapterPoolHPA(d)
+	if hpa.Namespace != d.Namespace || hpa.Spec.ScaleTargetRef.Name != d.Name {
+		t.Errorf("unexpected HPA %s/%s scaling %q, wanted it to scale %s/%s", hpa.Namespace, hpa.Name, hpa.Spec.ScaleTargetRef.Name, d.Namespace, d.Name)
+	}
+}
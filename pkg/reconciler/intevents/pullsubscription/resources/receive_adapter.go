@@ -18,7 +18,9 @@ package resources
 
 import (
 	"context"
+	"encoding/json"
 	"fmt"
+	"strconv"
 
 	"k8s.io/apimachinery/pkg/util/intstr"
 
@@ -51,9 +53,15 @@ type ReceiveAdapterArgs struct {
 	SubscriptionID   string
 	SinkURI          *apis.URL
 	TransformerURI   *apis.URL
-	MetricsConfig    string
-	LoggingConfig    string
-	TracingConfig    string
+	// DeadLetterSinkURI is optional, it is only set when the dead letter sink is not a Pub/Sub
+	// topic.
+	DeadLetterSinkURI *apis.URL
+	// DeadLetterRetry is the number of failed delivery attempts after which an event is sent to
+	// the DeadLetterSinkURI. It is only used with a DeadLetterSinkURI.
+	DeadLetterRetry int32
+	MetricsConfig   string
+	LoggingConfig   string
+	TracingConfig   string
 	// There are three types: `secret`, `workload-identity-gsa` and `workload-identity`.
 	AuthType authcheck.AuthType
 }
@@ -78,29 +86,15 @@ func makeReceiveAdapterPodSpec(ctx context.Context, args *ReceiveAdapterArgs) *c
 		}
 	}
 
-	var resourceGroup = defaultResourceGroup
-	if rg, ok := args.PullSubscription.Annotations["metrics-resource-group"]; ok {
-		resourceGroup = rg
-	}
-	// Needed for Channels, as we use a generate name for the PullSubscription.
-	var resourceName = args.PullSubscription.Name
-	if rn, ok := args.PullSubscription.Annotations["metrics-resource-name"]; ok {
-		resourceName = rn
-	}
+	resourceGroup := metricsResourceGroup(args.PullSubscription)
+	resourceName := metricsResourceName(args.PullSubscription)
 
 	var transformerURI string
 	if args.TransformerURI != nil {
 		transformerURI = args.TransformerURI.String()
 	}
 
-	adapterType := args.PullSubscription.Spec.AdapterType
-	// If the PullSubscription has no Channel nor Source label, means that users created a PullSubscription manually.
-	// Then we set the adapter type to be PubSubPull.
-	_, isFromSource := args.PullSubscription.Labels[intevents.SourceLabelKey]
-	_, isFromChannel := args.PullSubscription.Labels[intevents.ChannelLabelKey]
-	if !isFromSource && !isFromChannel {
-		adapterType = string(converters.PubSubPull)
-	}
+	adapterType := receiveAdapterType(args.PullSubscription)
 
 	receiveAdapterContainer := corev1.Container{
 		Name:  "receive-adapter",
@@ -186,6 +180,25 @@ func makeReceiveAdapterPodSpec(ctx context.Context, args *ReceiveAdapterArgs) *c
 		},
 	}
 
+	// The variable is only set when needed, so that the existing receive adapters are not
+	// redeployed.
+	if args.DeadLetterSinkURI != nil {
+		receiveAdapterContainer.Env = append(receiveAdapterContainer.Env, corev1.EnvVar{
+			Name:  "DEAD_LETTER_SINK_URI",
+			Value: args.DeadLetterSinkURI.String(),
+		}, corev1.EnvVar{
+			Name:  "DEAD_LETTER_RETRY",
+			Value: strconv.Itoa(int(args.DeadLetterRetry)),
+		})
+	}
+
+	if args.PullSubscription.Spec.Mapping != nil {
+		receiveAdapterContainer.Env = appendJSONEnv(ctx, receiveAdapterContainer.Env, "K_CE_MAPPING", args.PullSubscription.Spec.Mapping)
+	}
+	if args.PullSubscription.Spec.RawPayload != nil {
+		receiveAdapterContainer.Env = appendJSONEnv(ctx, receiveAdapterContainer.Env, "K_CE_RAW_PAYLOAD", args.PullSubscription.Spec.RawPayload)
+	}
+
 	// This is added purely for the TestCloudLogging E2E tests, which verify that the log line is
 	// written certain annotations are present.
 	receiveAdapterContainer.Env = testloggingutil.PropagateLoggingE2ETestAnnotation(
@@ -239,6 +252,48 @@ func makeReceiveAdapterPodSpec(ctx context.Context, args *ReceiveAdapterArgs) *c
 	}
 }
 
+// appendJSONEnv appends the environment variable holding the JSON of the value.
+func appendJSONEnv(ctx context.Context, env []corev1.EnvVar, name string, value interface{}) []corev1.EnvVar {
+	data, err := json.Marshal(value)
+	if err != nil {
+		logging.FromContext(ctx).Warnw("failed to marshal the value of an environment variable",
+			zap.Error(err),
+			zap.String("name", name))
+		return env
+	}
+	return append(env, corev1.EnvVar{Name: name, Value: string(data)})
+}
+
+// metricsResourceGroup returns the resource group of the PullSubscription reported in the metrics.
+func metricsResourceGroup(ps *intereventsv1.PullSubscription) string {
+	if rg, ok := ps.Annotations["metrics-resource-group"]; ok {
+		return rg
+	}
+	return defaultResourceGroup
+}
+
+// metricsResourceName returns the name of the PullSubscription reported in the metrics.
+func metricsResourceName(ps *intereventsv1.PullSubscription) string {
+	// Needed for Channels, as we use a generate name for the PullSubscription.
+	if rn, ok := ps.Annotations["metrics-resource-name"]; ok {
+		return rn
+	}
+	return ps.Name
+}
+
+// receiveAdapterType returns the adapter type selecting the converter of the messages of the
+// PullSubscription.
+func receiveAdapterType(ps *intereventsv1.PullSubscription) string {
+	// If the PullSubscription has no Channel nor Source label, means that users created a PullSubscription manually.
+	// Then we set the adapter type to be PubSubPull.
+	_, isFromSource := ps.Labels[intevents.SourceLabelKey]
+	_, isFromChannel := ps.Labels[intevents.ChannelLabelKey]
+	if !isFromSource && !isFromChannel {
+		return string(converters.PubSubPull)
+	}
+	return ps.Spec.AdapterType
+}
+
 // MakeReceiveAdapter generates (but does not insert into K8s) the Receive Adapter Deployment for
 // PullSubscriptions.
 func MakeReceiveAdapter(ctx context.Context, args *ReceiveAdapterArgs) *v1.Deployment {
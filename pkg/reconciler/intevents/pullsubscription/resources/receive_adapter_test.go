@@ -254,13 +254,15 @@ func TestMakeFullReceiveAdapter(t *testing.T) {
 			"test-key1": "test-value1",
 			"test-key2": "test-value2",
 		},
-		SubscriptionID: "sub-id",
-		SinkURI:        apis.HTTP("sink-uri"),
-		TransformerURI: apis.HTTP("transformer-uri"),
-		LoggingConfig:  "LoggingConfig-ABC123",
-		MetricsConfig:  "MetricsConfig-ABC123",
-		TracingConfig:  "TracingConfig-ABC123",
-		AuthType:       authcheck.Secret,
+		SubscriptionID:    "sub-id",
+		SinkURI:           apis.HTTP("sink-uri"),
+		TransformerURI:    apis.HTTP("transformer-uri"),
+		DeadLetterSinkURI: apis.HTTP("dead-letter-sink-uri"),
+		DeadLetterRetry:   5,
+		LoggingConfig:     "LoggingConfig-ABC123",
+		MetricsConfig:     "MetricsConfig-ABC123",
+		TracingConfig:     "TracingConfig-ABC123",
+		AuthType:          authcheck.Secret,
 	})
 
 	one := int32(1)
@@ -370,6 +372,12 @@ func TestMakeFullReceiveAdapter(t *testing.T) {
 						}, {
 							Name:  "K_GCP_AUTH_TYPE",
 							Value: "secret",
+						}, {
+							Name:  "DEAD_LETTER_SINK_URI",
+							Value: "http://dead-letter-sink-uri",
+						}, {
+							Name:  "DEAD_LETTER_RETRY",
+							Value: "5",
 						}, {
 							Name:  "GOOGLE_APPLICATION_CREDENTIALS",
 							Value: "/var/secrets/google/eventing-secret-key",
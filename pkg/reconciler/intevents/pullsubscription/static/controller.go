@@ -22,10 +22,12 @@ import (
 	"cloud.google.com/go/pubsub"
 	"github.com/kelseyhightower/envconfig"
 	"go.uber.org/zap"
+	"k8s.io/apimachinery/pkg/labels"
 	"k8s.io/client-go/tools/cache"
 	"knative.dev/pkg/injection"
 
 	deploymentinformer "knative.dev/pkg/client/injection/kube/informers/apps/v1/deployment"
+	configmapinformer "knative.dev/pkg/client/injection/kube/informers/core/v1/configmap"
 	serviceaccountinformers "knative.dev/pkg/client/injection/kube/informers/core/v1/serviceaccount"
 	"knative.dev/pkg/configmap"
 	"knative.dev/pkg/controller"
@@ -33,16 +35,19 @@ import (
 	"knative.dev/pkg/metrics"
 	pkgreconciler "knative.dev/pkg/reconciler"
 	"knative.dev/pkg/resolver"
+	"knative.dev/pkg/system"
 	tracingconfig "knative.dev/pkg/tracing/config"
 
 	"github.com/google/knative-gcp/pkg/apis/configs/gcpauth"
 	"github.com/google/knative-gcp/pkg/apis/duck"
 	pullsubscriptioninformers "github.com/google/knative-gcp/pkg/client/injection/informers/intevents/v1/pullsubscription"
 	pullsubscriptionreconciler "github.com/google/knative-gcp/pkg/client/injection/reconciler/intevents/v1/pullsubscription"
+	"github.com/google/knative-gcp/pkg/pubsub/adapter/pool"
 	"github.com/google/knative-gcp/pkg/reconciler"
 	"github.com/google/knative-gcp/pkg/reconciler/identity"
 	"github.com/google/knative-gcp/pkg/reconciler/identity/iam"
 	psreconciler "github.com/google/knative-gcp/pkg/reconciler/intevents/pullsubscription"
+	psresources "github.com/google/knative-gcp/pkg/reconciler/intevents/pullsubscription/resources"
 	"github.com/google/knative-gcp/pkg/utils/authcheck"
 )
 
@@ -96,6 +101,7 @@ func newController(
 			Identity:               identity.NewIdentity(ctx, ipm, gcpas),
 			DeploymentLister:       deploymentInformer.Lister(),
 			ServiceAccountLister:   serviceAccountInformer.Lister(),
+			ConfigMapLister:        configmapinformer.Get(ctx).Lister(),
 			PullSubscriptionLister: pullSubscriptionLister,
 			ReceiveAdapterImage:    env.ReceiveAdapter,
 			CreateClientFn:         pubsub.NewClient,
@@ -125,6 +131,31 @@ func newController(
 		Handler:    controller.HandleAll(impl.EnqueueControllerOf),
 	})
 
+	enqueuePooled := func(interface{}) {
+		pss, err := pullSubscriptionLister.List(labels.Everything())
+		if err != nil {
+			logger.Error("Failed to list PullSubscriptions", zap.Error(err))
+			return
+		}
+		for _, ps := range pss {
+			if psresources.UsesAdapterPool(ps) {
+				impl.Enqueue(ps)
+			}
+		}
+	}
+	// Whenever the receive adapter pool changes, enqueue the PullSubscriptions using it, so that
+	// their status reflects its availability.
+	deploymentInformer.Informer().AddEventHandler(cache.FilteringResourceEventHandler{
+		FilterFunc: controller.FilterWithNameAndNamespace(system.Namespace(), pool.Name),
+		Handler:    controller.HandleAll(enqueuePooled),
+	})
+	// Whenever the allow list of the pool changes, enqueue the PullSubscriptions using it, so that
+	// they join or leave the pool.
+	configmapinformer.Get(ctx).Informer().AddEventHandler(cache.FilteringResourceEventHandler{
+		FilterFunc: controller.FilterWithNameAndNamespace(system.Namespace(), pool.AllowListName),
+		Handler:    controller.HandleAll(enqueuePooled),
+	})
+
 	// Watch k8s service account, if a k8s service account resource changes, enqueue qualified pullsubscriptions from the same namespace.
 	serviceAccountInformer.Informer().AddEventHandler(authcheck.EnqueuePullSubscription(impl, pullSubscriptionLister))
 
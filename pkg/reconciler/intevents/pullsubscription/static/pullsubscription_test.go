@@ -51,10 +51,13 @@ import (
 	"knative.dev/pkg/controller"
 	. "knative.dev/pkg/reconciler/testing"
 	"knative.dev/pkg/resolver"
+	"knative.dev/pkg/system"
 
+	"github.com/google/knative-gcp/pkg/apis/duck"
 	gcpduckv1 "github.com/google/knative-gcp/pkg/apis/duck/v1"
 	pubsubv1 "github.com/google/knative-gcp/pkg/apis/intevents/v1"
 	"github.com/google/knative-gcp/pkg/client/injection/reconciler/intevents/v1/pullsubscription"
+	"github.com/google/knative-gcp/pkg/pubsub/adapter/pool"
 	"github.com/google/knative-gcp/pkg/reconciler"
 	psreconciler "github.com/google/knative-gcp/pkg/reconciler/intevents/pullsubscription"
 	"github.com/google/knative-gcp/pkg/reconciler/intevents/pullsubscription/resources"
@@ -571,6 +574,316 @@ func TestAllCases(t *testing.T) {
 		PostConditions: []func(*testing.T, *TableRow){
 			OnlySubscriptions(testSubscriptionID),
 		},
+	}, {
+		Name: "successfully joined the adapter pool",
+		// The adapter pool is in the system namespace.
+		SkipNamespaceValidation: true,
+		Objects: []runtime.Object{
+			reconcilertestingv1.NewPullSubscription(sourceName, testNS,
+				reconcilertestingv1.WithPullSubscriptionUID(sourceUID),
+				reconcilertestingv1.WithPullSubscriptionObjectMetaGeneration(generation),
+				reconcilertestingv1.WithPullSubscriptionAnnotations(map[string]string{duck.AdapterModeAnnotation: duck.AdapterModePool}),
+				reconcilertestingv1.WithPullSubscriptionSpec(pubsubv1.PullSubscriptionSpec{
+					PubSubSpec: gcpduckv1.PubSubSpec{
+						Secret:  &secret,
+						Project: testProject,
+					},
+					Topic: testTopicID,
+				}),
+				reconcilertestingv1.WithInitPullSubscriptionConditions,
+				reconcilertestingv1.WithPullSubscriptionSink(sinkGVK, sinkName),
+				reconcilertestingv1.WithPullSubscriptionMarkSink(sinkURI),
+				reconcilertestingv1.WithPullSubscriptionSetDefaults,
+			),
+			newSink(),
+			newSecret(),
+			newReceiveAdapter(context.Background(), testImage, nil),
+			newAdapterPoolAllowList(testNS),
+		},
+		Key: testNS + "/" + sourceName,
+		WantEvents: []string{
+			Eventf(corev1.EventTypeNormal, "FinalizerUpdate", "Updated %q finalizers", sourceName),
+			Eventf(corev1.EventTypeNormal, "PullSubscriptionReconciled", `PullSubscription reconciled: "%s/%s"`, testNS, sourceName),
+		},
+		OtherTestData: map[string]interface{}{
+			"pre": []PubsubAction{
+				Topic(testTopicID),
+			},
+		},
+		WantCreates: []runtime.Object{
+			newAdapterPoolConfigMap(newAdapterPoolEntry()),
+			newAdapterPool(),
+			resources.MakeAdapterPoolHPA(newAdapterPool()),
+		},
+		WantDeletes: []clientgotesting.DeleteActionImpl{{
+			ActionImpl: clientgotesting.ActionImpl{
+				Namespace: testNS,
+				Verb:      "delete",
+				Resource:  schema.GroupVersionResource{Group: "apps", Version: "v1", Resource: "deployments"},
+			},
+			Name: deploymentName(),
+		}},
+		WantStatusUpdates: []clientgotesting.UpdateActionImpl{{
+			Object: reconcilertestingv1.NewPullSubscription(sourceName, testNS,
+				reconcilertestingv1.WithPullSubscriptionUID(sourceUID),
+				reconcilertestingv1.WithPullSubscriptionObjectMetaGeneration(generation),
+				reconcilertestingv1.WithPullSubscriptionAnnotations(map[string]string{duck.AdapterModeAnnotation: duck.AdapterModePool}),
+				reconcilertestingv1.WithPullSubscriptionSpec(pubsubv1.PullSubscriptionSpec{
+					PubSubSpec: gcpduckv1.PubSubSpec{
+						Secret:  &secret,
+						Project: testProject,
+					},
+					Topic: testTopicID,
+				}),
+				reconcilertestingv1.WithInitPullSubscriptionConditions,
+				reconcilertestingv1.WithPullSubscriptionProjectID(testProject),
+				reconcilertestingv1.WithPullSubscriptionSink(sinkGVK, sinkName),
+				reconcilertestingv1.WithPullSubscriptionMarkSink(sinkURI),
+				reconcilertestingv1.WithPullSubscriptionMarkNoTransformer("TransformerNil", "Transformer is nil"),
+				reconcilertestingv1.WithPullSubscriptionTransformerURI(nil),
+				// Updates
+				reconcilertestingv1.WithPullSubscriptionStatusObservedGeneration(generation),
+				reconcilertestingv1.WithPullSubscriptionMarkSubscribed(testSubscriptionID),
+				reconcilertestingv1.WithPullSubscriptionMarkNoDeployed(pool.Name, system.Namespace()),
+				reconcilertestingv1.WithPullSubscriptionSetDefaults,
+			),
+		}},
+		WantPatches: []clientgotesting.PatchActionImpl{
+			patchFinalizers(testNS, sourceName, resourceGroup),
+		},
+		PostConditions: []func(*testing.T, *TableRow){
+			OnlySubscriptions(testSubscriptionID),
+		},
+	}, {
+		Name: "joined the adapter pool with its receive settings",
+		// The adapter pool is in the system namespace.
+		SkipNamespaceValidation: true,
+		Objects: []runtime.Object{
+			reconcilertestingv1.NewPullSubscription(sourceName, testNS,
+				reconcilertestingv1.WithPullSubscriptionUID(sourceUID),
+				reconcilertestingv1.WithPullSubscriptionObjectMetaGeneration(generation),
+				reconcilertestingv1.WithPullSubscriptionAnnotations(map[string]string{duck.AdapterModeAnnotation: duck.AdapterModePool}),
+				reconcilertestingv1.WithPullSubscriptionSpec(pubsubv1.PullSubscriptionSpec{
+					PubSubSpec: gcpduckv1.PubSubSpec{
+						Secret:  &secret,
+						Project: testProject,
+					},
+					Topic: testTopicID,
+				}),
+				reconcilertestingv1.WithInitPullSubscriptionConditions,
+				reconcilertestingv1.WithPullSubscriptionSink(sinkGVK, sinkName),
+				reconcilertestingv1.WithPullSubscriptionMarkSink(sinkURI),
+				reconcilertestingv1.WithPullSubscriptionSetDefaults,
+			),
+			newSink(),
+			newSecret(),
+			newReceiveAdapter(context.Background(), testImage, nil),
+			&corev1.ConfigMap{
+				ObjectMeta: metav1.ObjectMeta{
+					Namespace: system.Namespace(),
+					Name:      pool.AllowListName,
+				},
+				Data: map[string]string{
+					pool.AllowedNamespacesKey:      testNS,
+					pool.MaxOutstandingMessagesKey: "10",
+				},
+			},
+		},
+		Key: testNS + "/" + sourceName,
+		WantEvents: []string{
+			Eventf(corev1.EventTypeNormal, "FinalizerUpdate", "Updated %q finalizers", sourceName),
+			Eventf(corev1.EventTypeNormal, "PullSubscriptionReconciled", `PullSubscription reconciled: "%s/%s"`, testNS, sourceName),
+		},
+		OtherTestData: map[string]interface{}{
+			"pre": []PubsubAction{
+				Topic(testTopicID),
+			},
+		},
+		WantCreates: []runtime.Object{
+			newAdapterPoolConfigMapWithSettings(newAdapterPoolEntry(), &pool.ReceiveSettings{MaxOutstandingMessages: 10}),
+			newAdapterPool(),
+			resources.MakeAdapterPoolHPA(newAdapterPool()),
+		},
+		WantDeletes: []clientgotesting.DeleteActionImpl{{
+			ActionImpl: clientgotesting.ActionImpl{
+				Namespace: testNS,
+				Verb:      "delete",
+				Resource:  schema.GroupVersionResource{Group: "apps", Version: "v1", Resource: "deployments"},
+			},
+			Name: deploymentName(),
+		}},
+		WantStatusUpdates: []clientgotesting.UpdateActionImpl{{
+			Object: reconcilertestingv1.NewPullSubscription(sourceName, testNS,
+				reconcilertestingv1.WithPullSubscriptionUID(sourceUID),
+				reconcilertestingv1.WithPullSubscriptionObjectMetaGeneration(generation),
+				reconcilertestingv1.WithPullSubscriptionAnnotations(map[string]string{duck.AdapterModeAnnotation: duck.AdapterModePool}),
+				reconcilertestingv1.WithPullSubscriptionSpec(pubsubv1.PullSubscriptionSpec{
+					PubSubSpec: gcpduckv1.PubSubSpec{
+						Secret:  &secret,
+						Project: testProject,
+					},
+					Topic: testTopicID,
+				}),
+				reconcilertestingv1.WithInitPullSubscriptionConditions,
+				reconcilertestingv1.WithPullSubscriptionProjectID(testProject),
+				reconcilertestingv1.WithPullSubscriptionSink(sinkGVK, sinkName),
+				reconcilertestingv1.WithPullSubscriptionMarkSink(sinkURI),
+				reconcilertestingv1.WithPullSubscriptionMarkNoTransformer("TransformerNil", "Transformer is nil"),
+				reconcilertestingv1.WithPullSubscriptionTransformerURI(nil),
+				// Updates
+				reconcilertestingv1.WithPullSubscriptionStatusObservedGeneration(generation),
+				reconcilertestingv1.WithPullSubscriptionMarkSubscribed(testSubscriptionID),
+				reconcilertestingv1.WithPullSubscriptionMarkNoDeployed(pool.Name, system.Namespace()),
+				reconcilertestingv1.WithPullSubscriptionSetDefaults,
+			),
+		}},
+		WantPatches: []clientgotesting.PatchActionImpl{
+			patchFinalizers(testNS, sourceName, resourceGroup),
+		},
+		PostConditions: []func(*testing.T, *TableRow){
+			OnlySubscriptions(testSubscriptionID),
+		},
+	}, {
+		Name: "namespace not allowed to use the adapter pool",
+		// The adapter pool is in the system namespace.
+		SkipNamespaceValidation: true,
+		Objects: []runtime.Object{
+			reconcilertestingv1.NewPullSubscription(sourceName, testNS,
+				reconcilertestingv1.WithPullSubscriptionUID(sourceUID),
+				reconcilertestingv1.WithPullSubscriptionObjectMetaGeneration(generation),
+				reconcilertestingv1.WithPullSubscriptionAnnotations(map[string]string{duck.AdapterModeAnnotation: duck.AdapterModePool}),
+				reconcilertestingv1.WithPullSubscriptionSpec(pubsubv1.PullSubscriptionSpec{
+					PubSubSpec: gcpduckv1.PubSubSpec{
+						Secret:  &secret,
+						Project: testProject,
+					},
+					Topic: testTopicID,
+				}),
+				reconcilertestingv1.WithInitPullSubscriptionConditions,
+				reconcilertestingv1.WithPullSubscriptionSink(sinkGVK, sinkName),
+				reconcilertestingv1.WithPullSubscriptionMarkSink(sinkURI),
+				reconcilertestingv1.WithPullSubscriptionSetDefaults,
+			),
+			newSink(),
+			newSecret(),
+			newAdapterPoolConfigMap(newAdapterPoolEntry()),
+			newAdapterPoolAllowList("other-namespace"),
+		},
+		Key: testNS + "/" + sourceName,
+		WantEvents: []string{
+			Eventf(corev1.EventTypeNormal, "FinalizerUpdate", "Updated %q finalizers", sourceName),
+			Eventf(corev1.EventTypeWarning, "DataPlaneReconcileFailed", `Failed to reconcile Data Plane resource(s): namespace %q is not in the %q allow list of the adapter pool`, testNS, pool.AllowListName),
+		},
+		OtherTestData: map[string]interface{}{
+			"pre": []PubsubAction{
+				Topic(testTopicID),
+			},
+		},
+		// The entry the PullSubscription had in the pool is removed.
+		WantUpdates: []clientgotesting.UpdateActionImpl{{
+			Object: newAdapterPoolConfigMap(nil),
+		}},
+		WantStatusUpdates: []clientgotesting.UpdateActionImpl{{
+			Object: reconcilertestingv1.NewPullSubscription(sourceName, testNS,
+				reconcilertestingv1.WithPullSubscriptionUID(sourceUID),
+				reconcilertestingv1.WithPullSubscriptionObjectMetaGeneration(generation),
+				reconcilertestingv1.WithPullSubscriptionAnnotations(map[string]string{duck.AdapterModeAnnotation: duck.AdapterModePool}),
+				reconcilertestingv1.WithPullSubscriptionSpec(pubsubv1.PullSubscriptionSpec{
+					PubSubSpec: gcpduckv1.PubSubSpec{
+						Secret:  &secret,
+						Project: testProject,
+					},
+					Topic: testTopicID,
+				}),
+				reconcilertestingv1.WithInitPullSubscriptionConditions,
+				reconcilertestingv1.WithPullSubscriptionProjectID(testProject),
+				reconcilertestingv1.WithPullSubscriptionSink(sinkGVK, sinkName),
+				reconcilertestingv1.WithPullSubscriptionMarkSink(sinkURI),
+				reconcilertestingv1.WithPullSubscriptionMarkNoTransformer("TransformerNil", "Transformer is nil"),
+				reconcilertestingv1.WithPullSubscriptionTransformerURI(nil),
+				// Updates
+				reconcilertestingv1.WithPullSubscriptionStatusObservedGeneration(generation),
+				reconcilertestingv1.WithPullSubscriptionMarkSubscribed(testSubscriptionID),
+				reconcilertestingv1.WithPullSubscriptionMarkDeployedFailed("AdapterPoolNotAllowed", fmt.Sprintf("Namespace %q is not allowed to use the adapter pool", testNS)),
+				reconcilertestingv1.WithPullSubscriptionSetDefaults,
+			),
+		}},
+		WantPatches: []clientgotesting.PatchActionImpl{
+			patchFinalizers(testNS, sourceName, resourceGroup),
+		},
+		PostConditions: []func(*testing.T, *TableRow){
+			OnlySubscriptions(testSubscriptionID),
+		},
+	}, {
+		Name: "successfully left the adapter pool",
+		// The adapter pool is in the system namespace.
+		SkipNamespaceValidation: true,
+		Objects: []runtime.Object{
+			reconcilertestingv1.NewPullSubscription(sourceName, testNS,
+				reconcilertestingv1.WithPullSubscriptionUID(sourceUID),
+				reconcilertestingv1.WithPullSubscriptionObjectMetaGeneration(generation),
+				reconcilertestingv1.WithPullSubscriptionSpec(pubsubv1.PullSubscriptionSpec{
+					PubSubSpec: gcpduckv1.PubSubSpec{
+						Secret:  &secret,
+						Project: testProject,
+					},
+					Topic: testTopicID,
+				}),
+				reconcilertestingv1.WithInitPullSubscriptionConditions,
+				reconcilertestingv1.WithPullSubscriptionSink(sinkGVK, sinkName),
+				reconcilertestingv1.WithPullSubscriptionMarkSink(sinkURI),
+				reconcilertestingv1.WithPullSubscriptionSetDefaults,
+			),
+			newSink(),
+			newSecret(),
+			newAdapterPoolConfigMap(newAdapterPoolEntry()),
+		},
+		Key: testNS + "/" + sourceName,
+		WantEvents: []string{
+			Eventf(corev1.EventTypeNormal, "FinalizerUpdate", "Updated %q finalizers", sourceName),
+			Eventf(corev1.EventTypeNormal, "PullSubscriptionReconciled", `PullSubscription reconciled: "%s/%s"`, testNS, sourceName),
+		},
+		OtherTestData: map[string]interface{}{
+			"pre": []PubsubAction{
+				Topic(testTopicID),
+			},
+		},
+		WantCreates: []runtime.Object{
+			newReceiveAdapter(context.Background(), testImage, nil),
+		},
+		WantUpdates: []clientgotesting.UpdateActionImpl{{
+			Object: newAdapterPoolConfigMap(nil),
+		}},
+		WantStatusUpdates: []clientgotesting.UpdateActionImpl{{
+			Object: reconcilertestingv1.NewPullSubscription(sourceName, testNS,
+				reconcilertestingv1.WithPullSubscriptionUID(sourceUID),
+				reconcilertestingv1.WithPullSubscriptionObjectMetaGeneration(generation),
+				reconcilertestingv1.WithPullSubscriptionSpec(pubsubv1.PullSubscriptionSpec{
+					PubSubSpec: gcpduckv1.PubSubSpec{
+						Secret:  &secret,
+						Project: testProject,
+					},
+					Topic: testTopicID,
+				}),
+				reconcilertestingv1.WithInitPullSubscriptionConditions,
+				reconcilertestingv1.WithPullSubscriptionProjectID(testProject),
+				reconcilertestingv1.WithPullSubscriptionSink(sinkGVK, sinkName),
+				reconcilertestingv1.WithPullSubscriptionMarkSink(sinkURI),
+				reconcilertestingv1.WithPullSubscriptionMarkNoTransformer("TransformerNil", "Transformer is nil"),
+				reconcilertestingv1.WithPullSubscriptionTransformerURI(nil),
+				// Updates
+				reconcilertestingv1.WithPullSubscriptionStatusObservedGeneration(generation),
+				reconcilertestingv1.WithPullSubscriptionMarkSubscribed(testSubscriptionID),
+				reconcilertestingv1.WithPullSubscriptionMarkNoDeployed(deploymentName(), testNS),
+				reconcilertestingv1.WithPullSubscriptionSetDefaults,
+			),
+		}},
+		WantPatches: []clientgotesting.PatchActionImpl{
+			patchFinalizers(testNS, sourceName, resourceGroup),
+		},
+		PostConditions: []func(*testing.T, *TableRow){
+			OnlySubscriptions(testSubscriptionID),
+		},
 	}, {
 		Name: "sink namespace empty, default to the source one",
 		Objects: []runtime.Object{
@@ -1344,6 +1657,47 @@ func TestAllCases(t *testing.T) {
 		},
 		Key:        testNS + "/" + sourceName,
 		WantEvents: nil,
+	}, {
+		Name: "successfully deleted pooled subscription",
+		// The adapter pool is in the system namespace.
+		SkipNamespaceValidation: true,
+		Objects: []runtime.Object{
+			reconcilertestingv1.NewPullSubscription(sourceName, testNS,
+				reconcilertestingv1.WithPullSubscriptionUID(sourceUID),
+				reconcilertestingv1.WithPullSubscriptionObjectMetaGeneration(generation),
+				reconcilertestingv1.WithPullSubscriptionStatusObservedGeneration(generation),
+				reconcilertestingv1.WithPullSubscriptionAnnotations(map[string]string{duck.AdapterModeAnnotation: duck.AdapterModePool}),
+				reconcilertestingv1.WithPullSubscriptionSpec(pubsubv1.PullSubscriptionSpec{
+					PubSubSpec: gcpduckv1.PubSubSpec{
+						Secret:  &secret,
+						Project: testProject,
+					},
+					Topic: testTopicID,
+				}),
+				reconcilertestingv1.WithPullSubscriptionSink(sinkGVK, sinkName),
+				reconcilertestingv1.WithPullSubscriptionMarkSubscribed(testSubscriptionID),
+				reconcilertestingv1.WithPullSubscriptionMarkDeployed(pool.Name, system.Namespace()),
+				reconcilertestingv1.WithPullSubscriptionMarkSink(sinkURI),
+				reconcilertestingv1.WithPullSubscriptionProjectID(testProject),
+				reconcilertestingv1.WithPullSubscriptionDeleted,
+				reconcilertestingv1.WithPullSubscriptionSetDefaults,
+			),
+			newSecret(),
+			newAdapterPoolConfigMap(newAdapterPoolEntry()),
+		},
+		OtherTestData: map[string]interface{}{
+			"pre": []PubsubAction{
+				TopicAndSub(testTopicID, testSubscriptionID),
+			},
+		},
+		WantUpdates: []clientgotesting.UpdateActionImpl{{
+			Object: newAdapterPoolConfigMap(nil),
+		}},
+		PostConditions: []func(*testing.T, *TableRow){
+			NoSubscriptionsExist(),
+		},
+		Key:        testNS + "/" + sourceName,
+		WantEvents: nil,
 	}}
 
 	table.Test(t, MakeFactory(func(ctx context.Context, listers *Listers, cmw configmap.Watcher, testData map[string]interface{}) controller.Reconciler {
@@ -1389,6 +1743,7 @@ func TestAllCases(t *testing.T) {
 				Base:                   reconciler.NewBase(ctx, controllerAgentName, cmw),
 				DeploymentLister:       listers.GetDeploymentLister(),
 				PullSubscriptionLister: listers.GetPullSubscriptionLister(),
+				ConfigMapLister:        listers.GetConfigMapLister(),
 				UriResolver:            resolver.NewURIResolver(ctx, func(types.NamespacedName) {}),
 				ReceiveAdapterImage:    testImage,
 				CreateClientFn:         createClientFn,
@@ -1450,6 +1805,55 @@ func newPullSubscription() *pubsubv1.PullSubscription {
 	)
 }
 
+// newAdapterPoolEntry is the adapter pool entry of the PullSubscription once its subscription is
+// reconciled.
+func newAdapterPoolEntry() *pool.Entry {
+	ps := newPullSubscription()
+	ps.Status.ProjectID = testProject
+	return resources.MakeAdapterPoolEntry(&resources.ReceiveAdapterArgs{
+		PullSubscription: ps,
+		SubscriptionID:   testSubscriptionID,
+		SinkURI:          sinkURI,
+	})
+}
+
+// newAdapterPoolConfigMap is the adapter pool config with the entry of the PullSubscription, or
+// without entries if entry is nil.
+func newAdapterPoolConfigMap(entry *pool.Entry) runtime.Object {
+	return newAdapterPoolConfigMapWithSettings(entry, nil)
+}
+
+// newAdapterPoolConfigMapWithSettings is the adapter pool config of newAdapterPoolConfigMap, with
+// the receive settings of the pool.
+func newAdapterPoolConfigMapWithSettings(entry *pool.Entry, settings *pool.ReceiveSettings) runtime.Object {
+	cfg := &pool.Config{Entries: map[string]*pool.Entry{}, ReceiveSettings: settings}
+	if entry != nil {
+		cfg.Entries[testNS+"/"+sourceName] = entry
+	}
+	cm, err := resources.MakeAdapterPoolConfigMap(system.Namespace(), cfg)
+	if err != nil {
+		panic(err)
+	}
+	return cm
+}
+
+func newAdapterPoolAllowList(namespaces string) runtime.Object {
+	return &corev1.ConfigMap{
+		ObjectMeta: metav1.ObjectMeta{
+			Namespace: system.Namespace(),
+			Name:      pool.AllowListName,
+		},
+		Data: map[string]string{pool.AllowedNamespacesKey: namespaces},
+	}
+}
+
+func newAdapterPool() *v1.Deployment {
+	return resources.MakeAdapterPool(&resources.AdapterPoolArgs{
+		Image:     testImage,
+		Namespace: system.Namespace(),
+	})
+}
+
 func receiveAdapterGVR() schema.GroupVersionResource {
 	return schema.GroupVersionResource{
 		Group:    "apps",
@@ -156,6 +156,11 @@ func (psb *PubSubBase) ReconcilePullSubscription(ctx context.Context, pubsubable
 		Annotations: resources.GetAnnotations(annotations, resourceGroup),
 	}
 
+	if convertible, ok := pubsubable.(duck.Convertible); ok {
+		args.Mapping = convertible.EventMapping()
+		args.RawPayload = convertible.RawPayload()
+	}
+
 	if v, present := pubsubable.GetObjectMeta().GetAnnotations()[testloggingutil.LoggingE2ETestAnnotation]; present {
 		// This is added purely for the TestCloudLogging E2E tests, which verify that the log line
 		// is written if this annotation is present.
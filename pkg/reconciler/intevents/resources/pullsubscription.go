@@ -32,6 +32,10 @@ type PullSubscriptionArgs struct {
 	Owner       kmeta.OwnerRefable
 	Topic       string
 	AdapterType string
+	// Mapping is the optional mapping of the messages into events.
+	Mapping *gcpduckv1.EventMapping
+	// RawPayload is the optional raw payload mode of the events.
+	RawPayload  *gcpduckv1.RawPayload
 	Labels      map[string]string
 	Annotations map[string]string
 }
@@ -52,14 +56,21 @@ func MakePullSubscription(args *PullSubscriptionArgs) *inteventsv1.PullSubscript
 				IdentitySpec: gcpduckv1.IdentitySpec{
 					ServiceAccountName: args.Spec.IdentitySpec.ServiceAccountName,
 				},
-				Secret:  args.Spec.Secret,
-				Project: args.Spec.Project,
+				Secret:                    args.Spec.Secret,
+				Project:                   args.Spec.Project,
+				Delivery:                  args.Spec.Delivery,
+				EnableMessageOrdering:     args.Spec.EnableMessageOrdering,
+				EnableExactlyOnceDelivery: args.Spec.EnableExactlyOnceDelivery,
+				Filter:                    args.Spec.Filter,
+				ExpirationTTL:             args.Spec.ExpirationTTL,
 				SourceSpec: duckv1.SourceSpec{
 					Sink: args.Spec.SourceSpec.Sink,
 				},
 			},
 			Topic:       args.Topic,
 			AdapterType: args.AdapterType,
+			Mapping:     args.Mapping,
+			RawPayload:  args.RawPayload,
 		},
 	}
 	if args.Spec.CloudEventOverrides != nil && args.Spec.CloudEventOverrides.Extensions != nil {
@@ -336,7 +336,7 @@ func TestAllCasesChannel(t *testing.T) {
 				),
 			},
 		},
-		WantCreates:             []runtime.Object{resources.CreateBrokerCell(nil) /*Currently brokerCell doesn't require channel information*/},
+		WantCreates:             []runtime.Object{resources.CreateBrokerCell(resources.DefaultBrokerCellName)},
 		SkipNamespaceValidation: true, // The brokerCell resource is created in a different namespace (system namespace) than the channel
 		WantEvents: []string{
 			channelFinalizerUpdatedEvent,
@@ -365,7 +365,7 @@ func TestAllCasesChannel(t *testing.T) {
 				),
 			},
 		},
-		WantCreates:             []runtime.Object{resources.CreateBrokerCell(nil) /*Currently brokerCell doesn't require channel information*/},
+		WantCreates:             []runtime.Object{resources.CreateBrokerCell(resources.DefaultBrokerCellName)},
 		SkipNamespaceValidation: true, // The brokerCell resource is created in a different namespace (system namespace) than the channel
 		WantEvents: []string{
 			channelFinalizerUpdatedEvent,
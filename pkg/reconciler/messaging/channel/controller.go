@@ -42,6 +42,7 @@ import (
 	channelinformer "github.com/google/knative-gcp/pkg/client/injection/informers/messaging/v1beta1/channel"
 	channelreconciler "github.com/google/knative-gcp/pkg/client/injection/reconciler/messaging/v1beta1/channel"
 	"github.com/google/knative-gcp/pkg/reconciler"
+	reconcilerutils "github.com/google/knative-gcp/pkg/reconciler/utils"
 )
 
 const (
@@ -121,17 +122,18 @@ func newController(
 func filterChannelsForBrokerCell(
 	logger *zap.Logger, channelInformer channellister.ChannelLister, enqueue func(interface{})) func(obj interface{}) {
 	return func(obj interface{}) {
-		if _, ok := obj.(*inteventsv1alpha1.BrokerCell); ok {
-			// TODO(#866) Only select brokers that point to this brokercell by label selector once the
-			// webhook assigns the brokercell label, i.e.,
-			// r.brokerLister.List(labels.SelectorFromSet(map[string]string{"brokercell":bc.Name, "brokercellns":bc.Namespace}))
+		if bc, ok := obj.(*inteventsv1alpha1.BrokerCell); ok {
+			// Channels without the BrokerCell label belong to the default BrokerCell, so they
+			// can't be selected by label.
 			channels, err := channelInformer.List(labels.Everything())
 			if err != nil {
 				logger.Error("Failed to list Channels", zap.Error(err))
 				return
 			}
 			for _, channel := range channels {
-				enqueue(channel)
+				if reconcilerutils.InBrokerCell(channel, bc) {
+					enqueue(channel)
+				}
 			}
 		}
 	}
@@ -61,17 +61,28 @@ func TestFilterChannelsForBrokerCell(t *testing.T) {
 				channel("foo"),
 			},
 		},
-		"all channels are enqueued": {
-			objectChanged: &v1alpha1.BrokerCell{},
+		"all channels of the BrokerCell are enqueued": {
+			objectChanged: &v1alpha1.BrokerCell{ObjectMeta: v1.ObjectMeta{Name: v1alpha1.DefaultBrokerCellName}},
 			channels: []runtime.Object{
 				channel("foo"),
 				channel("bar"),
+				channelInBrokerCell("baz", "other"),
 			},
 			wantEnqueued: []interface{}{
 				channel("foo"),
 				channel("bar"),
 			},
 		},
+		"channels of another BrokerCell are enqueued": {
+			objectChanged: &v1alpha1.BrokerCell{ObjectMeta: v1.ObjectMeta{Name: "other"}},
+			channels: []runtime.Object{
+				channel("foo"),
+				channelInBrokerCell("baz", "other"),
+			},
+			wantEnqueued: []interface{}{
+				channelInBrokerCell("baz", "other"),
+			},
+		},
 	}
 	for n, tc := range testCases {
 		t.Run(n, func(t *testing.T) {
@@ -100,6 +111,12 @@ func channel(name string) *v1beta1.Channel {
 	}
 }
 
+func channelInBrokerCell(name, brokerCell string) *v1beta1.Channel {
+	c := channel(name)
+	c.Labels = map[string]string{v1alpha1.BrokerCellLabelKey: brokerCell}
+	return c
+}
+
 type fakeImpl struct {
 	enqueued []interface{}
 }
@@ -21,6 +21,7 @@ import (
 	"time"
 
 	brokerv1 "github.com/google/knative-gcp/pkg/apis/broker/v1"
+	inteventsv1alpha1 "github.com/google/knative-gcp/pkg/apis/intevents/v1alpha1"
 	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
 	"k8s.io/apimachinery/pkg/types"
 	eventingduckv1 "knative.dev/eventing/pkg/apis/duck/v1"
@@ -54,6 +55,25 @@ func WithInitBrokerConditions(b *brokerv1.Broker) {
 	b.Status.InitializeConditions()
 }
 
+func WithBrokerAnnotations(annotations map[string]string) BrokerOption {
+	return func(b *brokerv1.Broker) {
+		merged := b.GetAnnotations()
+		if merged == nil {
+			merged = make(map[string]string, len(annotations))
+		}
+		for k, v := range annotations {
+			merged[k] = v
+		}
+		b.SetAnnotations(merged)
+	}
+}
+
+func WithBrokerLastReplay(applied string) BrokerOption {
+	return func(b *brokerv1.Broker) {
+		b.Status.SetLastReplay(applied)
+	}
+}
+
 func WithBrokerFinalizers(finalizers ...string) BrokerOption {
 	return func(b *brokerv1.Broker) {
 		b.Finalizers = finalizers
@@ -146,6 +166,14 @@ func WithBrokerTopicReady(b *brokerv1.Broker) {
 	b.Status.MarkTopicReady()
 }
 
+// WithBrokerDataPlaneObservedGeneration propagates the generation of the Broker observed by the
+// data plane.
+func WithBrokerDataPlaneObservedGeneration(observed int64) BrokerOption {
+	return func(b *brokerv1.Broker) {
+		b.Status.PropagateDataPlaneObservedGeneration(observed, b.Generation)
+	}
+}
+
 func WithBrokerTopicUnknown(reason, msg string) BrokerOption {
 	return func(b *brokerv1.Broker) {
 		b.Status.MarkTopicUnknown(reason, msg)
@@ -158,6 +186,16 @@ func WithBrokerSubscriptionUnknown(reason, msg string) BrokerOption {
 	}
 }
 
+// WithBrokerBrokerCell selects the BrokerCell serving the Broker.
+func WithBrokerBrokerCell(name string) BrokerOption {
+	return func(b *brokerv1.Broker) {
+		if b.Labels == nil {
+			b.Labels = make(map[string]string, 1)
+		}
+		b.Labels[inteventsv1alpha1.BrokerCellLabelKey] = name
+	}
+}
+
 func WithBrokerClass(bc string) BrokerOption {
 	return func(b *brokerv1.Broker) {
 		annotations := b.GetAnnotations()
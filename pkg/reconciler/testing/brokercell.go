@@ -48,6 +48,27 @@ func WithBrokerCellAnnotations(annotations map[string]string) BrokerCellOption {
 	}
 }
 
+// WithBrokerCellSharding sets whether the Brokers and Triggers are sharded.
+func WithBrokerCellSharding(enabled bool) BrokerCellOption {
+	return func(bc *intv1alpha1.BrokerCell) {
+		bc.Spec.Sharding = &enabled
+	}
+}
+
+// WithBrokerCellTargetsConfig sets how the targets config is split between ConfigMaps.
+func WithBrokerCellTargetsConfig(shards int32, compression intv1alpha1.TargetsConfigCompression) BrokerCellOption {
+	return func(bc *intv1alpha1.BrokerCell) {
+		bc.Spec.TargetsConfig = &intv1alpha1.TargetsConfigSpec{Shards: &shards, Compression: compression}
+	}
+}
+
+// WithBrokerCellClaimCheckBuckets sets the buckets the claim checks of the Brokers may use.
+func WithBrokerCellClaimCheckBuckets(buckets ...string) BrokerCellOption {
+	return func(bc *intv1alpha1.BrokerCell) {
+		bc.Spec.ClaimCheckBuckets = buckets
+	}
+}
+
 // WithInitBrokerCellConditions initializes the BrokerCell's conditions.
 func WithInitBrokerCellConditions(bc *intv1alpha1.BrokerCell) {
 	bc.Status.InitializeConditions()
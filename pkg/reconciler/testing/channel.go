@@ -28,6 +28,7 @@ import (
 
 	eventingduckv1 "knative.dev/eventing/pkg/apis/duck/v1"
 
+	inteventsv1alpha1 "github.com/google/knative-gcp/pkg/apis/intevents/v1alpha1"
 	"github.com/google/knative-gcp/pkg/apis/messaging/v1beta1"
 )
 
@@ -137,6 +138,16 @@ func WithChannelSetDefaults(c *v1beta1.Channel) {
 	c.SetDefaults(gcpauthtesthelper.ContextWithDefaults())
 }
 
+// WithChannelBrokerCell selects the BrokerCell serving the Channel.
+func WithChannelBrokerCell(name string) ChannelOption {
+	return func(c *v1beta1.Channel) {
+		if c.Labels == nil {
+			c.Labels = make(map[string]string, 1)
+		}
+		c.Labels[inteventsv1alpha1.BrokerCellLabelKey] = name
+	}
+}
+
 func WithChannelDeletionTimestamp(c *v1beta1.Channel) {
 	t := metav1.NewTime(time.Unix(1e9, 0))
 	c.ObjectMeta.SetDeletionTimestamp(&t)
@@ -21,6 +21,7 @@ import (
 	hpav2beta2 "k8s.io/api/autoscaling/v2beta2"
 	batchv1 "k8s.io/api/batch/v1"
 	corev1 "k8s.io/api/core/v1"
+	policyv1beta1 "k8s.io/api/policy/v1beta1"
 	rbacv1 "k8s.io/api/rbac/v1"
 	"k8s.io/apimachinery/pkg/apis/meta/v1/unstructured"
 	"k8s.io/apimachinery/pkg/runtime"
@@ -29,6 +30,7 @@ import (
 	hpav2beta2listers "k8s.io/client-go/listers/autoscaling/v2beta2"
 	batchv1listers "k8s.io/client-go/listers/batch/v1"
 	corev1listers "k8s.io/client-go/listers/core/v1"
+	policyv1beta1listers "k8s.io/client-go/listers/policy/v1beta1"
 	rbacv1listers "k8s.io/client-go/listers/rbac/v1"
 	"k8s.io/client-go/tools/cache"
 
@@ -209,3 +211,7 @@ func (l *Listers) GetBrokerCellLister() intlisters.BrokerCellLister {
 func (l *Listers) GetHPALister() hpav2beta2listers.HorizontalPodAutoscalerLister {
 	return hpav2beta2listers.NewHorizontalPodAutoscalerLister(l.indexerFor(&hpav2beta2.HorizontalPodAutoscaler{}))
 }
+
+func (l *Listers) GetPodDisruptionBudgetLister() policyv1beta1listers.PodDisruptionBudgetLister {
+	return policyv1beta1listers.NewPodDisruptionBudgetLister(l.indexerFor(&policyv1beta1.PodDisruptionBudget{}))
+}
@@ -20,10 +20,12 @@ import (
 	"context"
 	"fmt"
 	"testing"
+	"time"
 
 	"cloud.google.com/go/pubsub"
 	"cloud.google.com/go/pubsub/pstest"
 	"github.com/google/go-cmp/cmp"
+	"github.com/google/go-cmp/cmp/cmpopts"
 	"google.golang.org/api/iterator"
 	"google.golang.org/api/option"
 	"google.golang.org/grpc"
@@ -95,7 +97,7 @@ func TopicExistsWithConfig(id string, expectedTopicConfig *pubsub.TopicConfig) f
 			t.Errorf("Expected topic %q to exist", id)
 		}
 		topicConfig, err := topic.Config(context.Background())
-		if diff := cmp.Diff(*expectedTopicConfig, topicConfig); diff != "" {
+		if diff := cmp.Diff(*expectedTopicConfig, topicConfig, cmpopts.IgnoreUnexported(pubsub.TopicConfig{})); diff != "" {
 			t.Errorf("Wrong topic config expected %v, got %v", *expectedTopicConfig, topicConfig)
 		}
 	}
@@ -183,6 +185,34 @@ func SubscriptionHasDeadLetterPolicy(id string, wantPolicy *pubsub.DeadLetterPol
 	}
 }
 
+func SubscriptionHasMessageOrdering(id string, want bool) func(*testing.T, *rtesting.TableRow) {
+	return func(t *testing.T, r *rtesting.TableRow) {
+		c := getPubsubClient(r)
+		sub := c.Subscription(id)
+		cfg, err := sub.Config(context.Background())
+		if err != nil {
+			t.Errorf("Error getting pubsub config: %v", err)
+		}
+		if cfg.EnableMessageOrdering != want {
+			t.Errorf("Pubsub config message ordering got=%v, want=%v", cfg.EnableMessageOrdering, want)
+		}
+	}
+}
+
+func SubscriptionHasRetention(id string, retainAckedMessages bool, retention time.Duration) func(*testing.T, *rtesting.TableRow) {
+	return func(t *testing.T, r *rtesting.TableRow) {
+		c := getPubsubClient(r)
+		sub := c.Subscription(id)
+		cfg, err := sub.Config(context.Background())
+		if err != nil {
+			t.Errorf("Error getting pubsub config: %v", err)
+		}
+		if cfg.RetainAckedMessages != retainAckedMessages || cfg.RetentionDuration != retention {
+			t.Errorf("Pubsub config retention got=(%v, %v), want=(%v, %v)", cfg.RetainAckedMessages, cfg.RetentionDuration, retainAckedMessages, retention)
+		}
+	}
+}
+
 func OnlySubscriptions(ids ...string) func(*testing.T, *rtesting.TableRow) {
 	return func(t *testing.T, r *rtesting.TableRow) {
 		c := getPubsubClient(r)
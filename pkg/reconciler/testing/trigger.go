@@ -23,6 +23,7 @@ import (
 	brokerv1 "github.com/google/knative-gcp/pkg/apis/broker/v1"
 	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
 	"k8s.io/apimachinery/pkg/types"
+	eventingduckv1 "knative.dev/eventing/pkg/apis/duck/v1"
 	"knative.dev/eventing/pkg/apis/eventing"
 	eventingv1 "knative.dev/eventing/pkg/apis/eventing/v1"
 	"knative.dev/pkg/apis"
@@ -150,6 +151,39 @@ func WithDependencyAnnotation(dependencyAnnotation string) TriggerOption {
 	}
 }
 
+func WithTriggerAnnotation(key, value string) TriggerOption {
+	return func(t *brokerv1.Trigger) {
+		if t.Annotations == nil {
+			t.Annotations = make(map[string]string)
+		}
+		t.Annotations[key] = value
+	}
+}
+
+func WithTriggerDeliverySpec(delivery *eventingduckv1.DeliverySpec) TriggerOption {
+	return func(t *brokerv1.Trigger) {
+		t.Spec.Delivery = delivery
+	}
+}
+
+func WithTriggerLastReplay(applied string) TriggerOption {
+	return func(t *brokerv1.Trigger) {
+		t.Status.SetLastReplay(applied)
+	}
+}
+
+func WithTriggerLastReplayTime(applied time.Time) TriggerOption {
+	return func(t *brokerv1.Trigger) {
+		t.Status.SetLastReplayTime(applied)
+	}
+}
+
+func WithTriggerReplaySubscription(id string) TriggerOption {
+	return func(t *brokerv1.Trigger) {
+		t.Status.SetReplaySubscription(id)
+	}
+}
+
 func WithTriggerDependencyReady(t *brokerv1.Trigger) {
 	t.Status.MarkDependencySucceeded()
 }
@@ -190,6 +224,14 @@ func WithTriggerTopicReady(t *brokerv1.Trigger) {
 	t.Status.MarkTopicReady()
 }
 
+// WithTriggerDataPlaneObservedGeneration propagates the generation of the Trigger observed by the
+// data plane.
+func WithTriggerDataPlaneObservedGeneration(observed int64) TriggerOption {
+	return func(t *brokerv1.Trigger) {
+		t.Status.PropagateDataPlaneObservedGeneration(observed, t.Generation)
+	}
+}
+
 func WithTriggerTopicUnknown(reason, msg string) TriggerOption {
 	return func(t *brokerv1.Trigger) {
 		t.Status.MarkTopicUnknown(reason, msg)
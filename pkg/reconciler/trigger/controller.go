@@ -24,6 +24,7 @@ import (
 	"cloud.google.com/go/pubsub"
 	"go.uber.org/zap"
 	"k8s.io/apimachinery/pkg/labels"
+	"k8s.io/apimachinery/pkg/util/clock"
 	"k8s.io/client-go/tools/cache"
 
 	"github.com/google/knative-gcp/pkg/logging"
@@ -97,6 +98,7 @@ func newController(ctx context.Context, cmw configmap.Watcher, drs *dataresidenc
 			PubsubClient:       client,
 			DataresidencyStore: drs,
 		},
+		clock: clock.RealClock{},
 	}
 
 	impl := triggerreconciler.NewImpl(ctx, r, withAgentAndFinalizer)
@@ -25,6 +25,7 @@ import (
 	"go.uber.org/zap"
 	corev1 "k8s.io/api/core/v1"
 	apierrs "k8s.io/apimachinery/pkg/api/errors"
+	"k8s.io/apimachinery/pkg/util/clock"
 
 	"github.com/google/knative-gcp/pkg/logging"
 	eventingv1 "knative.dev/eventing/pkg/apis/eventing/v1"
@@ -34,9 +35,11 @@ import (
 	"knative.dev/pkg/resolver"
 
 	brokerv1 "github.com/google/knative-gcp/pkg/apis/broker/v1"
+	inteventsv1alpha1 "github.com/google/knative-gcp/pkg/apis/intevents/v1alpha1"
 	triggerreconciler "github.com/google/knative-gcp/pkg/client/injection/reconciler/broker/v1/trigger"
 	brokerlisters "github.com/google/knative-gcp/pkg/client/listers/broker/v1"
 	"github.com/google/knative-gcp/pkg/reconciler"
+	brokerresources "github.com/google/knative-gcp/pkg/reconciler/broker/resources"
 	reconcilerutils "github.com/google/knative-gcp/pkg/reconciler/utils"
 )
 
@@ -59,6 +62,9 @@ type Reconciler struct {
 	// Dynamic tracker to track AddressableTypes. It tracks Trigger subscribers.
 	addressableTracker duck.ListableTracker
 	uriResolver        *resolver.URIResolver
+
+	// clock records when the replays of the Triggers are applied.
+	clock clock.Clock
 }
 
 // Check that TriggerReconciler implements Interface
@@ -132,15 +138,23 @@ func (r *Reconciler) reconcile(ctx context.Context, t *brokerv1.Trigger, b *brok
 		b.SetDefaults(ctx)
 	}
 
-	ct := celltenant.TargetFromTrigger(t, b.Spec.Delivery)
+	// The webhook rejects malformed orderings.
+	ordering, _ := b.GetOrdering()
+	ct := celltenant.TargetFromTrigger(t, inteventsv1alpha1.BrokerCellName(b), t.GetDeliverySpec(b), ordering != nil)
 	if err := r.targetReconciler.ReconcileRetryTopicAndSubscription(ctx, r.Recorder, ct); err != nil {
 		return err
 	}
+	if err := r.reconcileReplay(ctx, t, b, ct); err != nil {
+		return err
+	}
 
 	if err := r.checkDependencyAnnotation(ctx, t); err != nil {
 		return err
 	}
 
+	// The BrokerCell controller annotates the Trigger once its data plane has loaded it.
+	t.Status.PropagateDataPlaneObservedGeneration(brokerv1.DataPlaneObservedGeneration(t), t.Generation)
+
 	return pkgreconciler.NewEvent(corev1.EventTypeNormal, triggerReconciled, "Trigger reconciled: \"%s/%s\"", t.Namespace, t.Name)
 }
 
@@ -156,13 +170,33 @@ func (r *Reconciler) FinalizeKind(ctx context.Context, t *brokerv1.Trigger) pkgr
 	if !hasGCPBrokerFinalizer(t) {
 		return nil
 	}
-	ct := celltenant.TargetFromTrigger(t, nil)
+	ct := celltenant.TargetFromTrigger(t, "", nil, false)
 	if err := r.targetReconciler.DeleteRetryTopicAndSubscription(ctx, r.Recorder, ct); err != nil {
 		return err
 	}
+	if err := r.targetReconciler.DeleteReplaySubscription(ctx, r.Recorder, ct, brokerresources.GenerateReplaySubscriptionName(t)); err != nil {
+		return err
+	}
 	return pkgreconciler.NewEvent(corev1.EventTypeNormal, triggerFinalized, "Trigger finalized: \"%s/%s\"", t.Namespace, t.Name)
 }
 
+// reconcileReplay reconciles the replay subscription of the Trigger on the decoupling topic of
+// its Broker, and records when each replay is applied, since the data plane only replays the
+// events published before.
+func (r *Reconciler) reconcileReplay(ctx context.Context, t *brokerv1.Trigger, b *brokerv1.Broker, ct celltenant.Target) error {
+	lastReplay := t.Status.GetLastReplay()
+	// The time is taken before the subscription is seeked, so that the events published while it
+	// is seeked are only delivered by the decoupling subscription.
+	now := r.clock.Now()
+	if err := r.targetReconciler.ReconcileReplaySubscription(ctx, r.Recorder, ct, brokerresources.GenerateDecouplingTopicName(b), brokerresources.GenerateReplaySubscriptionName(t)); err != nil {
+		return err
+	}
+	if applied := t.Status.GetLastReplay(); applied != "" && applied != lastReplay {
+		t.Status.SetLastReplayTime(now)
+	}
+	return nil
+}
+
 func (r *Reconciler) resolveSubscriber(ctx context.Context, t *brokerv1.Trigger, b *brokerv1.Broker) error {
 	if t.Spec.Subscriber.Ref != nil && t.Spec.Subscriber.Ref.Namespace == "" {
 		// To call URIFromDestination(dest apisv1alpha1.Destination, parent interface{}), dest.Ref must have a Namespace
@@ -31,6 +31,7 @@ import (
 	"k8s.io/apimachinery/pkg/apis/meta/v1/unstructured"
 	"k8s.io/apimachinery/pkg/runtime"
 	"k8s.io/apimachinery/pkg/types"
+	"k8s.io/apimachinery/pkg/util/clock"
 	"k8s.io/client-go/kubernetes/scheme"
 	clientgotesting "k8s.io/client-go/testing"
 	eventingduckv1 "knative.dev/eventing/pkg/apis/duck/v1"
@@ -56,6 +57,7 @@ const (
 	testNS            = "testnamespace"
 	triggerName       = "test-trigger"
 	brokerName        = "test-broker"
+	brokerUID         = "def456"
 	testUID           = "abc123"
 	testProject       = "test-project-id"
 	testClusterRegion = "us-east1"
@@ -65,6 +67,9 @@ const (
 	subscriberName    = "subscriber-name"
 	subscriberGroup   = "serving.knative.dev"
 	subscriberVersion = "v1"
+
+	decouplingTopic    = "cre-bkr_testnamespace_test-broker_def456"
+	replaySubscription = "cre-tgr-replay_testnamespace_test-trigger_abc123"
 )
 
 var (
@@ -84,12 +89,19 @@ var (
 	subscriptionCreatedEvent       = Eventf(corev1.EventTypeNormal, "SubscriptionCreated", `Created PubSub subscription "cre-tgr_testnamespace_test-trigger_abc123"`)
 	subscriptionDeletedEvent       = Eventf(corev1.EventTypeNormal, "SubscriptionDeleted", `Deleted PubSub subscription "cre-tgr_testnamespace_test-trigger_abc123"`)
 	subscriptionConfigUpdatedEvent = Eventf(corev1.EventTypeNormal, "SubscriptionConfigUpdated", `Updated config for PubSub subscription "cre-tgr_testnamespace_test-trigger_abc123"`)
+	replaySubscriptionCreatedEvent = Eventf(corev1.EventTypeNormal, "SubscriptionCreated", `Created PubSub subscription "cre-tgr-replay_testnamespace_test-trigger_abc123"`)
+	replaySubscriptionDeletedEvent = Eventf(corev1.EventTypeNormal, "SubscriptionDeleted", `Deleted PubSub subscription "cre-tgr-replay_testnamespace_test-trigger_abc123"`)
+	replaySubscriptionSeekedEvent  = Eventf(corev1.EventTypeNormal, "SubscriptionSeeked", `Seeked PubSub subscription "cre-tgr-replay_testnamespace_test-trigger_abc123" to 2021-05-01T00:00:00Z`)
 	subscriberAPIVersion           = fmt.Sprintf("%s/%s", subscriberGroup, subscriberVersion)
 	subscriberGVK                  = metav1.GroupVersionKind{
 		Group:   subscriberGroup,
 		Version: subscriberVersion,
 		Kind:    subscriberKind,
 	}
+	testReplay            = `{"retention":"24h","time":"2021-05-01T00:00:00Z"}`
+	testReplayApplied     = `{"time":"2021-05-01T00:00:00Z"}`
+	testReplayAppliedTime = time.Date(2021, 5, 2, 10, 30, 0, 0, time.UTC)
+
 	brokerDeliverySpec = &eventingduckv1.DeliverySpec{
 		BackoffDelay:  &backoffDelay,
 		BackoffPolicy: &backoffPolicy,
@@ -189,6 +201,38 @@ func TestAllCasesTrigger(t *testing.T) {
 				NoSubscriptionsExist(),
 			},
 		},
+		{
+			Name: "Trigger with replay is being deleted, replay sub exists",
+			Key:  testKey,
+			Objects: []runtime.Object{
+				NewTrigger(triggerName, testNS, brokerName,
+					WithTriggerDeletionTimestamp,
+					WithTriggerUID(testUID),
+					WithTriggerFinalizers(finalizerName),
+					WithTriggerAnnotation(brokerv1.ReplayAnnotation, testReplay),
+					WithTriggerSetDefaults),
+			},
+			WantEvents: []string{
+				topicDeletedEvent,
+				subscriptionDeletedEvent,
+				replaySubscriptionDeletedEvent,
+				triggerFinalizerUpdatedEvent,
+				triggerFinalizedEvent,
+			},
+			WantPatches: []clientgotesting.PatchActionImpl{
+				patchRemoveFinalizers(testNS, triggerName),
+			},
+			OtherTestData: map[string]interface{}{
+				"pre": []PubsubAction{
+					TopicAndSub("cre-tgr_testnamespace_test-trigger_abc123", "cre-tgr_testnamespace_test-trigger_abc123"),
+					TopicAndSub(decouplingTopic, replaySubscription),
+				},
+			},
+			PostConditions: []func(*testing.T, *TableRow){
+				OnlyTopics(decouplingTopic),
+				NoSubscriptionsExist(),
+			},
+		},
 		{
 			Name: "Broker not found, Trigger with finalizer should be finalized",
 			Key:  testKey,
@@ -312,6 +356,7 @@ func TestAllCasesTrigger(t *testing.T) {
 					WithTriggerSubscriberResolvedSucceeded,
 					WithTriggerStatusSubscriberURI(subscriberURI),
 					WithTriggerSetDefaults,
+					WithTriggerDataPlaneObservedGeneration(0),
 				),
 			}},
 			WantEvents: []string{
@@ -323,9 +368,13 @@ func TestAllCasesTrigger(t *testing.T) {
 			WantPatches: []clientgotesting.PatchActionImpl{
 				patchFinalizers(testNS, triggerName, finalizerName),
 			},
-			OtherTestData: map[string]interface{}{},
+			OtherTestData: map[string]interface{}{
+				"pre": []PubsubAction{
+					Topic("test-dead-letter-topic-id"),
+				},
+			},
 			PostConditions: []func(*testing.T, *TableRow){
-				OnlyTopics("cre-tgr_testnamespace_test-trigger_abc123"),
+				OnlyTopics("cre-tgr_testnamespace_test-trigger_abc123", "test-dead-letter-topic-id"),
 				OnlySubscriptions("cre-tgr_testnamespace_test-trigger_abc123"),
 				SubscriptionHasRetryPolicy("cre-tgr_testnamespace_test-trigger_abc123",
 					&pubsub.RetryPolicy{
@@ -395,6 +444,331 @@ func TestAllCasesTrigger(t *testing.T) {
 					WithTriggerSubscriberResolvedSucceeded,
 					WithTriggerStatusSubscriberURI(subscriberURI),
 					WithTriggerSetDefaults,
+					WithTriggerDataPlaneObservedGeneration(0),
+				),
+			}},
+			WantEvents: []string{
+				triggerFinalizerUpdatedEvent,
+				topicCreatedEvent,
+				subscriptionCreatedEvent,
+				triggerReconciledEvent,
+			},
+			WantPatches: []clientgotesting.PatchActionImpl{
+				patchFinalizers(testNS, triggerName, finalizerName),
+			},
+			OtherTestData: map[string]interface{}{
+				"pre": []PubsubAction{
+					Topic("test-dead-letter-topic-id"),
+				},
+			},
+			PostConditions: []func(*testing.T, *TableRow){
+				OnlyTopics("cre-tgr_testnamespace_test-trigger_abc123", "test-dead-letter-topic-id"),
+				OnlySubscriptions("cre-tgr_testnamespace_test-trigger_abc123"),
+				SubscriptionHasRetryPolicy("cre-tgr_testnamespace_test-trigger_abc123",
+					&pubsub.RetryPolicy{
+						MaximumBackoff: 5 * time.Second,
+						MinimumBackoff: 5 * time.Second,
+					}),
+				SubscriptionHasDeadLetterPolicy("cre-tgr_testnamespace_test-trigger_abc123",
+					&pubsub.DeadLetterPolicy{
+						MaxDeliveryAttempts: 3,
+						DeadLetterTopic:     "projects/test-project-id/topics/test-dead-letter-topic-id",
+					}),
+				TopicExistsWithConfig("cre-tgr_testnamespace_test-trigger_abc123", &pubsub.TopicConfig{
+					Labels: map[string]string{
+						"name": "test-trigger", "namespace": "testnamespace", "resource": "triggers",
+					},
+				}),
+			},
+		},
+		{
+			Name: "Trigger created with replay, replay subscription seeked",
+			Key:  testKey,
+			Objects: []runtime.Object{
+				NewBroker(brokerName, testNS,
+					WithBrokerUID(brokerUID),
+					WithBrokerClass(brokerv1.BrokerClass),
+					WithInitBrokerConditions,
+					WithBrokerReady("url"),
+					WithBrokerDeliverySpec(brokerDeliverySpec),
+					WithBrokerSetDefaults,
+				),
+				makeSubscriberAddressableAsUnstructured(),
+				NewTrigger(triggerName, testNS, brokerName,
+					WithTriggerUID(testUID),
+					WithTriggerSubscriberRef(subscriberGVK, subscriberName, testNS),
+					WithTriggerAnnotation(brokerv1.ReplayAnnotation, testReplay),
+					WithTriggerSetDefaults),
+			},
+			WantStatusUpdates: []clientgotesting.UpdateActionImpl{{
+				Object: NewTrigger(triggerName, testNS, brokerName,
+					WithTriggerUID(testUID),
+					WithTriggerSubscriberRef(subscriberGVK, subscriberName, testNS),
+					WithTriggerBrokerReady,
+					WithTriggerSubscriptionReady,
+					WithTriggerTopicReady,
+					WithTriggerDependencyReady,
+					WithTriggerSubscriberResolvedSucceeded,
+					WithTriggerStatusSubscriberURI(subscriberURI),
+					WithTriggerAnnotation(brokerv1.ReplayAnnotation, testReplay),
+					WithTriggerLastReplay(testReplayApplied),
+					WithTriggerLastReplayTime(testReplayAppliedTime),
+					WithTriggerReplaySubscription(replaySubscription),
+					WithTriggerSetDefaults,
+					WithTriggerDataPlaneObservedGeneration(0),
+				),
+			}},
+			WantEvents: []string{
+				triggerFinalizerUpdatedEvent,
+				topicCreatedEvent,
+				subscriptionCreatedEvent,
+				replaySubscriptionCreatedEvent,
+				replaySubscriptionSeekedEvent,
+				triggerReconciledEvent,
+			},
+			WantPatches: []clientgotesting.PatchActionImpl{
+				patchFinalizers(testNS, triggerName, finalizerName),
+			},
+			OtherTestData: map[string]interface{}{
+				"pre": []PubsubAction{
+					Topic("test-dead-letter-topic-id"),
+					Topic(decouplingTopic),
+				},
+			},
+			PostConditions: []func(*testing.T, *TableRow){
+				OnlyTopics("cre-tgr_testnamespace_test-trigger_abc123", "test-dead-letter-topic-id", decouplingTopic),
+				OnlySubscriptions("cre-tgr_testnamespace_test-trigger_abc123", replaySubscription),
+				SubscriptionHasRetention(replaySubscription, true, 24*time.Hour),
+				SubscriptionHasRetryPolicy("cre-tgr_testnamespace_test-trigger_abc123",
+					&pubsub.RetryPolicy{
+						MaximumBackoff: 5 * time.Second,
+						MinimumBackoff: 5 * time.Second,
+					}),
+				SubscriptionHasDeadLetterPolicy("cre-tgr_testnamespace_test-trigger_abc123",
+					&pubsub.DeadLetterPolicy{
+						MaxDeliveryAttempts: 3,
+						DeadLetterTopic:     "projects/test-project-id/topics/test-dead-letter-topic-id",
+					}),
+				TopicExistsWithConfig("cre-tgr_testnamespace_test-trigger_abc123", &pubsub.TopicConfig{
+					Labels: map[string]string{
+						"name": "test-trigger", "namespace": "testnamespace", "resource": "triggers",
+					},
+				}),
+			},
+		},
+		{
+			Name: "Trigger with replay already applied",
+			Key:  testKey,
+			Objects: []runtime.Object{
+				NewBroker(brokerName, testNS,
+					WithBrokerUID(brokerUID),
+					WithBrokerClass(brokerv1.BrokerClass),
+					WithInitBrokerConditions,
+					WithBrokerReady("url"),
+					WithBrokerDeliverySpec(brokerDeliverySpec),
+					WithBrokerSetDefaults,
+				),
+				makeSubscriberAddressableAsUnstructured(),
+				NewTrigger(triggerName, testNS, brokerName,
+					WithTriggerUID(testUID),
+					WithTriggerSubscriberRef(subscriberGVK, subscriberName, testNS),
+					WithTriggerAnnotation(brokerv1.ReplayAnnotation, testReplay),
+					WithTriggerLastReplay(testReplayApplied),
+					WithTriggerSetDefaults),
+			},
+			WantStatusUpdates: []clientgotesting.UpdateActionImpl{{
+				Object: NewTrigger(triggerName, testNS, brokerName,
+					WithTriggerUID(testUID),
+					WithTriggerSubscriberRef(subscriberGVK, subscriberName, testNS),
+					WithTriggerBrokerReady,
+					WithTriggerSubscriptionReady,
+					WithTriggerTopicReady,
+					WithTriggerDependencyReady,
+					WithTriggerSubscriberResolvedSucceeded,
+					WithTriggerStatusSubscriberURI(subscriberURI),
+					WithTriggerAnnotation(brokerv1.ReplayAnnotation, testReplay),
+					WithTriggerLastReplay(testReplayApplied),
+					WithTriggerReplaySubscription(replaySubscription),
+					WithTriggerSetDefaults,
+					WithTriggerDataPlaneObservedGeneration(0),
+				),
+			}},
+			WantEvents: []string{
+				triggerFinalizerUpdatedEvent,
+				topicCreatedEvent,
+				subscriptionCreatedEvent,
+				replaySubscriptionCreatedEvent,
+				triggerReconciledEvent,
+			},
+			WantPatches: []clientgotesting.PatchActionImpl{
+				patchFinalizers(testNS, triggerName, finalizerName),
+			},
+			OtherTestData: map[string]interface{}{
+				"pre": []PubsubAction{
+					Topic("test-dead-letter-topic-id"),
+					Topic(decouplingTopic),
+				},
+			},
+			PostConditions: []func(*testing.T, *TableRow){
+				OnlyTopics("cre-tgr_testnamespace_test-trigger_abc123", "test-dead-letter-topic-id", decouplingTopic),
+				OnlySubscriptions("cre-tgr_testnamespace_test-trigger_abc123", replaySubscription),
+				SubscriptionHasRetention(replaySubscription, true, 24*time.Hour),
+				SubscriptionHasRetryPolicy("cre-tgr_testnamespace_test-trigger_abc123",
+					&pubsub.RetryPolicy{
+						MaximumBackoff: 5 * time.Second,
+						MinimumBackoff: 5 * time.Second,
+					}),
+				SubscriptionHasDeadLetterPolicy("cre-tgr_testnamespace_test-trigger_abc123",
+					&pubsub.DeadLetterPolicy{
+						MaxDeliveryAttempts: 3,
+						DeadLetterTopic:     "projects/test-project-id/topics/test-dead-letter-topic-id",
+					}),
+				TopicExistsWithConfig("cre-tgr_testnamespace_test-trigger_abc123", &pubsub.TopicConfig{
+					Labels: map[string]string{
+						"name": "test-trigger", "namespace": "testnamespace", "resource": "triggers",
+					},
+				}),
+			},
+		},
+		{
+			Name: "Trigger replay removed, replay subscription deleted",
+			Key:  testKey,
+			Objects: []runtime.Object{
+				NewBroker(brokerName, testNS,
+					WithBrokerUID(brokerUID),
+					WithBrokerClass(brokerv1.BrokerClass),
+					WithInitBrokerConditions,
+					WithBrokerReady("url"),
+					WithBrokerDeliverySpec(brokerDeliverySpec),
+					WithBrokerSetDefaults,
+				),
+				makeSubscriberAddressableAsUnstructured(),
+				NewTrigger(triggerName, testNS, brokerName,
+					WithTriggerUID(testUID),
+					WithTriggerSubscriberRef(subscriberGVK, subscriberName, testNS),
+					WithTriggerLastReplay(testReplayApplied),
+					WithTriggerLastReplayTime(testReplayAppliedTime),
+					WithTriggerReplaySubscription(replaySubscription),
+					WithTriggerSetDefaults),
+			},
+			WantStatusUpdates: []clientgotesting.UpdateActionImpl{{
+				Object: NewTrigger(triggerName, testNS, brokerName,
+					WithTriggerUID(testUID),
+					WithTriggerSubscriberRef(subscriberGVK, subscriberName, testNS),
+					WithTriggerBrokerReady,
+					WithTriggerSubscriptionReady,
+					WithTriggerTopicReady,
+					WithTriggerDependencyReady,
+					WithTriggerSubscriberResolvedSucceeded,
+					WithTriggerStatusSubscriberURI(subscriberURI),
+					WithTriggerSetDefaults,
+					WithTriggerDataPlaneObservedGeneration(0),
+				),
+			}},
+			WantEvents: []string{
+				triggerFinalizerUpdatedEvent,
+				topicCreatedEvent,
+				subscriptionCreatedEvent,
+				replaySubscriptionDeletedEvent,
+				triggerReconciledEvent,
+			},
+			WantPatches: []clientgotesting.PatchActionImpl{
+				patchFinalizers(testNS, triggerName, finalizerName),
+			},
+			OtherTestData: map[string]interface{}{
+				"pre": []PubsubAction{
+					Topic("test-dead-letter-topic-id"),
+					TopicAndSub(decouplingTopic, replaySubscription),
+				},
+			},
+			PostConditions: []func(*testing.T, *TableRow){
+				OnlyTopics("cre-tgr_testnamespace_test-trigger_abc123", "test-dead-letter-topic-id", decouplingTopic),
+				OnlySubscriptions("cre-tgr_testnamespace_test-trigger_abc123"),
+			},
+		},
+		{
+			// A replay subscription that isn't recorded in the status is not looked up.
+			Name: "Trigger without replay subscription",
+			Key:  testKey,
+			Objects: []runtime.Object{
+				NewBroker(brokerName, testNS,
+					WithBrokerUID(brokerUID),
+					WithBrokerClass(brokerv1.BrokerClass),
+					WithInitBrokerConditions,
+					WithBrokerReady("url"),
+					WithBrokerDeliverySpec(brokerDeliverySpec),
+					WithBrokerSetDefaults,
+				),
+				makeSubscriberAddressableAsUnstructured(),
+				NewTrigger(triggerName, testNS, brokerName,
+					WithTriggerUID(testUID),
+					WithTriggerSubscriberRef(subscriberGVK, subscriberName, testNS),
+					WithTriggerSetDefaults),
+			},
+			WantStatusUpdates: []clientgotesting.UpdateActionImpl{{
+				Object: NewTrigger(triggerName, testNS, brokerName,
+					WithTriggerUID(testUID),
+					WithTriggerSubscriberRef(subscriberGVK, subscriberName, testNS),
+					WithTriggerBrokerReady,
+					WithTriggerSubscriptionReady,
+					WithTriggerTopicReady,
+					WithTriggerDependencyReady,
+					WithTriggerSubscriberResolvedSucceeded,
+					WithTriggerStatusSubscriberURI(subscriberURI),
+					WithTriggerSetDefaults,
+					WithTriggerDataPlaneObservedGeneration(0),
+				),
+			}},
+			WantEvents: []string{
+				triggerFinalizerUpdatedEvent,
+				topicCreatedEvent,
+				subscriptionCreatedEvent,
+				triggerReconciledEvent,
+			},
+			WantPatches: []clientgotesting.PatchActionImpl{
+				patchFinalizers(testNS, triggerName, finalizerName),
+			},
+			OtherTestData: map[string]interface{}{
+				"pre": []PubsubAction{
+					Topic("test-dead-letter-topic-id"),
+					TopicAndSub(decouplingTopic, replaySubscription),
+				},
+			},
+			PostConditions: []func(*testing.T, *TableRow){
+				OnlyTopics("cre-tgr_testnamespace_test-trigger_abc123", "test-dead-letter-topic-id", decouplingTopic),
+				OnlySubscriptions("cre-tgr_testnamespace_test-trigger_abc123", replaySubscription),
+			},
+		},
+		{
+			Name: "Trigger created, broker with ordering ready, subscriber is addressable",
+			Key:  testKey,
+			Objects: []runtime.Object{
+				NewBroker(brokerName, testNS,
+					WithBrokerClass(brokerv1.BrokerClass),
+					WithBrokerAnnotations(map[string]string{brokerv1.OrderingAnnotation: `{"keyExtension": "partitionkey"}`}),
+					WithInitBrokerConditions,
+					WithBrokerReady("url"),
+					WithBrokerDeliverySpec(brokerDeliverySpec),
+					WithBrokerSetDefaults,
+				),
+				makeSubscriberAddressableAsUnstructured(),
+				NewTrigger(triggerName, testNS, brokerName,
+					WithTriggerUID(testUID),
+					WithTriggerSubscriberRef(subscriberGVK, subscriberName, testNS),
+					WithTriggerSetDefaults),
+			},
+			WantStatusUpdates: []clientgotesting.UpdateActionImpl{{
+				Object: NewTrigger(triggerName, testNS, brokerName,
+					WithTriggerUID(testUID),
+					WithTriggerSubscriberRef(subscriberGVK, subscriberName, testNS),
+					WithTriggerBrokerReady,
+					WithTriggerSubscriptionReady,
+					WithTriggerTopicReady,
+					WithTriggerDependencyReady,
+					WithTriggerSubscriberResolvedSucceeded,
+					WithTriggerStatusSubscriberURI(subscriberURI),
+					WithTriggerSetDefaults,
+					WithTriggerDataPlaneObservedGeneration(0),
 				),
 			}},
 			WantEvents: []string{
@@ -414,6 +788,7 @@ func TestAllCasesTrigger(t *testing.T) {
 			PostConditions: []func(*testing.T, *TableRow){
 				OnlyTopics("cre-tgr_testnamespace_test-trigger_abc123", "test-dead-letter-topic-id"),
 				OnlySubscriptions("cre-tgr_testnamespace_test-trigger_abc123"),
+				SubscriptionHasMessageOrdering("cre-tgr_testnamespace_test-trigger_abc123", true),
 				SubscriptionHasRetryPolicy("cre-tgr_testnamespace_test-trigger_abc123",
 					&pubsub.RetryPolicy{
 						MaximumBackoff: 5 * time.Second,
@@ -459,6 +834,7 @@ func TestAllCasesTrigger(t *testing.T) {
 					WithTriggerSubscriberResolvedSucceeded,
 					WithTriggerStatusSubscriberURI(subscriberURI),
 					WithTriggerSetDefaults,
+					WithTriggerDataPlaneObservedGeneration(0),
 				),
 			}},
 			WantEvents: []string{
@@ -518,6 +894,7 @@ func TestAllCasesTrigger(t *testing.T) {
 					WithTriggerSubscriberResolvedSucceeded,
 					WithTriggerStatusSubscriberURI(subscriberURI),
 					WithTriggerSetDefaults,
+					WithTriggerDataPlaneObservedGeneration(0),
 				),
 			}},
 			WantEvents: []string{
@@ -585,6 +962,7 @@ func TestAllCasesTrigger(t *testing.T) {
 					WithTriggerSubscriberResolvedSucceeded,
 					WithTriggerStatusSubscriberURI(subscriberURI),
 					WithTriggerSetDefaults,
+					WithTriggerDataPlaneObservedGeneration(0),
 				),
 			}},
 			WantEvents: []string{
@@ -653,6 +1031,7 @@ func TestAllCasesTrigger(t *testing.T) {
 					WithTriggerSubscriberResolvedSucceeded,
 					WithTriggerStatusSubscriberURI(subscriberURI),
 					WithTriggerSetDefaults,
+					WithTriggerDataPlaneObservedGeneration(0),
 				),
 			}},
 			WantEvents: []string{
@@ -792,6 +1171,7 @@ func TestAllCasesTrigger(t *testing.T) {
 				DataresidencyStore: drStore,
 				ClusterRegion:      testClusterRegion,
 			},
+			clock: clock.NewFakeClock(testReplayAppliedTime),
 		}
 
 		return triggerreconciler.NewReconciler(ctx, r.Logger, r.RunClientSet, listers.GetTriggerLister(), r.Recorder, r, withAgentAndFinalizer(nil))
@@ -17,10 +17,18 @@ limitations under the License.
 package utils
 
 import (
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+
 	brokerv1 "github.com/google/knative-gcp/pkg/apis/broker/v1"
+	inteventsv1alpha1 "github.com/google/knative-gcp/pkg/apis/intevents/v1alpha1"
 	eventingv1 "knative.dev/eventing/pkg/apis/eventing/v1"
 	reconciler "knative.dev/pkg/reconciler"
 )
 
 // BrokerClassFilter is the function to filter brokers with proper brokerclass.
 var BrokerClassFilter = reconciler.AnnotationFilterFunc(eventingv1.BrokerClassAnnotationKey, brokerv1.BrokerClass, false /*allowUnset*/)
+
+// InBrokerCell returns true if the Broker or Channel is served by the BrokerCell.
+func InBrokerCell(obj metav1.Object, bc *inteventsv1alpha1.BrokerCell) bool {
+	return inteventsv1alpha1.BrokerCellName(obj) == bc.Name
+}
@@ -19,6 +19,7 @@ package pubsub
 import (
 	"context"
 	"fmt"
+	"time"
 
 	"cloud.google.com/go/pubsub"
 	"github.com/google/knative-gcp/pkg/logging"
@@ -35,6 +36,8 @@ const (
 	subCreated       = "SubscriptionCreated"
 	subDeleted       = "SubscriptionDeleted"
 	subConfigUpdated = "SubscriptionConfigUpdated"
+	subSeeked        = "SubscriptionSeeked"
+	subSeekFailed    = "SubscriptionSeekFailed"
 )
 
 func (r *Reconciler) ReconcileSubscription(ctx context.Context, id string, subConfig pubsub.SubscriptionConfig, obj runtime.Object, updater StatusUpdater) (*pubsub.Subscription, error) {
@@ -67,18 +70,39 @@ func (r *Reconciler) ReconcileSubscription(ctx context.Context, id string, subCo
 			return r.createSubscription(ctx, id, subConfig, obj, updater)
 		}
 		// Update the subscription config in case the retry or dead letter policy changed. A nil policy indicates no change.
-		if (subConfig.RetryPolicy != nil && !equality.Semantic.DeepEqual(config.RetryPolicy, subConfig.RetryPolicy)) ||
-			(subConfig.DeadLetterPolicy != nil && !equality.Semantic.DeepEqual(config.DeadLetterPolicy, subConfig.DeadLetterPolicy)) {
+		// The retention of acked messages is always kept in sync, and its duration only changes while it is enabled.
+		retentionChanged := config.RetainAckedMessages != subConfig.RetainAckedMessages ||
+			(subConfig.RetainAckedMessages && subConfig.RetentionDuration != 0 && config.RetentionDuration != subConfig.RetentionDuration)
+		configChanged := (subConfig.RetryPolicy != nil && !equality.Semantic.DeepEqual(config.RetryPolicy, subConfig.RetryPolicy)) ||
+			(subConfig.DeadLetterPolicy != nil && !equality.Semantic.DeepEqual(config.DeadLetterPolicy, subConfig.DeadLetterPolicy)) ||
+			retentionChanged
+		// The labels select the subscriptions scaling the data plane, so the subscriptions created
+		// before they were set are labeled too.
+		labelsChanged := len(subConfig.Labels) > 0 && !equality.Semantic.DeepEqual(config.Labels, subConfig.Labels)
+		if configChanged || labelsChanged {
 			updateSubConfig := pubsub.SubscriptionConfigToUpdate{
 				RetryPolicy:      subConfig.RetryPolicy,
 				DeadLetterPolicy: subConfig.DeadLetterPolicy,
 			}
+			if retentionChanged {
+				updateSubConfig.RetainAckedMessages = subConfig.RetainAckedMessages
+				if subConfig.RetainAckedMessages {
+					updateSubConfig.RetentionDuration = subConfig.RetentionDuration
+				}
+			}
+			if labelsChanged {
+				updateSubConfig.Labels = subConfig.Labels
+			}
 			if _, err := sub.Update(ctx, updateSubConfig); err != nil {
 				updater.MarkSubscriptionFailed("SubscriptionConfigUpdateFailed", "Failed to update Pub/Sub subscription config: %v", err)
 				return nil, err
 			}
-			logger.Info("Updated PubSub subscription config", zap.String("name", sub.ID()))
-			r.recorder.Eventf(obj, corev1.EventTypeNormal, subConfigUpdated, "Updated config for PubSub subscription %q", sub.ID())
+			if configChanged {
+				logger.Info("Updated PubSub subscription config", zap.String("name", sub.ID()))
+				r.recorder.Eventf(obj, corev1.EventTypeNormal, subConfigUpdated, "Updated config for PubSub subscription %q", sub.ID())
+			} else {
+				logger.Debug("Updated PubSub subscription labels", zap.String("name", sub.ID()))
+			}
 		}
 		updater.MarkSubscriptionReady(sub.ID())
 		return sub, nil
@@ -87,6 +111,30 @@ func (r *Reconciler) ReconcileSubscription(ctx context.Context, id string, subCo
 	return r.createSubscription(ctx, id, subConfig, obj, updater)
 }
 
+// SeekSubscription replays the messages of the subscription from the snapshot if snapshotID is
+// set, and from the time otherwise. Only the acked messages retained by the subscription or the
+// snapshot can be replayed.
+func (r *Reconciler) SeekSubscription(ctx context.Context, sub *pubsub.Subscription, t time.Time, snapshotID string, obj runtime.Object) error {
+	logger := logging.FromContext(ctx)
+	var err error
+	var target string
+	if snapshotID != "" {
+		target = fmt.Sprintf("snapshot %q", snapshotID)
+		err = sub.SeekToSnapshot(ctx, r.client.Snapshot(snapshotID))
+	} else {
+		target = t.UTC().Format(time.RFC3339)
+		err = sub.SeekToTime(ctx, t)
+	}
+	if err != nil {
+		logger.Error("Failed to seek Pub/Sub subscription", zap.String("name", sub.ID()), zap.String("target", target), zap.Error(err))
+		r.recorder.Eventf(obj, corev1.EventTypeWarning, subSeekFailed, "Failed to seek PubSub subscription %q to %s: %v", sub.ID(), target, err)
+		return err
+	}
+	logger.Info("Seeked PubSub subscription", zap.String("name", sub.ID()), zap.String("target", target))
+	r.recorder.Eventf(obj, corev1.EventTypeNormal, subSeeked, "Seeked PubSub subscription %q to %s", sub.ID(), target)
+	return nil
+}
+
 func (r *Reconciler) DeleteSubscription(ctx context.Context, id string, obj runtime.Object, updater StatusUpdater) error {
 	logger := logging.FromContext(ctx)
 	logger.Debug("Deleting decoupling sub")
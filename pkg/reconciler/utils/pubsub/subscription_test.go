@@ -20,6 +20,7 @@ import (
 	"context"
 	"fmt"
 	"reflect"
+	"strings"
 	"testing"
 	"time"
 
@@ -62,14 +63,17 @@ func TestReconcileSub(t *testing.T) {
 		},
 		{
 			name: "sub already exists, modify config",
-			pre:  []reconcilertesting.PubsubAction{reconcilertesting.TopicAndSub(topic, sub)},
+			pre: []reconcilertesting.PubsubAction{
+				reconcilertesting.TopicAndSub(topic, sub),
+				reconcilertesting.Topic("some-topic-id"),
+			},
 			wantSubConfig: &pubsub.SubscriptionConfig{
 				RetryPolicy: &pubsub.RetryPolicy{
 					MinimumBackoff: time.Second,
 					MaximumBackoff: time.Second,
 				},
 				DeadLetterPolicy: &pubsub.DeadLetterPolicy{
-					DeadLetterTopic:     "some-topic-id",
+					DeadLetterTopic:     "projects/" + project + "/topics/some-topic-id",
 					MaxDeliveryAttempts: 10,
 				},
 			},
@@ -78,6 +82,25 @@ func TestReconcileSub(t *testing.T) {
 			},
 			wantSubCondition: apis.Condition{Status: corev1.ConditionTrue},
 		},
+		{
+			// Labels are synced without an event, as they only select the subscriptions.
+			name:             "sub already exists, label it",
+			pre:              []reconcilertesting.PubsubAction{reconcilertesting.TopicAndSub(topic, sub)},
+			wantSubConfig:    &pubsub.SubscriptionConfig{Labels: map[string]string{"brokercell": "default"}},
+			wantSubCondition: apis.Condition{Status: corev1.ConditionTrue},
+		},
+		{
+			name: "sub already exists, retain acked messages",
+			pre:  []reconcilertesting.PubsubAction{reconcilertesting.TopicAndSub(topic, sub)},
+			wantSubConfig: &pubsub.SubscriptionConfig{
+				RetainAckedMessages: true,
+				RetentionDuration:   24 * time.Hour,
+			},
+			wantEvents: []string{
+				`Normal SubscriptionConfigUpdated Updated config for PubSub subscription "test-sub"`,
+			},
+			wantSubCondition: apis.Condition{Status: corev1.ConditionTrue},
+		},
 	}
 	for _, tc := range tests {
 		t.Run(tc.name, func(t *testing.T) {
@@ -90,6 +113,8 @@ func TestReconcileSub(t *testing.T) {
 				subConfig.Labels = tc.wantSubConfig.Labels
 				subConfig.RetryPolicy = tc.wantSubConfig.RetryPolicy
 				subConfig.DeadLetterPolicy = tc.wantSubConfig.DeadLetterPolicy
+				subConfig.RetainAckedMessages = tc.wantSubConfig.RetainAckedMessages
+				subConfig.RetentionDuration = tc.wantSubConfig.RetentionDuration
 			}
 			res, err := r.ReconcileSubscription(context.Background(), sub, subConfig, obj, su)
 
@@ -133,6 +158,45 @@ func TestDeleteSub(t *testing.T) {
 
 }
 
+func TestSeekSub(t *testing.T) {
+	tests := []struct {
+		name       string
+		snapshotID string
+		wantErr    bool
+		wantEvent  string
+	}{
+		{
+			name:      "seek to time",
+			wantEvent: `Normal SubscriptionSeeked Seeked PubSub subscription "test-sub" to 2021-05-01T00:00:00Z`,
+		},
+		{
+			// The fake Pub/Sub server does not support snapshots.
+			name:       "seek to snapshot fails",
+			snapshotID: "test-snapshot",
+			wantErr:    true,
+			wantEvent:  `Warning SubscriptionSeekFailed Failed to seek PubSub subscription "test-sub" to snapshot "test-snapshot": `,
+		},
+	}
+	for _, tc := range tests {
+		t.Run(tc.name, func(t *testing.T) {
+			tr, cleanup := newTestRunner(t, testCase{
+				pre:        []reconcilertesting.PubsubAction{reconcilertesting.TopicAndSub(topic, sub)},
+				wantEvents: []string{tc.wantEvent},
+			})
+			defer cleanup()
+			r := NewReconciler(tr.client, tr.recorder)
+			seekTime := time.Date(2021, 5, 1, 0, 0, 0, 0, time.UTC)
+			err := r.SeekSubscription(context.Background(), tr.client.Subscription(sub), seekTime, tc.snapshotID, obj)
+			if (err != nil) != tc.wantErr {
+				t.Errorf("SeekSubscription() error = %v, wantErr %v", err, tc.wantErr)
+			}
+			if got := <-tr.recorder.Events; !strings.HasPrefix(got, tc.wantEvent) {
+				t.Errorf("Unexpected event recorded, got: %v, want: %v", got, tc.wantEvent)
+			}
+		})
+	}
+}
+
 func deleteTopic(ctx context.Context, t *testing.T, c *pubsub.Client) {
 	if err := c.Topic(topic).Delete(ctx); err != nil {
 		t.Fatalf("Failed to delete topic: %v", err)
@@ -160,4 +224,10 @@ func verifySub(t *testing.T, got *pubsub.Subscription, wantConfig pubsub.Subscri
 	if !reflect.DeepEqual(gotConfig.DeadLetterPolicy, wantConfig.DeadLetterPolicy) {
 		t.Errorf("Unexpected dead letter policy in config, got:%+v, want: %+v", gotConfig.DeadLetterPolicy, wantConfig.DeadLetterPolicy)
 	}
+	if gotConfig.RetainAckedMessages != wantConfig.RetainAckedMessages {
+		t.Errorf("Unexpected retain acked messages in config, got:%v, want: %v", gotConfig.RetainAckedMessages, wantConfig.RetainAckedMessages)
+	}
+	if wantConfig.RetainAckedMessages && gotConfig.RetentionDuration != wantConfig.RetentionDuration {
+		t.Errorf("Unexpected retention duration in config, got:%v, want: %v", gotConfig.RetentionDuration, wantConfig.RetentionDuration)
+	}
 }
@@ -23,6 +23,7 @@ import (
 
 	"cloud.google.com/go/pubsub"
 	"github.com/google/go-cmp/cmp"
+	"github.com/google/go-cmp/cmp/cmpopts"
 	corev1 "k8s.io/api/core/v1"
 	"k8s.io/client-go/tools/record"
 	"knative.dev/pkg/apis"
@@ -111,7 +112,7 @@ func verifyTopic(t *testing.T, got *pubsub.Topic) {
 	if err != nil {
 		t.Fatalf("Failed to get config: %v", err)
 	}
-	if diff := cmp.Diff(gotConfig, topicConfig); diff != "" {
+	if diff := cmp.Diff(gotConfig, topicConfig, cmpopts.IgnoreUnexported(pubsub.TopicConfig{})); diff != "" {
 		t.Errorf("Unexpected config, got:%+v, want: %+v", gotConfig, topicConfig)
 	}
 }
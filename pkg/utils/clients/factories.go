@@ -28,6 +28,7 @@ import (
 	"knative.dev/eventing/pkg/kncloudevents"
 	"knative.dev/pkg/tracing/propagation/tracecontextb3"
 
+	gstorage "github.com/google/knative-gcp/pkg/gclient/storage"
 	"github.com/google/knative-gcp/pkg/utils/authcheck"
 )
 
@@ -50,6 +51,11 @@ func NewPubsubClient(ctx context.Context, projectID ProjectID) (*pubsub.Client,
 	return pubsub.NewClient(ctx, string(projectID))
 }
 
+// NewStorageClient provides a Cloud Storage client.
+func NewStorageClient(ctx context.Context) (gstorage.Client, error) {
+	return gstorage.NewClient(ctx)
+}
+
 // NewObservedPubsubClient creates a pubsub Cloudevents client with observability support.
 func NewObservedPubsubClient(ctx context.Context, client *pubsub.Client) (cev2.Client, error) {
 	p, err := cepubsub.New(ctx, cepubsub.WithClient(client))
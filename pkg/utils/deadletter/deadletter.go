@@ -0,0 +1,140 @@
+/*
+Copyright 2021 Google LLC
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+// Package deadletter holds what the receive adapters and the broker data plane share to dead
+// letter the events they fail to deliver.
+package deadletter
+
+import (
+	"encoding/base64"
+	"strconv"
+	"sync"
+	"time"
+)
+
+const (
+	// Extensions describing why an event was dead lettered, as defined by the Knative data plane
+	// contract.
+	ErrorDestExtension = "knativeerrordest"
+	ErrorCodeExtension = "knativeerrorcode"
+	ErrorDataExtension = "knativeerrordata"
+
+	// MaxErrorDataSize is the maximum number of bytes of the failure kept in the knativeerrordata
+	// extension.
+	MaxErrorDataSize = 1024
+
+	// AttemptTTL is how long the attempts of an event are remembered after its last attempt. It
+	// must be longer than the maximum backoff of the subscriptions the events are retried from.
+	AttemptTTL = time.Hour
+)
+
+// Extensions returns the extensions describing why the event sent to the destination was dead
+// lettered. statusCode is the status code of the response of the destination, zero if there was
+// none, and data describes the failure. Only the first MaxErrorDataSize bytes of data are kept.
+func Extensions(destination string, statusCode int, data []byte) map[string]string {
+	extensions := map[string]string{ErrorDestExtension: destination}
+	if statusCode != 0 {
+		extensions[ErrorCodeExtension] = strconv.Itoa(statusCode)
+	}
+	if len(data) > MaxErrorDataSize {
+		data = data[:MaxErrorDataSize]
+	}
+	if len(data) > 0 {
+		extensions[ErrorDataExtension] = base64.StdEncoding.EncodeToString(data)
+	}
+	return extensions
+}
+
+// Tracker counts the delivery attempts of the events, by a comparable key identifying an event.
+//
+// Attempts are counted in memory, so the count of an event is local to a pod: an event
+// redelivered by Pub/Sub to another pod starts counting again. This may only delay the dead
+// lettering of the event.
+type Tracker struct {
+	mux       sync.Mutex
+	entries   map[interface{}]*entry
+	lastPrune time.Time
+}
+
+type entry struct {
+	count    int32
+	lastSeen time.Time
+	// lastErr is the error of the last failed attempt.
+	lastErr error
+}
+
+// NewTracker creates an empty Tracker.
+func NewTracker() *Tracker {
+	return &Tracker{
+		entries:   make(map[interface{}]*entry),
+		lastPrune: time.Now(),
+	}
+}
+
+// Attempt records a new delivery attempt of the event and returns the number of attempts so far,
+// along with the error of the last failed attempt, if it was recorded.
+func (t *Tracker) Attempt(key interface{}) (int32, error) {
+	now := time.Now()
+	t.mux.Lock()
+	defer t.mux.Unlock()
+	if now.Sub(t.lastPrune) > AttemptTTL {
+		t.prune(now)
+	}
+	e, ok := t.entries[key]
+	if !ok {
+		e = &entry{}
+		t.entries[key] = e
+	}
+	e.count++
+	e.lastSeen = now
+	return e.count, e.lastErr
+}
+
+// Failed records the error of the last delivery attempt of the event.
+func (t *Tracker) Failed(key interface{}, err error) {
+	t.mux.Lock()
+	defer t.mux.Unlock()
+	if e, ok := t.entries[key]; ok {
+		e.lastErr = err
+	}
+}
+
+// Retract cancels the last attempt of the event, if it was not actually delivered.
+func (t *Tracker) Retract(key interface{}) {
+	t.mux.Lock()
+	defer t.mux.Unlock()
+	if e, ok := t.entries[key]; ok && e.count > 0 {
+		e.count--
+	}
+}
+
+// Forget removes the attempts of the event, once it is either delivered or dead lettered.
+func (t *Tracker) Forget(key interface{}) {
+	t.mux.Lock()
+	defer t.mux.Unlock()
+	delete(t.entries, key)
+}
+
+// prune removes the events that were not attempted recently. They were most likely delivered by
+// another pod, or their destination was deleted.
+func (t *Tracker) prune(now time.Time) {
+	for key, e := range t.entries {
+		if now.Sub(e.lastSeen) > AttemptTTL {
+			delete(t.entries, key)
+		}
+	}
+	t.lastPrune = now
+}
@@ -0,0 +1,101 @@
+/*
+Copyright 2021 Google LLC
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package deadletter
+
+import (
+	"encoding/base64"
+	"errors"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/google/go-cmp/cmp"
+)
+
+func TestExtensions(t *testing.T) {
+	long := strings.Repeat("a", MaxErrorDataSize+1)
+	for _, tc := range []struct {
+		name       string
+		statusCode int
+		data       []byte
+		want       map[string]string
+	}{{
+		name: "no response",
+		data: []byte("connection refused"),
+		want: map[string]string{
+			ErrorDestExtension: "http://sink",
+			ErrorDataExtension: base64.StdEncoding.EncodeToString([]byte("connection refused")),
+		},
+	}, {
+		name:       "response without body",
+		statusCode: 500,
+		want: map[string]string{
+			ErrorDestExtension: "http://sink",
+			ErrorCodeExtension: "500",
+		},
+	}, {
+		name:       "truncated response body",
+		statusCode: 400,
+		data:       []byte(long),
+		want: map[string]string{
+			ErrorDestExtension: "http://sink",
+			ErrorCodeExtension: "400",
+			ErrorDataExtension: base64.StdEncoding.EncodeToString([]byte(long[:MaxErrorDataSize])),
+		},
+	}} {
+		t.Run(tc.name, func(t *testing.T) {
+			if diff := cmp.Diff(tc.want, Extensions("http://sink", tc.statusCode, tc.data)); diff != "" {
+				t.Errorf("unexpected extensions (-want, +got) = %v", diff)
+			}
+		})
+	}
+}
+
+func TestTracker(t *testing.T) {
+	tracker := NewTracker()
+	if got, err := tracker.Attempt("event"); got != 1 || err != nil {
+		t.Errorf("first attempt got=(%d, %v), want=(1, nil)", got, err)
+	}
+	failure := errors.New("failed")
+	tracker.Failed("event", failure)
+	if got, err := tracker.Attempt("event"); got != 2 || err != failure {
+		t.Errorf("second attempt got=(%d, %v), want=(2, %v)", got, err, failure)
+	}
+	tracker.Retract("event")
+	if got, _ := tracker.Attempt("event"); got != 2 {
+		t.Errorf("attempt after retract got=%d, want=2", got)
+	}
+	tracker.Forget("event")
+	if got, err := tracker.Attempt("event"); got != 1 || err != nil {
+		t.Errorf("attempt after forget got=(%d, %v), want=(1, nil)", got, err)
+	}
+}
+
+func TestTrackerPrune(t *testing.T) {
+	tracker := NewTracker()
+	tracker.Attempt("stale")
+	tracker.entries["stale"].lastSeen = time.Now().Add(-2 * AttemptTTL)
+	tracker.lastPrune = time.Now().Add(-2 * AttemptTTL)
+	tracker.Attempt("fresh")
+
+	if got, _ := tracker.Attempt("stale"); got != 1 {
+		t.Errorf("stale event attempts got=%d, want=1", got)
+	}
+	if got, _ := tracker.Attempt("fresh"); got != 2 {
+		t.Errorf("fresh event attempts got=%d, want=2", got)
+	}
+}